@@ -0,0 +1,13 @@
+// Command usermgmt is an admin tool for the user management service. It
+// works against the database directly or against a running instance over HTTP.
+package main
+
+import (
+	"os"
+)
+
+func main() {
+	if err := newRootCmd().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
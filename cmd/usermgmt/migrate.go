@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"example.com/user-management/internal/db"
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/emailaddr"
+	"github.com/pressly/goose/v3"
+	"github.com/spf13/cobra"
+)
+
+// migrations are the migrations of the Postgres or the SQLite database.
+type migrations struct {
+	up          func(dbConn *sql.DB, rules emailaddr.Rules) ([]*goose.MigrationResult, error)
+	down        func(dbConn *sql.DB) (*goose.MigrationResult, error)
+	status      func(dbConn *sql.DB) ([]*goose.MigrationStatus, error)
+	rekeyEmails func(ctx context.Context, dbConn *sql.DB, rules emailaddr.Rules) error
+}
+
+var (
+	postgresMigrations = migrations{db.MigrateUp, db.MigrateDown, db.MigrationStatus, db.RekeyEmails}
+	sqliteMigrations   = migrations{sqlitedb.MigrateUp, sqlitedb.MigrateDown, sqlitedb.MigrationStatus, sqlitedb.RekeyEmails}
+)
+
+func newMigrateCmd(opts *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply or roll back database migrations",
+		Long: `Apply or roll back the migrations of the Postgres database of --dsn, or of
+the SQLite database of --sqlite-path.`,
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply all pending migrations",
+			Long: `Apply all pending migrations.
+
+The migrations normalizing email addresses and recording their rules key them
+as --email-provider-rules says, which must match EMAIL_PROVIDER_RULES of the
+server. It backfills the
+users of every tenant, so it must run as the owner of the users table, or a
+member of the owning role; it fails for any other.`,
+			Args: cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				dbConn, migrations, err := opts.migrationDatabase()
+				if err != nil {
+					return err
+				}
+
+				results, err := migrations.up(dbConn, opts.emailRules())
+				if err != nil {
+					return err
+				}
+				if len(results) == 0 {
+					fmt.Fprintln(cmd.OutOrStdout(), "No pending migrations")
+				}
+				for _, result := range results {
+					fmt.Fprintf(cmd.OutOrStdout(), "Applied %s (%s)\n", result.Source.Path, result.Duration)
+				}
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "rekey-emails",
+			Short: "Key email addresses by the current provider rules",
+			Long: `Key the email addresses of the users of every tenant by --email-provider-rules
+and record those as the rules of the database.
+
+The server and the tool refuse to use a database whose addresses are keyed by
+other rules than they are given, since addresses keyed by two rule sets could
+let users share a mailbox. Run this after changing EMAIL_PROVIDER_RULES, with
+the new setting. It changes nothing if users of a tenant would then share a
+mailbox, and lists them. Like the migrations, it must run as the owner of the
+users table.`,
+			Args: cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				dbConn, migrations, err := opts.migrationDatabase()
+				if err != nil {
+					return err
+				}
+
+				if err := migrations.rekeyEmails(cmd.Context(), dbConn, opts.emailRules()); err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), "Rekeyed email addresses")
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "down",
+			Short: "Roll back the most recent migration",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				dbConn, migrations, err := opts.migrationDatabase()
+				if err != nil {
+					return err
+				}
+
+				result, err := migrations.down(dbConn)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Rolled back %s (%s)\n", result.Source.Path, result.Duration)
+				return nil
+			},
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "Show which migrations have been applied",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				dbConn, migrations, err := opts.migrationDatabase()
+				if err != nil {
+					return err
+				}
+
+				statuses, err := migrations.status(dbConn)
+				if err != nil {
+					return err
+				}
+
+				tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+				fmt.Fprintln(tw, "VERSION\tMIGRATION\tSTATE\tAPPLIED AT")
+				for _, s := range statuses {
+					appliedAt := ""
+					if !s.AppliedAt.IsZero() {
+						appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+					}
+					fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", s.Source.Version, s.Source.Path, s.State, appliedAt)
+				}
+				return tw.Flush()
+			},
+		},
+	)
+
+	return cmd
+}
+
+// migrationDatabase returns the database and the migrations for it, those
+// of SQLite with --sqlite-path.
+func (opts *rootOptions) migrationDatabase() (*sql.DB, migrations, error) {
+	if opts.server != "" {
+		return nil, migrations{}, errors.New("migrate talks to the database directly and cannot be used with --server")
+	}
+	dbConn, err := opts.database()
+	if err != nil {
+		return nil, migrations{}, err
+	}
+	if opts.sqlitePath != "" {
+		return dbConn, sqliteMigrations, nil
+	}
+	return dbConn, postgresMigrations, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"example.com/user-management/internal/model"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+var outputFormats = []string{outputTable, outputJSON, outputYAML}
+
+// userView is the shape users are printed and exported in. It uses the same
+// field names as the REST request bodies.
+type userView struct {
+	UserId    string       `json:"userId,omitempty" yaml:"userId,omitempty"`
+	FirstName string       `json:"firstName" yaml:"firstName"`
+	LastName  string       `json:"lastName" yaml:"lastName"`
+	Email     string       `json:"email" yaml:"email"`
+	Phone     string       `json:"phone" yaml:"phone"`
+	Age       int          `json:"age,omitempty" yaml:"age,omitempty"`
+	Status    model.Status `json:"status,omitempty" yaml:"status,omitempty"`
+}
+
+func toUserView(user model.User) userView {
+	return userView{
+		UserId:    user.UserId.String(),
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		Age:       user.Age,
+		Status:    user.Status,
+	}
+}
+
+func printUsers(w io.Writer, format string, users []model.User) error {
+	views := make([]userView, len(users))
+	for i, u := range users {
+		views[i] = toUserView(u)
+	}
+
+	switch format {
+	case outputJSON:
+		return writeJSON(w, views)
+	case outputYAML:
+		return yaml.NewEncoder(w).Encode(views)
+	case outputTable:
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tFIRST NAME\tLAST NAME\tEMAIL\tPHONE\tAGE\tSTATUS")
+		for _, v := range views {
+			age := ""
+			if v.Age > 0 {
+				age = strconv.Itoa(v.Age)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", v.UserId, v.FirstName, v.LastName, v.Email, v.Phone, age, v.Status)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
+
+func printUser(w io.Writer, format string, user model.User) error {
+	switch format {
+	case outputJSON:
+		return writeJSON(w, toUserView(user))
+	case outputYAML:
+		return yaml.NewEncoder(w).Encode(toUserView(user))
+	}
+	return printUsers(w, format, []model.User{user})
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/model"
+	"github.com/google/uuid"
+)
+
+// remoteBackend talks to a running instance through the REST API.
+type remoteBackend struct {
+	baseURL string
+	client  *http.Client
+}
+
+func newRemoteBackend(baseURL string) *remoteBackend {
+	return &remoteBackend{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type userResponse struct {
+	User model.User `json:"user"`
+}
+
+func (backend *remoteBackend) CreateUser(ctx context.Context, user model.User) (model.User, error) {
+	req := dto.CreateUserRequest{
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		Age:       user.Age,
+		Status:    user.Status,
+	}
+
+	var resp userResponse
+	if _, err := backend.do(ctx, http.MethodPost, "/users", req, &resp); err != nil {
+		return model.User{}, err
+	}
+	return resp.User, nil
+}
+
+func (backend *remoteBackend) GetAllUsers(ctx context.Context) ([]model.User, error) {
+	var users []model.User
+	if _, err := backend.do(ctx, http.MethodGet, "/users", nil, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func (backend *remoteBackend) GetUserById(ctx context.Context, userId uuid.UUID) (model.User, bool, error) {
+	var user model.User
+	found, err := backend.do(ctx, http.MethodGet, "/users/"+userId.String(), nil, &user)
+	return user, found, err
+}
+
+func (backend *remoteBackend) UpdateUser(ctx context.Context, user model.User, userId uuid.UUID) (model.User, bool, error) {
+	req := dto.UpdateUserRequest{
+		FirstName: &user.FirstName,
+		LastName:  &user.LastName,
+		Email:     &user.Email,
+		Phone:     &user.Phone,
+	}
+	if user.Age > 0 {
+		req.Age = &user.Age
+	}
+	if user.Status != "" {
+		req.Status = &user.Status
+	}
+
+	var resp userResponse
+	found, err := backend.do(ctx, http.MethodPatch, "/users/"+userId.String(), req, &resp)
+	return resp.User, found, err
+}
+
+func (backend *remoteBackend) DeleteUser(ctx context.Context, userId uuid.UUID) (bool, error) {
+	return backend.do(ctx, http.MethodDelete, "/users/"+userId.String(), nil, nil)
+}
+
+// do sends body as JSON and decodes the response into out. A 404 is reported
+// as not found rather than as an error.
+func (backend *remoteBackend) do(ctx context.Context, method, path string, body any, out any) (bool, error) {
+	var reqBody io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return false, err
+		}
+		reqBody = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, backend.baseURL+path, reqBody)
+	if err != nil {
+		return false, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := backend.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return false, fmt.Errorf("%s %s: %s: %s", method, path, resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return false, fmt.Errorf("invalid response from server: %w", err)
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/user-management/internal/handler"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// fakeUserStore keeps users in a map so the REST handlers can run without a
+// database.
+type fakeUserStore struct {
+	users map[uuid.UUID]model.User
+}
+
+func (s *fakeUserStore) CreateUser(_ context.Context, u model.User) (model.User, error) {
+	u.UserId = uuid.New()
+	if u.Status == "" {
+		u.Status = model.StatusActive
+	}
+	s.users[u.UserId] = u
+	return u, nil
+}
+func (s *fakeUserStore) GetAllUsers(_ context.Context) ([]model.User, error) {
+	users := make([]model.User, 0, len(s.users))
+	for _, u := range s.users {
+		users = append(users, u)
+	}
+	return users, nil
+}
+func (s *fakeUserStore) GetUserById(_ context.Context, id uuid.UUID) (model.User, bool, error) {
+	u, ok := s.users[id]
+	return u, ok, nil
+}
+func (s *fakeUserStore) GetUsersByIds(context.Context, []uuid.UUID) ([]model.User, error) {
+	return nil, nil
+}
+func (s *fakeUserStore) ListUsers(context.Context, model.UserFilter, uuid.UUID, int) ([]model.User, error) {
+	return nil, nil
+}
+func (s *fakeUserStore) UpdateUser(_ context.Context, u model.User, id uuid.UUID) (model.User, bool, error) {
+	if _, ok := s.users[id]; !ok {
+		return model.User{}, false, nil
+	}
+	s.users[id] = u
+	return u, true, nil
+}
+func (s *fakeUserStore) PutUser(_ context.Context, u model.User, _ model.Precondition) (model.User, bool, error) {
+	_, ok := s.users[u.UserId]
+	s.users[u.UserId] = u
+	return u, !ok, nil
+}
+func (s *fakeUserStore) ModifyUser(_ context.Context, id uuid.UUID, p model.Precondition, modify func(*model.User) error) (model.User, bool, error) {
+	u, ok := s.users[id]
+	if !ok {
+		return model.User{}, false, nil
+	}
+	if !p.Holds(u, true) {
+		return model.User{}, false, store.ErrPreconditionFailed
+	}
+	if err := modify(&u); err != nil {
+		return model.User{}, false, err
+	}
+	s.users[id] = u
+	return u, true, nil
+}
+func (s *fakeUserStore) DeleteUser(_ context.Context, id uuid.UUID) (bool, error) {
+	_, ok := s.users[id]
+	delete(s.users, id)
+	return ok, nil
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	userHandler := handler.NewUserHandler(&fakeUserStore{users: map[uuid.UUID]model.User{}})
+
+	r := chi.NewRouter()
+	r.Post("/users", userHandler.CreateUser)
+	r.Get("/users", userHandler.GetAllUsers)
+	r.Get("/users/{id}", userHandler.GetUserById)
+	r.Patch("/users/{id}", userHandler.UpdateUser)
+	r.Delete("/users/{id}", userHandler.DeleteUser)
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func run(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+
+	cmd := newRootCmd()
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetIn(strings.NewReader(stdin))
+	cmd.SetArgs(args)
+
+	err := cmd.Execute()
+	return out.String(), err
+}
+
+func TestRemoteBackend_RoundTrip(t *testing.T) {
+	backend := newRemoteBackend(newTestServer(t).URL)
+
+	created, err := backend.CreateUser(t.Context(), model.User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john@gmail.com",
+		Phone:     "+94712345678",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.UserId == uuid.Nil || created.Status != model.StatusActive {
+		t.Fatalf("expected an id and Active status, got %+v", created)
+	}
+
+	created.Age = 30
+	updated, ok, err := backend.UpdateUser(t.Context(), created, created.UserId)
+	if err != nil || !ok {
+		t.Fatalf("UpdateUser failed: ok=%v err=%v", ok, err)
+	}
+	if updated.Age != 30 {
+		t.Fatalf("expected age 30, got %d", updated.Age)
+	}
+
+	ok, err = backend.DeleteUser(t.Context(), created.UserId)
+	if err != nil || !ok {
+		t.Fatalf("DeleteUser failed: ok=%v err=%v", ok, err)
+	}
+
+	_, ok, err = backend.GetUserById(t.Context(), created.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatalf("expected user to be deleted")
+	}
+}
+
+func TestUsersImportAndExport(t *testing.T) {
+	server := newTestServer(t)
+
+	input := "firstName,lastName,email,phone,age,status\n" +
+		"John,Doe,john@gmail.com,+94712345678,27,Active\n" +
+		"J,Doe,invalid-email,+94712345679,,\n"
+
+	out, err := run(t, input, "--server", server.URL, "users", "import", "--format", "csv", "-")
+	if err == nil {
+		t.Fatalf("expected import to report the invalid row")
+	}
+	if !strings.Contains(out, "Imported 1 users, 1 failed") {
+		t.Fatalf("unexpected import output: %s", out)
+	}
+
+	out, err = run(t, "", "--server", server.URL, "-o", "json", "users", "list")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"email": "john@gmail.com"`) {
+		t.Fatalf("expected imported user in list, got %s", out)
+	}
+
+	out, err = run(t, "", "--server", server.URL, "users", "export", "--format", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 || lines[0] != strings.Join(csvHeader, ",") {
+		t.Fatalf("unexpected export output: %s", out)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+
+	"example.com/user-management/internal/db"
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/emailaddr"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/store/sqlite"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// userBackend is the part of store.UserStoreInterface the tool needs, so a
+// remote instance can stand in for the store.
+type userBackend interface {
+	CreateUser(ctx context.Context, user model.User) (model.User, error)
+	GetAllUsers(ctx context.Context) ([]model.User, error)
+	GetUserById(ctx context.Context, userId uuid.UUID) (model.User, bool, error)
+	UpdateUser(ctx context.Context, user model.User, userId uuid.UUID) (model.User, bool, error)
+	DeleteUser(ctx context.Context, userId uuid.UUID) (bool, error)
+}
+
+// directStore is the store of the database the tool talks to directly,
+// Postgres or SQLite.
+type directStore interface {
+	userBackend
+	NormalizePhones(ctx context.Context) (store.PhoneBackfill, error)
+}
+
+type rootOptions struct {
+	dsn string
+	// sqlitePath is the SQLite database file used instead of the Postgres
+	// database of dsn, if set.
+	sqlitePath string
+	server     string
+	output     string
+	// emailProviderRules is whether the address variants a mail provider
+	// delivers to one mailbox are one user.
+	emailProviderRules bool
+	// phoneRegion is the region national phone numbers belong to.
+	phoneRegion string
+
+	dbConn *sql.DB
+}
+
+func newRootCmd() *cobra.Command {
+	opts := &rootOptions{}
+
+	cmd := &cobra.Command{
+		Use:          "usermgmt",
+		Short:        "Manage users of the user management service",
+		SilenceUsage: true,
+		PersistentPreRunE: func(*cobra.Command, []string) error {
+			if !slices.Contains(outputFormats, opts.output) {
+				return fmt.Errorf("unknown output format %q, expected one of %v", opts.output, outputFormats)
+			}
+			if opts.phoneRegion != "" && !phonenumber.ValidRegion(opts.phoneRegion) {
+				return fmt.Errorf("unknown phone region %q, expected a region code such as US", opts.phoneRegion)
+			}
+			return nil
+		},
+		PersistentPostRunE: func(*cobra.Command, []string) error {
+			if opts.dbConn != nil {
+				return opts.dbConn.Close()
+			}
+			return nil
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&opts.dsn, "dsn", db.PostgresDSN, "Postgres connection string used when talking to the database directly")
+	cmd.PersistentFlags().StringVar(&opts.sqlitePath, "sqlite-path", "", "SQLite database file used instead of the Postgres database of --dsn; the file of the sqlite store for serve")
+	cmd.PersistentFlags().StringVar(&opts.server, "server", "", "base URL of a running instance, e.g. http://localhost:8080; when set the database is not used")
+	cmd.PersistentFlags().BoolVar(&opts.emailProviderRules, "email-provider-rules", false, "treat the address variants a mail provider delivers to one mailbox, such as Gmail's dots and plus tags, as one user; must match EMAIL_PROVIDER_RULES of the server")
+	cmd.PersistentFlags().StringVar(&opts.phoneRegion, "phone-region", "", "region code, such as US, of phone numbers given in national format; must match PHONE_DEFAULT_REGION of the server")
+	cmd.PersistentFlags().StringVarP(&opts.output, "output", "o", outputTable, "output format: table, json or yaml")
+	_ = cmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions(outputFormats, cobra.ShellCompDirectiveNoFileComp))
+
+	cmd.AddCommand(
+		newUsersCmd(opts),
+		newMigrateCmd(opts),
+		newServeCmd(opts),
+	)
+
+	return cmd
+}
+
+func (opts *rootOptions) database() (*sql.DB, error) {
+	if opts.dbConn == nil {
+		open, source := db.OpenPostgres, opts.dsn
+		if opts.sqlitePath != "" {
+			open, source = sqlitedb.Open, opts.sqlitePath
+		}
+		dbConn, err := open(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		opts.dbConn = dbConn
+	}
+	return opts.dbConn, nil
+}
+
+func (opts *rootOptions) backend(ctx context.Context) (userBackend, error) {
+	if opts.server != "" {
+		return newRemoteBackend(opts.server), nil
+	}
+	return opts.userStore(ctx)
+}
+
+// userStore returns the store of the database, keying addresses by
+// --email-provider-rules once they are checked to be the rules of the
+// database.
+func (opts *rootOptions) userStore(ctx context.Context) (directStore, error) {
+	dbConn, err := opts.database()
+	if err != nil {
+		return nil, err
+	}
+	phoneParser := phonenumber.Parser{DefaultRegion: opts.phoneRegion}
+
+	if opts.sqlitePath != "" {
+		if err := sqlitedb.CheckEmailRules(ctx, dbConn, opts.emailRules()); err != nil {
+			return nil, err
+		}
+		userStore := sqlite.NewUserStore(dbConn)
+		userStore.SetEmailRules(opts.emailRules())
+		userStore.SetPhoneParser(phoneParser)
+		return userStore, nil
+	}
+
+	if err := db.CheckEmailRules(ctx, dbConn, opts.emailRules()); err != nil {
+		return nil, err
+	}
+	userStore := store.NewUserStore(dbConn)
+	userStore.SetEmailRules(opts.emailRules())
+	userStore.SetPhoneParser(phoneParser)
+	return userStore, nil
+}
+
+func (opts *rootOptions) emailRules() emailaddr.Rules {
+	return emailaddr.Rules{Providers: opts.emailProviderRules}
+}
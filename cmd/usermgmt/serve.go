@@ -0,0 +1,116 @@
+package main
+
+import (
+	"example.com/user-management/internal/config"
+	"example.com/user-management/internal/server"
+	"github.com/spf13/cobra"
+)
+
+func newServeCmd(opts *rootOptions) *cobra.Command {
+	defaults := config.Default()
+	var flags config.Config
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Run the REST and gRPC servers",
+		Long: `Run the REST and gRPC servers.
+
+Settings are read from USER_STORE, DATABASE_URL, SQLITE_PATH, HTTP_ADDR and
+GRPC_ADDR; flags given on the command line take precedence.
+
+Spans are exported as set by TRACING_EXPORTER: none (the default), otlp,
+stdout or file, which appends to TRACING_FILE. The otlp exporter honours the
+standard OTEL_EXPORTER_OTLP_* variables.
+
+Logs are JSON lines on stdout at LOG_LEVEL (debug, info, warn or error) and
+above.
+
+Startup retries the database for up to DB_CONNECT_TIMEOUT. On SIGINT or
+SIGTERM the server fails /readyz for SHUTDOWN_DRAIN_DELAY, then lets
+in-flight requests finish for up to SHUTDOWN_TIMEOUT.
+
+Emails are written to files in MAIL_DIR unless MAIL_TRANSPORT is smtp, which
+relays through SMTP_ADDR using SMTP_USERNAME and SMTP_PASSWORD, sending from
+MAIL_FROM. Links in them point at PUBLIC_URL and are signed with
+TOKEN_SECRET; email verification links expire after EMAIL_VERIFICATION_TTL.
+
+Phone numbers are stored in E.164 format. Those in national format are read
+as numbers of PHONE_DEFAULT_REGION, such as US; without it only numbers in
+international format are accepted. Numbers stored before are normalized by
+"usermgmt users normalize-phones".
+
+Text messages are logged unless SMS_TRANSPORT is file, which appends them to
+SMS_FILE. Phone verification codes expire after PHONE_VERIFICATION_TTL, allow
+PHONE_VERIFICATION_MAX_ATTEMPTS guesses and are sent at most once per
+PHONE_VERIFICATION_RESEND_INTERVAL.
+
+Logins issue access tokens, signed with TOKEN_SECRET, that expire after
+ACCESS_TOKEN_TTL, and refresh tokens that keep the session alive for up to
+SESSION_TTL. Password reset links expire after PASSWORD_RESET_TTL.
+
+Users who enrol an authenticator app enter a code from it within
+MFA_CHALLENGE_TTL of their password, with at most MFA_MAX_ATTEMPTS tries. The
+app shows the account under MFA_ISSUER. Its secret is encrypted with
+MFA_ENCRYPTION_KEY, 32 bytes in base64, or a key derived from TOKEN_SECRET.
+
+Only the users listed by id in ADMIN_USER_IDS, separated by commas, may delete
+users, reset their MFA, change the attribute schema of their tenant or manage
+OpenID Connect clients.
+
+Avatar images are kept in the user store unless BLOB_STORE is file, which
+keeps them under BLOB_DIR.
+
+Identity providers provision users and groups over SCIM 2.0 at /scim/v2 with
+SCIM_TOKEN as their bearer token. SCIM is off unless SCIM_TOKEN is set.
+
+Registered clients sign users in with OpenID Connect, discovered at
+PUBLIC_URL/oidc. Its tokens are signed with keys that are replaced every
+OIDC_KEY_ROTATION.
+
+Email addresses differing only in case belong to one user. If
+EMAIL_PROVIDER_RULES is true, so do the variants a mail provider delivers to
+one mailbox, such as the dots and plus tags Gmail ignores. The database
+records the setting it was migrated with, and the server does not start with
+another; change it with usermgmt migrate rekey-emails.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+
+			if cmd.Flags().Changed("dsn") {
+				cfg.DSN = opts.dsn
+			}
+			if cmd.Flags().Changed("store") {
+				cfg.Store = flags.Store
+			}
+			if cmd.Flags().Changed("sqlite-path") {
+				cfg.SQLitePath = opts.sqlitePath
+			}
+			if cmd.Flags().Changed("http-addr") {
+				cfg.HTTPAddr = flags.HTTPAddr
+			}
+			if cmd.Flags().Changed("grpc-addr") {
+				cfg.GRPCAddr = flags.GRPCAddr
+			}
+			if cmd.Flags().Changed("email-provider-rules") {
+				cfg.EmailProviderRules = opts.emailProviderRules
+			}
+			if cmd.Flags().Changed("phone-region") {
+				cfg.PhoneDefaultRegion = opts.phoneRegion
+			}
+
+			return server.Run(cfg)
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.Store, "store", defaults.Store, "user store backend (postgres, sqlite or memory)")
+	cmd.Flags().StringVar(&flags.HTTPAddr, "http-addr", defaults.HTTPAddr, "address for the REST API")
+	cmd.Flags().StringVar(&flags.GRPCAddr, "grpc-addr", defaults.GRPCAddr, "address for the gRPC API")
+
+	storeValues := []string{config.StorePostgres, config.StoreSQLite, config.StoreMemory}
+	_ = cmd.RegisterFlagCompletionFunc("store", cobra.FixedCompletions(storeValues, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
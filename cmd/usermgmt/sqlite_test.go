@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store/sqlite"
+)
+
+func TestSQLiteDatabase_MigrateAndNormalizePhones(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.db")
+
+	if out, err := run(t, "", "--sqlite-path", path, "migrate", "up"); err != nil {
+		t.Fatalf("migrate up failed: %v: %s", err, out)
+	}
+
+	dbConn, err := sqlitedb.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := sqlite.NewUserStore(dbConn).CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"})
+	if err == nil {
+		// written as it is, the way numbers were stored before they were
+		// normalized
+		_, err = dbConn.ExecContext(t.Context(), "UPDATE users SET phone = '(234) 567-8901' WHERE user_id = ?", user.UserId)
+	}
+	_ = dbConn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := run(t, "", "--sqlite-path", path, "--phone-region", "US", "users", "normalize-phones")
+	if err != nil {
+		t.Fatalf("normalize-phones failed: %v: %s", err, out)
+	}
+	if !strings.Contains(out, "Normalized 1 phone numbers") {
+		t.Fatalf("unexpected normalize-phones output: %s", out)
+	}
+
+	out, err = run(t, "", "--sqlite-path", path, "-o", "json", "users", "get", user.UserId.String())
+	if err != nil {
+		t.Fatalf("users get failed: %v: %s", err, out)
+	}
+	if !strings.Contains(out, `"phone": "+12345678901"`) {
+		t.Fatalf("expected the number in E.164 format, got %s", out)
+	}
+}
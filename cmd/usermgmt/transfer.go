@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/mapper"
+	"example.com/user-management/internal/model"
+	"github.com/spf13/cobra"
+)
+
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+)
+
+var transferFormats = []string{formatJSON, formatCSV}
+
+var csvHeader = []string{"userId", "firstName", "lastName", "email", "phone", "age", "status"}
+
+func newUsersExportCmd(opts *rootOptions) *cobra.Command {
+	var format, file string
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write all users as JSON or CSV",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			backend, err := opts.backend(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			users, err := backend.GetAllUsers(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			w := cmd.OutOrStdout()
+			if file != "" {
+				f, err := os.Create(file)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+
+			return exportUsers(w, transferFormat(format, file), users)
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "", "json or csv (default: from the file extension, else json)")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "write to this file instead of stdout")
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(transferFormats, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
+
+func newUsersImportCmd(opts *rootOptions) *cobra.Command {
+	var format string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "import <file|->",
+		Short: "Create users from a JSON or CSV file",
+		Long: "Create users from a JSON array or a CSV file with a header row, as written by export.\n" +
+			"User ids in the input are ignored. Every row is validated and failures are reported without stopping the import.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var r io.Reader = cmd.InOrStdin()
+			if args[0] != "-" {
+				f, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				r = f
+			}
+
+			rows, err := readUsers(r, transferFormat(format, args[0]))
+			if err != nil {
+				return err
+			}
+
+			var backend userBackend
+			if !dryRun {
+				backend, err = opts.backend(cmd.Context())
+				if err != nil {
+					return err
+				}
+			}
+
+			imported, failed := 0, 0
+			for i, row := range rows {
+				req := dto.CreateUserRequest{
+					FirstName: row.FirstName,
+					LastName:  row.LastName,
+					Email:     row.Email,
+					Phone:     row.Phone,
+					Age:       row.Age,
+					Status:    row.Status,
+				}
+
+				err := validate.Struct(req)
+				if err == nil && !dryRun {
+					_, err = backend.CreateUser(cmd.Context(), mapper.CreateUserRequestToModel(req))
+				}
+				if err != nil {
+					failed++
+					fmt.Fprintf(cmd.ErrOrStderr(), "row %d (%s): %v\n", i+1, row.Email, err)
+					continue
+				}
+				imported++
+			}
+
+			verb := "Imported"
+			if dryRun {
+				verb = "Validated"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %d users, %d failed\n", verb, imported, failed)
+
+			if failed > 0 {
+				return fmt.Errorf("%d users could not be imported", failed)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "", "json or csv (default: from the file extension, else json)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "validate the input without creating users")
+	_ = cmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions(transferFormats, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
+
+func transferFormat(format, file string) string {
+	if format != "" {
+		return format
+	}
+	if strings.EqualFold(filepath.Ext(file), ".csv") {
+		return formatCSV
+	}
+	return formatJSON
+}
+
+func exportUsers(w io.Writer, format string, users []model.User) error {
+	switch format {
+	case formatJSON:
+		views := make([]userView, len(users))
+		for i, u := range users {
+			views[i] = toUserView(u)
+		}
+		return writeJSON(w, views)
+	case formatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvHeader); err != nil {
+			return err
+		}
+		for _, u := range users {
+			v := toUserView(u)
+			age := ""
+			if v.Age > 0 {
+				age = strconv.Itoa(v.Age)
+			}
+			if err := cw.Write([]string{v.UserId, v.FirstName, v.LastName, v.Email, v.Phone, age, string(v.Status)}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+func readUsers(r io.Reader, format string) ([]userView, error) {
+	switch format {
+	case formatJSON:
+		var rows []userView
+		if err := json.NewDecoder(r).Decode(&rows); err != nil {
+			return nil, fmt.Errorf("invalid JSON input: %w", err)
+		}
+		return rows, nil
+	case formatCSV:
+		return readCSVUsers(r)
+	}
+	return nil, fmt.Errorf("unknown format %q", format)
+}
+
+func readCSVUsers(r io.Reader) ([]userView, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV input: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("CSV input has no header row")
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.TrimSpace(name)] = i
+	}
+
+	value := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	rows := make([]userView, 0, len(records)-1)
+	for n, record := range records[1:] {
+		row := userView{
+			FirstName: value(record, "firstName"),
+			LastName:  value(record, "lastName"),
+			Email:     value(record, "email"),
+			Phone:     value(record, "phone"),
+			Status:    model.Status(value(record, "status")),
+		}
+		if age := value(record, "age"); age != "" {
+			row.Age, err = strconv.Atoi(age)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid age %q", n+1, age)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
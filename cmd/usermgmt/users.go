@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/mapper"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/tenant"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+var validate = validator.New()
+
+var statusValues = []string{string(model.StatusActive), string(model.StatusInactive)}
+
+func newUsersCmd(opts *rootOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "users",
+		Short: "List, inspect and change users",
+	}
+
+	cmd.AddCommand(
+		newUsersListCmd(opts),
+		newUsersGetCmd(opts),
+		newUsersCreateCmd(opts),
+		newUsersUpdateCmd(opts),
+		newUsersDeleteCmd(opts),
+		newUsersImportCmd(opts),
+		newUsersExportCmd(opts),
+		newUsersNormalizePhonesCmd(opts),
+	)
+
+	return cmd
+}
+
+func newUsersListCmd(opts *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List all users",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			backend, err := opts.backend(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			users, err := backend.GetAllUsers(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			return printUsers(cmd.OutOrStdout(), opts.output, users)
+		},
+	}
+}
+
+func newUsersGetCmd(opts *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <id>",
+		Short: "Show a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userId, err := uuid.Parse(args[0])
+			if err != nil {
+				return errors.New("invalid user id")
+			}
+
+			backend, err := opts.backend(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			user, ok, err := backend.GetUserById(cmd.Context(), userId)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("user %s not found", userId)
+			}
+
+			return printUser(cmd.OutOrStdout(), opts.output, user)
+		},
+	}
+}
+
+func newUsersCreateCmd(opts *rootOptions) *cobra.Command {
+	var req dto.CreateUserRequest
+	var status string
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Create a user",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			req.Status = model.Status(status)
+			if err := validate.Struct(req); err != nil {
+				return err
+			}
+
+			backend, err := opts.backend(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			createdUser, err := backend.CreateUser(cmd.Context(), mapper.CreateUserRequestToModel(req))
+			if err != nil {
+				return err
+			}
+
+			return printUser(cmd.OutOrStdout(), opts.output, createdUser)
+		},
+	}
+
+	cmd.Flags().StringVar(&req.FirstName, "first-name", "", "first name")
+	cmd.Flags().StringVar(&req.LastName, "last-name", "", "last name")
+	cmd.Flags().StringVar(&req.Email, "email", "", "email address")
+	cmd.Flags().StringVar(&req.Phone, "phone", "", "phone number, in international format or the national format of --phone-region")
+	cmd.Flags().IntVar(&req.Age, "age", 0, "age")
+	cmd.Flags().StringVar(&status, "status", "", "Active or Inactive (default Active)")
+	for _, name := range []string{"first-name", "last-name", "email", "phone"} {
+		_ = cmd.MarkFlagRequired(name)
+	}
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions(statusValues, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
+
+func newUsersUpdateCmd(opts *rootOptions) *cobra.Command {
+	var firstName, lastName, email, phone, status string
+	var age int
+
+	cmd := &cobra.Command{
+		Use:   "update <id>",
+		Short: "Change the given fields of a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userId, err := uuid.Parse(args[0])
+			if err != nil {
+				return errors.New("invalid user id")
+			}
+
+			// only flags given on the command line are applied, like a PATCH
+			var req dto.UpdateUserRequest
+			flags := cmd.Flags()
+			if flags.Changed("first-name") {
+				req.FirstName = &firstName
+			}
+			if flags.Changed("last-name") {
+				req.LastName = &lastName
+			}
+			if flags.Changed("email") {
+				req.Email = &email
+			}
+			if flags.Changed("phone") {
+				req.Phone = &phone
+			}
+			if flags.Changed("age") {
+				req.Age = &age
+			}
+			if flags.Changed("status") {
+				s := model.Status(status)
+				req.Status = &s
+			}
+
+			if err := validate.Struct(req); err != nil {
+				return err
+			}
+
+			backend, err := opts.backend(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			user, ok, err := backend.GetUserById(cmd.Context(), userId)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("user %s not found", userId)
+			}
+
+			mapper.ApplyUpdateUserRequest(&user, req)
+			updatedUser, ok, err := backend.UpdateUser(cmd.Context(), user, userId)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("user %s not found", userId)
+			}
+
+			return printUser(cmd.OutOrStdout(), opts.output, updatedUser)
+		},
+	}
+
+	cmd.Flags().StringVar(&firstName, "first-name", "", "first name")
+	cmd.Flags().StringVar(&lastName, "last-name", "", "last name")
+	cmd.Flags().StringVar(&email, "email", "", "email address")
+	cmd.Flags().StringVar(&phone, "phone", "", "phone number, in international format or the national format of --phone-region")
+	cmd.Flags().IntVar(&age, "age", 0, "age")
+	cmd.Flags().StringVar(&status, "status", "", "Active or Inactive")
+	_ = cmd.RegisterFlagCompletionFunc("status", cobra.FixedCompletions(statusValues, cobra.ShellCompDirectiveNoFileComp))
+
+	return cmd
+}
+
+func newUsersDeleteCmd(opts *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <id>",
+		Short: "Delete a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userId, err := uuid.Parse(args[0])
+			if err != nil {
+				return errors.New("invalid user id")
+			}
+
+			backend, err := opts.backend(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			ok, err := backend.DeleteUser(cmd.Context(), userId)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("user %s not found", userId)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "User %s deleted\n", userId)
+			return nil
+		},
+	}
+}
+
+func newUsersNormalizePhonesCmd(opts *rootOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "normalize-phones",
+		Short: "Rewrite stored phone numbers in E.164 format",
+		Long: `Rewrite the phone numbers of the users of every tenant in E.164 format.
+
+Numbers are only normalized when users are created or changed, so this is
+run once to normalize those stored before. National numbers are read in
+--phone-region, which must match PHONE_DEFAULT_REGION of the server.
+Numbers that cannot be read are listed and left as they are, to be
+corrected by hand. It works on the Postgres database of --dsn, or the SQLite
+database of --sqlite-path.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if opts.server != "" {
+				return errors.New("normalize-phones talks to the database directly and cannot be used with --server")
+			}
+			userStore, err := opts.userStore(cmd.Context())
+			if err != nil {
+				return err
+			}
+			backfill, err := userStore.NormalizePhones(tenant.WithAllTenants(cmd.Context()))
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Normalized %d phone numbers\n", backfill.Normalized)
+			if len(backfill.Unparseable) == 0 {
+				return nil
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Could not parse %d phone numbers:\n", len(backfill.Unparseable))
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tTENANT\tPHONE\tERROR")
+			for _, unparseable := range backfill.Unparseable {
+				user := unparseable.User
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%v\n", user.UserId, user.TenantId, user.Phone, unparseable.Err)
+			}
+			return tw.Flush()
+		},
+	}
+}
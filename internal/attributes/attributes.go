@@ -0,0 +1,183 @@
+// Package attributes validates the custom attributes of users, such as an
+// employee number or a department, against the JSON Schema of their tenant.
+package attributes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+var (
+	// ErrInvalidSchema is returned for attribute schemas that are not a JSON
+	// Schema this package can compile.
+	ErrInvalidSchema = errors.New("invalid attribute schema")
+	// ErrInvalid is returned for attributes that do not satisfy the schema
+	// or are larger than MaxSize.
+	ErrInvalid = errors.New("invalid attributes")
+)
+
+// MaxSize is the most bytes of JSON the attributes of a user may take. Change
+// events carry the whole user, and Postgres notifications hold under 8000
+// bytes.
+const MaxSize = 4096
+
+// schemaURL names the schema in compile errors; nothing is loaded from it.
+const schemaURL = "urn:user-management:attributes"
+
+// compiled keeps recently used schemas by their text, since every write of a
+// user reads the schema of its tenant.
+var compiled, _ = lru.New[string, *Schema](64)
+
+// Schema is a compiled attribute schema.
+type Schema struct {
+	schema *jsonschema.Schema
+	// types holds the JSON type of each top-level property that names one,
+	// for reading filter values
+	types map[string]string
+}
+
+// Parse compiles an attribute schema. Schemas cannot refer to other documents.
+func Parse(schema []byte) (*Schema, error) {
+	if cached, ok := compiled.Get(string(schema)); ok {
+		return cached, nil
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	// the default loader reads files, which an admin's $ref must not reach
+	compiler.UseLoader(jsonschema.SchemeURLLoader{})
+	if err := compiler.AddResource(schemaURL, doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	compiledSchema, err := compiler.Compile(schemaURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+
+	parsed := &Schema{
+		schema: compiledSchema,
+		types:  propertyTypes(doc),
+	}
+	compiled.Add(string(schema), parsed)
+	return parsed, nil
+}
+
+// Validate checks attrs against the schema and MaxSize. A nil schema accepts
+// any attributes that fit.
+func (schema *Schema) Validate(attrs map[string]any) error {
+	encoded, err := Encode(attrs)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if len(encoded) > MaxSize {
+		return fmt.Errorf("%w: larger than %d bytes", ErrInvalid, MaxSize)
+	}
+	if schema == nil {
+		return nil
+	}
+	// numbers are checked as written rather than as float64
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(encoded))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+
+	err = schema.schema.Validate(doc)
+	var validationErr *jsonschema.ValidationError
+	if errors.As(err, &validationErr) {
+		return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(describe(validationErr), "; "))
+	}
+	return err
+}
+
+// Filter reads the values of an attribute filter, which arrive as strings, as
+// the type the schema gives each attribute, so that ?attr.level=3 matches the
+// number 3. Values of other attributes, or that do not parse, stay strings. A
+// nil schema leaves every value a string.
+func (schema *Schema) Filter(values map[string]string) map[string]any {
+	filter := make(map[string]any, len(values))
+	for name, value := range values {
+		filter[name] = value
+
+		var typ string
+		if schema != nil {
+			typ = schema.types[name]
+		}
+		switch typ {
+		case "integer", "number":
+			if number, err := strconv.ParseFloat(value, 64); err == nil {
+				filter[name] = number
+			}
+		case "boolean":
+			if boolean, err := strconv.ParseBool(value); err == nil {
+				filter[name] = boolean
+			}
+		}
+	}
+	return filter
+}
+
+// Encode returns attrs as a JSON object; nil attributes are an empty one.
+func Encode(attrs map[string]any) ([]byte, error) {
+	if attrs == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(attrs)
+}
+
+// Decode reads attributes written by Encode.
+func Decode(data []byte) (map[string]any, error) {
+	attrs := map[string]any{}
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+	return attrs, nil
+}
+
+// describe lists the failures under err, one per violated keyword.
+func describe(err *jsonschema.ValidationError) []string {
+	if len(err.Causes) == 0 {
+		return []string{err.Error()}
+	}
+	var failures []string
+	for _, cause := range err.Causes {
+		failures = append(failures, describe(cause)...)
+	}
+	return failures
+}
+
+// propertyTypes returns the type of each top-level property of doc that names
+// a single one besides null.
+func propertyTypes(doc any) map[string]string {
+	types := map[string]string{}
+	object, _ := doc.(map[string]any)
+	properties, _ := object["properties"].(map[string]any)
+	for name, property := range properties {
+		property, _ := property.(map[string]any)
+		switch typ := property["type"].(type) {
+		case string:
+			types[name] = typ
+		case []any:
+			var named []string
+			for _, t := range typ {
+				if t, ok := t.(string); ok && t != "null" {
+					named = append(named, t)
+				}
+			}
+			if len(named) == 1 {
+				types[name] = named[0]
+			}
+		}
+	}
+	return types
+}
@@ -0,0 +1,147 @@
+package attributes
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const departmentSchema = `{
+	"type": "object",
+	"properties": {
+		"department": {"type": "string", "enum": ["eng", "sales"]},
+		"employeeNumber": {"type": "integer", "minimum": 1},
+		"remote": {"type": ["boolean", "null"]}
+	},
+	"required": ["department"],
+	"additionalProperties": false
+}`
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"not json", `{"type":`},
+		{"unknown type", `{"type": "thing"}`},
+		{"file reference", `{"$ref": "file:///etc/passwd"}`},
+		{"remote reference", `{"$ref": "https://example.com/schema.json"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse([]byte(test.schema)); !errors.Is(err, ErrInvalidSchema) {
+				t.Errorf("Expected ErrInvalidSchema, got %v", err)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := Parse([]byte(departmentSchema))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		attrs    map[string]any
+		expected string
+	}{
+		{"valid", map[string]any{"department": "eng", "employeeNumber": 42, "remote": true}, ""},
+		{"null allowed", map[string]any{"department": "eng", "remote": nil}, ""},
+		{"missing required", nil, "department"},
+		{"not in enum", map[string]any{"department": "legal"}, "/department"},
+		{"wrong type", map[string]any{"department": "eng", "employeeNumber": "42"}, "/employeeNumber"},
+		{"not an integer", map[string]any{"department": "eng", "employeeNumber": 4.2}, "/employeeNumber"},
+		{"unknown attribute", map[string]any{"department": "eng", "shoeSize": 9}, "shoeSize"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := schema.Validate(test.attrs)
+			if test.expected == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("Expected ErrInvalid, got %v", err)
+			}
+			if !strings.Contains(err.Error(), test.expected) {
+				t.Errorf("Expected the error to mention %q, got %q", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidate_NilSchema(t *testing.T) {
+	var schema *Schema
+	if err := schema.Validate(map[string]any{"anything": []any{1, "two"}}); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+}
+
+func TestValidate_TooLarge(t *testing.T) {
+	var schema *Schema
+	attrs := map[string]any{"notes": strings.Repeat("x", MaxSize)}
+	if err := schema.Validate(attrs); !errors.Is(err, ErrInvalid) {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	schema, err := Parse([]byte(departmentSchema))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	filter := schema.Filter(map[string]string{
+		"department":     "eng",
+		"employeeNumber": "42",
+		"remote":         "true",
+		"unknown":        "7",
+	})
+	expected := map[string]any{
+		"department":     "eng",
+		"employeeNumber": 42.0,
+		"remote":         true,
+		"unknown":        "7",
+	}
+	if !reflect.DeepEqual(filter, expected) {
+		t.Errorf("Expected %v, got %v", expected, filter)
+	}
+
+	// a value that does not parse as its type matches nothing rather than
+	// failing
+	if filter := schema.Filter(map[string]string{"employeeNumber": "many"}); filter["employeeNumber"] != "many" {
+		t.Errorf("Expected the value to stay a string, got %v", filter["employeeNumber"])
+	}
+
+	var none *Schema
+	if filter := none.Filter(map[string]string{"level": "3"}); filter["level"] != "3" {
+		t.Errorf("Expected strings without a schema, got %v", filter["level"])
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	encoded, err := Encode(nil)
+	if err != nil || string(encoded) != "{}" {
+		t.Fatalf("Expected an empty object, got %s, %v", encoded, err)
+	}
+
+	attrs := map[string]any{"department": "eng", "employeeNumber": 42.0}
+	encoded, err = Encode(attrs)
+	if err != nil {
+		t.Fatalf("Encode failed: %v", err)
+	}
+	decoded, err := Decode(encoded)
+	if err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, attrs) {
+		t.Errorf("Expected %v, got %v", attrs, decoded)
+	}
+}
@@ -0,0 +1,599 @@
+// Package auth signs users in with their password and, if they have enrolled
+// one, an authenticator app. It keeps their sessions and lets them reset a
+// forgotten password by email. Each step is written to the audit trail.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/mail"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tenant"
+	"example.com/user-management/internal/token"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	resetPurpose       = "password-reset"
+	accessTokenPurpose = "access-token"
+
+	// amrPassword and amrMFA are the authentication methods, as RFC 8176
+	// names them, an access token lists for a login with a password alone
+	// and for one with a second factor.
+	amrPassword = "pwd"
+	amrMFA      = "mfa"
+
+	// resetMailTimeout bounds storing and sending a password reset link,
+	// which outlive the request that asked for it.
+	resetMailTimeout = time.Minute
+)
+
+var (
+	// ErrInvalidCredentials is returned for any failed login, so that
+	// callers cannot tell unknown emails from wrong passwords.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrUserNotFound       = errors.New("user not found")
+	// ErrAdminRequired is returned by AuthorizeAdmin for requests without
+	// the access token of an admin.
+	ErrAdminRequired = errors.New("admin access required")
+	// ErrAdminMFARequired is returned by AuthorizeAdmin for admins who did
+	// not log in with a second factor, or no longer have one.
+	ErrAdminMFARequired = errors.New("admin access requires logging in with a second factor")
+)
+
+// Store is what Authenticator needs from the store: the stores that keep
+// credentials are also user stores.
+type Store interface {
+	store.UserStoreInterface
+	store.AuthStoreInterface
+	store.MFAStoreInterface
+	store.AuditStoreInterface
+}
+
+type Options struct {
+	// AccessTokenTTL is how long an access token is accepted.
+	AccessTokenTTL time.Duration
+	// SessionTTL is how long a session can be refreshed after login.
+	SessionTTL time.Duration
+	// ResetTTL is how long a password reset link stays valid.
+	ResetTTL time.Duration
+	// ResetURL is the public address of the page that collects the new
+	// password. The token is added as the token query parameter.
+	ResetURL string
+	// MFAIssuer names this service in authenticator apps.
+	MFAIssuer string
+	// MFAKey is the 32 byte AES key TOTP secrets are encrypted with. If
+	// empty, a key is derived from the token secret.
+	MFAKey []byte
+	// MFAChallengeTTL is how long a user has to enter their second factor
+	// after their password.
+	MFAChallengeTTL time.Duration
+	// MFAMaxAttempts is how many codes can be tried per login.
+	MFAMaxAttempts int
+	// Admins are the users who may manage the accounts of others.
+	Admins []uuid.UUID
+}
+
+// Tokens are handed to a client when it logs in or refreshes its session.
+type Tokens struct {
+	// AccessToken is a JWT naming the user and the session.
+	AccessToken string
+	// RefreshToken can be exchanged once for new tokens.
+	RefreshToken string
+	// MFAToken is set instead of the other tokens when the user has to
+	// enter a second factor to finish logging in.
+	MFAToken  string
+	ExpiresIn time.Duration
+}
+
+// Claims are what an access token says about its bearer.
+type Claims struct {
+	UserId    uuid.UUID
+	SessionId uuid.UUID
+	// TenantId is the tenant the bearer logged in to.
+	TenantId uuid.UUID
+	// MFA is whether the bearer logged in with a second factor.
+	MFA bool
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a copy of ctx carrying the claims of the access token a
+// request was made with.
+func WithClaims(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims stored by WithClaims, if any.
+func ClaimsFromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
+
+type accessClaims struct {
+	SessionId string `json:"sid"`
+	// TenantId is left out for the default tenant.
+	TenantId string `json:"tid,omitempty"`
+	// AMR lists how the bearer logged in.
+	AMR []string `json:"amr,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// dummyHash is checked against when a login has no password to check, so
+// that it takes as long as one that does.
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("not the password of anyone")
+	return hash
+})
+
+// Authenticator checks passwords and issues the tokens that prove a user
+// logged in.
+type Authenticator struct {
+	store  Store
+	sender mail.Sender
+	signer *token.Signer
+	opts   Options
+	now    func() time.Time
+	// mails tracks the password reset emails being sent.
+	mails sync.WaitGroup
+}
+
+func NewAuthenticator(authStore Store, sender mail.Sender, signer *token.Signer, opts Options) *Authenticator {
+	return &Authenticator{
+		store:  authStore,
+		sender: sender,
+		signer: signer,
+		opts:   opts,
+		now:    time.Now,
+	}
+}
+
+// Login starts a session for the user with email if password is theirs. If
+// they have a second factor, it returns an MFA token for LoginMFA instead.
+func (authenticator *Authenticator) Login(ctx context.Context, email, password string) (Tokens, error) {
+	user, ok, err := authenticator.findUser(ctx, email)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if !ok {
+		_, _ = CheckPassword(dummyHash(), password)
+		authenticator.loginFailed(ctx, nil, "unknown email")
+		return Tokens{}, ErrInvalidCredentials
+	}
+
+	hash, ok, err := authenticator.store.GetPasswordHash(ctx, user.UserId)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if !ok {
+		_, _ = CheckPassword(dummyHash(), password)
+		authenticator.loginFailed(ctx, &user.UserId, "no password")
+		return Tokens{}, ErrInvalidCredentials
+	}
+
+	match, err := CheckPassword(hash, password)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if !match {
+		authenticator.loginFailed(ctx, &user.UserId, "wrong password")
+		return Tokens{}, ErrInvalidCredentials
+	}
+	if user.Status != model.StatusActive {
+		authenticator.loginFailed(ctx, &user.UserId, "inactive")
+		return Tokens{}, ErrInvalidCredentials
+	}
+
+	mfa, err := authenticator.mfaEnabled(ctx, user.UserId)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if mfa {
+		return authenticator.challenge(ctx, user.UserId)
+	}
+	return authenticator.startSession(ctx, user.UserId, "password")
+}
+
+// startSession issues tokens for a new session of the user, who proved who
+// they are with the given factor: their password, or a second factor after
+// it.
+func (authenticator *Authenticator) startSession(ctx context.Context, userId uuid.UUID, factor string) (Tokens, error) {
+	now := authenticator.now().UTC()
+	refreshToken, refreshTokenHash := newRefreshToken()
+	session := model.Session{
+		SessionId:        uuid.New(),
+		UserId:           userId,
+		RefreshTokenHash: refreshTokenHash,
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(authenticator.opts.SessionTTL),
+		MFA:              factor != "password",
+	}
+	if err := authenticator.store.CreateSession(ctx, session); err != nil {
+		return Tokens{}, fmt.Errorf("failed to store session: %w", err)
+	}
+
+	authenticator.audit(ctx, model.AuditEvent{
+		UserId: &userId,
+		Action: model.AuditLoginSucceeded,
+		Detail: map[string]string{
+			"sessionId": session.SessionId.String(),
+			"factor":    factor,
+		},
+	})
+	return authenticator.issue(ctx, session, refreshToken)
+}
+
+// Refresh exchanges a refresh token for new tokens. Besides the errors of
+// store.RefreshSession it returns store.ErrSessionNotFound for malformed
+// tokens. The session is looked up in the tenant of ctx, like a login.
+func (authenticator *Authenticator) Refresh(ctx context.Context, refreshToken string) (Tokens, error) {
+	refreshTokenHash, err := hashRefreshToken(refreshToken)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	newRefreshToken, newRefreshTokenHash := newRefreshToken()
+	session, err := authenticator.store.RefreshSession(ctx, refreshTokenHash, newRefreshTokenHash, authenticator.now().UTC())
+	if err != nil {
+		return Tokens{}, err
+	}
+	return authenticator.issue(ctx, session, newRefreshToken)
+}
+
+// Logout ends the session the refresh token belongs to, along with the access
+// tokens issued for it.
+func (authenticator *Authenticator) Logout(ctx context.Context, refreshToken string) error {
+	refreshTokenHash, err := hashRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+
+	session, err := authenticator.store.RevokeSession(ctx, refreshTokenHash, authenticator.now().UTC())
+	if err != nil {
+		return err
+	}
+
+	authenticator.audit(ctx, model.AuditEvent{
+		UserId: &session.UserId,
+		Action: model.AuditLogout,
+		Detail: map[string]string{"sessionId": session.SessionId.String()},
+	})
+	return nil
+}
+
+// ParseAccessToken returns the claims of an access token issued by this
+// authenticator, if it has not expired and its session has not ended. Tokens
+// of sessions ended by logging out or resetting the password are turned away
+// at once rather than when they expire. Errors other than token.ErrInvalid
+// are failures to look the session up.
+func (authenticator *Authenticator) ParseAccessToken(ctx context.Context, accessToken string) (Claims, error) {
+	claims, err := authenticator.parseAccessToken(accessToken)
+	if err != nil {
+		return Claims{}, err
+	}
+
+	// the session is in the tenant the bearer logged in to
+	session, ok, err := authenticator.store.GetSession(tenant.WithID(ctx, claims.TenantId), claims.SessionId)
+	if err != nil {
+		return Claims{}, err
+	}
+	if !ok || session.UserId != claims.UserId {
+		return Claims{}, fmt.Errorf("%w: session has ended", token.ErrInvalid)
+	}
+	if err := store.CheckSession(session, authenticator.now().UTC()); err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", token.ErrInvalid, err)
+	}
+	return claims, nil
+}
+
+// AccessTokenTenant returns the tenant an access token was issued in. Unlike
+// ParseAccessToken it only checks the signature and expiry, enough to route a
+// request whose token is checked in full later.
+func (authenticator *Authenticator) AccessTokenTenant(accessToken string) (uuid.UUID, error) {
+	claims, err := authenticator.parseAccessToken(accessToken)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return claims.TenantId, nil
+}
+
+// parseAccessToken returns the claims of an access token signed by this
+// authenticator that has not expired, leaving its session unchecked.
+func (authenticator *Authenticator) parseAccessToken(accessToken string) (Claims, error) {
+	var claims accessClaims
+	_, err := jwt.ParseWithClaims(accessToken, &claims,
+		func(*jwt.Token) (any, error) {
+			return authenticator.accessTokenKey(), nil
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(authenticator.now),
+	)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %w", token.ErrInvalid, err)
+	}
+
+	userId, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return Claims{}, token.ErrInvalid
+	}
+	sessionId, err := uuid.Parse(claims.SessionId)
+	if err != nil {
+		return Claims{}, token.ErrInvalid
+	}
+	tenantId := tenant.Default
+	if claims.TenantId != "" {
+		tenantId, err = uuid.Parse(claims.TenantId)
+		if err != nil {
+			return Claims{}, token.ErrInvalid
+		}
+	}
+	return Claims{
+		UserId:    userId,
+		SessionId: sessionId,
+		TenantId:  tenantId,
+		MFA:       slices.Contains(claims.AMR, amrMFA),
+	}, nil
+}
+
+// ForgotPassword mails the user with email a link to reset their password.
+// Unknown emails are not an error, so that callers cannot probe for
+// accounts. For the same reason the link is stored and sent in the
+// background, where failures are logged; only failures to look the user up
+// are returned.
+func (authenticator *Authenticator) ForgotPassword(ctx context.Context, email string) error {
+	user, ok, err := authenticator.findUser(ctx, email)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		authenticator.audit(ctx, model.AuditEvent{
+			Action: model.AuditPasswordResetRequested,
+			Detail: map[string]string{"reason": "unknown email"},
+		})
+		return nil
+	}
+
+	authenticator.audit(ctx, model.AuditEvent{
+		UserId: &user.UserId,
+		Action: model.AuditPasswordResetRequested,
+	})
+
+	// the tenant and logger of the request carry over, its deadline does not
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), resetMailTimeout)
+	authenticator.mails.Go(func() {
+		defer cancel()
+
+		if err := authenticator.sendReset(ctx, user); err != nil {
+			logging.FromContext(ctx).Error("failed to send password reset", logging.Err(err))
+			authenticator.audit(ctx, model.AuditEvent{
+				UserId: &user.UserId,
+				Action: model.AuditPasswordResetEmailFailed,
+			})
+			return
+		}
+
+		authenticator.audit(ctx, model.AuditEvent{
+			UserId: &user.UserId,
+			Action: model.AuditPasswordResetEmailSent,
+		})
+	})
+	return nil
+}
+
+// Wait blocks until the password reset emails being sent have been sent or
+// have failed.
+func (authenticator *Authenticator) Wait() {
+	authenticator.mails.Wait()
+}
+
+// ResetPassword sets the password of the user the token was mailed to and
+// ends all their sessions, turning away their access tokens too. Besides
+// token.ErrInvalid and errors wrapping ErrWeakPassword it returns the store's
+// password reset errors.
+func (authenticator *Authenticator) ResetPassword(ctx context.Context, tokenString, password string) error {
+	tenantId, tokenId, err := authenticator.signer.Verify(resetPurpose, tokenString)
+	if err != nil {
+		return authenticator.resetFailed(ctx, nil, err)
+	}
+	// the page the link leads to need not know the tenant, so the token does
+	ctx = tenant.WithID(ctx, tenantId)
+
+	now := authenticator.now().UTC()
+	reset, ok, err := authenticator.store.GetPasswordReset(ctx, tokenId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return authenticator.resetFailed(ctx, nil, store.ErrResetNotFound)
+	}
+	if err := store.CheckPasswordReset(reset, now); err != nil {
+		return authenticator.resetFailed(ctx, &reset.UserId, err)
+	}
+
+	user, ok, err := authenticator.store.GetUserById(ctx, reset.UserId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// deleted users take their resets with them
+		return authenticator.resetFailed(ctx, nil, store.ErrResetNotFound)
+	}
+	if err := CheckPasswordPolicy(password, user); err != nil {
+		return authenticator.resetFailed(ctx, &user.UserId, err)
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	err = authenticator.store.ResetPassword(ctx, tokenId, hash, now)
+	if errors.Is(err, store.ErrResetNotFound) || errors.Is(err, store.ErrResetUsed) || errors.Is(err, store.ErrResetExpired) {
+		return authenticator.resetFailed(ctx, &user.UserId, err)
+	}
+	return err
+}
+
+func (authenticator *Authenticator) sendReset(ctx context.Context, user model.User) error {
+	reset := model.PasswordReset{
+		TokenId:   uuid.New(),
+		UserId:    user.UserId,
+		ExpiresAt: authenticator.now().UTC().Add(authenticator.opts.ResetTTL),
+	}
+	if err := authenticator.store.CreatePasswordReset(ctx, reset); err != nil {
+		return fmt.Errorf("failed to store password reset: %w", err)
+	}
+
+	link, err := url.Parse(authenticator.opts.ResetURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", authenticator.signer.Sign(resetPurpose, tenant.FromContext(ctx), reset.TokenId))
+	link.RawQuery = query.Encode()
+
+	msg, err := mail.NewMessage(user.Email, "reset_password", map[string]any{
+		"FirstName": user.FirstName,
+		"Email":     user.Email,
+		"Link":      link.String(),
+		"ExpiresIn": authenticator.opts.ResetTTL.String(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := authenticator.sender.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+	return nil
+}
+
+// IsAdmin reports whether the user may manage the accounts of others.
+func (authenticator *Authenticator) IsAdmin(userId uuid.UUID) bool {
+	return slices.Contains(authenticator.opts.Admins, userId)
+}
+
+// AuthorizeAdmin returns nil if the claims in ctx, stored by WithClaims,
+// belong to an admin who logged in with a second factor and still has it.
+// Admins can delete users, so their password alone is not enough. Otherwise
+// it returns ErrAdminRequired, ErrAdminMFARequired or the store's error.
+func (authenticator *Authenticator) AuthorizeAdmin(ctx context.Context) error {
+	claims, ok := ClaimsFromContext(ctx)
+	if !ok || !authenticator.IsAdmin(claims.UserId) {
+		return ErrAdminRequired
+	}
+	if !claims.MFA {
+		return ErrAdminMFARequired
+	}
+
+	// the factor may have been reset since the login
+	mfa, err := authenticator.mfaEnabled(tenant.WithID(ctx, claims.TenantId), claims.UserId)
+	if err != nil {
+		return err
+	}
+	if !mfa {
+		return ErrAdminMFARequired
+	}
+	return nil
+}
+
+// findUser returns the user with email, if there is one.
+func (authenticator *Authenticator) findUser(ctx context.Context, email string) (model.User, bool, error) {
+	users, err := authenticator.store.ListUsers(ctx, model.UserFilter{Email: email}, uuid.Nil, 1)
+	if err != nil || len(users) == 0 {
+		return model.User{}, false, err
+	}
+	return users[0], true, nil
+}
+
+func (authenticator *Authenticator) issue(ctx context.Context, session model.Session, refreshToken string) (Tokens, error) {
+	now := authenticator.now()
+	claims := accessClaims{
+		SessionId: session.SessionId.String(),
+		AMR:       []string{amrPassword},
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   session.UserId.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(authenticator.opts.AccessTokenTTL)),
+		},
+	}
+	if tenantId := tenant.FromContext(ctx); tenantId != tenant.Default {
+		claims.TenantId = tenantId.String()
+	}
+	if session.MFA {
+		claims.AMR = append(claims.AMR, amrMFA)
+	}
+	accessToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(authenticator.accessTokenKey())
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	return Tokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    authenticator.opts.AccessTokenTTL,
+	}, nil
+}
+
+// accessTokenKey derives the key access tokens are signed with from the
+// token secret, so that no separate secret has to be configured.
+func (authenticator *Authenticator) accessTokenKey() []byte {
+	return authenticator.signer.Digest(accessTokenPurpose)
+}
+
+func (authenticator *Authenticator) loginFailed(ctx context.Context, userId *uuid.UUID, reason string) {
+	authenticator.audit(ctx, model.AuditEvent{
+		UserId: userId,
+		Action: model.AuditLoginFailed,
+		Detail: map[string]string{"reason": reason},
+	})
+}
+
+// resetFailed records why a password reset was refused and returns err.
+func (authenticator *Authenticator) resetFailed(ctx context.Context, userId *uuid.UUID, err error) error {
+	authenticator.audit(ctx, model.AuditEvent{
+		UserId: userId,
+		Action: model.AuditPasswordResetFailed,
+		Detail: map[string]string{"reason": err.Error()},
+	})
+	return err
+}
+
+// audit records event, logging rather than returning failures: the step it
+// describes has already happened.
+func (authenticator *Authenticator) audit(ctx context.Context, event model.AuditEvent) {
+	if err := authenticator.store.RecordAuditEvent(ctx, event); err != nil {
+		slog.ErrorContext(ctx, "failed to record audit event", slog.String("action", string(event.Action)), logging.Err(err))
+	}
+}
+
+// newRefreshToken returns a random refresh token and the hash it is stored
+// as. The token is long enough that a plain hash cannot be reversed.
+func newRefreshToken() (string, []byte) {
+	raw := make([]byte, 32)
+	_, _ = rand.Read(raw)
+	hash := sha256.Sum256(raw)
+	return base64.RawURLEncoding.EncodeToString(raw), hash[:]
+}
+
+func hashRefreshToken(refreshToken string) ([]byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(refreshToken)
+	if err != nil || len(raw) != 32 {
+		return nil, store.ErrSessionNotFound
+	}
+	hash := sha256.Sum256(raw)
+	return hash[:], nil
+}
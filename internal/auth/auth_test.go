@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/mail"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store/memory"
+	"example.com/user-management/internal/token"
+	"github.com/google/uuid"
+)
+
+func TestParseAccessToken(t *testing.T) {
+	userStore := memory.NewUserStore()
+	signer := token.NewSigner([]byte("secret"))
+	authenticator := NewAuthenticator(userStore, mail.NewMemorySender(), signer, Options{
+		AccessTokenTTL: 15 * time.Minute,
+		SessionTTL:     time.Hour,
+	})
+
+	user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	setPassword(t, userStore, user.UserId, hash)
+
+	tokens, err := authenticator.Login(t.Context(), "alice@example.com", "correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	claims, err := authenticator.ParseAccessToken(t.Context(), tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("ParseAccessToken failed: %v", err)
+	}
+	if claims.UserId != user.UserId || claims.SessionId == uuid.Nil {
+		t.Errorf("Expected claims for user %s with a session, got %+v", user.UserId, claims)
+	}
+	if claims.MFA {
+		t.Errorf("Expected a login with a password alone not to count as MFA")
+	}
+
+	other := NewAuthenticator(userStore, mail.NewMemorySender(), token.NewSigner([]byte("other secret")), Options{})
+	if _, err := other.ParseAccessToken(t.Context(), tokens.AccessToken); !errors.Is(err, token.ErrInvalid) {
+		t.Errorf("Expected token.ErrInvalid for a token signed with another secret, got %v", err)
+	}
+
+	authenticator.now = func() time.Time { return time.Now().Add(16 * time.Minute) }
+	if _, err := authenticator.ParseAccessToken(t.Context(), tokens.AccessToken); !errors.Is(err, token.ErrInvalid) {
+		t.Errorf("Expected token.ErrInvalid for an expired token, got %v", err)
+	}
+}
+
+func TestParseAccessToken_SessionEnded(t *testing.T) {
+	userStore := memory.NewUserStore()
+	authenticator := NewAuthenticator(userStore, mail.NewMemorySender(), token.NewSigner([]byte("secret")), Options{
+		AccessTokenTTL: 15 * time.Minute,
+		SessionTTL:     time.Hour,
+	})
+
+	user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	setPassword(t, userStore, user.UserId, hash)
+
+	login := func() Tokens {
+		t.Helper()
+		tokens, err := authenticator.Login(t.Context(), "alice@example.com", "correct horse battery staple")
+		if err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		return tokens
+	}
+
+	loggedOut := login()
+	if err := authenticator.Logout(t.Context(), loggedOut.RefreshToken); err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	if _, err := authenticator.ParseAccessToken(t.Context(), loggedOut.AccessToken); !errors.Is(err, token.ErrInvalid) {
+		t.Errorf("Expected token.ErrInvalid after logging out, got %v", err)
+	}
+
+	reset := login()
+	setPassword(t, userStore, user.UserId, hash)
+	if _, err := authenticator.ParseAccessToken(t.Context(), reset.AccessToken); !errors.Is(err, token.ErrInvalid) {
+		t.Errorf("Expected token.ErrInvalid after a password reset, got %v", err)
+	}
+
+	if _, err := authenticator.ParseAccessToken(t.Context(), login().AccessToken); err != nil {
+		t.Errorf("Expected a new login to be accepted, got %v", err)
+	}
+}
+
+// setPassword gives the user a password through a password reset, the only
+// way one is set.
+func setPassword(t *testing.T, userStore *memory.UserStore, userId uuid.UUID, hash string) {
+	t.Helper()
+
+	reset := model.PasswordReset{TokenId: uuid.New(), UserId: userId, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := userStore.CreatePasswordReset(t.Context(), reset); err != nil {
+		t.Fatalf("CreatePasswordReset failed: %v", err)
+	}
+	if err := userStore.ResetPassword(t.Context(), reset.TokenId, hash, time.Now()); err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+}
+
+// blockingSender holds every email until release is closed.
+type blockingSender struct {
+	release chan struct{}
+	sent    chan mail.Message
+}
+
+func (sender *blockingSender) Send(ctx context.Context, msg mail.Message) error {
+	select {
+	case <-sender.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	sender.sent <- msg
+	return nil
+}
+
+func TestForgotPassword_SendsInBackground(t *testing.T) {
+	userStore := memory.NewUserStore()
+	sender := &blockingSender{release: make(chan struct{}), sent: make(chan mail.Message, 1)}
+	authenticator := NewAuthenticator(userStore, sender, token.NewSigner([]byte("secret")), Options{
+		ResetTTL: time.Hour,
+		ResetURL: "https://example.com/reset-password",
+	})
+
+	if _, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	// a known address returns before its email is sent, like an unknown one
+	ctx, cancel := context.WithCancel(t.Context())
+	if err := authenticator.ForgotPassword(ctx, "alice@example.com"); err != nil {
+		t.Fatalf("ForgotPassword failed: %v", err)
+	}
+	cancel()
+
+	// the email outlives the request that asked for it
+	close(sender.release)
+	authenticator.Wait()
+	select {
+	case msg := <-sender.sent:
+		if msg.To != "alice@example.com" {
+			t.Errorf("Expected the email to go to alice@example.com, got %s", msg.To)
+		}
+	default:
+		t.Fatal("Expected a password reset email")
+	}
+}
@@ -0,0 +1,346 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/pquerna/otp/totp"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	mfaChallengePurpose  = "mfa-challenge"
+	mfaEncryptionPurpose = "mfa-encryption"
+	recoveryCodePurpose  = "mfa-recovery-code"
+
+	// totpPeriod is the lifetime of a code in seconds, as authenticator apps
+	// expect.
+	totpPeriod = 30
+	// totpSkew is how many periods either side of now a code is accepted
+	// for, to allow for clocks that drift and users that type slowly.
+	totpSkew = 1
+	// RecoveryCodeCount is how many recovery codes a user gets at a time.
+	RecoveryCodeCount = 10
+	qrCodeSize        = 256
+)
+
+// TOTPEnrolment is what a user needs to add their account to an
+// authenticator app.
+type TOTPEnrolment struct {
+	// Secret is the base32 shared secret, for apps that cannot scan codes.
+	Secret string
+	// URI is the otpauth:// URI the QR code encodes.
+	URI string
+	// QRCodePNG is a PNG image of the QR code.
+	QRCodePNG []byte
+}
+
+// EnrolTOTP starts adding an authenticator app as a second factor for the
+// user. It only guards their logins once confirmed with ConfirmTOTP. Besides
+// the errors of store.CreateTOTPFactor it returns ErrUserNotFound.
+func (authenticator *Authenticator) EnrolTOTP(ctx context.Context, userId uuid.UUID) (TOTPEnrolment, error) {
+	user, ok, err := authenticator.store.GetUserById(ctx, userId)
+	if err != nil {
+		return TOTPEnrolment{}, err
+	}
+	if !ok {
+		return TOTPEnrolment{}, ErrUserNotFound
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      authenticator.opts.MFAIssuer,
+		AccountName: user.Email,
+		Period:      totpPeriod,
+	})
+	if err != nil {
+		return TOTPEnrolment{}, err
+	}
+	ciphertext, err := authenticator.encryptSecret(userId, key.Secret())
+	if err != nil {
+		return TOTPEnrolment{}, err
+	}
+	qrCode, err := qrcode.Encode(key.URL(), qrcode.Medium, qrCodeSize)
+	if err != nil {
+		return TOTPEnrolment{}, err
+	}
+
+	if err := authenticator.store.CreateTOTPFactor(ctx, model.TOTPFactor{
+		UserId:           userId,
+		SecretCiphertext: ciphertext,
+		CreatedAt:        authenticator.now().UTC(),
+	}); err != nil {
+		return TOTPEnrolment{}, err
+	}
+
+	return TOTPEnrolment{
+		Secret:    key.Secret(),
+		URI:       key.URL(),
+		QRCodePNG: qrCode,
+	}, nil
+}
+
+// ConfirmTOTP turns on the user's authenticator app if code came from it,
+// returning their recovery codes. These are only ever shown here and by
+// RegenerateRecoveryCodes. Besides the errors of store.ConfirmTOTPFactor it
+// returns store.ErrWrongCode.
+func (authenticator *Authenticator) ConfirmTOTP(ctx context.Context, userId uuid.UUID, code string) ([]string, error) {
+	factor, ok, err := authenticator.store.GetTOTPFactor(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, store.ErrFactorNotFound
+	}
+	if factor.ConfirmedAt != nil {
+		return nil, store.ErrMFAEnabled
+	}
+
+	step, err := authenticator.matchTOTP(factor, code)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes := authenticator.newRecoveryCodes(userId)
+	if err := authenticator.store.ConfirmTOTPFactor(ctx, userId, step, hashes, authenticator.now().UTC()); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, for when they
+// have used or lost them. It returns store.ErrMFANotEnabled if the user has
+// no confirmed authenticator app.
+func (authenticator *Authenticator) RegenerateRecoveryCodes(ctx context.Context, userId uuid.UUID) ([]string, error) {
+	codes, hashes := authenticator.newRecoveryCodes(userId)
+	if err := authenticator.store.ReplaceRecoveryCodes(ctx, userId, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ResetMFA removes the user's second factor, for when they have lost both
+// their authenticator app and their recovery codes. It returns false if they
+// had none.
+func (authenticator *Authenticator) ResetMFA(ctx context.Context, userId uuid.UUID) (bool, error) {
+	return authenticator.store.ResetMFA(ctx, userId)
+}
+
+// LoginMFA finishes a login that Login answered with an MFA token. code is
+// either a code from the user's authenticator app or one of their recovery
+// codes. Besides the errors of store.CountMFAChallengeAttempt it returns
+// store.ErrChallengeNotFound for malformed tokens, store.ErrWrongCode and
+// ErrInvalidCredentials if the user can no longer log in.
+func (authenticator *Authenticator) LoginMFA(ctx context.Context, mfaToken, code string) (Tokens, error) {
+	tenantId, challengeId, err := authenticator.signer.Verify(mfaChallengePurpose, mfaToken)
+	if err != nil {
+		return Tokens{}, store.ErrChallengeNotFound
+	}
+	ctx = tenant.WithID(ctx, tenantId)
+
+	challenge, err := authenticator.store.CountMFAChallengeAttempt(ctx, challengeId, authenticator.opts.MFAMaxAttempts, authenticator.now().UTC())
+	if err != nil {
+		return Tokens{}, err
+	}
+	userId := challenge.UserId
+
+	// the user may have been deactivated since the password was checked
+	user, ok, err := authenticator.store.GetUserById(ctx, userId)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if !ok || user.Status != model.StatusActive {
+		authenticator.loginFailed(ctx, &userId, "inactive")
+		return Tokens{}, ErrInvalidCredentials
+	}
+
+	factorUsed, err := authenticator.checkSecondFactor(ctx, userId, code)
+	if errors.Is(err, store.ErrWrongCode) || errors.Is(err, store.ErrCodeReused) {
+		authenticator.loginFailed(ctx, &userId, err.Error())
+		return Tokens{}, store.ErrWrongCode
+	}
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	// a concurrent attempt may have answered it since it was counted
+	deleted, err := authenticator.store.DeleteMFAChallenge(ctx, challengeId)
+	if err != nil {
+		return Tokens{}, err
+	}
+	if !deleted {
+		return Tokens{}, store.ErrChallengeNotFound
+	}
+
+	return authenticator.startSession(ctx, userId, factorUsed)
+}
+
+// challenge stands in for a session when the user has a second factor: the
+// returned tokens carry only the MFA token that LoginMFA accepts.
+func (authenticator *Authenticator) challenge(ctx context.Context, userId uuid.UUID) (Tokens, error) {
+	challenge := model.MFAChallenge{
+		ChallengeId: uuid.New(),
+		UserId:      userId,
+		ExpiresAt:   authenticator.now().UTC().Add(authenticator.opts.MFAChallengeTTL),
+	}
+	if err := authenticator.store.CreateMFAChallenge(ctx, challenge); err != nil {
+		return Tokens{}, fmt.Errorf("failed to store MFA challenge: %w", err)
+	}
+
+	authenticator.audit(ctx, model.AuditEvent{
+		UserId: &userId,
+		Action: model.AuditLoginMFAChallenged,
+	})
+	return Tokens{
+		MFAToken:  authenticator.signer.Sign(mfaChallengePurpose, tenant.FromContext(ctx), challenge.ChallengeId),
+		ExpiresIn: authenticator.opts.MFAChallengeTTL,
+	}, nil
+}
+
+// mfaEnabled reports whether the user has a confirmed second factor.
+func (authenticator *Authenticator) mfaEnabled(ctx context.Context, userId uuid.UUID) (bool, error) {
+	factor, ok, err := authenticator.store.GetTOTPFactor(ctx, userId)
+	if err != nil || !ok {
+		return false, err
+	}
+	return factor.ConfirmedAt != nil, nil
+}
+
+// checkSecondFactor uses up code as a second factor for the user, returning
+// the kind of factor it was.
+func (authenticator *Authenticator) checkSecondFactor(ctx context.Context, userId uuid.UUID, code string) (string, error) {
+	code = normalizeCode(code)
+	if !isTOTPCode(code) {
+		codeHash := authenticator.signer.Digest(recoveryCodePurpose, userId[:], []byte(code))
+		return "recovery_code", authenticator.store.UseRecoveryCode(ctx, userId, codeHash, authenticator.now().UTC())
+	}
+
+	factor, ok, err := authenticator.store.GetTOTPFactor(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	if !ok || factor.ConfirmedAt == nil {
+		// reset since the password was checked
+		return "", store.ErrWrongCode
+	}
+
+	step, err := authenticator.matchTOTP(factor, code)
+	if err != nil {
+		return "", err
+	}
+	return "totp", authenticator.store.UseTOTPStep(ctx, userId, step)
+}
+
+// matchTOTP returns the time step code was generated for from the factor's
+// secret, or store.ErrWrongCode if it matches none near now.
+func (authenticator *Authenticator) matchTOTP(factor model.TOTPFactor, code string) (int64, error) {
+	code = normalizeCode(code)
+	if !isTOTPCode(code) {
+		return 0, store.ErrWrongCode
+	}
+	secret, err := authenticator.decryptSecret(factor.UserId, factor.SecretCiphertext)
+	if err != nil {
+		return 0, err
+	}
+
+	now := authenticator.now().Unix() / totpPeriod
+	for step := now - totpSkew; step <= now+totpSkew; step++ {
+		expected, err := hotp.GenerateCodeCustom(secret, uint64(step), hotp.ValidateOpts{
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, err
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, nil
+		}
+	}
+	return 0, store.ErrWrongCode
+}
+
+// newRecoveryCodes returns fresh recovery codes for the user and the hashes
+// they are stored as. Like phone codes they are keyed, but with 50 bits each
+// they would be hard to brute force even without the key.
+func (authenticator *Authenticator) newRecoveryCodes(userId uuid.UUID) ([]string, [][]byte) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([][]byte, RecoveryCodeCount)
+	for i := range codes {
+		raw := strings.ToLower(rand.Text()[:10])
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = authenticator.signer.Digest(recoveryCodePurpose, userId[:], []byte(raw))
+	}
+	return codes, hashes
+}
+
+// encryptSecret seals a TOTP secret with AES-GCM. The user id is bound in as
+// additional data, so that a secret copied to another user's row fails to
+// decrypt.
+func (authenticator *Authenticator) encryptSecret(userId uuid.UUID, secret string) ([]byte, error) {
+	aead, err := authenticator.secretAEAD()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(secret)+aead.Overhead())
+	_, _ = rand.Read(nonce)
+	return aead.Seal(nonce, nonce, []byte(secret), userId[:]), nil
+}
+
+func (authenticator *Authenticator) decryptSecret(userId uuid.UUID, ciphertext []byte) (string, error) {
+	aead, err := authenticator.secretAEAD()
+	if err != nil {
+		return "", err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return "", errors.New("TOTP secret ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	secret, err := aead.Open(nil, nonce, sealed, userId[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt TOTP secret: %w", err)
+	}
+	return string(secret), nil
+}
+
+// secretAEAD uses the configured MFA key, or else one derived from the token
+// secret, so that no separate secret has to be configured.
+func (authenticator *Authenticator) secretAEAD() (cipher.AEAD, error) {
+	key := authenticator.opts.MFAKey
+	if len(key) == 0 {
+		key = authenticator.signer.Digest(mfaEncryptionPurpose)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// normalizeCode drops the spaces and dashes users type or paste along with a
+// code, and lowercases recovery codes.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(code))
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != otp.DigitsSix.Length() {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,90 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/mail"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/store/memory"
+	"example.com/user-management/internal/token"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+)
+
+func TestTOTPSecretEncryption(t *testing.T) {
+	authenticator := NewAuthenticator(memory.NewUserStore(), mail.NewMemorySender(), token.NewSigner([]byte("secret")), Options{})
+	userId := uuid.New()
+
+	ciphertext, err := authenticator.encryptSecret(userId, "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("encryptSecret failed: %v", err)
+	}
+	if bytes.Contains(ciphertext, []byte("JBSWY3DPEHPK3PXP")) {
+		t.Errorf("Expected the secret not to appear in the ciphertext")
+	}
+
+	secret, err := authenticator.decryptSecret(userId, ciphertext)
+	if err != nil || secret != "JBSWY3DPEHPK3PXP" {
+		t.Errorf("Expected the secret back, got %q, %v", secret, err)
+	}
+	if _, err := authenticator.decryptSecret(uuid.New(), ciphertext); err == nil {
+		t.Errorf("Expected a secret moved to another user to fail to decrypt")
+	}
+
+	other := NewAuthenticator(memory.NewUserStore(), mail.NewMemorySender(), token.NewSigner([]byte("secret")), Options{MFAKey: bytes.Repeat([]byte{1}, 32)})
+	if _, err := other.decryptSecret(userId, ciphertext); err == nil {
+		t.Errorf("Expected a secret sealed with another key to fail to decrypt")
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	authenticator := NewAuthenticator(memory.NewUserStore(), mail.NewMemorySender(), token.NewSigner([]byte("secret")), Options{})
+	now := time.Unix(1_700_000_010, 0)
+	authenticator.now = func() time.Time { return now }
+
+	userId := uuid.New()
+	ciphertext, err := authenticator.encryptSecret(userId, "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("encryptSecret failed: %v", err)
+	}
+	factor := model.TOTPFactor{UserId: userId, SecretCiphertext: ciphertext}
+
+	tests := []struct {
+		name    string
+		at      time.Time
+		format  func(string) string
+		wantErr error
+	}{
+		{"Current step", now, nil, nil},
+		{"Previous step", now.Add(-totpPeriod * time.Second), nil, nil},
+		{"Next step", now.Add(totpPeriod * time.Second), nil, nil},
+		{"Spaced", now, func(code string) string { return code[:3] + " " + code[3:] }, nil},
+		{"Too old", now.Add(-2 * totpPeriod * time.Second), nil, store.ErrWrongCode},
+		{"Too new", now.Add(2 * totpPeriod * time.Second), nil, store.ErrWrongCode},
+		{"Not digits", now, func(string) string { return "abcdef" }, store.ErrWrongCode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := totp.GenerateCode("JBSWY3DPEHPK3PXP", tt.at)
+			if err != nil {
+				t.Fatalf("GenerateCode failed: %v", err)
+			}
+			if tt.format != nil {
+				code = tt.format(code)
+			}
+
+			step, err := authenticator.matchTOTP(factor, code)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && step != tt.at.Unix()/totpPeriod {
+				t.Errorf("Expected step %d, got %d", tt.at.Unix()/totpPeriod, step)
+			}
+		})
+	}
+}
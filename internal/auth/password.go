@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"example.com/user-management/internal/model"
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	MinPasswordLength = 12
+	MaxPasswordLength = 128
+)
+
+// ErrWeakPassword is wrapped by the errors CheckPasswordPolicy returns, whose
+// messages say which rule the password broke.
+var ErrWeakPassword = errors.New("password does not meet the policy")
+
+// argon2id parameters, as recommended by OWASP. They are stored with each
+// hash, so raising them later does not invalidate existing passwords.
+const (
+	argonMemory  = 19 * 1024
+	argonTime    = 2
+	argonThreads = 1
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+// CheckPasswordPolicy returns an error wrapping ErrWeakPassword if password
+// is not acceptable for user.
+func CheckPasswordPolicy(password string, user model.User) error {
+	length := utf8.RuneCountInString(password)
+	if length < MinPasswordLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrWeakPassword, MinPasswordLength)
+	}
+	if length > MaxPasswordLength {
+		return fmt.Errorf("%w: must be at most %d characters", ErrWeakPassword, MaxPasswordLength)
+	}
+
+	localPart, _, _ := strings.Cut(user.Email, "@")
+	if localPart != "" && strings.Contains(strings.ToLower(password), strings.ToLower(localPart)) {
+		return fmt.Errorf("%w: must not contain the email address", ErrWeakPassword)
+	}
+	return nil
+}
+
+// HashPassword returns an argon2id hash of password in the PHC string format.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// CheckPassword reports whether password matches a hash from HashPassword.
+func CheckPassword(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return false, errors.New("unsupported password hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+	var memory, time uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, fmt.Errorf("malformed argon2 parameters: %w", err)
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, fmt.Errorf("malformed argon2 salt: %w", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, fmt.Errorf("malformed argon2 key: %w", err)
+	}
+
+	other := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"example.com/user-management/internal/model"
+)
+
+func TestHashPassword_RoundTrip(t *testing.T) {
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=19456,t=2,p=1$") {
+		t.Errorf("Expected an argon2id PHC string, got %q", hash)
+	}
+
+	if ok, err := CheckPassword(hash, "correct horse battery staple"); err != nil || !ok {
+		t.Errorf("Expected the password to match, got %v, %v", ok, err)
+	}
+	if ok, err := CheckPassword(hash, "Correct horse battery staple"); err != nil || ok {
+		t.Errorf("Expected a different password not to match, got %v, %v", ok, err)
+	}
+
+	other, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	if other == hash {
+		t.Errorf("Expected hashes of the same password to be salted differently")
+	}
+}
+
+func TestCheckPassword_Malformed(t *testing.T) {
+	for _, hash := range []string{"", "plaintext", "$2a$10$bcrypthashbcrypthashbcrypthash", "$argon2id$v=19$m=x$salt$key"} {
+		if _, err := CheckPassword(hash, "password"); err == nil {
+			t.Errorf("Expected an error for %q", hash)
+		}
+	}
+}
+
+func TestCheckPasswordPolicy(t *testing.T) {
+	user := model.User{Email: "alice.smith@example.com"}
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{"Long enough", "correct horse battery staple", false},
+		{"Too short", "short pass", true},
+		{"Too long", strings.Repeat("a", MaxPasswordLength+1), true},
+		{"Counts characters not bytes", "ĉĝĥĵŝŭĉĝĥĵŝŭ", false},
+		{"Contains the email", "xxAlice.Smith2024", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPasswordPolicy(tt.password, user)
+			if tt.wantErr && !errors.Is(err, ErrWeakPassword) {
+				t.Errorf("Expected ErrWeakPassword, got %v", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Package avatar keeps the profile pictures of users: it checks and resizes
+// uploaded images, keeps the results in a blob store and records where users'
+// avatars are served from.
+package avatar
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	// ErrNotFound is returned for users without an avatar.
+	ErrNotFound = errors.New("avatar not found")
+	// ErrUnknownSize is returned when asked for a size that is not one of
+	// Sizes.
+	ErrUnknownSize = errors.New("unknown avatar size")
+)
+
+// Store is what Service needs from the user store.
+type Store interface {
+	store.UserStoreInterface
+	store.AvatarStoreInterface
+}
+
+// Service uploads, serves and deletes the avatars of users.
+type Service struct {
+	users Store
+	blobs store.BlobStoreInterface
+}
+
+func NewService(users Store, blobs store.BlobStoreInterface) *Service {
+	return &Service{
+		users: users,
+		blobs: blobs,
+	}
+}
+
+// Upload replaces the avatar of the user with userId with the image in data
+// and returns the updated user. Besides ErrUserNotFound it returns the errors
+// of Process.
+func (service *Service) Upload(ctx context.Context, userId uuid.UUID, data []byte) (model.User, error) {
+	if _, ok, err := service.users.GetUserById(ctx, userId); err != nil || !ok {
+		return model.User{}, notFound(err, ErrUserNotFound)
+	}
+
+	images, err := Process(data)
+	if err != nil {
+		return model.User{}, err
+	}
+	for _, img := range images {
+		err := service.blobs.PutBlob(ctx, model.Blob{
+			Key:         key(userId, img.Size),
+			ContentType: img.ContentType,
+			Data:        img.Data,
+		})
+		if err != nil {
+			return model.User{}, fmt.Errorf("failed to store avatar: %w", err)
+		}
+	}
+
+	// the URL changes with the image, so clients may cache what it serves
+	sum := sha256.Sum256(images[0].Data)
+	user, ok, err := service.users.SetUserAvatar(ctx, userId, URL(userId, hex.EncodeToString(sum[:8])))
+	if err != nil || !ok {
+		return model.User{}, notFound(err, ErrUserNotFound)
+	}
+	return user, nil
+}
+
+// Get returns the avatar of the user with userId at size, which must be one
+// of Sizes.
+func (service *Service) Get(ctx context.Context, userId uuid.UUID, size int) (model.Blob, error) {
+	if !slices.Contains(Sizes, size) {
+		return model.Blob{}, ErrUnknownSize
+	}
+
+	// blobs outlive deleted users, whose avatars must not be served
+	user, ok, err := service.users.GetUserById(ctx, userId)
+	if err != nil || !ok {
+		return model.Blob{}, notFound(err, ErrUserNotFound)
+	}
+	if user.AvatarUrl == "" {
+		return model.Blob{}, ErrNotFound
+	}
+
+	blob, ok, err := service.blobs.GetBlob(ctx, key(userId, size))
+	if err != nil || !ok {
+		return model.Blob{}, notFound(err, ErrNotFound)
+	}
+	return blob, nil
+}
+
+// Delete removes the avatar of the user with userId and returns the updated
+// user.
+func (service *Service) Delete(ctx context.Context, userId uuid.UUID) (model.User, error) {
+	user, ok, err := service.users.GetUserById(ctx, userId)
+	if err != nil || !ok {
+		return model.User{}, notFound(err, ErrUserNotFound)
+	}
+	if user.AvatarUrl == "" {
+		return model.User{}, ErrNotFound
+	}
+
+	user, ok, err = service.users.SetUserAvatar(ctx, userId, "")
+	if err != nil || !ok {
+		return model.User{}, notFound(err, ErrUserNotFound)
+	}
+	if _, err := service.blobs.DeleteBlobs(ctx, prefix(userId)); err != nil {
+		return model.User{}, fmt.Errorf("failed to delete avatar: %w", err)
+	}
+	return user, nil
+}
+
+// URL returns the path the avatar of the user with userId is served from.
+// version tells apart the images the user has uploaded.
+func URL(userId uuid.UUID, version string) string {
+	return "/users/" + userId.String() + "/avatar?v=" + version
+}
+
+func prefix(userId uuid.UUID) string {
+	return "avatars/" + userId.String() + "/"
+}
+
+func key(userId uuid.UUID, size int) string {
+	return prefix(userId) + strconv.Itoa(size)
+}
+
+// notFound returns err if there was one, and otherwise errNotFound.
+func notFound(err, errNotFound error) error {
+	if err != nil {
+		return err
+	}
+	return errNotFound
+}
@@ -0,0 +1,107 @@
+package avatar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store/memory"
+	"github.com/google/uuid"
+)
+
+func newTestService(t *testing.T) (*Service, model.User) {
+	t.Helper()
+
+	userStore := memory.NewUserStore()
+	user, err := userStore.CreateUser(t.Context(), model.User{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	return NewService(userStore, userStore), user
+}
+
+func TestService_UploadGetDelete(t *testing.T) {
+	service, user := newTestService(t)
+
+	updated, err := service.Upload(t.Context(), user.UserId, encodePNG(t, halves(300, 300)))
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if !strings.HasPrefix(updated.AvatarUrl, "/users/"+user.UserId.String()+"/avatar?v=") {
+		t.Errorf("Expected an avatar URL, got %q", updated.AvatarUrl)
+	}
+
+	for _, size := range Sizes {
+		blob, err := service.Get(t.Context(), user.UserId, size)
+		if err != nil {
+			t.Fatalf("Get(%d) failed: %v", size, err)
+		}
+		if bounds := decode(t, blob.Data).Bounds(); bounds.Dx() != size {
+			t.Errorf("Expected a %d pixel image, got %d", size, bounds.Dx())
+		}
+	}
+
+	// a different image is served from a different URL
+	replaced, err := service.Upload(t.Context(), user.UserId, encodePNG(t, halves(40, 80)))
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if replaced.AvatarUrl == updated.AvatarUrl {
+		t.Errorf("Expected the avatar URL to change, still %q", replaced.AvatarUrl)
+	}
+
+	deleted, err := service.Delete(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if deleted.AvatarUrl != "" {
+		t.Errorf("Expected no avatar URL, got %q", deleted.AvatarUrl)
+	}
+	if _, err := service.Get(t.Context(), user.UserId, Sizes[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after Delete, got %v", err)
+	}
+	if _, err := service.Delete(t.Context(), user.UserId); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+}
+
+func TestService_Errors(t *testing.T) {
+	service, user := newTestService(t)
+
+	if _, err := service.Upload(t.Context(), uuid.New(), encodePNG(t, halves(10, 10))); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+	if _, err := service.Upload(t.Context(), user.UserId, []byte("not an image")); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Expected ErrUnsupportedType, got %v", err)
+	}
+	if _, err := service.Get(t.Context(), user.UserId, 100); !errors.Is(err, ErrUnknownSize) {
+		t.Errorf("Expected ErrUnknownSize, got %v", err)
+	}
+	if _, err := service.Get(t.Context(), user.UserId, Sizes[0]); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound before an upload, got %v", err)
+	}
+}
+
+func TestService_DeletedUser(t *testing.T) {
+	userStore := memory.NewUserStore()
+	service := NewService(userStore, userStore)
+	user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Jane", Email: "jane@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if _, err := service.Upload(t.Context(), user.UserId, encodePNG(t, halves(10, 10))); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+
+	if _, err := userStore.DeleteUser(t.Context(), user.UserId); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	if _, err := service.Get(t.Context(), user.UserId, Sizes[0]); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected the avatar of a deleted user to be gone, got %v", err)
+	}
+}
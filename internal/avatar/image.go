@@ -0,0 +1,96 @@
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/disintegration/imaging"
+	"github.com/gabriel-vasile/mimetype"
+	// registers the WebP decoder with package image
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrTooLarge        = errors.New("avatar too large")
+	ErrUnsupportedType = errors.New("unsupported avatar type")
+	ErrInvalidImage    = errors.New("invalid avatar image")
+)
+
+// MaxSize is the most bytes an uploaded image may take.
+const MaxSize = 5 << 20
+
+// maxPixels bounds the images that are decoded, since a small file can
+// describe a very large image.
+const maxPixels = 50_000_000
+
+const jpegQuality = 85
+
+// Sizes are the widths, in pixels, of the square images an avatar is kept at,
+// largest first.
+var Sizes = []int{512, 256, 128, 64}
+
+// contentTypes are the types of image accepted, as sniffed from their bytes.
+var contentTypes = []string{"image/jpeg", "image/png", "image/webp"}
+
+// Image is an avatar at one of Sizes.
+type Image struct {
+	Size        int
+	ContentType string
+	Data        []byte
+}
+
+// Process checks that data is a JPEG, PNG or WebP image and returns it
+// cropped to a square and scaled to each of Sizes. The images are encoded
+// afresh, so EXIF and other metadata are left behind once the orientation
+// they record has been applied. Images with transparency are kept as PNG and
+// others as JPEG.
+func Process(data []byte) ([]Image, error) {
+	if len(data) > MaxSize {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrTooLarge, MaxSize)
+	}
+	detected := mimetype.Detect(data)
+	if !mimetype.EqualsAny(detected.String(), contentTypes...) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedType, detected.String())
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d is too many pixels", ErrInvalidImage, config.Width, config.Height)
+	}
+	decoded, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	images := make([]Image, 0, len(Sizes))
+	for _, size := range Sizes {
+		resized := imaging.Fill(decoded, size, size, imaging.Center, imaging.Lanczos)
+		encoded, contentType, err := encode(resized)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, Image{
+			Size:        size,
+			ContentType: contentType,
+			Data:        encoded,
+		})
+	}
+	return images, nil
+}
+
+func encode(img *image.NRGBA) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if img.Opaque() {
+		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+		return buf.Bytes(), "image/jpeg", err
+	}
+	err := png.Encode(&buf, img)
+	return buf.Bytes(), "image/png", err
+}
@@ -0,0 +1,163 @@
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var (
+	red  = color.NRGBA{R: 255, A: 255}
+	blue = color.NRGBA{B: 255, A: 255}
+)
+
+// halves returns an image with a red left half and a blue right half.
+func halves(width, height int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			if x < width/2 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// encodeJPEG returns img as a JPEG whose EXIF says to rotate it for display
+// as orientation describes.
+func encodeJPEG(t *testing.T, img image.Image, orientation byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+
+	// an APP1 segment with a big endian TIFF header and a single IFD entry
+	exif := []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08" +
+		"\x00\x01" + "\x01\x12\x00\x03\x00\x00\x00\x01\x00" + string([]byte{orientation}) + "\x00\x00" +
+		"\x00\x00\x00\x00")
+	segment := append([]byte{0xff, 0xe1, 0x00, byte(len(exif) + 2)}, exif...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func decode(t *testing.T, data []byte) image.Image {
+	t.Helper()
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to decode image: %v", err)
+	}
+	return img
+}
+
+func near(c color.Color, want color.NRGBA) bool {
+	r, g, b, _ := c.RGBA()
+	wr, wg, wb, _ := want.RGBA()
+	diff := func(a, b uint32) bool { return max(a, b)-min(a, b) < 0x2000 }
+	return diff(r, wr) && diff(g, wg) && diff(b, wb)
+}
+
+func TestProcess_Sizes(t *testing.T) {
+	images, err := Process(encodePNG(t, halves(800, 600)))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	if len(images) != len(Sizes) {
+		t.Fatalf("Expected %d images, got %d", len(Sizes), len(images))
+	}
+	for i, img := range images {
+		if img.Size != Sizes[i] {
+			t.Errorf("Expected size %d, got %d", Sizes[i], img.Size)
+		}
+		// opaque images are kept as JPEG whatever they were uploaded as
+		if img.ContentType != "image/jpeg" {
+			t.Errorf("Expected image/jpeg, got %s", img.ContentType)
+		}
+		bounds := decode(t, img.Data).Bounds()
+		if bounds.Dx() != img.Size || bounds.Dy() != img.Size {
+			t.Errorf("Expected %dx%d, got %dx%d", img.Size, img.Size, bounds.Dx(), bounds.Dy())
+		}
+	}
+}
+
+func TestProcess_Transparent(t *testing.T) {
+	images, err := Process(encodePNG(t, image.NewNRGBA(image.Rect(0, 0, 100, 100))))
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if images[0].ContentType != "image/png" {
+		t.Errorf("Expected transparent images to stay PNG, got %s", images[0].ContentType)
+	}
+}
+
+func TestProcess_EXIF(t *testing.T) {
+	data := encodeJPEG(t, halves(200, 200), 6)
+	if !bytes.Contains(data, []byte("Exif")) {
+		t.Fatalf("Expected the upload to carry EXIF")
+	}
+
+	images, err := Process(data)
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+
+	for _, img := range images {
+		if bytes.Contains(img.Data, []byte("Exif")) {
+			t.Errorf("Expected EXIF to be stripped from the %d pixel image", img.Size)
+		}
+	}
+
+	// orientation 6 turns the image a quarter clockwise, so the left half
+	// ends up on top
+	img := decode(t, images[0].Data)
+	if top, bottom := img.At(256, 64), img.At(256, 448); !near(top, red) || !near(bottom, blue) {
+		t.Errorf("Expected red above blue, got %v above %v", top, bottom)
+	}
+}
+
+func TestProcess_Rejected(t *testing.T) {
+	var gifData bytes.Buffer
+	if err := gif.Encode(&gifData, halves(10, 10), nil); err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+	truncated := encodePNG(t, halves(100, 100))
+	truncated = truncated[:len(truncated)/2]
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"gif", gifData.Bytes(), ErrUnsupportedType},
+		{"text", []byte("<svg xmlns=\"http://www.w3.org/2000/svg\"/>"), ErrUnsupportedType},
+		{"truncated", truncated, ErrInvalidImage},
+		{"too large", append(encodePNG(t, halves(10, 10)), make([]byte, MaxSize)...), ErrTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Process(test.data); !errors.Is(err, test.expected) {
+				t.Errorf("Expected %v, got %v", test.expected, err)
+			}
+		})
+	}
+}
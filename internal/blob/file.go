@@ -0,0 +1,136 @@
+// Package blob keeps files, such as avatar images, on the local filesystem.
+// The stores in package store keep them in the database instead.
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tenant"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// ErrInvalidKey is returned for keys that would leave the store's directory.
+var ErrInvalidKey = errors.New("invalid blob key")
+
+// FileStore keeps each blob in a file named by its key, under a directory for
+// its tenant. Only the data is kept: the content type is sniffed again when a
+// blob is read, and its UpdatedAt is the file's modification time.
+type FileStore struct {
+	dir string
+}
+
+var _ store.BlobStoreInterface = (*FileStore)(nil)
+
+// NewFileStore returns a store that keeps blobs under dir, creating it if
+// needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (fileStore *FileStore) PutBlob(ctx context.Context, blob model.Blob) error {
+	name, err := fileStore.path(ctx, blob.Key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o750); err != nil {
+		return err
+	}
+
+	// readers see the old file or the new one, never part of either
+	file, err := os.CreateTemp(filepath.Dir(name), ".blob-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(blob.Data); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), name)
+}
+
+func (fileStore *FileStore) GetBlob(ctx context.Context, key string) (model.Blob, bool, error) {
+	name, err := fileStore.path(ctx, key)
+	if err != nil {
+		return model.Blob{}, false, err
+	}
+
+	info, err := os.Stat(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return model.Blob{}, false, nil
+	}
+	if err != nil {
+		return model.Blob{}, false, err
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return model.Blob{}, false, err
+	}
+
+	return model.Blob{
+		Key:         key,
+		ContentType: mimetype.Detect(data).String(),
+		Data:        data,
+		UpdatedAt:   info.ModTime(),
+	}, true, nil
+}
+
+func (fileStore *FileStore) DeleteBlobs(ctx context.Context, prefix string) (bool, error) {
+	// every key with the prefix is under the directory the prefix is in
+	dir, err := fileStore.path(ctx, path.Dir(prefix+"_"))
+	if err != nil {
+		return false, err
+	}
+	root, err := fileStore.path(ctx, ".")
+	if err != nil {
+		return false, err
+	}
+
+	var deleted bool
+	err = filepath.WalkDir(dir, func(name string, entry fs.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || entry.IsDir() || strings.HasPrefix(entry.Name(), ".blob-") {
+			return err
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(filepath.ToSlash(rel), prefix) {
+			return nil
+		}
+		if err := os.Remove(name); err != nil {
+			return err
+		}
+		deleted = true
+		return nil
+	})
+	return deleted, err
+}
+
+// path returns the file that holds key for the tenant in ctx.
+func (fileStore *FileStore) path(ctx context.Context, key string) (string, error) {
+	local := filepath.FromSlash(key)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	return filepath.Join(fileStore.dir, tenant.FromContext(ctx).String(), local), nil
+}
@@ -0,0 +1,55 @@
+package blob
+
+import (
+	"errors"
+	"testing"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/store/storetest"
+	"example.com/user-management/internal/tenant"
+	"github.com/google/uuid"
+)
+
+func newTestFileStore(t *testing.T) *FileStore {
+	t.Helper()
+
+	fileStore, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	return fileStore
+}
+
+func TestFileStoreConformance(t *testing.T) {
+	storetest.RunBlobStoreTests(t, func(t *testing.T) store.BlobStoreInterface {
+		return newTestFileStore(t)
+	})
+}
+
+func TestFileStore_InvalidKey(t *testing.T) {
+	fileStore := newTestFileStore(t)
+
+	for _, key := range []string{"../escape", "/etc/passwd", "avatars/../../escape", ""} {
+		err := fileStore.PutBlob(t.Context(), model.Blob{Key: key, Data: []byte("x")})
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected ErrInvalidKey for %q, got %v", key, err)
+		}
+	}
+}
+
+func TestFileStore_Tenants(t *testing.T) {
+	fileStore := newTestFileStore(t)
+	other := tenant.WithID(t.Context(), uuid.New())
+
+	if err := fileStore.PutBlob(other, model.Blob{Key: "avatars/a", Data: []byte("x")}); err != nil {
+		t.Fatalf("PutBlob failed: %v", err)
+	}
+
+	if _, ok, err := fileStore.GetBlob(t.Context(), "avatars/a"); err != nil || ok {
+		t.Errorf("Expected the blob to be hidden from the default tenant, got %v, %v", ok, err)
+	}
+	if _, ok, err := fileStore.GetBlob(other, "avatars/a"); err != nil || !ok {
+		t.Errorf("Expected the blob to be found by its tenant, got %v, %v", ok, err)
+	}
+}
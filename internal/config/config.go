@@ -0,0 +1,457 @@
+// Package config reads the server settings from the environment.
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/phonenumber"
+	"github.com/google/uuid"
+)
+
+const (
+	StorePostgres = "postgres"
+	StoreSQLite   = "sqlite"
+	StoreMemory   = "memory"
+)
+
+const (
+	TracingNone   = "none"
+	TracingOTLP   = "otlp"
+	TracingStdout = "stdout"
+	TracingFile   = "file"
+)
+
+const (
+	MailFile = "file"
+	MailSMTP = "smtp"
+)
+
+const (
+	SMSLog  = "log"
+	SMSFile = "file"
+)
+
+const (
+	BlobDatabase = "database"
+	BlobFile     = "file"
+)
+
+type Config struct {
+	// Store selects the user store backend: "postgres", "sqlite" or
+	// "memory". The memory store keeps nothing across restarts.
+	Store string
+	DSN   string
+	// SQLitePath is the database file used by the sqlite store.
+	SQLitePath string
+	HTTPAddr   string
+	GRPCAddr   string
+	Cache      CacheConfig
+	Tracing    TracingConfig
+	// LogLevel is the least severe level logged.
+	LogLevel slog.Level
+	// DBConnectTimeout bounds how long startup keeps retrying to reach the
+	// database.
+	DBConnectTimeout time.Duration
+	// DrainDelay is how long the server keeps serving, while reporting itself
+	// not ready, between being told to stop and closing its listeners.
+	DrainDelay time.Duration
+	// ShutdownTimeout bounds how long in-flight requests may take to finish
+	// once the listeners are closed.
+	ShutdownTimeout time.Duration
+	Mail            MailConfig
+	// PublicURL is where users reach the HTTP API, for links mailed to them.
+	PublicURL string
+	// TokenSecret signs the tokens in those links. If it is empty a random
+	// key is used and links stop working when the server restarts.
+	TokenSecret string
+	// EmailVerificationTTL is how long an email verification link is valid.
+	EmailVerificationTTL time.Duration
+	SMS                  SMSConfig
+	// PhoneVerificationTTL is how long a phone verification code is valid.
+	PhoneVerificationTTL time.Duration
+	// PhoneVerificationMaxAttempts is how many guesses a code allows.
+	PhoneVerificationMaxAttempts int
+	// PhoneVerificationResendInterval is how long a user must wait before
+	// another code is sent.
+	PhoneVerificationResendInterval time.Duration
+	// PhoneDefaultRegion is the ISO 3166-1 alpha-2 code of the region whose
+	// national format phone numbers may be given in, such as "LK". Numbers
+	// in international format are always accepted, and only they are while
+	// it is empty.
+	PhoneDefaultRegion string
+	// AccessTokenTTL is how long an access token issued at login is valid.
+	AccessTokenTTL time.Duration
+	// SessionTTL is how long a login can be kept alive with refresh tokens.
+	SessionTTL time.Duration
+	// PasswordResetTTL is how long a password reset link is valid.
+	PasswordResetTTL time.Duration
+	// MFAIssuer names the service in authenticator apps.
+	MFAIssuer string
+	// MFAEncryptionKey is the base64 encoded 32 byte key TOTP secrets are
+	// encrypted with. If it is empty a key is derived from TokenSecret.
+	MFAEncryptionKey string
+	// MFAChallengeTTL is how long a user has to enter their second factor
+	// after their password.
+	MFAChallengeTTL time.Duration
+	// MFAMaxAttempts is how many second factor codes a login allows.
+	MFAMaxAttempts int
+	Blob           BlobConfig
+	// AdminUserIds are the users who may manage the accounts of others, such
+	// as deleting them or resetting their MFA, the attribute schema and
+	// OpenID Connect clients.
+	AdminUserIds []uuid.UUID
+	// SCIMToken is the bearer token identity providers provision users and
+	// groups over SCIM with. SCIM is off while it is empty.
+	SCIMToken string
+	// OIDCKeyRotation is how long a key signs OpenID Connect tokens before a
+	// new one takes over. It must outlast an access token.
+	OIDCKeyRotation time.Duration
+	// EmailProviderRules makes the variants of an address that a mail
+	// provider delivers to one mailbox, such as the dots and plus tags Gmail
+	// ignores, one user. Migrations key existing addresses by it and record
+	// it, and the server refuses a database keyed by the other setting until
+	// the addresses are rekeyed.
+	EmailProviderRules bool
+}
+
+// MailConfig selects how emails are delivered.
+type MailConfig struct {
+	// Transport is "file", which writes each message to Dir for local
+	// development, or "smtp".
+	Transport    string
+	Dir          string
+	From         string
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// SMSConfig selects how text messages are delivered. Only local transports
+// exist so far.
+type SMSConfig struct {
+	// Transport is "log", which logs each message, or "file", which appends
+	// them to File as JSON lines.
+	Transport string
+	File      string
+}
+
+// BlobConfig selects where files, such as avatar images, are kept.
+type BlobConfig struct {
+	// Store is "database", which keeps them in the user store, or "file",
+	// which keeps them under Dir.
+	Store string
+	Dir   string
+}
+
+// CacheConfig controls the read-through user cache. It is off unless Size is
+// positive.
+type CacheConfig struct {
+	Size        int
+	TTL         time.Duration
+	NegativeTTL time.Duration
+	// PeerInvalidation drops entries changed by other instances as their
+	// Postgres notifications arrive. It only applies to the postgres store;
+	// changes made by this instance always invalidate their entries.
+	PeerInvalidation bool
+}
+
+// TracingConfig selects where spans are exported. The OTLP exporter takes its
+// endpoint and headers from the standard OTEL_EXPORTER_OTLP_* variables.
+type TracingConfig struct {
+	// Exporter is "none", "otlp", "stdout" or "file".
+	Exporter string
+	// File is the path the file exporter appends spans to.
+	File string
+}
+
+func Default() Config {
+	return Config{
+		Store:      StorePostgres,
+		DSN:        db.PostgresDSN,
+		SQLitePath: "users.db",
+		HTTPAddr:   ":8080",
+		GRPCAddr:   ":9090",
+		Cache: CacheConfig{
+			TTL:              time.Minute,
+			NegativeTTL:      10 * time.Second,
+			PeerInvalidation: true,
+		},
+		Tracing: TracingConfig{
+			Exporter: TracingNone,
+			File:     "traces.json",
+		},
+		LogLevel:         slog.LevelInfo,
+		DBConnectTimeout: time.Minute,
+		DrainDelay:       5 * time.Second,
+		ShutdownTimeout:  15 * time.Second,
+		Mail: MailConfig{
+			Transport: MailFile,
+			Dir:       "mail",
+			From:      "no-reply@localhost",
+		},
+		PublicURL:            "http://localhost:8080",
+		EmailVerificationTTL: 24 * time.Hour,
+		SMS: SMSConfig{
+			Transport: SMSLog,
+			File:      "sms.jsonl",
+		},
+		PhoneVerificationTTL:            10 * time.Minute,
+		PhoneVerificationMaxAttempts:    5,
+		PhoneVerificationResendInterval: time.Minute,
+		AccessTokenTTL:                  15 * time.Minute,
+		SessionTTL:                      30 * 24 * time.Hour,
+		PasswordResetTTL:                time.Hour,
+		MFAIssuer:                       "User Management",
+		MFAChallengeTTL:                 5 * time.Minute,
+		MFAMaxAttempts:                  5,
+		Blob: BlobConfig{
+			Store: BlobDatabase,
+			Dir:   "blobs",
+		},
+		OIDCKeyRotation: 30 * 24 * time.Hour,
+	}
+}
+
+// Load starts from Default and overrides every setting whose environment
+// variable is set.
+func Load() (Config, error) {
+	cfg := Default()
+
+	var env envLoader
+	env.string(&cfg.Store, "USER_STORE")
+	env.string(&cfg.DSN, "DATABASE_URL")
+	env.string(&cfg.SQLitePath, "SQLITE_PATH")
+	env.string(&cfg.HTTPAddr, "HTTP_ADDR")
+	env.string(&cfg.GRPCAddr, "GRPC_ADDR")
+	env.int(&cfg.Cache.Size, "USER_CACHE_SIZE")
+	env.duration(&cfg.Cache.TTL, "USER_CACHE_TTL")
+	env.duration(&cfg.Cache.NegativeTTL, "USER_CACHE_NEGATIVE_TTL")
+	env.bool(&cfg.Cache.PeerInvalidation, "USER_CACHE_PEER_INVALIDATION")
+	env.string(&cfg.Tracing.Exporter, "TRACING_EXPORTER")
+	env.string(&cfg.Tracing.File, "TRACING_FILE")
+	env.level(&cfg.LogLevel, "LOG_LEVEL")
+	env.duration(&cfg.DBConnectTimeout, "DB_CONNECT_TIMEOUT")
+	env.duration(&cfg.DrainDelay, "SHUTDOWN_DRAIN_DELAY")
+	env.duration(&cfg.ShutdownTimeout, "SHUTDOWN_TIMEOUT")
+	env.string(&cfg.Mail.Transport, "MAIL_TRANSPORT")
+	env.string(&cfg.Mail.Dir, "MAIL_DIR")
+	env.string(&cfg.Mail.From, "MAIL_FROM")
+	env.string(&cfg.Mail.SMTPAddr, "SMTP_ADDR")
+	env.string(&cfg.Mail.SMTPUsername, "SMTP_USERNAME")
+	env.string(&cfg.Mail.SMTPPassword, "SMTP_PASSWORD")
+	env.string(&cfg.PublicURL, "PUBLIC_URL")
+	env.string(&cfg.TokenSecret, "TOKEN_SECRET")
+	env.duration(&cfg.EmailVerificationTTL, "EMAIL_VERIFICATION_TTL")
+	env.string(&cfg.SMS.Transport, "SMS_TRANSPORT")
+	env.string(&cfg.SMS.File, "SMS_FILE")
+	env.duration(&cfg.PhoneVerificationTTL, "PHONE_VERIFICATION_TTL")
+	env.int(&cfg.PhoneVerificationMaxAttempts, "PHONE_VERIFICATION_MAX_ATTEMPTS")
+	env.duration(&cfg.PhoneVerificationResendInterval, "PHONE_VERIFICATION_RESEND_INTERVAL")
+	env.string(&cfg.PhoneDefaultRegion, "PHONE_DEFAULT_REGION")
+	env.duration(&cfg.AccessTokenTTL, "ACCESS_TOKEN_TTL")
+	env.duration(&cfg.SessionTTL, "SESSION_TTL")
+	env.duration(&cfg.PasswordResetTTL, "PASSWORD_RESET_TTL")
+	env.string(&cfg.MFAIssuer, "MFA_ISSUER")
+	env.string(&cfg.MFAEncryptionKey, "MFA_ENCRYPTION_KEY")
+	env.duration(&cfg.MFAChallengeTTL, "MFA_CHALLENGE_TTL")
+	env.int(&cfg.MFAMaxAttempts, "MFA_MAX_ATTEMPTS")
+	env.uuids(&cfg.AdminUserIds, "ADMIN_USER_IDS")
+	env.string(&cfg.Blob.Store, "BLOB_STORE")
+	env.string(&cfg.Blob.Dir, "BLOB_DIR")
+	env.string(&cfg.SCIMToken, "SCIM_TOKEN")
+	env.duration(&cfg.OIDCKeyRotation, "OIDC_KEY_ROTATION")
+	env.bool(&cfg.EmailProviderRules, "EMAIL_PROVIDER_RULES")
+
+	if env.err != nil {
+		return Config{}, env.err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (cfg Config) Validate() error {
+	switch cfg.Store {
+	case StorePostgres, StoreSQLite, StoreMemory:
+	default:
+		return fmt.Errorf("unsupported store %q, must be %s, %s or %s", cfg.Store, StorePostgres, StoreSQLite, StoreMemory)
+	}
+
+	if cfg.Cache.Size > 0 && cfg.Cache.TTL <= 0 {
+		return errors.New("cache TTL must be positive")
+	}
+
+	switch cfg.Tracing.Exporter {
+	case TracingNone, TracingOTLP, TracingStdout, TracingFile:
+	default:
+		return fmt.Errorf("unsupported tracing exporter %q, must be %s, %s, %s or %s", cfg.Tracing.Exporter, TracingNone, TracingOTLP, TracingStdout, TracingFile)
+	}
+
+	switch cfg.Mail.Transport {
+	case MailFile:
+	case MailSMTP:
+		if cfg.Mail.SMTPAddr == "" {
+			return errors.New("the smtp mail transport needs an SMTP address")
+		}
+	default:
+		return fmt.Errorf("unsupported mail transport %q, must be %s or %s", cfg.Mail.Transport, MailFile, MailSMTP)
+	}
+
+	if publicURL, err := url.Parse(cfg.PublicURL); err != nil || !publicURL.IsAbs() {
+		return fmt.Errorf("public URL %q must be absolute", cfg.PublicURL)
+	}
+	if cfg.EmailVerificationTTL <= 0 {
+		return errors.New("email verification TTL must be positive")
+	}
+
+	switch cfg.SMS.Transport {
+	case SMSLog, SMSFile:
+	default:
+		return fmt.Errorf("unsupported SMS transport %q, must be %s or %s", cfg.SMS.Transport, SMSLog, SMSFile)
+	}
+	if cfg.PhoneVerificationTTL <= 0 {
+		return errors.New("phone verification TTL must be positive")
+	}
+	if cfg.PhoneVerificationMaxAttempts <= 0 {
+		return errors.New("phone verification attempts must be positive")
+	}
+	if cfg.PhoneDefaultRegion != "" && !phonenumber.ValidRegion(cfg.PhoneDefaultRegion) {
+		return fmt.Errorf("unsupported default phone region %q, must be a region code such as US", cfg.PhoneDefaultRegion)
+	}
+
+	if cfg.AccessTokenTTL <= 0 || cfg.SessionTTL <= 0 {
+		return errors.New("access token and session TTLs must be positive")
+	}
+	if cfg.PasswordResetTTL <= 0 {
+		return errors.New("password reset TTL must be positive")
+	}
+
+	if cfg.MFAIssuer == "" {
+		return errors.New("MFA issuer must not be empty")
+	}
+	if _, err := cfg.MFAKey(); err != nil {
+		return err
+	}
+	if cfg.MFAChallengeTTL <= 0 {
+		return errors.New("MFA challenge TTL must be positive")
+	}
+	if cfg.MFAMaxAttempts <= 0 {
+		return errors.New("MFA attempts must be positive")
+	}
+
+	switch cfg.Blob.Store {
+	case BlobDatabase:
+	case BlobFile:
+		if cfg.Blob.Dir == "" {
+			return errors.New("the file blob store needs a directory")
+		}
+	default:
+		return fmt.Errorf("unsupported blob store %q, must be %s or %s", cfg.Blob.Store, BlobDatabase, BlobFile)
+	}
+
+	// tokens must be verifiable until they expire, and keys are only kept
+	// for two rotations
+	if cfg.OIDCKeyRotation < cfg.AccessTokenTTL {
+		return errors.New("OIDC key rotation must not be shorter than the access token TTL")
+	}
+	return nil
+}
+
+// MFAKey decodes MFAEncryptionKey, returning nil if it is empty.
+func (cfg Config) MFAKey() ([]byte, error) {
+	if cfg.MFAEncryptionKey == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.MFAEncryptionKey)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("MFA encryption key must be 32 bytes encoded in base64")
+	}
+	return key, nil
+}
+
+// envLoader sets values from environment variables, keeping the first parse
+// error. Unset and empty variables leave the value alone.
+type envLoader struct {
+	err error
+}
+
+func (env *envLoader) lookup(key string) (string, bool) {
+	v, ok := os.LookupEnv(key)
+	return v, ok && v != "" && env.err == nil
+}
+
+func (env *envLoader) string(value *string, key string) {
+	if v, ok := env.lookup(key); ok {
+		*value = v
+	}
+}
+
+func (env *envLoader) int(value *int, key string) {
+	if v, ok := env.lookup(key); ok {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			env.err = fmt.Errorf("%s: %w", key, err)
+			return
+		}
+		*value = parsed
+	}
+}
+
+func (env *envLoader) duration(value *time.Duration, key string) {
+	if v, ok := env.lookup(key); ok {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			env.err = fmt.Errorf("%s: %w", key, err)
+			return
+		}
+		*value = parsed
+	}
+}
+
+func (env *envLoader) bool(value *bool, key string) {
+	if v, ok := env.lookup(key); ok {
+		parsed, err := strconv.ParseBool(v)
+		if err != nil {
+			env.err = fmt.Errorf("%s: %w", key, err)
+			return
+		}
+		*value = parsed
+	}
+}
+
+// uuids reads a comma separated list.
+func (env *envLoader) uuids(value *[]uuid.UUID, key string) {
+	if v, ok := env.lookup(key); ok {
+		var parsed []uuid.UUID
+		for _, field := range strings.Split(v, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(field))
+			if err != nil {
+				env.err = fmt.Errorf("%s: %w", key, err)
+				return
+			}
+			parsed = append(parsed, id)
+		}
+		*value = parsed
+	}
+}
+
+func (env *envLoader) level(value *slog.Level, key string) {
+	if v, ok := env.lookup(key); ok {
+		var parsed slog.Level
+		if err := parsed.UnmarshalText([]byte(v)); err != nil {
+			env.err = fmt.Errorf("%s: %w", key, err)
+			return
+		}
+		*value = parsed
+	}
+}
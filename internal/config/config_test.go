@@ -0,0 +1,286 @@
+package config
+
+import (
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Setenv("USER_STORE", "")
+	t.Setenv("HTTP_ADDR", "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Expected defaults, got %+v", cfg)
+	}
+}
+
+func TestLoad_FromEnv(t *testing.T) {
+	t.Setenv("USER_STORE", "memory")
+	t.Setenv("HTTP_ADDR", ":8081")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Store != StoreMemory {
+		t.Errorf("Expected memory store, got %s", cfg.Store)
+	}
+	if cfg.HTTPAddr != ":8081" {
+		t.Errorf("Expected HTTP address :8081, got %s", cfg.HTTPAddr)
+	}
+}
+
+func TestLoad_Cache(t *testing.T) {
+	t.Setenv("USER_CACHE_SIZE", "1000")
+	t.Setenv("USER_CACHE_TTL", "30s")
+	t.Setenv("USER_CACHE_PEER_INVALIDATION", "false")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	want := CacheConfig{Size: 1000, TTL: 30 * time.Second, NegativeTTL: Default().Cache.NegativeTTL}
+	if cfg.Cache != want {
+		t.Errorf("Expected %+v, got %+v", want, cfg.Cache)
+	}
+}
+
+func TestLoad_InvalidDuration(t *testing.T) {
+	t.Setenv("USER_CACHE_TTL", "soon")
+
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for an invalid duration")
+	}
+}
+
+func TestLoad_UnknownStore(t *testing.T) {
+	t.Setenv("USER_STORE", "mysql")
+
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for an unknown store")
+	}
+}
+
+func TestLoad_UnknownTracingExporter(t *testing.T) {
+	t.Setenv("TRACING_EXPORTER", "jaeger")
+
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for an unknown tracing exporter")
+	}
+}
+
+func TestLoad_LogLevel(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "debug")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.LogLevel != slog.LevelDebug {
+		t.Errorf("Expected level DEBUG, got %s", cfg.LogLevel)
+	}
+
+	t.Setenv("LOG_LEVEL", "loud")
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for an unknown log level")
+	}
+}
+
+func TestLoad_Mail(t *testing.T) {
+	t.Setenv("MAIL_TRANSPORT", "smtp")
+
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for the smtp transport without an address")
+	}
+
+	t.Setenv("SMTP_ADDR", "mail.example.com:587")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Mail.Transport != MailSMTP || cfg.Mail.SMTPAddr != "mail.example.com:587" {
+		t.Errorf("Expected the smtp transport at mail.example.com:587, got %+v", cfg.Mail)
+	}
+}
+
+func TestLoad_RelativePublicURL(t *testing.T) {
+	t.Setenv("PUBLIC_URL", "example.com")
+
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for a relative public URL")
+	}
+}
+
+func TestLoad_PhoneVerification(t *testing.T) {
+	t.Setenv("SMS_TRANSPORT", "file")
+	t.Setenv("PHONE_VERIFICATION_MAX_ATTEMPTS", "3")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.SMS.Transport != SMSFile {
+		t.Errorf("Expected the file SMS transport, got %s", cfg.SMS.Transport)
+	}
+	if cfg.PhoneVerificationMaxAttempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", cfg.PhoneVerificationMaxAttempts)
+	}
+
+	t.Setenv("SMS_TRANSPORT", "twilio")
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for an unknown SMS transport")
+	}
+}
+
+func TestLoad_PhoneDefaultRegion(t *testing.T) {
+	t.Setenv("PHONE_DEFAULT_REGION", "LK")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.PhoneDefaultRegion != "LK" {
+		t.Errorf("Expected the LK region, got %q", cfg.PhoneDefaultRegion)
+	}
+
+	t.Setenv("PHONE_DEFAULT_REGION", "Lanka")
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for an unknown region")
+	}
+}
+
+func TestLoad_Blob(t *testing.T) {
+	t.Setenv("BLOB_STORE", "file")
+	t.Setenv("BLOB_DIR", "/var/lib/usermgmt/blobs")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Blob.Store != BlobFile || cfg.Blob.Dir != "/var/lib/usermgmt/blobs" {
+		t.Errorf("Expected the file blob store under /var/lib/usermgmt/blobs, got %+v", cfg.Blob)
+	}
+
+	t.Setenv("BLOB_STORE", "s3")
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for an unknown blob store")
+	}
+}
+
+func TestLoad_SCIM(t *testing.T) {
+	if cfg := Default(); cfg.SCIMToken != "" {
+		t.Errorf("Expected SCIM to be off by default, got token %q", cfg.SCIMToken)
+	}
+
+	t.Setenv("SCIM_TOKEN", "provisioning-token")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.SCIMToken != "provisioning-token" {
+		t.Errorf("Expected the SCIM token from SCIM_TOKEN, got %q", cfg.SCIMToken)
+	}
+}
+
+func TestLoad_OIDC(t *testing.T) {
+	t.Setenv("OIDC_KEY_ROTATION", "168h")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.OIDCKeyRotation != 7*24*time.Hour {
+		t.Errorf("Expected keys to rotate weekly, got %s", cfg.OIDCKeyRotation)
+	}
+
+	t.Setenv("OIDC_KEY_ROTATION", "1m")
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for keys rotating before access tokens expire")
+	}
+}
+
+func TestLoad_Auth(t *testing.T) {
+	t.Setenv("ACCESS_TOKEN_TTL", "5m")
+	t.Setenv("PASSWORD_RESET_TTL", "30m")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.AccessTokenTTL != 5*time.Minute || cfg.PasswordResetTTL != 30*time.Minute {
+		t.Errorf("Expected 5m access tokens and 30m resets, got %s and %s", cfg.AccessTokenTTL, cfg.PasswordResetTTL)
+	}
+	if cfg.SessionTTL != Default().SessionTTL {
+		t.Errorf("Expected the default session TTL, got %s", cfg.SessionTTL)
+	}
+
+	t.Setenv("SESSION_TTL", "0s")
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for a zero session TTL")
+	}
+}
+
+func TestLoad_MFA(t *testing.T) {
+	t.Setenv("MFA_ENCRYPTION_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	t.Setenv("MFA_MAX_ATTEMPTS", "3")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	key, err := cfg.MFAKey()
+	if err != nil {
+		t.Fatalf("MFAKey failed: %v", err)
+	}
+	if string(key) != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("Expected the decoded key, got %q", key)
+	}
+	if cfg.MFAMaxAttempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", cfg.MFAMaxAttempts)
+	}
+
+	t.Setenv("MFA_ENCRYPTION_KEY", "c2hvcnQ=")
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for a short MFA encryption key")
+	}
+}
+
+func TestLoad_Admins(t *testing.T) {
+	t.Setenv("ADMIN_USER_IDS", "6f1c2a4e-3b5d-4c7e-9f8a-1b2c3d4e5f60, 0a9b8c7d-6e5f-4a3b-8c2d-1e0f9a8b7c6d")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.AdminUserIds) != 2 || cfg.AdminUserIds[1].String() != "0a9b8c7d-6e5f-4a3b-8c2d-1e0f9a8b7c6d" {
+		t.Errorf("Expected both admins from ADMIN_USER_IDS, got %v", cfg.AdminUserIds)
+	}
+
+	t.Setenv("ADMIN_USER_IDS", "alice")
+	if _, err := Load(); err == nil {
+		t.Errorf("Expected an error for an admin that is not a user id")
+	}
+}
+
+func TestLoad_EmailProviderRules(t *testing.T) {
+	if cfg, err := Load(); err != nil || cfg.EmailProviderRules {
+		t.Fatalf("Expected provider rules to be off by default, got %v, %v", cfg.EmailProviderRules, err)
+	}
+
+	t.Setenv("EMAIL_PROVIDER_RULES", "true")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if !cfg.EmailProviderRules {
+		t.Errorf("Expected provider rules from EMAIL_PROVIDER_RULES")
+	}
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"example.com/user-management/internal/emailaddr"
+	"github.com/pressly/goose/v3"
+)
+
+// ErrEmailRulesMismatch is returned when a process is configured with other
+// email rules than those the stored addresses are keyed by, which would let
+// two users share a mailbox.
+var ErrEmailRulesMismatch = errors.New("email provider rules do not match the database")
+
+// recordEmailRulesMigration keys every address anew by rules and records
+// them, for CheckEmailRules. The migration normalizing emails did not record
+// the rules it keyed addresses by, so they may not be the ones in use.
+func recordEmailRulesMigration(rules emailaddr.Rules) *goose.Migration {
+	migration := goose.NewGoMigration(14,
+		&goose.GoFunc{RunTx: func(ctx context.Context, tx *sql.Tx) error {
+			// one row, of the rules addresses are keyed by
+			if _, err := tx.ExecContext(ctx, `
+				CREATE TABLE email_rules (
+				    singleton  BOOLEAN PRIMARY KEY DEFAULT true CHECK (singleton),
+				    providers  BOOLEAN NOT NULL
+				)`); err != nil {
+				return err
+			}
+			return rekeyEmails(ctx, tx, rules)
+		}},
+		&goose.GoFunc{RunTx: func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `DROP TABLE email_rules`)
+			return err
+		}},
+	)
+	migration.Source = "00014_record_email_rules.go"
+	return migration
+}
+
+// CheckEmailRules returns ErrEmailRulesMismatch unless the addresses in the
+// database are keyed by rules.
+func CheckEmailRules(ctx context.Context, dbConn *sql.DB, rules emailaddr.Rules) error {
+	var providers bool
+	if err := dbConn.QueryRowContext(ctx, `SELECT providers FROM email_rules`).Scan(&providers); err != nil {
+		return fmt.Errorf("failed to read the email rules of the database: %w", err)
+	}
+	return CompareEmailRules(emailaddr.Rules{Providers: providers}, rules)
+}
+
+// CompareEmailRules returns ErrEmailRulesMismatch unless configured are the
+// rules stored in a database.
+func CompareEmailRules(stored, configured emailaddr.Rules) error {
+	if stored == configured {
+		return nil
+	}
+	return fmt.Errorf("%w: addresses are keyed with provider rules %s, not %s; "+
+		"configure those or rekey the addresses with usermgmt migrate rekey-emails",
+		ErrEmailRulesMismatch, onOff(stored.Providers), onOff(configured.Providers))
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// RekeyEmails keys every address anew by rules, which the servers and the
+// CLI must then be configured with, and records them. It fails, changing
+// nothing, if users of a tenant would share a mailbox. Like the migrations,
+// it must run as the owner of the users table.
+func RekeyEmails(ctx context.Context, dbConn *sql.DB, rules emailaddr.Rules) error {
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := rekeyEmails(ctx, tx, rules); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func rekeyEmails(ctx context.Context, tx *sql.Tx, rules emailaddr.Rules) error {
+	if err := checkOwnsUsers(ctx, tx, "rekeying emails"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE users NO FORCE ROW LEVEL SECURITY`); err != nil {
+		return err
+	}
+
+	owners, err := readEmailOwners(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := EmailCollisions(owners, rules); err != nil {
+		return err
+	}
+
+	// clear the old keys first, so that none collides with a new one while
+	// the others are being rewritten
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET email_key = user_id::text`); err != nil {
+		return err
+	}
+	for _, owner := range owners {
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET email_key = $1 WHERE user_id = $2`,
+			rules.Key(owner.Email), owner.UserID); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO email_rules (providers) VALUES ($1)
+		ON CONFLICT (singleton) DO UPDATE SET providers = EXCLUDED.providers`, rules.Providers); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `ALTER TABLE users FORCE ROW LEVEL SECURITY`)
+	return err
+}
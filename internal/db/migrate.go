@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"io/fs"
+
+	"example.com/user-management/internal/emailaddr"
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// newMigrationProvider returns the provider of the migrations. rules are how
+// the migrations normalizing emails and recording their rules key addresses,
+// which only matters when applying them.
+func newMigrationProvider(dbConn *sql.DB, rules emailaddr.Rules) (*goose.Provider, error) {
+	// goose looks for migration files at the root of the file system
+	migrationFiles, err := fs.Sub(migrations, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return goose.NewProvider(goose.DialectPostgres, dbConn, migrationFiles,
+		goose.WithDisableGlobalRegistry(true),
+		goose.WithGoMigrations(normalizeEmailsMigration(rules), recordEmailRulesMigration(rules)),
+	)
+}
+
+// MigrateUp applies every pending migration, keying email addresses by
+// rules, which must be the rules the user store is given.
+func MigrateUp(dbConn *sql.DB, rules emailaddr.Rules) ([]*goose.MigrationResult, error) {
+	provider, err := newMigrationProvider(dbConn, rules)
+	if err != nil {
+		return nil, err
+	}
+	return provider.Up(context.Background())
+}
+
+// MigrateDown rolls back the most recently applied migration.
+func MigrateDown(dbConn *sql.DB) (*goose.MigrationResult, error) {
+	provider, err := newMigrationProvider(dbConn, emailaddr.Rules{})
+	if err != nil {
+		return nil, err
+	}
+	return provider.Down(context.Background())
+}
+
+func MigrationStatus(dbConn *sql.DB) ([]*goose.MigrationStatus, error) {
+	provider, err := newMigrationProvider(dbConn, emailaddr.Rules{})
+	if err != nil {
+		return nil, err
+	}
+	return provider.Status(context.Background())
+}
+
+// HasPendingMigrations reports whether any migration has not been applied.
+func HasPendingMigrations(ctx context.Context, dbConn *sql.DB) (bool, error) {
+	provider, err := newMigrationProvider(dbConn, emailaddr.Rules{})
+	if err != nil {
+		return false, err
+	}
+	return provider.HasPending(ctx)
+}
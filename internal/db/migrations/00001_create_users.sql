@@ -1,3 +1,4 @@
+-- +goose Up
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 CREATE TABLE users (
@@ -10,3 +11,6 @@ CREATE TABLE users (
     status      TEXT NOT NULL DEFAULT 'Active' CHECK (status IN ('Active', 'Inactive')),
     created_at  TIMESTAMP NOT NULL DEFAULT now()
 );
+
+-- +goose Down
+DROP TABLE users;
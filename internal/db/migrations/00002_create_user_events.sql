@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE user_events (
+    event_id    BIGSERIAL PRIMARY KEY,
+    event_type  TEXT NOT NULL CHECK (event_type IN ('user.created', 'user.updated', 'user.deleted')),
+    user_id     UUID NOT NULL,
+    payload     JSONB NOT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX user_events_user_id_idx ON user_events (user_id);
+
+-- +goose Down
+DROP TABLE user_events;
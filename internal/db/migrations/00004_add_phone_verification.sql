@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN phone_verified_at TIMESTAMPTZ;
+
+-- at most one outstanding code per user, replaced when a new one is sent
+CREATE TABLE phone_verifications (
+    user_id     UUID PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    phone       TEXT NOT NULL,
+    code_hash   BYTEA NOT NULL,
+    attempts    INT NOT NULL DEFAULT 0,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    sent_at     TIMESTAMPTZ NOT NULL
+);
+
+-- +goose Down
+DROP TABLE phone_verifications;
+
+ALTER TABLE users DROP COLUMN phone_verified_at;
@@ -0,0 +1,47 @@
+-- +goose Up
+CREATE TABLE user_credentials (
+    user_id        UUID PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    password_hash  TEXT NOT NULL,
+    updated_at     TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE sessions (
+    session_id          UUID PRIMARY KEY,
+    user_id             UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    refresh_token_hash  BYTEA NOT NULL UNIQUE,
+    created_at          TIMESTAMPTZ NOT NULL,
+    expires_at          TIMESTAMPTZ NOT NULL,
+    revoked_at          TIMESTAMPTZ
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+CREATE TABLE password_resets (
+    token_id    UUID PRIMARY KEY,
+    user_id     UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX password_resets_user_id_idx ON password_resets (user_id);
+
+-- outlives the users it mentions, so user_id is not a foreign key
+CREATE TABLE audit_events (
+    audit_id    BIGSERIAL PRIMARY KEY,
+    user_id     UUID,
+    action      TEXT NOT NULL,
+    detail      JSONB NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX audit_events_user_id_idx ON audit_events (user_id, audit_id);
+
+-- +goose Down
+DROP TABLE audit_events;
+
+DROP TABLE password_resets;
+
+DROP TABLE sessions;
+
+DROP TABLE user_credentials;
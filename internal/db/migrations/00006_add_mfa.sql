@@ -0,0 +1,34 @@
+-- +goose Up
+-- the secret is encrypted by the application; the factor only guards logins
+-- once the user has confirmed it with a code
+CREATE TABLE totp_factors (
+    user_id            UUID PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    secret_ciphertext  BYTEA NOT NULL,
+    last_used_step     BIGINT NOT NULL DEFAULT 0,
+    created_at         TIMESTAMPTZ NOT NULL,
+    confirmed_at       TIMESTAMPTZ
+);
+
+CREATE TABLE recovery_codes (
+    user_id    UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    code_hash  BYTEA NOT NULL,
+    used_at    TIMESTAMPTZ,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+-- a login that passed the password check and waits for a second factor
+CREATE TABLE mfa_challenges (
+    challenge_id  UUID PRIMARY KEY,
+    user_id       UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    attempts      INT NOT NULL DEFAULT 0,
+    expires_at    TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX mfa_challenges_user_id_idx ON mfa_challenges (user_id);
+
+-- +goose Down
+DROP TABLE mfa_challenges;
+
+DROP TABLE recovery_codes;
+
+DROP TABLE totp_factors;
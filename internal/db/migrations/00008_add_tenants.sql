@@ -0,0 +1,233 @@
+-- +goose Up
+-- the tenant a transaction acts for, set by the application with
+-- set_config('app.tenant_id', ..., true); nothing is visible without one
+-- +goose StatementBegin
+CREATE FUNCTION current_tenant_id() RETURNS UUID
+    LANGUAGE sql STABLE
+    AS $$ SELECT NULLIF(current_setting('app.tenant_id', true), '')::uuid $$;
+-- +goose StatementEnd
+
+-- system work, such as metrics and relaying change events, reads every tenant
+-- +goose StatementBegin
+CREATE FUNCTION all_tenants() RETURNS BOOLEAN
+    LANGUAGE sql STABLE
+    AS $$ SELECT coalesce(current_setting('app.all_tenants', true), '') = 'on' $$;
+-- +goose StatementEnd
+
+-- superusers and table owners bypass row level security, so the application
+-- switches to this role inside every transaction whatever it connects as
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'app_tenant') THEN
+        CREATE ROLE app_tenant NOLOGIN;
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+
+GRANT app_tenant TO CURRENT_USER;
+GRANT USAGE ON SCHEMA public TO app_tenant;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO app_tenant;
+GRANT USAGE ON ALL SEQUENCES IN SCHEMA public TO app_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO app_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE ON SEQUENCES TO app_tenant;
+
+-- existing rows belong to the default tenant, new ones to the current tenant
+ALTER TABLE users ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE user_events ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE email_verifications ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE phone_verifications ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE user_credentials ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE sessions ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE password_resets ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE audit_events ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE totp_factors ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE recovery_codes ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE mfa_challenges ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE groups ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+ALTER TABLE group_members ADD COLUMN tenant_id UUID NOT NULL DEFAULT '00000000-0000-0000-0000-000000000000';
+
+ALTER TABLE users ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE user_events ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE email_verifications ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE phone_verifications ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE user_credentials ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE sessions ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE password_resets ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE audit_events ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE totp_factors ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE recovery_codes ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE mfa_challenges ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE groups ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+ALTER TABLE group_members ALTER COLUMN tenant_id SET DEFAULT current_tenant_id();
+
+-- FORCE applies the policies to the table owner as well
+ALTER TABLE users ENABLE ROW LEVEL SECURITY;
+ALTER TABLE users FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON users
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE user_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_events FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_events
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE email_verifications ENABLE ROW LEVEL SECURITY;
+ALTER TABLE email_verifications FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON email_verifications
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE phone_verifications ENABLE ROW LEVEL SECURITY;
+ALTER TABLE phone_verifications FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON phone_verifications
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE user_credentials ENABLE ROW LEVEL SECURITY;
+ALTER TABLE user_credentials FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON user_credentials
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE sessions ENABLE ROW LEVEL SECURITY;
+ALTER TABLE sessions FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON sessions
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE password_resets ENABLE ROW LEVEL SECURITY;
+ALTER TABLE password_resets FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON password_resets
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE audit_events ENABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_events FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON audit_events
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE totp_factors ENABLE ROW LEVEL SECURITY;
+ALTER TABLE totp_factors FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON totp_factors
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE recovery_codes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE recovery_codes FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON recovery_codes
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE mfa_challenges ENABLE ROW LEVEL SECURITY;
+ALTER TABLE mfa_challenges FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON mfa_challenges
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE groups ENABLE ROW LEVEL SECURITY;
+ALTER TABLE groups FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON groups
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE group_members ENABLE ROW LEVEL SECURITY;
+ALTER TABLE group_members FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON group_members
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+-- addresses and group names only need to be unique within a tenant
+ALTER TABLE users DROP CONSTRAINT users_email_key;
+ALTER TABLE users ADD CONSTRAINT users_tenant_id_email_key UNIQUE (tenant_id, email);
+
+ALTER TABLE groups DROP CONSTRAINT groups_name_key;
+ALTER TABLE groups ADD CONSTRAINT groups_tenant_id_name_key UNIQUE (tenant_id, name);
+
+-- +goose Down
+-- fails if two tenants share an address or a group name
+ALTER TABLE groups DROP CONSTRAINT groups_tenant_id_name_key;
+ALTER TABLE groups ADD CONSTRAINT groups_name_key UNIQUE (name);
+
+ALTER TABLE users DROP CONSTRAINT users_tenant_id_email_key;
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+
+DROP POLICY tenant_isolation ON group_members;
+ALTER TABLE group_members NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE group_members DISABLE ROW LEVEL SECURITY;
+ALTER TABLE group_members DROP COLUMN tenant_id;
+
+DROP POLICY tenant_isolation ON groups;
+ALTER TABLE groups NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE groups DISABLE ROW LEVEL SECURITY;
+ALTER TABLE groups DROP COLUMN tenant_id;
+
+DROP POLICY tenant_isolation ON mfa_challenges;
+ALTER TABLE mfa_challenges NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE mfa_challenges DISABLE ROW LEVEL SECURITY;
+ALTER TABLE mfa_challenges DROP COLUMN tenant_id;
+
+DROP POLICY tenant_isolation ON recovery_codes;
+ALTER TABLE recovery_codes NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE recovery_codes DISABLE ROW LEVEL SECURITY;
+ALTER TABLE recovery_codes DROP COLUMN tenant_id;
+
+DROP POLICY tenant_isolation ON totp_factors;
+ALTER TABLE totp_factors NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE totp_factors DISABLE ROW LEVEL SECURITY;
+ALTER TABLE totp_factors DROP COLUMN tenant_id;
+
+DROP POLICY tenant_isolation ON audit_events;
+ALTER TABLE audit_events NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE audit_events DISABLE ROW LEVEL SECURITY;
+ALTER TABLE audit_events DROP COLUMN tenant_id;
+
+DROP POLICY tenant_isolation ON password_resets;
+ALTER TABLE password_resets NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE password_resets DISABLE ROW LEVEL SECURITY;
+ALTER TABLE password_resets DROP COLUMN tenant_id;
+
+DROP POLICY tenant_isolation ON sessions;
+ALTER TABLE sessions NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE sessions DISABLE ROW LEVEL SECURITY;
+ALTER TABLE sessions DROP COLUMN tenant_id;
+
+DROP POLICY tenant_isolation ON user_credentials;
+ALTER TABLE user_credentials NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE user_credentials DISABLE ROW LEVEL SECURITY;
+ALTER TABLE user_credentials DROP COLUMN tenant_id;
+
+DROP POLICY tenant_isolation ON phone_verifications;
+ALTER TABLE phone_verifications NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE phone_verifications DISABLE ROW LEVEL SECURITY;
+ALTER TABLE phone_verifications DROP COLUMN tenant_id;
+
+DROP POLICY tenant_isolation ON email_verifications;
+ALTER TABLE email_verifications NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE email_verifications DISABLE ROW LEVEL SECURITY;
+ALTER TABLE email_verifications DROP COLUMN tenant_id;
+
+DROP POLICY tenant_isolation ON user_events;
+ALTER TABLE user_events NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE user_events DISABLE ROW LEVEL SECURITY;
+ALTER TABLE user_events DROP COLUMN tenant_id;
+
+DROP POLICY tenant_isolation ON users;
+ALTER TABLE users NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE users DISABLE ROW LEVEL SECURITY;
+ALTER TABLE users DROP COLUMN tenant_id;
+
+-- the role is shared by every database in the cluster, so it is left in place
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE USAGE ON SEQUENCES FROM app_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public REVOKE SELECT, INSERT, UPDATE, DELETE ON TABLES FROM app_tenant;
+REVOKE USAGE ON ALL SEQUENCES IN SCHEMA public FROM app_tenant;
+REVOKE SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public FROM app_tenant;
+REVOKE USAGE ON SCHEMA public FROM app_tenant;
+
+DROP FUNCTION all_tenants();
+
+DROP FUNCTION current_tenant_id();
@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}';
+
+-- jsonb_path_ops serves the containment (@>) that filtering by attribute uses
+CREATE INDEX users_attributes_idx ON users USING GIN (attributes jsonb_path_ops);
+
+-- each tenant has at most one schema for the attributes of its users
+CREATE TABLE attribute_schemas (
+    tenant_id   UUID PRIMARY KEY DEFAULT current_tenant_id(),
+    schema      JSONB NOT NULL,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE attribute_schemas ENABLE ROW LEVEL SECURITY;
+ALTER TABLE attribute_schemas FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON attribute_schemas
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+-- +goose Down
+DROP TABLE attribute_schemas;
+
+DROP INDEX users_attributes_idx;
+
+ALTER TABLE users DROP COLUMN attributes;
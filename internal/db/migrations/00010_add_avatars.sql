@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '';
+
+-- blobs holds files, such as avatar images, for deployments that keep them
+-- in the database rather than on disk
+CREATE TABLE blobs (
+    tenant_id     UUID NOT NULL DEFAULT current_tenant_id(),
+    key           TEXT NOT NULL,
+    content_type  TEXT NOT NULL,
+    data          BYTEA NOT NULL,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (tenant_id, key)
+);
+
+ALTER TABLE blobs ENABLE ROW LEVEL SECURITY;
+ALTER TABLE blobs FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON blobs
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+-- +goose Down
+DROP TABLE blobs;
+
+ALTER TABLE users DROP COLUMN avatar_url;
@@ -0,0 +1,90 @@
+-- +goose Up
+-- oauth_clients are the applications users sign in to through the OpenID
+-- Connect provider
+CREATE TABLE oauth_clients (
+    tenant_id      UUID NOT NULL DEFAULT current_tenant_id(),
+    client_id      UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name           TEXT NOT NULL,
+    -- null for public clients, such as single page apps, which cannot keep
+    -- a secret
+    secret_hash    BYTEA,
+    redirect_uris  JSONB NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE oauth_consents (
+    tenant_id   UUID NOT NULL DEFAULT current_tenant_id(),
+    user_id     UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    client_id   UUID NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+    scope       TEXT NOT NULL,
+    granted_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, client_id)
+);
+
+CREATE INDEX oauth_consents_client_id_idx ON oauth_consents (client_id);
+
+CREATE TABLE oauth_authorization_codes (
+    tenant_id       UUID NOT NULL DEFAULT current_tenant_id(),
+    code_id         UUID PRIMARY KEY,
+    client_id       UUID NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+    user_id         UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    redirect_uri    TEXT NOT NULL,
+    scope           TEXT NOT NULL,
+    nonce           TEXT NOT NULL,
+    code_challenge  TEXT NOT NULL,
+    expires_at      TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE oauth_refresh_tokens (
+    tenant_id   UUID NOT NULL DEFAULT current_tenant_id(),
+    token_id    UUID PRIMARY KEY,
+    client_id   UUID NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+    user_id     UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    scope       TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX oauth_refresh_tokens_user_id_idx ON oauth_refresh_tokens (user_id);
+
+-- the provider has one issuer, so its signing keys are shared by every tenant
+CREATE TABLE oidc_signing_keys (
+    key_id                  TEXT PRIMARY KEY,
+    private_key_ciphertext  BYTEA NOT NULL,
+    created_at              TIMESTAMPTZ NOT NULL
+);
+
+ALTER TABLE oauth_clients ENABLE ROW LEVEL SECURITY;
+ALTER TABLE oauth_clients FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON oauth_clients
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE oauth_consents ENABLE ROW LEVEL SECURITY;
+ALTER TABLE oauth_consents FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON oauth_consents
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE oauth_authorization_codes ENABLE ROW LEVEL SECURITY;
+ALTER TABLE oauth_authorization_codes FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON oauth_authorization_codes
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+ALTER TABLE oauth_refresh_tokens ENABLE ROW LEVEL SECURITY;
+ALTER TABLE oauth_refresh_tokens FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON oauth_refresh_tokens
+    USING (tenant_id = current_tenant_id() OR all_tenants())
+    WITH CHECK (tenant_id = current_tenant_id());
+
+-- +goose Down
+DROP TABLE oidc_signing_keys;
+
+DROP TABLE oauth_refresh_tokens;
+
+DROP TABLE oauth_authorization_codes;
+
+DROP TABLE oauth_consents;
+
+DROP TABLE oauth_clients;
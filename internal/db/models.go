@@ -6,18 +6,188 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-type User struct {
+type AttributeSchema struct {
+	TenantID  uuid.UUID
+	Schema    json.RawMessage
+	UpdatedAt time.Time
+}
+
+type AuditEvent struct {
+	AuditID   int64
+	UserID    uuid.NullUUID
+	Action    string
+	Detail    json.RawMessage
+	CreatedAt time.Time
+	TenantID  uuid.UUID
+}
+
+type Blob struct {
+	TenantID    uuid.UUID
+	Key         string
+	ContentType string
+	Data        []byte
+	UpdatedAt   time.Time
+}
+
+type EmailVerification struct {
+	TokenID   uuid.UUID
 	UserID    uuid.UUID
-	FirstName string
-	LastName  string
 	Email     string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+	TenantID  uuid.UUID
+}
+
+type Group struct {
+	GroupID     uuid.UUID
+	Name        string
+	Description string
+	CreatedAt   time.Time
+	TenantID    uuid.UUID
+}
+
+type GroupMember struct {
+	GroupID  uuid.UUID
+	UserID   uuid.UUID
+	AddedAt  time.Time
+	TenantID uuid.UUID
+}
+
+type MfaChallenge struct {
+	ChallengeID uuid.UUID
+	UserID      uuid.UUID
+	Attempts    int32
+	ExpiresAt   time.Time
+	TenantID    uuid.UUID
+}
+
+type OauthAuthorizationCode struct {
+	TenantID      uuid.UUID
+	CodeID        uuid.UUID
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scope         string
+	Nonce         string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+type OauthClient struct {
+	TenantID     uuid.UUID
+	ClientID     uuid.UUID
+	Name         string
+	SecretHash   []byte
+	RedirectUris json.RawMessage
+	CreatedAt    time.Time
+}
+
+type OauthConsent struct {
+	TenantID  uuid.UUID
+	UserID    uuid.UUID
+	ClientID  uuid.UUID
+	Scope     string
+	GrantedAt time.Time
+}
+
+type OauthRefreshToken struct {
+	TenantID  uuid.UUID
+	TokenID   uuid.UUID
+	ClientID  uuid.UUID
+	UserID    uuid.UUID
+	Scope     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type OidcSigningKey struct {
+	KeyID                string
+	PrivateKeyCiphertext []byte
+	CreatedAt            time.Time
+}
+
+type PasswordReset struct {
+	TokenID   uuid.UUID
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+	TenantID  uuid.UUID
+}
+
+type PhoneVerification struct {
+	UserID    uuid.UUID
 	Phone     string
-	Age       sql.NullInt32
-	Status    string
+	CodeHash  []byte
+	Attempts  int32
+	ExpiresAt time.Time
+	SentAt    time.Time
+	TenantID  uuid.UUID
+}
+
+type RecoveryCode struct {
+	UserID   uuid.UUID
+	CodeHash []byte
+	UsedAt   sql.NullTime
+	TenantID uuid.UUID
+}
+
+type Session struct {
+	SessionID        uuid.UUID
+	UserID           uuid.UUID
+	RefreshTokenHash []byte
+	CreatedAt        time.Time
+	ExpiresAt        time.Time
+	RevokedAt        sql.NullTime
+	TenantID         uuid.UUID
+	Mfa              bool
+}
+
+type TotpFactor struct {
+	UserID           uuid.UUID
+	SecretCiphertext []byte
+	LastUsedStep     int64
+	CreatedAt        time.Time
+	ConfirmedAt      sql.NullTime
+	TenantID         uuid.UUID
+}
+
+type User struct {
+	UserID          uuid.UUID
+	FirstName       string
+	LastName        string
+	Email           string
+	Phone           string
+	Age             sql.NullInt32
+	Status          string
+	CreatedAt       time.Time
+	EmailVerifiedAt sql.NullTime
+	PhoneVerifiedAt sql.NullTime
+	TenantID        uuid.UUID
+	Attributes      json.RawMessage
+	AvatarUrl       string
+	EmailKey        string
+}
+
+type UserCredential struct {
+	UserID       uuid.UUID
+	PasswordHash string
+	UpdatedAt    time.Time
+	TenantID     uuid.UUID
+}
+
+type UserEvent struct {
+	EventID   int64
+	EventType string
+	UserID    uuid.UUID
+	Payload   json.RawMessage
 	CreatedAt time.Time
+	TenantID  uuid.UUID
 }
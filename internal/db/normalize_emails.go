@@ -0,0 +1,165 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"example.com/user-management/internal/emailaddr"
+	"github.com/pressly/goose/v3"
+)
+
+// normalizeEmailsMigration makes addresses unique by the mailbox they reach,
+// under rules, rather than by how they were written. Postgres cannot convert
+// domains to punycode, so unlike the others this migration is written in Go.
+//
+// The backfill has to see the users of every tenant, so the migration must
+// run as the owner of the users table, or a member of the owning role: row
+// level security exempts only them, and only while it is not forced.
+func normalizeEmailsMigration(rules emailaddr.Rules) *goose.Migration {
+	migration := goose.NewGoMigration(12,
+		&goose.GoFunc{RunTx: func(ctx context.Context, tx *sql.Tx) error {
+			return normalizeEmailsUp(ctx, tx, rules)
+		}},
+		&goose.GoFunc{RunTx: normalizeEmailsDown},
+	)
+	migration.Source = "00012_normalize_emails.go"
+	return migration
+}
+
+// EmailOwner is a user holding an address, as the migrations normalizing
+// addresses read it.
+type EmailOwner struct {
+	UserID string
+	// TenantID is empty for stores without tenants.
+	TenantID string
+	Email    string
+}
+
+func normalizeEmailsUp(ctx context.Context, tx *sql.Tx, rules emailaddr.Rules) error {
+	if err := checkOwnsUsers(ctx, tx, "normalizing emails"); err != nil {
+		return err
+	}
+
+	// the owner of the table is only exempt from row level security if it
+	// is not forced
+	if _, err := tx.ExecContext(ctx, `
+		ALTER TABLE users ADD COLUMN email_key TEXT;
+		ALTER TABLE users NO FORCE ROW LEVEL SECURITY`); err != nil {
+		return err
+	}
+
+	owners, err := readEmailOwners(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := EmailCollisions(owners, rules); err != nil {
+		return err
+	}
+
+	for _, owner := range owners {
+		email := owner.Email
+		if normalized, err := emailaddr.Normalize(email); err == nil {
+			email = normalized
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET email = $1, email_key = $2 WHERE user_id = $3`,
+			email, rules.Key(email), owner.UserID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		ALTER TABLE users ALTER COLUMN email_key SET NOT NULL;
+		ALTER TABLE users DROP CONSTRAINT users_tenant_id_email_key;
+		ALTER TABLE users ADD CONSTRAINT users_tenant_id_email_key_key UNIQUE (tenant_id, email_key);
+		ALTER TABLE users FORCE ROW LEVEL SECURITY`)
+	return err
+}
+
+// checkOwnsUsers returns an error naming the owner of the users table unless
+// tx runs as it, or as a member of the owning role. Any other role only sees
+// the users its tenant setting allows, and doing would miss the rest.
+func checkOwnsUsers(ctx context.Context, tx *sql.Tx, doing string) error {
+	var owner, currentUser string
+	var owns bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT pg_get_userbyid(relowner), current_user, pg_has_role(current_user, relowner, 'USAGE')
+		FROM pg_class WHERE oid = 'users'::regclass`).Scan(&owner, &currentUser, &owns); err != nil {
+		return err
+	}
+	if !owns {
+		return fmt.Errorf("%s must run as %s, the owner of the users table, "+
+			"to see the users of every tenant, not as %s", doing, owner, currentUser)
+	}
+	return nil
+}
+
+// readEmailOwners returns the users of every tenant, oldest first. Row level
+// security must not apply to tx.
+func readEmailOwners(ctx context.Context, tx *sql.Tx) ([]EmailOwner, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT user_id, tenant_id, email FROM users ORDER BY created_at, user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var owners []EmailOwner
+	for rows.Next() {
+		var owner EmailOwner
+		if err := rows.Scan(&owner.UserID, &owner.TenantID, &owner.Email); err != nil {
+			return nil, err
+		}
+		owners = append(owners, owner)
+	}
+	return owners, rows.Err()
+}
+
+func normalizeEmailsDown(ctx context.Context, tx *sql.Tx) error {
+	// addresses stay normalized, which the old constraint accepts
+	_, err := tx.ExecContext(ctx, `
+		ALTER TABLE users DROP CONSTRAINT users_tenant_id_email_key_key;
+		ALTER TABLE users ADD CONSTRAINT users_tenant_id_email_key UNIQUE (tenant_id, email);
+		ALTER TABLE users DROP COLUMN email_key`)
+	return err
+}
+
+// EmailCollisions returns an error listing the users of a tenant whose
+// addresses reach the same mailbox under rules, which have to be merged or
+// given other addresses before a migration can make them unique.
+func EmailCollisions(owners []EmailOwner, rules emailaddr.Rules) error {
+	type mailbox struct{ tenantID, key string }
+	var mailboxes []mailbox
+	byMailbox := make(map[mailbox][]EmailOwner)
+	for _, owner := range owners {
+		m := mailbox{owner.TenantID, rules.Key(owner.Email)}
+		if _, ok := byMailbox[m]; !ok {
+			mailboxes = append(mailboxes, m)
+		}
+		byMailbox[m] = append(byMailbox[m], owner)
+	}
+
+	var report strings.Builder
+	for _, m := range mailboxes {
+		holders := byMailbox[m]
+		if len(holders) < 2 {
+			continue
+		}
+		report.WriteString("\n\t")
+		if m.tenantID != "" {
+			fmt.Fprintf(&report, "tenant %s, ", m.tenantID)
+		}
+		fmt.Fprintf(&report, "%s:", m.key)
+		for i, owner := range holders {
+			if i > 0 {
+				report.WriteString(",")
+			}
+			fmt.Fprintf(&report, " %s (%s)", owner.Email, owner.UserID)
+		}
+	}
+	if report.Len() > 0 {
+		return fmt.Errorf("users share email addresses once they are normalized; "+
+			"change the address of all but one user of each and migrate again:%s", report.String())
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+-- name: GetAttributeSchema :one
+SELECT * FROM attribute_schemas
+WHERE tenant_id = current_tenant_id();
+
+-- name: SetAttributeSchema :one
+INSERT INTO attribute_schemas (
+    schema
+) VALUES (
+             $1
+)
+ON CONFLICT (tenant_id) DO UPDATE
+SET schema = EXCLUDED.schema,
+    updated_at = now()
+RETURNING *;
+
+-- name: DeleteAttributeSchema :execrows
+DELETE FROM attribute_schemas
+WHERE tenant_id = current_tenant_id();
@@ -0,0 +1,14 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (
+    user_id,
+    action,
+    detail
+) VALUES (
+             $1, $2, $3
+);
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_events
+WHERE user_id = $1 AND audit_id > $2
+ORDER BY audit_id
+LIMIT $3;
@@ -0,0 +1,20 @@
+-- name: PutBlob :exec
+INSERT INTO blobs (
+    key,
+    content_type,
+    data
+) VALUES (
+             $1, $2, $3
+)
+ON CONFLICT (tenant_id, key) DO UPDATE
+SET content_type = EXCLUDED.content_type,
+    data = EXCLUDED.data,
+    updated_at = now();
+
+-- name: GetBlob :one
+SELECT * FROM blobs
+WHERE key = $1 AND tenant_id = current_tenant_id();
+
+-- name: DeleteBlobs :execrows
+DELETE FROM blobs
+WHERE starts_with(key, sqlc.arg(prefix)) AND tenant_id = current_tenant_id();
@@ -0,0 +1,18 @@
+-- name: CreateEmailVerification :exec
+INSERT INTO email_verifications (
+    token_id,
+    user_id,
+    email,
+    expires_at
+) VALUES (
+             $1, $2, $3, $4
+);
+
+-- name: GetEmailVerification :one
+SELECT * FROM email_verifications
+WHERE token_id = $1;
+
+-- name: UseEmailVerification :execrows
+UPDATE email_verifications
+SET used_at = $2
+WHERE token_id = $1 AND used_at IS NULL;
@@ -0,0 +1,54 @@
+-- name: CreateGroup :one
+INSERT INTO groups (
+    name,
+    description
+) VALUES (
+             $1, $2
+)
+RETURNING *;
+
+-- name: GetAllGroups :many
+SELECT * FROM groups
+ORDER BY name;
+
+-- name: GetGroupByID :one
+SELECT * FROM groups
+WHERE group_id = $1;
+
+-- name: UpdateGroup :one
+UPDATE groups
+SET
+    name = $1,
+    description = $2
+WHERE group_id = $3
+    RETURNING *;
+
+-- name: DeleteGroup :execrows
+DELETE FROM groups
+WHERE group_id = $1;
+
+-- name: AddGroupMember :execrows
+-- adding a member twice changes nothing
+INSERT INTO group_members (
+    group_id,
+    user_id
+) VALUES (
+             $1, $2
+)
+ON CONFLICT (group_id, user_id) DO NOTHING;
+
+-- name: RemoveGroupMember :execrows
+DELETE FROM group_members
+WHERE group_id = $1 AND user_id = $2;
+
+-- name: ListUserGroups :many
+SELECT groups.* FROM groups
+JOIN group_members ON group_members.group_id = groups.group_id
+WHERE group_members.user_id = $1
+ORDER BY groups.name;
+
+-- name: ListGroupMembers :many
+SELECT group_members.group_id, sqlc.embed(users) FROM group_members
+JOIN users ON users.user_id = group_members.user_id
+WHERE group_members.group_id = ANY(sqlc.arg(group_ids)::uuid[])
+ORDER BY group_members.group_id, users.user_id;
@@ -0,0 +1,23 @@
+-- name: CreatePasswordReset :exec
+INSERT INTO password_resets (
+    token_id,
+    user_id,
+    expires_at
+) VALUES (
+             $1, $2, $3
+);
+
+-- name: GetPasswordReset :one
+SELECT * FROM password_resets
+WHERE token_id = $1;
+
+-- name: UsePasswordReset :execrows
+UPDATE password_resets
+SET used_at = $1
+WHERE token_id = $2 AND used_at IS NULL;
+
+-- name: UseUserPasswordResets :execrows
+-- other links sent to the user stop working once one is used
+UPDATE password_resets
+SET used_at = $1
+WHERE user_id = $2 AND used_at IS NULL;
@@ -0,0 +1,32 @@
+-- name: CreatePhoneVerification :execrows
+-- a code sent after sent_before is kept, so that resends can be throttled
+INSERT INTO phone_verifications (
+    user_id,
+    phone,
+    code_hash,
+    expires_at,
+    sent_at
+) VALUES (
+             sqlc.arg(user_id), sqlc.arg(phone), sqlc.arg(code_hash), sqlc.arg(expires_at), sqlc.arg(sent_at)
+)
+ON CONFLICT (user_id) DO UPDATE
+SET phone = EXCLUDED.phone,
+    code_hash = EXCLUDED.code_hash,
+    attempts = 0,
+    expires_at = EXCLUDED.expires_at,
+    sent_at = EXCLUDED.sent_at
+WHERE phone_verifications.sent_at <= sqlc.arg(sent_before);
+
+-- name: GetPhoneVerification :one
+SELECT * FROM phone_verifications
+WHERE user_id = $1;
+
+-- name: CountPhoneVerificationAttempt :one
+UPDATE phone_verifications
+SET attempts = attempts + 1
+WHERE user_id = sqlc.arg(user_id) AND attempts < sqlc.arg(max_attempts)
+RETURNING *;
+
+-- name: DeletePhoneVerification :execrows
+DELETE FROM phone_verifications
+WHERE user_id = $1 AND code_hash = $2;
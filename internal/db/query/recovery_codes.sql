@@ -0,0 +1,16 @@
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (
+    user_id,
+    code_hash
+) VALUES (
+             $1, $2
+);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = $1
+WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
@@ -0,0 +1,32 @@
+-- name: CreateSession :exec
+INSERT INTO sessions (
+    session_id,
+    user_id,
+    refresh_token_hash,
+    created_at,
+    expires_at,
+    mfa
+) VALUES (
+             $1, $2, $3, $4, $5, $6
+);
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE session_id = $1 AND revoked_at IS NULL;
+
+-- name: RotateSession :one
+UPDATE sessions
+SET refresh_token_hash = sqlc.arg(new_refresh_token_hash)
+WHERE refresh_token_hash = sqlc.arg(refresh_token_hash) AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeSession :one
+UPDATE sessions
+SET revoked_at = $1
+WHERE refresh_token_hash = $2 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeUserSessions :execrows
+UPDATE sessions
+SET revoked_at = $1
+WHERE user_id = $2 AND revoked_at IS NULL;
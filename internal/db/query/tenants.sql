@@ -0,0 +1,6 @@
+-- name: ActAsTenant :exec
+-- scopes the rest of the transaction to a tenant; the role switch makes row
+-- level security apply however the connection logged in
+SELECT set_config('role', 'app_tenant', true),
+       set_config('app.tenant_id', sqlc.arg(tenant_id)::text, true),
+       set_config('app.all_tenants', sqlc.arg(all_tenants)::text, true);
@@ -0,0 +1,34 @@
+-- name: CreateTOTPFactor :execrows
+-- a confirmed factor is kept; it has to be reset before enrolling again
+INSERT INTO totp_factors (
+    user_id,
+    secret_ciphertext,
+    created_at
+) VALUES (
+             $1, $2, $3
+)
+ON CONFLICT (user_id) DO UPDATE
+SET secret_ciphertext = EXCLUDED.secret_ciphertext,
+    last_used_step = 0,
+    created_at = EXCLUDED.created_at
+WHERE totp_factors.confirmed_at IS NULL;
+
+-- name: GetTOTPFactor :one
+SELECT * FROM totp_factors
+WHERE user_id = $1;
+
+-- name: ConfirmTOTPFactor :execrows
+UPDATE totp_factors
+SET confirmed_at = sqlc.arg(confirmed_at),
+    last_used_step = sqlc.arg(step)
+WHERE user_id = sqlc.arg(user_id) AND confirmed_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+-- each code is good once, so a step can only move forward
+UPDATE totp_factors
+SET last_used_step = sqlc.arg(step)
+WHERE user_id = sqlc.arg(user_id) AND confirmed_at IS NOT NULL AND last_used_step < sqlc.arg(step);
+
+-- name: DeleteTOTPFactor :execrows
+DELETE FROM totp_factors
+WHERE user_id = $1;
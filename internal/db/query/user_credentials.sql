@@ -0,0 +1,15 @@
+-- name: GetUserCredential :one
+SELECT * FROM user_credentials
+WHERE user_id = $1;
+
+-- name: UpsertUserCredential :exec
+INSERT INTO user_credentials (
+    user_id,
+    password_hash,
+    updated_at
+) VALUES (
+             $1, $2, $3
+)
+ON CONFLICT (user_id) DO UPDATE
+SET password_hash = EXCLUDED.password_hash,
+    updated_at = EXCLUDED.updated_at;
@@ -0,0 +1,25 @@
+-- name: LockUserEvents :exec
+-- serializes the events of the tenant the transaction acts for
+SELECT pg_advisory_xact_lock(hashtext('user_events'), hashtext(current_tenant_id()::text));
+
+-- name: CreateUserEvent :one
+INSERT INTO user_events (
+    event_type,
+    user_id,
+    payload
+) VALUES (
+             $1, $2, $3
+)
+RETURNING *;
+
+-- name: GetUserEventsAfter :many
+SELECT * FROM user_events
+WHERE event_id > $1
+ORDER BY event_id
+LIMIT $2;
+
+-- name: GetLastUserEventId :one
+SELECT COALESCE(MAX(event_id), 0)::bigint FROM user_events;
+
+-- name: NotifyUserEvent :exec
+SELECT pg_notify('user_events', sqlc.arg(payload)::text);
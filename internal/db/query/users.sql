@@ -5,9 +5,11 @@ INSERT INTO users (
     email,
     phone,
     age,
-    status
+    status,
+    attributes,
+    email_key
 ) VALUES (
-             $1, $2, $3, $4, $5, $6
+             $1, $2, $3, $4, $5, $6, $7, $8
 )
 RETURNING *;
 
@@ -26,14 +28,86 @@ SET
     email = $4,
     phone = $5,
     age = $6,
-    status = $7
+    status = $7,
+    attributes = $8,
+    email_key = $9,
+    -- a new address or number has not been verified
+    email_verified_at = CASE WHEN email_key = $9 THEN email_verified_at END,
+    phone_verified_at = CASE WHEN phone = $5 THEN phone_verified_at END
 WHERE user_id = $1
     RETURNING *;
 
 -- name: DeleteUser :one
 DELETE FROM users
 WHERE user_id = $1
-    RETURNING user_id;
+    RETURNING *;
+
+-- name: ListUsers :many
+SELECT * FROM users
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+  AND (sqlc.narg(email_key)::text IS NULL OR email_key = sqlc.narg(email_key))
+  AND (sqlc.narg(name)::text IS NULL
+       OR first_name ILIKE '%' || sqlc.narg(name) || '%'
+       OR last_name ILIKE '%' || sqlc.narg(name) || '%')
+  AND (sqlc.narg(group_id)::uuid IS NULL
+       OR user_id IN (SELECT user_id FROM group_members WHERE group_id = sqlc.narg(group_id)))
+  AND (sqlc.narg(attributes)::text IS NULL
+       OR attributes @> sqlc.narg(attributes)::text::jsonb)
+  AND user_id > sqlc.arg(after)
+ORDER BY user_id
+LIMIT sqlc.arg(row_limit);
+
+-- name: GetUsersByIDs :many
+SELECT * FROM users
+WHERE user_id = ANY(sqlc.arg(user_ids)::uuid[]);
+
+-- name: CountUsersByStatus :many
+SELECT status, count(*) AS user_count FROM users
+GROUP BY status;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET email_verified_at = $1
+WHERE user_id = $2 AND email = $3
+RETURNING *;
+
+-- name: VerifyUserPhone :one
+UPDATE users
+SET phone_verified_at = $1
+WHERE user_id = $2 AND phone = $3
+RETURNING *;
+
+-- name: SetUserAvatar :one
+UPDATE users
+SET avatar_url = $1
+WHERE user_id = $2
+RETURNING *;
 
+-- name: SetUserPhone :one
+UPDATE users
+SET phone = sqlc.arg(new_phone)
+WHERE user_id = sqlc.arg(user_id) AND phone = sqlc.arg(phone)
+RETURNING *;
 
+-- name: GetUserByIDForUpdate :one
+SELECT * FROM users
+WHERE user_id = $1
+FOR UPDATE;
 
+-- name: CreateUserWithID :one
+INSERT INTO users (
+    user_id,
+    first_name,
+    last_name,
+    email,
+    phone,
+    age,
+    status,
+    attributes,
+    email_key
+) VALUES (
+             $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+-- a user created since it was looked up is left to the caller to update
+ON CONFLICT (user_id) DO NOTHING
+RETURNING *;
@@ -0,0 +1,31 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"example.com/user-management/internal/db"
+	"github.com/XSAM/otelsql"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	_ "modernc.org/sqlite"
+)
+
+// Open opens the SQLite database at path, creating it if needed. Use
+// ":memory:" for a throwaway database.
+//
+// SQLite allows a single writer, so the pool is limited to one connection.
+// This also keeps an in-memory database alive and shared for the lifetime of
+// the pool. Every statement run through it is traced.
+func Open(path string) (*sql.DB, error) {
+	dbConn, err := otelsql.Open("sqlite", path, db.TraceOptions(semconv.DBSystemNameSQLite)...)
+	if err != nil {
+		return nil, err
+	}
+	dbConn.SetMaxOpenConns(1)
+
+	if _, err := dbConn.Exec("PRAGMA foreign_keys = ON; PRAGMA busy_timeout = 5000"); err != nil {
+		_ = dbConn.Close()
+		return nil, err
+	}
+
+	return dbConn, nil
+}
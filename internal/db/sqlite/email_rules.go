@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/emailaddr"
+	"github.com/pressly/goose/v3"
+)
+
+// recordEmailRulesMigration mirrors the Postgres migration of the same
+// version.
+func recordEmailRulesMigration(rules emailaddr.Rules) *goose.Migration {
+	migration := goose.NewGoMigration(14,
+		&goose.GoFunc{RunTx: func(ctx context.Context, tx *sql.Tx) error {
+			// one row, of the rules addresses are keyed by
+			if _, err := tx.ExecContext(ctx, `
+				CREATE TABLE email_rules (
+				    singleton  BOOLEAN PRIMARY KEY DEFAULT true CHECK (singleton),
+				    providers  BOOLEAN NOT NULL
+				)`); err != nil {
+				return err
+			}
+			return rekeyEmails(ctx, tx, rules)
+		}},
+		&goose.GoFunc{RunTx: func(ctx context.Context, tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, `DROP TABLE email_rules`)
+			return err
+		}},
+	)
+	migration.Source = "00014_record_email_rules.go"
+	return migration
+}
+
+// CheckEmailRules returns db.ErrEmailRulesMismatch unless the addresses in
+// the database are keyed by rules.
+func CheckEmailRules(ctx context.Context, dbConn *sql.DB, rules emailaddr.Rules) error {
+	var providers bool
+	if err := dbConn.QueryRowContext(ctx, `SELECT providers FROM email_rules`).Scan(&providers); err != nil {
+		return fmt.Errorf("failed to read the email rules of the database: %w", err)
+	}
+	return db.CompareEmailRules(emailaddr.Rules{Providers: providers}, rules)
+}
+
+// RekeyEmails keys every address anew by rules, which the server must then
+// be configured with, and records them. It fails, changing nothing, if two
+// users would share a mailbox.
+func RekeyEmails(ctx context.Context, dbConn *sql.DB, rules emailaddr.Rules) error {
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := rekeyEmails(ctx, tx, rules); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func rekeyEmails(ctx context.Context, tx *sql.Tx, rules emailaddr.Rules) error {
+	owners, err := readEmailOwners(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := db.EmailCollisions(owners, rules); err != nil {
+		return err
+	}
+
+	// clear the old keys first, so that none collides with a new one while
+	// the others are being rewritten
+	if _, err := tx.ExecContext(ctx, `UPDATE users SET email_key = user_id`); err != nil {
+		return err
+	}
+	for _, owner := range owners {
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET email_key = ? WHERE user_id = ?`,
+			rules.Key(owner.Email), owner.UserID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO email_rules (providers) VALUES (?)
+		ON CONFLICT (singleton) DO UPDATE SET providers = excluded.providers`, rules.Providers)
+	return err
+}
+
+// readEmailOwners returns every user, oldest first.
+func readEmailOwners(ctx context.Context, tx *sql.Tx) ([]db.EmailOwner, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT user_id, email FROM users ORDER BY created_at, user_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var owners []db.EmailOwner
+	for rows.Next() {
+		var owner db.EmailOwner
+		if err := rows.Scan(&owner.UserID, &owner.Email); err != nil {
+			return nil, err
+		}
+		owners = append(owners, owner)
+	}
+	return owners, rows.Err()
+}
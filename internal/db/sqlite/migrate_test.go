@@ -0,0 +1,157 @@
+package sqlite
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/emailaddr"
+)
+
+func TestMigrations_MatchPostgres(t *testing.T) {
+	postgres, err := os.ReadDir(filepath.Join("..", "migrations"))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	sqlite, err := migrations.ReadDir("migrations")
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+
+	if len(sqlite) != len(postgres) {
+		t.Fatalf("Expected %d SQLite migrations, got %d", len(postgres), len(sqlite))
+	}
+	for i := range postgres {
+		if sqlite[i].Name() != postgres[i].Name() {
+			t.Errorf("Expected migration %s, got %s", postgres[i].Name(), sqlite[i].Name())
+		}
+	}
+}
+
+func TestMigrations_UpAndDown(t *testing.T) {
+	dbConn, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer dbConn.Close()
+
+	results, err := MigrateUp(dbConn, emailaddr.Rules{})
+	if err != nil {
+		t.Fatalf("MigrateUp failed: %v", err)
+	}
+
+	for range results {
+		if _, err := MigrateDown(dbConn); err != nil {
+			t.Fatalf("MigrateDown failed: %v", err)
+		}
+	}
+
+	if _, err := MigrateUp(dbConn, emailaddr.Rules{}); err != nil {
+		t.Fatalf("MigrateUp after rollback failed: %v", err)
+	}
+}
+
+func TestMigrations_NormalizeEmails(t *testing.T) {
+	dbConn, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer dbConn.Close()
+
+	provider, err := newMigrationProvider(dbConn, emailaddr.Rules{Providers: true})
+	if err != nil {
+		t.Fatalf("newMigrationProvider failed: %v", err)
+	}
+	if _, err := provider.UpTo(t.Context(), 11); err != nil {
+		t.Fatalf("UpTo failed: %v", err)
+	}
+
+	insert := `INSERT INTO users (user_id, first_name, last_name, email, phone) VALUES (?, 'A', 'B', ?, '+12345678901')`
+	for id, email := range map[string]string{
+		"00000000-0000-0000-0000-000000000001": "John@Gmail.com",
+		"00000000-0000-0000-0000-000000000002": "j.ohn+news@gmail.com",
+		"00000000-0000-0000-0000-000000000003": " Jane@Example.COM",
+	} {
+		if _, err := dbConn.Exec(insert, id, email); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	_, err = provider.Up(t.Context())
+	if err == nil || !strings.Contains(err.Error(), "john@gmail.com: ") {
+		t.Fatalf("Expected the collision to be reported, got %v", err)
+	}
+
+	if _, err := dbConn.Exec(`DELETE FROM users WHERE user_id = '00000000-0000-0000-0000-000000000002'`); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := provider.Up(t.Context()); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	var email, key string
+	err = dbConn.QueryRow(`SELECT email, email_key FROM users WHERE user_id = '00000000-0000-0000-0000-000000000003'`).Scan(&email, &key)
+	if err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if email != "Jane@example.com" || key != "jane@example.com" {
+		t.Errorf("Expected Jane@example.com keyed as jane@example.com, got %s and %s", email, key)
+	}
+}
+
+func TestEmailRules_CheckAndRekey(t *testing.T) {
+	dbConn, err := Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer dbConn.Close()
+
+	providers := emailaddr.Rules{Providers: true}
+	if _, err := MigrateUp(dbConn, providers); err != nil {
+		t.Fatalf("MigrateUp failed: %v", err)
+	}
+	insert := `INSERT INTO users (user_id, first_name, last_name, email, phone, email_key) VALUES (?, 'A', 'B', ?, '+12345678901', ?)`
+	for id, email := range map[string]string{
+		"00000000-0000-0000-0000-000000000001": "j.ohn@gmail.com",
+		"00000000-0000-0000-0000-000000000002": "jane@gmail.com",
+	} {
+		if _, err := dbConn.Exec(insert, id, email, providers.Key(email)); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	if err := CheckEmailRules(t.Context(), dbConn, providers); err != nil {
+		t.Errorf("Expected the rules migrated with to match, got %v", err)
+	}
+	if err := CheckEmailRules(t.Context(), dbConn, emailaddr.Rules{}); !errors.Is(err, db.ErrEmailRulesMismatch) {
+		t.Errorf("Expected ErrEmailRulesMismatch, got %v", err)
+	}
+
+	if err := RekeyEmails(t.Context(), dbConn, emailaddr.Rules{}); err != nil {
+		t.Fatalf("RekeyEmails failed: %v", err)
+	}
+	if err := CheckEmailRules(t.Context(), dbConn, emailaddr.Rules{}); err != nil {
+		t.Errorf("Expected the rekeyed rules to match, got %v", err)
+	}
+	var key string
+	if err := dbConn.QueryRow(`SELECT email_key FROM users WHERE user_id = '00000000-0000-0000-0000-000000000001'`).Scan(&key); err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	if key != "j.ohn@gmail.com" {
+		t.Errorf("Expected the address keyed without provider rules, got %s", key)
+	}
+
+	// a second address of one mailbox keeps the rules from being turned on
+	if _, err := dbConn.Exec(insert, "00000000-0000-0000-0000-000000000003", "john@gmail.com", "john@gmail.com"); err != nil {
+		t.Fatalf("Insert failed: %v", err)
+	}
+	if err := RekeyEmails(t.Context(), dbConn, providers); err == nil || !strings.Contains(err.Error(), "john@gmail.com: ") {
+		t.Errorf("Expected the collision to be reported, got %v", err)
+	}
+	if err := CheckEmailRules(t.Context(), dbConn, emailaddr.Rules{}); err != nil {
+		t.Errorf("Expected a failed rekey to change nothing, got %v", err)
+	}
+}
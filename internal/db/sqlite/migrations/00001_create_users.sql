@@ -0,0 +1,16 @@
+-- +goose Up
+-- Mirrors migrations/00001_create_users.sql. SQLite has no uuid type or
+-- generator, so ids are stored as text and assigned by the application.
+CREATE TABLE users (
+    user_id     TEXT PRIMARY KEY,
+    first_name  TEXT NOT NULL,
+    last_name   TEXT NOT NULL,
+    email       TEXT NOT NULL UNIQUE,
+    phone       TEXT NOT NULL,
+    age         INTEGER,
+    status      TEXT NOT NULL DEFAULT 'Active' CHECK (status IN ('Active', 'Inactive')),
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE users;
@@ -0,0 +1,13 @@
+-- +goose Up
+CREATE TABLE user_events (
+    event_id    INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_type  TEXT NOT NULL CHECK (event_type IN ('user.created', 'user.updated', 'user.deleted')),
+    user_id     TEXT NOT NULL,
+    payload     TEXT NOT NULL CHECK (json_valid(payload)),
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX user_events_user_id_idx ON user_events (user_id);
+
+-- +goose Down
+DROP TABLE user_events;
@@ -0,0 +1,17 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN phone_verified_at TIMESTAMP;
+
+-- at most one outstanding code per user, replaced when a new one is sent
+CREATE TABLE phone_verifications (
+    user_id     TEXT PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    phone       TEXT NOT NULL,
+    code_hash   BLOB NOT NULL,
+    attempts    INTEGER NOT NULL DEFAULT 0,
+    expires_at  TIMESTAMP NOT NULL,
+    sent_at     TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE phone_verifications;
+
+ALTER TABLE users DROP COLUMN phone_verified_at;
@@ -0,0 +1,47 @@
+-- +goose Up
+CREATE TABLE user_credentials (
+    user_id        TEXT PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    password_hash  TEXT NOT NULL,
+    updated_at     TIMESTAMP NOT NULL
+);
+
+CREATE TABLE sessions (
+    session_id          TEXT PRIMARY KEY,
+    user_id             TEXT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    refresh_token_hash  BLOB NOT NULL UNIQUE,
+    created_at          TIMESTAMP NOT NULL,
+    expires_at          TIMESTAMP NOT NULL,
+    revoked_at          TIMESTAMP
+);
+
+CREATE INDEX sessions_user_id_idx ON sessions (user_id);
+
+CREATE TABLE password_resets (
+    token_id    TEXT PRIMARY KEY,
+    user_id     TEXT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    expires_at  TIMESTAMP NOT NULL,
+    used_at     TIMESTAMP,
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX password_resets_user_id_idx ON password_resets (user_id);
+
+-- outlives the users it mentions, so user_id is not a foreign key
+CREATE TABLE audit_events (
+    audit_id    INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id     TEXT,
+    action      TEXT NOT NULL,
+    detail      TEXT NOT NULL CHECK (json_valid(detail)),
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_events_user_id_idx ON audit_events (user_id, audit_id);
+
+-- +goose Down
+DROP TABLE audit_events;
+
+DROP TABLE password_resets;
+
+DROP TABLE sessions;
+
+DROP TABLE user_credentials;
@@ -0,0 +1,34 @@
+-- +goose Up
+-- the secret is encrypted by the application; the factor only guards logins
+-- once the user has confirmed it with a code
+CREATE TABLE totp_factors (
+    user_id            TEXT PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    secret_ciphertext  BLOB NOT NULL,
+    last_used_step     INTEGER NOT NULL DEFAULT 0,
+    created_at         TIMESTAMP NOT NULL,
+    confirmed_at       TIMESTAMP
+);
+
+CREATE TABLE recovery_codes (
+    user_id    TEXT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    code_hash  BLOB NOT NULL,
+    used_at    TIMESTAMP,
+    PRIMARY KEY (user_id, code_hash)
+);
+
+-- a login that passed the password check and waits for a second factor
+CREATE TABLE mfa_challenges (
+    challenge_id  TEXT PRIMARY KEY,
+    user_id       TEXT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    attempts      INTEGER NOT NULL DEFAULT 0,
+    expires_at    TIMESTAMP NOT NULL
+);
+
+CREATE INDEX mfa_challenges_user_id_idx ON mfa_challenges (user_id);
+
+-- +goose Down
+DROP TABLE mfa_challenges;
+
+DROP TABLE recovery_codes;
+
+DROP TABLE totp_factors;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE groups (
+    group_id     TEXT PRIMARY KEY,
+    name         TEXT NOT NULL UNIQUE,
+    description  TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- memberships go with either side, so deleting a user or a group needs no
+-- clean-up of its own
+CREATE TABLE group_members (
+    group_id  TEXT NOT NULL REFERENCES groups (group_id) ON DELETE CASCADE,
+    user_id   TEXT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    added_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (group_id, user_id)
+);
+
+CREATE INDEX group_members_user_id_idx ON group_members (user_id);
+
+-- +goose Down
+DROP TABLE group_members;
+
+DROP TABLE groups;
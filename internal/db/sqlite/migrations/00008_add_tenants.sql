@@ -0,0 +1,5 @@
+-- +goose Up
+-- SQLite keeps every user in the default tenant; keeping tenants apart takes
+-- the row level security of Postgres, so there is nothing to add here
+
+-- +goose Down
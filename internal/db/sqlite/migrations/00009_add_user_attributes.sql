@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN attributes TEXT NOT NULL DEFAULT '{}';
+
+-- SQLite keeps only the default tenant, so there is at most one schema
+CREATE TABLE attribute_schema (
+    schema_id   INTEGER PRIMARY KEY CHECK (schema_id = 1),
+    schema      TEXT NOT NULL,
+    updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE attribute_schema;
+
+ALTER TABLE users DROP COLUMN attributes;
@@ -0,0 +1,16 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN avatar_url TEXT NOT NULL DEFAULT '';
+
+-- blobs holds files, such as avatar images, for deployments that keep them
+-- in the database rather than on disk
+CREATE TABLE blobs (
+    key           TEXT PRIMARY KEY,
+    content_type  TEXT NOT NULL,
+    data          BLOB NOT NULL,
+    updated_at    TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE blobs;
+
+ALTER TABLE users DROP COLUMN avatar_url;
@@ -0,0 +1,61 @@
+-- +goose Up
+-- oauth_clients are the applications users sign in to through the OpenID
+-- Connect provider
+CREATE TABLE oauth_clients (
+    client_id      TEXT PRIMARY KEY,
+    name           TEXT NOT NULL,
+    -- null for public clients, such as single page apps, which cannot keep
+    -- a secret
+    secret_hash    BLOB,
+    redirect_uris  TEXT NOT NULL,
+    created_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE oauth_consents (
+    user_id     TEXT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    client_id   TEXT NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+    scope       TEXT NOT NULL,
+    granted_at  TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, client_id)
+);
+
+CREATE INDEX oauth_consents_client_id_idx ON oauth_consents (client_id);
+
+CREATE TABLE oauth_authorization_codes (
+    code_id         TEXT PRIMARY KEY,
+    client_id       TEXT NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+    user_id         TEXT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    redirect_uri    TEXT NOT NULL,
+    scope           TEXT NOT NULL,
+    nonce           TEXT NOT NULL,
+    code_challenge  TEXT NOT NULL,
+    expires_at      TIMESTAMP NOT NULL
+);
+
+CREATE TABLE oauth_refresh_tokens (
+    token_id    TEXT PRIMARY KEY,
+    client_id   TEXT NOT NULL REFERENCES oauth_clients (client_id) ON DELETE CASCADE,
+    user_id     TEXT NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    scope       TEXT NOT NULL,
+    created_at  TIMESTAMP NOT NULL,
+    expires_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX oauth_refresh_tokens_user_id_idx ON oauth_refresh_tokens (user_id);
+
+CREATE TABLE oidc_signing_keys (
+    key_id                  TEXT PRIMARY KEY,
+    private_key_ciphertext  BLOB NOT NULL,
+    created_at              TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE oidc_signing_keys;
+
+DROP TABLE oauth_refresh_tokens;
+
+DROP TABLE oauth_authorization_codes;
+
+DROP TABLE oauth_consents;
+
+DROP TABLE oauth_clients;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- mfa is whether the login that started a session used a second factor, which
+-- admin access requires; earlier sessions are taken not to have
+ALTER TABLE sessions ADD COLUMN mfa BOOLEAN NOT NULL DEFAULT false;
+
+-- +goose Down
+ALTER TABLE sessions DROP COLUMN mfa;
@@ -0,0 +1,60 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/emailaddr"
+	"github.com/pressly/goose/v3"
+)
+
+// normalizeEmailsMigration mirrors the Postgres migration of the same
+// version, which is written in Go to convert domains to punycode.
+func normalizeEmailsMigration(rules emailaddr.Rules) *goose.Migration {
+	migration := goose.NewGoMigration(12,
+		&goose.GoFunc{RunTx: func(ctx context.Context, tx *sql.Tx) error {
+			return normalizeEmailsUp(ctx, tx, rules)
+		}},
+		&goose.GoFunc{RunTx: normalizeEmailsDown},
+	)
+	migration.Source = "00012_normalize_emails.go"
+	return migration
+}
+
+func normalizeEmailsUp(ctx context.Context, tx *sql.Tx, rules emailaddr.Rules) error {
+	owners, err := readEmailOwners(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := db.EmailCollisions(owners, rules); err != nil {
+		return err
+	}
+
+	// the UNIQUE of the email column cannot be dropped without rebuilding the
+	// table; every address with its own key is unique anyway
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE users ADD COLUMN email_key TEXT NOT NULL DEFAULT ''`); err != nil {
+		return err
+	}
+	for _, owner := range owners {
+		email := owner.Email
+		if normalized, err := emailaddr.Normalize(email); err == nil {
+			email = normalized
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE users SET email = ?, email_key = ? WHERE user_id = ?`,
+			email, rules.Key(email), owner.UserID); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `CREATE UNIQUE INDEX users_email_key_key ON users (email_key)`)
+	return err
+}
+
+func normalizeEmailsDown(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `DROP INDEX users_email_key_key`); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `ALTER TABLE users DROP COLUMN email_key`)
+	return err
+}
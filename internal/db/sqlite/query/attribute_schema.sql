@@ -0,0 +1,18 @@
+-- name: GetAttributeSchema :one
+SELECT * FROM attribute_schema
+WHERE schema_id = 1;
+
+-- name: SetAttributeSchema :one
+INSERT INTO attribute_schema (
+    schema_id,
+    schema
+) VALUES (
+             1, ?
+)
+ON CONFLICT (schema_id) DO UPDATE
+SET schema = excluded.schema,
+    updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: DeleteAttributeSchema :execrows
+DELETE FROM attribute_schema;
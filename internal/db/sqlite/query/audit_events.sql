@@ -0,0 +1,14 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (
+    user_id,
+    action,
+    detail
+) VALUES (
+             ?, ?, ?
+);
+
+-- name: ListAuditEvents :many
+SELECT * FROM audit_events
+WHERE user_id = ? AND audit_id > ?
+ORDER BY audit_id
+LIMIT ?;
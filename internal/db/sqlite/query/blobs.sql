@@ -0,0 +1,20 @@
+-- name: PutBlob :exec
+INSERT INTO blobs (
+    key,
+    content_type,
+    data
+) VALUES (
+             ?, ?, ?
+)
+ON CONFLICT (key) DO UPDATE
+SET content_type = excluded.content_type,
+    data = excluded.data,
+    updated_at = CURRENT_TIMESTAMP;
+
+-- name: GetBlob :one
+SELECT * FROM blobs
+WHERE key = ?;
+
+-- name: DeleteBlobs :execrows
+DELETE FROM blobs
+WHERE substr(key, 1, length(sqlc.arg(prefix))) = sqlc.arg(prefix);
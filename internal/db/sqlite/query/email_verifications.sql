@@ -0,0 +1,18 @@
+-- name: CreateEmailVerification :exec
+INSERT INTO email_verifications (
+    token_id,
+    user_id,
+    email,
+    expires_at
+) VALUES (
+             ?, ?, ?, ?
+);
+
+-- name: GetEmailVerification :one
+SELECT * FROM email_verifications
+WHERE token_id = ?;
+
+-- name: UseEmailVerification :execrows
+UPDATE email_verifications
+SET used_at = ?
+WHERE token_id = ? AND used_at IS NULL;
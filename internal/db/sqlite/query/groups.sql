@@ -0,0 +1,55 @@
+-- name: CreateGroup :one
+INSERT INTO groups (
+    group_id,
+    name,
+    description
+) VALUES (
+             ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetAllGroups :many
+SELECT * FROM groups
+ORDER BY name;
+
+-- name: GetGroupByID :one
+SELECT * FROM groups
+WHERE group_id = ?;
+
+-- name: UpdateGroup :one
+UPDATE groups
+SET
+    name = ?,
+    description = ?
+WHERE group_id = ?
+    RETURNING *;
+
+-- name: DeleteGroup :execrows
+DELETE FROM groups
+WHERE group_id = ?;
+
+-- name: AddGroupMember :execrows
+-- adding a member twice changes nothing
+INSERT INTO group_members (
+    group_id,
+    user_id
+) VALUES (
+             ?, ?
+)
+ON CONFLICT (group_id, user_id) DO NOTHING;
+
+-- name: RemoveGroupMember :execrows
+DELETE FROM group_members
+WHERE group_id = ? AND user_id = ?;
+
+-- name: ListUserGroups :many
+SELECT groups.* FROM groups
+JOIN group_members ON group_members.group_id = groups.group_id
+WHERE group_members.user_id = ?
+ORDER BY groups.name;
+
+-- name: ListGroupMembers :many
+SELECT group_members.group_id, sqlc.embed(users) FROM group_members
+JOIN users ON users.user_id = group_members.user_id
+WHERE group_members.group_id IN (sqlc.slice(group_ids))
+ORDER BY group_members.group_id, users.user_id;
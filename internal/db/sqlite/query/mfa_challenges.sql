@@ -0,0 +1,22 @@
+-- name: CreateMFAChallenge :exec
+INSERT INTO mfa_challenges (
+    challenge_id,
+    user_id,
+    expires_at
+) VALUES (
+             ?, ?, ?
+);
+
+-- name: GetMFAChallenge :one
+SELECT * FROM mfa_challenges
+WHERE challenge_id = ?;
+
+-- name: CountMFAChallengeAttempt :one
+UPDATE mfa_challenges
+SET attempts = attempts + 1
+WHERE challenge_id = sqlc.arg(challenge_id) AND attempts < sqlc.arg(max_attempts)
+RETURNING *;
+
+-- name: DeleteMFAChallenge :execrows
+DELETE FROM mfa_challenges
+WHERE challenge_id = ?;
@@ -0,0 +1,97 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (
+    client_id,
+    name,
+    secret_hash,
+    redirect_uris
+) VALUES (
+             ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT * FROM oauth_clients
+WHERE client_id = ?;
+
+-- name: GetAllOAuthClients :many
+SELECT * FROM oauth_clients
+ORDER BY name;
+
+-- name: DeleteOAuthClient :execrows
+DELETE FROM oauth_clients
+WHERE client_id = ?;
+
+-- name: GetOAuthConsent :one
+SELECT * FROM oauth_consents
+WHERE user_id = ? AND client_id = ?;
+
+-- name: SaveOAuthConsent :exec
+INSERT INTO oauth_consents (
+    user_id,
+    client_id,
+    scope,
+    granted_at
+) VALUES (
+             ?, ?, ?, ?
+)
+ON CONFLICT (user_id, client_id) DO UPDATE
+SET scope = excluded.scope,
+    granted_at = excluded.granted_at;
+
+-- name: CreateAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (
+    code_id,
+    client_id,
+    user_id,
+    redirect_uri,
+    scope,
+    nonce,
+    code_challenge,
+    expires_at
+) VALUES (
+             ?, ?, ?, ?, ?, ?, ?, ?
+);
+
+-- name: ConsumeAuthorizationCode :one
+DELETE FROM oauth_authorization_codes
+WHERE code_id = ?
+RETURNING *;
+
+-- name: CreateOAuthRefreshToken :exec
+INSERT INTO oauth_refresh_tokens (
+    token_id,
+    client_id,
+    user_id,
+    scope,
+    created_at,
+    expires_at
+) VALUES (
+             ?, ?, ?, ?, ?, ?
+);
+
+-- name: RotateOAuthRefreshToken :one
+UPDATE oauth_refresh_tokens
+SET token_id = sqlc.arg(new_token_id)
+WHERE token_id = sqlc.arg(token_id) AND client_id = sqlc.arg(client_id)
+RETURNING *;
+
+-- name: RevokeOAuthRefreshToken :execrows
+DELETE FROM oauth_refresh_tokens
+WHERE token_id = ? AND client_id = ?;
+
+-- name: CreateSigningKey :exec
+INSERT INTO oidc_signing_keys (
+    key_id,
+    private_key_ciphertext,
+    created_at
+) VALUES (
+             ?, ?, ?
+);
+
+-- name: GetSigningKeys :many
+SELECT * FROM oidc_signing_keys
+ORDER BY created_at DESC;
+
+-- name: DeleteSigningKeys :exec
+DELETE FROM oidc_signing_keys
+WHERE created_at < sqlc.arg(created_before);
@@ -0,0 +1,23 @@
+-- name: CreatePasswordReset :exec
+INSERT INTO password_resets (
+    token_id,
+    user_id,
+    expires_at
+) VALUES (
+             ?, ?, ?
+);
+
+-- name: GetPasswordReset :one
+SELECT * FROM password_resets
+WHERE token_id = ?;
+
+-- name: UsePasswordReset :execrows
+UPDATE password_resets
+SET used_at = ?
+WHERE token_id = ? AND used_at IS NULL;
+
+-- name: UseUserPasswordResets :execrows
+-- other links sent to the user stop working once one is used
+UPDATE password_resets
+SET used_at = ?
+WHERE user_id = ? AND used_at IS NULL;
@@ -0,0 +1,16 @@
+-- name: CreateRecoveryCode :exec
+INSERT INTO recovery_codes (
+    user_id,
+    code_hash
+) VALUES (
+             ?, ?
+);
+
+-- name: UseRecoveryCode :execrows
+UPDATE recovery_codes
+SET used_at = ?
+WHERE user_id = ? AND code_hash = ? AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = ?;
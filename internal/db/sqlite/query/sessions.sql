@@ -0,0 +1,32 @@
+-- name: CreateSession :exec
+INSERT INTO sessions (
+    session_id,
+    user_id,
+    refresh_token_hash,
+    created_at,
+    expires_at,
+    mfa
+) VALUES (
+             ?, ?, ?, ?, ?, ?
+);
+
+-- name: GetSession :one
+SELECT * FROM sessions
+WHERE session_id = ? AND revoked_at IS NULL;
+
+-- name: RotateSession :one
+UPDATE sessions
+SET refresh_token_hash = sqlc.arg(new_refresh_token_hash)
+WHERE refresh_token_hash = sqlc.arg(refresh_token_hash) AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeSession :one
+UPDATE sessions
+SET revoked_at = ?
+WHERE refresh_token_hash = ? AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeUserSessions :execrows
+UPDATE sessions
+SET revoked_at = ?
+WHERE user_id = ? AND revoked_at IS NULL;
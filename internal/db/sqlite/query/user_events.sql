@@ -0,0 +1,15 @@
+-- name: CreateUserEvent :one
+INSERT INTO user_events (
+    event_type,
+    user_id,
+    payload
+) VALUES (
+             ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetUserEventsAfter :many
+SELECT * FROM user_events
+WHERE event_id > ?
+ORDER BY event_id
+LIMIT ?;
@@ -0,0 +1,115 @@
+-- name: CreateUser :one
+INSERT INTO users (
+    user_id,
+    first_name,
+    last_name,
+    email,
+    phone,
+    age,
+    status,
+    attributes,
+    email_key
+) VALUES (
+             ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetAllUsers :many
+SELECT * FROM users;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE user_id = ?;
+
+-- name: UpdateUser :one
+UPDATE users
+SET
+    first_name = sqlc.arg(first_name),
+    last_name = sqlc.arg(last_name),
+    email = sqlc.arg(email),
+    phone = sqlc.arg(phone),
+    age = sqlc.arg(age),
+    status = sqlc.arg(status),
+    attributes = sqlc.arg(attributes),
+    email_key = sqlc.arg(email_key),
+    -- a new address or number has not been verified
+    email_verified_at = CASE WHEN email_key = sqlc.arg(email_key) THEN email_verified_at END,
+    phone_verified_at = CASE WHEN phone = sqlc.arg(phone) THEN phone_verified_at END
+WHERE user_id = sqlc.arg(user_id)
+    RETURNING *;
+
+-- name: DeleteUser :one
+DELETE FROM users
+WHERE user_id = ?
+    RETURNING *;
+
+-- name: ListUsers :many
+SELECT * FROM users
+WHERE (sqlc.narg(status) IS NULL OR status = sqlc.narg(status))
+  AND (sqlc.narg(email_key) IS NULL OR email_key = sqlc.narg(email_key))
+  AND (sqlc.narg(name) IS NULL
+       OR first_name LIKE '%' || sqlc.narg(name) || '%'
+       OR last_name LIKE '%' || sqlc.narg(name) || '%')
+  AND (sqlc.narg(group_id) IS NULL
+       OR user_id IN (SELECT user_id FROM group_members WHERE group_id = sqlc.narg(group_id)))
+  AND (CAST(sqlc.narg(attributes) AS TEXT) IS NULL
+       OR NOT EXISTS (
+           SELECT 1 FROM json_each(sqlc.narg(attributes)) AS wanted
+           WHERE NOT EXISTS (
+               SELECT 1 FROM json_each(users.attributes) AS held
+               WHERE held.key = wanted.key
+                 AND held.type = wanted.type
+                 AND held.atom = wanted.atom)))
+  AND user_id > sqlc.arg(after)
+ORDER BY user_id
+LIMIT sqlc.arg(row_limit);
+
+-- name: GetUsersByIDs :many
+SELECT * FROM users
+WHERE user_id IN (sqlc.slice(user_ids));
+
+-- name: CountUsersByStatus :many
+SELECT status, count(*) AS user_count FROM users
+GROUP BY status;
+
+-- name: VerifyUserEmail :one
+UPDATE users
+SET email_verified_at = ?
+WHERE user_id = ? AND email = ?
+RETURNING *;
+
+-- name: VerifyUserPhone :one
+UPDATE users
+SET phone_verified_at = ?
+WHERE user_id = ? AND phone = ?
+RETURNING *;
+
+-- name: SetUserAvatar :one
+UPDATE users
+SET avatar_url = ?
+WHERE user_id = ?
+RETURNING *;
+
+-- name: SetUserPhone :one
+UPDATE users
+SET phone = sqlc.arg(new_phone)
+WHERE user_id = sqlc.arg(user_id) AND phone = sqlc.arg(phone)
+RETURNING *;
+
+-- name: CreateUserWithID :one
+INSERT INTO users (
+    user_id,
+    first_name,
+    last_name,
+    email,
+    phone,
+    age,
+    status,
+    attributes,
+    email_key
+) VALUES (
+             ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+-- a user created since it was looked up is left to the caller to update
+ON CONFLICT (user_id) DO NOTHING
+RETURNING *;
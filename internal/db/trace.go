@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// TraceOptions configures otelsql to record a span for every statement,
+// tagged with the database system and carrying the SQL text in db.statement.
+// Spans for iterating rows and resetting sessions are left out as noise.
+func TraceOptions(system attribute.KeyValue) []otelsql.Option {
+	return []otelsql.Option{
+		otelsql.WithAttributes(system),
+		otelsql.WithAttributesGetter(statementAttributes),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			// replaced by the db.statement attribute below
+			DisableQuery:         true,
+			DisableErrSkip:       true,
+			OmitRows:             true,
+			OmitConnResetSession: true,
+		}),
+	}
+}
+
+func statementAttributes(_ context.Context, _ otelsql.Method, query string, _ []driver.NamedValue) []attribute.KeyValue {
+	if query == "" {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("db.statement", query)}
+}
@@ -8,10 +8,45 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const countUsersByStatus = `-- name: CountUsersByStatus :many
+SELECT status, count(*) AS user_count FROM users
+GROUP BY status
+`
+
+type CountUsersByStatusRow struct {
+	Status    string
+	UserCount int64
+}
+
+func (q *Queries) CountUsersByStatus(ctx context.Context) ([]CountUsersByStatusRow, error) {
+	rows, err := q.db.QueryContext(ctx, countUsersByStatus)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CountUsersByStatusRow
+	for rows.Next() {
+		var i CountUsersByStatusRow
+		if err := rows.Scan(&i.Status, &i.UserCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (
     first_name,
@@ -19,20 +54,24 @@ INSERT INTO users (
     email,
     phone,
     age,
-    status
+    status,
+    attributes,
+    email_key
 ) VALUES (
-             $1, $2, $3, $4, $5, $6
+             $1, $2, $3, $4, $5, $6, $7, $8
 )
-RETURNING user_id, first_name, last_name, email, phone, age, status, created_at
+RETURNING user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key
 `
 
 type CreateUserParams struct {
-	FirstName string
-	LastName  string
-	Email     string
-	Phone     string
-	Age       sql.NullInt32
-	Status    string
+	FirstName  string
+	LastName   string
+	Email      string
+	Phone      string
+	Age        sql.NullInt32
+	Status     string
+	Attributes json.RawMessage
+	EmailKey   string
 }
 
 func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
@@ -43,6 +82,8 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		arg.Phone,
 		arg.Age,
 		arg.Status,
+		arg.Attributes,
+		arg.EmailKey,
 	)
 	var i User
 	err := row.Scan(
@@ -54,6 +95,12 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Age,
 		&i.Status,
 		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.PhoneVerifiedAt,
+		&i.TenantID,
+		&i.Attributes,
+		&i.AvatarUrl,
+		&i.EmailKey,
 	)
 	return i, err
 }
@@ -61,18 +108,33 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 const deleteUser = `-- name: DeleteUser :one
 DELETE FROM users
 WHERE user_id = $1
-    RETURNING user_id
+    RETURNING user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key
 `
 
-func (q *Queries) DeleteUser(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
+func (q *Queries) DeleteUser(ctx context.Context, userID uuid.UUID) (User, error) {
 	row := q.db.QueryRowContext(ctx, deleteUser, userID)
-	var user_id uuid.UUID
-	err := row.Scan(&user_id)
-	return user_id, err
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.Phone,
+		&i.Age,
+		&i.Status,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.PhoneVerifiedAt,
+		&i.TenantID,
+		&i.Attributes,
+		&i.AvatarUrl,
+		&i.EmailKey,
+	)
+	return i, err
 }
 
 const getAllUsers = `-- name: GetAllUsers :many
-SELECT user_id, first_name, last_name, email, phone, age, status, created_at FROM users
+SELECT user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key FROM users
 `
 
 func (q *Queries) GetAllUsers(ctx context.Context) ([]User, error) {
@@ -93,6 +155,12 @@ func (q *Queries) GetAllUsers(ctx context.Context) ([]User, error) {
 			&i.Age,
 			&i.Status,
 			&i.CreatedAt,
+			&i.EmailVerifiedAt,
+			&i.PhoneVerifiedAt,
+			&i.TenantID,
+			&i.Attributes,
+			&i.AvatarUrl,
+			&i.EmailKey,
 		); err != nil {
 			return nil, err
 		}
@@ -108,7 +176,7 @@ func (q *Queries) GetAllUsers(ctx context.Context) ([]User, error) {
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT user_id, first_name, last_name, email, phone, age, status, created_at FROM users
+SELECT user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key FROM users
 WHERE user_id = $1
 `
 
@@ -124,10 +192,131 @@ func (q *Queries) GetUserByID(ctx context.Context, userID uuid.UUID) (User, erro
 		&i.Age,
 		&i.Status,
 		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.PhoneVerifiedAt,
+		&i.TenantID,
+		&i.Attributes,
+		&i.AvatarUrl,
+		&i.EmailKey,
 	)
 	return i, err
 }
 
+const getUsersByIDs = `-- name: GetUsersByIDs :many
+SELECT user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key FROM users
+WHERE user_id = ANY($1::uuid[])
+`
+
+func (q *Queries) GetUsersByIDs(ctx context.Context, userIds []uuid.UUID) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, getUsersByIDs, pq.Array(userIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.UserID,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.Phone,
+			&i.Age,
+			&i.Status,
+			&i.CreatedAt,
+			&i.EmailVerifiedAt,
+			&i.PhoneVerifiedAt,
+			&i.TenantID,
+			&i.Attributes,
+			&i.AvatarUrl,
+			&i.EmailKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUsers = `-- name: ListUsers :many
+SELECT user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key FROM users
+WHERE ($1::text IS NULL OR status = $1)
+  AND ($2::text IS NULL OR email_key = $2)
+  AND ($3::text IS NULL
+       OR first_name ILIKE '%' || $3 || '%'
+       OR last_name ILIKE '%' || $3 || '%')
+  AND ($4::uuid IS NULL
+       OR user_id IN (SELECT user_id FROM group_members WHERE group_id = $4))
+  AND ($5::text IS NULL
+       OR attributes @> $5::text::jsonb)
+  AND user_id > $6
+ORDER BY user_id
+LIMIT $7
+`
+
+type ListUsersParams struct {
+	Status     sql.NullString
+	EmailKey   sql.NullString
+	Name       sql.NullString
+	GroupID    uuid.NullUUID
+	Attributes sql.NullString
+	After      uuid.UUID
+	RowLimit   int32
+}
+
+func (q *Queries) ListUsers(ctx context.Context, arg ListUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listUsers,
+		arg.Status,
+		arg.EmailKey,
+		arg.Name,
+		arg.GroupID,
+		arg.Attributes,
+		arg.After,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.UserID,
+			&i.FirstName,
+			&i.LastName,
+			&i.Email,
+			&i.Phone,
+			&i.Age,
+			&i.Status,
+			&i.CreatedAt,
+			&i.EmailVerifiedAt,
+			&i.PhoneVerifiedAt,
+			&i.TenantID,
+			&i.Attributes,
+			&i.AvatarUrl,
+			&i.EmailKey,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET
@@ -136,19 +325,26 @@ SET
     email = $4,
     phone = $5,
     age = $6,
-    status = $7
+    status = $7,
+    attributes = $8,
+    email_key = $9,
+    -- a new address or number has not been verified
+    email_verified_at = CASE WHEN email_key = $9 THEN email_verified_at END,
+    phone_verified_at = CASE WHEN phone = $5 THEN phone_verified_at END
 WHERE user_id = $1
-    RETURNING user_id, first_name, last_name, email, phone, age, status, created_at
+    RETURNING user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key
 `
 
 type UpdateUserParams struct {
-	UserID    uuid.UUID
-	FirstName string
-	LastName  string
-	Email     string
-	Phone     string
-	Age       sql.NullInt32
-	Status    string
+	UserID     uuid.UUID
+	FirstName  string
+	LastName   string
+	Email      string
+	Phone      string
+	Age        sql.NullInt32
+	Status     string
+	Attributes json.RawMessage
+	EmailKey   string
 }
 
 func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
@@ -160,6 +356,238 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		arg.Phone,
 		arg.Age,
 		arg.Status,
+		arg.Attributes,
+		arg.EmailKey,
+	)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.Phone,
+		&i.Age,
+		&i.Status,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.PhoneVerifiedAt,
+		&i.TenantID,
+		&i.Attributes,
+		&i.AvatarUrl,
+		&i.EmailKey,
+	)
+	return i, err
+}
+
+const verifyUserEmail = `-- name: VerifyUserEmail :one
+UPDATE users
+SET email_verified_at = $1
+WHERE user_id = $2 AND email = $3
+RETURNING user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key
+`
+
+type VerifyUserEmailParams struct {
+	EmailVerifiedAt sql.NullTime
+	UserID          uuid.UUID
+	Email           string
+}
+
+func (q *Queries) VerifyUserEmail(ctx context.Context, arg VerifyUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserEmail, arg.EmailVerifiedAt, arg.UserID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.Phone,
+		&i.Age,
+		&i.Status,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.PhoneVerifiedAt,
+		&i.TenantID,
+		&i.Attributes,
+		&i.AvatarUrl,
+		&i.EmailKey,
+	)
+	return i, err
+}
+
+const verifyUserPhone = `-- name: VerifyUserPhone :one
+UPDATE users
+SET phone_verified_at = $1
+WHERE user_id = $2 AND phone = $3
+RETURNING user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key
+`
+
+type VerifyUserPhoneParams struct {
+	PhoneVerifiedAt sql.NullTime
+	UserID          uuid.UUID
+	Phone           string
+}
+
+func (q *Queries) VerifyUserPhone(ctx context.Context, arg VerifyUserPhoneParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, verifyUserPhone, arg.PhoneVerifiedAt, arg.UserID, arg.Phone)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.Phone,
+		&i.Age,
+		&i.Status,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.PhoneVerifiedAt,
+		&i.TenantID,
+		&i.Attributes,
+		&i.AvatarUrl,
+		&i.EmailKey,
+	)
+	return i, err
+}
+
+const setUserAvatar = `-- name: SetUserAvatar :one
+UPDATE users
+SET avatar_url = $1
+WHERE user_id = $2
+RETURNING user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key
+`
+
+type SetUserAvatarParams struct {
+	AvatarUrl string
+	UserID    uuid.UUID
+}
+
+func (q *Queries) SetUserAvatar(ctx context.Context, arg SetUserAvatarParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserAvatar, arg.AvatarUrl, arg.UserID)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.Phone,
+		&i.Age,
+		&i.Status,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.PhoneVerifiedAt,
+		&i.TenantID,
+		&i.Attributes,
+		&i.AvatarUrl,
+		&i.EmailKey,
+	)
+	return i, err
+}
+
+const setUserPhone = `-- name: SetUserPhone :one
+UPDATE users
+SET phone = $1
+WHERE user_id = $2 AND phone = $3
+RETURNING user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key
+`
+
+type SetUserPhoneParams struct {
+	NewPhone string
+	UserID   uuid.UUID
+	Phone    string
+}
+
+func (q *Queries) SetUserPhone(ctx context.Context, arg SetUserPhoneParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserPhone, arg.NewPhone, arg.UserID, arg.Phone)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.Phone,
+		&i.Age,
+		&i.Status,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.PhoneVerifiedAt,
+		&i.TenantID,
+		&i.Attributes,
+		&i.AvatarUrl,
+		&i.EmailKey,
+	)
+	return i, err
+}
+
+const getUserByIDForUpdate = `-- name: GetUserByIDForUpdate :one
+SELECT user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key FROM users
+WHERE user_id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetUserByIDForUpdate(ctx context.Context, userID uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByIDForUpdate, userID)
+	var i User
+	err := row.Scan(
+		&i.UserID,
+		&i.FirstName,
+		&i.LastName,
+		&i.Email,
+		&i.Phone,
+		&i.Age,
+		&i.Status,
+		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.PhoneVerifiedAt,
+		&i.TenantID,
+		&i.Attributes,
+		&i.AvatarUrl,
+		&i.EmailKey,
+	)
+	return i, err
+}
+
+const createUserWithID = `-- name: CreateUserWithID :one
+INSERT INTO users (
+    user_id,
+    first_name,
+    last_name,
+    email,
+    phone,
+    age,
+    status,
+    attributes,
+    email_key
+) VALUES (
+             $1, $2, $3, $4, $5, $6, $7, $8, $9
+)
+-- a user created since it was looked up is left to the caller to update
+ON CONFLICT (user_id) DO NOTHING
+RETURNING user_id, first_name, last_name, email, phone, age, status, created_at, email_verified_at, phone_verified_at, tenant_id, attributes, avatar_url, email_key
+`
+
+type CreateUserWithIDParams struct {
+	UserID     uuid.UUID
+	FirstName  string
+	LastName   string
+	Email      string
+	Phone      string
+	Age        sql.NullInt32
+	Status     string
+	Attributes json.RawMessage
+	EmailKey   string
+}
+
+func (q *Queries) CreateUserWithID(ctx context.Context, arg CreateUserWithIDParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, createUserWithID,
+		arg.UserID,
+		arg.FirstName,
+		arg.LastName,
+		arg.Email,
+		arg.Phone,
+		arg.Age,
+		arg.Status,
+		arg.Attributes,
+		arg.EmailKey,
 	)
 	var i User
 	err := row.Scan(
@@ -171,6 +599,12 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.Age,
 		&i.Status,
 		&i.CreatedAt,
+		&i.EmailVerifiedAt,
+		&i.PhoneVerifiedAt,
+		&i.TenantID,
+		&i.Attributes,
+		&i.AvatarUrl,
+		&i.EmailKey,
 	)
 	return i, err
 }
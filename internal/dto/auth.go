@@ -0,0 +1,58 @@
+package dto
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" validate:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type TokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	// ExpiresIn is the number of seconds the access token is valid for.
+	ExpiresIn int `json:"expiresIn"`
+}
+
+type LoginMFARequest struct {
+	MFAToken string `json:"mfaToken" validate:"required"`
+	// Code is a code from the user's authenticator app or one of their
+	// recovery codes.
+	Code string `json:"code" validate:"required"`
+}
+
+// MFAChallengeResponse answers a login whose password was right but that
+// needs a second factor to finish.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfaRequired"`
+	MFAToken    string `json:"mfaToken"`
+	// ExpiresIn is the number of seconds the MFA token is valid for.
+	ExpiresIn int `json:"expiresIn"`
+}
+
+type TOTPEnrolmentResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+	// QRCodePNG is a base64 encoded PNG image of the otpauth URI.
+	QRCodePNG []byte `json:"qrCodePng"`
+}
+
+type ConfirmTOTPRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
@@ -0,0 +1,15 @@
+package dto
+
+type CreateGroupRequest struct {
+	Name        string `json:"name" validate:"required,min=2,max=100"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+type UpdateGroupRequest struct {
+	Name        *string `json:"name" validate:"omitempty,min=2,max=100"`
+	Description *string `json:"description" validate:"omitempty,max=500"`
+}
+
+type AddGroupMemberRequest struct {
+	UserId string `json:"userId" validate:"required,uuid"`
+}
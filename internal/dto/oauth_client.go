@@ -0,0 +1,8 @@
+package dto
+
+type RegisterOAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,min=2,max=100"`
+	RedirectURIs []string `json:"redirectUris" validate:"required,min=1,max=10,dive,required,url"`
+	// Public clients, such as single page and mobile apps, get no secret.
+	Public bool `json:"public"`
+}
@@ -6,16 +6,35 @@ type CreateUserRequest struct {
 	FirstName string       `json:"firstName" validate:"required,min=2,max=50"`
 	LastName  string       `json:"lastName" validate:"required,min=2,max=50"`
 	Email     string       `json:"email" validate:"required,email"`
-	Phone     string       `json:"phone" validate:"required,e164"`
+	Phone     string       `json:"phone" validate:"required,max=32"`
 	Age       int          `json:"age" validate:"omitempty,gt=0"`
 	Status    model.Status `json:"status" validate:"omitempty,oneof=Active Inactive"`
+	// Attributes are checked against the tenant's attribute schema.
+	Attributes map[string]any `json:"attributes"`
 }
 
 type UpdateUserRequest struct {
 	FirstName *string       `json:"firstName" validate:"omitempty,min=2,max=50"`
 	LastName  *string       `json:"lastName" validate:"omitempty,min=2,max=50"`
 	Email     *string       `json:"email" validate:"omitempty,email"`
-	Phone     *string       `json:"phone" validate:"omitempty,e164"`
+	Phone     *string       `json:"phone" validate:"omitempty,max=32"`
 	Age       *int          `json:"age" validate:"omitempty,gt=0"`
 	Status    *model.Status `json:"status" validate:"omitempty,oneof=Active Inactive"`
+	// Attributes replace all of the user's attributes when given.
+	Attributes map[string]any `json:"attributes"`
+}
+
+// UserDocument is the user as PATCH requests in merge-patch+json and
+// json-patch+json see it. Patches apply to a user's document, and the patched
+// document must still be valid.
+type UserDocument struct {
+	FirstName string `json:"firstName" validate:"required,min=2,max=50"`
+	LastName  string `json:"lastName" validate:"required,min=2,max=50"`
+	Email     string `json:"email" validate:"required,email"`
+	Phone     string `json:"phone" validate:"required,max=32"`
+	// Age is null for users who have not given it, and cleared by setting
+	// it to null.
+	Age        *int           `json:"age" validate:"omitempty,gt=0"`
+	Status     model.Status   `json:"status" validate:"required,oneof=Active Inactive"`
+	Attributes map[string]any `json:"attributes"`
 }
@@ -0,0 +1,5 @@
+package dto
+
+type VerifyPhoneRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
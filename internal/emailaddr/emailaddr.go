@@ -0,0 +1,94 @@
+// Package emailaddr normalizes email addresses, so that the ways of writing
+// one mailbox, such as John@Example.com and john@example.com, belong to one
+// user.
+package emailaddr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// ErrInvalid is returned for addresses without a local part and a domain, or
+// whose domain is not a valid internationalized domain name.
+var ErrInvalid = errors.New("invalid email address")
+
+// provider describes a mail provider that delivers variants of an address to
+// one mailbox.
+type provider struct {
+	// domain is the provider's main domain, which its other domains are
+	// keyed as.
+	domain string
+	// ignoresDots is set if dots in the local part are ignored.
+	ignoresDots bool
+	// plusTags is set if anything after a + in the local part is ignored.
+	plusTags bool
+}
+
+var gmail = provider{domain: "gmail.com", ignoresDots: true, plusTags: true}
+
+// providers are the mail providers whose rules Rules.Key can apply, by
+// domain.
+var providers = map[string]provider{
+	"gmail.com":      gmail,
+	"googlemail.com": gmail,
+}
+
+// Normalize returns address as it is stored and shown: trimmed, with its
+// domain lowercased and, if internationalized, converted to punycode. The
+// local part keeps the case it was entered in.
+func Normalize(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	at := strings.LastIndex(address, "@")
+	if at <= 0 || at == len(address)-1 {
+		return "", fmt.Errorf("%w: %q", ErrInvalid, address)
+	}
+
+	domain, err := idna.Lookup.ToASCII(address[at+1:])
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalid, err)
+	}
+	return address[:at] + "@" + domain, nil
+}
+
+// Rules selects which addresses Key treats as one mailbox. The zero value
+// only ignores the case of local parts.
+type Rules struct {
+	// Providers also applies the rules of providers that deliver variants of
+	// an address to one mailbox, such as the dots and plus tags Gmail
+	// ignores. Those variants are then one user instead of several.
+	Providers bool
+}
+
+// Key returns the form of address that identifies its mailbox, which no two
+// users may share. Local parts are compared without case, as every common
+// provider does. Addresses that do not normalize are only trimmed and
+// lowercased, so they never match a valid one.
+func (rules Rules) Key(address string) string {
+	normalized, err := Normalize(address)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(address))
+	}
+
+	normalized = strings.ToLower(normalized)
+	if !rules.Providers {
+		return normalized
+	}
+
+	at := strings.LastIndex(normalized, "@")
+	local, domain := normalized[:at], normalized[at+1:]
+
+	p, ok := providers[domain]
+	if !ok {
+		return normalized
+	}
+	if p.plusTags {
+		local, _, _ = strings.Cut(local, "+")
+	}
+	if p.ignoresDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + p.domain
+}
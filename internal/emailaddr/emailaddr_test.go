@@ -0,0 +1,61 @@
+package emailaddr
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"john@example.com", "john@example.com"},
+		{"  John.Doe@Example.COM ", "John.Doe@example.com"},
+		{"jörg@Bücher.example", "jörg@xn--bcher-kva.example"},
+		{`"a@b"@example.com`, `"a@b"@example.com`},
+	}
+
+	for _, test := range tests {
+		got, err := Normalize(test.address)
+		if err != nil {
+			t.Errorf("Normalize(%q) failed: %v", test.address, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Normalize(%q) = %q, want %q", test.address, got, test.want)
+		}
+	}
+}
+
+func TestNormalize_Invalid(t *testing.T) {
+	for _, address := range []string{"", "john", "@example.com", "john@", "john@exa mple.com"} {
+		if _, err := Normalize(address); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Normalize(%q): expected ErrInvalid, got %v", address, err)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		address   string
+		want      string
+		providers string
+	}{
+		{"John@Example.com", "john@example.com", "john@example.com"},
+		{"john.doe+news@example.com", "john.doe+news@example.com", "john.doe+news@example.com"},
+		{"J.o.h.n+news@Gmail.com", "j.o.h.n+news@gmail.com", "john@gmail.com"},
+		{"john@googlemail.com", "john@googlemail.com", "john@gmail.com"},
+		{"jörg@Bücher.example", "jörg@xn--bcher-kva.example", "jörg@xn--bcher-kva.example"},
+		{" Not An Address ", "not an address", "not an address"},
+	}
+
+	for _, test := range tests {
+		if got := (Rules{}).Key(test.address); got != test.want {
+			t.Errorf("Key(%q) = %q, want %q", test.address, got, test.want)
+		}
+		if got := (Rules{Providers: true}).Key(test.address); got != test.providers {
+			t.Errorf("Key(%q) with provider rules = %q, want %q", test.address, got, test.providers)
+		}
+	}
+}
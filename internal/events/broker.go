@@ -0,0 +1,66 @@
+package events
+
+import (
+	"sync"
+
+	"example.com/user-management/internal/model"
+)
+
+// Broker fans user events out to every local subscriber.
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	broker *Broker
+	events chan model.UserEvent
+	once   sync.Once
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+func (broker *Broker) Subscribe(bufferSize int) *Subscription {
+	sub := &Subscription{
+		broker: broker,
+		events: make(chan model.UserEvent, bufferSize),
+	}
+
+	broker.mu.Lock()
+	broker.subscribers[sub] = struct{}{}
+	broker.mu.Unlock()
+
+	return sub
+}
+
+// Publish never blocks. A subscriber whose buffer is full is dropped and its
+// channel closed, so that the client reconnects and resumes from the event log.
+func (broker *Broker) Publish(event model.UserEvent) {
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+
+	for sub := range broker.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			delete(broker.subscribers, sub)
+			sub.once.Do(func() { close(sub.events) })
+		}
+	}
+}
+
+func (sub *Subscription) Events() <-chan model.UserEvent {
+	return sub.events
+}
+
+func (sub *Subscription) Close() {
+	sub.broker.mu.Lock()
+	delete(sub.broker.subscribers, sub)
+	sub.broker.mu.Unlock()
+
+	sub.once.Do(func() { close(sub.events) })
+}
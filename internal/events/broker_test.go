@@ -0,0 +1,65 @@
+package events
+
+import (
+	"testing"
+
+	"example.com/user-management/internal/model"
+)
+
+func TestBroker_FansOutToEverySubscriber(t *testing.T) {
+	broker := NewBroker()
+	first := broker.Subscribe(2)
+	defer first.Close()
+	second := broker.Subscribe(2)
+	defer second.Close()
+
+	broker.Publish(model.UserEvent{EventId: 1})
+	broker.Publish(model.UserEvent{EventId: 2})
+
+	for _, sub := range []*Subscription{first, second} {
+		for _, expected := range []int64{1, 2} {
+			if event := <-sub.Events(); event.EventId != expected {
+				t.Fatalf("Expected event %d, got %d", expected, event.EventId)
+			}
+		}
+	}
+}
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	broker := NewBroker()
+	slow := broker.Subscribe(1)
+	defer slow.Close()
+	fast := broker.Subscribe(3)
+	defer fast.Close()
+
+	for id := int64(1); id <= 3; id++ {
+		broker.Publish(model.UserEvent{EventId: id})
+	}
+
+	// the buffered event is still delivered before the channel closes
+	if event := <-slow.Events(); event.EventId != 1 {
+		t.Fatalf("Expected event 1, got %d", event.EventId)
+	}
+	if event, ok := <-slow.Events(); ok {
+		t.Fatalf("Expected the slow subscriber to be dropped, got event %d", event.EventId)
+	}
+
+	for _, expected := range []int64{1, 2, 3} {
+		if event := <-fast.Events(); event.EventId != expected {
+			t.Fatalf("Expected event %d, got %d", expected, event.EventId)
+		}
+	}
+}
+
+func TestSubscription_Close(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe(1)
+	sub.Close()
+	sub.Close()
+
+	broker.Publish(model.UserEvent{EventId: 1})
+
+	if _, ok := <-sub.Events(); ok {
+		t.Fatal("Expected no events after Close")
+	}
+}
@@ -0,0 +1,145 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tenant"
+	"github.com/lib/pq"
+)
+
+const channel = "user_events"
+
+// EventLog is the durable log the listener catches up from after missing
+// notifications.
+type EventLog interface {
+	store.UserEventStoreInterface
+	GetLastUserEventId(ctx context.Context) (int64, error)
+}
+
+// PostgresListener relays NOTIFY messages on the user_events channel to the
+// local broker, so that every instance sees changes made by any other.
+type PostgresListener struct {
+	listener    *pq.Listener
+	broker      *Broker
+	store       EventLog
+	lastEventId int64
+	connected   atomic.Bool
+	done        chan struct{}
+}
+
+func NewPostgresListener(dsn string, broker *Broker, eventStore EventLog) (*PostgresListener, error) {
+	l := &PostgresListener{
+		broker: broker,
+		store:  eventStore,
+		done:   make(chan struct{}),
+	}
+
+	l.listener = pq.NewListener(dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventConnected, pq.ListenerEventReconnected:
+			l.connected.Store(true)
+		case pq.ListenerEventDisconnected, pq.ListenerEventConnectionAttemptFailed:
+			l.connected.Store(false)
+		}
+		if err != nil {
+			slog.Warn("user event listener connection error", logging.Err(err))
+		}
+	})
+
+	if err := l.listener.Listen(channel); err != nil {
+		_ = l.listener.Close()
+		return nil, err
+	}
+
+	// events committed from now on are either notified or caught up with
+	lastEventId, err := eventStore.GetLastUserEventId(tenant.WithAllTenants(context.Background()))
+	if err != nil {
+		_ = l.listener.Close()
+		return nil, err
+	}
+	l.lastEventId = lastEventId
+
+	return l, nil
+}
+
+func (l *PostgresListener) Start() {
+	go l.run()
+}
+
+// Check reports an error while the listener is not connected, during which
+// changes made by other instances go unnoticed until it reconnects.
+func (l *PostgresListener) Check(_ context.Context) error {
+	if !l.connected.Load() {
+		return errors.New("not connected")
+	}
+	return nil
+}
+
+func (l *PostgresListener) Close() error {
+	close(l.done)
+	return l.listener.Close()
+}
+
+func (l *PostgresListener) run() {
+	for {
+		select {
+		case <-l.done:
+			return
+		case n := <-l.listener.Notify:
+			// A nil notification means the connection was re-established and
+			// notifications sent in the meantime may have been lost.
+			if n == nil {
+				l.catchUp()
+				continue
+			}
+			l.handle(n.Extra)
+		case <-time.After(90 * time.Second):
+			go func() {
+				_ = l.listener.Ping()
+			}()
+		}
+	}
+}
+
+func (l *PostgresListener) handle(payload string) {
+	var event model.UserEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		slog.Error("user event listener received an invalid payload", logging.Err(err))
+		return
+	}
+
+	if event.EventId > l.lastEventId {
+		l.lastEventId = event.EventId
+	}
+	l.broker.Publish(event)
+}
+
+func (l *PostgresListener) catchUp() {
+	for {
+		// the broker relays every tenant's events; subscribers pick out theirs.
+		// Ids are only committed in order within a tenant, but an event of
+		// another tenant committed after this read is notified.
+		events, err := l.store.GetUserEventsAfter(tenant.WithAllTenants(context.Background()), l.lastEventId, 100)
+		if err != nil {
+			slog.Error("user event listener failed to catch up", logging.Err(err))
+			return
+		}
+
+		for _, event := range events {
+			l.lastEventId = event.EventId
+			l.broker.Publish(event)
+		}
+
+		if len(events) < 100 {
+			return
+		}
+	}
+}
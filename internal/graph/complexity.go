@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// connectionDefaults is the page size assumed for list fields that are
+// queried without a "first" argument.
+var connectionDefaults = map[string]int{
+	"users": defaultPageSize,
+}
+
+// queryComplexity estimates the cost of an operation before it runs. Every
+// field costs one, and the selections under a paginated field are multiplied
+// by the number of items it may return. Syntax errors are left to the
+// executor to report.
+func queryComplexity(query string, operationName string, variables map[string]any) (int, error) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil {
+		return 0, nil
+	}
+
+	op := doc.Operations.ForName(operationName)
+	if op == nil {
+		return 0, nil
+	}
+
+	c := &complexityCounter{doc: doc, variables: variables, visiting: map[string]bool{}}
+	return c.selectionSet(op.SelectionSet)
+}
+
+type complexityCounter struct {
+	doc       *ast.QueryDocument
+	variables map[string]any
+	visiting  map[string]bool
+}
+
+func (c *complexityCounter) selectionSet(set ast.SelectionSet) (int, error) {
+	total := 0
+	for _, selection := range set {
+		var cost int
+		var err error
+
+		switch s := selection.(type) {
+		case *ast.Field:
+			cost, err = c.field(s)
+		case *ast.InlineFragment:
+			cost, err = c.selectionSet(s.SelectionSet)
+		case *ast.FragmentSpread:
+			fragment := c.doc.Fragments.ForName(s.Name)
+			if fragment == nil || c.visiting[s.Name] {
+				continue
+			}
+			c.visiting[s.Name] = true
+			cost, err = c.selectionSet(fragment.SelectionSet)
+			c.visiting[s.Name] = false
+		}
+
+		if err != nil {
+			return 0, err
+		}
+		total += cost
+	}
+	return total, nil
+}
+
+func (c *complexityCounter) field(field *ast.Field) (int, error) {
+	children, err := c.selectionSet(field.SelectionSet)
+	if err != nil {
+		return 0, err
+	}
+
+	multiplier := 1
+	if size, ok := connectionDefaults[field.Name]; ok {
+		multiplier = size
+	}
+	if arg := field.Arguments.ForName("first"); arg != nil {
+		value, err := arg.Value.Value(c.variables)
+		if err != nil {
+			return 0, err
+		}
+		// out of range values are rejected by the resolver, but must not
+		// lower or overflow the estimate before it runs
+		switch v := value.(type) {
+		case int64:
+			multiplier = int(min(max(v, 0), maxPageSize))
+		case float64:
+			multiplier = int(min(max(v, 0), maxPageSize))
+		case nil:
+		default:
+			return 0, fmt.Errorf("invalid value for first: %v", v)
+		}
+	}
+
+	return 1 + multiplier*children, nil
+}
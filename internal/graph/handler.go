@@ -0,0 +1,82 @@
+package graph
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tracing"
+	"github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/errors"
+)
+
+//go:embed schema.graphql
+var schemaString string
+
+// maxDepth leaves room for the standard introspection query, which nests
+// ofType several levels deep.
+const (
+	maxDepth      = 15
+	maxComplexity = 1000
+)
+
+type Handler struct {
+	schema *graphql.Schema
+	store  store.UserStoreInterface
+}
+
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// NewHandler serves userStore. Mutations only admins may make, such as
+// deleting users, are checked with authorizer against the claims of the
+// request's access token, which must be in its context.
+func NewHandler(userStore store.UserStoreInterface, authorizer Authorizer) *Handler {
+	schema := graphql.MustParseSchema(schemaString, &Resolver{store: userStore, authorizer: authorizer},
+		graphql.MaxDepth(maxDepth),
+	)
+
+	return &Handler{
+		schema: schema,
+		store:  userStore,
+	}
+}
+
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	var response *graphql.Response
+
+	complexity, err := queryComplexity(req.Query, req.OperationName, req.Variables)
+	switch {
+	case err != nil:
+		response = &graphql.Response{Errors: []*errors.QueryError{errors.Errorf("%s", err)}}
+	case complexity > maxComplexity:
+		response = &graphql.Response{Errors: []*errors.QueryError{
+			errors.Errorf("query complexity %d exceeds the limit of %d", complexity, maxComplexity),
+		}}
+	default:
+		ctx := withUserLoader(r.Context(), newUserLoader(handler.store))
+		response = handler.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to encode response", logging.Err(err))
+		tracing.Error(w, r, "Failed to encode response", http.StatusInternalServerError)
+		return
+	}
+}
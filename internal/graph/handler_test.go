@@ -0,0 +1,333 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+type MockUserStore struct {
+	CreateUserFn    func(model.User) (model.User, error)
+	GetAllUsersFn   func() ([]model.User, error)
+	GetUserByIdFn   func(uuid.UUID) (model.User, bool, error)
+	GetUsersByIdsFn func([]uuid.UUID) ([]model.User, error)
+	ListUsersFn     func(model.UserFilter, uuid.UUID, int) ([]model.User, error)
+	UpdateUserFn    func(model.User, uuid.UUID) (model.User, bool, error)
+	PutUserFn       func(model.User, model.Precondition) (model.User, bool, error)
+	ModifyUserFn    func(uuid.UUID, model.Precondition, func(*model.User) error) (model.User, bool, error)
+	DeleteUserFn    func(uuid.UUID) (bool, error)
+}
+
+func (m *MockUserStore) CreateUser(_ context.Context, u model.User) (model.User, error) {
+	return m.CreateUserFn(u)
+}
+func (m *MockUserStore) GetAllUsers(_ context.Context) ([]model.User, error) {
+	return m.GetAllUsersFn()
+}
+func (m *MockUserStore) GetUserById(_ context.Context, id uuid.UUID) (model.User, bool, error) {
+	return m.GetUserByIdFn(id)
+}
+func (m *MockUserStore) GetUsersByIds(_ context.Context, ids []uuid.UUID) ([]model.User, error) {
+	return m.GetUsersByIdsFn(ids)
+}
+func (m *MockUserStore) ListUsers(_ context.Context, f model.UserFilter, after uuid.UUID, limit int) ([]model.User, error) {
+	return m.ListUsersFn(f, after, limit)
+}
+func (m *MockUserStore) UpdateUser(_ context.Context, u model.User, id uuid.UUID) (model.User, bool, error) {
+	return m.UpdateUserFn(u, id)
+}
+func (m *MockUserStore) PutUser(_ context.Context, u model.User, p model.Precondition) (model.User, bool, error) {
+	return m.PutUserFn(u, p)
+}
+func (m *MockUserStore) ModifyUser(_ context.Context, id uuid.UUID, p model.Precondition, modify func(*model.User) error) (model.User, bool, error) {
+	return m.ModifyUserFn(id, p, modify)
+}
+func (m *MockUserStore) DeleteUser(_ context.Context, id uuid.UUID) (bool, error) {
+	return m.DeleteUserFn(id)
+}
+
+// stubAuthorizer answers every admin check with err.
+type stubAuthorizer struct {
+	err error
+}
+
+func (a stubAuthorizer) AuthorizeAdmin(context.Context) error {
+	return a.err
+}
+
+type graphqlResponse struct {
+	Data   map[string]json.RawMessage `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// execute runs query as an admin.
+func execute(t *testing.T, userStore store.UserStoreInterface, query string, variables map[string]any) graphqlResponse {
+	t.Helper()
+	return executeAs(t, userStore, stubAuthorizer{}, query, variables)
+}
+
+func executeAs(t *testing.T, userStore store.UserStoreInterface, authorizer Authorizer, query string, variables map[string]any) graphqlResponse {
+	t.Helper()
+
+	body, _ := json.Marshal(map[string]any{"query": query, "variables": variables})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewBuffer(body))
+	w := httptest.NewRecorder()
+
+	NewHandler(userStore, authorizer).ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+
+	var resp graphqlResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestUsers_FilterAndPagination(t *testing.T) {
+	users := []model.User{
+		{UserId: uuid.New(), FirstName: "John", Status: model.StatusActive},
+		{UserId: uuid.New(), FirstName: "Jane", Status: model.StatusActive},
+		{UserId: uuid.New(), FirstName: "Jim", Status: model.StatusActive},
+	}
+
+	var gotFilter model.UserFilter
+	var gotLimit int
+	mockStore := &MockUserStore{
+		ListUsersFn: func(f model.UserFilter, _ uuid.UUID, limit int) ([]model.User, error) {
+			gotFilter, gotLimit = f, limit
+			return users, nil
+		},
+	}
+
+	resp := execute(t, mockStore, `{
+		users(filter: {status: Active}, first: 2) {
+			edges { cursor node { firstName } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`, nil)
+
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+	if gotFilter.Status != model.StatusActive || gotLimit != 3 {
+		t.Fatalf("expected Active filter with limit 3, got %+v limit %d", gotFilter, gotLimit)
+	}
+
+	var data struct {
+		Edges []struct {
+			Cursor string
+			Node   struct{ FirstName string }
+		}
+		PageInfo struct {
+			HasNextPage bool
+			EndCursor   string
+		}
+	}
+	if err := json.Unmarshal(resp.Data["users"], &data); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Edges) != 2 || !data.PageInfo.HasNextPage {
+		t.Fatalf("expected 2 edges and a next page, got %d edges, hasNextPage=%v", len(data.Edges), data.PageInfo.HasNextPage)
+	}
+	if after, err := decodeCursor(data.PageInfo.EndCursor); err != nil || after != users[1].UserId {
+		t.Fatalf("expected end cursor to point at the second user, got %v (%v)", after, err)
+	}
+}
+
+func TestUser_BatchesLookups(t *testing.T) {
+	first, second := uuid.New(), uuid.New()
+
+	var calls int32
+	mockStore := &MockUserStore{
+		GetUsersByIdsFn: func(ids []uuid.UUID) ([]model.User, error) {
+			atomic.AddInt32(&calls, 1)
+			users := make([]model.User, len(ids))
+			for i, id := range ids {
+				users[i] = model.User{UserId: id, FirstName: "User"}
+			}
+			return users, nil
+		},
+	}
+
+	resp := execute(t, mockStore, `query($a: ID!, $b: ID!) {
+		a: user(id: $a) { userId }
+		b: user(id: $b) { userId }
+	}`, map[string]any{"a": first.String(), "b": second.String()})
+
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 batched lookup, got %d", calls)
+	}
+}
+
+func TestCreateUser_FieldErrors(t *testing.T) {
+	resp := execute(t, &MockUserStore{}, `mutation {
+		createUser(input: {firstName: "J", lastName: "Doe", email: "invalid-email", phone: "+94712345678"}) {
+			user { userId }
+			userErrors { field message }
+		}
+	}`, nil)
+
+	if len(resp.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", resp.Errors)
+	}
+
+	var payload struct {
+		User       *struct{ UserId string }
+		UserErrors []struct{ Field, Message string }
+	}
+	if err := json.Unmarshal(resp.Data["createUser"], &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if payload.User != nil {
+		t.Fatalf("expected no user on validation failure")
+	}
+	fields := map[string]bool{}
+	for _, e := range payload.UserErrors {
+		fields[e.Field] = true
+	}
+	if len(fields) != 2 || !fields["firstName"] || !fields["email"] {
+		t.Fatalf("expected firstName and email errors, got %+v", payload.UserErrors)
+	}
+}
+
+func TestCreateUser_DuplicateEmail(t *testing.T) {
+	mockStore := &MockUserStore{
+		CreateUserFn: func(model.User) (model.User, error) {
+			return model.User{}, store.ErrDuplicateEmail
+		},
+	}
+
+	resp := execute(t, mockStore, `mutation {
+		createUser(input: {firstName: "John", lastName: "Doe", email: "john@gmail.com", phone: "+94712345678"}) {
+			userErrors { field }
+		}
+	}`, nil)
+
+	if !strings.Contains(string(resp.Data["createUser"]), `"field":"email"`) {
+		t.Fatalf("expected an email field error, got %s", resp.Data["createUser"])
+	}
+}
+
+func TestCreateUser_InvalidPhone(t *testing.T) {
+	mockStore := &MockUserStore{
+		CreateUserFn: func(model.User) (model.User, error) {
+			return model.User{}, fmt.Errorf("%w \"0712345678\": invalid country code", phonenumber.ErrInvalid)
+		},
+	}
+
+	resp := execute(t, mockStore, `mutation {
+		createUser(input: {firstName: "John", lastName: "Doe", email: "john@gmail.com", phone: "0712345678"}) {
+			userErrors { field }
+		}
+	}`, nil)
+
+	if !strings.Contains(string(resp.Data["createUser"]), `"field":"phone"`) {
+		t.Fatalf("expected a phone field error, got %s", resp.Data["createUser"])
+	}
+}
+
+func TestDeleteUser_RequiresAdmin(t *testing.T) {
+	userId := uuid.New()
+	var deleted []uuid.UUID
+	userStore := &MockUserStore{
+		DeleteUserFn: func(id uuid.UUID) (bool, error) {
+			deleted = append(deleted, id)
+			return true, nil
+		},
+	}
+	const query = `mutation($id: ID!) { deleteUser(id: $id) { deletedUserId userErrors { field message } } }`
+	variables := map[string]any{"id": userId.String()}
+
+	for _, err := range []error{auth.ErrAdminRequired, auth.ErrAdminMFARequired} {
+		resp := executeAs(t, userStore, stubAuthorizer{err: err}, query, variables)
+		if len(resp.Errors) != 1 || resp.Errors[0].Message != err.Error() {
+			t.Errorf("expected %q, got %+v", err, resp.Errors)
+		}
+	}
+	if len(deleted) != 0 {
+		t.Fatalf("expected no user to be deleted, got %v", deleted)
+	}
+
+	resp := execute(t, userStore, query, variables)
+	if len(resp.Errors) != 0 || len(deleted) != 1 || deleted[0] != userId {
+		t.Errorf("expected the admin to delete the user, got %+v, %v", resp.Errors, deleted)
+	}
+}
+
+func TestComplexityLimit(t *testing.T) {
+	resp := execute(t, &MockUserStore{}, `{
+		users(first: 100) {
+			edges { node { userId firstName lastName email phone age status } }
+		}
+		more: users(first: 100) {
+			edges { node { userId firstName lastName email phone age status } }
+		}
+	}`, nil)
+
+	if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "complexity") {
+		t.Fatalf("expected a complexity error, got %+v", resp.Errors)
+	}
+}
+
+func TestComplexity_ClampsFirst(t *testing.T) {
+	const query = `query($first: Int) { users(first: $first) { edges { node { userId email } } } }`
+	complexity := func(first any) int {
+		t.Helper()
+		c, err := queryComplexity(query, "", map[string]any{"first": first})
+		if err != nil {
+			t.Fatalf("queryComplexity failed: %v", err)
+		}
+		return c
+	}
+
+	if got, want := complexity(float64(-1000000)), complexity(float64(0)); got != want {
+		t.Errorf("expected a negative first to cost %d like first: 0, got %d", want, got)
+	}
+	if got, want := complexity(float64(1<<40)), complexity(float64(maxPageSize)); got != want {
+		t.Errorf("expected an oversized first to cost %d like first: %d, got %d", want, maxPageSize, got)
+	}
+
+	// a negative page must not pay for a sibling that is over the limit
+	resp := execute(t, &MockUserStore{}, `{
+		users(first: -1000000) { edges { node { userId } } }
+		a: users(first: 100) { edges { node { userId firstName lastName email phone age status } } }
+		b: users(first: 100) { edges { node { userId firstName lastName email phone age status } } }
+	}`, nil)
+	if len(resp.Errors) != 1 || !strings.Contains(resp.Errors[0].Message, "complexity") {
+		t.Fatalf("expected a complexity error, got %+v", resp.Errors)
+	}
+}
+
+func TestDepthLimit(t *testing.T) {
+	query := "{ __schema { types { fields { type " +
+		strings.Repeat("{ ofType ", maxDepth) + "{ name }" + strings.Repeat(" }", maxDepth) +
+		" } } } }"
+
+	resp := execute(t, &MockUserStore{}, query, nil)
+
+	if len(resp.Errors) == 0 {
+		t.Fatalf("expected a depth error")
+	}
+}
@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+	"github.com/graph-gophers/dataloader/v7"
+)
+
+type loaderKey struct{}
+
+type userLoader = dataloader.Loader[uuid.UUID, *model.User]
+
+// newUserLoader collects the user ids requested while resolving one query and
+// fetches them with a single GetUsersByIds call.
+func newUserLoader(userStore store.UserStoreInterface) *userLoader {
+	batch := func(ctx context.Context, userIds []uuid.UUID) []*dataloader.Result[*model.User] {
+		results := make([]*dataloader.Result[*model.User], len(userIds))
+
+		users, err := userStore.GetUsersByIds(ctx, userIds)
+		if err != nil {
+			for i := range results {
+				results[i] = &dataloader.Result[*model.User]{Error: err}
+			}
+			return results
+		}
+
+		byId := make(map[uuid.UUID]*model.User, len(users))
+		for i := range users {
+			byId[users[i].UserId] = &users[i]
+		}
+
+		for i, userId := range userIds {
+			results[i] = &dataloader.Result[*model.User]{Data: byId[userId]}
+		}
+		return results
+	}
+
+	return dataloader.NewBatchedLoader(batch, dataloader.WithWait[uuid.UUID, *model.User](2*time.Millisecond))
+}
+
+func withUserLoader(ctx context.Context, loader *userLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, loader)
+}
+
+// loadUser goes through the request's loader when there is one and falls back
+// to a direct lookup otherwise.
+func loadUser(ctx context.Context, userStore store.UserStoreInterface, userId uuid.UUID) (model.User, bool, error) {
+	loader, ok := ctx.Value(loaderKey{}).(*userLoader)
+	if !ok {
+		return userStore.GetUserById(ctx, userId)
+	}
+
+	user, err := loader.Load(ctx, userId)()
+	if err != nil {
+		return model.User{}, false, err
+	}
+	if user == nil {
+		return model.User{}, false, nil
+	}
+	return *user, true, nil
+}
@@ -0,0 +1,334 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"example.com/user-management/internal/attributes"
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/emailaddr"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/mapper"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/store"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	cursorPrefix    = "user:"
+)
+
+var validate = newValidator()
+
+// newValidator reports field errors by their json names, which are also the
+// GraphQL input field names.
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		return strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	})
+	return v
+}
+
+// Authorizer decides who may make the mutations only admins may make.
+type Authorizer interface {
+	// AuthorizeAdmin returns auth.ErrAdminRequired or
+	// auth.ErrAdminMFARequired unless the request ctx belongs to was made
+	// by an admin who logged in with a second factor.
+	AuthorizeAdmin(ctx context.Context) error
+}
+
+type Resolver struct {
+	store      store.UserStoreInterface
+	authorizer Authorizer
+}
+
+type userFilterInput struct {
+	Status *string
+	Email  *string
+	Name   *string
+	Group  *graphql.ID
+}
+
+type createUserInput struct {
+	FirstName string
+	LastName  string
+	Email     string
+	Phone     string
+	Age       *int32
+	Status    *string
+}
+
+type updateUserInput struct {
+	FirstName *string
+	LastName  *string
+	Email     *string
+	Phone     *string
+	Age       *int32
+	Status    *string
+}
+
+func (r *Resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	userId, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	user, ok, err := loadUser(ctx, r.store, userId)
+	if err != nil {
+		return nil, internalError(ctx, "failed to retrieve user", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	return &userResolver{user: user}, nil
+}
+
+func (r *Resolver) Users(ctx context.Context, args struct {
+	Filter *userFilterInput
+	First  *int32
+	After  *string
+}) (*userConnectionResolver, error) {
+	limit := defaultPageSize
+	if args.First != nil {
+		limit = int(*args.First)
+	}
+	if limit < 0 || limit > maxPageSize {
+		return nil, fmt.Errorf("first must be between 0 and %d", maxPageSize)
+	}
+
+	after := uuid.Nil
+	if args.After != nil {
+		var err error
+		after, err = decodeCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var filter model.UserFilter
+	if args.Filter != nil {
+		if args.Filter.Status != nil {
+			filter.Status = model.Status(*args.Filter.Status)
+		}
+		if args.Filter.Email != nil {
+			filter.Email = *args.Filter.Email
+		}
+		if args.Filter.Name != nil {
+			filter.Name = *args.Filter.Name
+		}
+		if args.Filter.Group != nil {
+			groupId, err := uuid.Parse(string(*args.Filter.Group))
+			if err != nil {
+				return nil, errors.New("invalid group id")
+			}
+			filter.GroupId = groupId
+		}
+	}
+
+	// fetch one extra row to learn whether another page exists
+	users, err := r.store.ListUsers(ctx, filter, after, limit+1)
+	if err != nil {
+		return nil, internalError(ctx, "failed to retrieve users", err)
+	}
+
+	hasNextPage := len(users) > limit
+	if hasNextPage {
+		users = users[:limit]
+	}
+
+	return &userConnectionResolver{users: users, hasNextPage: hasNextPage}, nil
+}
+
+func (r *Resolver) CreateUser(ctx context.Context, args struct{ Input createUserInput }) (*userPayloadResolver, error) {
+	req := dto.CreateUserRequest{
+		FirstName: args.Input.FirstName,
+		LastName:  args.Input.LastName,
+		Email:     strings.TrimSpace(args.Input.Email),
+		Phone:     args.Input.Phone,
+	}
+	if args.Input.Age != nil {
+		req.Age = int(*args.Input.Age)
+	}
+	if args.Input.Status != nil {
+		req.Status = model.Status(*args.Input.Status)
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return &userPayloadResolver{errors: fieldErrors(err)}, nil
+	}
+
+	createdUser, err := r.store.CreateUser(ctx, mapper.CreateUserRequestToModel(req))
+	if err != nil {
+		if errors.Is(err, store.ErrDuplicateEmail) {
+			return &userPayloadResolver{errors: []fieldErrorResolver{{field: "email", message: "is already in use"}}}, nil
+		}
+		if errors.Is(err, emailaddr.ErrInvalid) {
+			return &userPayloadResolver{errors: []fieldErrorResolver{{field: "email", message: err.Error()}}}, nil
+		}
+		if errors.Is(err, phonenumber.ErrInvalid) {
+			return &userPayloadResolver{errors: []fieldErrorResolver{{field: "phone", message: err.Error()}}}, nil
+		}
+		if errors.Is(err, attributes.ErrInvalid) {
+			return &userPayloadResolver{errors: []fieldErrorResolver{{field: "attributes", message: err.Error()}}}, nil
+		}
+		return nil, internalError(ctx, "failed to create user", err)
+	}
+
+	return &userPayloadResolver{user: &createdUser}, nil
+}
+
+func (r *Resolver) UpdateUser(ctx context.Context, args struct {
+	ID    graphql.ID
+	Input updateUserInput
+}) (*userPayloadResolver, error) {
+	userId, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return &userPayloadResolver{errors: []fieldErrorResolver{{field: "id", message: "is not a valid user id"}}}, nil
+	}
+
+	req := dto.UpdateUserRequest{
+		FirstName: args.Input.FirstName,
+		LastName:  args.Input.LastName,
+		Email:     args.Input.Email,
+		Phone:     args.Input.Phone,
+	}
+	if args.Input.Email != nil {
+		email := strings.TrimSpace(*args.Input.Email)
+		req.Email = &email
+	}
+	if args.Input.Age != nil {
+		age := int(*args.Input.Age)
+		req.Age = &age
+	}
+	if args.Input.Status != nil {
+		status := model.Status(*args.Input.Status)
+		req.Status = &status
+	}
+
+	if err := validate.Struct(req); err != nil {
+		return &userPayloadResolver{errors: fieldErrors(err)}, nil
+	}
+
+	user, ok, err := r.store.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, internalError(ctx, "failed to retrieve user", err)
+	}
+	if !ok {
+		return &userPayloadResolver{errors: []fieldErrorResolver{{field: "id", message: "user not found"}}}, nil
+	}
+
+	mapper.ApplyUpdateUserRequest(&user, req)
+	updatedUser, ok, err := r.store.UpdateUser(ctx, user, userId)
+	if err != nil {
+		if errors.Is(err, store.ErrDuplicateEmail) {
+			return &userPayloadResolver{errors: []fieldErrorResolver{{field: "email", message: "is already in use"}}}, nil
+		}
+		if errors.Is(err, emailaddr.ErrInvalid) {
+			return &userPayloadResolver{errors: []fieldErrorResolver{{field: "email", message: err.Error()}}}, nil
+		}
+		if errors.Is(err, phonenumber.ErrInvalid) {
+			return &userPayloadResolver{errors: []fieldErrorResolver{{field: "phone", message: err.Error()}}}, nil
+		}
+		if errors.Is(err, attributes.ErrInvalid) {
+			return &userPayloadResolver{errors: []fieldErrorResolver{{field: "attributes", message: err.Error()}}}, nil
+		}
+		return nil, internalError(ctx, "failed to update user", err)
+	}
+	if !ok {
+		return &userPayloadResolver{errors: []fieldErrorResolver{{field: "id", message: "user not found"}}}, nil
+	}
+
+	return &userPayloadResolver{user: &updatedUser}, nil
+}
+
+func (r *Resolver) DeleteUser(ctx context.Context, args struct{ ID graphql.ID }) (*deleteUserPayloadResolver, error) {
+	userId, err := uuid.Parse(string(args.ID))
+	if err != nil {
+		return &deleteUserPayloadResolver{errors: []fieldErrorResolver{{field: "id", message: "is not a valid user id"}}}, nil
+	}
+
+	// deleting users is for admins, as on the REST API
+	if err := r.authorizer.AuthorizeAdmin(ctx); err != nil {
+		if errors.Is(err, auth.ErrAdminRequired) || errors.Is(err, auth.ErrAdminMFARequired) {
+			return nil, err
+		}
+		return nil, internalError(ctx, "failed to check admin access", err)
+	}
+
+	ok, err := r.store.DeleteUser(ctx, userId)
+	if err != nil {
+		return nil, internalError(ctx, "failed to delete user", err)
+	}
+	if !ok {
+		return &deleteUserPayloadResolver{errors: []fieldErrorResolver{{field: "id", message: "user not found"}}}, nil
+	}
+
+	return &deleteUserPayloadResolver{userId: &userId}, nil
+}
+
+func encodeCursor(userId uuid.UUID) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + userId.String()))
+}
+
+func decodeCursor(cursor string) (uuid.UUID, error) {
+	raw, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), cursorPrefix) {
+		return uuid.Nil, errors.New("invalid cursor")
+	}
+
+	userId, err := uuid.Parse(strings.TrimPrefix(string(raw), cursorPrefix))
+	if err != nil {
+		return uuid.Nil, errors.New("invalid cursor")
+	}
+	return userId, nil
+}
+
+func fieldErrors(err error) []fieldErrorResolver {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return []fieldErrorResolver{{field: "input", message: err.Error()}}
+	}
+
+	result := make([]fieldErrorResolver, len(validationErrors))
+	for i, fe := range validationErrors {
+		result[i] = fieldErrorResolver{field: fe.Field(), message: validationMessage(fe)}
+	}
+	return result
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return fmt.Sprintf("must be at least %s characters", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", fe.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fe.Param())
+	}
+	return fmt.Sprintf("failed the %s check", fe.Tag())
+}
+
+// internalError logs err with its cause chain and returns an error carrying
+// only message, so that internal details stay out of the response.
+func internalError(ctx context.Context, message string, err error) error {
+	logging.FromContext(ctx).Error(message, logging.Err(err))
+	return errors.New(message)
+}
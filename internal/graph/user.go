@@ -0,0 +1,156 @@
+package graph
+
+import (
+	"example.com/user-management/internal/model"
+	"github.com/google/uuid"
+	"github.com/graph-gophers/graphql-go"
+)
+
+type userResolver struct {
+	user model.User
+}
+
+func (r *userResolver) UserId() graphql.ID {
+	return graphql.ID(r.user.UserId.String())
+}
+
+func (r *userResolver) FirstName() string {
+	return r.user.FirstName
+}
+
+func (r *userResolver) LastName() string {
+	return r.user.LastName
+}
+
+func (r *userResolver) Email() string {
+	return r.user.Email
+}
+
+func (r *userResolver) Phone() string {
+	return r.user.Phone
+}
+
+func (r *userResolver) PhoneCountry() *string {
+	if r.user.PhoneCountry == "" {
+		return nil
+	}
+	return &r.user.PhoneCountry
+}
+
+func (r *userResolver) PhoneType() *string {
+	if r.user.PhoneType == "" {
+		return nil
+	}
+	return &r.user.PhoneType
+}
+
+func (r *userResolver) Age() *int32 {
+	if r.user.Age <= 0 {
+		return nil
+	}
+	age := int32(r.user.Age)
+	return &age
+}
+
+func (r *userResolver) Status() string {
+	return string(r.user.Status)
+}
+
+type userConnectionResolver struct {
+	users       []model.User
+	hasNextPage bool
+}
+
+func (r *userConnectionResolver) Edges() []*userEdgeResolver {
+	edges := make([]*userEdgeResolver, len(r.users))
+	for i, u := range r.users {
+		edges[i] = &userEdgeResolver{user: u}
+	}
+	return edges
+}
+
+func (r *userConnectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{hasNextPage: r.hasNextPage}
+	if len(r.users) > 0 {
+		cursor := encodeCursor(r.users[len(r.users)-1].UserId)
+		info.endCursor = &cursor
+	}
+	return info
+}
+
+type userEdgeResolver struct {
+	user model.User
+}
+
+func (r *userEdgeResolver) Cursor() string {
+	return encodeCursor(r.user.UserId)
+}
+
+func (r *userEdgeResolver) Node() *userResolver {
+	return &userResolver{user: r.user}
+}
+
+type pageInfoResolver struct {
+	hasNextPage bool
+	endCursor   *string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool {
+	return r.hasNextPage
+}
+
+func (r *pageInfoResolver) EndCursor() *string {
+	return r.endCursor
+}
+
+type fieldErrorResolver struct {
+	field   string
+	message string
+}
+
+func (r fieldErrorResolver) Field() string {
+	return r.field
+}
+
+func (r fieldErrorResolver) Message() string {
+	return r.message
+}
+
+type userPayloadResolver struct {
+	user   *model.User
+	errors []fieldErrorResolver
+}
+
+func (r *userPayloadResolver) User() *userResolver {
+	if r.user == nil {
+		return nil
+	}
+	return &userResolver{user: *r.user}
+}
+
+func (r *userPayloadResolver) UserErrors() []fieldErrorResolver {
+	if r.errors == nil {
+		return []fieldErrorResolver{}
+	}
+	return r.errors
+}
+
+type deleteUserPayloadResolver struct {
+	userId *uuid.UUID
+	errors []fieldErrorResolver
+}
+
+func (r *deleteUserPayloadResolver) DeletedUserId() *graphql.ID {
+	if r.userId == nil {
+		return nil
+	}
+	id := graphql.ID(r.userId.String())
+	return &id
+}
+
+func (r *deleteUserPayloadResolver) UserErrors() []fieldErrorResolver {
+	if r.errors == nil {
+		return []fieldErrorResolver{}
+	}
+	return r.errors
+}
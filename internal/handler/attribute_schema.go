@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"example.com/user-management/internal/attributes"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tracing"
+)
+
+type AttributeSchemaHandler struct {
+	store store.AttributeSchemaStoreInterface
+}
+
+func NewAttributeSchemaHandler(store store.AttributeSchemaStoreInterface) *AttributeSchemaHandler {
+	return &AttributeSchemaHandler{
+		store: store,
+	}
+}
+
+// GetAttributeSchema godoc
+// @Summary Get the attribute schema
+// @Description Retrieve the JSON Schema the custom attributes of the tenant's users must satisfy
+// @Tags Attributes
+// @Produce json
+// @Success 200 {object} model.AttributeSchema
+// @Failure 404 {string} string "Attribute Schema Not Found"
+// @Failure 500 {string} string "Failed to Retrieve Attribute Schema"
+// @Router /attribute-schema [get]
+func (handler *AttributeSchemaHandler) GetAttributeSchema(w http.ResponseWriter, r *http.Request) {
+	schema, ok, err := handler.store.GetAttributeSchema(r.Context())
+
+	if err != nil {
+		serverError(w, r, "Failed to Retrieve Attribute Schema!", err)
+		return
+	}
+
+	if !ok {
+		tracing.Error(w, r, "Attribute Schema Not Found!", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(schema)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
+
+// SetAttributeSchema godoc
+// @Summary Set the attribute schema
+// @Description Replace the JSON Schema the custom attributes of the tenant's users must satisfy. The body is the
+// @Description schema itself. It may not refer to other documents. Existing users are checked against it when
+// @Description next updated. Only admins may set the schema, for the tenant they logged in to.
+// @Tags Attributes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param schema body object true "JSON Schema"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {string} string "Invalid Request Body or Attribute Schema"
+// @Failure 401 {string} string "Access Token Required"
+// @Failure 403 {string} string "Admin Access Required, With a Second Factor"
+// @Failure 500 {string} string "Failed to Save Attribute Schema"
+// @Router /attribute-schema [put]
+func (handler *AttributeSchemaHandler) SetAttributeSchema(w http.ResponseWriter, r *http.Request) {
+	var body json.RawMessage
+
+	err := json.NewDecoder(r.Body).Decode(&body)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	schema, err := handler.store.SetAttributeSchema(r.Context(), body)
+
+	if errors.Is(err, attributes.ErrInvalidSchema) {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to Save Attribute Schema!", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Attribute Schema Saved!",
+		"schema":  schema,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
+
+// DeleteAttributeSchema godoc
+// @Summary Delete the attribute schema
+// @Description Remove the attribute schema, after which users may have any custom attributes. Only admins may delete the schema, for the tenant they logged in to.
+// @Tags Attributes
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {string} string "Access Token Required"
+// @Failure 403 {string} string "Admin Access Required, With a Second Factor"
+// @Failure 404 {string} string "Attribute Schema Not Found"
+// @Failure 500 {string} string "Failed to Delete Attribute Schema"
+// @Router /attribute-schema [delete]
+func (handler *AttributeSchemaHandler) DeleteAttributeSchema(w http.ResponseWriter, r *http.Request) {
+	ok, err := handler.store.DeleteAttributeSchema(r.Context())
+
+	if err != nil {
+		serverError(w, r, "Failed to Delete Attribute Schema!", err)
+		return
+	}
+
+	if !ok {
+		tracing.Error(w, r, "Attribute Schema Not Found!", http.StatusNotFound)
+		return
+	}
+
+	writeMessage(w, r, http.StatusOK, "Attribute Schema Deleted successfully!")
+}
@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store/memory"
+	"github.com/go-chi/chi/v5"
+)
+
+func newAttributeSchemaRouter(t *testing.T) http.Handler {
+	t.Helper()
+
+	userStore := memory.NewUserStore()
+	handler := NewAttributeSchemaHandler(userStore)
+
+	router := chi.NewRouter()
+	router.Post("/users", NewUserHandler(userStore).CreateUser)
+	router.Get("/attribute-schema", handler.GetAttributeSchema)
+	router.Put("/attribute-schema", handler.SetAttributeSchema)
+	router.Delete("/attribute-schema", handler.DeleteAttributeSchema)
+	return router
+}
+
+func putSchema(router http.Handler, schema string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/attribute-schema", bytes.NewBufferString(schema)))
+	return w
+}
+
+func TestAttributeSchema_SetGetDelete(t *testing.T) {
+	router := newAttributeSchemaRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/attribute-schema", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 before a schema is set, got %d", w.Code)
+	}
+
+	schema := `{"type": "object", "properties": {"department": {"type": "string"}}, "required": ["department"]}`
+	if w := putSchema(router, schema); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	got := getJSON[model.AttributeSchema](t, router, "/attribute-schema")
+	var decoded map[string]any
+	if err := json.Unmarshal(got.Schema, &decoded); err != nil || decoded["type"] != "object" {
+		t.Errorf("Expected the schema back, got %s", got.Schema)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/attribute-schema", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/attribute-schema", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 once deleted, got %d", w.Code)
+	}
+}
+
+func TestAttributeSchema_Invalid(t *testing.T) {
+	router := newAttributeSchemaRouter(t)
+
+	tests := []struct {
+		name   string
+		schema string
+	}{
+		{"not json", `{"type":`},
+		{"not a schema", `{"type": "thing"}`},
+		{"file reference", `{"$ref": "file:///etc/passwd"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if w := putSchema(router, test.schema); w.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400, got %d: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestAttributeSchema_ValidatesUsers(t *testing.T) {
+	router := newAttributeSchemaRouter(t)
+
+	schema := `{"type": "object", "properties": {"employeeNumber": {"type": "integer"}}}`
+	if w := putSchema(router, schema); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	user := map[string]any{
+		"firstName":  "John",
+		"lastName":   "Doe",
+		"email":      "john@example.com",
+		"phone":      "+94712345678",
+		"age":        27,
+		"status":     "Active",
+		"attributes": map[string]any{"employeeNumber": "forty-two"},
+	}
+	if w := postJSON(router, "/users", user); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid attributes, got %d: %s", w.Code, w.Body)
+	}
+
+	user["attributes"] = map[string]any{"employeeNumber": 42}
+	if w := postJSON(router, "/users", user); w.Code != http.StatusCreated {
+		t.Errorf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestAttributeSchema_RequiresAdmin(t *testing.T) {
+	router, userStore, sender := newAuthRouter(t)
+	_, tokens := newPasswordUser(t, router, userStore, sender)
+	admin := loginAdmin(t, router, sender)
+
+	schema := `{"type": "object"}`
+	for _, test := range []struct {
+		name         string
+		accessToken  string
+		expectedCode int
+	}{
+		{"no access token", "", http.StatusUnauthorized},
+		{"not an admin", tokens.AccessToken, http.StatusForbidden},
+		{"admin", admin.AccessToken, http.StatusOK},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/attribute-schema", bytes.NewBufferString(schema))
+			if test.accessToken != "" {
+				req.Header.Set("Authorization", "Bearer "+test.accessToken)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != test.expectedCode {
+				t.Errorf("Expected %d, got %d: %s", test.expectedCode, w.Code, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tracing"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 100
+)
+
+type AuditHandler struct {
+	store store.AuditStoreInterface
+}
+
+func NewAuditHandler(store store.AuditStoreInterface) *AuditHandler {
+	return &AuditHandler{
+		store: store,
+	}
+}
+
+// ListAuditEvents godoc
+// @Summary List a user's audit events
+// @Description Get the security-relevant steps recorded for a user, such as logins and password resets, oldest first. Events are kept after the user is deleted.
+// @Tags Users
+// @Produce json
+// @Param id path string true "User ID"
+// @Param after query int false "Only return events after this audit id"
+// @Param limit query int false "Maximum number of events, 1 to 100" default(50)
+// @Success 200 {array} model.AuditEvent
+// @Failure 400 {string} string "Invalid User Id, After or Limit"
+// @Failure 500 {string} string "Failed to Retrieve Audit Events"
+// @Router /users/{id}/audit-events [get]
+func (handler *AuditHandler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "id")
+	parsedId, err := uuid.Parse(userId)
+	if err != nil {
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", parsedId.String()))
+
+	var after int64
+	if value := r.URL.Query().Get("after"); value != "" {
+		after, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || after < 0 {
+			tracing.Error(w, r, "Invalid After!", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := defaultAuditLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			tracing.Error(w, r, "Invalid Limit!", http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, err := handler.store.ListAuditEvents(r.Context(), parsedId, after, limit)
+	if err != nil {
+		serverError(w, r, "Failed to Retrieve Audit Events!", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(events)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
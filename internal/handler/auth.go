@@ -0,0 +1,392 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/token"
+	"example.com/user-management/internal/tracing"
+)
+
+type AuthHandler struct {
+	authenticator *auth.Authenticator
+}
+
+func NewAuthHandler(authenticator *auth.Authenticator) *AuthHandler {
+	return &AuthHandler{
+		authenticator: authenticator,
+	}
+}
+
+// Login godoc
+// @Summary Log in
+// @Description Start a session with an email and password, returning an access token and a refresh token. Users with an authenticator app get an MFA token instead, to finish logging in at /auth/login/mfa.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param credentials body dto.LoginRequest true "Email and password"
+// @Success 200 {object} dto.TokenResponse
+// @Success 202 {object} dto.MFAChallengeResponse
+// @Failure 400 {string} string "Invalid Request Body"
+// @Failure 401 {string} string "Invalid Email or Password"
+// @Failure 500 {string} string "Failed to Log In"
+// @Router /auth/login [post]
+func (handler *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req dto.LoginRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := handler.authenticator.Login(r.Context(), req.Email, req.Password)
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		tracing.Error(w, r, "Invalid Email or Password!", http.StatusUnauthorized)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Log In!", err)
+		return
+	}
+
+	if tokens.MFAToken != "" {
+		writeMFAChallenge(w, r, tokens)
+		return
+	}
+	writeTokens(w, r, tokens)
+}
+
+// LoginMFA godoc
+// @Summary Finish logging in with a second factor
+// @Description Exchange the MFA token from a login and a code from the user's authenticator app, or one of their recovery codes, for an access token and a refresh token
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param code body dto.LoginMFARequest true "MFA token and code"
+// @Success 200 {object} dto.TokenResponse
+// @Failure 400 {string} string "Invalid Request Body"
+// @Failure 401 {string} string "Wrong Code or Login Ended"
+// @Failure 429 {string} string "Too Many Attempts"
+// @Failure 500 {string} string "Failed to Log In"
+// @Router /auth/login/mfa [post]
+func (handler *AuthHandler) LoginMFA(w http.ResponseWriter, r *http.Request) {
+	var req dto.LoginMFARequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := handler.authenticator.LoginMFA(r.Context(), req.MFAToken, req.Code)
+	switch {
+	case errors.Is(err, store.ErrWrongCode):
+		tracing.Error(w, r, "Wrong Code!", http.StatusUnauthorized)
+		return
+	case errors.Is(err, store.ErrChallengeNotFound), errors.Is(err, store.ErrChallengeExpired), errors.Is(err, auth.ErrInvalidCredentials):
+		tracing.Error(w, r, "Login Ended, Log In Again!", http.StatusUnauthorized)
+		return
+	case errors.Is(err, store.ErrTooManyAttempts):
+		tracing.Error(w, r, "Too Many Attempts, Log In Again!", http.StatusTooManyRequests)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Log In!", err)
+		return
+	}
+
+	writeTokens(w, r, tokens)
+}
+
+// Refresh godoc
+// @Summary Refresh a session
+// @Description Exchange a refresh token for a new access token and refresh token. Each refresh token can be used once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param refreshToken body dto.RefreshRequest true "Refresh token"
+// @Success 200 {object} dto.TokenResponse
+// @Failure 400 {string} string "Invalid Request Body"
+// @Failure 401 {string} string "Session Ended"
+// @Failure 500 {string} string "Failed to Refresh Session"
+// @Router /auth/refresh [post]
+func (handler *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req dto.RefreshRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := handler.authenticator.Refresh(r.Context(), req.RefreshToken)
+	switch {
+	case errors.Is(err, store.ErrSessionNotFound), errors.Is(err, store.ErrSessionExpired):
+		tracing.Error(w, r, "Session Ended, Log In Again!", http.StatusUnauthorized)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Refresh Session!", err)
+		return
+	}
+
+	writeTokens(w, r, tokens)
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description End the session a refresh token belongs to
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param refreshToken body dto.RefreshRequest true "Refresh token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {string} string "Invalid Request Body"
+// @Failure 401 {string} string "Session Ended"
+// @Failure 500 {string} string "Failed to Log Out"
+// @Router /auth/logout [post]
+func (handler *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req dto.RefreshRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = handler.authenticator.Logout(r.Context(), req.RefreshToken)
+	switch {
+	case errors.Is(err, store.ErrSessionNotFound):
+		tracing.Error(w, r, "Session Ended!", http.StatusUnauthorized)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Log Out!", err)
+		return
+	}
+
+	writeMessage(w, r, http.StatusOK, "Logged out successfully!")
+}
+
+// ForgotPassword godoc
+// @Summary Request a password reset
+// @Description Email the user a single-use link to choose a new password. The response is the same whether or not the email belongs to a user.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param email body dto.ForgotPasswordRequest true "Email address"
+// @Success 202 {object} map[string]string
+// @Failure 400 {string} string "Invalid Request Body"
+// @Router /auth/password/forgot [post]
+func (handler *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.ForgotPasswordRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// failures are logged but not reported, so that they do not reveal
+	// whether the email belongs to a user
+	if err := handler.authenticator.ForgotPassword(r.Context(), req.Email); err != nil {
+		logging.FromContext(r.Context()).Error("failed to send password reset", logging.Err(err))
+	}
+
+	writeMessage(w, r, http.StatusAccepted, "If the email belongs to a user, a password reset link has been sent!")
+}
+
+// ResetPassword godoc
+// @Summary Reset a password
+// @Description Set a new password with the token from a password reset email. All of the user's sessions are ended.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param reset body dto.ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {string} string "Invalid Request Body, Token or Password"
+// @Failure 409 {string} string "Reset Already Used"
+// @Failure 410 {string} string "Reset Expired"
+// @Failure 500 {string} string "Failed to Reset Password"
+// @Router /auth/password/reset [post]
+func (handler *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req dto.ResetPasswordRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = handler.authenticator.ResetPassword(r.Context(), req.Token, req.Password)
+	switch {
+	case errors.Is(err, token.ErrInvalid), errors.Is(err, store.ErrResetNotFound):
+		tracing.Error(w, r, "Invalid Reset Token!", http.StatusBadRequest)
+		return
+	case errors.Is(err, auth.ErrWeakPassword):
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, store.ErrResetUsed):
+		tracing.Error(w, r, "Reset Token Already Used!", http.StatusConflict)
+		return
+	case errors.Is(err, store.ErrResetExpired):
+		tracing.Error(w, r, "Reset Token Expired!", http.StatusGone)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Reset Password!", err)
+		return
+	}
+
+	writeMessage(w, r, http.StatusOK, "Password reset successfully!")
+}
+
+func writeTokens(w http.ResponseWriter, r *http.Request, tokens auth.Tokens) {
+	response := dto.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(tokens.ExpiresIn.Seconds()),
+	}
+	writeSecret(w, r, http.StatusOK, response)
+}
+
+// RequireAccessToken only lets requests with a valid bearer access token
+// through, making its claims available with auth.ClaimsFromContext.
+func (handler *AuthHandler) RequireAccessToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			tracing.Error(w, r, "Access Token Required!", http.StatusUnauthorized)
+			return
+		}
+		handler.OptionalAccessToken(next).ServeHTTP(w, r)
+	})
+}
+
+// OptionalAccessToken makes the claims of a bearer access token available
+// with auth.ClaimsFromContext, for handlers that check them only for some
+// operations. Requests without a token are let through without claims, and
+// those with an invalid one are turned away.
+func (handler *AuthHandler) OptionalAccessToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, err := handler.authenticator.ParseAccessToken(r.Context(), accessToken)
+		if errors.Is(err, token.ErrInvalid) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			tracing.Error(w, r, "Invalid Access Token!", http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			serverError(w, r, "Failed to Check Access Token!", err)
+			return
+		}
+
+		logging.AddAttrs(r.Context(), slog.String("auth_user_id", claims.UserId.String()))
+		next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+	})
+}
+
+// RequireAdmin only lets requests through whose access token, checked by
+// RequireAccessToken before it, belongs to an admin who logged in with a
+// second factor.
+func (handler *AuthHandler) RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := handler.authenticator.AuthorizeAdmin(r.Context())
+		switch {
+		case errors.Is(err, auth.ErrAdminRequired):
+			tracing.Error(w, r, "Admin Access Required!", http.StatusForbidden)
+			return
+		case errors.Is(err, auth.ErrAdminMFARequired):
+			tracing.Error(w, r, "Admin Access Requires Logging In With a Second Factor!", http.StatusForbidden)
+			return
+		case err != nil:
+			serverError(w, r, "Failed to Check Admin Access!", err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeMFAChallenge(w http.ResponseWriter, r *http.Request, tokens auth.Tokens) {
+	response := dto.MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    tokens.MFAToken,
+		ExpiresIn:   int(tokens.ExpiresIn.Seconds()),
+	}
+	writeSecret(w, r, http.StatusAccepted, response)
+}
+
+// writeSecret writes a response holding secrets, such as tokens, which must
+// not end up in shared caches.
+func writeSecret(w http.ResponseWriter, r *http.Request, status int, response any) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
+
+func writeMessage(w http.ResponseWriter, r *http.Request, status int, message string) {
+	response := map[string]string{
+		"message": message,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
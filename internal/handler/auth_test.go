@@ -0,0 +1,383 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/mail"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/oidc"
+	"example.com/user-management/internal/store/memory"
+	"example.com/user-management/internal/token"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var resetLinkPattern = regexp.MustCompile(`https://example\.com/reset-password\?token=\S+`)
+
+const newPassword = "correct horse battery staple"
+
+// adminEmail is the address of the admin every auth router is set up with.
+const adminEmail = "admin@example.com"
+
+func newAuthRouter(t *testing.T) (http.Handler, *memory.UserStore, *mail.MemorySender) {
+	t.Helper()
+
+	userStore := memory.NewUserStore()
+	admin, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Admin", Email: adminEmail})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	sender := mail.NewMemorySender()
+	authenticator := auth.NewAuthenticator(userStore, sender, token.NewSigner([]byte("secret")), auth.Options{
+		AccessTokenTTL:  15 * time.Minute,
+		SessionTTL:      time.Hour,
+		ResetTTL:        time.Hour,
+		ResetURL:        "https://example.com/reset-password",
+		MFAIssuer:       "Example",
+		MFAChallengeTTL: 5 * time.Minute,
+		MFAMaxAttempts:  3,
+		Admins:          []uuid.UUID{admin.UserId},
+	})
+	handler := NewAuthHandler(authenticator)
+	auditHandler := NewAuditHandler(userStore)
+
+	router := chi.NewRouter()
+	router.Post("/auth/login", handler.Login)
+	router.Post("/auth/login/mfa", handler.LoginMFA)
+	router.Post("/auth/refresh", handler.Refresh)
+	router.Post("/auth/logout", handler.Logout)
+	// reset emails are sent in the background; wait for them, so that tests
+	// can read them once the request is done
+	router.Post("/auth/password/forgot", func(w http.ResponseWriter, r *http.Request) {
+		handler.ForgotPassword(w, r)
+		authenticator.Wait()
+	})
+	router.Post("/auth/password/reset", handler.ResetPassword)
+	router.With(handler.RequireAccessToken).Post("/auth/mfa/totp", handler.EnrolTOTP)
+	router.With(handler.RequireAccessToken).Post("/auth/mfa/totp/confirm", handler.ConfirmTOTP)
+	router.With(handler.RequireAccessToken).Post("/auth/mfa/recovery-codes", handler.RegenerateRecoveryCodes)
+	router.Get("/users/{id}/audit-events", auditHandler.ListAuditEvents)
+	router.With(handler.RequireAccessToken, handler.RequireAdmin).Delete("/users/{id}", NewUserHandler(userStore).DeleteUser)
+	router.With(handler.RequireAccessToken, handler.RequireAdmin).Delete("/users/{id}/mfa", handler.ResetMFA)
+	router.With(handler.RequireAccessToken, handler.RequireAdmin).Put("/attribute-schema", NewAttributeSchemaHandler(userStore).SetAttributeSchema)
+	provider := oidc.NewProvider(userStore, authenticator, token.NewSigner([]byte("secret")), oidc.Options{
+		Issuer:          "https://id.example.com/oidc",
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: time.Hour,
+		KeyRotation:     24 * time.Hour,
+	})
+	router.With(handler.RequireAccessToken, handler.RequireAdmin).Post("/oauth-clients", NewOAuthClientHandler(provider).RegisterOAuthClient)
+	return router, userStore, sender
+}
+
+func postJSON(router http.Handler, target string, body any) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, target, bytes.NewReader(payload)))
+	return w
+}
+
+// requestReset asks for a reset for email and returns the token mailed for it.
+func requestReset(t *testing.T, router http.Handler, sender *mail.MemorySender, email string) string {
+	t.Helper()
+
+	if w := postJSON(router, "/auth/password/forgot", dto.ForgotPasswordRequest{Email: email}); w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body)
+	}
+
+	messages := sender.Messages()
+	if len(messages) == 0 {
+		t.Fatalf("Expected a password reset email")
+	}
+	link := resetLinkPattern.FindString(messages[len(messages)-1].Text)
+	if link == "" {
+		t.Fatalf("Expected a reset link in %q", messages[len(messages)-1].Text)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("Invalid link %q: %v", link, err)
+	}
+	return parsed.Query().Get("token")
+}
+
+func login(t *testing.T, router http.Handler, email, password string) dto.TokenResponse {
+	t.Helper()
+
+	w := postJSON(router, "/auth/login", dto.LoginRequest{Email: email, Password: password})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var tokens dto.TokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&tokens); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return tokens
+}
+
+// loginAdminWithPassword gives the admin newPassword as their password and
+// logs them in with it alone.
+func loginAdminWithPassword(t *testing.T, router http.Handler, sender *mail.MemorySender) dto.TokenResponse {
+	t.Helper()
+
+	resetToken := requestReset(t, router, sender, adminEmail)
+	if w := postJSON(router, "/auth/password/reset", dto.ResetPasswordRequest{Token: resetToken, Password: newPassword}); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	return login(t, router, adminEmail, newPassword)
+}
+
+// loginAdmin enrols the admin in MFA and logs them in with a second factor,
+// as admin routes require.
+func loginAdmin(t *testing.T, router http.Handler, sender *mail.MemorySender) dto.TokenResponse {
+	t.Helper()
+	return loginAdminMFA(t, router, loginAdminWithPassword(t, router, sender))
+}
+
+// loginAdminMFA enrols the admin, logged in with passwordTokens, in MFA and
+// logs them in again with a second factor.
+func loginAdminMFA(t *testing.T, router http.Handler, passwordTokens dto.TokenResponse) dto.TokenResponse {
+	t.Helper()
+
+	_, codes := enableMFA(t, router, passwordTokens.AccessToken)
+	mfaToken := loginChallenged(t, router, adminEmail, newPassword)
+	w := postJSON(router, "/auth/login/mfa", dto.LoginMFARequest{MFAToken: mfaToken, Code: codes[0]})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var tokens dto.TokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&tokens); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return tokens
+}
+
+// deleteWithToken sends a DELETE to target, with accessToken as the bearer
+// token unless it is empty.
+func deleteWithToken(router http.Handler, target, accessToken string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodDelete, target, nil)
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func auditActions(t *testing.T, router http.Handler, user model.User) []model.AuditAction {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/"+user.UserId.String()+"/audit-events", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var events []model.AuditEvent
+	if err := json.NewDecoder(w.Body).Decode(&events); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	actions := make([]model.AuditAction, len(events))
+	for i, event := range events {
+		actions[i] = event.Action
+	}
+	return actions
+}
+
+func TestPasswordReset_ResetThenLogin(t *testing.T) {
+	router, userStore, sender := newAuthRouter(t)
+	user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	// without a password nobody can log in
+	if w := postJSON(router, "/auth/login", dto.LoginRequest{Email: "alice@example.com", Password: newPassword}); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401, got %d", w.Code)
+	}
+
+	resetToken := requestReset(t, router, sender, "alice@example.com")
+	if to := sender.Messages()[0].To; to != "alice@example.com" {
+		t.Errorf("Expected the reset to be mailed to alice@example.com, got %q", to)
+	}
+
+	w := postJSON(router, "/auth/password/reset", dto.ResetPasswordRequest{Token: resetToken, Password: newPassword})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	// the token is single-use
+	w = postJSON(router, "/auth/password/reset", dto.ResetPasswordRequest{Token: resetToken, Password: "another good password"})
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a used token, got %d", w.Code)
+	}
+
+	tokens := login(t, router, "alice@example.com", newPassword)
+	if tokens.TokenType != "Bearer" || tokens.ExpiresIn != 900 || tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Errorf("Unexpected tokens %+v", tokens)
+	}
+
+	want := []model.AuditAction{
+		model.AuditLoginFailed,
+		model.AuditPasswordResetRequested,
+		model.AuditPasswordResetEmailSent,
+		model.AuditPasswordReset,
+		model.AuditSessionsRevoked,
+		model.AuditPasswordResetFailed,
+		model.AuditLoginSucceeded,
+	}
+	got := auditActions(t, router, user)
+	if len(got) != len(want) {
+		t.Fatalf("Expected audit actions %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected audit action %d to be %s, got %s", i, want[i], got[i])
+		}
+	}
+}
+
+func TestPasswordReset_RevokesSessions(t *testing.T) {
+	router, userStore, sender := newAuthRouter(t)
+	if _, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	resetToken := requestReset(t, router, sender, "alice@example.com")
+	if w := postJSON(router, "/auth/password/reset", dto.ResetPasswordRequest{Token: resetToken, Password: newPassword}); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	tokens := login(t, router, "alice@example.com", newPassword)
+	w := postJSON(router, "/auth/refresh", dto.RefreshRequest{RefreshToken: tokens.RefreshToken})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var refreshed dto.TokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&refreshed); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	resetToken = requestReset(t, router, sender, "alice@example.com")
+	if w := postJSON(router, "/auth/password/reset", dto.ResetPasswordRequest{Token: resetToken, Password: "another good password"}); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	if w := postJSON(router, "/auth/refresh", dto.RefreshRequest{RefreshToken: refreshed.RefreshToken}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a session ended by the reset, got %d", w.Code)
+	}
+	if w := postJSON(router, "/auth/login", dto.LoginRequest{Email: "alice@example.com", Password: newPassword}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for the old password, got %d", w.Code)
+	}
+	login(t, router, "alice@example.com", "another good password")
+}
+
+func TestForgotPassword_UnknownEmail(t *testing.T) {
+	router, _, sender := newAuthRouter(t)
+
+	w := postJSON(router, "/auth/password/forgot", dto.ForgotPasswordRequest{Email: "nobody@example.com"})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body)
+	}
+	if messages := sender.Messages(); len(messages) != 0 {
+		t.Errorf("Expected no email, got %+v", messages)
+	}
+}
+
+func TestResetPassword_Rejected(t *testing.T) {
+	router, userStore, sender := newAuthRouter(t)
+	if _, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	resetToken := requestReset(t, router, sender, "alice@example.com")
+	foreignToken := token.NewSigner([]byte("other secret")).Sign("password-reset", uuid.Nil, [16]byte{1})
+
+	tests := []struct {
+		name     string
+		req      dto.ResetPasswordRequest
+		wantCode int
+	}{
+		{"Missing token", dto.ResetPasswordRequest{Password: newPassword}, http.StatusBadRequest},
+		{"Foreign token", dto.ResetPasswordRequest{Token: foreignToken, Password: newPassword}, http.StatusBadRequest},
+		{"Short password", dto.ResetPasswordRequest{Token: resetToken, Password: "short"}, http.StatusBadRequest},
+		{"Password contains the email", dto.ResetPasswordRequest{Token: resetToken, Password: "alice-the-great-2024"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := postJSON(router, "/auth/password/reset", tt.req); w.Code != tt.wantCode {
+				t.Errorf("Expected %d, got %d: %s", tt.wantCode, w.Code, w.Body)
+			}
+		})
+	}
+
+	// rejected attempts do not use up the token
+	if w := postJSON(router, "/auth/password/reset", dto.ResetPasswordRequest{Token: resetToken, Password: newPassword}); w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	router, userStore, sender := newAuthRouter(t)
+	if _, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	resetToken := requestReset(t, router, sender, "alice@example.com")
+	if w := postJSON(router, "/auth/password/reset", dto.ResetPasswordRequest{Token: resetToken, Password: newPassword}); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	tokens := login(t, router, "alice@example.com", newPassword)
+
+	if w := postJSON(router, "/auth/logout", dto.RefreshRequest{RefreshToken: tokens.RefreshToken}); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if w := postJSON(router, "/auth/refresh", dto.RefreshRequest{RefreshToken: tokens.RefreshToken}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 after logging out, got %d", w.Code)
+	}
+	if w := postJSON(router, "/auth/logout", dto.RefreshRequest{RefreshToken: "not-a-token"}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a malformed token, got %d", w.Code)
+	}
+}
+
+func TestAdminRoutes_RequireAdmin(t *testing.T) {
+	router, userStore, sender := newAuthRouter(t)
+	user, tokens := newPasswordUser(t, router, userStore, sender)
+	passwordAdmin := loginAdminWithPassword(t, router, sender)
+	// another password reset would end the password-only session
+	admin := loginAdminMFA(t, router, passwordAdmin)
+
+	for _, target := range []string{"/users/" + user.UserId.String() + "/mfa", "/users/" + user.UserId.String()} {
+		t.Run(target, func(t *testing.T) {
+			w := deleteWithToken(router, target, "")
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Expected 401 without a token, got %d", w.Code)
+			}
+
+			w = deleteWithToken(router, target, tokens.AccessToken)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("Expected 403 for a user who is not an admin, got %d", w.Code)
+			}
+
+			w = deleteWithToken(router, target, passwordAdmin.AccessToken)
+			if w.Code != http.StatusForbidden {
+				t.Errorf("Expected 403 for an admin who logged in without a second factor, got %d", w.Code)
+			}
+
+			// the admin gets past the check; Alice has no MFA to reset
+			w = deleteWithToken(router, target, admin.AccessToken)
+			if w.Code == http.StatusUnauthorized || w.Code == http.StatusForbidden {
+				t.Errorf("Expected the admin to be let through, got %d", w.Code)
+			}
+		})
+	}
+
+	if _, ok, _ := userStore.GetUserById(t.Context(), user.UserId); ok {
+		t.Errorf("Expected the admin to have deleted the user")
+	}
+}
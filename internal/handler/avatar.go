@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"example.com/user-management/internal/avatar"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/tracing"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// versionedCacheControl is sent for avatar URLs that name a version,
+	// which serve the same image for as long as it exists.
+	versionedCacheControl = "public, max-age=31536000, immutable"
+	// unversionedCacheControl makes clients check back with the ETag, since
+	// the image may be replaced at any time.
+	unversionedCacheControl = "no-cache"
+)
+
+type AvatarHandler struct {
+	avatars *avatar.Service
+}
+
+func NewAvatarHandler(avatars *avatar.Service) *AvatarHandler {
+	return &AvatarHandler{
+		avatars: avatars,
+	}
+}
+
+// UploadAvatar godoc
+// @Summary Upload a user's avatar
+// @Description Replace the avatar of a user with the JPEG, PNG or WebP image in the body, of at most 5 MiB. The type
+// @Description is sniffed from the image itself. The image is cropped to a square, resized and stripped of EXIF and
+// @Description other metadata; the user's avatarUrl then points at the result.
+// @Tags Users
+// @Accept image/jpeg,image/png,image/webp
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {string} string "Invalid User Id or Image"
+// @Failure 404 {string} string "User Not Found"
+// @Failure 413 {string} string "Avatar Too Large"
+// @Failure 415 {string} string "Unsupported Avatar Type"
+// @Failure 500 {string} string "Failed to Upload Avatar"
+// @Router /users/{id}/avatar [put]
+func (handler *AvatarHandler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "id")
+	parsedId, err := uuid.Parse(userId)
+	if err != nil {
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", parsedId.String()))
+
+	// one byte past the limit is enough to tell it was passed
+	data, err := io.ReadAll(io.LimitReader(r.Body, avatar.MaxSize+1))
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	user, err := handler.avatars.Upload(r.Context(), parsedId, data)
+	switch {
+	case errors.Is(err, avatar.ErrUserNotFound):
+		tracing.Error(w, r, "User Not Found!", http.StatusNotFound)
+		return
+	case errors.Is(err, avatar.ErrTooLarge):
+		tracing.Error(w, r, "Avatar Too Large!", http.StatusRequestEntityTooLarge)
+		return
+	case errors.Is(err, avatar.ErrUnsupportedType):
+		tracing.Error(w, r, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	case errors.Is(err, avatar.ErrInvalidImage):
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Upload Avatar!", err)
+		return
+	}
+
+	writeAvatarUser(w, r, "Avatar Uploaded successfully!", user)
+}
+
+// GetAvatar godoc
+// @Summary Get a user's avatar
+// @Description Retrieve the avatar of a user as a square image of the given size. Requests for the user's avatarUrl,
+// @Description which changes with the image, may be cached indefinitely; others are revalidated with the ETag.
+// @Tags Users
+// @Produce image/jpeg,image/png
+// @Param id path string true "User ID"
+// @Param size query int false "Width and height in pixels: 512, 256, 128 or 64" default(512)
+// @Success 200 {file} file
+// @Success 304 {string} string "Not Modified"
+// @Failure 400 {string} string "Invalid User Id or Size"
+// @Failure 404 {string} string "User or Avatar Not Found"
+// @Failure 500 {string} string "Failed to Retrieve Avatar"
+// @Router /users/{id}/avatar [get]
+func (handler *AvatarHandler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "id")
+	parsedId, err := uuid.Parse(userId)
+	if err != nil {
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", parsedId.String()))
+
+	size := avatar.Sizes[0]
+	if value := r.URL.Query().Get("size"); value != "" {
+		size, err = strconv.Atoi(value)
+		if err != nil {
+			tracing.Error(w, r, "Invalid Size!", http.StatusBadRequest)
+			return
+		}
+	}
+
+	blob, err := handler.avatars.Get(r.Context(), parsedId, size)
+	switch {
+	case errors.Is(err, avatar.ErrUnknownSize):
+		tracing.Error(w, r, "Invalid Size!", http.StatusBadRequest)
+		return
+	case errors.Is(err, avatar.ErrUserNotFound):
+		tracing.Error(w, r, "User Not Found!", http.StatusNotFound)
+		return
+	case errors.Is(err, avatar.ErrNotFound):
+		tracing.Error(w, r, "Avatar Not Found!", http.StatusNotFound)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Retrieve Avatar!", err)
+		return
+	}
+
+	sum := sha256.Sum256(blob.Data)
+	w.Header().Set("Content-Type", blob.ContentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if r.URL.Query().Has("v") {
+		w.Header().Set("Cache-Control", versionedCacheControl)
+	} else {
+		w.Header().Set("Cache-Control", unversionedCacheControl)
+	}
+	// ServeContent answers conditional and range requests
+	http.ServeContent(w, r, "", blob.UpdatedAt, bytes.NewReader(blob.Data))
+}
+
+// DeleteAvatar godoc
+// @Summary Delete a user's avatar
+// @Description Remove the avatar of a user and clear their avatarUrl
+// @Tags Users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {string} string "Invalid User Id"
+// @Failure 404 {string} string "User or Avatar Not Found"
+// @Failure 500 {string} string "Failed to Delete Avatar"
+// @Router /users/{id}/avatar [delete]
+func (handler *AvatarHandler) DeleteAvatar(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "id")
+	parsedId, err := uuid.Parse(userId)
+	if err != nil {
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", parsedId.String()))
+
+	user, err := handler.avatars.Delete(r.Context(), parsedId)
+	switch {
+	case errors.Is(err, avatar.ErrUserNotFound):
+		tracing.Error(w, r, "User Not Found!", http.StatusNotFound)
+		return
+	case errors.Is(err, avatar.ErrNotFound):
+		tracing.Error(w, r, "Avatar Not Found!", http.StatusNotFound)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Delete Avatar!", err)
+		return
+	}
+
+	writeAvatarUser(w, r, "Avatar Deleted successfully!", user)
+}
+
+func writeAvatarUser(w http.ResponseWriter, r *http.Request, message string, user model.User) {
+	response := map[string]interface{}{
+		"message": message,
+		"user":    user,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/user-management/internal/avatar"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store/memory"
+	"github.com/go-chi/chi/v5"
+)
+
+func newAvatarRouter(t *testing.T) (http.Handler, model.User) {
+	t.Helper()
+
+	userStore := memory.NewUserStore()
+	user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "John", Email: "john@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	handler := NewAvatarHandler(avatar.NewService(userStore, userStore))
+
+	router := chi.NewRouter()
+	router.Put("/users/{id}/avatar", handler.UploadAvatar)
+	router.Get("/users/{id}/avatar", handler.GetAvatar)
+	router.Delete("/users/{id}/avatar", handler.DeleteAvatar)
+	return router, user
+}
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 64, 64))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func serve(router http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestAvatar_UploadGetDelete(t *testing.T) {
+	router, user := newAvatarRouter(t)
+	target := "/users/" + user.UserId.String() + "/avatar"
+
+	w := serve(router, httptest.NewRequest(http.MethodPut, target, bytes.NewReader(testPNG(t))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var response struct{ User model.User }
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.User.AvatarUrl == "" {
+		t.Fatalf("Expected an avatar URL")
+	}
+
+	w = serve(router, httptest.NewRequest(http.MethodGet, response.User.AvatarUrl+"&size=64", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "image/jpeg" {
+		t.Errorf("Expected image/jpeg, got %s", contentType)
+	}
+	if cacheControl := w.Header().Get("Cache-Control"); cacheControl != versionedCacheControl {
+		t.Errorf("Expected versioned URLs to be cached, got %q", cacheControl)
+	}
+	config, _, err := image.DecodeConfig(w.Body)
+	if err != nil || config.Width != 64 {
+		t.Errorf("Expected a 64 pixel image, got %+v, %v", config, err)
+	}
+
+	w = serve(router, httptest.NewRequest(http.MethodGet, target, nil))
+	if cacheControl := w.Header().Get("Cache-Control"); cacheControl != unversionedCacheControl {
+		t.Errorf("Expected unversioned URLs to be revalidated, got %q", cacheControl)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatalf("Expected an ETag")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	r.Header.Set("If-None-Match", etag)
+	if w := serve(router, r); w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %d", w.Code)
+	}
+
+	if w := serve(router, httptest.NewRequest(http.MethodDelete, target, nil)); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if w := serve(router, httptest.NewRequest(http.MethodGet, target, nil)); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 once deleted, got %d", w.Code)
+	}
+	if w := serve(router, httptest.NewRequest(http.MethodDelete, target, nil)); w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting twice, got %d", w.Code)
+	}
+}
+
+func TestAvatar_Rejected(t *testing.T) {
+	router, user := newAvatarRouter(t)
+	target := "/users/" + user.UserId.String() + "/avatar"
+
+	tests := []struct {
+		name     string
+		method   string
+		target   string
+		body     []byte
+		expected int
+	}{
+		{"invalid user id", http.MethodPut, "/users/abc/avatar", testPNG(t), http.StatusBadRequest},
+		{"unknown user", http.MethodPut, "/users/00000000-0000-0000-0000-000000000001/avatar", testPNG(t), http.StatusNotFound},
+		{"unsupported type", http.MethodPut, target, []byte("GIF89a"), http.StatusUnsupportedMediaType},
+		{"too large", http.MethodPut, target, append(testPNG(t), make([]byte, avatar.MaxSize)...), http.StatusRequestEntityTooLarge},
+		{"corrupt image", http.MethodPut, target, testPNG(t)[:40], http.StatusBadRequest},
+		{"unknown size", http.MethodGet, target + "?size=100", nil, http.StatusBadRequest},
+		{"no avatar", http.MethodGet, target, nil, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := serve(router, httptest.NewRequest(test.method, test.target, bytes.NewReader(test.body)))
+			if w.Code != test.expected {
+				t.Errorf("Expected %d, got %d: %s", test.expected, w.Code, w.Body)
+			}
+		})
+	}
+}
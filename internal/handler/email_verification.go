@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/token"
+	"example.com/user-management/internal/tracing"
+	"example.com/user-management/internal/verification"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type EmailVerificationHandler struct {
+	verifier *verification.EmailVerifier
+}
+
+func NewEmailVerificationHandler(verifier *verification.EmailVerifier) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		verifier: verifier,
+	}
+}
+
+// SendVerificationEmail godoc
+// @Summary Send a verification email
+// @Description Mail the user a link that confirms their current email address
+// @Tags Users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 202 {object} map[string]string
+// @Failure 400 {string} string "Invalid User Id"
+// @Failure 404 {string} string "User Not Found"
+// @Failure 409 {string} string "Email Already Verified"
+// @Failure 500 {string} string "Failed to Send Verification Email"
+// @Router /users/{id}/verify-email/send [post]
+func (handler *EmailVerificationHandler) SendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "id")
+	parsedId, err := uuid.Parse(userId)
+	if err != nil {
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", parsedId.String()))
+
+	err = handler.verifier.Send(r.Context(), parsedId)
+	switch {
+	case errors.Is(err, verification.ErrUserNotFound):
+		tracing.Error(w, r, "User Not Found!", http.StatusNotFound)
+		return
+	case errors.Is(err, verification.ErrAlreadyVerified):
+		tracing.Error(w, r, "Email Already Verified!", http.StatusConflict)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Send Verification Email!", err)
+		return
+	}
+
+	response := map[string]string{
+		"message": "Verification email sent!",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
+
+// VerifyEmail godoc
+// @Summary Verify an email address
+// @Description Confirm the email address a verification link was sent to
+// @Tags Users
+// @Produce json
+// @Param token query string true "Verification token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {string} string "Invalid Verification Token"
+// @Failure 409 {string} string "Verification Already Used or Email Changed"
+// @Failure 410 {string} string "Verification Expired"
+// @Failure 500 {string} string "Failed to Verify Email"
+// @Router /verify-email [get]
+func (handler *EmailVerificationHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	user, err := handler.verifier.Verify(r.Context(), r.URL.Query().Get("token"))
+	switch {
+	case errors.Is(err, token.ErrInvalid), errors.Is(err, store.ErrVerificationNotFound):
+		tracing.Error(w, r, "Invalid Verification Token!", http.StatusBadRequest)
+		return
+	case errors.Is(err, store.ErrVerificationUsed):
+		tracing.Error(w, r, "Verification Already Used!", http.StatusConflict)
+		return
+	case errors.Is(err, store.ErrEmailChanged):
+		tracing.Error(w, r, "Email Changed Since the Verification Was Sent!", http.StatusConflict)
+		return
+	case errors.Is(err, store.ErrVerificationExpired):
+		tracing.Error(w, r, "Verification Expired!", http.StatusGone)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Verify Email!", err)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", user.UserId.String()))
+
+	response := map[string]interface{}{
+		"message": "Email verified successfully!",
+		"user":    user,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/mail"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store/memory"
+	"example.com/user-management/internal/token"
+	"example.com/user-management/internal/verification"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+var linkPattern = regexp.MustCompile(`https://example\.com/verify-email\?token=\S+`)
+
+func newEmailVerificationRouter(t *testing.T) (http.Handler, *memory.UserStore, *mail.MemorySender) {
+	t.Helper()
+
+	userStore := memory.NewUserStore()
+	sender := mail.NewMemorySender()
+	verifier := verification.NewEmailVerifier(userStore, sender, token.NewSigner([]byte("secret")), verification.EmailOptions{
+		TTL:       time.Hour,
+		VerifyURL: "https://example.com/verify-email",
+	})
+	handler := NewEmailVerificationHandler(verifier)
+
+	router := chi.NewRouter()
+	router.Post("/users/{id}/verify-email/send", handler.SendVerificationEmail)
+	router.Get("/verify-email", handler.VerifyEmail)
+	return router, userStore, sender
+}
+
+func TestEmailVerification_SendAndVerify(t *testing.T) {
+	router, userStore, sender := newEmailVerificationRouter(t)
+	user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/"+user.UserId.String()+"/verify-email/send", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body)
+	}
+
+	messages := sender.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	if messages[0].To != "alice@example.com" {
+		t.Errorf("Expected message to alice@example.com, got %q", messages[0].To)
+	}
+	link := linkPattern.FindString(messages[0].Text)
+	if link == "" {
+		t.Fatalf("Expected a verification link in %q", messages[0].Text)
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("Invalid link %q: %v", link, err)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, parsed.RequestURI(), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	verified, _, _ := userStore.GetUserById(t.Context(), user.UserId)
+	if verified.EmailVerifiedAt == nil {
+		t.Errorf("Expected the email to be verified")
+	}
+
+	// the link is single-use and the address no longer needs verifying
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, parsed.RequestURI(), nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a reused link, got %d", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/users/"+user.UserId.String()+"/verify-email/send", nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a verified address, got %d", w.Code)
+	}
+}
+
+func TestEmailVerification_Errors(t *testing.T) {
+	router, _, sender := newEmailVerificationRouter(t)
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		code   int
+	}{
+		{"invalid user id", http.MethodPost, "/users/nope/verify-email/send", http.StatusBadRequest},
+		{"unknown user", http.MethodPost, "/users/00000000-0000-0000-0000-000000000001/verify-email/send", http.StatusNotFound},
+		{"missing token", http.MethodGet, "/verify-email", http.StatusBadRequest},
+		{"forged token", http.MethodGet, "/verify-email?token=" + token.NewSigner([]byte("other")).Sign("email-verification", uuid.Nil, [16]byte{1}), http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+			if w.Code != test.code {
+				t.Errorf("Expected %d, got %d", test.code, w.Code)
+			}
+		})
+	}
+
+	if got := len(sender.Messages()); got != 0 {
+		t.Errorf("Expected no messages, got %d", got)
+	}
+}
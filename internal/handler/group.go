@@ -0,0 +1,485 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/mapper"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tracing"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultMemberLimit = 50
+	maxMemberLimit     = 100
+)
+
+type GroupHandler struct {
+	store store.GroupStoreInterface
+	// users lists the members of groups and looks up users
+	users store.UserStoreInterface
+}
+
+func NewGroupHandler(store store.GroupStoreInterface, users store.UserStoreInterface) *GroupHandler {
+	return &GroupHandler{
+		store: store,
+		users: users,
+	}
+}
+
+// CreateGroup godoc
+// @Summary Create a new group
+// @Description Create a new group with the input payload. Group names are unique.
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param group body dto.CreateGroupRequest true "Group payload"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {string} string "Invalid Request Body"
+// @Failure 409 {string} string "Group Name Already Exists"
+// @Failure 500 {string} string "Failed to Create Group"
+// @Router /groups [post]
+func (handler *GroupHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateGroupRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	group := mapper.CreateGroupRequestToModel(req)
+	createdGroup, err := handler.store.CreateGroup(r.Context(), group)
+
+	if errors.Is(err, store.ErrDuplicateGroupName) {
+		tracing.Error(w, r, "Group Name Already Exists!", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to Create Group!", err)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("group_id", createdGroup.GroupId.String()))
+
+	response := map[string]interface{}{
+		"message": "Group created successfully!",
+		"group":   createdGroup,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
+
+// GetAllGroups godoc
+// @Summary Retrieve all groups
+// @Description Get a list of all groups, ordered by name
+// @Tags Groups
+// @Produce json
+// @Success 200 {array} model.Group
+// @Failure 500 {string} string "Failed to Retrieve Groups"
+// @Router /groups [get]
+func (handler *GroupHandler) GetAllGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := handler.store.GetAllGroups(r.Context())
+
+	if err != nil {
+		serverError(w, r, "Failed to Retrieve Groups!", err)
+		return
+	}
+
+	writeGroups(w, r, groups)
+}
+
+// GetGroupById godoc
+// @Summary Get group by ID
+// @Description Retrieve a group by its UUID
+// @Tags Groups
+// @Produce json
+// @Param id path string true "Group ID"
+// @Success 200 {object} model.Group
+// @Failure 400 {string} string "Invalid Group Id"
+// @Failure 404 {string} string "Group Not Found"
+// @Failure 500 {string} string "Failed to Retrieve Group"
+// @Router /groups/{id} [get]
+func (handler *GroupHandler) GetGroupById(w http.ResponseWriter, r *http.Request) {
+	groupId, ok := groupIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	group, ok, err := handler.store.GetGroupById(r.Context(), groupId)
+
+	if err != nil {
+		serverError(w, r, "Failed to Retrieve Group by Id!", err)
+		return
+	}
+
+	if !ok {
+		tracing.Error(w, r, "Group Not Found!", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(group)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
+
+// UpdateGroup godoc
+// @Summary Update a group
+// @Description Update a group's name or description by UUID
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param group body dto.UpdateGroupRequest true "Group update payload"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {string} string "Invalid Request Body or Group Id"
+// @Failure 404 {string} string "Group Not Found"
+// @Failure 409 {string} string "Group Name Already Exists"
+// @Failure 500 {string} string "Failed to Update Group"
+// @Router /groups/{id} [patch]
+func (handler *GroupHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	groupId, ok := groupIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateGroupRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	group, ok, err := handler.store.GetGroupById(r.Context(), groupId)
+
+	if err != nil {
+		serverError(w, r, "Failed to Retrieve Group by Id!", err)
+		return
+	}
+
+	if !ok {
+		tracing.Error(w, r, "Group Not Found!", http.StatusNotFound)
+		return
+	}
+
+	mapper.ApplyUpdateGroupRequest(&group, req)
+	updatedGroup, ok, err := handler.store.UpdateGroup(r.Context(), group, groupId)
+
+	if errors.Is(err, store.ErrDuplicateGroupName) {
+		tracing.Error(w, r, "Group Name Already Exists!", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to Update Group!", err)
+		return
+	}
+
+	// deleted since it was read
+	if !ok {
+		tracing.Error(w, r, "Group Not Found!", http.StatusNotFound)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Group Updated successfully!",
+		"group":   updatedGroup,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
+
+// DeleteGroup godoc
+// @Summary Delete a group
+// @Description Delete a group by UUID. Its members lose their membership but are not deleted.
+// @Tags Groups
+// @Produce json
+// @Param id path string true "Group ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {string} string "Invalid Group Id"
+// @Failure 404 {string} string "Group Not Found"
+// @Failure 500 {string} string "Failed to Delete Group"
+// @Router /groups/{id} [delete]
+func (handler *GroupHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	groupId, ok := groupIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	ok, err := handler.store.DeleteGroup(r.Context(), groupId)
+
+	if err != nil {
+		serverError(w, r, "Failed to Delete Group!", err)
+		return
+	}
+
+	if !ok {
+		tracing.Error(w, r, "Group Not Found!", http.StatusNotFound)
+		return
+	}
+
+	writeMessage(w, r, http.StatusOK, "Group Deleted successfully!")
+}
+
+// AddGroupMember godoc
+// @Summary Add a user to a group
+// @Description Make an existing user a member of the group
+// @Tags Groups
+// @Accept json
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param member body dto.AddGroupMemberRequest true "User to add"
+// @Success 201 {object} map[string]string
+// @Failure 400 {string} string "Invalid Request Body or Group Id"
+// @Failure 404 {string} string "Group or User Not Found"
+// @Failure 409 {string} string "User Already a Member"
+// @Failure 500 {string} string "Failed to Add Group Member"
+// @Router /groups/{id}/members [post]
+func (handler *GroupHandler) AddGroupMember(w http.ResponseWriter, r *http.Request) {
+	groupId, ok := groupIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.AddGroupMemberRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	userId := uuid.MustParse(req.UserId)
+	logging.AddAttrs(r.Context(), slog.String("user_id", userId.String()))
+
+	err = handler.store.AddGroupMember(r.Context(), groupId, userId)
+	switch {
+	case errors.Is(err, store.ErrGroupNotFound):
+		tracing.Error(w, r, "Group Not Found!", http.StatusNotFound)
+		return
+	case errors.Is(err, store.ErrUserNotFound):
+		tracing.Error(w, r, "User Not Found!", http.StatusNotFound)
+		return
+	case errors.Is(err, store.ErrAlreadyMember):
+		tracing.Error(w, r, "User Already a Member!", http.StatusConflict)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Add Group Member!", err)
+		return
+	}
+
+	writeMessage(w, r, http.StatusCreated, "Member added successfully!")
+}
+
+// RemoveGroupMember godoc
+// @Summary Remove a user from a group
+// @Description End a user's membership of the group. The user is kept.
+// @Tags Groups
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param userId path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {string} string "Invalid Group Id or User Id"
+// @Failure 404 {string} string "Member Not Found"
+// @Failure 500 {string} string "Failed to Remove Group Member"
+// @Router /groups/{id}/members/{userId} [delete]
+func (handler *GroupHandler) RemoveGroupMember(w http.ResponseWriter, r *http.Request) {
+	groupId, ok := groupIdParam(w, r)
+	if !ok {
+		return
+	}
+	userId, err := uuid.Parse(chi.URLParam(r, "userId"))
+	if err != nil {
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", userId.String()))
+
+	removed, err := handler.store.RemoveGroupMember(r.Context(), groupId, userId)
+	if err != nil {
+		serverError(w, r, "Failed to Remove Group Member!", err)
+		return
+	}
+	if !removed {
+		tracing.Error(w, r, "Member Not Found!", http.StatusNotFound)
+		return
+	}
+
+	writeMessage(w, r, http.StatusOK, "Member removed successfully!")
+}
+
+// ListGroupMembers godoc
+// @Summary List a group's members
+// @Description Get the users in a group, ordered by id. Pass the id of the last user of a page as after to get the next one.
+// @Tags Groups
+// @Produce json
+// @Param id path string true "Group ID"
+// @Param after query string false "Only return users after this user id"
+// @Param limit query int false "Maximum number of users, 1 to 100" default(50)
+// @Success 200 {array} model.User
+// @Failure 400 {string} string "Invalid Group Id, After or Limit"
+// @Failure 404 {string} string "Group Not Found"
+// @Failure 500 {string} string "Failed to Retrieve Group Members"
+// @Router /groups/{id}/members [get]
+func (handler *GroupHandler) ListGroupMembers(w http.ResponseWriter, r *http.Request) {
+	groupId, ok := groupIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	var after uuid.UUID
+	if value := r.URL.Query().Get("after"); value != "" {
+		var err error
+		after, err = uuid.Parse(value)
+		if err != nil {
+			tracing.Error(w, r, "Invalid After!", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := defaultMemberLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxMemberLimit {
+			tracing.Error(w, r, "Invalid Limit!", http.StatusBadRequest)
+			return
+		}
+	}
+
+	_, ok, err := handler.store.GetGroupById(r.Context(), groupId)
+	if err != nil {
+		serverError(w, r, "Failed to Retrieve Group by Id!", err)
+		return
+	}
+	if !ok {
+		tracing.Error(w, r, "Group Not Found!", http.StatusNotFound)
+		return
+	}
+
+	members, err := handler.users.ListUsers(r.Context(), model.UserFilter{GroupId: groupId}, after, limit)
+	if err != nil {
+		serverError(w, r, "Failed to Retrieve Group Members!", err)
+		return
+	}
+	if members == nil {
+		members = []model.User{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(members)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
+
+// ListUserGroups godoc
+// @Summary List a user's groups
+// @Description Get the groups a user belongs to, ordered by name
+// @Tags Users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {array} model.Group
+// @Failure 400 {string} string "Invalid User Id"
+// @Failure 404 {string} string "User Not Found"
+// @Failure 500 {string} string "Failed to Retrieve Groups"
+// @Router /users/{id}/groups [get]
+func (handler *GroupHandler) ListUserGroups(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", userId.String()))
+
+	_, ok, err := handler.users.GetUserById(r.Context(), userId)
+	if err != nil {
+		serverError(w, r, "Failed to Retrieve User by Id!", err)
+		return
+	}
+	if !ok {
+		tracing.Error(w, r, "User Not Found!", http.StatusNotFound)
+		return
+	}
+
+	groups, err := handler.store.ListUserGroups(r.Context(), userId)
+	if err != nil {
+		serverError(w, r, "Failed to Retrieve Groups!", err)
+		return
+	}
+
+	writeGroups(w, r, groups)
+}
+
+// groupIdParam parses the group id in the path, replying with a 400 if it is
+// not a UUID.
+func groupIdParam(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	groupId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		tracing.Error(w, r, "Invalid Group Id!", http.StatusBadRequest)
+		return uuid.Nil, false
+	}
+	logging.AddAttrs(r.Context(), slog.String("group_id", groupId.String()))
+	return groupId, true
+}
+
+func writeGroups(w http.ResponseWriter, r *http.Request, groups []model.Group) {
+	if groups == nil {
+		groups = []model.Group{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(groups)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
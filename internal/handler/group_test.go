@@ -0,0 +1,222 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func newGroupRouter(t *testing.T) (http.Handler, *memory.UserStore) {
+	t.Helper()
+
+	userStore := memory.NewUserStore()
+	handler := NewGroupHandler(userStore, userStore)
+
+	router := chi.NewRouter()
+	router.Get("/users", NewUserHandler(userStore).GetAllUsers)
+	router.Get("/users/{id}/groups", handler.ListUserGroups)
+	router.Post("/groups", handler.CreateGroup)
+	router.Get("/groups", handler.GetAllGroups)
+	router.Get("/groups/{id}", handler.GetGroupById)
+	router.Patch("/groups/{id}", handler.UpdateGroup)
+	router.Delete("/groups/{id}", handler.DeleteGroup)
+	router.Post("/groups/{id}/members", handler.AddGroupMember)
+	router.Get("/groups/{id}/members", handler.ListGroupMembers)
+	router.Delete("/groups/{id}/members/{userId}", handler.RemoveGroupMember)
+	return router, userStore
+}
+
+func createTestGroup(t *testing.T, router http.Handler, name string) model.Group {
+	t.Helper()
+
+	w := postJSON(router, "/groups", dto.CreateGroupRequest{Name: name})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+	var response struct{ Group model.Group }
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response.Group
+}
+
+func patchJSON(router http.Handler, target string, body any) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPatch, target, bytes.NewReader(payload)))
+	return w
+}
+
+func getJSON[T any](t *testing.T, router http.Handler, target string) T {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from %s, got %d: %s", target, w.Code, w.Body)
+	}
+	var response T
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response
+}
+
+func TestGroups_CRUD(t *testing.T) {
+	router, _ := newGroupRouter(t)
+
+	group := createTestGroup(t, router, "platform")
+	createTestGroup(t, router, "data")
+
+	if w := postJSON(router, "/groups", dto.CreateGroupRequest{Name: "platform"}); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a taken name, got %d", w.Code)
+	}
+	if w := postJSON(router, "/groups", dto.CreateGroupRequest{}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a name, got %d", w.Code)
+	}
+
+	groups := getJSON[[]model.Group](t, router, "/groups")
+	if len(groups) != 2 || groups[0].Name != "data" || groups[1].Name != "platform" {
+		t.Errorf("Expected [data platform], got %+v", groups)
+	}
+
+	w := patchJSON(router, "/groups/"+group.GroupId.String(), map[string]string{"description": "Runs the platform"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	got := getJSON[model.Group](t, router, "/groups/"+group.GroupId.String())
+	if got.Name != "platform" || got.Description != "Runs the platform" {
+		t.Errorf("Expected only the description to change, got %+v", got)
+	}
+
+	if w := patchJSON(router, "/groups/"+group.GroupId.String(), map[string]string{"name": "data"}); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a taken name, got %d", w.Code)
+	}
+
+	for _, want := range []int{http.StatusOK, http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/groups/"+group.GroupId.String(), nil))
+		if w.Code != want {
+			t.Errorf("Expected %d, got %d", want, w.Code)
+		}
+	}
+}
+
+func TestGroups_Membership(t *testing.T) {
+	router, userStore := newGroupRouter(t)
+	group := createTestGroup(t, router, "platform")
+	other := createTestGroup(t, router, "data")
+
+	var members []model.User
+	for i := range 3 {
+		user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: fmt.Sprintf("alice%d@example.com", i)})
+		if err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		members = append(members, user)
+
+		w := postJSON(router, "/groups/"+group.GroupId.String()+"/members", dto.AddGroupMemberRequest{UserId: user.UserId.String()})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+		}
+	}
+	alice := members[0]
+	if w := postJSON(router, "/groups/"+other.GroupId.String()+"/members", dto.AddGroupMemberRequest{UserId: alice.UserId.String()}); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+
+	page := getJSON[[]model.User](t, router, "/groups/"+group.GroupId.String()+"/members?limit=2")
+	if len(page) != 2 {
+		t.Fatalf("Expected 2 members, got %d", len(page))
+	}
+	rest := getJSON[[]model.User](t, router, "/groups/"+group.GroupId.String()+"/members?limit=2&after="+page[1].UserId.String())
+	if len(rest) != 1 {
+		t.Errorf("Expected 1 member, got %d", len(rest))
+	}
+
+	users := getJSON[[]model.User](t, router, "/users?group="+group.GroupId.String())
+	if len(users) != 3 {
+		t.Errorf("Expected 3 users in the group, got %d", len(users))
+	}
+	users = getJSON[[]model.User](t, router, "/users?group="+other.GroupId.String())
+	if len(users) != 1 || users[0].UserId != alice.UserId {
+		t.Errorf("Expected only alice in the other group, got %+v", users)
+	}
+
+	groups := getJSON[[]model.Group](t, router, "/users/"+alice.UserId.String()+"/groups")
+	if len(groups) != 2 || groups[0].Name != "data" || groups[1].Name != "platform" {
+		t.Errorf("Expected [data platform], got %+v", groups)
+	}
+
+	for _, want := range []int{http.StatusOK, http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/groups/"+other.GroupId.String()+"/members/"+alice.UserId.String(), nil))
+		if w.Code != want {
+			t.Errorf("Expected %d, got %d", want, w.Code)
+		}
+	}
+
+	// deleting a user ends their memberships
+	if _, err := userStore.DeleteUser(t.Context(), members[1].UserId); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	users = getJSON[[]model.User](t, router, "/groups/"+group.GroupId.String()+"/members")
+	if len(users) != 2 {
+		t.Errorf("Expected 2 members left, got %d", len(users))
+	}
+}
+
+func TestGroups_MembershipErrors(t *testing.T) {
+	router, userStore := newGroupRouter(t)
+	group := createTestGroup(t, router, "platform")
+	user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	members := "/groups/" + group.GroupId.String() + "/members"
+
+	tests := []struct {
+		name   string
+		target string
+		body   any
+		want   int
+	}{
+		{"Invalid group id", "/groups/nope/members", dto.AddGroupMemberRequest{UserId: user.UserId.String()}, http.StatusBadRequest},
+		{"Invalid user id", members, dto.AddGroupMemberRequest{UserId: "nope"}, http.StatusBadRequest},
+		{"Unknown group", "/groups/" + uuid.NewString() + "/members", dto.AddGroupMemberRequest{UserId: user.UserId.String()}, http.StatusNotFound},
+		{"Unknown user", members, dto.AddGroupMemberRequest{UserId: uuid.NewString()}, http.StatusNotFound},
+		{"Added", members, dto.AddGroupMemberRequest{UserId: user.UserId.String()}, http.StatusCreated},
+		{"Already a member", members, dto.AddGroupMemberRequest{UserId: user.UserId.String()}, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := postJSON(router, tt.target, tt.body); w.Code != tt.want {
+				t.Errorf("Expected %d, got %d: %s", tt.want, w.Code, w.Body)
+			}
+		})
+	}
+
+	for target, want := range map[string]int{
+		members + "?limit=0":                       http.StatusBadRequest,
+		members + "?after=nope":                    http.StatusBadRequest,
+		"/groups/" + uuid.NewString() + "/members": http.StatusNotFound,
+		"/users/" + uuid.NewString() + "/groups":   http.StatusNotFound,
+		"/users?group=nope":                        http.StatusBadRequest,
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+		if w.Code != want {
+			t.Errorf("Expected %d from %s, got %d", want, target, w.Code)
+		}
+	}
+}
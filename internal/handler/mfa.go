@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tracing"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// EnrolTOTP godoc
+// @Summary Start enrolling an authenticator app
+// @Description Generate a TOTP secret for the logged in user, returned as text, an otpauth:// URI and a QR code PNG. Logins only ask for a code once the app is confirmed. Starting again replaces an unconfirmed secret.
+// @Tags MFA
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} dto.TOTPEnrolmentResponse
+// @Failure 401 {string} string "Access Token Required"
+// @Failure 409 {string} string "MFA Already Enabled"
+// @Failure 500 {string} string "Failed to Enrol Authenticator App"
+// @Router /auth/mfa/totp [post]
+func (handler *AuthHandler) EnrolTOTP(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.ClaimsFromContext(r.Context())
+
+	enrolment, err := handler.authenticator.EnrolTOTP(r.Context(), claims.UserId)
+	switch {
+	case errors.Is(err, auth.ErrUserNotFound):
+		tracing.Error(w, r, "User Not Found!", http.StatusNotFound)
+		return
+	case errors.Is(err, store.ErrMFAEnabled):
+		tracing.Error(w, r, "MFA Already Enabled!", http.StatusConflict)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Enrol Authenticator App!", err)
+		return
+	}
+
+	response := dto.TOTPEnrolmentResponse{
+		Secret:     enrolment.Secret,
+		OtpauthURI: enrolment.URI,
+		QRCodePNG:  enrolment.QRCodePNG,
+	}
+	writeSecret(w, r, http.StatusCreated, response)
+}
+
+// ConfirmTOTP godoc
+// @Summary Confirm an authenticator app
+// @Description Turn on the logged in user's authenticator app with a code from it, returning single-use recovery codes for when the app is lost. They are not shown again.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code body dto.ConfirmTOTPRequest true "Code from the authenticator app"
+// @Success 200 {object} dto.RecoveryCodesResponse
+// @Failure 400 {string} string "Invalid Request Body or Wrong Code"
+// @Failure 401 {string} string "Access Token Required"
+// @Failure 404 {string} string "No Authenticator App Enrolled"
+// @Failure 409 {string} string "MFA Already Enabled"
+// @Failure 500 {string} string "Failed to Confirm Authenticator App"
+// @Router /auth/mfa/totp/confirm [post]
+func (handler *AuthHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.ClaimsFromContext(r.Context())
+
+	var req dto.ConfirmTOTPRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	codes, err := handler.authenticator.ConfirmTOTP(r.Context(), claims.UserId, req.Code)
+	switch {
+	case errors.Is(err, store.ErrWrongCode):
+		tracing.Error(w, r, "Wrong Code!", http.StatusBadRequest)
+		return
+	case errors.Is(err, store.ErrFactorNotFound):
+		tracing.Error(w, r, "No Authenticator App Enrolled!", http.StatusNotFound)
+		return
+	case errors.Is(err, store.ErrMFAEnabled):
+		tracing.Error(w, r, "MFA Already Enabled!", http.StatusConflict)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Confirm Authenticator App!", err)
+		return
+	}
+
+	writeSecret(w, r, http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Replace recovery codes
+// @Description Replace the logged in user's recovery codes with new ones. The old ones stop working.
+// @Tags MFA
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.RecoveryCodesResponse
+// @Failure 401 {string} string "Access Token Required"
+// @Failure 409 {string} string "MFA Not Enabled"
+// @Failure 500 {string} string "Failed to Replace Recovery Codes"
+// @Router /auth/mfa/recovery-codes [post]
+func (handler *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	claims, _ := auth.ClaimsFromContext(r.Context())
+
+	codes, err := handler.authenticator.RegenerateRecoveryCodes(r.Context(), claims.UserId)
+	switch {
+	case errors.Is(err, store.ErrMFANotEnabled):
+		tracing.Error(w, r, "MFA Not Enabled!", http.StatusConflict)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Replace Recovery Codes!", err)
+		return
+	}
+
+	writeSecret(w, r, http.StatusOK, dto.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// ResetMFA godoc
+// @Summary Reset a user's MFA
+// @Description Remove a user's authenticator app and recovery codes, so that they log in with their password alone until they enrol again. Only admins may reset MFA.
+// @Tags MFA
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {string} string "Invalid User Id"
+// @Failure 401 {string} string "Access Token Required"
+// @Failure 403 {string} string "Admin Access Required, With a Second Factor"
+// @Failure 404 {string} string "MFA Not Enabled"
+// @Failure 500 {string} string "Failed to Reset MFA"
+// @Router /users/{id}/mfa [delete]
+func (handler *AuthHandler) ResetMFA(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "id")
+	parsedId, err := uuid.Parse(userId)
+	if err != nil {
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", parsedId.String()))
+
+	reset, err := handler.authenticator.ResetMFA(r.Context(), parsedId)
+	if err != nil {
+		serverError(w, r, "Failed to Reset MFA!", err)
+		return
+	}
+	if !reset {
+		tracing.Error(w, r, "MFA Not Enabled!", http.StatusNotFound)
+		return
+	}
+
+	writeMessage(w, r, http.StatusOK, "MFA reset successfully!")
+}
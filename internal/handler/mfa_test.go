@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/mail"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store/memory"
+	"github.com/pquerna/otp/totp"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// newPasswordUser creates Alice with newPassword as her password and logs
+// her in.
+func newPasswordUser(t *testing.T, router http.Handler, userStore *memory.UserStore, sender *mail.MemorySender) (model.User, dto.TokenResponse) {
+	t.Helper()
+
+	user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	resetToken := requestReset(t, router, sender, "alice@example.com")
+	if w := postJSON(router, "/auth/password/reset", dto.ResetPasswordRequest{Token: resetToken, Password: newPassword}); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	return user, login(t, router, "alice@example.com", newPassword)
+}
+
+// postWithToken posts body to target with accessToken as the bearer token.
+func postWithToken(router http.Handler, target, accessToken string, body any) *httptest.ResponseRecorder {
+	payload, _ := json.Marshal(body)
+	req := httptest.NewRequest(http.MethodPost, target, bytes.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// enableMFA enrols and confirms an authenticator app for the user the access
+// token belongs to, returning its secret and the recovery codes.
+func enableMFA(t *testing.T, router http.Handler, accessToken string) (string, []string) {
+	t.Helper()
+
+	w := postWithToken(router, "/auth/mfa/totp", accessToken, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+	var enrolment dto.TOTPEnrolmentResponse
+	if err := json.NewDecoder(w.Body).Decode(&enrolment); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	code, err := totp.GenerateCode(enrolment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	w = postWithToken(router, "/auth/mfa/totp/confirm", accessToken, dto.ConfirmTOTPRequest{Code: code})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var recovery dto.RecoveryCodesResponse
+	if err := json.NewDecoder(w.Body).Decode(&recovery); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return enrolment.Secret, recovery.RecoveryCodes
+}
+
+// loginChallenged logs in with a password and returns the MFA token the
+// login is answered with.
+func loginChallenged(t *testing.T, router http.Handler, email, password string) string {
+	t.Helper()
+
+	w := postJSON(router, "/auth/login", dto.LoginRequest{Email: email, Password: password})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body)
+	}
+	var challenge dto.MFAChallengeResponse
+	if err := json.NewDecoder(w.Body).Decode(&challenge); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !challenge.MFARequired || challenge.MFAToken == "" || challenge.ExpiresIn != 300 {
+		t.Fatalf("Unexpected challenge %+v", challenge)
+	}
+	return challenge.MFAToken
+}
+
+func TestMFA_EnrolConfirmLogin(t *testing.T) {
+	router, userStore, sender := newAuthRouter(t)
+	user, tokens := newPasswordUser(t, router, userStore, sender)
+
+	w := postWithToken(router, "/auth/mfa/totp", tokens.AccessToken, nil)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+	if cacheControl := w.Header().Get("Cache-Control"); cacheControl != "no-store" {
+		t.Errorf("Expected Cache-Control no-store, got %q", cacheControl)
+	}
+	var enrolment dto.TOTPEnrolmentResponse
+	if err := json.NewDecoder(w.Body).Decode(&enrolment); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if !strings.HasPrefix(enrolment.OtpauthURI, "otpauth://totp/Example:alice@example.com?") || !strings.Contains(enrolment.OtpauthURI, "secret="+enrolment.Secret) {
+		t.Errorf("Unexpected otpauth URI %q", enrolment.OtpauthURI)
+	}
+	if !bytes.HasPrefix(enrolment.QRCodePNG, pngSignature) {
+		t.Errorf("Expected a PNG QR code")
+	}
+
+	// the secret is only kept encrypted
+	factor, _, _ := userStore.GetTOTPFactor(t.Context(), user.UserId)
+	if bytes.Contains(factor.SecretCiphertext, []byte(enrolment.Secret)) {
+		t.Errorf("Expected the stored secret to be encrypted")
+	}
+
+	// an unconfirmed app does not guard logins
+	login(t, router, "alice@example.com", newPassword)
+
+	if w := postWithToken(router, "/auth/mfa/totp/confirm", tokens.AccessToken, dto.ConfirmTOTPRequest{Code: "000000"}); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a wrong code, got %d", w.Code)
+	}
+	now := time.Now()
+	code, _ := totp.GenerateCode(enrolment.Secret, now)
+	w = postWithToken(router, "/auth/mfa/totp/confirm", tokens.AccessToken, dto.ConfirmTOTPRequest{Code: code})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var recovery dto.RecoveryCodesResponse
+	if err := json.NewDecoder(w.Body).Decode(&recovery); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(recovery.RecoveryCodes) != 10 {
+		t.Errorf("Expected 10 recovery codes, got %v", recovery.RecoveryCodes)
+	}
+
+	mfaToken := loginChallenged(t, router, "alice@example.com", newPassword)
+
+	// the code that confirmed the app is spent
+	if w := postJSON(router, "/auth/login/mfa", dto.LoginMFARequest{MFAToken: mfaToken, Code: code}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a reused code, got %d", w.Code)
+	}
+
+	nextCode, _ := totp.GenerateCode(enrolment.Secret, now.Add(30*time.Second))
+	w = postJSON(router, "/auth/login/mfa", dto.LoginMFARequest{MFAToken: mfaToken, Code: nextCode})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var mfaTokens dto.TokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&mfaTokens); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if mfaTokens.AccessToken == "" || mfaTokens.RefreshToken == "" {
+		t.Errorf("Unexpected tokens %+v", mfaTokens)
+	}
+
+	// each MFA token finishes one login
+	if w := postJSON(router, "/auth/login/mfa", dto.LoginMFARequest{MFAToken: mfaToken, Code: nextCode}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a used MFA token, got %d", w.Code)
+	}
+
+	want := []model.AuditAction{
+		model.AuditMFAEnrolmentStarted,
+		model.AuditLoginSucceeded,
+		model.AuditMFAEnabled,
+		model.AuditLoginMFAChallenged,
+		model.AuditLoginFailed,
+		model.AuditLoginSucceeded,
+	}
+	got := auditActions(t, router, user)
+	if got = got[len(got)-len(want):]; !slices.Equal(got, want) {
+		t.Errorf("Expected audit actions to end with %v, got %v", want, got)
+	}
+}
+
+func TestMFA_RecoveryCodes(t *testing.T) {
+	router, userStore, sender := newAuthRouter(t)
+	_, tokens := newPasswordUser(t, router, userStore, sender)
+	_, codes := enableMFA(t, router, tokens.AccessToken)
+
+	mfaToken := loginChallenged(t, router, "alice@example.com", newPassword)
+	// codes are accepted however they are typed
+	typed := " " + strings.ToUpper(codes[0]) + " "
+	if w := postJSON(router, "/auth/login/mfa", dto.LoginMFARequest{MFAToken: mfaToken, Code: typed}); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	mfaToken = loginChallenged(t, router, "alice@example.com", newPassword)
+	if w := postJSON(router, "/auth/login/mfa", dto.LoginMFARequest{MFAToken: mfaToken, Code: codes[0]}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a used recovery code, got %d", w.Code)
+	}
+
+	w := postWithToken(router, "/auth/mfa/recovery-codes", tokens.AccessToken, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var recovery dto.RecoveryCodesResponse
+	if err := json.NewDecoder(w.Body).Decode(&recovery); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if w := postJSON(router, "/auth/login/mfa", dto.LoginMFARequest{MFAToken: mfaToken, Code: codes[1]}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a replaced recovery code, got %d", w.Code)
+	}
+	if w := postJSON(router, "/auth/login/mfa", dto.LoginMFARequest{MFAToken: mfaToken, Code: recovery.RecoveryCodes[0]}); w.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestMFA_TooManyAttempts(t *testing.T) {
+	router, userStore, sender := newAuthRouter(t)
+	_, tokens := newPasswordUser(t, router, userStore, sender)
+	secret, _ := enableMFA(t, router, tokens.AccessToken)
+
+	mfaToken := loginChallenged(t, router, "alice@example.com", newPassword)
+	for range 3 {
+		if w := postJSON(router, "/auth/login/mfa", dto.LoginMFARequest{MFAToken: mfaToken, Code: "000000"}); w.Code != http.StatusUnauthorized {
+			t.Fatalf("Expected 401 for a wrong code, got %d", w.Code)
+		}
+	}
+
+	code, _ := totp.GenerateCode(secret, time.Now().Add(30*time.Second))
+	if w := postJSON(router, "/auth/login/mfa", dto.LoginMFARequest{MFAToken: mfaToken, Code: code}); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 once the attempts are used up, got %d", w.Code)
+	}
+}
+
+func TestMFA_RequiresAccessToken(t *testing.T) {
+	router, _, _ := newAuthRouter(t)
+
+	tests := []struct {
+		name   string
+		header string
+	}{
+		{"Missing", ""},
+		{"Not bearer", "Basic YWxpY2U6cGFzc3dvcmQ="},
+		{"Invalid", "Bearer not-a-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/auth/mfa/totp", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Expected 401, got %d", w.Code)
+			}
+			if !strings.HasPrefix(w.Header().Get("WWW-Authenticate"), "Bearer") {
+				t.Errorf("Expected a Bearer challenge, got %q", w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestResetMFA(t *testing.T) {
+	router, userStore, sender := newAuthRouter(t)
+	user, tokens := newPasswordUser(t, router, userStore, sender)
+	enableMFA(t, router, tokens.AccessToken)
+	admin := loginAdmin(t, router, sender)
+
+	if w := postWithToken(router, "/auth/mfa/totp", tokens.AccessToken, nil); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for enrolling twice, got %d", w.Code)
+	}
+
+	w := deleteWithToken(router, "/users/"+user.UserId.String()+"/mfa", admin.AccessToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+
+	// the password alone logs in again
+	login(t, router, "alice@example.com", newPassword)
+
+	w = deleteWithToken(router, "/users/"+user.UserId.String()+"/mfa", admin.AccessToken)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without MFA, got %d", w.Code)
+	}
+
+	if got := auditActions(t, router, user); !slices.Contains(got, model.AuditMFAReset) {
+		t.Errorf("Expected the reset to be audited, got %v", got)
+	}
+}
@@ -0,0 +1,172 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/oidc"
+	"example.com/user-management/internal/tracing"
+	"github.com/go-chi/chi/v5"
+)
+
+type OAuthClientHandler struct {
+	provider *oidc.Provider
+}
+
+func NewOAuthClientHandler(provider *oidc.Provider) *OAuthClientHandler {
+	return &OAuthClientHandler{
+		provider: provider,
+	}
+}
+
+// RegisterOAuthClient godoc
+// @Summary Register an OpenID Connect client
+// @Description Register an app that signs users in with OpenID Connect. Confidential clients get a secret, which is only returned here. Only admins may manage clients.
+// @Tags OAuth Clients
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param client body dto.RegisterOAuthClientRequest true "Client payload"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {string} string "Invalid Request Body or Redirect URI"
+// @Failure 401 {string} string "Access Token Required"
+// @Failure 403 {string} string "Admin Access Required, With a Second Factor"
+// @Failure 500 {string} string "Failed to Register Client"
+// @Router /oauth-clients [post]
+func (handler *OAuthClientHandler) RegisterOAuthClient(w http.ResponseWriter, r *http.Request) {
+	var req dto.RegisterOAuthClientRequest
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	client, err := handler.provider.RegisterClient(r.Context(), req.Name, req.RedirectURIs, req.Public)
+
+	if errors.Is(err, oidc.ErrInvalidRedirectURI) {
+		tracing.Error(w, r, "Invalid Redirect URI!", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		serverError(w, r, "Failed to Register Client!", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Client registered successfully!",
+		"client":  client,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	err = json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
+
+// GetAllOAuthClients godoc
+// @Summary Retrieve all OpenID Connect clients
+// @Description Get a list of all registered clients, ordered by name. Secrets are not included.
+// @Tags OAuth Clients
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} oidc.Client
+// @Failure 401 {string} string "Access Token Required"
+// @Failure 403 {string} string "Admin Access Required, With a Second Factor"
+// @Failure 500 {string} string "Failed to Retrieve Clients"
+// @Router /oauth-clients [get]
+func (handler *OAuthClientHandler) GetAllOAuthClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := handler.provider.Clients(r.Context())
+
+	if err != nil {
+		serverError(w, r, "Failed to Retrieve Clients!", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(clients)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
+
+// GetOAuthClient godoc
+// @Summary Get an OpenID Connect client
+// @Description Retrieve a registered client by its client id
+// @Tags OAuth Clients
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Client ID"
+// @Success 200 {object} oidc.Client
+// @Failure 401 {string} string "Access Token Required"
+// @Failure 403 {string} string "Admin Access Required, With a Second Factor"
+// @Failure 404 {string} string "Client Not Found"
+// @Failure 500 {string} string "Failed to Retrieve Client"
+// @Router /oauth-clients/{id} [get]
+func (handler *OAuthClientHandler) GetOAuthClient(w http.ResponseWriter, r *http.Request) {
+	client, ok, err := handler.provider.GetClient(r.Context(), chi.URLParam(r, "id"))
+
+	if err != nil {
+		serverError(w, r, "Failed to Retrieve Client!", err)
+		return
+	}
+
+	if !ok {
+		tracing.Error(w, r, "Client Not Found!", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(client)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
+
+// DeleteOAuthClient godoc
+// @Summary Delete an OpenID Connect client
+// @Description Delete a registered client along with its consents and refresh tokens. Access tokens it holds stay valid until they expire.
+// @Tags OAuth Clients
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Client ID"
+// @Success 200 {object} map[string]string
+// @Failure 401 {string} string "Access Token Required"
+// @Failure 403 {string} string "Admin Access Required, With a Second Factor"
+// @Failure 404 {string} string "Client Not Found"
+// @Failure 500 {string} string "Failed to Delete Client"
+// @Router /oauth-clients/{id} [delete]
+func (handler *OAuthClientHandler) DeleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	ok, err := handler.provider.DeleteClient(r.Context(), chi.URLParam(r, "id"))
+
+	if err != nil {
+		serverError(w, r, "Failed to Delete Client!", err)
+		return
+	}
+
+	if !ok {
+		tracing.Error(w, r, "Client Not Found!", http.StatusNotFound)
+		return
+	}
+
+	writeMessage(w, r, http.StatusOK, "Client Deleted successfully!")
+}
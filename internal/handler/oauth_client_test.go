@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/mail"
+	"example.com/user-management/internal/oidc"
+	"example.com/user-management/internal/store/memory"
+	"example.com/user-management/internal/token"
+	"github.com/go-chi/chi/v5"
+)
+
+func newOAuthClientRouter(t *testing.T) http.Handler {
+	t.Helper()
+
+	userStore := memory.NewUserStore()
+	signer := token.NewSigner([]byte("secret"))
+	authenticator := auth.NewAuthenticator(userStore, mail.NewMemorySender(), signer, auth.Options{
+		AccessTokenTTL: 15 * time.Minute,
+		SessionTTL:     time.Hour,
+	})
+	provider := oidc.NewProvider(userStore, authenticator, signer, oidc.Options{
+		Issuer:          "https://id.example.com/oidc",
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: time.Hour,
+		KeyRotation:     24 * time.Hour,
+	})
+	handler := NewOAuthClientHandler(provider)
+
+	router := chi.NewRouter()
+	router.Post("/oauth-clients", handler.RegisterOAuthClient)
+	router.Get("/oauth-clients", handler.GetAllOAuthClients)
+	router.Get("/oauth-clients/{id}", handler.GetOAuthClient)
+	router.Delete("/oauth-clients/{id}", handler.DeleteOAuthClient)
+	return router
+}
+
+func TestOAuthClients_CRUD(t *testing.T) {
+	router := newOAuthClientRouter(t)
+
+	w := postJSON(router, "/oauth-clients", dto.RegisterOAuthClientRequest{
+		Name:         "Dashboard",
+		RedirectURIs: []string{"https://dashboard.example.com/callback"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body)
+	}
+	var response struct{ Client oidc.Client }
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	client := response.Client
+	if client.ClientId == "" || client.ClientSecret == "" || client.Public {
+		t.Fatalf("Expected a confidential client with a secret, got %+v", client)
+	}
+
+	got := getJSON[oidc.Client](t, router, "/oauth-clients/"+client.ClientId)
+	if got.Name != "Dashboard" || got.ClientSecret != "" {
+		t.Errorf("Expected the client without its secret, got %+v", got)
+	}
+	if clients := getJSON[[]oidc.Client](t, router, "/oauth-clients"); len(clients) != 1 {
+		t.Errorf("Expected 1 client, got %d", len(clients))
+	}
+
+	for _, want := range []int{http.StatusOK, http.StatusNotFound} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/oauth-clients/"+client.ClientId, nil))
+		if w.Code != want {
+			t.Errorf("Expected %d, got %d: %s", want, w.Code, w.Body)
+		}
+	}
+}
+
+func TestRegisterOAuthClient_Invalid(t *testing.T) {
+	router := newOAuthClientRouter(t)
+
+	tests := []struct {
+		name string
+		req  dto.RegisterOAuthClientRequest
+	}{
+		{"no redirect URIs", dto.RegisterOAuthClientRequest{Name: "Dashboard"}},
+		{"relative redirect URI", dto.RegisterOAuthClientRequest{Name: "Dashboard", RedirectURIs: []string{"/callback"}}},
+		{"fragment", dto.RegisterOAuthClientRequest{Name: "Dashboard", RedirectURIs: []string{"https://dashboard.example.com/#callback"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if w := postJSON(router, "/oauth-clients", tt.req); w.Code != http.StatusBadRequest {
+				t.Errorf("Expected 400, got %d: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestGetOAuthClient_UnknownId(t *testing.T) {
+	router := newOAuthClientRouter(t)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/oauth-clients/not-a-client", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", w.Code)
+	}
+}
+
+func TestOAuthClients_RequireAdmin(t *testing.T) {
+	router, userStore, sender := newAuthRouter(t)
+	_, tokens := newPasswordUser(t, router, userStore, sender)
+	admin := loginAdmin(t, router, sender)
+
+	request := dto.RegisterOAuthClientRequest{
+		Name:         "Dashboard",
+		RedirectURIs: []string{"https://dashboard.example.com/callback"},
+	}
+	if w := postJSON(router, "/oauth-clients", request); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without an access token, got %d", w.Code)
+	}
+	if w := postWithToken(router, "/oauth-clients", tokens.AccessToken, request); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a user who is not an admin, got %d", w.Code)
+	}
+	if w := postWithToken(router, "/oauth-clients", admin.AccessToken, request); w.Code != http.StatusCreated {
+		t.Errorf("Expected 201 for an admin, got %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tracing"
+	"example.com/user-management/internal/verification"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type PhoneVerificationHandler struct {
+	verifier *verification.PhoneVerifier
+}
+
+func NewPhoneVerificationHandler(verifier *verification.PhoneVerifier) *PhoneVerificationHandler {
+	return &PhoneVerificationHandler{
+		verifier: verifier,
+	}
+}
+
+// SendVerificationCode godoc
+// @Summary Send a phone verification code
+// @Description Text the user a one-time code that confirms their current phone number
+// @Tags Users
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 202 {object} map[string]string
+// @Failure 400 {string} string "Invalid User Id"
+// @Failure 404 {string} string "User Not Found"
+// @Failure 409 {string} string "Phone Already Verified"
+// @Failure 429 {string} string "Code Sent Too Recently"
+// @Failure 500 {string} string "Failed to Send Verification Code"
+// @Router /users/{id}/verify-phone/send [post]
+func (handler *PhoneVerificationHandler) SendVerificationCode(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "id")
+	parsedId, err := uuid.Parse(userId)
+	if err != nil {
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", parsedId.String()))
+
+	err = handler.verifier.Send(r.Context(), parsedId)
+	var throttled *verification.ThrottledError
+	switch {
+	case errors.Is(err, verification.ErrUserNotFound):
+		tracing.Error(w, r, "User Not Found!", http.StatusNotFound)
+		return
+	case errors.Is(err, verification.ErrAlreadyVerified):
+		tracing.Error(w, r, "Phone Already Verified!", http.StatusConflict)
+		return
+	case errors.As(err, &throttled):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+		tracing.Error(w, r, "Code Sent Too Recently!", http.StatusTooManyRequests)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Send Verification Code!", err)
+		return
+	}
+
+	response := map[string]string{
+		"message": "Verification code sent!",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	err = json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
+
+// VerifyPhone godoc
+// @Summary Verify a phone number
+// @Description Confirm the phone number a verification code was sent to
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param code body dto.VerifyPhoneRequest true "Verification code"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {string} string "Invalid Request Body, User Id or Code"
+// @Failure 404 {string} string "No Code Pending"
+// @Failure 409 {string} string "Phone Changed"
+// @Failure 410 {string} string "Code Expired"
+// @Failure 429 {string} string "Too Many Attempts"
+// @Failure 500 {string} string "Failed to Verify Phone"
+// @Router /users/{id}/verify-phone [post]
+func (handler *PhoneVerificationHandler) VerifyPhone(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "id")
+	parsedId, err := uuid.Parse(userId)
+	if err != nil {
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", parsedId.String()))
+
+	var req dto.VerifyPhoneRequest
+
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+		return
+	}
+
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := handler.verifier.Verify(r.Context(), parsedId, req.Code)
+	switch {
+	case errors.Is(err, store.ErrWrongCode):
+		tracing.Error(w, r, "Incorrect Code!", http.StatusBadRequest)
+		return
+	case errors.Is(err, store.ErrVerificationNotFound), errors.Is(err, store.ErrVerificationUsed):
+		tracing.Error(w, r, "No Code Pending!", http.StatusNotFound)
+		return
+	case errors.Is(err, store.ErrPhoneChanged):
+		tracing.Error(w, r, "Phone Changed Since the Code Was Sent!", http.StatusConflict)
+		return
+	case errors.Is(err, store.ErrVerificationExpired):
+		tracing.Error(w, r, "Code Expired!", http.StatusGone)
+		return
+	case errors.Is(err, store.ErrTooManyAttempts):
+		tracing.Error(w, r, "Too Many Attempts, Request a New Code!", http.StatusTooManyRequests)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Verify Phone!", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message": "Phone verified successfully!",
+		"user":    user,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(response)
+
+	if err != nil {
+		serverError(w, r, "Failed to encode response", err)
+		return
+	}
+}
@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/sms"
+	"example.com/user-management/internal/store/memory"
+	"example.com/user-management/internal/token"
+	"example.com/user-management/internal/verification"
+	"github.com/go-chi/chi/v5"
+)
+
+var codePattern = regexp.MustCompile(`\b\d{6}\b`)
+
+func newPhoneVerificationRouter(t *testing.T) (http.Handler, model.User, *sms.MemorySender) {
+	t.Helper()
+
+	userStore := memory.NewUserStore()
+	sender := sms.NewMemorySender()
+	verifier := verification.NewPhoneVerifier(userStore, sender, token.NewSigner([]byte("secret")), verification.PhoneOptions{
+		TTL:            10 * time.Minute,
+		MaxAttempts:    2,
+		ResendInterval: time.Minute,
+	})
+	handler := NewPhoneVerificationHandler(verifier)
+
+	router := chi.NewRouter()
+	router.Post("/users/{id}/verify-phone/send", handler.SendVerificationCode)
+	router.Post("/users/{id}/verify-phone", handler.VerifyPhone)
+
+	user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com", Phone: "+12345678901"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	return router, user, sender
+}
+
+func postPhone(router http.Handler, target, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(body)))
+	return w
+}
+
+func TestPhoneVerification_SendAndVerify(t *testing.T) {
+	router, user, sender := newPhoneVerificationRouter(t)
+	base := "/users/" + user.UserId.String() + "/verify-phone"
+
+	if w := postPhone(router, base+"/send", ""); w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d: %s", w.Code, w.Body)
+	}
+
+	messages := sender.Messages()
+	if len(messages) != 1 || messages[0].To != "+12345678901" {
+		t.Fatalf("Expected 1 message to +12345678901, got %+v", messages)
+	}
+	code := codePattern.FindString(messages[0].Body)
+	if code == "" {
+		t.Fatalf("Expected a code in %q", messages[0].Body)
+	}
+
+	// resending straight away is throttled
+	w := postPhone(router, base+"/send", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected a Retry-After header")
+	}
+
+	w = postPhone(router, base, `{"code":"`+code+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"PhoneVerifiedAt":"`)) {
+		t.Errorf("Expected the user to be verified, got %s", w.Body)
+	}
+
+	if w := postPhone(router, base+"/send", ""); w.Code != http.StatusConflict {
+		t.Errorf("Expected 409 for a verified phone, got %d", w.Code)
+	}
+}
+
+func TestPhoneVerification_AttemptLimit(t *testing.T) {
+	router, user, sender := newPhoneVerificationRouter(t)
+	base := "/users/" + user.UserId.String() + "/verify-phone"
+
+	if w := postPhone(router, base+"/send", ""); w.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d", w.Code)
+	}
+	code := codePattern.FindString(sender.Messages()[0].Body)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	for range 2 {
+		if w := postPhone(router, base, `{"code":"`+wrong+`"}`); w.Code != http.StatusBadRequest {
+			t.Fatalf("Expected 400 for a wrong code, got %d", w.Code)
+		}
+	}
+	if w := postPhone(router, base, `{"code":"`+code+`"}`); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 once attempts are used up, got %d", w.Code)
+	}
+}
+
+func TestPhoneVerification_Errors(t *testing.T) {
+	router, user, _ := newPhoneVerificationRouter(t)
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+		code   int
+	}{
+		{"invalid user id", "/users/nope/verify-phone/send", "", http.StatusBadRequest},
+		{"unknown user", "/users/00000000-0000-0000-0000-000000000001/verify-phone/send", "", http.StatusNotFound},
+		{"malformed code", "/users/" + user.UserId.String() + "/verify-phone", `{"code":"12ab56"}`, http.StatusBadRequest},
+		{"no code pending", "/users/" + user.UserId.String() + "/verify-phone", `{"code":"123456"}`, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if w := postPhone(router, test.target, test.body); w.Code != test.code {
+				t.Errorf("Expected %d, got %d", test.code, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/tenant"
+	"example.com/user-management/internal/tracing"
+	"github.com/google/uuid"
+)
+
+type TenantResolver struct {
+	authenticator *auth.Authenticator
+	// multiTenant is false for stores that keep every user in the default
+	// tenant.
+	multiTenant bool
+}
+
+func NewTenantResolver(authenticator *auth.Authenticator, multiTenant bool) *TenantResolver {
+	return &TenantResolver{
+		authenticator: authenticator,
+		multiTenant:   multiTenant,
+	}
+}
+
+// Middleware makes the tenant a request acts for available with
+// tenant.FromContext. A valid bearer access token names the tenant its bearer
+// logged in to, whatever the client claims; other requests name theirs in
+// the X-Tenant-ID header or act for the default tenant.
+func (resolver *TenantResolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantId, ok := resolver.tokenTenant(r)
+		if !ok {
+			tenantId = tenant.Default
+			if header := r.Header.Get(tenant.Header); header != "" {
+				parsedId, err := uuid.Parse(header)
+				if err != nil {
+					tracing.Error(w, r, "Invalid Tenant Id!", http.StatusBadRequest)
+					return
+				}
+				tenantId = parsedId
+			}
+		}
+
+		if tenantId != tenant.Default && !resolver.multiTenant {
+			tracing.Error(w, r, "Tenants Not Supported!", http.StatusBadRequest)
+			return
+		}
+
+		logging.AddAttrs(r.Context(), slog.String("tenant_id", tenantId.String()))
+		next.ServeHTTP(w, r.WithContext(tenant.WithID(r.Context(), tenantId)))
+	})
+}
+
+// tokenTenant returns the tenant of the request's bearer access token, if it
+// has a valid one. Invalid tokens are left for RequireAccessToken to turn
+// away.
+func (resolver *TenantResolver) tokenTenant(r *http.Request) (uuid.UUID, bool) {
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return uuid.Nil, false
+	}
+	tenantId, err := resolver.authenticator.AccessTokenTenant(accessToken)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return tenantId, true
+}
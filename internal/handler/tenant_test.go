@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/mail"
+	"example.com/user-management/internal/store/memory"
+	"example.com/user-management/internal/tenant"
+	"example.com/user-management/internal/token"
+	"github.com/google/uuid"
+)
+
+func TestTenantResolver(t *testing.T) {
+	router, userStore, sender := newAuthRouter(t)
+	_, defaultTokens := newPasswordUser(t, router, userStore, sender)
+
+	// access tokens only need the same secret to be read
+	authenticator := auth.NewAuthenticator(memory.NewUserStore(), mail.NewMemorySender(), token.NewSigner([]byte("secret")), auth.Options{})
+	resolver := NewTenantResolver(authenticator, true)
+
+	// the memory store ignores tenants, so the user can log in to any
+	tenantId := uuid.New()
+	payload, _ := json.Marshal(dto.LoginRequest{Email: "alice@example.com", Password: newPassword})
+	req := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewReader(payload))
+	req.Header.Set(tenant.Header, tenantId.String())
+	w := httptest.NewRecorder()
+	resolver.Middleware(router).ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body)
+	}
+	var tenantTokens dto.TokenResponse
+	if err := json.NewDecoder(w.Body).Decode(&tenantTokens); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	echo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(tenant.FromContext(r.Context()).String()))
+	})
+
+	tests := []struct {
+		name         string
+		multiTenant  bool
+		header       string
+		accessToken  string
+		expectedCode int
+		expected     uuid.UUID
+	}{
+		{"nothing named", true, "", "", http.StatusOK, tenant.Default},
+		{"header", true, tenantId.String(), "", http.StatusOK, tenantId},
+		{"invalid header", true, "not-a-tenant", "", http.StatusBadRequest, uuid.Nil},
+		{"access token", true, "", tenantTokens.AccessToken, http.StatusOK, tenantId},
+		{"default tenant access token", true, "", defaultTokens.AccessToken, http.StatusOK, tenant.Default},
+		{"access token and matching header", true, tenantId.String(), tenantTokens.AccessToken, http.StatusOK, tenantId},
+		{"access token and other header", true, uuid.NewString(), tenantTokens.AccessToken, http.StatusOK, tenantId},
+		{"access token and invalid header", true, "not-a-tenant", tenantTokens.AccessToken, http.StatusOK, tenantId},
+		{"invalid access token", true, tenantId.String(), "not-a-token", http.StatusOK, tenantId},
+		{"single tenant", false, "", "", http.StatusOK, tenant.Default},
+		{"single tenant with header", false, tenantId.String(), "", http.StatusBadRequest, uuid.Nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.header != "" {
+				req.Header.Set(tenant.Header, test.header)
+			}
+			if test.accessToken != "" {
+				req.Header.Set("Authorization", "Bearer "+test.accessToken)
+			}
+			w := httptest.NewRecorder()
+			NewTenantResolver(authenticator, test.multiTenant).Middleware(echo).ServeHTTP(w, req)
+
+			if w.Code != test.expectedCode {
+				t.Fatalf("Expected %d, got %d: %s", test.expectedCode, w.Code, w.Body)
+			}
+			if test.expectedCode == http.StatusOK && w.Body.String() != test.expected.String() {
+				t.Errorf("Expected tenant %s, got %s", test.expected, w.Body)
+			}
+		})
+	}
+}
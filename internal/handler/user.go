@@ -1,23 +1,48 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log/slog"
 	"net/http"
+	"net/url"
+	"strings"
 
+	"example.com/user-management/internal/attributes"
 	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/emailaddr"
+	"example.com/user-management/internal/logging"
 	"example.com/user-management/internal/mapper"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
 	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tracing"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 )
 
+// userPageSize is how many users listAllUsers reads at a time.
+const userPageSize = 100
+
+// attributeFilterPrefix starts the query parameters that filter users by
+// attribute, as in ?attr.department=eng.
+const attributeFilterPrefix = "attr."
+
 var validate = validator.New()
 
 type UserHandler struct {
 	store store.UserStoreInterface
 }
 
+// serverError logs err with its cause chain and replies with a 500 carrying
+// only message, so that internal details stay out of the response.
+func serverError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	logging.FromContext(r.Context()).Error(message, logging.Err(err))
+	tracing.Error(w, r, message, http.StatusInternalServerError)
+}
+
 func NewUserHandler(store store.UserStoreInterface) *UserHandler {
 	return &UserHandler{
 		store: store,
@@ -27,12 +52,15 @@ func NewUserHandler(store store.UserStoreInterface) *UserHandler {
 // CreateUser godoc
 // @Summary Create a new user
 // @Description Create a new user with the input payload
+// @Description The email address is stored with its domain lowercased and in punycode. Addresses differing only in case belong to one user, as do the variants differing in the dots and plus tags Gmail ignores if EMAIL_PROVIDER_RULES is set.
+// @Description The phone number is stored in E.164 format, with the country and type derived from it. It may be given in international format or, if PHONE_DEFAULT_REGION is set, in the national format of that region.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param user body dto.CreateUserRequest true "User payload"
 // @Success 201 {object} map[string]interface{}
-// @Failure 400 {string} string "Invalid Request Body"
+// @Failure 400 {string} string "Invalid Request Body, Email, Phone or Attributes"
+// @Failure 409 {string} string "Email Already Exists"
 // @Failure 500 {string} string "Failed to Create User"
 // @Router /users [post]
 func (handler *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
@@ -41,23 +69,34 @@ func (handler *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	// read the request body and decode it as user
 	err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
 		return
 	}
+	// whitespace around an address is not part of it
+	req.Email = strings.TrimSpace(req.Email)
 
 	err = validate.Struct(req)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	user := mapper.CreateUserRequestToModel(req)
-	createdUser, err := handler.store.CreateUser(user)
+	createdUser, err := handler.store.CreateUser(r.Context(), user)
 
+	if errors.Is(err, store.ErrDuplicateEmail) {
+		tracing.Error(w, r, "Email Already Exists!", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, attributes.ErrInvalid) || errors.Is(err, emailaddr.ErrInvalid) || errors.Is(err, phonenumber.ErrInvalid) {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Failed to Create User!", http.StatusInternalServerError)
+		serverError(w, r, "Failed to Create User!", err)
 		return
 	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", createdUser.UserId.String()))
 
 	response := map[string]interface{}{
 		"message": "User created successfully!",
@@ -69,24 +108,45 @@ func (handler *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	err = json.NewEncoder(w).Encode(response)
 
 	if err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		serverError(w, r, "Failed to encode response", err)
 		return
 	}
 }
 
 // GetAllUsers godoc
 // @Summary Retrieve all users
-// @Description Get a list of all users
+// @Description Get a list of all users, or only the members of a group. Users can be filtered by attribute with
+// @Description attr.<name>=<value> parameters, such as ?attr.department=eng; values are read as the type the
+// @Description attribute schema gives them.
 // @Tags Users
 // @Produce json
+// @Param group query string false "Only return members of this group"
 // @Success 200 {array} model.User
+// @Failure 400 {string} string "Invalid Group Id"
 // @Failure 500 {string} string "Failed to Retrieve Users"
 // @Router /users [get]
-func (handler *UserHandler) GetAllUsers(w http.ResponseWriter, _ *http.Request) {
-	allUsers, err := handler.store.GetAllUsers()
+func (handler *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
+	filter := model.UserFilter{Attributes: attributeFilter(r.URL.Query())}
+	if value := r.URL.Query().Get("group"); value != "" {
+		groupId, err := uuid.Parse(value)
+		if err != nil {
+			tracing.Error(w, r, "Invalid Group Id!", http.StatusBadRequest)
+			return
+		}
+		logging.AddAttrs(r.Context(), slog.String("group_id", groupId.String()))
+		filter.GroupId = groupId
+	}
+
+	var allUsers []model.User
+	var err error
+	if filter.GroupId != uuid.Nil || len(filter.Attributes) > 0 {
+		allUsers, err = listAllUsers(r.Context(), handler.store, filter)
+	} else {
+		allUsers, err = handler.store.GetAllUsers(r.Context())
+	}
 
 	if err != nil {
-		http.Error(w, "Failed to Retrieve Users!", http.StatusInternalServerError)
+		serverError(w, r, "Failed to Retrieve Users!", err)
 		return
 	}
 
@@ -95,7 +155,7 @@ func (handler *UserHandler) GetAllUsers(w http.ResponseWriter, _ *http.Request)
 	err = json.NewEncoder(w).Encode(allUsers)
 
 	if err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		serverError(w, r, "Failed to encode response", err)
 		return
 	}
 }
@@ -107,6 +167,7 @@ func (handler *UserHandler) GetAllUsers(w http.ResponseWriter, _ *http.Request)
 // @Produce json
 // @Param id path string true "User ID"
 // @Success 200 {object} model.User
+// @Header 200 {string} ETag "Version of the user, for If-Match"
 // @Failure 400 {string} string "Invalid User Id"
 // @Failure 404 {string} string "User Not Found"
 // @Failure 500 {string} string "Failed to Retrieve User"
@@ -115,27 +176,29 @@ func (handler *UserHandler) GetUserById(w http.ResponseWriter, r *http.Request)
 	userId := chi.URLParam(r, "id")
 	parsedId, err := uuid.Parse(userId)
 	if err != nil {
-		http.Error(w, "Invalid User Id!", http.StatusBadRequest)
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
 		return
 	}
-	user, ok, err := handler.store.GetUserById(parsedId)
+	logging.AddAttrs(r.Context(), slog.String("user_id", parsedId.String()))
+	user, ok, err := handler.store.GetUserById(r.Context(), parsedId)
 
 	if err != nil {
-		http.Error(w, "Failed to Retrieve User by Id!", http.StatusInternalServerError)
+		serverError(w, r, "Failed to Retrieve User by Id!", err)
 		return
 	}
 
 	if !ok {
-		http.Error(w, "User Not Found!", http.StatusNotFound)
+		tracing.Error(w, r, "User Not Found!", http.StatusNotFound)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", userETag(user))
 	w.WriteHeader(http.StatusOK)
 	err = json.NewEncoder(w).Encode(user)
 
 	if err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		serverError(w, r, "Failed to encode response", err)
 		return
 	}
 
@@ -143,82 +206,227 @@ func (handler *UserHandler) GetUserById(w http.ResponseWriter, r *http.Request)
 
 // UpdateUser godoc
 // @Summary Update a user
-// @Description Update a user's information by UUID
+// @Description Update a user's information by UUID. A JSON body sets the fields it gives. A JSON Merge Patch (RFC 7396) can also clear fields by setting them to null, and a JSON Patch (RFC 6902) can test fields before changing them. Patches apply to the user as dto.UserDocument, and the result must be a valid user.
 // @Tags Users
-// @Accept json
+// @Accept json,application/merge-patch+json,application/json-patch+json
 // @Produce json
+// @Description If-Match makes the update depend on the user's ETag.
 // @Param id path string true "User ID"
+// @Param If-Match header string false "ETags the user must have, or * for any"
 // @Param user body dto.UpdateUserRequest true "User update payload"
 // @Success 200 {object} map[string]interface{}
-// @Failure 400 {string} string "Invalid Request Body or User Id"
+// @Header 200 {string} ETag "Version of the user, for If-Match"
+// @Failure 400 {string} string "Invalid Request Body, Patch, User Id, If-None-Match, Email, Phone or Attributes"
 // @Failure 404 {string} string "User Not Found"
+// @Failure 409 {string} string "Patch Test Failed or Email Already Exists"
+// @Failure 412 {string} string "Precondition Failed"
+// @Failure 415 {string} string "Unsupported Content Type"
 // @Failure 500 {string} string "Failed to Update User"
 // @Router /users/{id} [patch]
 func (handler *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 	userId := chi.URLParam(r, "id")
 	parsedId, err := uuid.Parse(userId)
 	if err != nil {
-		http.Error(w, "Invalid User Id!", http.StatusBadRequest)
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", parsedId.String()))
+
+	mediaType, err := patchMediaType(r)
+	if err != nil {
+		w.Header().Set("Accept-Patch", acceptPatch)
+		tracing.Error(w, r, "Unsupported Content Type!", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	precondition, ok := userPrecondition(r)
+	if !ok {
+		tracing.Error(w, r, "Only If-None-Match: * Is Supported!", http.StatusBadRequest)
 		return
 	}
 
 	var req dto.UpdateUserRequest
+	var patch userPatch
+
+	if mediaType == jsonType {
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
+			return
+		}
+		if req.Email != nil {
+			email := strings.TrimSpace(*req.Email)
+			req.Email = &email
+		}
+
+		err = validate.Struct(req)
+		if err != nil {
+			tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		patch, err = readUserPatch(r.Body, mediaType)
+		if err != nil {
+			tracing.Error(w, r, "Invalid Patch!", http.StatusBadRequest)
+			return
+		}
+	}
 
-	err = json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		http.Error(w, "Invalid Request Body!", http.StatusBadRequest)
+	// the patch is applied to, and its tests read, the user as the write
+	// replaces it
+	updatedUser, ok, err := handler.store.ModifyUser(r.Context(), parsedId, precondition, func(user *model.User) error {
+		if patch == nil {
+			mapper.ApplyUpdateUserRequest(user, req)
+			return nil
+		}
+		return applyUserPatch(user, patch)
+	})
+
+	switch {
+	case errors.Is(err, store.ErrPreconditionFailed):
+		tracing.Error(w, r, "Precondition Failed!", http.StatusPreconditionFailed)
+		return
+	case errors.Is(err, errPatchTestFailed):
+		tracing.Error(w, r, "Patch Test Failed!", http.StatusConflict)
+		return
+	case errors.Is(err, errPatchNotApplied), errors.Is(err, errInvalidPatchedDoc):
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	case errors.Is(err, store.ErrDuplicateEmail):
+		tracing.Error(w, r, "Email Already Exists!", http.StatusConflict)
+		return
+	case errors.Is(err, attributes.ErrInvalid), errors.Is(err, emailaddr.ErrInvalid), errors.Is(err, phonenumber.ErrInvalid):
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Update User!", err)
 		return
 	}
 
-	err = validate.Struct(req)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if !ok {
+		tracing.Error(w, r, "User Not Found!", http.StatusNotFound)
 		return
 	}
 
-	user, ok, err := handler.store.GetUserById(parsedId)
+	response := map[string]interface{}{
+		"message": "User Updated successfully!",
+		"user":    updatedUser,
+	}
+
+	w.Header().Set("ETag", userETag(updatedUser))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err = json.NewEncoder(w).Encode(response)
 
 	if err != nil {
-		http.Error(w, "Failed to Retrieve User by Id!", http.StatusInternalServerError)
+		serverError(w, r, "Failed to encode response", err)
 		return
 	}
+}
 
+// PutUser godoc
+// @Summary Create or replace a user
+// @Description Replace all of a user's fields with the payload, clearing optional fields it leaves out, or create the user with this UUID if there is none.
+// @Description If-Match makes the write depend on the user's ETag, and If-None-Match: * only creates the user.
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param If-Match header string false "ETags the user must have, or * for any"
+// @Param If-None-Match header string false "* to only create the user"
+// @Param user body dto.UserDocument true "User payload"
+// @Success 200 {object} map[string]interface{}
+// @Success 201 {object} map[string]interface{}
+// @Header 200,201 {string} ETag "Version of the user, for If-Match"
+// @Failure 400 {string} string "Invalid Request Body, User Id, If-None-Match, Email, Phone or Attributes"
+// @Failure 409 {string} string "Email Already Exists or User Id Taken"
+// @Failure 412 {string} string "Precondition Failed"
+// @Failure 500 {string} string "Failed to Save User"
+// @Router /users/{id} [put]
+func (handler *UserHandler) PutUser(w http.ResponseWriter, r *http.Request) {
+	userId := chi.URLParam(r, "id")
+	parsedId, err := uuid.Parse(userId)
+	if err != nil {
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+		return
+	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", parsedId.String()))
+
+	precondition, ok := userPrecondition(r)
 	if !ok {
-		http.Error(w, "User Not Found!", http.StatusNotFound)
+		tracing.Error(w, r, "Only If-None-Match: * Is Supported!", http.StatusBadRequest)
 		return
 	}
 
-	mapper.ApplyUpdateUserRequest(&user, req)
-	updatedUser, _, err := handler.store.UpdateUser(user, parsedId)
+	var req dto.UserDocument
 
+	err = json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
-		http.Error(w, "Failed to Update User!", http.StatusInternalServerError)
+		tracing.Error(w, r, "Invalid Request Body!", http.StatusBadRequest)
 		return
 	}
+	req.Email = strings.TrimSpace(req.Email)
 
+	err = validate.Struct(req)
+	if err != nil {
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := model.User{UserId: parsedId}
+	mapper.ApplyUserDocument(&user, req)
+	putUser, created, err := handler.store.PutUser(r.Context(), user, precondition)
+
+	switch {
+	case errors.Is(err, store.ErrPreconditionFailed):
+		tracing.Error(w, r, "Precondition Failed!", http.StatusPreconditionFailed)
+		return
+	case errors.Is(err, store.ErrDuplicateEmail):
+		tracing.Error(w, r, "Email Already Exists!", http.StatusConflict)
+		return
+	case errors.Is(err, store.ErrUserIdTaken):
+		tracing.Error(w, r, "User Id Taken!", http.StatusConflict)
+		return
+	case errors.Is(err, attributes.ErrInvalid), errors.Is(err, emailaddr.ErrInvalid), errors.Is(err, phonenumber.ErrInvalid):
+		tracing.Error(w, r, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		serverError(w, r, "Failed to Save User!", err)
+		return
+	}
+
+	status := http.StatusOK
 	response := map[string]interface{}{
-		"message": "User Updated successfully!",
-		"user":    updatedUser,
+		"message": "User Replaced successfully!",
+		"user":    putUser,
+	}
+	if created {
+		status = http.StatusCreated
+		response["message"] = "User created successfully!"
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("ETag", userETag(putUser))
+	w.WriteHeader(status)
 	err = json.NewEncoder(w).Encode(response)
 
 	if err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		serverError(w, r, "Failed to encode response", err)
 		return
 	}
 }
 
 // DeleteUser godoc
 // @Summary Delete a user
-// @Description Delete a user by UUID
+// @Description Delete a user by UUID. Only admins may delete users.
 // @Tags Users
 // @Produce json
+// @Security BearerAuth
 // @Param id path string true "User ID"
 // @Success 200 {object} map[string]string
 // @Failure 400 {string} string "Invalid User Id"
+// @Failure 401 {string} string "Access Token Required"
+// @Failure 403 {string} string "Admin Access Required, With a Second Factor"
 // @Failure 404 {string} string "User Not Found"
 // @Failure 500 {string} string "Failed to Delete User"
 // @Router /users/{id} [delete]
@@ -226,19 +434,20 @@ func (handler *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	userId := chi.URLParam(r, "id")
 	parsedId, err := uuid.Parse(userId)
 	if err != nil {
-		http.Error(w, "Invalid User Id!", http.StatusBadRequest)
+		tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
 		return
 	}
+	logging.AddAttrs(r.Context(), slog.String("user_id", parsedId.String()))
 
-	ok, err := handler.store.DeleteUser(parsedId)
+	ok, err := handler.store.DeleteUser(r.Context(), parsedId)
 
 	if err != nil {
-		http.Error(w, "Failed to Delete User!", http.StatusInternalServerError)
+		serverError(w, r, "Failed to Delete User!", err)
 		return
 	}
 
 	if !ok {
-		http.Error(w, "User Not Found!", http.StatusNotFound)
+		tracing.Error(w, r, "User Not Found!", http.StatusNotFound)
 		return
 	}
 
@@ -251,7 +460,71 @@ func (handler *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	err = json.NewEncoder(w).Encode(response)
 
 	if err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		serverError(w, r, "Failed to encode response", err)
 		return
 	}
 }
+
+// userETag returns the strong ETag of user.
+func userETag(user model.User) string {
+	return `"` + user.Version() + `"`
+}
+
+// userPrecondition reads the If-Match and If-None-Match headers of r. Weak
+// ETags never match, since a write needs a strong comparison. Only * is
+// supported for If-None-Match, and ok is false for anything else.
+func userPrecondition(r *http.Request) (model.Precondition, bool) {
+	var precondition model.Precondition
+	for _, header := range r.Header.Values("If-Match") {
+		for _, tag := range strings.Split(header, ",") {
+			tag = strings.TrimSpace(tag)
+			if unquoted, ok := strings.CutPrefix(tag, `"`); ok {
+				tag = strings.TrimSuffix(unquoted, `"`)
+			}
+			precondition.IfMatch = append(precondition.IfMatch, tag)
+		}
+	}
+
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		if strings.TrimSpace(header) != "*" {
+			return model.Precondition{}, false
+		}
+		precondition.IfNoneMatch = true
+	}
+	return precondition, true
+}
+
+// listAllUsers returns every user matching filter, reading them a page at a
+// time.
+func listAllUsers(ctx context.Context, userStore store.UserStoreInterface, filter model.UserFilter) ([]model.User, error) {
+	users := []model.User{}
+	after := uuid.Nil
+	for {
+		page, err := userStore.ListUsers(ctx, filter, after, userPageSize)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, page...)
+		if len(page) < userPageSize {
+			return users, nil
+		}
+		after = page[len(page)-1].UserId
+	}
+}
+
+// attributeFilter returns the attribute values named by the attr.<name>
+// parameters of query, or nil if there are none.
+func attributeFilter(query url.Values) map[string]string {
+	var filter map[string]string
+	for key, values := range query {
+		name, ok := strings.CutPrefix(key, attributeFilterPrefix)
+		if !ok || name == "" {
+			continue
+		}
+		if filter == nil {
+			filter = make(map[string]string)
+		}
+		filter[name] = values[0]
+	}
+	return filter
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/user-management/internal/events"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tenant"
+	"example.com/user-management/internal/tracing"
+	"github.com/google/uuid"
+)
+
+const (
+	eventReplayBatchSize    = 100
+	eventSubscriptionBuffer = 64
+)
+
+type UserEventHandler struct {
+	store             store.UserEventStoreInterface
+	broker            *events.Broker
+	heartbeatInterval time.Duration
+}
+
+func NewUserEventHandler(store store.UserEventStoreInterface, broker *events.Broker) *UserEventHandler {
+	return &UserEventHandler{
+		store:             store,
+		broker:            broker,
+		heartbeatInterval: 15 * time.Second,
+	}
+}
+
+type userEventFilter struct {
+	// tenantId keeps out other tenants' events, which the broker relays too.
+	tenantId uuid.UUID
+	types    map[model.EventType]bool
+	userId   uuid.UUID
+}
+
+func (f userEventFilter) matches(event model.UserEvent) bool {
+	if f.tenantId != event.User.TenantId {
+		return false
+	}
+	if len(f.types) > 0 && !f.types[event.Type] {
+		return false
+	}
+	if f.userId != uuid.Nil && f.userId != event.UserId {
+		return false
+	}
+	return true
+}
+
+// StreamUserEvents godoc
+// @Summary Stream user change events
+// @Description Server-Sent Events stream of user changes. Send Last-Event-ID to resume after a given event.
+// @Tags Users
+// @Produce text/event-stream
+// @Param type query string false "Comma separated event types (user.created, user.updated, user.deleted)"
+// @Param userId query string false "Only stream events for this user"
+// @Param Last-Event-ID header string false "Resume after this event id"
+// @Success 200 {object} model.UserEvent
+// @Failure 400 {string} string "Invalid Event Type, User Id or Last-Event-ID"
+// @Failure 500 {string} string "Streaming Unsupported"
+// @Router /users/events [get]
+func (handler *UserEventHandler) StreamUserEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		tracing.Error(w, r, "Streaming Unsupported!", http.StatusInternalServerError)
+		return
+	}
+
+	filter := userEventFilter{
+		tenantId: tenant.FromContext(r.Context()),
+		types:    map[model.EventType]bool{},
+	}
+	for _, param := range r.URL.Query()["type"] {
+		for _, t := range strings.Split(param, ",") {
+			eventType := model.EventType(strings.TrimSpace(t))
+			if !eventType.IsValid() {
+				tracing.Error(w, r, "Invalid Event Type!", http.StatusBadRequest)
+				return
+			}
+			filter.types[eventType] = true
+		}
+	}
+
+	if userId := r.URL.Query().Get("userId"); userId != "" {
+		parsedId, err := uuid.Parse(userId)
+		if err != nil {
+			tracing.Error(w, r, "Invalid User Id!", http.StatusBadRequest)
+			return
+		}
+		filter.userId = parsedId
+	}
+
+	// browsers send Last-Event-ID on reconnect; the query parameter allows
+	// resuming from clients that cannot set headers
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if lastEventId == "" {
+		lastEventId = r.URL.Query().Get("lastEventId")
+	}
+
+	var replayFrom int64 = -1
+	if lastEventId != "" {
+		parsedId, err := strconv.ParseInt(lastEventId, 10, 64)
+		if err != nil || parsedId < 0 {
+			tracing.Error(w, r, "Invalid Last-Event-ID!", http.StatusBadRequest)
+			return
+		}
+		replayFrom = parsedId
+	}
+
+	// subscribe before replaying so that nothing committed in between is lost
+	sub := handler.broker.Subscribe(eventSubscriptionBuffer)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var replayedUpTo int64
+	if replayFrom >= 0 {
+		replayedUpTo = replayFrom
+		for {
+			replayed, err := handler.store.GetUserEventsAfter(r.Context(), replayedUpTo, eventReplayBatchSize)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("failed to replay user events", logging.Err(err))
+				return
+			}
+
+			for _, event := range replayed {
+				replayedUpTo = event.EventId
+				if filter.matches(event) {
+					if err := writeUserEvent(w, event); err != nil {
+						return
+					}
+				}
+			}
+			flusher.Flush()
+
+			if len(replayed) < eventReplayBatchSize {
+				break
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(handler.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := io.WriteString(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-sub.Events():
+			if !ok {
+				// dropped for falling behind; the client resumes with Last-Event-ID
+				return
+			}
+			if event.EventId <= replayedUpTo || !filter.matches(event) {
+				continue
+			}
+			if err := writeUserEvent(w, event); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func writeUserEvent(w io.Writer, event model.UserEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.EventId, event.Type, data)
+	return err
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/events"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/tenant"
+	"github.com/google/uuid"
+)
+
+type MockUserEventStore struct {
+	GetUserEventsAfterFn func(int64, int) ([]model.UserEvent, error)
+}
+
+func (m *MockUserEventStore) GetUserEventsAfter(_ context.Context, eventId int64, limit int) ([]model.UserEvent, error) {
+	return m.GetUserEventsAfterFn(eventId, limit)
+}
+
+// unit tests for StreamUserEvents
+func TestStreamUserEvents_ReplayFromLastEventId(t *testing.T) {
+	userId := uuid.New()
+
+	mockStore := &MockUserEventStore{
+		GetUserEventsAfterFn: func(eventId int64, limit int) ([]model.UserEvent, error) {
+			if eventId != 5 {
+				t.Fatalf("expected replay after event 5, got %d", eventId)
+			}
+			return []model.UserEvent{
+				{EventId: 6, Type: model.EventUserCreated, UserId: userId},
+				{EventId: 7, Type: model.EventUserUpdated, UserId: userId},
+				{EventId: 8, Type: model.EventUserUpdated, UserId: uuid.New()},
+			}, nil
+		},
+	}
+
+	handler := NewUserEventHandler(mockStore, events.NewBroker())
+
+	// a cancelled request context ends the stream once the replay is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/users/events?type=user.updated&userId="+userId.String(), nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "5")
+	w := httptest.NewRecorder()
+
+	handler.StreamUserEvents(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %s", ct)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, "id: 7\nevent: user.updated\n") {
+		t.Fatalf("expected event 7 in stream, got %q", body)
+	}
+	if strings.Contains(body, "id: 6\n") || strings.Contains(body, "id: 8\n") {
+		t.Fatalf("expected filtered events to be skipped, got %q", body)
+	}
+}
+
+func TestStreamUserEvents_LiveEvent(t *testing.T) {
+	broker := events.NewBroker()
+	handler := NewUserEventHandler(&MockUserEventStore{}, broker)
+
+	server := httptest.NewServer(http.HandlerFunc(handler.StreamUserEvents))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?type=user.deleted")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+
+	broker.Publish(model.UserEvent{EventId: 1, Type: model.EventUserCreated, UserId: uuid.New()})
+	broker.Publish(model.UserEvent{EventId: 2, Type: model.EventUserDeleted, UserId: uuid.New()})
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	select {
+	case line := <-lines:
+		if line != "id: 2" {
+			t.Fatalf("expected first line to be id: 2, got %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestStreamUserEvents_OtherTenantsSkipped(t *testing.T) {
+	broker := events.NewBroker()
+	handler := NewUserEventHandler(&MockUserEventStore{}, broker)
+	tenantId := uuid.New()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handler.StreamUserEvents(w, r.WithContext(tenant.WithID(r.Context(), tenantId)))
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	broker.Publish(model.UserEvent{EventId: 1, Type: model.EventUserCreated, UserId: uuid.New()})
+	broker.Publish(model.UserEvent{EventId: 2, Type: model.EventUserCreated, UserId: uuid.New(), User: model.User{TenantId: tenantId}})
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	select {
+	case line := <-lines:
+		if line != "id: 2" {
+			t.Fatalf("expected first line to be id: 2, got %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestStreamUserEvents_Heartbeat(t *testing.T) {
+	handler := NewUserEventHandler(&MockUserEventStore{}, events.NewBroker())
+	handler.heartbeatInterval = 10 * time.Millisecond
+
+	server := httptest.NewServer(http.HandlerFunc(handler.StreamUserEvents))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != ": heartbeat\n" {
+		t.Fatalf("expected heartbeat, got %q", line)
+	}
+}
+
+func TestStreamUserEvents_InvalidType(t *testing.T) {
+	handler := NewUserEventHandler(&MockUserEventStore{}, events.NewBroker())
+
+	req := httptest.NewRequest(http.MethodGet, "/users/events?type=user.renamed", nil)
+	w := httptest.NewRecorder()
+
+	handler.StreamUserEvents(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestStreamUserEvents_InvalidLastEventId(t *testing.T) {
+	handler := NewUserEventHandler(&MockUserEventStore{}, events.NewBroker())
+
+	req := httptest.NewRequest(http.MethodGet, "/users/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	w := httptest.NewRecorder()
+
+	handler.StreamUserEvents(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/mapper"
+	"example.com/user-management/internal/model"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	jsonType = "application/json"
+	// mergePatchType patches are partial documents, in which null removes a
+	// field (RFC 7396).
+	mergePatchType = "application/merge-patch+json"
+	// jsonPatchType patches are lists of operations, which test operations
+	// can make conditional (RFC 6902).
+	jsonPatchType = "application/json-patch+json"
+)
+
+// acceptPatch lists the media types PATCH /users/{id} accepts, for the
+// Accept-Patch header (RFC 5789).
+const acceptPatch = jsonType + ", " + mergePatchType + ", " + jsonPatchType
+
+var (
+	errInvalidPatch      = errors.New("invalid patch")
+	errPatchTestFailed   = errors.New("patch test failed")
+	errPatchNotApplied   = errors.New("patch cannot be applied")
+	errInvalidPatchedDoc = errors.New("patched user is invalid")
+)
+
+// userPatch applies a patch to a user's document.
+type userPatch func(doc []byte) ([]byte, error)
+
+// patchMediaType returns the media type of the request body, which is JSON if
+// it is not given.
+func patchMediaType(r *http.Request) (string, error) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return jsonType, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", err
+	}
+	switch mediaType {
+	case jsonType, mergePatchType, jsonPatchType:
+		return mediaType, nil
+	default:
+		return "", fmt.Errorf("unsupported media type %q", mediaType)
+	}
+}
+
+// readUserPatch reads a patch of mediaType from body, turning away patches
+// that are not well formed before the user is looked up.
+func readUserPatch(body io.Reader, mediaType string) (userPatch, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, errInvalidPatch
+	}
+
+	if mediaType == mergePatchType {
+		// a patch that is not an object would replace the whole user
+		if !json.Valid(data) || !bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+			return nil, errInvalidPatch
+		}
+		return func(doc []byte) ([]byte, error) {
+			return jsonpatch.MergePatch(doc, data)
+		}, nil
+	}
+
+	patch, err := jsonpatch.DecodePatch(data)
+	if err != nil {
+		return nil, errInvalidPatch
+	}
+	return patch.Apply, nil
+}
+
+// applyUserPatch patches the document of user and, if the result is a valid
+// user, sets user's fields from it.
+func applyUserPatch(user *model.User, patch userPatch) error {
+	doc, err := json.Marshal(mapper.UserToDocument(*user))
+	if err != nil {
+		return err
+	}
+
+	patched, err := patch(doc)
+	if errors.Is(err, jsonpatch.ErrTestFailed) {
+		return fmt.Errorf("%w: %w", errPatchTestFailed, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", errPatchNotApplied, err)
+	}
+
+	// fields outside the document, such as the user's id, cannot be patched
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	var result dto.UserDocument
+	if err := decoder.Decode(&result); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidPatchedDoc, err)
+	}
+	result.Email = strings.TrimSpace(result.Email)
+	if err := validate.Struct(result); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidPatchedDoc, err)
+	}
+
+	mapper.ApplyUserDocument(user, result)
+	return nil
+}
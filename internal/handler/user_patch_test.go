@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"example.com/user-management/internal/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// patchUser sends a PATCH of contentType to a user with an age and an
+// attribute, and returns the response and the user as it was saved.
+func patchUser(t *testing.T, contentType, body string) (*httptest.ResponseRecorder, model.User) {
+	t.Helper()
+
+	id := uuid.New()
+	var saved model.User
+	mockStore := &MockUserStore{
+		GetUserByIdFn: func(uuid.UUID) (model.User, bool, error) {
+			return model.User{
+				UserId:     id,
+				FirstName:  "John",
+				LastName:   "Doe",
+				Email:      "john@gmail.com",
+				Phone:      "+94712345678",
+				Age:        27,
+				Status:     model.StatusActive,
+				Attributes: map[string]any{"department": "eng"},
+			}, true, nil
+		},
+		UpdateUserFn: func(u model.User, _ uuid.UUID) (model.User, bool, error) {
+			saved = u
+			return u, true, nil
+		},
+	}
+
+	r := chi.NewRouter()
+	r.Patch("/users/{id}", NewUserHandler(mockStore).UpdateUser)
+
+	req := httptest.NewRequest(http.MethodPatch, "/users/"+id.String(), bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w, saved
+}
+
+func TestUpdateUser_MergePatch(t *testing.T) {
+	w, saved := patchUser(t, "application/merge-patch+json",
+		`{"firstName":"Jane","age":null,"attributes":{"department":null,"team":"core"}}`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if saved.FirstName != "Jane" || saved.LastName != "Doe" {
+		t.Errorf("expected only the first name to change, got %s %s", saved.FirstName, saved.LastName)
+	}
+	if saved.Age != 0 {
+		t.Errorf("expected null to clear the age, got %d", saved.Age)
+	}
+	if want := map[string]any{"team": "core"}; !reflect.DeepEqual(saved.Attributes, want) {
+		t.Errorf("expected attributes %v, got %v", want, saved.Attributes)
+	}
+}
+
+func TestUpdateUser_JSONPatch(t *testing.T) {
+	w, saved := patchUser(t, "application/json-patch+json", `[
+		{"op":"test","path":"/email","value":"john@gmail.com"},
+		{"op":"replace","path":"/email","value":"jane@gmail.com"},
+		{"op":"remove","path":"/age"},
+		{"op":"add","path":"/attributes/team","value":"core"}
+	]`)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body)
+	}
+	if saved.Email != "jane@gmail.com" || saved.Age != 0 {
+		t.Errorf("expected the new email and no age, got %s and %d", saved.Email, saved.Age)
+	}
+	if want := map[string]any{"department": "eng", "team": "core"}; !reflect.DeepEqual(saved.Attributes, want) {
+		t.Errorf("expected attributes %v, got %v", want, saved.Attributes)
+	}
+}
+
+func TestUpdateUser_PatchErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        int
+	}{
+		{"failed test", "application/json-patch+json", `[{"op":"test","path":"/age","value":28},{"op":"replace","path":"/age","value":29}]`, http.StatusConflict},
+		{"malformed JSON patch", "application/json-patch+json", `{"op":"remove"}`, http.StatusBadRequest},
+		{"missing path", "application/json-patch+json", `[{"op":"replace","path":"/nickname","value":"JD"}]`, http.StatusBadRequest},
+		{"unknown field", "application/merge-patch+json", `{"userId":"00000000-0000-0000-0000-000000000000"}`, http.StatusBadRequest},
+		{"cleared required field", "application/merge-patch+json", `{"email":null}`, http.StatusBadRequest},
+		{"invalid value", "application/merge-patch+json", `{"phone":"+94 71 234 5678 ext. 123456789012345"}`, http.StatusBadRequest},
+		{"wrong type", "application/merge-patch+json", `{"age":"old"}`, http.StatusBadRequest},
+		{"non-object merge patch", "application/merge-patch+json", `null`, http.StatusBadRequest},
+		{"unsupported content type", "text/plain", `firstName=Jane`, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, saved := patchUser(t, tt.contentType, tt.body)
+			if w.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, w.Code, w.Body)
+			}
+			if saved.UserId != uuid.Nil {
+				t.Errorf("expected the user not to be saved")
+			}
+		})
+	}
+}
+
+func TestUpdateUser_UnsupportedContentTypeListsPatchTypes(t *testing.T) {
+	w, _ := patchUser(t, "application/xml", `<user/>`)
+
+	if got := w.Header().Get("Accept-Patch"); got != acceptPatch {
+		t.Errorf("expected Accept-Patch %q, got %q", acceptPatch, got)
+	}
+}
+
+func TestUpdateUser_IfMatch(t *testing.T) {
+	user := model.User{
+		UserId:    uuid.New(),
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john@gmail.com",
+		Phone:     "+94712345678",
+		Status:    model.StatusActive,
+	}
+	mockStore := &MockUserStore{
+		GetUserByIdFn: func(uuid.UUID) (model.User, bool, error) {
+			return user, true, nil
+		},
+		UpdateUserFn: func(u model.User, _ uuid.UUID) (model.User, bool, error) {
+			return u, true, nil
+		},
+	}
+	r := chi.NewRouter()
+	r.Patch("/users/{id}", NewUserHandler(mockStore).UpdateUser)
+
+	tests := []struct {
+		name    string
+		ifMatch string
+		want    int
+	}{
+		{"stale", `"stale"`, http.StatusPreconditionFailed},
+		{"current", userETag(user), http.StatusOK},
+		{"any", "*", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/users/"+user.UserId.String(), bytes.NewBufferString(`{"firstName":"Jane"}`))
+			req.Header.Set("If-Match", tt.ifMatch)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, w.Code, w.Body)
+			}
+			if tt.want == http.StatusOK && w.Header().Get("ETag") == "" {
+				t.Errorf("expected the new ETag")
+			}
+		})
+	}
+}
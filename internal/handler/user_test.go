@@ -2,36 +2,73 @@ package handler
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 
+	"example.com/user-management/internal/attributes"
+	"example.com/user-management/internal/emailaddr"
 	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/store"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
 type MockUserStore struct {
-	CreateUserFn  func(model.User) (model.User, error)
-	GetAllUsersFn func() ([]model.User, error)
-	GetUserByIdFn func(uuid.UUID) (model.User, bool, error)
-	UpdateUserFn  func(model.User, uuid.UUID) (model.User, bool, error)
-	DeleteUserFn  func(uuid.UUID) (bool, error)
+	CreateUserFn    func(model.User) (model.User, error)
+	GetAllUsersFn   func() ([]model.User, error)
+	GetUserByIdFn   func(uuid.UUID) (model.User, bool, error)
+	GetUsersByIdsFn func([]uuid.UUID) ([]model.User, error)
+	ListUsersFn     func(model.UserFilter, uuid.UUID, int) ([]model.User, error)
+	UpdateUserFn    func(model.User, uuid.UUID) (model.User, bool, error)
+	PutUserFn       func(model.User, model.Precondition) (model.User, bool, error)
+	DeleteUserFn    func(uuid.UUID) (bool, error)
 }
 
-func (m *MockUserStore) CreateUser(u model.User) (model.User, error) {
+func (m *MockUserStore) CreateUser(_ context.Context, u model.User) (model.User, error) {
 	return m.CreateUserFn(u)
 }
-func (m *MockUserStore) GetAllUsers() ([]model.User, error) {
+func (m *MockUserStore) GetAllUsers(_ context.Context) ([]model.User, error) {
 	return m.GetAllUsersFn()
 }
-func (m *MockUserStore) GetUserById(id uuid.UUID) (model.User, bool, error) {
+func (m *MockUserStore) GetUserById(_ context.Context, id uuid.UUID) (model.User, bool, error) {
 	return m.GetUserByIdFn(id)
 }
-func (m *MockUserStore) UpdateUser(u model.User, id uuid.UUID) (model.User, bool, error) {
+func (m *MockUserStore) GetUsersByIds(_ context.Context, ids []uuid.UUID) ([]model.User, error) {
+	return m.GetUsersByIdsFn(ids)
+}
+func (m *MockUserStore) ListUsers(_ context.Context, f model.UserFilter, after uuid.UUID, limit int) ([]model.User, error) {
+	return m.ListUsersFn(f, after, limit)
+}
+func (m *MockUserStore) UpdateUser(_ context.Context, u model.User, id uuid.UUID) (model.User, bool, error) {
+	return m.UpdateUserFn(u, id)
+}
+func (m *MockUserStore) PutUser(_ context.Context, u model.User, p model.Precondition) (model.User, bool, error) {
+	return m.PutUserFn(u, p)
+}
+
+// ModifyUser reads the user with GetUserByIdFn and writes it with
+// UpdateUserFn, as the stores do under a lock.
+func (m *MockUserStore) ModifyUser(_ context.Context, id uuid.UUID, p model.Precondition, modify func(*model.User) error) (model.User, bool, error) {
+	u, ok, err := m.GetUserByIdFn(id)
+	if err != nil || !ok {
+		return model.User{}, ok, err
+	}
+	if !p.Holds(u, true) {
+		return model.User{}, false, store.ErrPreconditionFailed
+	}
+	if err := modify(&u); err != nil {
+		return model.User{}, false, err
+	}
 	return m.UpdateUserFn(u, id)
 }
-func (m *MockUserStore) DeleteUser(id uuid.UUID) (bool, error) {
+func (m *MockUserStore) DeleteUser(_ context.Context, id uuid.UUID) (bool, error) {
 	return m.DeleteUserFn(id)
 }
 
@@ -65,6 +102,83 @@ func TestCreateUser_Success(t *testing.T) {
 	}
 }
 
+func TestCreateUser_TrimsEmail(t *testing.T) {
+	var saved model.User
+	mockUserStore := &MockUserStore{
+		CreateUserFn: func(user model.User) (model.User, error) {
+			saved = user
+			return user, nil
+		},
+	}
+
+	body := `{"firstName":"John","lastName":"Doe","email":" John@Gmail.com\n","phone":"+94712345678"}`
+	req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	NewUserHandler(mockUserStore).CreateUser(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", w.Code, w.Body)
+	}
+	if saved.Email != "John@Gmail.com" {
+		t.Errorf("expected the address without whitespace, got %q", saved.Email)
+	}
+}
+
+func TestCreateUser_InvalidEmail(t *testing.T) {
+	mockUserStore := &MockUserStore{
+		CreateUserFn: func(model.User) (model.User, error) {
+			return model.User{}, fmt.Errorf("%w: bad domain", emailaddr.ErrInvalid)
+		},
+	}
+
+	body := `{"firstName":"John","lastName":"Doe","email":"john@example.com","phone":"+94712345678"}`
+	req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	NewUserHandler(mockUserStore).CreateUser(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestCreateUser_InvalidPhone(t *testing.T) {
+	mockUserStore := &MockUserStore{
+		CreateUserFn: func(model.User) (model.User, error) {
+			return model.User{}, fmt.Errorf("%w \"071 234 5678\": invalid country code", phonenumber.ErrInvalid)
+		},
+	}
+
+	body := `{"firstName":"John","lastName":"Doe","email":"john@example.com","phone":"071 234 5678"}`
+	req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	NewUserHandler(mockUserStore).CreateUser(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestCreateUser_DuplicateEmail(t *testing.T) {
+	mockUserStore := &MockUserStore{
+		CreateUserFn: func(model.User) (model.User, error) {
+			return model.User{}, store.ErrDuplicateEmail
+		},
+	}
+
+	body := `{"firstName":"John","lastName":"Doe","email":"john@example.com","phone":"+94712345678"}`
+	req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	NewUserHandler(mockUserStore).CreateUser(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d", w.Code)
+	}
+}
+
 func TestCreateUser_ValidationError(t *testing.T) {
 	handler := NewUserHandler(&MockUserStore{})
 
@@ -84,6 +198,41 @@ func TestCreateUser_ValidationError(t *testing.T) {
 	}
 }
 
+func TestCreateUser_InvalidAttributes(t *testing.T) {
+	mockUserStore := &MockUserStore{
+		CreateUserFn: func(user model.User) (model.User, error) {
+			if user.Attributes["department"] != "eng" {
+				t.Errorf("expected the attributes to reach the store, got %v", user.Attributes)
+			}
+			return model.User{}, fmt.Errorf("%w: /department: value must be one of 'sales'", attributes.ErrInvalid)
+		},
+	}
+
+	userHandler := NewUserHandler(mockUserStore)
+
+	body := `{
+		"firstName":"John",
+		"lastName":"Doe",
+		"email":"john@gmail.com",
+		"phone":"+94712345678",
+		"age": 27,
+		"status": "Active",
+		"attributes": {"department": "eng"}
+	}`
+
+	req := httptest.NewRequest(http.MethodPost, "/users", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	userHandler.CreateUser(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "/department") {
+		t.Errorf("expected the error to name the attribute, got %q", w.Body)
+	}
+}
+
 // unit tests for GetAllUsers
 func TestGetAllUsers_Success(t *testing.T) {
 	mockUserStore := &MockUserStore{
@@ -113,6 +262,67 @@ func TestGetAllUsers_Success(t *testing.T) {
 	}
 }
 
+func TestGetAllUsers_GroupReadsEveryPage(t *testing.T) {
+	groupId := uuid.New()
+	var calls int
+	mockUserStore := &MockUserStore{
+		ListUsersFn: func(filter model.UserFilter, after uuid.UUID, limit int) ([]model.User, error) {
+			calls++
+			if filter.GroupId != groupId {
+				t.Errorf("expected group filter %s, got %s", groupId, filter.GroupId)
+			}
+			if calls == 1 {
+				if after != uuid.Nil {
+					t.Errorf("expected the first page to start at the beginning, got %s", after)
+				}
+				return make([]model.User, limit), nil
+			}
+			return []model.User{{UserId: uuid.New()}}, nil
+		},
+	}
+
+	userHandler := NewUserHandler(mockUserStore)
+
+	req := httptest.NewRequest(http.MethodGet, "/users?group="+groupId.String(), nil)
+	w := httptest.NewRecorder()
+
+	userHandler.GetAllUsers(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	var users []model.User
+	if err := json.NewDecoder(w.Body).Decode(&users); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if calls != 2 || len(users) != userPageSize+1 {
+		t.Errorf("expected %d users from 2 pages, got %d from %d", userPageSize+1, len(users), calls)
+	}
+}
+
+func TestGetAllUsers_AttributeFilter(t *testing.T) {
+	mockUserStore := &MockUserStore{
+		ListUsersFn: func(filter model.UserFilter, after uuid.UUID, limit int) ([]model.User, error) {
+			expected := map[string]string{"department": "eng", "level": "3"}
+			if !reflect.DeepEqual(filter.Attributes, expected) {
+				t.Errorf("expected attribute filter %v, got %v", expected, filter.Attributes)
+			}
+			return []model.User{{UserId: uuid.New()}}, nil
+		},
+	}
+
+	userHandler := NewUserHandler(mockUserStore)
+
+	req := httptest.NewRequest(http.MethodGet, "/users?attr.department=eng&attr.level=3&attr.=x", nil)
+	w := httptest.NewRecorder()
+
+	userHandler.GetAllUsers(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+}
+
 // unit tests for GetUserById
 func TestGetUserById_Success(t *testing.T) {
 	id := uuid.New()
@@ -144,6 +354,9 @@ func TestGetUserById_Success(t *testing.T) {
 	if w.Code != http.StatusOK {
 		t.Fatalf("expected 200, got %d", w.Code)
 	}
+	if w.Header().Get("ETag") == "" {
+		t.Errorf("expected an ETag")
+	}
 }
 
 func TestGetUserById_InvalidUUID(t *testing.T) {
@@ -266,6 +479,115 @@ func TestUpdateUser_NotFound(t *testing.T) {
 	}
 }
 
+// unit tests for PutUser
+func putUser(t *testing.T, mockStore *MockUserStore, id string, headers map[string]string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := chi.NewRouter()
+	r.Put("/users/{id}", NewUserHandler(mockStore).PutUser)
+
+	req := httptest.NewRequest(http.MethodPut, "/users/"+id, bytes.NewBufferString(body))
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestPutUser_CreatedAndReplaced(t *testing.T) {
+	id := uuid.New()
+	body := `{"firstName":"John","lastName":"Doe","email":"john@gmail.com","phone":"+94712345678","status":"Active"}`
+
+	for _, created := range []bool{true, false} {
+		var saved model.User
+		mockStore := &MockUserStore{
+			PutUserFn: func(u model.User, _ model.Precondition) (model.User, bool, error) {
+				saved = u
+				return u, created, nil
+			},
+		}
+
+		w := putUser(t, mockStore, id.String(), nil, body)
+
+		want := http.StatusOK
+		if created {
+			want = http.StatusCreated
+		}
+		if w.Code != want {
+			t.Fatalf("expected %d, got %d: %s", want, w.Code, w.Body)
+		}
+		if saved.UserId != id || saved.Age != 0 {
+			t.Errorf("expected the user with the path's id and no age, got %+v", saved)
+		}
+		if got := w.Header().Get("ETag"); got != userETag(saved) {
+			t.Errorf("expected ETag %s, got %s", userETag(saved), got)
+		}
+	}
+}
+
+func TestPutUser_Preconditions(t *testing.T) {
+	var got model.Precondition
+	mockStore := &MockUserStore{
+		PutUserFn: func(u model.User, p model.Precondition) (model.User, bool, error) {
+			got = p
+			return model.User{}, false, store.ErrPreconditionFailed
+		},
+	}
+	body := `{"firstName":"John","lastName":"Doe","email":"john@gmail.com","phone":"+94712345678","status":"Active"}`
+
+	w := putUser(t, mockStore, uuid.New().String(), map[string]string{
+		"If-Match":      `"abc", W/"def"`,
+		"If-None-Match": "*",
+	}, body)
+
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412, got %d: %s", w.Code, w.Body)
+	}
+	want := model.Precondition{IfMatch: []string{"abc", `W/"def"`}, IfNoneMatch: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected precondition %+v, got %+v", want, got)
+	}
+}
+
+func TestPutUser_BadRequest(t *testing.T) {
+	valid := `{"firstName":"John","lastName":"Doe","email":"john@gmail.com","phone":"+94712345678","status":"Active"}`
+
+	tests := []struct {
+		name    string
+		id      string
+		headers map[string]string
+		body    string
+	}{
+		{"invalid id", "invalid", nil, valid},
+		{"If-None-Match with an ETag", uuid.New().String(), map[string]string{"If-None-Match": `"abc"`}, valid},
+		{"missing required field", uuid.New().String(), nil, `{"firstName":"John"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := putUser(t, &MockUserStore{}, tt.id, tt.headers, tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400, got %d: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestPutUser_Conflict(t *testing.T) {
+	for _, err := range []error{store.ErrDuplicateEmail, store.ErrUserIdTaken} {
+		mockStore := &MockUserStore{
+			PutUserFn: func(model.User, model.Precondition) (model.User, bool, error) {
+				return model.User{}, false, err
+			},
+		}
+		body := `{"firstName":"John","lastName":"Doe","email":"john@gmail.com","phone":"+94712345678","status":"Active"}`
+
+		if w := putUser(t, mockStore, uuid.New().String(), nil, body); w.Code != http.StatusConflict {
+			t.Errorf("expected 409 for %v, got %d", err, w.Code)
+		}
+	}
+}
+
 // unit tests for DeleteUser
 func TestDeleteUser_Success(t *testing.T) {
 	id := uuid.New()
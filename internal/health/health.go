@@ -0,0 +1,135 @@
+// Package health serves the liveness and readiness probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	statusOK          = "ok"
+	statusFailing     = "failing"
+	statusUnavailable = "unavailable"
+)
+
+// Check reports whether a dependency is usable. It must give up once ctx is
+// done.
+type Check func(ctx context.Context) error
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the readiness checks. A draining checker reports the server
+// as not ready whatever its checks say, so that load balancers stop sending
+// it traffic before it shuts down.
+type Checker struct {
+	timeout  time.Duration
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+// NewChecker returns a checker giving each check at most timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers check under name. Checks must be added before the checker
+// serves requests.
+func (checker *Checker) Add(name string, check Check) {
+	checker.checks = append(checker.checks, namedCheck{name: name, check: check})
+}
+
+// Drain marks the server as shutting down.
+func (checker *Checker) Drain() {
+	checker.draining.Store(true)
+}
+
+type checkResult struct {
+	Status     string  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"durationMs"`
+}
+
+type readiness struct {
+	Status   string                 `json:"status"`
+	Draining bool                   `json:"draining"`
+	Checks   map[string]checkResult `json:"checks"`
+}
+
+// Live answers the liveness probe. It only shows that the process can serve
+// HTTP, so that a failing dependency never gets the process restarted.
+func (checker *Checker) Live(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": statusOK})
+}
+
+// Ready answers the readiness probe, running every check concurrently and
+// reporting each one. It answers 503 while draining or if any check fails.
+func (checker *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	result := readiness{
+		Status:   statusOK,
+		Draining: checker.draining.Load(),
+		Checks:   checker.run(r.Context()),
+	}
+
+	if result.Draining {
+		result.Status = statusUnavailable
+	}
+	for _, check := range result.Checks {
+		if check.Status != statusOK {
+			result.Status = statusUnavailable
+		}
+	}
+
+	code := http.StatusOK
+	if result.Status != statusOK {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, result)
+}
+
+func (checker *Checker) run(ctx context.Context) map[string]checkResult {
+	results := make(map[string]checkResult, len(checker.checks))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, named := range checker.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, checker.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := named.check(checkCtx)
+			result := checkResult{
+				Status:     statusOK,
+				DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				result.Status = statusFailing
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			results[named.name] = result
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func ready(t *testing.T, checker *Checker) (int, readiness) {
+	t.Helper()
+
+	w := httptest.NewRecorder()
+	checker.Ready(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var body readiness
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return w.Code, body
+}
+
+func TestLive(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(context.Context) error { return errors.New("down") })
+
+	w := httptest.NewRecorder()
+	checker.Live(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 whatever the checks say, got %d", w.Code)
+	}
+}
+
+func TestReady_AllChecksPass(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(context.Context) error { return nil })
+
+	code, body := ready(t, checker)
+	if code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", code)
+	}
+	if body.Status != statusOK || body.Checks["database"].Status != statusOK {
+		t.Errorf("Expected everything ok, got %+v", body)
+	}
+}
+
+func TestReady_ReportsFailingCheck(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("database", func(context.Context) error { return nil })
+	checker.Add("migrations", func(context.Context) error { return errors.New("migrations pending") })
+
+	code, body := ready(t, checker)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", code)
+	}
+	if body.Checks["database"].Status != statusOK {
+		t.Errorf("Expected database ok, got %+v", body.Checks["database"])
+	}
+	if got := body.Checks["migrations"]; got.Status != statusFailing || got.Error != "migrations pending" {
+		t.Errorf("Expected migrations to fail with its error, got %+v", got)
+	}
+}
+
+func TestReady_TimesOutSlowCheck(t *testing.T) {
+	checker := NewChecker(10 * time.Millisecond)
+	checker.Add("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	code, body := ready(t, checker)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", code)
+	}
+	if body.Checks["database"].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("Expected the check to time out, got %+v", body.Checks["database"])
+	}
+}
+
+func TestReady_Draining(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Drain()
+
+	code, body := ready(t, checker)
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503 while draining, got %d", code)
+	}
+	if !body.Draining || body.Status != statusUnavailable {
+		t.Errorf("Expected a draining, unavailable report, got %+v", body)
+	}
+}
@@ -11,13 +11,21 @@ import (
 	"os"
 	"testing"
 
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/emailaddr"
 	"example.com/user-management/internal/handler"
+	"example.com/user-management/internal/mail"
 	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tenant"
 	"example.com/user-management/internal/testutils"
+	"example.com/user-management/internal/token"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 var dbConn *sql.DB
+var dsn string
 var userStore *store.UserStore
 
 func TestMain(m *testing.M) {
@@ -27,23 +35,9 @@ func TestMain(m *testing.M) {
 	defer env.Terminate()
 
 	dbConn = env.DB
+	dsn = env.DSN
 
-	schema := `
-	CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
-
-	CREATE TABLE IF NOT EXISTS users (
-		user_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-		first_name TEXT NOT NULL,
-		last_name TEXT NOT NULL,
-		email TEXT NOT NULL UNIQUE,
-		phone TEXT NOT NULL,
-		age INT,
-		status TEXT NOT NULL DEFAULT 'Active' CHECK (status IN ('Active','Inactive')),
-		created_at TIMESTAMP NOT NULL DEFAULT now()
-	);
-	`
-
-	if _, err := dbConn.Exec(schema); err != nil {
+	if _, err := db.MigrateUp(dbConn, emailaddr.Rules{}); err != nil {
 		log.Fatal(err)
 	}
 
@@ -147,3 +141,63 @@ func TestUserEndpoints(t *testing.T) {
 		t.Fatalf("expected 404 for deleted user, got %d", rr.Code)
 	}
 }
+
+func TestTenantIsolation(t *testing.T) {
+	authenticator := auth.NewAuthenticator(userStore, mail.NewMemorySender(), token.NewSigner([]byte("secret")), auth.Options{})
+	router := chi.NewRouter()
+	router.Use(handler.NewTenantResolver(authenticator, true).Middleware)
+	router.Mount("/", setupRouter())
+
+	tenantA, tenantB := uuid.NewString(), uuid.NewString()
+	send := func(method, target, tenantId string, body any) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, target, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(tenant.Header, tenantId)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	userReq := map[string]any{
+		"firstName": "Carol",
+		"lastName":  "Jones",
+		"email":     "carol@example.com",
+		"phone":     "+94771234567",
+	}
+	rr := send(http.MethodPost, "/users", tenantA, userReq)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d, body: %s", rr.Code, rr.Body.String())
+	}
+	var createResp map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &createResp); err != nil {
+		t.Fatal(err)
+	}
+	userID := createResp["user"].(map[string]any)["UserId"].(string)
+
+	if rr := send(http.MethodGet, "/users/"+userID, tenantA, nil); rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 in the user's tenant, got %d", rr.Code)
+	}
+	if rr := send(http.MethodGet, "/users/"+userID, tenantB, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 in another tenant, got %d", rr.Code)
+	}
+	if rr := send(http.MethodDelete, "/users/"+userID, tenantB, nil); rr.Code != http.StatusNotFound {
+		t.Errorf("expected status 404 deleting from another tenant, got %d", rr.Code)
+	}
+
+	rr = send(http.MethodGet, "/users", tenantB, nil)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	if bytes.Contains(rr.Body.Bytes(), []byte(userID)) {
+		t.Errorf("expected another tenant's listing to leave the user out, got %s", rr.Body.String())
+	}
+
+	// the address is only taken in tenant A
+	if rr := send(http.MethodPost, "/users", tenantB, userReq); rr.Code != http.StatusCreated {
+		t.Errorf("expected status 201 for the same email in another tenant, got %d", rr.Code)
+	}
+	if rr := send(http.MethodPost, "/users", tenantA, userReq); rr.Code == http.StatusCreated {
+		t.Errorf("expected a duplicate email in the same tenant to be refused")
+	}
+}
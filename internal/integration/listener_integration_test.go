@@ -0,0 +1,108 @@
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/events"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+// listenerApplication names the listener's connection, so that tests can cut
+// it.
+const listenerApplication = "user_event_listener_test"
+
+func startListener(t *testing.T) (*events.PostgresListener, *events.Subscription) {
+	t.Helper()
+	broker := events.NewBroker()
+	sub := broker.Subscribe(100)
+	t.Cleanup(sub.Close)
+
+	listener, err := events.NewPostgresListener(dsn+" application_name="+listenerApplication, broker, store.NewUserEventStore(dbConn))
+	if err != nil {
+		t.Fatalf("NewPostgresListener failed: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	listener.Start()
+
+	return listener, sub
+}
+
+func createUsers(t *testing.T, n int) []model.User {
+	t.Helper()
+	users := make([]model.User, n)
+	for i := range users {
+		user, err := userStore.CreateUser(t.Context(), model.User{
+			FirstName: "Alice",
+			LastName:  "Smith",
+			Email:     fmt.Sprintf("alice.%s@example.com", uuid.NewString()),
+		})
+		if err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		users[i] = user
+	}
+	return users
+}
+
+// expectCreated reads an event for the creation of each of users, in order.
+func expectCreated(t *testing.T, sub *events.Subscription, users []model.User) {
+	t.Helper()
+	var lastEventId int64
+	for _, user := range users {
+		select {
+		case event := <-sub.Events():
+			if event.Type != model.EventUserCreated || event.UserId != user.UserId {
+				t.Fatalf("Expected the creation of %s, got %s of %s", user.UserId, event.Type, event.UserId)
+			}
+			if event.EventId <= lastEventId {
+				t.Fatalf("Expected event ids to increase, got %d after %d", event.EventId, lastEventId)
+			}
+			lastEventId = event.EventId
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Timed out waiting for the creation of %s", user.UserId)
+		}
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool, what string) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPostgresListener_RelaysNotifications(t *testing.T) {
+	listener, sub := startListener(t)
+	waitFor(t, func() bool { return listener.Check(t.Context()) == nil }, "the listener to connect")
+
+	users := createUsers(t, 3)
+
+	expectCreated(t, sub, users)
+}
+
+func TestPostgresListener_CatchesUpAfterReconnect(t *testing.T) {
+	listener, sub := startListener(t)
+	waitFor(t, func() bool { return listener.Check(t.Context()) == nil }, "the listener to connect")
+
+	if _, err := dbConn.ExecContext(t.Context(),
+		"SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE application_name = $1",
+		listenerApplication,
+	); err != nil {
+		t.Fatalf("pg_terminate_backend failed: %v", err)
+	}
+	waitFor(t, func() bool { return listener.Check(t.Context()) != nil }, "the listener to disconnect")
+
+	// notifications of these are sent while nobody listens
+	users := createUsers(t, 3)
+
+	waitFor(t, func() bool { return listener.Check(t.Context()) == nil }, "the listener to reconnect")
+	expectCreated(t, sub, users)
+}
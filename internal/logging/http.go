@@ -0,0 +1,99 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"example.com/user-management/internal/tracing"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+type contextKey struct{}
+
+// requestLogger is shared by everything serving one request, so that
+// attributes added by a handler also end up on the access log line.
+type requestLogger struct {
+	mu     sync.Mutex
+	logger *slog.Logger
+}
+
+// FromContext returns the logger of the request ctx belongs to, which tags
+// every line with the request id, trace id and route. Outside a request it
+// returns the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	requestLog, ok := ctx.Value(contextKey{}).(*requestLogger)
+	if !ok {
+		return slog.Default()
+	}
+
+	requestLog.mu.Lock()
+	logger := requestLog.logger
+	requestLog.mu.Unlock()
+
+	if route := routePattern(ctx); route != "" {
+		logger = logger.With(slog.String("route", route))
+	}
+	return logger
+}
+
+// AddAttrs adds args, given as for slog.Logger.With, to every later line
+// logged for the request ctx belongs to. Outside a request it does nothing.
+func AddAttrs(ctx context.Context, args ...any) {
+	requestLog, ok := ctx.Value(contextKey{}).(*requestLogger)
+	if !ok {
+		return
+	}
+
+	requestLog.mu.Lock()
+	defer requestLog.mu.Unlock()
+	requestLog.logger = requestLog.logger.With(args...)
+}
+
+// Middleware puts a request logger in the context of every request and logs
+// one line once the request is served, with its route, status and latency.
+// Server errors are logged at error level. It must run after chi's RequestID
+// and the tracing middleware so that their ids are known.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		logger := slog.Default().With(slog.String("request_id", middleware.GetReqID(r.Context())))
+		if traceId := tracing.TraceId(r.Context()); traceId != "" {
+			logger = logger.With(slog.String("trace_id", traceId))
+		}
+
+		ctx := context.WithValue(r.Context(), contextKey{}, &requestLogger{logger: logger})
+		r = r.WithContext(ctx)
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+
+		FromContext(ctx).LogAttrs(ctx, level, "request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Int("bytes", ww.BytesWritten()),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		)
+	})
+}
+
+func routePattern(ctx context.Context) string {
+	if rctx := chi.RouteContext(ctx); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
@@ -0,0 +1,111 @@
+// Package logging writes JSON logs with log/slog and carries a request-scoped
+// logger in the context.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// piiKeys are the attribute keys whose values are never written, matched
+// case-insensitively against the end of the key so that "email" also covers
+// "user_email".
+var piiKeys = []string{"email", "phone"}
+
+var emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+
+// New returns a logger writing JSON lines to w for records at level or
+// above, with personal data redacted.
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redact,
+	}))
+}
+
+// redact replaces the values of attributes named after personal data and
+// masks email addresses quoted in any other text, such as an error message
+// repeating the row that failed.
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	key := strings.ToLower(attr.Key)
+	for _, piiKey := range piiKeys {
+		if strings.HasSuffix(key, piiKey) {
+			return slog.String(attr.Key, redacted)
+		}
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, maskEmails(attr.Value.String()))
+	case slog.KindAny:
+		switch value := attr.Value.Any().(type) {
+		case error:
+			return slog.String(attr.Key, maskEmails(value.Error()))
+		case []string:
+			masked := make([]string, len(value))
+			for i, s := range value {
+				masked[i] = maskEmails(s)
+			}
+			return slog.Any(attr.Key, masked)
+		}
+	}
+	return attr
+}
+
+func maskEmails(s string) string {
+	if !strings.Contains(s, "@") {
+		return s
+	}
+	return emailPattern.ReplaceAllString(s, redacted)
+}
+
+// Err describes err under the "error" key: its message, its type and the
+// chain of errors it wraps, outermost first.
+func Err(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+
+	attrs := []any{
+		slog.String("message", err.Error()),
+		slog.String("type", fmt.Sprintf("%T", err)),
+	}
+	if causes := causeChain(err); len(causes) > 0 {
+		attrs = append(attrs, slog.Any("causes", causes))
+	}
+	return slog.Group("error", attrs...)
+}
+
+// causeChain lists the errors wrapped by err. The errors of an errors.Join
+// or a multi-%w fmt.Errorf are listed in order along with their own causes.
+func causeChain(err error) []string {
+	var causes []string
+	for {
+		switch wrapper := err.(type) {
+		case interface{ Unwrap() error }:
+			err = wrapper.Unwrap()
+		case interface{ Unwrap() []error }:
+			for _, joined := range wrapper.Unwrap() {
+				causes = append(causes, describe(joined))
+				causes = append(causes, causeChain(joined)...)
+			}
+			return causes
+		default:
+			return causes
+		}
+
+		if err == nil {
+			return causes
+		}
+		causes = append(causes, describe(err))
+	}
+}
+
+func describe(err error) string {
+	return fmt.Sprintf("%T: %s", err, err.Error())
+}
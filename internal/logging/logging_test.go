@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/user-management/internal/model"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+)
+
+// decodeLines parses every JSON line written to buf.
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var decoded map[string]any
+		if err := json.Unmarshal([]byte(line), &decoded); err != nil {
+			t.Fatalf("Expected a JSON line, got %q: %v", line, err)
+		}
+		lines = append(lines, decoded)
+	}
+	return lines
+}
+
+// useDefault makes logger the default for the rest of the test.
+func useDefault(t *testing.T, logger *slog.Logger) {
+	t.Helper()
+
+	previous := slog.Default()
+	slog.SetDefault(logger)
+	t.Cleanup(func() { slog.SetDefault(previous) })
+}
+
+func TestNew_RedactsPersonalData(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	logger.Info("created alice@example.com",
+		slog.String("email", "alice@example.com"),
+		slog.String("user_phone", "+12345678901"),
+		slog.Any("user", model.User{UserId: uuid.New(), Email: "alice@example.com", Phone: "+12345678901"}),
+		slog.Any("err", errors.New("duplicate alice@example.com")),
+	)
+
+	output := buf.String()
+	if strings.Contains(output, "alice@example.com") || strings.Contains(output, "+12345678901") {
+		t.Errorf("Expected personal data to be redacted, got %s", output)
+	}
+
+	line := decodeLines(t, &buf)[0]
+	if line["email"] != redacted {
+		t.Errorf("Expected email to be %s, got %v", redacted, line["email"])
+	}
+	if line["msg"] != "created "+redacted {
+		t.Errorf("Expected the address in the message to be masked, got %v", line["msg"])
+	}
+}
+
+func TestNew_FiltersByLevel(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelWarn)
+
+	logger.Info("quiet")
+	logger.Warn("loud")
+
+	lines := decodeLines(t, &buf)
+	if len(lines) != 1 || lines[0]["msg"] != "loud" {
+		t.Errorf("Expected only the warning, got %v", lines)
+	}
+}
+
+func TestErr_IncludesCauseChain(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo)
+
+	root := errors.New("connection refused")
+	err := fmt.Errorf("create user: %w", fmt.Errorf("begin transaction: %w", root))
+	logger.Error("failed", Err(err))
+
+	logged := decodeLines(t, &buf)[0]["error"].(map[string]any)
+	if logged["message"] != err.Error() {
+		t.Errorf("Expected message %q, got %v", err.Error(), logged["message"])
+	}
+
+	causes, _ := logged["causes"].([]any)
+	if len(causes) != 2 {
+		t.Fatalf("Expected 2 causes, got %v", logged["causes"])
+	}
+	if !strings.HasSuffix(causes[1].(string), "connection refused") {
+		t.Errorf("Expected the root cause last, got %v", causes[1])
+	}
+}
+
+func TestMiddleware_LogsRequest(t *testing.T) {
+	var buf bytes.Buffer
+	useDefault(t, New(&buf, slog.LevelInfo))
+
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(Middleware)
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		AddAttrs(r.Context(), slog.String("user_id", chi.URLParam(r, "id")))
+		FromContext(r.Context()).Error("failed to retrieve user", Err(errors.New("boom")))
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-1")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := decodeLines(t, &buf)
+	if len(lines) != 2 {
+		t.Fatalf("Expected an error line and an access line, got %d", len(lines))
+	}
+
+	for _, line := range lines {
+		if line["request_id"] != "req-1" {
+			t.Errorf("Expected request_id req-1, got %v", line["request_id"])
+		}
+		if line["route"] != "/users/{id}" {
+			t.Errorf("Expected route /users/{id}, got %v", line["route"])
+		}
+		if line["user_id"] != "42" {
+			t.Errorf("Expected user_id 42, got %v", line["user_id"])
+		}
+	}
+
+	access := lines[1]
+	if access["msg"] != "request" || access["level"] != "ERROR" {
+		t.Errorf("Expected an error level access line, got %v", access)
+	}
+	if access["status"] != float64(http.StatusInternalServerError) {
+		t.Errorf("Expected status 500, got %v", access["status"])
+	}
+	if _, ok := access["latency_ms"].(float64); !ok {
+		t.Errorf("Expected latency_ms, got %v", access["latency_ms"])
+	}
+}
+
+func TestFromContext_OutsideRequest(t *testing.T) {
+	if FromContext(t.Context()) != slog.Default() {
+		t.Errorf("Expected the default logger outside a request")
+	}
+}
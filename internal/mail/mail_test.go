@@ -0,0 +1,87 @@
+package mail
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewMessage_RendersTemplates(t *testing.T) {
+	msg, err := NewMessage("bob@example.com", "verify_email", map[string]any{
+		"FirstName": "<Bob>",
+		"Email":     "bob@example.com",
+		"Link":      "https://example.com/verify-email?token=abc&x=1",
+		"ExpiresIn": "24h0m0s",
+	})
+	if err != nil {
+		t.Fatalf("NewMessage failed: %v", err)
+	}
+
+	if msg.Subject != "Confirm your email address" {
+		t.Errorf("Expected the template subject, got %q", msg.Subject)
+	}
+	if !strings.Contains(msg.Text, "Hi <Bob>,") || !strings.Contains(msg.Text, "token=abc&x=1") {
+		t.Errorf("Expected the text body to be unescaped, got %q", msg.Text)
+	}
+	if !strings.Contains(msg.HTML, "Hi &lt;Bob&gt;,") || !strings.Contains(msg.HTML, `href="https://example.com/verify-email?token=abc&amp;x=1"`) {
+		t.Errorf("Expected the HTML body to be escaped, got %q", msg.HTML)
+	}
+}
+
+func TestFileSender_WritesMIMEMessage(t *testing.T) {
+	dir := t.TempDir()
+	sender := NewFileSender(dir, "no-reply@example.com")
+
+	err := sender.Send(t.Context(), Message{To: "bob@example.com", Subject: "Héllo", Text: "plain", HTML: "<p>html</p>"})
+	if err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(files))
+	}
+	raw, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+
+	parsed, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("ReadMessage failed: %v", err)
+	}
+	if subject, _ := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject")); subject != "Héllo" {
+		t.Errorf("Expected subject Héllo, got %q", subject)
+	}
+	if got := parsed.Header.Get("To"); got != "bob@example.com" {
+		t.Errorf("Expected To bob@example.com, got %q", got)
+	}
+
+	_, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("Invalid Content-Type: %v", err)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart failed: %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		bodies = append(bodies, part.Header.Get("Content-Type")+": "+string(body))
+	}
+
+	expected := []string{"text/plain; charset=utf-8: plain", "text/html; charset=utf-8: <p>html</p>"}
+	if strings.Join(bodies, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected parts %q, got %q", expected, bodies)
+	}
+}
@@ -0,0 +1,90 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SMTPSender relays messages through an SMTP server, using STARTTLS when the
+// server offers it.
+type SMTPSender struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPSender sends from from through the server at addr, a host:port.
+// Credentials are only sent if username is set.
+func NewSMTPSender(addr, from, username, password string) *SMTPSender {
+	sender := &SMTPSender{addr: addr, from: from}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		sender.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return sender
+}
+
+func (sender *SMTPSender) Send(_ context.Context, msg Message) error {
+	body, err := msg.Bytes(sender.from)
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(sender.addr, sender.auth, sender.from, []string{msg.To}, body)
+}
+
+// FileSender writes every message to its own .eml file in a directory, for
+// local development.
+type FileSender struct {
+	dir  string
+	from string
+}
+
+func NewFileSender(dir, from string) *FileSender {
+	return &FileSender{dir: dir, from: from}
+}
+
+func (sender *FileSender) Send(_ context.Context, msg Message) error {
+	body, err := msg.Bytes(sender.from)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(sender.dir, 0o755); err != nil {
+		return err
+	}
+
+	// named so that a directory listing shows them in the order sent
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), uuid.NewString()[:8])
+	return os.WriteFile(filepath.Join(sender.dir, name), body, 0o644)
+}
+
+// MemorySender keeps the messages it is given, for tests.
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (sender *MemorySender) Send(_ context.Context, msg Message) error {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	sender.messages = append(sender.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (sender *MemorySender) Messages() []Message {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	return append([]Message(nil), sender.messages...)
+}
@@ -0,0 +1,22 @@
+package mapper
+
+import (
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/model"
+)
+
+func CreateGroupRequestToModel(req dto.CreateGroupRequest) model.Group {
+	return model.Group{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+}
+
+func ApplyUpdateGroupRequest(g *model.Group, req dto.UpdateGroupRequest) {
+	if req.Name != nil {
+		g.Name = *req.Name
+	}
+	if req.Description != nil {
+		g.Description = *req.Description
+	}
+}
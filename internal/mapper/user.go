@@ -7,12 +7,13 @@ import (
 
 func CreateUserRequestToModel(req dto.CreateUserRequest) model.User {
 	return model.User{
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Email:     req.Email,
-		Phone:     req.Phone,
-		Age:       req.Age,
-		Status:    req.Status,
+		FirstName:  req.FirstName,
+		LastName:   req.LastName,
+		Email:      req.Email,
+		Phone:      req.Phone,
+		Age:        req.Age,
+		Status:     req.Status,
+		Attributes: req.Attributes,
 	}
 }
 
@@ -35,4 +36,35 @@ func ApplyUpdateUserRequest(u *model.User, req dto.UpdateUserRequest) {
 	if req.Status != nil {
 		u.Status = *req.Status
 	}
+	if req.Attributes != nil {
+		u.Attributes = req.Attributes
+	}
+}
+
+func UserToDocument(u model.User) dto.UserDocument {
+	doc := dto.UserDocument{
+		FirstName:  u.FirstName,
+		LastName:   u.LastName,
+		Email:      u.Email,
+		Phone:      u.Phone,
+		Status:     u.Status,
+		Attributes: u.Attributes,
+	}
+	if u.Age > 0 {
+		doc.Age = &u.Age
+	}
+	return doc
+}
+
+func ApplyUserDocument(u *model.User, doc dto.UserDocument) {
+	u.FirstName = doc.FirstName
+	u.LastName = doc.LastName
+	u.Email = doc.Email
+	u.Phone = doc.Phone
+	u.Age = 0
+	if doc.Age != nil {
+		u.Age = *doc.Age
+	}
+	u.Status = doc.Status
+	u.Attributes = doc.Attributes
 }
@@ -0,0 +1,81 @@
+// Package metrics exposes Prometheus metrics for the HTTP API, the database
+// pool and the user store.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"example.com/user-management/internal/store/cache"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics owns a registry with the metrics of one server.
+type Metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	storeDuration   *prometheus.HistogramVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route pattern, method and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		storeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "user_store_operation_duration_seconds",
+			Help:    "User store call latency by method.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"method", "outcome"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.storeDuration,
+	)
+
+	return m
+}
+
+// Handler serves the registry in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDB exports the connection pool statistics of dbConn.
+func (m *Metrics) RegisterDB(dbConn *sql.DB, name string) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(dbConn, name))
+}
+
+// RegisterUserCounts exports the number of users in each status, counted on
+// every scrape.
+func (m *Metrics) RegisterUserCounts(counter UserStatusCounter) {
+	m.registry.MustRegister(newUserCountCollector(counter))
+}
+
+// RegisterCache exports the hit and miss counts of the user cache.
+func (m *Metrics) RegisterCache(cachedStore *cache.UserStore) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "user_cache_hits_total",
+			Help: "User lookups answered from the cache, including cached not-found results.",
+		}, func() float64 { return float64(cachedStore.Stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "user_cache_misses_total",
+			Help: "User lookups that had to go to the underlying store.",
+		}, func() float64 { return float64(cachedStore.Stats().Misses) }),
+	)
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestMiddleware_LabelsByRoutePattern(t *testing.T) {
+	m := New()
+
+	router := chi.NewRouter()
+	router.Use(m.Middleware)
+	router.Route("/users", func(r chi.Router) {
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+	})
+
+	for _, path := range []string{"/users/1", "/users/2", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("/users/{id}", "GET", "404")); got != 2 {
+		t.Errorf("Expected 2 requests for /users/{id}, got %v", got)
+	}
+	if got := testutil.ToFloat64(m.requests.WithLabelValues(unmatchedRoute, "GET", "404")); got != 1 {
+		t.Errorf("Expected 1 unmatched request, got %v", got)
+	}
+	if got := testutil.CollectAndCount(m.requestDuration); got != 2 {
+		t.Errorf("Expected 2 latency series, got %d", got)
+	}
+}
+
+func TestInstrumentUserStore_RecordsLatencyByMethod(t *testing.T) {
+	m := New()
+	userStore := m.InstrumentUserStore(memory.NewUserStore())
+
+	user, err := userStore.CreateUser(t.Context(), model.User{Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	_, _, _ = userStore.GetUserById(t.Context(), user.UserId)
+	_, _, _ = userStore.GetUserById(t.Context(), uuid.New())
+	_, _ = userStore.CreateUser(t.Context(), model.User{Email: "alice@example.com"})
+
+	expected := []struct {
+		method, outcome string
+		count           uint64
+	}{
+		{"CreateUser", "ok", 1},
+		{"CreateUser", "error", 1},
+		{"GetUserById", "ok", 2},
+	}
+	for _, e := range expected {
+		if got := sampleCount(t, m.storeDuration.WithLabelValues(e.method, e.outcome)); got != e.count {
+			t.Errorf("Expected %d %s/%s observations, got %d", e.count, e.method, e.outcome, got)
+		}
+	}
+}
+
+func sampleCount(t *testing.T, observer prometheus.Observer) uint64 {
+	t.Helper()
+
+	var metric dto.Metric
+	if err := observer.(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestRegisterUserCounts(t *testing.T) {
+	m := New()
+	userStore := memory.NewUserStore()
+	m.RegisterUserCounts(userStore)
+
+	for _, status := range []model.Status{model.StatusActive, model.StatusActive, model.StatusInactive} {
+		if _, err := userStore.CreateUser(t.Context(), model.User{Email: uuid.New().String() + "@example.com", Status: status}); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+	}
+
+	expected := `
+# HELP users Number of users by status.
+# TYPE users gauge
+users{status="Active"} 2
+users{status="Inactive"} 1
+`
+	if err := testutil.GatherAndCompare(m.registry, strings.NewReader(expected), "users"); err != nil {
+		t.Error(err)
+	}
+}
+
+type failingCounter struct{}
+
+func (failingCounter) CountUsersByStatus(context.Context) (map[model.Status]int, error) {
+	return nil, errors.New("database is down")
+}
+
+func TestRegisterUserCounts_ReportsErrors(t *testing.T) {
+	m := New()
+	m.RegisterUserCounts(failingCounter{})
+
+	if _, err := m.registry.Gather(); err == nil {
+		t.Errorf("Expected gathering to fail")
+	}
+}
@@ -0,0 +1,100 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+// UserStatusCounter is implemented by the user stores that can count their
+// users by status.
+type UserStatusCounter interface {
+	CountUsersByStatus(ctx context.Context) (map[model.Status]int, error)
+}
+
+// InstrumentUserStore wraps next so that the latency of every call is
+// recorded by method and outcome.
+func (m *Metrics) InstrumentUserStore(next store.UserStoreInterface) store.UserStoreInterface {
+	return &instrumentedUserStore{next: next, metrics: m}
+}
+
+type instrumentedUserStore struct {
+	next    store.UserStoreInterface
+	metrics *Metrics
+}
+
+// observe records a call to method that started at start. The outcome is
+// "error" for failures and "ok" otherwise, including not-found results.
+func (userStore *instrumentedUserStore) observe(method string, start time.Time, err error) {
+	outcome := "ok"
+	if err != nil {
+		outcome = "error"
+	}
+	userStore.metrics.storeDuration.WithLabelValues(method, outcome).Observe(time.Since(start).Seconds())
+}
+
+func (userStore *instrumentedUserStore) CreateUser(ctx context.Context, user model.User) (model.User, error) {
+	start := time.Now()
+	createdUser, err := userStore.next.CreateUser(ctx, user)
+	userStore.observe("CreateUser", start, err)
+	return createdUser, err
+}
+
+func (userStore *instrumentedUserStore) GetAllUsers(ctx context.Context) ([]model.User, error) {
+	start := time.Now()
+	users, err := userStore.next.GetAllUsers(ctx)
+	userStore.observe("GetAllUsers", start, err)
+	return users, err
+}
+
+func (userStore *instrumentedUserStore) GetUserById(ctx context.Context, userId uuid.UUID) (model.User, bool, error) {
+	start := time.Now()
+	user, ok, err := userStore.next.GetUserById(ctx, userId)
+	userStore.observe("GetUserById", start, err)
+	return user, ok, err
+}
+
+func (userStore *instrumentedUserStore) GetUsersByIds(ctx context.Context, userIds []uuid.UUID) ([]model.User, error) {
+	start := time.Now()
+	users, err := userStore.next.GetUsersByIds(ctx, userIds)
+	userStore.observe("GetUsersByIds", start, err)
+	return users, err
+}
+
+func (userStore *instrumentedUserStore) ListUsers(ctx context.Context, filter model.UserFilter, after uuid.UUID, limit int) ([]model.User, error) {
+	start := time.Now()
+	users, err := userStore.next.ListUsers(ctx, filter, after, limit)
+	userStore.observe("ListUsers", start, err)
+	return users, err
+}
+
+func (userStore *instrumentedUserStore) UpdateUser(ctx context.Context, user model.User, userId uuid.UUID) (model.User, bool, error) {
+	start := time.Now()
+	updatedUser, ok, err := userStore.next.UpdateUser(ctx, user, userId)
+	userStore.observe("UpdateUser", start, err)
+	return updatedUser, ok, err
+}
+
+func (userStore *instrumentedUserStore) PutUser(ctx context.Context, user model.User, precondition model.Precondition) (model.User, bool, error) {
+	start := time.Now()
+	putUser, created, err := userStore.next.PutUser(ctx, user, precondition)
+	userStore.observe("PutUser", start, err)
+	return putUser, created, err
+}
+
+func (userStore *instrumentedUserStore) ModifyUser(ctx context.Context, userId uuid.UUID, precondition model.Precondition, modify func(user *model.User) error) (model.User, bool, error) {
+	start := time.Now()
+	modifiedUser, ok, err := userStore.next.ModifyUser(ctx, userId, precondition, modify)
+	userStore.observe("ModifyUser", start, err)
+	return modifiedUser, ok, err
+}
+
+func (userStore *instrumentedUserStore) DeleteUser(ctx context.Context, userId uuid.UUID) (bool, error) {
+	start := time.Now()
+	ok, err := userStore.next.DeleteUser(ctx, userId)
+	userStore.observe("DeleteUser", start, err)
+	return ok, err
+}
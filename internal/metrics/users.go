@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"context"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/tenant"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var usersDesc = prometheus.NewDesc(
+	"users",
+	"Number of users by status.",
+	[]string{"status"}, nil,
+)
+
+// userCountCollector counts users when scraped, so the gauge is always
+// current and costs nothing between scrapes.
+type userCountCollector struct {
+	counter UserStatusCounter
+}
+
+func newUserCountCollector(counter UserStatusCounter) *userCountCollector {
+	return &userCountCollector{counter: counter}
+}
+
+func (c *userCountCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- usersDesc
+}
+
+func (c *userCountCollector) Collect(ch chan<- prometheus.Metric) {
+	// the gauge counts the users of the whole deployment
+	counts, err := c.counter.CountUsersByStatus(tenant.WithAllTenants(context.Background()))
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(usersDesc, err)
+		return
+	}
+
+	// report every status so that a status losing its last user drops to
+	// zero instead of disappearing
+	for _, status := range []model.Status{model.StatusActive, model.StatusInactive} {
+		ch <- prometheus.MustNewConstMetric(usersDesc, prometheus.GaugeValue, float64(counts[status]), string(status))
+	}
+}
@@ -0,0 +1,14 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AttributeSchema is the JSON Schema the attributes of a tenant's users must
+// satisfy. It is checked whenever a user is created or updated; users written
+// before it changed keep their attributes until then.
+type AttributeSchema struct {
+	Schema    json.RawMessage
+	UpdatedAt time.Time
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent records a security-relevant step, such as a login or a password
+// change, for later review. Unlike user events, audit events are kept after
+// the user they concern is deleted.
+type AuditEvent struct {
+	AuditId int64
+	// UserId is the user the event concerns, or nil if no user could be
+	// identified, such as for a login with an unknown email.
+	UserId    *uuid.UUID
+	Action    AuditAction
+	Detail    map[string]string
+	CreatedAt time.Time
+}
+
+type AuditAction string
+
+const (
+	AuditLoginSucceeded           AuditAction = "login.succeeded"
+	AuditLoginFailed              AuditAction = "login.failed"
+	AuditLoginMFAChallenged       AuditAction = "login.mfa_challenged"
+	AuditLogout                   AuditAction = "logout"
+	AuditPasswordResetRequested   AuditAction = "password.reset_requested"
+	AuditPasswordResetEmailSent   AuditAction = "password.reset_email_sent"
+	AuditPasswordResetEmailFailed AuditAction = "password.reset_email_failed"
+	AuditPasswordResetFailed      AuditAction = "password.reset_failed"
+	AuditPasswordReset            AuditAction = "password.reset"
+	AuditSessionsRevoked          AuditAction = "sessions.revoked"
+	AuditMFAEnrolmentStarted      AuditAction = "mfa.enrolment_started"
+	AuditMFAEnabled               AuditAction = "mfa.enabled"
+	AuditMFARecoveryCodesReplaced AuditAction = "mfa.recovery_codes_replaced"
+	AuditMFARecoveryCodeUsed      AuditAction = "mfa.recovery_code_used"
+	AuditMFAReset                 AuditAction = "mfa.reset"
+)
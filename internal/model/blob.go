@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// Blob is a file kept by a blob store, such as an avatar image.
+type Blob struct {
+	// Key names the blob within its tenant. Keys are paths separated by
+	// slashes, as in avatars/<user id>/256.
+	Key         string
+	ContentType string
+	Data        []byte
+	UpdatedAt   time.Time
+}
@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailVerification is a request to confirm that a user reads mail sent to
+// Email. It is identified by the id embedded in the token mailed to them and
+// can be used once, before ExpiresAt.
+type EmailVerification struct {
+	TokenId   uuid.UUID
+	UserId    uuid.UUID
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
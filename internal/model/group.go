@@ -0,0 +1,16 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Group is a team of users. Access is managed by team, so a user can belong
+// to any number of groups; group names are unique.
+type Group struct {
+	GroupId     uuid.UUID
+	Name        string
+	Description string
+	CreatedAt   time.Time
+}
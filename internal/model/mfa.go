@@ -0,0 +1,32 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TOTPFactor is a user's authenticator app. It only guards their logins once
+// ConfirmedAt is set, which happens when they prove the app is set up by
+// entering a code from it.
+type TOTPFactor struct {
+	UserId uuid.UUID
+	// SecretCiphertext is the shared secret, encrypted by the authenticator
+	// so that a database dump alone cannot generate codes.
+	SecretCiphertext []byte
+	// LastUsedStep is the time step of the last code accepted, so that no
+	// code is accepted twice.
+	LastUsedStep int64
+	CreatedAt    time.Time
+	ConfirmedAt  *time.Time
+}
+
+// MFAChallenge is a login that passed the password check and waits for a
+// second factor. It is identified by the id embedded in the token handed
+// back in place of the session tokens.
+type MFAChallenge struct {
+	ChallengeId uuid.UUID
+	UserId      uuid.UUID
+	Attempts    int
+	ExpiresAt   time.Time
+}
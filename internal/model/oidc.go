@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OAuthClient is an application users sign in to through the OpenID Connect
+// provider. Confidential clients authenticate with a secret, of which only a
+// hash is kept; public clients have none and rely on PKCE alone.
+type OAuthClient struct {
+	ClientId     uuid.UUID
+	Name         string
+	SecretHash   []byte `json:"-"`
+	RedirectURIs []string
+	CreatedAt    time.Time
+}
+
+// Public reports whether the client has no secret.
+func (client OAuthClient) Public() bool {
+	return client.SecretHash == nil
+}
+
+// OAuthConsent records the scopes a user has agreed to share with a client,
+// separated by spaces.
+type OAuthConsent struct {
+	UserId    uuid.UUID
+	ClientId  uuid.UUID
+	Scope     string
+	GrantedAt time.Time
+}
+
+// AuthorizationCode is handed to a client after the user signs in, to be
+// swapped once for tokens. CodeChallenge is the S256 PKCE challenge the
+// client sent with the authorization request.
+type AuthorizationCode struct {
+	CodeId        uuid.UUID
+	ClientId      uuid.UUID
+	UserId        uuid.UUID
+	RedirectURI   string
+	Scope         string
+	Nonce         string
+	CodeChallenge string
+	ExpiresAt     time.Time
+}
+
+// OAuthRefreshToken lets a client that was granted offline_access get new
+// tokens. Its id changes on every use, so a token can only be used once.
+type OAuthRefreshToken struct {
+	TokenId   uuid.UUID
+	ClientId  uuid.UUID
+	UserId    uuid.UUID
+	Scope     string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// SigningKey is a private key the provider signs tokens with, encrypted at
+// rest. KeyId is the kid published with its public half.
+type SigningKey struct {
+	KeyId                string
+	PrivateKeyCiphertext []byte
+	CreatedAt            time.Time
+}
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PasswordReset lets a user who has forgotten their password choose a new
+// one. It is identified by the id embedded in the token mailed to them and
+// can be used once, before ExpiresAt.
+type PasswordReset struct {
+	TokenId   uuid.UUID
+	UserId    uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
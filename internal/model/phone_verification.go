@@ -0,0 +1,19 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PhoneVerification is the one-time code most recently texted to a user to
+// confirm that they receive messages sent to Phone. Only a hash of the code
+// is kept. Each wrong guess counts towards Attempts.
+type PhoneVerification struct {
+	UserId    uuid.UUID
+	Phone     string
+	CodeHash  []byte
+	Attempts  int
+	ExpiresAt time.Time
+	SentAt    time.Time
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a login. The client holds a refresh token for it, which is
+// swapped for a new one on every refresh; only a hash of the current token is
+// kept. A session ends when it expires or is revoked.
+type Session struct {
+	SessionId        uuid.UUID
+	UserId           uuid.UUID
+	RefreshTokenHash []byte
+	CreatedAt        time.Time
+	ExpiresAt        time.Time
+	RevokedAt        *time.Time
+	// MFA is whether the user logged in with a second factor.
+	MFA bool
+}
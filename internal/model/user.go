@@ -1,17 +1,97 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
 	"github.com/google/uuid"
 )
 
 type User struct {
-	UserId    uuid.UUID
+	UserId uuid.UUID
+	// TenantId is the tenant the user belongs to, which is the tenant they
+	// were created for.
+	TenantId  uuid.UUID
 	FirstName string
 	LastName  string
 	Email     string
 	Phone     string
 	Age       int
 	Status    Status
+	// EmailVerifiedAt is when the user proved they read mail sent to Email,
+	// or nil if they have not. Changing Email clears it.
+	EmailVerifiedAt *time.Time
+	// PhoneVerifiedAt is when the user proved they receive texts sent to
+	// Phone, or nil if they have not. Changing Phone clears it.
+	PhoneVerifiedAt *time.Time
+	// PhoneCountry is the ISO 3166-1 alpha-2 region of Phone, and PhoneType
+	// the kind of line it reaches, such as "mobile" or "fixed". Stores
+	// derive both from Phone, which they keep in E.164 format.
+	PhoneCountry string
+	PhoneType    string
+	// Attributes are the fields a tenant adds to its users, such as a
+	// department, as described by its AttributeSchema. Users read back from
+	// a store always have a map, empty if they have no attributes.
+	Attributes map[string]any
+	// AvatarUrl is the path the user's avatar is served from, which changes
+	// whenever it is replaced, or empty if they have none.
+	AvatarUrl string
+}
+
+// LogValue keeps personal data out of logs: a logged user shows only its id
+// and status.
+func (user User) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("id", user.UserId.String()),
+		slog.String("status", string(user.Status)),
+	)
+}
+
+// Version identifies the state of the user: it changes whenever any of its
+// fields do. It is served as the user's ETag.
+func (user User) Version() string {
+	// users only hold values that encode
+	encoded, _ := json.Marshal(user)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:16])
+}
+
+// Precondition makes a write depend on the current state of a user, as the
+// If-Match and If-None-Match headers do. The zero value always holds.
+type Precondition struct {
+	// IfMatch, when not empty, holds if the user exists with one of these
+	// versions, or exists at all if it contains "*".
+	IfMatch []string
+	// IfNoneMatch holds if the user does not exist.
+	IfNoneMatch bool
+}
+
+// Holds reports whether the precondition is met by current, which is the
+// zero User if exists is false.
+func (precondition Precondition) Holds(current User, exists bool) bool {
+	if precondition.IfNoneMatch && exists {
+		return false
+	}
+	if len(precondition.IfMatch) > 0 {
+		return exists && (slices.Contains(precondition.IfMatch, "*") || slices.Contains(precondition.IfMatch, current.Version()))
+	}
+	return true
+}
+
+// UserFilter narrows a user listing. Zero-valued fields are not applied.
+type UserFilter struct {
+	Status Status
+	Email  string
+	Name   string
+	// GroupId keeps only members of the group.
+	GroupId uuid.UUID
+	// Attributes keeps only users whose attributes have these values. Values
+	// are read as the type the attribute schema gives them.
+	Attributes map[string]string
 }
 
 type Status string
@@ -0,0 +1,31 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserEvent struct {
+	EventId   int64
+	Type      EventType
+	UserId    uuid.UUID
+	User      User
+	CreatedAt time.Time
+}
+
+type EventType string
+
+const (
+	EventUserCreated EventType = "user.created"
+	EventUserUpdated EventType = "user.updated"
+	EventUserDeleted EventType = "user.deleted"
+)
+
+func (t EventType) IsValid() bool {
+	switch t {
+	case EventUserCreated, EventUserUpdated, EventUserDeleted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,449 @@
+package oidc
+
+import (
+	"context"
+	"crypto/hmac"
+	"embed"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tenant"
+	"example.com/user-management/internal/token"
+	"github.com/google/uuid"
+)
+
+// sessionCookie holds the access token of the user signed in to the
+// provider, so that they are not asked for their password by every client.
+const sessionCookie = "oidc_session"
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+var templates = template.Must(template.ParseFS(templateFiles, "templates/*.html"))
+
+// scopeDescriptions say on the consent page what a client gets with each
+// scope.
+var scopeDescriptions = map[string]string{
+	ScopeOpenID:        "Know who you are",
+	ScopeProfile:       "See your name",
+	ScopeEmail:         "See your email address",
+	ScopePhone:         "See your phone number",
+	ScopeOfflineAccess: "Keep access while you are away",
+}
+
+// authorizationRequest is what a client asks for when it sends a user to
+// sign in. The login and consent forms carry it along as hidden fields, and
+// it is checked again at every step.
+type authorizationRequest struct {
+	ClientId            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	Prompt              string
+
+	// ctx acts for the tenant of the client
+	ctx    context.Context
+	client model.OAuthClient
+	scopes []string
+}
+
+// prompts reports whether the client asked for prompt, such as "login".
+func (req *authorizationRequest) prompts(prompt string) bool {
+	return slices.Contains(strings.Fields(req.Prompt), prompt)
+}
+
+type page struct {
+	Title      string
+	Base       string
+	ClientName string
+	Request    *authorizationRequest
+	Error      string
+	Email      string
+	MFAToken   string
+	CSRFToken  string
+	Scopes     []string
+}
+
+func (provider *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	req, ok := provider.parseAuthorizationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !req.prompts("login") {
+		user, session, ok, err := provider.sessionUser(req.ctx, r)
+		if err != nil {
+			provider.renderServerError(w, r, "failed to look up signed in user", err)
+			return
+		}
+		if ok {
+			provider.authorizeUser(w, r, req, user, session)
+			return
+		}
+	}
+
+	if req.prompts("none") {
+		provider.redirectError(w, r, req, errLoginRequired, "The user is not signed in")
+		return
+	}
+	provider.render(w, r, http.StatusOK, "login.html", page{Title: "Sign in", Request: req})
+}
+
+func (provider *Provider) login(w http.ResponseWriter, r *http.Request) {
+	req, ok := provider.parseAuthorizationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	email := r.PostForm.Get("email")
+	tokens, err := provider.authenticator.Login(req.ctx, email, r.PostForm.Get("password"))
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		provider.render(w, r, http.StatusUnauthorized, "login.html", page{
+			Title:   "Sign in",
+			Request: req,
+			Email:   email,
+			Error:   "Wrong email or password.",
+		})
+	case err != nil:
+		provider.renderServerError(w, r, "failed to sign in", err)
+	case tokens.MFAToken != "":
+		provider.render(w, r, http.StatusOK, "mfa.html", page{Title: "Sign in", Request: req, MFAToken: tokens.MFAToken})
+	default:
+		provider.signedIn(w, r, req, tokens)
+	}
+}
+
+func (provider *Provider) loginMFA(w http.ResponseWriter, r *http.Request) {
+	req, ok := provider.parseAuthorizationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	mfaToken := r.PostForm.Get("mfa_token")
+	tokens, err := provider.authenticator.LoginMFA(req.ctx, mfaToken, r.PostForm.Get("code"))
+	switch {
+	case errors.Is(err, store.ErrWrongCode):
+		provider.render(w, r, http.StatusUnauthorized, "mfa.html", page{
+			Title:    "Sign in",
+			Request:  req,
+			MFAToken: mfaToken,
+			Error:    "Wrong code.",
+		})
+	case errors.Is(err, store.ErrChallengeNotFound), errors.Is(err, store.ErrChallengeExpired),
+		errors.Is(err, store.ErrTooManyAttempts), errors.Is(err, auth.ErrInvalidCredentials):
+		provider.render(w, r, http.StatusUnauthorized, "login.html", page{
+			Title:   "Sign in",
+			Request: req,
+			Error:   "Please sign in again.",
+		})
+	case err != nil:
+		provider.renderServerError(w, r, "failed to sign in", err)
+	default:
+		provider.signedIn(w, r, req, tokens)
+	}
+}
+
+func (provider *Provider) consent(w http.ResponseWriter, r *http.Request) {
+	req, ok := provider.parseAuthorizationRequest(w, r)
+	if !ok {
+		return
+	}
+
+	user, session, ok, err := provider.sessionUser(req.ctx, r)
+	if err != nil {
+		provider.renderServerError(w, r, "failed to look up signed in user", err)
+		return
+	}
+	if !ok {
+		provider.render(w, r, http.StatusUnauthorized, "login.html", page{
+			Title:   "Sign in",
+			Request: req,
+			Error:   "Please sign in again.",
+		})
+		return
+	}
+	if !hmac.Equal([]byte(r.PostForm.Get("csrf_token")), []byte(provider.csrfToken(session))) {
+		provider.renderError(w, r, http.StatusForbidden, "The form has expired. Please go back to the application and try again.")
+		return
+	}
+
+	if r.PostForm.Get("action") != "allow" {
+		provider.redirectError(w, r, req, errAccessDenied, "The user denied access")
+		return
+	}
+
+	// scopes consented to before are kept, so that clients asking for less
+	// at times do not have to ask again for the rest
+	granted := req.scopes
+	consent, ok, err := provider.store.GetOAuthConsent(req.ctx, user.UserId, req.client.ClientId)
+	if err != nil {
+		provider.renderServerError(w, r, "failed to read consent", err)
+		return
+	}
+	if ok {
+		granted = parseScope(consent.Scope + " " + req.Scope)
+	}
+	if err := provider.store.SaveOAuthConsent(req.ctx, model.OAuthConsent{
+		UserId:    user.UserId,
+		ClientId:  req.client.ClientId,
+		Scope:     strings.Join(granted, " "),
+		GrantedAt: provider.now().UTC(),
+	}); err != nil {
+		provider.renderServerError(w, r, "failed to store consent", err)
+		return
+	}
+
+	provider.issueCode(w, r, req, user)
+}
+
+// parseAuthorizationRequest reads the authorization request from the query
+// or form. Requests naming an unknown client or redirect URI are turned away
+// with an error page, as they cannot be trusted with a redirect; other errors
+// are sent back to the client. ok is false once a response has been sent.
+func (provider *Provider) parseAuthorizationRequest(w http.ResponseWriter, r *http.Request) (*authorizationRequest, bool) {
+	if err := r.ParseForm(); err != nil {
+		provider.renderError(w, r, http.StatusBadRequest, "The sign in request is malformed.")
+		return nil, false
+	}
+
+	req := &authorizationRequest{
+		ClientId:            r.Form.Get("client_id"),
+		RedirectURI:         r.Form.Get("redirect_uri"),
+		ResponseType:        r.Form.Get("response_type"),
+		Scope:               r.Form.Get("scope"),
+		State:               r.Form.Get("state"),
+		Nonce:               r.Form.Get("nonce"),
+		CodeChallenge:       r.Form.Get("code_challenge"),
+		CodeChallengeMethod: r.Form.Get("code_challenge_method"),
+		Prompt:              r.Form.Get("prompt"),
+	}
+
+	ctx, client, ok, err := provider.resolveClient(r.Context(), req.ClientId)
+	if err != nil {
+		provider.renderServerError(w, r, "failed to look up client", err)
+		return nil, false
+	}
+	if !ok {
+		provider.renderError(w, r, http.StatusBadRequest, "The application is not registered.")
+		return nil, false
+	}
+	if !slices.Contains(client.RedirectURIs, req.RedirectURI) {
+		provider.renderError(w, r, http.StatusBadRequest, "The application sent an unregistered redirect URI.")
+		return nil, false
+	}
+	req.ctx = ctx
+	req.client = client
+	req.scopes = parseScope(req.Scope)
+
+	switch {
+	case req.ResponseType != "code":
+		provider.redirectError(w, r, req, errUnsupportedResponseType, "Only the code response type is supported")
+	case !slices.Contains(req.scopes, ScopeOpenID):
+		provider.redirectError(w, r, req, errInvalidScope, "The openid scope is required")
+	case req.CodeChallengeMethod != "S256" || !validChallenge(req.CodeChallenge):
+		provider.redirectError(w, r, req, errInvalidRequest, "PKCE with the S256 method is required")
+	case req.prompts("none") && len(strings.Fields(req.Prompt)) > 1:
+		provider.redirectError(w, r, req, errInvalidRequest, "prompt=none cannot be combined with other prompts")
+	default:
+		return req, true
+	}
+	return nil, false
+}
+
+// sessionUser returns the user signed in to the provider and their session
+// token, if they signed in to the tenant of ctx and can still sign in.
+func (provider *Provider) sessionUser(ctx context.Context, r *http.Request) (model.User, string, bool, error) {
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return model.User{}, "", false, nil
+	}
+	claims, err := provider.authenticator.ParseAccessToken(ctx, cookie.Value)
+	if err != nil && !errors.Is(err, token.ErrInvalid) {
+		return model.User{}, "", false, err
+	}
+	if err != nil || claims.TenantId != tenant.FromContext(ctx) {
+		return model.User{}, "", false, nil
+	}
+
+	user, ok, err := provider.store.GetUserById(ctx, claims.UserId)
+	if err != nil || !ok || user.Status != model.StatusActive {
+		return model.User{}, "", false, err
+	}
+	return user, cookie.Value, true, nil
+}
+
+// signedIn remembers the user who just signed in and carries on with the
+// request.
+func (provider *Provider) signedIn(w http.ResponseWriter, r *http.Request, req *authorizationRequest, tokens auth.Tokens) {
+	claims, err := provider.authenticator.ParseAccessToken(req.ctx, tokens.AccessToken)
+	if err != nil {
+		provider.renderServerError(w, r, "failed to read access token", err)
+		return
+	}
+	user, ok, err := provider.store.GetUserById(req.ctx, claims.UserId)
+	if err != nil {
+		provider.renderServerError(w, r, "failed to look up signed in user", err)
+		return
+	}
+	// an MFA token of another tenant signs the user in to that tenant
+	if !ok || claims.TenantId != tenant.FromContext(req.ctx) {
+		provider.render(w, r, http.StatusUnauthorized, "login.html", page{
+			Title:   "Sign in",
+			Request: req,
+			Error:   "Please sign in again.",
+		})
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    tokens.AccessToken,
+		Path:     provider.basePath(),
+		Expires:  provider.now().Add(tokens.ExpiresIn),
+		Secure:   strings.HasPrefix(provider.opts.Issuer, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	provider.authorizeUser(w, r, req, user, tokens.AccessToken)
+}
+
+// authorizeUser asks the signed in user for consent, unless they have
+// already given it, and then issues a code.
+func (provider *Provider) authorizeUser(w http.ResponseWriter, r *http.Request, req *authorizationRequest, user model.User, session string) {
+	consent, ok, err := provider.store.GetOAuthConsent(req.ctx, user.UserId, req.client.ClientId)
+	if err != nil {
+		provider.renderServerError(w, r, "failed to read consent", err)
+		return
+	}
+	if ok && covers(parseScope(consent.Scope), req.scopes) && !req.prompts("consent") {
+		provider.issueCode(w, r, req, user)
+		return
+	}
+
+	if req.prompts("none") {
+		provider.redirectError(w, r, req, errConsentRequired, "The user has not consented to the requested scopes")
+		return
+	}
+
+	descriptions := make([]string, len(req.scopes))
+	for i, scope := range req.scopes {
+		descriptions[i] = scopeDescriptions[scope]
+	}
+	provider.render(w, r, http.StatusOK, "consent.html", page{
+		Title:     "Allow access",
+		Request:   req,
+		CSRFToken: provider.csrfToken(session),
+		Scopes:    descriptions,
+	})
+}
+
+func (provider *Provider) issueCode(w http.ResponseWriter, r *http.Request, req *authorizationRequest, user model.User) {
+	code := model.AuthorizationCode{
+		CodeId:        uuid.New(),
+		ClientId:      req.client.ClientId,
+		UserId:        user.UserId,
+		RedirectURI:   req.RedirectURI,
+		Scope:         strings.Join(req.scopes, " "),
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     provider.now().UTC().Add(codeTTL),
+	}
+	if err := provider.store.CreateAuthorizationCode(req.ctx, code); err != nil {
+		provider.renderServerError(w, r, "failed to store authorization code", err)
+		return
+	}
+
+	provider.redirect(w, r, req, url.Values{
+		"code": {provider.signer.Sign(codePurpose, tenant.FromContext(req.ctx), code.CodeId)},
+	})
+}
+
+func (provider *Provider) redirectError(w http.ResponseWriter, r *http.Request, req *authorizationRequest, code, description string) {
+	provider.redirect(w, r, req, url.Values{
+		"error":             {code},
+		"error_description": {description},
+	})
+}
+
+// redirect sends the user back to the client with params, along with the
+// state the client sent and the issuer, by which clients talking to several
+// providers tell the responses apart.
+func (provider *Provider) redirect(w http.ResponseWriter, r *http.Request, req *authorizationRequest, params url.Values) {
+	// registered redirect URIs are absolute, so they parse
+	redirectURI, _ := url.Parse(req.RedirectURI)
+	query := redirectURI.Query()
+	for name, values := range params {
+		query[name] = values
+	}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	query.Set("iss", provider.opts.Issuer)
+	redirectURI.RawQuery = query.Encode()
+
+	status := http.StatusFound
+	if r.Method == http.MethodPost {
+		status = http.StatusSeeOther
+	}
+	http.Redirect(w, r, redirectURI.String(), status)
+}
+
+// csrfToken ties the consent form to the session that was shown it.
+func (provider *Provider) csrfToken(session string) string {
+	return base64.RawURLEncoding.EncodeToString(provider.signer.Digest(csrfPurpose, []byte(session)))
+}
+
+// basePath is the path the provider is served from, which the session cookie
+// is limited to.
+func (provider *Provider) basePath() string {
+	issuer, err := url.Parse(provider.opts.Issuer)
+	if err != nil || issuer.Path == "" {
+		return "/"
+	}
+	return issuer.Path
+}
+
+func (provider *Provider) render(w http.ResponseWriter, r *http.Request, status int, name string, data page) {
+	data.Base = strings.TrimSuffix(provider.basePath(), "/")
+	if data.Request != nil {
+		data.ClientName = data.Request.client.Name
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	if err := templates.ExecuteTemplate(w, name, data); err != nil {
+		logging.FromContext(r.Context()).Error("failed to render page", logging.Err(err))
+	}
+}
+
+func (provider *Provider) renderError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	provider.render(w, r, status, "error.html", page{Title: "Sign in failed", Error: message})
+}
+
+func (provider *Provider) renderServerError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	logging.FromContext(r.Context()).Error(message, logging.Err(err))
+	provider.renderError(w, r, http.StatusInternalServerError, "Something went wrong. Please try again later.")
+}
+
+// validChallenge reports whether challenge could be an S256 code challenge:
+// the unpadded base64url encoding of a SHA-256 hash.
+func validChallenge(challenge string) bool {
+	hash, err := base64.RawURLEncoding.DecodeString(challenge)
+	return err == nil && len(hash) == 32
+}
@@ -0,0 +1,182 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/tenant"
+	"github.com/google/uuid"
+)
+
+// clientAuthMethods are the ways a client can authenticate at the token and
+// revocation endpoints. Public clients use none.
+var clientAuthMethods = []string{"client_secret_basic", "client_secret_post", "none"}
+
+// Client is a registered client as its developers see it.
+type Client struct {
+	// ClientId is what the client identifies itself with. It is signed and
+	// names the tenant the client belongs to, so that users can sign in to
+	// it without saying which tenant they belong to.
+	ClientId     string
+	Name         string
+	RedirectURIs []string
+	// Public is set for clients without a secret, which must use PKCE.
+	Public    bool
+	CreatedAt time.Time
+	// ClientSecret is only set when the client has just been registered: it
+	// is not kept, so it cannot be shown again.
+	ClientSecret string `json:",omitempty"`
+}
+
+// RegisterClient registers a client in the tenant of ctx. Public clients,
+// such as single page and mobile apps, get no secret. Redirect URIs must be
+// absolute and have no fragment, or ErrInvalidRedirectURI is returned.
+func (provider *Provider) RegisterClient(ctx context.Context, name string, redirectURIs []string, public bool) (Client, error) {
+	for _, redirectURI := range redirectURIs {
+		parsed, err := url.Parse(redirectURI)
+		if err != nil || !parsed.IsAbs() || parsed.Host == "" || parsed.Fragment != "" || parsed.RawFragment != "" {
+			return Client{}, ErrInvalidRedirectURI
+		}
+	}
+
+	var secret string
+	client := model.OAuthClient{Name: name, RedirectURIs: redirectURIs}
+	if !public {
+		secret = rand.Text()
+		client.SecretHash = hashSecret(secret)
+	}
+
+	created, err := provider.store.CreateOAuthClient(ctx, client)
+	if err != nil {
+		return Client{}, err
+	}
+
+	registered := provider.client(ctx, created)
+	registered.ClientSecret = secret
+	return registered, nil
+}
+
+// Clients returns the clients of the tenant of ctx, ordered by name.
+func (provider *Provider) Clients(ctx context.Context) ([]Client, error) {
+	stored, err := provider.store.GetAllOAuthClients(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clients := make([]Client, len(stored))
+	for i, client := range stored {
+		clients[i] = provider.client(ctx, client)
+	}
+	return clients, nil
+}
+
+// GetClient returns the client with clientId, which must belong to the
+// tenant of ctx.
+func (provider *Provider) GetClient(ctx context.Context, clientId string) (Client, bool, error) {
+	client, ok, err := provider.lookupClient(ctx, clientId)
+	if err != nil || !ok {
+		return Client{}, false, err
+	}
+	return provider.client(ctx, client), true, nil
+}
+
+// DeleteClient deletes the client with clientId along with everything issued
+// to it. Tokens it already holds stay valid until they expire.
+func (provider *Provider) DeleteClient(ctx context.Context, clientId string) (bool, error) {
+	client, ok, err := provider.lookupClient(ctx, clientId)
+	if err != nil || !ok {
+		return false, err
+	}
+	return provider.store.DeleteOAuthClient(ctx, client.ClientId)
+}
+
+func (provider *Provider) client(ctx context.Context, client model.OAuthClient) Client {
+	return Client{
+		ClientId:     provider.clientID(ctx, client.ClientId),
+		Name:         client.Name,
+		RedirectURIs: client.RedirectURIs,
+		Public:       client.Public(),
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+// lookupClient returns the client with clientId if it belongs to the tenant
+// of ctx.
+func (provider *Provider) lookupClient(ctx context.Context, clientId string) (model.OAuthClient, bool, error) {
+	tenantId, id, err := provider.signer.Verify(clientPurpose, clientId)
+	if err != nil || tenantId != tenant.FromContext(ctx) {
+		return model.OAuthClient{}, false, nil
+	}
+	return provider.store.GetOAuthClient(ctx, id)
+}
+
+// resolveClient returns the client with clientId and a copy of ctx acting for
+// its tenant, which every later step of a sign in acts for.
+func (provider *Provider) resolveClient(ctx context.Context, clientId string) (context.Context, model.OAuthClient, bool, error) {
+	tenantId, id, err := provider.signer.Verify(clientPurpose, clientId)
+	if err != nil {
+		return ctx, model.OAuthClient{}, false, nil
+	}
+	ctx = tenant.WithID(ctx, tenantId)
+	client, ok, err := provider.store.GetOAuthClient(ctx, id)
+	return ctx, client, ok, err
+}
+
+// authenticateClient identifies the client calling the token or revocation
+// endpoint by HTTP basic authentication or form parameters. Public clients
+// only send their id.
+func (provider *Provider) authenticateClient(r *http.Request) (context.Context, model.OAuthClient, error) {
+	clientId, secret, basic := r.BasicAuth()
+	if basic {
+		// the credentials are form encoded before they are put in the header
+		var err error
+		if clientId, err = url.QueryUnescape(clientId); err != nil {
+			return nil, model.OAuthClient{}, invalidClient(basic)
+		}
+		if secret, err = url.QueryUnescape(secret); err != nil {
+			return nil, model.OAuthClient{}, invalidClient(basic)
+		}
+	} else {
+		clientId = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	ctx, client, ok, err := provider.resolveClient(r.Context(), clientId)
+	if err != nil {
+		return nil, model.OAuthClient{}, err
+	}
+	if !ok {
+		return nil, model.OAuthClient{}, invalidClient(basic)
+	}
+	if !client.Public() && subtle.ConstantTimeCompare(hashSecret(secret), client.SecretHash) != 1 {
+		return nil, model.OAuthClient{}, invalidClient(basic)
+	}
+	return ctx, client, nil
+}
+
+func invalidClient(basic bool) *Error {
+	err := &Error{Status: http.StatusBadRequest, Code: errInvalidClient, Description: "Client authentication failed"}
+	if basic {
+		err.Status = http.StatusUnauthorized
+	}
+	return err
+}
+
+// hashSecret hashes a client secret for storage. Secrets are random, so a
+// plain hash is enough.
+func hashSecret(secret string) []byte {
+	hash := sha256.Sum256([]byte(secret))
+	return hash[:]
+}
+
+// clientID returns the id of a client of the tenant of ctx, as the client
+// knows it.
+func (provider *Provider) clientID(ctx context.Context, id uuid.UUID) string {
+	return provider.signer.Sign(clientPurpose, tenant.FromContext(ctx), id)
+}
@@ -0,0 +1,246 @@
+package oidc
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/token"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/google/uuid"
+)
+
+const (
+	keyPurpose       = "oidc-signing-key"
+	signingAlgorithm = "RS256"
+	keyBits          = 2048
+
+	// keyCacheTTL is how long keys are kept in memory before they are read
+	// again, to pick up keys generated by other instances.
+	keyCacheTTL = time.Minute
+	// keyReloadInterval is how often tokens signed with unknown keys may
+	// cause the keys to be read again.
+	keyReloadInterval = 10 * time.Second
+)
+
+type signingKey struct {
+	id        string
+	key       *rsa.PrivateKey
+	createdAt time.Time
+}
+
+// keySet keeps the provider's signing keys, encrypted in the store with a key
+// derived from the token secret. The newest key signs; all of them verify.
+// A new key is generated once the newest is due for rotation, and keys are
+// dropped once nothing they signed can still be valid.
+type keySet struct {
+	store    Store
+	signer   *token.Signer
+	rotation time.Duration
+	now      func() time.Time
+
+	mu       sync.Mutex
+	keys     []signingKey
+	loadedAt time.Time
+}
+
+func newKeySet(keyStore Store, signer *token.Signer, rotation time.Duration, now func() time.Time) *keySet {
+	return &keySet{
+		store:    keyStore,
+		signer:   signer,
+		rotation: rotation,
+		now:      now,
+	}
+}
+
+// current returns the key to sign with, generating one if there is none or
+// the newest is due for rotation.
+func (keys *keySet) current(ctx context.Context) (signingKey, error) {
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+
+	now := keys.now()
+	if len(keys.keys) == 0 || now.Sub(keys.loadedAt) >= keyCacheTTL || keys.due(now) {
+		if err := keys.load(ctx); err != nil {
+			return signingKey{}, err
+		}
+	}
+	if keys.due(now) {
+		if err := keys.rotate(ctx, now); err != nil {
+			return signingKey{}, err
+		}
+	}
+	return keys.keys[0], nil
+}
+
+// lookup returns the public key with id, reading the keys again if it is not
+// known, as another instance may have generated it.
+func (keys *keySet) lookup(ctx context.Context, id string) (*rsa.PublicKey, error) {
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+
+	now := keys.now()
+	if now.Sub(keys.loadedAt) >= keyCacheTTL || (keys.find(id) == nil && now.Sub(keys.loadedAt) >= keyReloadInterval) {
+		if err := keys.load(ctx); err != nil {
+			return nil, err
+		}
+	}
+	key := keys.find(id)
+	if key == nil {
+		return nil, fmt.Errorf("unknown signing key %q", id)
+	}
+	return &key.key.PublicKey, nil
+}
+
+// publicKeys returns the public halves of all keys as a JSON Web Key Set.
+func (keys *keySet) publicKeys(ctx context.Context) (jose.JSONWebKeySet, error) {
+	if _, err := keys.current(ctx); err != nil {
+		return jose.JSONWebKeySet{}, err
+	}
+
+	keys.mu.Lock()
+	defer keys.mu.Unlock()
+
+	keySet := jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, len(keys.keys))}
+	for i, key := range keys.keys {
+		keySet.Keys[i] = jose.JSONWebKey{
+			Key:       &key.key.PublicKey,
+			KeyID:     key.id,
+			Algorithm: signingAlgorithm,
+			Use:       "sig",
+		}
+	}
+	return keySet, nil
+}
+
+// due reports whether the newest key should no longer sign at now.
+func (keys *keySet) due(now time.Time) bool {
+	return len(keys.keys) == 0 || now.Sub(keys.keys[0].createdAt) >= keys.rotation
+}
+
+func (keys *keySet) find(id string) *signingKey {
+	for i := range keys.keys {
+		if keys.keys[i].id == id {
+			return &keys.keys[i]
+		}
+	}
+	return nil
+}
+
+// load reads the keys from the store. Keys that fail to decrypt, such as
+// those stored under a previous token secret, are skipped.
+func (keys *keySet) load(ctx context.Context) error {
+	stored, err := keys.store.GetSigningKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read signing keys: %w", err)
+	}
+
+	loaded := make([]signingKey, 0, len(stored))
+	for _, key := range stored {
+		privateKey, err := keys.decrypt(key)
+		if err != nil {
+			logging.FromContext(ctx).Warn("skipping signing key", slog.String("key_id", key.KeyId), logging.Err(err))
+			continue
+		}
+		loaded = append(loaded, signingKey{id: key.KeyId, key: privateKey, createdAt: key.CreatedAt})
+	}
+
+	keys.keys = loaded
+	keys.loadedAt = keys.now()
+	return nil
+}
+
+// rotate generates a new key to sign with and deletes those that have not
+// signed anything for a whole rotation, whose tokens have all expired.
+func (keys *keySet) rotate(ctx context.Context, now time.Time) error {
+	privateKey, err := rsa.GenerateKey(rand.Reader, keyBits)
+	if err != nil {
+		return err
+	}
+	key := signingKey{id: uuid.NewString(), key: privateKey, createdAt: now.UTC()}
+
+	ciphertext, err := keys.encrypt(key)
+	if err != nil {
+		return err
+	}
+	if err := keys.store.CreateSigningKey(ctx, model.SigningKey{
+		KeyId:                key.id,
+		PrivateKeyCiphertext: ciphertext,
+		CreatedAt:            key.createdAt,
+	}); err != nil {
+		return fmt.Errorf("failed to store signing key: %w", err)
+	}
+
+	expired := key.createdAt.Add(-2 * keys.rotation)
+	if err := keys.store.DeleteSigningKeys(ctx, expired); err != nil {
+		return fmt.Errorf("failed to delete expired signing keys: %w", err)
+	}
+
+	kept := []signingKey{key}
+	for _, old := range keys.keys {
+		if !old.createdAt.Before(expired) {
+			kept = append(kept, old)
+		}
+	}
+	keys.keys = kept
+	return nil
+}
+
+// encrypt seals the key with AES-GCM, binding in its id so that a key copied
+// to another row fails to decrypt.
+func (keys *keySet) encrypt(key signingKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key.key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := keys.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(der)+aead.Overhead())
+	_, _ = rand.Read(nonce)
+	return aead.Seal(nonce, nonce, der, []byte(key.id)), nil
+}
+
+func (keys *keySet) decrypt(key model.SigningKey) (*rsa.PrivateKey, error) {
+	aead, err := keys.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(key.PrivateKeyCiphertext) < aead.NonceSize() {
+		return nil, errors.New("signing key ciphertext too short")
+	}
+	nonce, sealed := key.PrivateKeyCiphertext[:aead.NonceSize()], key.PrivateKeyCiphertext[aead.NonceSize():]
+	der, err := aead.Open(nil, nonce, sealed, []byte(key.KeyId))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt signing key: %w", err)
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("signing key is not an RSA key")
+	}
+	return privateKey, nil
+}
+
+func (keys *keySet) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(keys.signer.Digest(keyPurpose))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,70 @@
+package oidc
+
+import (
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/store/memory"
+	"example.com/user-management/internal/token"
+)
+
+func TestKeySet_Rotation(t *testing.T) {
+	ctx := t.Context()
+	keyStore := memory.NewUserStore()
+	signer := token.NewSigner([]byte("secret"))
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	keys := newKeySet(keyStore, signer, 24*time.Hour, func() time.Time { return now })
+
+	first, err := keys.current(ctx)
+	if err != nil {
+		t.Fatalf("current failed: %v", err)
+	}
+	if again, _ := keys.current(ctx); again.id != first.id {
+		t.Errorf("Expected the key to be kept until it is due")
+	}
+
+	now = now.Add(25 * time.Hour)
+	second, err := keys.current(ctx)
+	if err != nil {
+		t.Fatalf("current failed: %v", err)
+	}
+	if second.id == first.id {
+		t.Fatalf("Expected a new key once the first is due")
+	}
+	// tokens signed with the first key must still verify
+	if _, err := keys.lookup(ctx, first.id); err != nil {
+		t.Errorf("Expected the first key to still verify: %v", err)
+	}
+	published, err := keys.publicKeys(ctx)
+	if err != nil {
+		t.Fatalf("publicKeys failed: %v", err)
+	}
+	if len(published.Keys) != 2 || published.Keys[0].KeyID != second.id {
+		t.Errorf("Expected both keys, newest first, got %+v", published.Keys)
+	}
+
+	// another instance sharing the store reads the same keys
+	other := newKeySet(keyStore, signer, 24*time.Hour, func() time.Time { return now })
+	if key, err := other.current(ctx); err != nil || key.id != second.id {
+		t.Errorf("Expected the other instance to sign with %s, got %s: %v", second.id, key.id, err)
+	}
+
+	// after two more rotations nothing the first key signed can be valid
+	now = now.Add(25 * time.Hour)
+	if _, err := keys.current(ctx); err != nil {
+		t.Fatalf("current failed: %v", err)
+	}
+	now = now.Add(25 * time.Hour)
+	if _, err := keys.current(ctx); err != nil {
+		t.Fatalf("current failed: %v", err)
+	}
+	if _, err := keys.lookup(ctx, first.id); err == nil {
+		t.Errorf("Expected the first key to be deleted")
+	}
+
+	// keys stored under another secret are skipped
+	rekeyed := newKeySet(keyStore, token.NewSigner([]byte("other")), 24*time.Hour, func() time.Time { return now })
+	if key, err := rekeyed.current(ctx); err != nil || keys.find(key.id) != nil {
+		t.Errorf("Expected a new key under the new secret: %v", err)
+	}
+}
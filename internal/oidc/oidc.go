@@ -0,0 +1,262 @@
+// Package oidc makes the service an OpenID Connect provider, so that other
+// applications can sign their users in with the accounts kept here. It serves
+// the authorization code flow with PKCE: users sign in and consent on pages
+// served by the provider, and clients swap the code they are sent for an ID
+// token, an access token for the userinfo endpoint and, if they asked for
+// offline access, a refresh token. Tokens are signed with RSA keys that are
+// rotated and published as a JSON Web Key Set.
+package oidc
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/token"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	clientPurpose       = "oidc-client"
+	codePurpose         = "oidc-authorization-code"
+	refreshTokenPurpose = "oidc-refresh-token"
+	csrfPurpose         = "oidc-csrf"
+
+	// codeTTL is how long a client has to redeem an authorization code.
+	codeTTL = time.Minute
+)
+
+// Scopes a client can ask for. Each but openid and offline_access grants the
+// claims of the same name in the userinfo response.
+const (
+	ScopeOpenID        = "openid"
+	ScopeProfile       = "profile"
+	ScopeEmail         = "email"
+	ScopePhone         = "phone"
+	ScopeOfflineAccess = "offline_access"
+)
+
+// Scopes lists the supported scopes in the order they are shown on the
+// consent page.
+var Scopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopePhone, ScopeOfflineAccess}
+
+var (
+	ErrClientNotFound = errors.New("client not found")
+	// ErrInvalidRedirectURI is returned when registering a client with a
+	// redirect URI that is not absolute or has a fragment.
+	ErrInvalidRedirectURI = errors.New("redirect URIs must be absolute and have no fragment")
+)
+
+// Store is what Provider needs from the store.
+type Store interface {
+	store.UserStoreInterface
+	store.OIDCStoreInterface
+}
+
+type Options struct {
+	// Issuer is the public URL the provider is served from, which clients
+	// discover it by. Tokens name it as their issuer.
+	Issuer string
+	// AccessTokenTTL is how long ID and access tokens are valid.
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL is how long a client can keep refreshing after the
+	// user signed in.
+	RefreshTokenTTL time.Duration
+	// KeyRotation is how long a signing key is used for. Keys are published
+	// for twice as long, so that tokens signed with them can be verified
+	// until they expire.
+	KeyRotation time.Duration
+}
+
+// Provider serves the OpenID Connect endpoints. Users sign in with the
+// authenticator, so the provider's login follows the same rules as the API's,
+// second factor included.
+type Provider struct {
+	store         Store
+	authenticator *auth.Authenticator
+	signer        *token.Signer
+	opts          Options
+	keys          *keySet
+	router        chi.Router
+	now           func() time.Time
+}
+
+func NewProvider(oidcStore Store, authenticator *auth.Authenticator, signer *token.Signer, opts Options) *Provider {
+	provider := &Provider{
+		store:         oidcStore,
+		authenticator: authenticator,
+		signer:        signer,
+		opts:          opts,
+		now:           time.Now,
+	}
+	provider.keys = newKeySet(oidcStore, signer, opts.KeyRotation, func() time.Time { return provider.now() })
+
+	router := chi.NewRouter()
+	router.Get("/.well-known/openid-configuration", provider.discovery)
+	router.Get("/jwks", provider.jwks)
+	router.Get("/authorize", provider.authorize)
+	router.Post("/authorize", provider.authorize)
+	router.Post("/login", provider.login)
+	router.Post("/login/mfa", provider.loginMFA)
+	router.Post("/consent", provider.consent)
+	router.Post("/token", provider.token)
+	router.Get("/userinfo", provider.userinfo)
+	router.Post("/userinfo", provider.userinfo)
+	router.Post("/revoke", provider.revoke)
+	provider.router = router
+
+	return provider
+}
+
+func (provider *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	provider.router.ServeHTTP(w, r)
+}
+
+func (provider *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	issuer := provider.opts.Issuer
+	writeJSON(w, r, http.StatusOK, map[string]any{
+		"issuer":                                     issuer,
+		"authorization_endpoint":                     issuer + "/authorize",
+		"token_endpoint":                             issuer + "/token",
+		"userinfo_endpoint":                          issuer + "/userinfo",
+		"jwks_uri":                                   issuer + "/jwks",
+		"revocation_endpoint":                        issuer + "/revoke",
+		"scopes_supported":                           Scopes,
+		"response_types_supported":                   []string{"code"},
+		"response_modes_supported":                   []string{"query"},
+		"grant_types_supported":                      []string{"authorization_code", "refresh_token"},
+		"subject_types_supported":                    []string{"public"},
+		"id_token_signing_alg_values_supported":      []string{signingAlgorithm},
+		"token_endpoint_auth_methods_supported":      clientAuthMethods,
+		"revocation_endpoint_auth_methods_supported": clientAuthMethods,
+		"code_challenge_methods_supported":           []string{"S256"},
+		"prompt_values_supported":                    []string{"none", "login", "consent"},
+		"claims_supported": []string{
+			"iss", "sub", "aud", "exp", "iat", "nonce",
+			"name", "given_name", "family_name", "email", "email_verified", "phone_number", "phone_number_verified",
+		},
+		"authorization_response_iss_parameter_supported": true,
+	})
+}
+
+func (provider *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	keySet, err := provider.keys.publicKeys(r.Context())
+	if err != nil {
+		writeError(w, r, "failed to load signing keys", err)
+		return
+	}
+	// clients fetch the set again when they meet a key id they do not know
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, r, http.StatusOK, keySet)
+}
+
+// parseScope splits a space separated scope into the supported scopes it
+// names, in the order of Scopes. Unknown scopes are dropped, as the spec asks.
+func parseScope(scope string) []string {
+	requested := strings.Fields(scope)
+	var scopes []string
+	for _, s := range Scopes {
+		if slices.Contains(requested, s) {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
+
+// covers reports whether granted includes every scope in requested.
+func covers(granted, requested []string) bool {
+	for _, s := range requested {
+		if !slices.Contains(granted, s) {
+			return false
+		}
+	}
+	return true
+}
+
+// userClaims returns what the scopes let a client know about the user.
+func userClaims(user model.User, scopes []string) map[string]any {
+	claims := map[string]any{"sub": user.UserId.String()}
+	if slices.Contains(scopes, ScopeProfile) {
+		claims["name"] = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		claims["given_name"] = user.FirstName
+		if user.LastName != "" {
+			claims["family_name"] = user.LastName
+		}
+	}
+	if slices.Contains(scopes, ScopeEmail) {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.EmailVerifiedAt != nil
+	}
+	if slices.Contains(scopes, ScopePhone) && user.Phone != "" {
+		claims["phone_number"] = user.Phone
+		claims["phone_number_verified"] = user.PhoneVerifiedAt != nil
+	}
+	return claims
+}
+
+// Error codes of OAuth 2.0 and OpenID Connect error responses.
+const (
+	errInvalidRequest          = "invalid_request"
+	errInvalidClient           = "invalid_client"
+	errInvalidGrant            = "invalid_grant"
+	errInvalidScope            = "invalid_scope"
+	errInvalidToken            = "invalid_token"
+	errUnsupportedGrantType    = "unsupported_grant_type"
+	errUnsupportedResponseType = "unsupported_response_type"
+	errAccessDenied            = "access_denied"
+	errLoginRequired           = "login_required"
+	errConsentRequired         = "consent_required"
+	errServerError             = "server_error"
+)
+
+// Error is an OAuth 2.0 error response, such as invalid_grant.
+type Error struct {
+	Status      int
+	Code        string
+	Description string
+}
+
+func (err *Error) Error() string {
+	if err.Description == "" {
+		return err.Code
+	}
+	return err.Code + ": " + err.Description
+}
+
+func (err *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code        string `json:"error"`
+		Description string `json:"error_description,omitempty"`
+	}{err.Code, err.Description})
+}
+
+func badRequest(code, description string) *Error {
+	return &Error{Status: http.StatusBadRequest, Code: code, Description: description}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logging.FromContext(r.Context()).Error("failed to encode response", logging.Err(err))
+	}
+}
+
+// writeError sends err as an OAuth error response, logging it as a server
+// error unless it is an *Error.
+func writeError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	var oauthErr *Error
+	if !errors.As(err, &oauthErr) {
+		logging.FromContext(r.Context()).Error(message, logging.Err(err))
+		oauthErr = &Error{Status: http.StatusInternalServerError, Code: errServerError}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, oauthErr.Status, oauthErr)
+}
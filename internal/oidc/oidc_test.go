@@ -0,0 +1,476 @@
+package oidc
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/mail"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store/memory"
+	"example.com/user-management/internal/token"
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+	"golang.org/x/oauth2"
+)
+
+const (
+	testEmail    = "ada@example.com"
+	testPassword = "correct horse battery staple"
+	redirectURI  = "https://app.example.com/callback"
+)
+
+type testEnv struct {
+	server        *httptest.Server
+	provider      *Provider
+	store         *memory.UserStore
+	authenticator *auth.Authenticator
+	user          model.User
+	client        Client
+	// browser keeps the provider's session cookie and stops at redirects to
+	// the client
+	browser *http.Client
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+
+	userStore := memory.NewUserStore()
+	signer := token.NewSigner([]byte("secret"))
+	authenticator := auth.NewAuthenticator(userStore, mail.NewMemorySender(), signer, auth.Options{
+		AccessTokenTTL:  15 * time.Minute,
+		SessionTTL:      time.Hour,
+		MFAChallengeTTL: 5 * time.Minute,
+		MFAMaxAttempts:  5,
+		MFAIssuer:       "Example",
+	})
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	provider := NewProvider(userStore, authenticator, signer, Options{
+		Issuer:          server.URL + "/oidc",
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 24 * time.Hour,
+		KeyRotation:     24 * time.Hour,
+	})
+	mux.Handle("/oidc/", http.StripPrefix("/oidc", provider))
+
+	user, err := userStore.CreateUser(t.Context(), model.User{
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Email:     testEmail,
+		Phone:     "+12025550123",
+		Status:    model.StatusActive,
+	})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	setPassword(t, userStore, user.UserId, testPassword)
+
+	client, err := provider.RegisterClient(t.Context(), "Analytical Engine", []string{redirectURI}, false)
+	if err != nil {
+		t.Fatalf("RegisterClient failed: %v", err)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	browser := &http.Client{
+		Jar: jar,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if strings.HasPrefix(req.URL.String(), redirectURI) {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+
+	return &testEnv{
+		server:        server,
+		provider:      provider,
+		store:         userStore,
+		authenticator: authenticator,
+		user:          user,
+		client:        client,
+		browser:       browser,
+	}
+}
+
+// setPassword gives the user a password through a password reset, the only
+// way one is set.
+func setPassword(t *testing.T, userStore *memory.UserStore, userId uuid.UUID, password string) {
+	t.Helper()
+
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		t.Fatalf("HashPassword failed: %v", err)
+	}
+	reset := model.PasswordReset{TokenId: uuid.New(), UserId: userId, ExpiresAt: time.Now().Add(time.Hour)}
+	if err := userStore.CreatePasswordReset(t.Context(), reset); err != nil {
+		t.Fatalf("CreatePasswordReset failed: %v", err)
+	}
+	if err := userStore.ResetPassword(t.Context(), reset.TokenId, hash, time.Now()); err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+}
+
+func (env *testEnv) oauth2Config(t *testing.T, scopes ...string) (*gooidc.Provider, *oauth2.Config) {
+	t.Helper()
+
+	provider, err := gooidc.NewProvider(t.Context(), env.server.URL+"/oidc")
+	if err != nil {
+		t.Fatalf("Discovery failed: %v", err)
+	}
+	return provider, &oauth2.Config{
+		ClientID:     env.client.ClientId,
+		ClientSecret: env.client.ClientSecret,
+		Endpoint:     provider.Endpoint(),
+		RedirectURL:  redirectURI,
+		Scopes:       scopes,
+	}
+}
+
+// get loads page in the browser.
+func (env *testEnv) get(t *testing.T, page string) (*http.Response, string) {
+	t.Helper()
+
+	resp, err := env.browser.Get(page)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", page, err)
+	}
+	return resp, readBody(t, resp)
+}
+
+// submit posts a form of the provider's pages, along with the authorization
+// request in authURL.
+func (env *testEnv) submit(t *testing.T, path, authURL string, fields url.Values) (*http.Response, string) {
+	t.Helper()
+
+	parsed, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatalf("Failed to parse %s: %v", authURL, err)
+	}
+	form := parsed.Query()
+	for name, values := range fields {
+		form[name] = values
+	}
+
+	resp, err := env.browser.PostForm(env.server.URL+"/oidc"+path, form)
+	if err != nil {
+		t.Fatalf("POST %s failed: %v", path, err)
+	}
+	return resp, readBody(t, resp)
+}
+
+// signIn signs the user in and consents through the provider's pages, and
+// returns where the browser was sent back to the client.
+func (env *testEnv) signIn(t *testing.T, authURL string) *url.URL {
+	t.Helper()
+
+	resp, body := env.get(t, authURL)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `name="password"`) {
+		t.Fatalf("Expected the login page, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, body = env.submit(t, "/login", authURL, url.Values{"email": {testEmail}, "password": {testPassword}})
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "would like to") {
+		t.Fatalf("Expected the consent page, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, _ = env.submit(t, "/consent", authURL, url.Values{"csrf_token": {formValue(t, body, "csrf_token")}, "action": {"allow"}})
+	return callback(t, resp)
+}
+
+func readBody(t *testing.T, resp *http.Response) string {
+	t.Helper()
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read body: %v", err)
+	}
+	return string(body)
+}
+
+// formValue returns the value of the hidden field name in page.
+func formValue(t *testing.T, page, name string) string {
+	t.Helper()
+
+	match := regexp.MustCompile(`name="` + name + `" value="([^"]*)"`).FindStringSubmatch(page)
+	if match == nil {
+		t.Fatalf("No %s field in %s", name, page)
+	}
+	return match[1]
+}
+
+// callback returns where resp redirects to, which must be the client.
+func callback(t *testing.T, resp *http.Response) *url.URL {
+	t.Helper()
+
+	location, err := resp.Location()
+	if err != nil || !strings.HasPrefix(location.String(), redirectURI) {
+		t.Fatalf("Expected a redirect to the client, got %d to %v", resp.StatusCode, location)
+	}
+	return location
+}
+
+func TestProvider_EndToEnd(t *testing.T) {
+	env := newTestEnv(t)
+	ctx := t.Context()
+	provider, config := env.oauth2Config(t, gooidc.ScopeOpenID, "profile", "email", "phone", gooidc.ScopeOfflineAccess)
+
+	verifier := oauth2.GenerateVerifier()
+	authURL := config.AuthCodeURL("state-1", oauth2.S256ChallengeOption(verifier), gooidc.Nonce("nonce-1"))
+	location := env.signIn(t, authURL)
+
+	query := location.Query()
+	if query.Get("state") != "state-1" || query.Get("iss") != env.server.URL+"/oidc" || query.Get("code") == "" {
+		t.Fatalf("Expected a code, the state and the issuer, got %s", location)
+	}
+
+	tokens, err := config.Exchange(ctx, query.Get("code"), oauth2.VerifierOption(verifier))
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+	rawIDToken, _ := tokens.Extra("id_token").(string)
+	idToken, err := provider.Verifier(&gooidc.Config{ClientID: env.client.ClientId}).Verify(ctx, rawIDToken)
+	if err != nil {
+		t.Fatalf("Failed to verify the ID token: %v", err)
+	}
+	if idToken.Subject != env.user.UserId.String() || idToken.Nonce != "nonce-1" {
+		t.Errorf("Expected the user and nonce, got %s and %s", idToken.Subject, idToken.Nonce)
+	}
+
+	userInfo, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(tokens))
+	if err != nil {
+		t.Fatalf("UserInfo failed: %v", err)
+	}
+	var claims map[string]any
+	if err := userInfo.Claims(&claims); err != nil {
+		t.Fatalf("Failed to decode claims: %v", err)
+	}
+	want := map[string]any{
+		"sub":                   env.user.UserId.String(),
+		"name":                  "Ada Lovelace",
+		"given_name":            "Ada",
+		"family_name":           "Lovelace",
+		"email":                 testEmail,
+		"email_verified":        false,
+		"phone_number":          "+12025550123",
+		"phone_number_verified": false,
+	}
+	for name, value := range want {
+		if claims[name] != value {
+			t.Errorf("Expected %s to be %v, got %v", name, value, claims[name])
+		}
+	}
+
+	// an expired token makes the token source refresh
+	tokens.Expiry = time.Now().Add(-time.Minute)
+	refreshed, err := config.TokenSource(ctx, tokens).Token()
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if refreshed.RefreshToken == "" || refreshed.RefreshToken == tokens.RefreshToken {
+		t.Errorf("Expected a new refresh token")
+	}
+	if _, err := config.TokenSource(ctx, tokens).Token(); err == nil {
+		t.Errorf("Expected a spent refresh token to be turned away")
+	}
+
+	// the user is still signed in and has consented, so they go straight
+	// back to the client
+	verifier = oauth2.GenerateVerifier()
+	resp, _ := env.get(t, config.AuthCodeURL("state-2", oauth2.S256ChallengeOption(verifier)))
+	if code := callback(t, resp).Query().Get("code"); code == "" {
+		t.Errorf("Expected a code without signing in again")
+	}
+}
+
+func TestProvider_PublicClient(t *testing.T) {
+	env := newTestEnv(t)
+	client, err := env.provider.RegisterClient(t.Context(), "Single Page App", []string{redirectURI}, true)
+	if err != nil {
+		t.Fatalf("RegisterClient failed: %v", err)
+	}
+	if client.ClientSecret != "" || !client.Public {
+		t.Fatalf("Expected a public client without a secret, got %+v", client)
+	}
+	env.client = client
+	_, config := env.oauth2Config(t, gooidc.ScopeOpenID)
+
+	verifier := oauth2.GenerateVerifier()
+	location := env.signIn(t, config.AuthCodeURL("state", oauth2.S256ChallengeOption(verifier)))
+
+	// the code is used up by a failed attempt too
+	code := location.Query().Get("code")
+	if _, err := config.Exchange(t.Context(), code, oauth2.VerifierOption(oauth2.GenerateVerifier())); err == nil {
+		t.Fatalf("Expected the wrong code verifier to be turned away")
+	}
+	if _, err := config.Exchange(t.Context(), code, oauth2.VerifierOption(verifier)); err == nil {
+		t.Errorf("Expected a used code to be turned away")
+	}
+}
+
+func TestProvider_MFA(t *testing.T) {
+	env := newTestEnv(t)
+	enrolment, err := env.authenticator.EnrolTOTP(t.Context(), env.user.UserId)
+	if err != nil {
+		t.Fatalf("EnrolTOTP failed: %v", err)
+	}
+	code, err := totp.GenerateCode(enrolment.Secret, time.Now())
+	if err != nil {
+		t.Fatalf("GenerateCode failed: %v", err)
+	}
+	recoveryCodes, err := env.authenticator.ConfirmTOTP(t.Context(), env.user.UserId, code)
+	if err != nil {
+		t.Fatalf("ConfirmTOTP failed: %v", err)
+	}
+	_, config := env.oauth2Config(t, gooidc.ScopeOpenID)
+	authURL := config.AuthCodeURL("state", oauth2.S256ChallengeOption(oauth2.GenerateVerifier()))
+
+	resp, body := env.submit(t, "/login", authURL, url.Values{"email": {testEmail}, "password": {testPassword}})
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, `name="code"`) {
+		t.Fatalf("Expected the second factor page, got %d: %s", resp.StatusCode, body)
+	}
+	mfaToken := formValue(t, body, "mfa_token")
+
+	resp, _ = env.submit(t, "/login/mfa", authURL, url.Values{"mfa_token": {mfaToken}, "code": {"00000-00000"}})
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a wrong code, got %d", resp.StatusCode)
+	}
+
+	resp, body = env.submit(t, "/login/mfa", authURL, url.Values{"mfa_token": {mfaToken}, "code": {recoveryCodes[0]}})
+	if resp.StatusCode != http.StatusOK || !strings.Contains(body, "would like to") {
+		t.Errorf("Expected the consent page, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestProvider_WrongPassword(t *testing.T) {
+	env := newTestEnv(t)
+	_, config := env.oauth2Config(t, gooidc.ScopeOpenID)
+	authURL := config.AuthCodeURL("state", oauth2.S256ChallengeOption(oauth2.GenerateVerifier()))
+
+	resp, body := env.submit(t, "/login", authURL, url.Values{"email": {testEmail}, "password": {"wrong"}})
+	if resp.StatusCode != http.StatusUnauthorized || !strings.Contains(body, "Wrong email or password.") {
+		t.Errorf("Expected the login page with an error, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+func TestProvider_Consent(t *testing.T) {
+	env := newTestEnv(t)
+	_, config := env.oauth2Config(t, gooidc.ScopeOpenID)
+	authURL := config.AuthCodeURL("state", oauth2.S256ChallengeOption(oauth2.GenerateVerifier()))
+
+	_, body := env.submit(t, "/login", authURL, url.Values{"email": {testEmail}, "password": {testPassword}})
+	csrfToken := formValue(t, body, "csrf_token")
+
+	resp, _ := env.submit(t, "/consent", authURL, url.Values{"csrf_token": {"forged"}, "action": {"allow"}})
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a forged consent, got %d", resp.StatusCode)
+	}
+
+	resp, _ = env.submit(t, "/consent", authURL, url.Values{"csrf_token": {csrfToken}, "action": {"deny"}})
+	if got := callback(t, resp).Query().Get("error"); got != errAccessDenied {
+		t.Errorf("Expected %s, got %q", errAccessDenied, got)
+	}
+
+	// asking without a prompt tells the client consent is missing
+	resp, _ = env.get(t, authURL+"&prompt=none")
+	if got := callback(t, resp).Query().Get("error"); got != errConsentRequired {
+		t.Errorf("Expected %s, got %q", errConsentRequired, got)
+	}
+}
+
+func TestProvider_AuthorizeErrors(t *testing.T) {
+	env := newTestEnv(t)
+	_, config := env.oauth2Config(t, gooidc.ScopeOpenID)
+	challenge := oauth2.S256ChallengeOption(oauth2.GenerateVerifier())
+
+	pages := []struct {
+		name    string
+		authURL string
+	}{
+		{"unknown client", strings.Replace(config.AuthCodeURL("state", challenge), "client_id=", "client_id=x", 1)},
+		{"unregistered redirect URI", strings.Replace(config.AuthCodeURL("state", challenge), "callback", "elsewhere", 1)},
+	}
+	for _, tt := range pages {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, _ := env.get(t, tt.authURL)
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected an error page, got %d", resp.StatusCode)
+			}
+		})
+	}
+
+	redirects := []struct {
+		name    string
+		authURL string
+		want    string
+	}{
+		{"no PKCE", config.AuthCodeURL("state"), errInvalidRequest},
+		{"plain PKCE", config.AuthCodeURL("state", oauth2.SetAuthURLParam("code_challenge", "plain"), oauth2.SetAuthURLParam("code_challenge_method", "plain")), errInvalidRequest},
+		{"no openid scope", strings.Replace(config.AuthCodeURL("state", challenge), "scope=openid", "scope=email", 1), errInvalidScope},
+		{"token response type", strings.Replace(config.AuthCodeURL("state", challenge), "response_type=code", "response_type=token", 1), errUnsupportedResponseType},
+		{"not signed in", config.AuthCodeURL("state", challenge, oauth2.SetAuthURLParam("prompt", "none")), errLoginRequired},
+	}
+	for _, tt := range redirects {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, _ := env.get(t, tt.authURL)
+			location := callback(t, resp)
+			if got := location.Query().Get("error"); got != tt.want || location.Query().Get("state") != "state" {
+				t.Errorf("Expected %s with the state, got %s", tt.want, location)
+			}
+		})
+	}
+}
+
+func TestProvider_ClientAuthentication(t *testing.T) {
+	env := newTestEnv(t)
+	_, config := env.oauth2Config(t, gooidc.ScopeOpenID)
+	config.ClientSecret = "wrong"
+
+	verifier := oauth2.GenerateVerifier()
+	location := env.signIn(t, config.AuthCodeURL("state", oauth2.S256ChallengeOption(verifier)))
+
+	_, err := config.Exchange(t.Context(), location.Query().Get("code"), oauth2.VerifierOption(verifier))
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) || retrieveErr.ErrorCode != errInvalidClient {
+		t.Errorf("Expected %s, got %v", errInvalidClient, err)
+	}
+}
+
+func TestProvider_UserinfoRejectsIDToken(t *testing.T) {
+	env := newTestEnv(t)
+	provider, config := env.oauth2Config(t, gooidc.ScopeOpenID)
+
+	verifier := oauth2.GenerateVerifier()
+	location := env.signIn(t, config.AuthCodeURL("state", oauth2.S256ChallengeOption(verifier)))
+	tokens, err := config.Exchange(t.Context(), location.Query().Get("code"), oauth2.VerifierOption(verifier))
+	if err != nil {
+		t.Fatalf("Exchange failed: %v", err)
+	}
+
+	idToken := &oauth2.Token{AccessToken: tokens.Extra("id_token").(string), TokenType: "Bearer"}
+	if _, err := provider.UserInfo(t.Context(), oauth2.StaticTokenSource(idToken)); err == nil {
+		t.Errorf("Expected an ID token to be turned away as an access token")
+	}
+
+	// deactivated users lose access
+	env.user.Status = model.StatusInactive
+	if _, _, err := env.store.UpdateUser(t.Context(), env.user, env.user.UserId); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if _, err := provider.UserInfo(t.Context(), oauth2.StaticTokenSource(tokens)); err == nil {
+		t.Errorf("Expected the access token of a deactivated user to be turned away")
+	}
+}
@@ -0,0 +1,286 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tenant"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// accessTokenType marks access tokens as such (RFC 9068), so that an ID token
+// is not accepted in their place.
+const accessTokenType = "at+jwt"
+
+// accessClaims are the claims of the access tokens clients call the userinfo
+// endpoint with.
+type accessClaims struct {
+	ClientId string `json:"client_id"`
+	Scope    string `json:"scope"`
+	// TenantId is left out for the default tenant.
+	TenantId string `json:"tid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	IDToken      string `json:"id_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope"`
+}
+
+func (provider *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, "", badRequest(errInvalidRequest, "The request body is malformed"))
+		return
+	}
+
+	ctx, client, err := provider.authenticateClient(r)
+	if err != nil {
+		writeError(w, r, "failed to authenticate client", err)
+		return
+	}
+
+	var response tokenResponse
+	switch grantType := r.PostForm.Get("grant_type"); grantType {
+	case "authorization_code":
+		response, err = provider.redeemCode(ctx, client, r)
+	case "refresh_token":
+		response, err = provider.refresh(ctx, client, r)
+	default:
+		err = badRequest(errUnsupportedGrantType, fmt.Sprintf("Grant type %q is not supported", grantType))
+	}
+	if err != nil {
+		writeError(w, r, "failed to issue tokens", err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, response)
+}
+
+// redeemCode swaps an authorization code for tokens. The code is used up
+// whatever goes wrong, so that a stolen code cannot be tried twice.
+func (provider *Provider) redeemCode(ctx context.Context, client model.OAuthClient, r *http.Request) (tokenResponse, error) {
+	invalidGrant := badRequest(errInvalidGrant, "The authorization code is invalid or expired")
+
+	tenantId, codeId, err := provider.signer.Verify(codePurpose, r.PostForm.Get("code"))
+	if err != nil || tenantId != tenant.FromContext(ctx) {
+		return tokenResponse{}, invalidGrant
+	}
+	code, ok, err := provider.store.ConsumeAuthorizationCode(ctx, codeId)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	if !ok || code.ClientId != client.ClientId || !provider.now().Before(code.ExpiresAt) {
+		return tokenResponse{}, invalidGrant
+	}
+	if r.PostForm.Get("redirect_uri") != code.RedirectURI {
+		return tokenResponse{}, badRequest(errInvalidGrant, "The redirect URI does not match the authorization request")
+	}
+	if !verifyChallenge(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+		return tokenResponse{}, badRequest(errInvalidGrant, "The code verifier does not match the code challenge")
+	}
+
+	user, err := provider.activeUser(ctx, code.UserId)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	scopes := parseScope(code.Scope)
+
+	var refreshToken string
+	if slices.Contains(scopes, ScopeOfflineAccess) {
+		now := provider.now().UTC()
+		stored := model.OAuthRefreshToken{
+			TokenId:   uuid.New(),
+			ClientId:  client.ClientId,
+			UserId:    user.UserId,
+			Scope:     code.Scope,
+			CreatedAt: now,
+			ExpiresAt: now.Add(provider.opts.RefreshTokenTTL),
+		}
+		if err := provider.store.CreateOAuthRefreshToken(ctx, stored); err != nil {
+			return tokenResponse{}, fmt.Errorf("failed to store refresh token: %w", err)
+		}
+		refreshToken = provider.signer.Sign(refreshTokenPurpose, tenant.FromContext(ctx), stored.TokenId)
+	}
+
+	return provider.issue(ctx, client, user, scopes, code.Nonce, refreshToken)
+}
+
+// refresh swaps a refresh token for new tokens and a new refresh token. The
+// client may ask for fewer scopes than it was granted.
+func (provider *Provider) refresh(ctx context.Context, client model.OAuthClient, r *http.Request) (tokenResponse, error) {
+	invalidGrant := badRequest(errInvalidGrant, "The refresh token is invalid or expired")
+
+	tenantId, tokenId, err := provider.signer.Verify(refreshTokenPurpose, r.PostForm.Get("refresh_token"))
+	if err != nil || tenantId != tenant.FromContext(ctx) {
+		return tokenResponse{}, invalidGrant
+	}
+
+	newTokenId := uuid.New()
+	stored, err := provider.store.RotateOAuthRefreshToken(ctx, tokenId, newTokenId, client.ClientId, provider.now().UTC())
+	if errors.Is(err, store.ErrRefreshTokenNotFound) || errors.Is(err, store.ErrRefreshTokenExpired) {
+		return tokenResponse{}, invalidGrant
+	}
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	scopes := parseScope(stored.Scope)
+	if scope := r.PostForm.Get("scope"); scope != "" {
+		requested := parseScope(scope)
+		if !covers(scopes, requested) {
+			return tokenResponse{}, badRequest(errInvalidScope, "The requested scope exceeds the granted scope")
+		}
+		scopes = requested
+	}
+
+	user, err := provider.activeUser(ctx, stored.UserId)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+	return provider.issue(ctx, client, user, scopes, "", provider.signer.Sign(refreshTokenPurpose, tenantId, newTokenId))
+}
+
+// activeUser returns the user tokens are issued for, or invalid_grant if
+// they have since been deleted or deactivated.
+func (provider *Provider) activeUser(ctx context.Context, userId uuid.UUID) (model.User, error) {
+	user, ok, err := provider.store.GetUserById(ctx, userId)
+	if err != nil {
+		return model.User{}, err
+	}
+	if !ok || user.Status != model.StatusActive {
+		return model.User{}, badRequest(errInvalidGrant, "The user can no longer sign in")
+	}
+	return user, nil
+}
+
+// issue signs an ID token and an access token for the user.
+func (provider *Provider) issue(ctx context.Context, client model.OAuthClient, user model.User, scopes []string, nonce, refreshToken string) (tokenResponse, error) {
+	key, err := provider.keys.current(ctx)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	now := provider.now()
+	clientId := provider.clientID(ctx, client.ClientId)
+	expiresAt := jwt.NewNumericDate(now.Add(provider.opts.AccessTokenTTL))
+
+	idClaims := jwt.MapClaims(userClaims(user, scopes))
+	idClaims["iss"] = provider.opts.Issuer
+	idClaims["aud"] = clientId
+	idClaims["iat"] = jwt.NewNumericDate(now)
+	idClaims["exp"] = expiresAt
+	if nonce != "" {
+		idClaims["nonce"] = nonce
+	}
+	idToken, err := sign(key, idClaims, "JWT")
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	scope := strings.Join(scopes, " ")
+	accessClaims := accessClaims{
+		ClientId: clientId,
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    provider.opts.Issuer,
+			Subject:   user.UserId.String(),
+			Audience:  jwt.ClaimStrings{provider.opts.Issuer},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: expiresAt,
+			ID:        uuid.NewString(),
+		},
+	}
+	if tenantId := tenant.FromContext(ctx); tenantId != tenant.Default {
+		accessClaims.TenantId = tenantId.String()
+	}
+	accessToken, err := sign(key, accessClaims, accessTokenType)
+	if err != nil {
+		return tokenResponse{}, err
+	}
+
+	return tokenResponse{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(provider.opts.AccessTokenTTL.Seconds()),
+		IDToken:      idToken,
+		RefreshToken: refreshToken,
+		Scope:        scope,
+	}, nil
+}
+
+// parseAccessToken returns the claims of an access token issued by the
+// provider, if it has not expired.
+func (provider *Provider) parseAccessToken(ctx context.Context, accessToken string) (accessClaims, error) {
+	var claims accessClaims
+	_, err := jwt.ParseWithClaims(accessToken, &claims,
+		func(token *jwt.Token) (any, error) {
+			if token.Header["typ"] != accessTokenType {
+				return nil, errors.New("not an access token")
+			}
+			keyId, _ := token.Header["kid"].(string)
+			return provider.keys.lookup(ctx, keyId)
+		},
+		jwt.WithValidMethods([]string{signingAlgorithm}),
+		jwt.WithIssuer(provider.opts.Issuer),
+		jwt.WithAudience(provider.opts.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(provider.now),
+	)
+	return claims, err
+}
+
+func (provider *Provider) revoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, r, "", badRequest(errInvalidRequest, "The request body is malformed"))
+		return
+	}
+
+	ctx, client, err := provider.authenticateClient(r)
+	if err != nil {
+		writeError(w, r, "failed to authenticate client", err)
+		return
+	}
+
+	// access tokens cannot be revoked, and unknown tokens are not an error,
+	// so only refresh tokens of the client are acted on (RFC 7009)
+	tenantId, tokenId, err := provider.signer.Verify(refreshTokenPurpose, r.PostForm.Get("token"))
+	if err == nil && tenantId == tenant.FromContext(ctx) {
+		if _, err := provider.store.RevokeOAuthRefreshToken(ctx, tokenId, client.ClientId); err != nil {
+			writeError(w, r, "failed to revoke refresh token", err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func sign(key signingKey, claims jwt.Claims, tokenType string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.id
+	token.Header["typ"] = tokenType
+	return token.SignedString(key.key)
+}
+
+// verifyChallenge reports whether verifier hashes to the S256 challenge.
+func verifyChallenge(verifier, challenge string) bool {
+	// RFC 7636 verifiers are 43 to 128 characters long
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	hash := sha256.Sum256([]byte(verifier))
+	return subtle.ConstantTimeCompare([]byte(base64.RawURLEncoding.EncodeToString(hash[:])), []byte(challenge)) == 1
+}
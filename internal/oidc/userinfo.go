@@ -0,0 +1,57 @@
+package oidc
+
+import (
+	"net/http"
+	"strings"
+
+	"example.com/user-management/internal/tenant"
+	"github.com/google/uuid"
+)
+
+// userinfo returns the claims the scopes of the access token grant about its
+// user. Tokens are only taken from the Authorization header.
+func (provider *Provider) userinfo(w http.ResponseWriter, r *http.Request) {
+	accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		writeError(w, r, "", &Error{Status: http.StatusUnauthorized, Code: errInvalidRequest, Description: "An access token is required"})
+		return
+	}
+
+	claims, err := provider.parseAccessToken(r.Context(), accessToken)
+	if err != nil {
+		provider.invalidToken(w, r)
+		return
+	}
+	userId, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		provider.invalidToken(w, r)
+		return
+	}
+	tenantId := tenant.Default
+	if claims.TenantId != "" {
+		if tenantId, err = uuid.Parse(claims.TenantId); err != nil {
+			provider.invalidToken(w, r)
+			return
+		}
+	}
+
+	ctx := tenant.WithID(r.Context(), tenantId)
+	user, err := provider.activeUser(ctx, userId)
+	if err != nil {
+		if _, ok := err.(*Error); ok {
+			provider.invalidToken(w, r)
+			return
+		}
+		writeError(w, r, "failed to look up user", err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, r, http.StatusOK, userClaims(user, parseScope(claims.Scope)))
+}
+
+func (provider *Provider) invalidToken(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	writeError(w, r, "", &Error{Status: http.StatusUnauthorized, Code: errInvalidToken, Description: "The access token is invalid or expired"})
+}
@@ -0,0 +1,100 @@
+// Package phonenumber parses phone numbers with libphonenumber's rules, so
+// that a number is stored in one form, E.164, however it was entered.
+package phonenumber
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nyaruka/phonenumbers"
+)
+
+// ErrInvalid is returned for numbers that do not parse, or that are not valid
+// numbers of the region they belong to.
+var ErrInvalid = errors.New("invalid phone number")
+
+// Type is the kind of line a number reaches.
+type Type string
+
+const (
+	TypeMobile Type = "mobile"
+	TypeFixed  Type = "fixed"
+	// TypeFixedOrMobile is the type of numbers in regions, such as the US,
+	// whose fixed and mobile numbers cannot be told apart.
+	TypeFixedOrMobile Type = "fixedOrMobile"
+	TypeTollFree      Type = "tollFree"
+	TypePremiumRate   Type = "premiumRate"
+	TypeSharedCost    Type = "sharedCost"
+	TypeVoIP          Type = "voip"
+	TypePersonal      Type = "personal"
+	TypePager         Type = "pager"
+	TypeUAN           Type = "uan"
+	TypeVoicemail     Type = "voicemail"
+	TypeUnknown       Type = "unknown"
+)
+
+var types = map[phonenumbers.PhoneNumberType]Type{
+	phonenumbers.MOBILE:               TypeMobile,
+	phonenumbers.FIXED_LINE:           TypeFixed,
+	phonenumbers.FIXED_LINE_OR_MOBILE: TypeFixedOrMobile,
+	phonenumbers.TOLL_FREE:            TypeTollFree,
+	phonenumbers.PREMIUM_RATE:         TypePremiumRate,
+	phonenumbers.SHARED_COST:          TypeSharedCost,
+	phonenumbers.VOIP:                 TypeVoIP,
+	phonenumbers.PERSONAL_NUMBER:      TypePersonal,
+	phonenumbers.PAGER:                TypePager,
+	phonenumbers.UAN:                  TypeUAN,
+	phonenumbers.VOICEMAIL:            TypeVoicemail,
+}
+
+// Parser reads numbers written in international format, starting with + and
+// the country code, and those written in the national format of its
+// DefaultRegion.
+type Parser struct {
+	// DefaultRegion is the ISO 3166-1 alpha-2 code, such as "LK", of the
+	// region national numbers belong to. Without one only international
+	// numbers parse.
+	DefaultRegion string
+}
+
+// Normalize returns number in E.164 format, or an error wrapping ErrInvalid
+// if it does not parse or is not a valid number of its region. Spaces,
+// dashes and brackets are allowed wherever they are usually written.
+func (parser Parser) Normalize(number string) (string, error) {
+	parsed, err := phonenumbers.Parse(number, strings.ToUpper(parser.DefaultRegion))
+	if err != nil {
+		return "", fmt.Errorf("%w %q: %w", ErrInvalid, number, err)
+	}
+	if !phonenumbers.IsValidNumber(parsed) {
+		return "", fmt.Errorf("%w %q: not a number of %s", ErrInvalid, number, regionName(parsed))
+	}
+	return phonenumbers.Format(parsed, phonenumbers.E164), nil
+}
+
+// Describe returns the region, as an ISO 3166-1 alpha-2 code, and the type
+// of a number in E.164 format. Both are empty if it does not parse.
+func Describe(number string) (string, Type) {
+	parsed, err := phonenumbers.Parse(number, "")
+	if err != nil {
+		return "", ""
+	}
+	numberType, ok := types[phonenumbers.GetNumberType(parsed)]
+	if !ok {
+		numberType = TypeUnknown
+	}
+	return phonenumbers.GetRegionCodeForNumber(parsed), numberType
+}
+
+// ValidRegion reports whether region is a region code numbers can be
+// parsed in.
+func ValidRegion(region string) bool {
+	return phonenumbers.GetSupportedRegions()[strings.ToUpper(region)]
+}
+
+func regionName(number *phonenumbers.PhoneNumber) string {
+	if region := phonenumbers.GetRegionCodeForNumber(number); region != "" && region != "ZZ" {
+		return region
+	}
+	return fmt.Sprintf("country code %d", number.GetCountryCode())
+}
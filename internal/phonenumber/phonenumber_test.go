@@ -0,0 +1,80 @@
+package phonenumber
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		region string
+		number string
+		want   string
+	}{
+		{"", "+94712345678", "+94712345678"},
+		{"", "+1 (202) 555-0123", "+12025550123"},
+		{"LK", "071 234 5678", "+94712345678"},
+		{"lk", "0712345678", "+94712345678"},
+		{"LK", "+44 20 7183 8750", "+442071838750"},
+		{"US", "202-555-0123", "+12025550123"},
+	}
+
+	for _, test := range tests {
+		got, err := Parser{DefaultRegion: test.region}.Normalize(test.number)
+		if err != nil {
+			t.Errorf("Normalize(%q) in %q failed: %v", test.number, test.region, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Normalize(%q) in %q = %q, want %q", test.number, test.region, got, test.want)
+		}
+	}
+}
+
+func TestNormalize_Invalid(t *testing.T) {
+	tests := []struct {
+		region string
+		number string
+	}{
+		{"", "0712345678"},
+		{"LK", ""},
+		{"LK", "not a number"},
+		{"US", "+1 555 555 0100"},
+		{"LK", "071 234"},
+	}
+
+	for _, test := range tests {
+		if _, err := (Parser{DefaultRegion: test.region}).Normalize(test.number); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Normalize(%q) in %q: expected ErrInvalid, got %v", test.number, test.region, err)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		number     string
+		region     string
+		numberType Type
+	}{
+		{"+94712345678", "LK", TypeMobile},
+		{"+442071838750", "GB", TypeFixed},
+		{"+12025550123", "US", TypeFixedOrMobile},
+		{"not a number", "", ""},
+	}
+
+	for _, test := range tests {
+		region, numberType := Describe(test.number)
+		if region != test.region || numberType != test.numberType {
+			t.Errorf("Describe(%q) = %q, %q, want %q, %q", test.number, region, numberType, test.region, test.numberType)
+		}
+	}
+}
+
+func TestValidRegion(t *testing.T) {
+	if !ValidRegion("LK") || !ValidRegion("us") {
+		t.Errorf("Expected LK and us to be valid regions")
+	}
+	if ValidRegion("XX") || ValidRegion("") {
+		t.Errorf("Expected XX and the empty region to be invalid")
+	}
+}
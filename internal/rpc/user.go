@@ -0,0 +1,314 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"example.com/user-management/internal/attributes"
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/emailaddr"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/mapper"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/rpc/userpb"
+	"example.com/user-management/internal/store"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var validate = validator.New()
+
+// Authorizer decides who may make the calls only admins may make.
+type Authorizer interface {
+	// AuthorizeAdmin returns auth.ErrAdminRequired or
+	// auth.ErrAdminMFARequired unless the call ctx belongs to was made by an
+	// admin who logged in with a second factor.
+	AuthorizeAdmin(ctx context.Context) error
+}
+
+type UserServer struct {
+	userpb.UnimplementedUserServiceServer
+	store      store.UserStoreInterface
+	authorizer Authorizer
+}
+
+// NewUserServer serves store. Calls only admins may make, such as deleting
+// users, are checked with authorizer against the claims of the call's access
+// token, which must be in its context.
+func NewUserServer(store store.UserStoreInterface, authorizer Authorizer) *UserServer {
+	return &UserServer{
+		store:      store,
+		authorizer: authorizer,
+	}
+}
+
+func (server *UserServer) CreateUser(ctx context.Context, req *userpb.CreateUserRequest) (*userpb.User, error) {
+	createReq := dto.CreateUserRequest{
+		FirstName: req.GetFirstName(),
+		LastName:  req.GetLastName(),
+		Email:     strings.TrimSpace(req.GetEmail()),
+		Phone:     req.GetPhone(),
+		Age:       int(req.GetAge()),
+		Status:    statusFromProto(req.GetStatus()),
+	}
+	if req.GetAttributes() != nil {
+		createReq.Attributes = req.GetAttributes().AsMap()
+	}
+
+	if err := validate.Struct(createReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	createdUser, err := server.store.CreateUser(ctx, mapper.CreateUserRequestToModel(createReq))
+	if err != nil {
+		return nil, storeError(ctx, err, "failed to create user")
+	}
+
+	return userToProto(createdUser), nil
+}
+
+func (server *UserServer) GetUser(ctx context.Context, req *userpb.GetUserRequest) (*userpb.User, error) {
+	userId, err := parseUserId(req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	user, ok, err := server.store.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, storeError(ctx, err, "failed to retrieve user")
+	}
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	return userToProto(user), nil
+}
+
+func (server *UserServer) ListUsers(_ *userpb.ListUsersRequest, stream userpb.UserService_ListUsersServer) error {
+	users, err := server.store.GetAllUsers(stream.Context())
+	if err != nil {
+		return storeError(stream.Context(), err, "failed to retrieve users")
+	}
+
+	for _, user := range users {
+		if err := stream.Send(userToProto(user)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (server *UserServer) UpdateUser(ctx context.Context, req *userpb.UpdateUserRequest) (*userpb.User, error) {
+	userId, err := parseUserId(req.GetUser().GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	updateReq, err := updateRequestFromMask(req.GetUser(), req.GetUpdateMask().GetPaths())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validate.Struct(updateReq); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	user, ok, err := server.store.GetUserById(ctx, userId)
+	if err != nil {
+		return nil, storeError(ctx, err, "failed to retrieve user")
+	}
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	mapper.ApplyUpdateUserRequest(&user, updateReq)
+	updatedUser, ok, err := server.store.UpdateUser(ctx, user, userId)
+	if err != nil {
+		return nil, storeError(ctx, err, "failed to update user")
+	}
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	return userToProto(updatedUser), nil
+}
+
+func (server *UserServer) DeleteUser(ctx context.Context, req *userpb.DeleteUserRequest) (*emptypb.Empty, error) {
+	userId, err := parseUserId(req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	// deleting users is for admins, as on the REST API
+	if err := server.authorizer.AuthorizeAdmin(ctx); err != nil {
+		return nil, adminError(ctx, err)
+	}
+
+	ok, err := server.store.DeleteUser(ctx, userId)
+	if err != nil {
+		return nil, storeError(ctx, err, "failed to delete user")
+	}
+	if !ok {
+		return nil, status.Error(codes.NotFound, "user not found")
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// updateRequestFromMask builds the same partial update the REST PATCH handler
+// uses, taking only the fields named in the mask. An empty mask takes every
+// field that is set, "*" takes all of them.
+func updateRequestFromMask(user *userpb.User, paths []string) (dto.UpdateUserRequest, error) {
+	var req dto.UpdateUserRequest
+
+	if len(paths) == 0 {
+		if user.GetFirstName() != "" {
+			paths = append(paths, "first_name")
+		}
+		if user.GetLastName() != "" {
+			paths = append(paths, "last_name")
+		}
+		if user.GetEmail() != "" {
+			paths = append(paths, "email")
+		}
+		if user.GetPhone() != "" {
+			paths = append(paths, "phone")
+		}
+		if user.GetAge() != 0 {
+			paths = append(paths, "age")
+		}
+		if user.GetStatus() != userpb.Status_STATUS_UNSPECIFIED {
+			paths = append(paths, "status")
+		}
+		if user.GetAttributes() != nil {
+			paths = append(paths, "attributes")
+		}
+	} else if len(paths) == 1 && paths[0] == "*" {
+		paths = []string{"first_name", "last_name", "email", "phone", "age", "status", "attributes"}
+	}
+
+	for _, path := range paths {
+		switch path {
+		case "first_name":
+			firstName := user.GetFirstName()
+			req.FirstName = &firstName
+		case "last_name":
+			lastName := user.GetLastName()
+			req.LastName = &lastName
+		case "email":
+			email := strings.TrimSpace(user.GetEmail())
+			req.Email = &email
+		case "phone":
+			phone := user.GetPhone()
+			req.Phone = &phone
+		case "age":
+			age := int(user.GetAge())
+			req.Age = &age
+		case "status":
+			if user.GetStatus() == userpb.Status_STATUS_UNSPECIFIED {
+				return dto.UpdateUserRequest{}, status.Error(codes.InvalidArgument, "status cannot be unspecified")
+			}
+			userStatus := statusFromProto(user.GetStatus())
+			req.Status = &userStatus
+		case "attributes":
+			req.Attributes = user.GetAttributes().AsMap()
+		default:
+			return dto.UpdateUserRequest{}, status.Errorf(codes.InvalidArgument, "unknown update_mask path %q", path)
+		}
+	}
+
+	return req, nil
+}
+
+func parseUserId(userId string) (uuid.UUID, error) {
+	parsedId, err := uuid.Parse(userId)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "invalid user id")
+	}
+	return parsedId, nil
+}
+
+// storeError maps a store failure to a status. Unexpected failures are logged
+// with their cause chain and reported only by message.
+func storeError(ctx context.Context, err error, message string) error {
+	if errors.Is(err, store.ErrDuplicateEmail) {
+		return status.Error(codes.AlreadyExists, err.Error())
+	}
+	if errors.Is(err, attributes.ErrInvalid) || errors.Is(err, emailaddr.ErrInvalid) || errors.Is(err, phonenumber.ErrInvalid) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	logging.FromContext(ctx).Error(message, logging.Err(err))
+	return status.Error(codes.Internal, message)
+}
+
+// adminError maps a failed admin check to a status. Calls without an access
+// token are unauthenticated rather than denied.
+func adminError(ctx context.Context, err error) error {
+	if errors.Is(err, auth.ErrAdminRequired) {
+		if _, ok := auth.ClaimsFromContext(ctx); !ok {
+			return status.Error(codes.Unauthenticated, "access token required")
+		}
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	if errors.Is(err, auth.ErrAdminMFARequired) {
+		return status.Error(codes.PermissionDenied, err.Error())
+	}
+	logging.FromContext(ctx).Error("failed to check admin access", logging.Err(err))
+	return status.Error(codes.Internal, "failed to check admin access")
+}
+
+func userToProto(user model.User) *userpb.User {
+	return &userpb.User{
+		UserId:       user.UserId.String(),
+		FirstName:    user.FirstName,
+		LastName:     user.LastName,
+		Email:        user.Email,
+		Phone:        user.Phone,
+		Age:          int32(user.Age),
+		Status:       statusToProto(user.Status),
+		PhoneCountry: user.PhoneCountry,
+		PhoneType:    user.PhoneType,
+		Attributes:   attributesToProto(user.Attributes),
+	}
+}
+
+// attributesToProto converts custom attributes to a Struct. They are decoded
+// from JSON, so every value has a Struct counterpart.
+func attributesToProto(attrs map[string]any) *structpb.Struct {
+	if len(attrs) == 0 {
+		return nil
+	}
+	s, err := structpb.NewStruct(attrs)
+	if err != nil {
+		return nil
+	}
+	return s
+}
+
+func statusToProto(s model.Status) userpb.Status {
+	switch s {
+	case model.StatusActive:
+		return userpb.Status_STATUS_ACTIVE
+	case model.StatusInactive:
+		return userpb.Status_STATUS_INACTIVE
+	}
+	return userpb.Status_STATUS_UNSPECIFIED
+}
+
+func statusFromProto(s userpb.Status) model.Status {
+	switch s {
+	case userpb.Status_STATUS_ACTIVE:
+		return model.StatusActive
+	case userpb.Status_STATUS_INACTIVE:
+		return model.StatusInactive
+	}
+	return ""
+}
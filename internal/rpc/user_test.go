@@ -0,0 +1,370 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/rpc/userpb"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type MockUserStore struct {
+	CreateUserFn    func(model.User) (model.User, error)
+	GetAllUsersFn   func() ([]model.User, error)
+	GetUserByIdFn   func(uuid.UUID) (model.User, bool, error)
+	GetUsersByIdsFn func([]uuid.UUID) ([]model.User, error)
+	ListUsersFn     func(model.UserFilter, uuid.UUID, int) ([]model.User, error)
+	UpdateUserFn    func(model.User, uuid.UUID) (model.User, bool, error)
+	PutUserFn       func(model.User, model.Precondition) (model.User, bool, error)
+	ModifyUserFn    func(uuid.UUID, model.Precondition, func(*model.User) error) (model.User, bool, error)
+	DeleteUserFn    func(uuid.UUID) (bool, error)
+}
+
+func (m *MockUserStore) CreateUser(_ context.Context, u model.User) (model.User, error) {
+	return m.CreateUserFn(u)
+}
+func (m *MockUserStore) GetAllUsers(_ context.Context) ([]model.User, error) {
+	return m.GetAllUsersFn()
+}
+func (m *MockUserStore) GetUserById(_ context.Context, id uuid.UUID) (model.User, bool, error) {
+	return m.GetUserByIdFn(id)
+}
+func (m *MockUserStore) GetUsersByIds(_ context.Context, ids []uuid.UUID) ([]model.User, error) {
+	return m.GetUsersByIdsFn(ids)
+}
+func (m *MockUserStore) ListUsers(_ context.Context, f model.UserFilter, after uuid.UUID, limit int) ([]model.User, error) {
+	return m.ListUsersFn(f, after, limit)
+}
+func (m *MockUserStore) UpdateUser(_ context.Context, u model.User, id uuid.UUID) (model.User, bool, error) {
+	return m.UpdateUserFn(u, id)
+}
+func (m *MockUserStore) PutUser(_ context.Context, u model.User, p model.Precondition) (model.User, bool, error) {
+	return m.PutUserFn(u, p)
+}
+func (m *MockUserStore) ModifyUser(_ context.Context, id uuid.UUID, p model.Precondition, modify func(*model.User) error) (model.User, bool, error) {
+	return m.ModifyUserFn(id, p, modify)
+}
+func (m *MockUserStore) DeleteUser(_ context.Context, id uuid.UUID) (bool, error) {
+	return m.DeleteUserFn(id)
+}
+
+// stubAuthorizer answers every admin check with err.
+type stubAuthorizer struct {
+	err error
+}
+
+func (a stubAuthorizer) AuthorizeAdmin(context.Context) error {
+	return a.err
+}
+
+// newTestClient calls userStore as an admin.
+func newTestClient(t *testing.T, userStore store.UserStoreInterface) userpb.UserServiceClient {
+	t.Helper()
+	return newTestClientAs(t, userStore, stubAuthorizer{})
+}
+
+func newTestClientAs(t *testing.T, userStore store.UserStoreInterface, authorizer Authorizer) userpb.UserServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	grpcServer := grpc.NewServer()
+	userpb.RegisterUserServiceServer(grpcServer, NewUserServer(userStore, authorizer))
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return userpb.NewUserServiceClient(conn)
+}
+
+func assertCode(t *testing.T, err error, expected codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != expected {
+		t.Fatalf("expected %s, got %s (%v)", expected, got, err)
+	}
+}
+
+// unit tests for CreateUser
+func TestCreateUser_Success(t *testing.T) {
+	client := newTestClient(t, &MockUserStore{
+		CreateUserFn: func(user model.User) (model.User, error) {
+			user.UserId = uuid.New()
+			return user, nil
+		},
+	})
+
+	user, err := client.CreateUser(context.Background(), &userpb.CreateUserRequest{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john@gmail.com",
+		Phone:     "+94712345678",
+		Age:       27,
+		Status:    userpb.Status_STATUS_ACTIVE,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.GetStatus() != userpb.Status_STATUS_ACTIVE {
+		t.Fatalf("expected STATUS_ACTIVE, got %s", user.GetStatus())
+	}
+}
+
+func TestCreateUser_InvalidArgument(t *testing.T) {
+	client := newTestClient(t, &MockUserStore{})
+
+	_, err := client.CreateUser(context.Background(), &userpb.CreateUserRequest{
+		FirstName: "J",
+		Email:     "invalid-email",
+		Phone:     "0712345678",
+	})
+	assertCode(t, err, codes.InvalidArgument)
+}
+
+func TestCreateUser_AlreadyExists(t *testing.T) {
+	client := newTestClient(t, &MockUserStore{
+		CreateUserFn: func(model.User) (model.User, error) {
+			return model.User{}, store.ErrDuplicateEmail
+		},
+	})
+
+	_, err := client.CreateUser(context.Background(), &userpb.CreateUserRequest{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john@gmail.com",
+		Phone:     "+94712345678",
+	})
+	assertCode(t, err, codes.AlreadyExists)
+}
+
+func TestCreateUser_Internal(t *testing.T) {
+	client := newTestClient(t, &MockUserStore{
+		CreateUserFn: func(model.User) (model.User, error) {
+			return model.User{}, errors.New("connection refused")
+		},
+	})
+
+	_, err := client.CreateUser(context.Background(), &userpb.CreateUserRequest{
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john@gmail.com",
+		Phone:     "+94712345678",
+	})
+	assertCode(t, err, codes.Internal)
+}
+
+// unit tests for GetUser
+func TestGetUser_NotFound(t *testing.T) {
+	client := newTestClient(t, &MockUserStore{
+		GetUserByIdFn: func(uuid.UUID) (model.User, bool, error) {
+			return model.User{}, false, nil
+		},
+	})
+
+	_, err := client.GetUser(context.Background(), &userpb.GetUserRequest{UserId: uuid.NewString()})
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestGetUser_InvalidId(t *testing.T) {
+	client := newTestClient(t, &MockUserStore{})
+
+	_, err := client.GetUser(context.Background(), &userpb.GetUserRequest{UserId: "invalid-id"})
+	assertCode(t, err, codes.InvalidArgument)
+}
+
+// unit tests for ListUsers
+func TestListUsers_Streams(t *testing.T) {
+	client := newTestClient(t, &MockUserStore{
+		GetAllUsersFn: func() ([]model.User, error) {
+			return []model.User{
+				{UserId: uuid.New(), FirstName: "John"},
+				{UserId: uuid.New(), FirstName: "Jane"},
+			}, nil
+		},
+	})
+
+	stream, err := client.ListUsers(context.Background(), &userpb.ListUsersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var received int
+	for {
+		_, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		received++
+	}
+
+	if received != 2 {
+		t.Fatalf("expected 2 users, got %d", received)
+	}
+}
+
+// unit tests for UpdateUser
+func TestUpdateUser_FieldMask(t *testing.T) {
+	id := uuid.New()
+
+	client := newTestClient(t, &MockUserStore{
+		GetUserByIdFn: func(uuid.UUID) (model.User, bool, error) {
+			return model.User{
+				UserId:    id,
+				FirstName: "John",
+				LastName:  "Doe",
+				Email:     "john@gmail.com",
+				Phone:     "+94712345678",
+				Status:    model.StatusActive,
+			}, true, nil
+		},
+		UpdateUserFn: func(u model.User, _ uuid.UUID) (model.User, bool, error) {
+			return u, true, nil
+		},
+	})
+
+	user, err := client.UpdateUser(context.Background(), &userpb.UpdateUserRequest{
+		User: &userpb.User{
+			UserId:    id.String(),
+			FirstName: "Updated",
+			LastName:  "Ignored",
+		},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"first_name"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.GetFirstName() != "Updated" || user.GetLastName() != "Doe" {
+		t.Fatalf("expected only first_name to change, got %s %s", user.GetFirstName(), user.GetLastName())
+	}
+}
+
+func TestUser_Attributes(t *testing.T) {
+	id := uuid.New()
+	var stored model.User
+
+	client := newTestClient(t, &MockUserStore{
+		CreateUserFn: func(user model.User) (model.User, error) {
+			user.UserId = id
+			stored = user
+			return user, nil
+		},
+		GetUserByIdFn: func(uuid.UUID) (model.User, bool, error) {
+			return stored, true, nil
+		},
+		UpdateUserFn: func(u model.User, _ uuid.UUID) (model.User, bool, error) {
+			stored = u
+			return u, true, nil
+		},
+	})
+
+	attrs, err := structpb.NewStruct(map[string]any{"department": "Sales", "level": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, err := client.CreateUser(context.Background(), &userpb.CreateUserRequest{
+		FirstName:  "John",
+		LastName:   "Doe",
+		Email:      "john@gmail.com",
+		Phone:      "+94712345678",
+		Status:     userpb.Status_STATUS_ACTIVE,
+		Attributes: attrs,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := stored.Attributes["department"]; got != "Sales" {
+		t.Fatalf("expected department Sales to be stored, got %v", got)
+	}
+	if got := created.GetAttributes().AsMap()["level"]; got != float64(3) {
+		t.Fatalf("expected level 3 to be returned, got %v", got)
+	}
+
+	replaced, err := structpb.NewStruct(map[string]any{"department": "Support"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated, err := client.UpdateUser(context.Background(), &userpb.UpdateUserRequest{
+		User:       &userpb.User{UserId: id.String(), FirstName: "Ignored", Attributes: replaced},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"attributes"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := updated.GetAttributes().AsMap()
+	if len(got) != 1 || got["department"] != "Support" || updated.GetFirstName() != "John" {
+		t.Fatalf("expected only the attributes to be replaced, got %s %v", updated.GetFirstName(), got)
+	}
+}
+
+func TestUpdateUser_UnknownMaskPath(t *testing.T) {
+	client := newTestClient(t, &MockUserStore{})
+
+	_, err := client.UpdateUser(context.Background(), &userpb.UpdateUserRequest{
+		User:       &userpb.User{UserId: uuid.NewString()},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"password"}},
+	})
+	assertCode(t, err, codes.InvalidArgument)
+}
+
+// unit tests for DeleteUser
+func TestDeleteUser_NotFound(t *testing.T) {
+	client := newTestClient(t, &MockUserStore{
+		DeleteUserFn: func(uuid.UUID) (bool, error) {
+			return false, nil
+		},
+	})
+
+	_, err := client.DeleteUser(context.Background(), &userpb.DeleteUserRequest{UserId: uuid.NewString()})
+	assertCode(t, err, codes.NotFound)
+}
+
+func TestDeleteUser_RequiresAdmin(t *testing.T) {
+	userStore := &MockUserStore{
+		DeleteUserFn: func(uuid.UUID) (bool, error) {
+			t.Fatalf("expected the user not to be deleted")
+			return false, nil
+		},
+	}
+
+	tests := []struct {
+		err      error
+		expected codes.Code
+	}{
+		// without an access token there are no claims
+		{auth.ErrAdminRequired, codes.Unauthenticated},
+		{auth.ErrAdminMFARequired, codes.PermissionDenied},
+		{errors.New("database down"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		client := newTestClientAs(t, userStore, stubAuthorizer{err: tt.err})
+		_, err := client.DeleteUser(context.Background(), &userpb.DeleteUserRequest{UserId: uuid.NewString()})
+		assertCode(t, err, tt.expected)
+	}
+}
@@ -0,0 +1,154 @@
+package scim
+
+import (
+	"net/http"
+	"testing"
+)
+
+// TestCompliance_EntraProvisioning replays the requests Microsoft Entra ID
+// provisioning, and its SCIM validator, send to an endpoint, in the order
+// they are sent, checking the responses the validator checks.
+func TestCompliance_EntraProvisioning(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	// a userName that is not the email cannot be kept
+	body := decode(t, do(handler, http.MethodPost, "/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+		"externalId": "0a21f0f2-8d2a-4f8e-bf98-7363c4aed4ef",
+		"userName": "Test_User_ab6490ee-1e48-479e-a20b-2d77186b5dd1",
+		"active": true,
+		"emails": [{"primary": true, "type": "work", "value": "Test_User_fd0ea19b@testuser.com"}],
+		"meta": {"resourceType": "User"},
+		"name": {"formatted": "givenName familyName", "familyName": "familyName", "givenName": "givenName"},
+		"roles": []
+	}`), http.StatusBadRequest)
+	if body["scimType"] != mutability {
+		t.Errorf("Expected a mutability error, got %v", body)
+	}
+
+	w := do(handler, http.MethodPost, "/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User"],
+		"externalId": "0a21f0f2-8d2a-4f8e-bf98-7363c4aed4ef",
+		"userName": "Test_User_fd0ea19b@testuser.com",
+		"active": true,
+		"displayName": "givenName familyName",
+		"emails": [{"primary": true, "type": "work", "value": "Test_User_fd0ea19b@testuser.com"}],
+		"meta": {"resourceType": "User"},
+		"name": {"formatted": "givenName familyName", "familyName": "familyName", "givenName": "givenName"},
+		"roles": [],
+		"urn:ietf:params:scim:schemas:extension:enterprise:2.0:User": {"department": "Sales", "employeeNumber": "701984"}
+	}`)
+	created := decode(t, w, http.StatusCreated)
+	id, _ := created["id"].(string)
+	userMeta, _ := created["meta"].(map[string]any)
+	if id == "" || userMeta["resourceType"] != "User" || userMeta["location"] != w.Header().Get("Location") {
+		t.Fatalf("Expected an id and meta matching the Location header, got %v", created)
+	}
+
+	list := decode(t, do(handler, http.MethodGet, `/Users?filter=userName+eq+"Test_User_fd0ea19b@testuser.com"`, ""), http.StatusOK)
+	if list["totalResults"] != float64(1) || list["itemsPerPage"] != float64(1) || list["startIndex"] != float64(1) {
+		t.Errorf("Expected one user, got %v", list)
+	}
+
+	list = decode(t, do(handler, http.MethodGet, `/Users?filter=userName+eq+"non-existent@testuser.com"`, ""), http.StatusOK)
+	if resources, ok := list["Resources"].([]any); list["totalResults"] != float64(0) || !ok || len(resources) != 0 {
+		t.Errorf("Expected an empty list, got %v", list)
+	}
+
+	// attribute names are case-insensitive
+	list = decode(t, do(handler, http.MethodGet, `/Users?filter=UserName+eq+"test_user_fd0ea19b@testuser.com"`, ""), http.StatusOK)
+	if list["totalResults"] != float64(1) {
+		t.Errorf("Expected one user, got %v", list)
+	}
+
+	patched := decode(t, do(handler, http.MethodPatch, "/Users/"+id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "path": "emails[type eq \"work\"].value", "value": "updatedEmail@testuser.com"},
+			{"op": "Replace", "path": "name.familyName", "value": "updatedFamilyName"}
+		]
+	}`), http.StatusOK)
+	if patched["userName"] != "updatedEmail@testuser.com" {
+		t.Errorf("Expected userName to follow the email, got %v", patched)
+	}
+
+	decode(t, do(handler, http.MethodPatch, "/Users/"+id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "Replace", "path": "userName", "value": "5b50642d@testuser.com"}]
+	}`), http.StatusOK)
+
+	// enterprise extension attributes are not kept, but must not fail
+	decode(t, do(handler, http.MethodPatch, "/Users/"+id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Add", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "Marketing"},
+			{"op": "Replace", "path": "urn:ietf:params:scim:schemas:core:2.0:User:name.givenName", "value": "updatedGivenName"},
+			{"op": "Add", "value": {"externalId": "a", "displayName": "updatedGivenName updatedFamilyName"}}
+		]
+	}`), http.StatusOK)
+
+	disabled := decode(t, do(handler, http.MethodPatch, "/Users/"+id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "Replace", "path": "active", "value": false}]
+	}`), http.StatusOK)
+	if disabled["active"] != false || disabled["userName"] != "5b50642d@testuser.com" {
+		t.Errorf("Expected an inactive user with the patched userName, got %v", disabled)
+	}
+	if name, _ := disabled["name"].(map[string]any); name["givenName"] != "updatedGivenName" {
+		t.Errorf("Expected the patched givenName, got %v", disabled["name"])
+	}
+
+	w = do(handler, http.MethodPost, "/Groups", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group", "http://schemas.microsoft.com/2006/11/ResourceManagement/ADSCIM/2.0/Group"],
+		"externalId": "8aa1a0c0-c4c3-4bc0-b4a5-2ef676900159",
+		"displayName": "displayName",
+		"meta": {"resourceType": "Group"}
+	}`)
+	group := decode(t, w, http.StatusCreated)
+	groupId, _ := group["id"].(string)
+	groupMeta, _ := group["meta"].(map[string]any)
+	if groupId == "" || groupMeta["resourceType"] != "Group" || groupMeta["location"] != w.Header().Get("Location") {
+		t.Fatalf("Expected an id and meta matching the Location header, got %v", group)
+	}
+
+	got := decode(t, do(handler, http.MethodGet, "/Groups/"+groupId+"?excludedAttributes=members", ""), http.StatusOK)
+	if got["displayName"] != "displayName" || got["members"] != nil {
+		t.Errorf("Expected the group without members, got %v", got)
+	}
+
+	list = decode(t, do(handler, http.MethodGet, `/Groups?excludedAttributes=members&filter=displayName+eq+"displayName"`, ""), http.StatusOK)
+	if list["totalResults"] != float64(1) {
+		t.Errorf("Expected one group, got %v", list)
+	}
+
+	decode(t, do(handler, http.MethodPatch, "/Groups/"+groupId, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "Replace", "path": "displayName", "value": "1879db59-3bdf-4490-ad68-ab880a269474updatedDisplayName"}]
+	}`), http.StatusOK)
+
+	withMember := decode(t, do(handler, http.MethodPatch, "/Groups/"+groupId, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "Add", "path": "members", "value": [{"$ref": null, "value": "`+id+`"}]}]
+	}`), http.StatusOK)
+	if members, _ := withMember["members"].([]any); len(members) != 1 {
+		t.Errorf("Expected one member, got %v", withMember["members"])
+	}
+
+	withoutMember := decode(t, do(handler, http.MethodPatch, "/Groups/"+groupId, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "Remove", "path": "members", "value": [{"$ref": null, "value": "`+id+`"}]}]
+	}`), http.StatusOK)
+	if members, _ := withoutMember["members"].([]any); len(members) != 0 {
+		t.Errorf("Expected no members, got %v", withoutMember["members"])
+	}
+
+	for _, target := range []string{"/Groups/" + groupId, "/Users/" + id} {
+		if w := do(handler, http.MethodDelete, target, ""); w.Code != http.StatusNoContent {
+			t.Errorf("Expected 204 deleting %s, got %d: %s", target, w.Code, w.Body)
+		}
+		body := decode(t, do(handler, http.MethodGet, target, ""), http.StatusNotFound)
+		if body["status"] != "404" {
+			t.Errorf("Expected a SCIM 404 error for %s, got %v", target, body)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package scim
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// schemasJSON describes the attributes of users and groups that are served.
+//
+//go:embed schemas.json
+var schemasJSON []byte
+
+const (
+	serviceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	resourceTypeSchema          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+)
+
+func (handler *Handler) getServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, map[string]any{
+		"schemas":        []string{serviceProviderConfigSchema},
+		"patch":          map[string]any{"supported": true},
+		"bulk":           map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]any{"supported": true, "maxResults": maxResults},
+		"changePassword": map[string]any{"supported": false},
+		"sort":           map[string]any{"supported": false},
+		"etag":           map[string]any{"supported": false},
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer Token",
+			"description": "Authentication with the token the service is configured with",
+			"primary":     true,
+		}},
+		"meta": map[string]any{
+			"resourceType": "ServiceProviderConfig",
+			"location":     handler.baseURL + "/ServiceProviderConfig",
+		},
+	})
+}
+
+func (handler *Handler) resourceTypes() []map[string]any {
+	resourceType := func(name, endpoint, description, schema string) map[string]any {
+		return map[string]any{
+			"schemas":     []string{resourceTypeSchema},
+			"id":          name,
+			"name":        name,
+			"endpoint":    endpoint,
+			"description": description,
+			"schema":      schema,
+			"meta": map[string]any{
+				"resourceType": "ResourceType",
+				"location":     handler.location("ResourceTypes", name),
+			},
+		}
+	}
+	userType := resourceType("User", "/Users", "User Account", userSchema)
+	userType["schemaExtensions"] = []map[string]any{{"schema": userExtensionSchema, "required": false}}
+	return []map[string]any{
+		userType,
+		resourceType("Group", "/Groups", "Group", groupSchema),
+	}
+}
+
+func (handler *Handler) listResourceTypes(w http.ResponseWriter, r *http.Request) {
+	resourceTypes := handler.resourceTypes()
+	writeList(w, r, resourceTypes, listParams{startIndex: 1, count: len(resourceTypes)})
+}
+
+func (handler *Handler) getResourceType(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	for _, resourceType := range handler.resourceTypes() {
+		if resourceType["id"] == id {
+			writeJSON(w, r, http.StatusOK, resourceType)
+			return
+		}
+	}
+	writeError(w, r, "", notFound("ResourceType", id))
+}
+
+func (handler *Handler) schemas() []map[string]any {
+	var schemas []map[string]any
+	if err := json.Unmarshal(schemasJSON, &schemas); err != nil {
+		panic(err)
+	}
+	for _, schema := range schemas {
+		schema["meta"] = map[string]any{
+			"resourceType": "Schema",
+			"location":     handler.location("Schemas", schema["id"].(string)),
+		}
+	}
+	return schemas
+}
+
+func (handler *Handler) listSchemas(w http.ResponseWriter, r *http.Request) {
+	schemas := handler.schemas()
+	writeList(w, r, schemas, listParams{startIndex: 1, count: len(schemas)})
+}
+
+func (handler *Handler) getSchema(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	for _, schema := range handler.schemas() {
+		if schema["id"] == id {
+			writeJSON(w, r, http.StatusOK, schema)
+			return
+		}
+	}
+	writeError(w, r, "", notFound("Schema", id))
+}
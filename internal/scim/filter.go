@@ -0,0 +1,485 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// filter is a parsed filter expression of RFC 7644 section 3.4.2.2. It is
+// matched against resources, and the values of multi-valued attributes, in
+// their JSON form. Attribute names and string comparisons ignore case, as
+// every attribute served here has caseExact false.
+type filter interface {
+	matches(resource map[string]any) bool
+}
+
+type logical struct {
+	or          bool
+	left, right filter
+}
+
+func (expr logical) matches(resource map[string]any) bool {
+	if expr.or {
+		return expr.left.matches(resource) || expr.right.matches(resource)
+	}
+	return expr.left.matches(resource) && expr.right.matches(resource)
+}
+
+type negation struct {
+	filter filter
+}
+
+func (expr negation) matches(resource map[string]any) bool {
+	return !expr.filter.matches(resource)
+}
+
+// valuePath matches resources with a value of the multi-valued attribute attr
+// that matches filter, as in emails[type eq "work"].
+type valuePath struct {
+	attr   string
+	filter filter
+}
+
+func (expr valuePath) matches(resource map[string]any) bool {
+	values, _ := lookup(resource, expr.attr).([]any)
+	for _, value := range values {
+		if element, ok := value.(map[string]any); ok && expr.filter.matches(element) {
+			return true
+		}
+	}
+	return false
+}
+
+// comparison compares the values at path with value using op, which is "pr"
+// or one of the comparison operators. It matches if any value does, so
+// emails.value eq "a@example.com" finds users with that email among others.
+type comparison struct {
+	path  attrPath
+	op    string
+	value any
+}
+
+func (expr comparison) matches(resource map[string]any) bool {
+	values := expr.path.resolve(resource)
+
+	present := false
+	for _, value := range values {
+		if value != nil && value != "" {
+			present = true
+		}
+	}
+	switch {
+	case expr.op == "pr":
+		return present
+	case expr.value == nil && expr.op == "eq":
+		return !present
+	case expr.value == nil && expr.op == "ne":
+		return present
+	case expr.op == "ne":
+		return !comparison{path: expr.path, op: "eq", value: expr.value}.matches(resource)
+	}
+
+	for _, value := range values {
+		if compare(value, expr.op, expr.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(value any, op string, literal any) bool {
+	switch literal := literal.(type) {
+	case string:
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		a, b := strings.ToLower(s), strings.ToLower(literal)
+		switch op {
+		case "eq":
+			return a == b
+		case "co":
+			return strings.Contains(a, b)
+		case "sw":
+			return strings.HasPrefix(a, b)
+		case "ew":
+			return strings.HasSuffix(a, b)
+		case "gt":
+			return a > b
+		case "ge":
+			return a >= b
+		case "lt":
+			return a < b
+		case "le":
+			return a <= b
+		}
+	case float64:
+		n, ok := value.(float64)
+		if !ok {
+			return false
+		}
+		switch op {
+		case "eq":
+			return n == literal
+		case "gt":
+			return n > literal
+		case "ge":
+			return n >= literal
+		case "lt":
+			return n < literal
+		case "le":
+			return n <= literal
+		}
+	case bool:
+		b, ok := value.(bool)
+		return ok && op == "eq" && b == literal
+	}
+	return false
+}
+
+// attrPath names an attribute, and optionally one of its sub-attributes, with
+// any schema URN prefix removed.
+type attrPath struct {
+	attr, sub string
+}
+
+func parseAttrPath(s string) (attrPath, error) {
+	name := s
+	if strings.HasPrefix(strings.ToLower(s), "urn:") {
+		name = s[strings.LastIndex(s, ":")+1:]
+	}
+	attr, sub, _ := strings.Cut(name, ".")
+	if !validAttrName(attr) || (sub != "" && !validAttrName(sub)) {
+		return attrPath{}, fmt.Errorf("invalid attribute path %q", s)
+	}
+	return attrPath{attr: attr, sub: sub}, nil
+}
+
+func validAttrName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '$' && i == 0:
+		case i > 0 && (c >= '0' && c <= '9' || c == '_' || c == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// resolve returns the values at path. Multi-valued attributes give each of
+// their values, and those of complex attributes without a sub-attribute
+// stand for their "value" sub-attribute.
+func (path attrPath) resolve(resource map[string]any) []any {
+	value := lookup(resource, path.attr)
+	values, multi := value.([]any)
+	if !multi {
+		values = []any{value}
+	}
+
+	sub := path.sub
+	if sub == "" && multi {
+		sub = "value"
+	}
+	if sub == "" {
+		return values
+	}
+	resolved := make([]any, 0, len(values))
+	for _, value := range values {
+		if element, ok := value.(map[string]any); ok {
+			resolved = append(resolved, lookup(element, sub))
+		} else if path.sub == "" {
+			resolved = append(resolved, value)
+		}
+	}
+	return resolved
+}
+
+// lookup returns the value of the attribute name, ignoring case, or nil.
+func lookup(resource map[string]any, name string) any {
+	if value, ok := resource[name]; ok {
+		return value
+	}
+	for key, value := range resource {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return nil
+}
+
+var comparisonOperators = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true,
+}
+
+// parseFilter parses a filter query parameter.
+func parseFilter(s string) (filter, error) {
+	p, err := newParser(s)
+	if err != nil {
+		return nil, err
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.next(); t.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q", t.text)
+	}
+	return expr, nil
+}
+
+// patchPath is the target of a PATCH operation: an attribute, values of it
+// selected by a filter and a sub-attribute of those, as in
+// emails[type eq "work"].value.
+type patchPath struct {
+	attrPath
+	filter filter
+}
+
+func parsePatchPath(s string) (patchPath, error) {
+	p, err := newParser(s)
+	if err != nil {
+		return patchPath{}, err
+	}
+	t := p.next()
+	if t.kind != tokenWord {
+		return patchPath{}, fmt.Errorf("invalid path %q", s)
+	}
+	path, err := parseAttrPath(t.text)
+	if err != nil {
+		return patchPath{}, err
+	}
+	result := patchPath{attrPath: path}
+
+	if p.peek().kind == tokenLBracket && path.sub == "" {
+		p.next()
+		if result.filter, err = p.parseOr(); err != nil {
+			return patchPath{}, err
+		}
+		if p.next().kind != tokenRBracket {
+			return patchPath{}, fmt.Errorf("missing ] in path %q", s)
+		}
+		if t := p.peek(); t.kind == tokenWord && strings.HasPrefix(t.text, ".") {
+			p.next()
+			result.sub = t.text[1:]
+			if !validAttrName(result.sub) {
+				return patchPath{}, fmt.Errorf("invalid path %q", s)
+			}
+		}
+	}
+	if p.next().kind != tokenEOF {
+		return patchPath{}, fmt.Errorf("invalid path %q", s)
+	}
+	return result, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenLBracket
+	tokenRBracket
+)
+
+type token struct {
+	kind tokenKind
+	// text is the token as written. The value of a string is in value.
+	text  string
+	value string
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func newParser(s string) (*parser, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		switch c := s[i]; c {
+		case ' ', '\t', '\n', '\r':
+			i++
+		case '(', ')', '[', ']':
+			kind := map[byte]tokenKind{'(': tokenLParen, ')': tokenRParen, '[': tokenLBracket, ']': tokenRBracket}[c]
+			tokens = append(tokens, token{kind: kind, text: string(c)})
+			i++
+		case '"':
+			end := i + 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated string in %q", s)
+			}
+			var value string
+			if err := json.Unmarshal([]byte(s[i:end+1]), &value); err != nil {
+				return nil, fmt.Errorf("invalid string %s", s[i:end+1])
+			}
+			tokens = append(tokens, token{kind: tokenString, text: s[i : end+1], value: value})
+			i = end + 1
+		default:
+			end := i
+			for end < len(s) && !strings.ContainsRune(" \t\n\r()[]\"", rune(s[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: s[i:end]})
+			i = end
+		}
+	}
+	return &parser{tokens: tokens}, nil
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return token{kind: tokenEOF}
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	if p.pos < len(p.tokens) {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokenWord && strings.EqualFold(t.text, word)
+}
+
+// parseOr parses "or" expressions, which bind less tightly than "and".
+func (p *parser) parseOr() (filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{or: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (filter, error) {
+	left, err := p.parseFactor()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		p.next()
+		right, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		left = logical{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseFactor() (filter, error) {
+	negate := false
+	if p.keyword("not") {
+		p.next()
+		negate = true
+		if p.peek().kind != tokenLParen {
+			return nil, fmt.Errorf("expected ( after not")
+		}
+	}
+
+	if p.peek().kind == tokenLParen {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenRParen {
+			return nil, fmt.Errorf("missing )")
+		}
+		if negate {
+			return negation{filter: expr}, nil
+		}
+		return expr, nil
+	}
+	return p.parseAttrExpr()
+}
+
+func (p *parser) parseAttrExpr() (filter, error) {
+	t := p.next()
+	if t.kind != tokenWord {
+		return nil, fmt.Errorf("expected an attribute, got %q", t.text)
+	}
+	path, err := parseAttrPath(t.text)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.peek().kind == tokenLBracket {
+		if path.sub != "" {
+			return nil, fmt.Errorf("invalid attribute path %q", t.text)
+		}
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokenRBracket {
+			return nil, fmt.Errorf("missing ]")
+		}
+		return valuePath{attr: path.attr, filter: expr}, nil
+	}
+
+	t = p.next()
+	op := strings.ToLower(t.text)
+	if t.kind != tokenWord || (op != "pr" && !comparisonOperators[op]) {
+		return nil, fmt.Errorf("expected an operator after %s, got %q", path.attr, t.text)
+	}
+	if op == "pr" {
+		return comparison{path: path, op: op}, nil
+	}
+
+	value, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	return comparison{path: path, op: op, value: value}, nil
+}
+
+func (p *parser) parseValue() (any, error) {
+	t := p.next()
+	if t.kind == tokenString {
+		return t.value, nil
+	}
+	if t.kind == tokenWord {
+		switch strings.ToLower(t.text) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		if n, err := strconv.ParseFloat(t.text, 64); err == nil {
+			return n, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid value %q", t.text)
+}
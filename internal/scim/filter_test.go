@@ -0,0 +1,100 @@
+package scim
+
+import "testing"
+
+func TestParseFilter_Matches(t *testing.T) {
+	resource := map[string]any{
+		"id":       "2819c223-7f76-453a-919d-413861904646",
+		"userName": "Barbara.Jensen@example.com",
+		"name":     map[string]any{"givenName": "Barbara", "familyName": "Jensen"},
+		"emails": []any{
+			map[string]any{"value": "barbara.jensen@example.com", "type": "work", "primary": true},
+			map[string]any{"value": "babs@example.org", "type": "home"},
+		},
+		"active": true,
+		"meta":   map[string]any{"created": "2024-01-23T04:56:22Z"},
+	}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`userName eq "barbara.jensen@example.com"`, true},
+		{`USERNAME EQ "BARBARA.JENSEN@EXAMPLE.COM"`, true},
+		{`urn:ietf:params:scim:schemas:core:2.0:User:userName sw "barbara"`, true},
+		{`userName ne "barbara.jensen@example.com"`, false},
+		{`name.givenName co "arb"`, true},
+		{`name.familyName ew "sen"`, true},
+		{`emails co "example.org"`, true},
+		{`emails.value eq "babs@example.org"`, true},
+		{`emails[type eq "work" and value co "@example.com"]`, true},
+		{`emails[type eq "home" and value co "@example.com"]`, false},
+		{`emails[primary eq true and value eq "babs@example.org"]`, false},
+		{`active eq true`, true},
+		{`active eq false`, false},
+		{`meta.created gt "2024-01-01T00:00:00Z"`, true},
+		{`meta.created lt "2024-01-01T00:00:00Z"`, false},
+		{`title pr`, false},
+		{`name pr and not (title pr)`, true},
+		{`title eq null`, true},
+		{`userName eq "x" or name.givenName eq "Barbara"`, true},
+		{`userName eq "x" or userName eq "y" and active eq true`, false},
+		{`(userName eq "x" or active eq true) and name.givenName sw "B"`, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.filter, func(t *testing.T) {
+			parsed, err := parseFilter(test.filter)
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+			if got := parsed.matches(resource); got != test.want {
+				t.Errorf("Expected %v, got %v", test.want, got)
+			}
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	for _, filter := range []string{
+		``,
+		`userName`,
+		`userName eq`,
+		`userName is "x"`,
+		`userName eq "x`,
+		`userName eq x`,
+		`(userName eq "x"`,
+		`emails[type eq "work"`,
+		`not userName eq "x"`,
+		`userName eq "x" and`,
+		`userName eq "x" extra`,
+	} {
+		if _, err := parseFilter(filter); err == nil {
+			t.Errorf("Expected %q to be rejected", filter)
+		}
+	}
+}
+
+func TestParsePatchPath(t *testing.T) {
+	path, err := parsePatchPath(`emails[type eq "work"].value`)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if path.attr != "emails" || path.sub != "value" || path.filter == nil {
+		t.Errorf("Expected emails, a filter and value, got %+v", path)
+	}
+
+	path, err = parsePatchPath(`urn:ietf:params:scim:schemas:core:2.0:User:name.givenName`)
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+	if path.attr != "name" || path.sub != "givenName" || path.filter != nil {
+		t.Errorf("Expected name.givenName, got %+v", path)
+	}
+
+	for _, invalid := range []string{`emails[type eq "work"`, `emails[type eq "work"]value`, `name..x`, `"name"`} {
+		if _, err := parsePatchPath(invalid); err == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,678 @@
+package scim
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"example.com/user-management/internal/attributes"
+	"example.com/user-management/internal/emailaddr"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/store"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultCount is how many resources a page holds when the request does
+	// not say.
+	defaultCount = 100
+	// maxResults bounds the resources a page holds.
+	maxResults = 1000
+	// maxBodySize bounds request bodies, which a large group's members
+	// dominate.
+	maxBodySize = 1 << 20
+	pageSize    = 500
+)
+
+// Handler serves the SCIM endpoints, which it expects to be mounted under
+// the path of Config.BaseURL. Every request needs Config.Token as its bearer
+// token and acts for the tenant resolved for it.
+//
+// Resources are listed by reading every user or group and filtering them in
+// memory, which suits the directory sizes identity providers synchronise
+// here. The groups of users are only given when a single user is returned.
+type Handler struct {
+	users   store.UserStoreInterface
+	groups  store.GroupStoreInterface
+	token   []byte
+	baseURL string
+	router  chi.Router
+}
+
+func NewHandler(users store.UserStoreInterface, groups store.GroupStoreInterface, config Config) *Handler {
+	handler := &Handler{
+		users:   users,
+		groups:  groups,
+		token:   []byte(config.Token),
+		baseURL: strings.TrimSuffix(config.BaseURL, "/"),
+	}
+
+	router := chi.NewRouter()
+	router.Use(handler.authenticate)
+	router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, "", &Error{Status: http.StatusNotFound, Detail: "no such endpoint"})
+	})
+	router.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, "", &Error{Status: http.StatusMethodNotAllowed, Detail: "method not allowed"})
+	})
+
+	router.Get("/ServiceProviderConfig", handler.getServiceProviderConfig)
+	router.Get("/ResourceTypes", handler.listResourceTypes)
+	router.Get("/ResourceTypes/{id}", handler.getResourceType)
+	router.Get("/Schemas", handler.listSchemas)
+	router.Get("/Schemas/{id}", handler.getSchema)
+
+	router.Get("/Users", handler.listUsers)
+	router.Post("/Users", handler.createUser)
+	router.Get("/Users/{id}", handler.getUser)
+	router.Put("/Users/{id}", handler.replaceUser)
+	router.Patch("/Users/{id}", handler.patchUser)
+	router.Delete("/Users/{id}", handler.deleteUser)
+
+	router.Get("/Groups", handler.listGroups)
+	router.Post("/Groups", handler.createGroup)
+	router.Get("/Groups/{id}", handler.getGroup)
+	router.Put("/Groups/{id}", handler.replaceGroup)
+	router.Patch("/Groups/{id}", handler.patchGroup)
+	router.Delete("/Groups/{id}", handler.deleteGroup)
+
+	handler.router = router
+	return handler
+}
+
+func (handler *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler.router.ServeHTTP(w, r)
+}
+
+func (handler *Handler) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), handler.token) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+			writeError(w, r, "", &Error{Status: http.StatusUnauthorized, Detail: "a valid bearer token is required"})
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (handler *Handler) listUsers(w http.ResponseWriter, r *http.Request) {
+	params, err := parseListParams(r.URL.Query())
+	if err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+
+	var resources []map[string]any
+	after := uuid.Nil
+	for {
+		page, err := handler.users.ListUsers(r.Context(), model.UserFilter{}, after, pageSize)
+		if err != nil {
+			writeError(w, r, "Failed to Retrieve Users!", err)
+			return
+		}
+		for _, user := range page {
+			resource := toMap(handler.userResource(user, nil))
+			if params.filter == nil || params.filter.matches(resource) {
+				resources = append(resources, resource)
+			}
+		}
+		if len(page) < pageSize {
+			break
+		}
+		after = page[len(page)-1].UserId
+	}
+
+	writeList(w, r, resources, params)
+}
+
+func (handler *Handler) createUser(w http.ResponseWriter, r *http.Request) {
+	var resource userResource
+	if err := readResource(w, r, &resource, userSchema); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+
+	var user model.User
+	if err := resource.apply(&user); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+
+	created, err := handler.users.CreateUser(r.Context(), user)
+	if err != nil {
+		writeError(w, r, "Failed to Create User!", userError(err))
+		return
+	}
+
+	response := handler.userResource(created, nil)
+	w.Header().Set("Location", response.Meta.Location)
+	writeJSON(w, r, http.StatusCreated, response)
+}
+
+func (handler *Handler) getUser(w http.ResponseWriter, r *http.Request) {
+	user, err := handler.findUser(r)
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve User!", err)
+		return
+	}
+	handler.writeUser(w, r, user)
+}
+
+func (handler *Handler) replaceUser(w http.ResponseWriter, r *http.Request) {
+	var resource userResource
+	if err := readResource(w, r, &resource, userSchema); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+
+	user, err := handler.findUser(r)
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve User!", err)
+		return
+	}
+	if err := resource.apply(&user); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+	handler.updateUser(w, r, user)
+}
+
+func (handler *Handler) patchUser(w http.ResponseWriter, r *http.Request) {
+	var patch patchRequest
+	if err := readResource(w, r, &patch, patchOpSchema); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+
+	user, err := handler.findUser(r)
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve User!", err)
+		return
+	}
+
+	current := toMap(handler.userResource(user, nil))
+	if err := applyPatch(current, patch.Operations, []string{"id", "groups", "meta"}); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+	var resource userResource
+	if err := fromMap(current, &resource); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+	// the patched attributes are all there are, even if none are left
+	if resource.Attributes == nil {
+		resource.Attributes = map[string]any{}
+	}
+	// userName and the primary email are one address, so changing either
+	// changes both; only changing them to different addresses conflicts
+	if resource.UserName == user.Email {
+		resource.UserName = ""
+	} else if primaryValue(resource.Emails) == user.Email {
+		resource.Emails = nil
+	}
+	if err := resource.apply(&user); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+	handler.updateUser(w, r, user)
+}
+
+func (handler *Handler) updateUser(w http.ResponseWriter, r *http.Request, user model.User) {
+	updated, ok, err := handler.users.UpdateUser(r.Context(), user, user.UserId)
+	if err == nil && !ok {
+		err = notFound("User", user.UserId.String())
+	}
+	if err != nil {
+		writeError(w, r, "Failed to Update User!", userError(err))
+		return
+	}
+	handler.writeUser(w, r, updated)
+}
+
+func (handler *Handler) deleteUser(w http.ResponseWriter, r *http.Request) {
+	userId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, "", notFound("User", chi.URLParam(r, "id")))
+		return
+	}
+
+	ok, err := handler.users.DeleteUser(r.Context(), userId)
+	if err == nil && !ok {
+		err = notFound("User", userId.String())
+	}
+	if err != nil {
+		writeError(w, r, "Failed to Delete User!", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (handler *Handler) findUser(r *http.Request) (model.User, error) {
+	userId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return model.User{}, notFound("User", chi.URLParam(r, "id"))
+	}
+	user, ok, err := handler.users.GetUserById(r.Context(), userId)
+	if err == nil && !ok {
+		err = notFound("User", userId.String())
+	}
+	return user, err
+}
+
+func (handler *Handler) writeUser(w http.ResponseWriter, r *http.Request, user model.User) {
+	groups, err := handler.groups.ListUserGroups(r.Context(), user.UserId)
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve Groups!", err)
+		return
+	}
+	resource := toMap(handler.userResource(user, groups))
+	query := r.URL.Query()
+	writeJSON(w, r, http.StatusOK, project(resource, splitList(query.Get("attributes")), splitList(query.Get("excludedAttributes"))))
+}
+
+// userError turns the errors of creating or updating a user a client can fix
+// into SCIM errors.
+func userError(err error) error {
+	switch {
+	case errors.Is(err, store.ErrDuplicateEmail):
+		return &Error{Status: http.StatusConflict, ScimType: uniqueness, Detail: "a user with the email already exists"}
+	case errors.Is(err, attributes.ErrInvalid):
+		return badRequest(invalidValue, "%s; custom attributes are the members of %s", err, userExtensionSchema)
+	case errors.Is(err, emailaddr.ErrInvalid), errors.Is(err, phonenumber.ErrInvalid):
+		return badRequest(invalidValue, "%s", err)
+	}
+	return err
+}
+
+func (handler *Handler) listGroups(w http.ResponseWriter, r *http.Request) {
+	params, err := parseListParams(r.URL.Query())
+	if err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+
+	groups, err := handler.groups.GetAllGroups(r.Context())
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve Groups!", err)
+		return
+	}
+
+	var members map[uuid.UUID][]model.User
+	if !slices.ContainsFunc(params.excluded, func(attr string) bool { return strings.EqualFold(attr, "members") }) {
+		groupIds := make([]uuid.UUID, len(groups))
+		for i, group := range groups {
+			groupIds[i] = group.GroupId
+		}
+		if members, err = handler.groups.ListGroupMembers(r.Context(), groupIds); err != nil {
+			writeError(w, r, "Failed to Retrieve Group Members!", err)
+			return
+		}
+	}
+	var resources []map[string]any
+	for _, group := range groups {
+		resource := toMap(handler.groupResource(group, members[group.GroupId]))
+		if params.filter == nil || params.filter.matches(resource) {
+			resources = append(resources, resource)
+		}
+	}
+
+	writeList(w, r, resources, params)
+}
+
+func (handler *Handler) createGroup(w http.ResponseWriter, r *http.Request) {
+	var resource groupResource
+	if err := readResource(w, r, &resource, groupSchema); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+	if err := resource.validate(); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+	memberIds, err := handler.memberIds(r.Context(), resource.Members)
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve Users!", err)
+		return
+	}
+
+	group, err := handler.groups.CreateGroup(r.Context(), model.Group{Name: resource.DisplayName})
+	if err != nil {
+		writeError(w, r, "Failed to Create Group!", groupError(err))
+		return
+	}
+	members, err := handler.setMembers(r.Context(), group.GroupId, nil, memberIds)
+	if err != nil {
+		writeError(w, r, "Failed to Add Group Members!", err)
+		return
+	}
+
+	response := handler.groupResource(group, members)
+	w.Header().Set("Location", response.Meta.Location)
+	writeJSON(w, r, http.StatusCreated, response)
+}
+
+func (handler *Handler) getGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := handler.findGroup(r)
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve Group!", err)
+		return
+	}
+	members, err := handler.members(r.Context(), group.GroupId)
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve Group Members!", err)
+		return
+	}
+	handler.writeGroup(w, r, group, members)
+}
+
+func (handler *Handler) replaceGroup(w http.ResponseWriter, r *http.Request) {
+	var resource groupResource
+	if err := readResource(w, r, &resource, groupSchema); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+
+	group, err := handler.findGroup(r)
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve Group!", err)
+		return
+	}
+	handler.updateGroup(w, r, group, resource)
+}
+
+func (handler *Handler) patchGroup(w http.ResponseWriter, r *http.Request) {
+	var patch patchRequest
+	if err := readResource(w, r, &patch, patchOpSchema); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+
+	group, err := handler.findGroup(r)
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve Group!", err)
+		return
+	}
+	members, err := handler.members(r.Context(), group.GroupId)
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve Group Members!", err)
+		return
+	}
+
+	current := toMap(handler.groupResource(group, members))
+	if err := applyPatch(current, patch.Operations, []string{"id", "meta"}); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+	var resource groupResource
+	if err := fromMap(current, &resource); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+	handler.updateGroup(w, r, group, resource)
+}
+
+// updateGroup makes group match resource, renaming it and adding and
+// removing members as needed.
+func (handler *Handler) updateGroup(w http.ResponseWriter, r *http.Request, group model.Group, resource groupResource) {
+	if err := resource.validate(); err != nil {
+		writeError(w, r, "", err)
+		return
+	}
+	memberIds, err := handler.memberIds(r.Context(), resource.Members)
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve Users!", err)
+		return
+	}
+
+	if resource.DisplayName != group.Name {
+		group.Name = resource.DisplayName
+		updated, ok, err := handler.groups.UpdateGroup(r.Context(), group, group.GroupId)
+		if err == nil && !ok {
+			err = notFound("Group", group.GroupId.String())
+		}
+		if err != nil {
+			writeError(w, r, "Failed to Update Group!", groupError(err))
+			return
+		}
+		group = updated
+	}
+
+	current, err := handler.members(r.Context(), group.GroupId)
+	if err != nil {
+		writeError(w, r, "Failed to Retrieve Group Members!", err)
+		return
+	}
+	members, err := handler.setMembers(r.Context(), group.GroupId, current, memberIds)
+	if err != nil {
+		writeError(w, r, "Failed to Update Group Members!", err)
+		return
+	}
+	handler.writeGroup(w, r, group, members)
+}
+
+func (handler *Handler) deleteGroup(w http.ResponseWriter, r *http.Request) {
+	groupId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, r, "", notFound("Group", chi.URLParam(r, "id")))
+		return
+	}
+
+	ok, err := handler.groups.DeleteGroup(r.Context(), groupId)
+	if err == nil && !ok {
+		err = notFound("Group", groupId.String())
+	}
+	if err != nil {
+		writeError(w, r, "Failed to Delete Group!", err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (handler *Handler) findGroup(r *http.Request) (model.Group, error) {
+	groupId, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return model.Group{}, notFound("Group", chi.URLParam(r, "id"))
+	}
+	group, ok, err := handler.groups.GetGroupById(r.Context(), groupId)
+	if err == nil && !ok {
+		err = notFound("Group", groupId.String())
+	}
+	return group, err
+}
+
+func (handler *Handler) writeGroup(w http.ResponseWriter, r *http.Request, group model.Group, members []model.User) {
+	resource := toMap(handler.groupResource(group, members))
+	query := r.URL.Query()
+	writeJSON(w, r, http.StatusOK, project(resource, splitList(query.Get("attributes")), splitList(query.Get("excludedAttributes"))))
+}
+
+func (handler *Handler) members(ctx context.Context, groupId uuid.UUID) ([]model.User, error) {
+	var members []model.User
+	after := uuid.Nil
+	for {
+		page, err := handler.users.ListUsers(ctx, model.UserFilter{GroupId: groupId}, after, pageSize)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, page...)
+		if len(page) < pageSize {
+			return members, nil
+		}
+		after = page[len(page)-1].UserId
+	}
+}
+
+// memberIds returns the users the members refer to, failing if any is not an
+// existing user.
+func (handler *Handler) memberIds(ctx context.Context, members []reference) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(members))
+	for _, member := range members {
+		id, err := uuid.Parse(member.Value)
+		if err != nil {
+			return nil, badRequest(invalidValue, "member %q is not a user", member.Value)
+		}
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	users, err := handler.users.GetUsersByIds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if !slices.ContainsFunc(users, func(user model.User) bool { return user.UserId == id }) {
+			return nil, badRequest(invalidValue, "member %s is not a user", id)
+		}
+	}
+	return ids, nil
+}
+
+// setMembers adds and removes members of the group so that the users with
+// ids are its members, given its current members, and returns them.
+func (handler *Handler) setMembers(ctx context.Context, groupId uuid.UUID, current []model.User, ids []uuid.UUID) ([]model.User, error) {
+	for _, member := range current {
+		if !slices.Contains(ids, member.UserId) {
+			if _, err := handler.groups.RemoveGroupMember(ctx, groupId, member.UserId); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, id := range ids {
+		if slices.ContainsFunc(current, func(user model.User) bool { return user.UserId == id }) {
+			continue
+		}
+		err := handler.groups.AddGroupMember(ctx, groupId, id)
+		if errors.Is(err, store.ErrUserNotFound) {
+			return nil, badRequest(invalidValue, "member %s is not a user", id)
+		}
+		if err != nil && !errors.Is(err, store.ErrAlreadyMember) {
+			return nil, err
+		}
+	}
+	return handler.members(ctx, groupId)
+}
+
+func groupError(err error) error {
+	if errors.Is(err, store.ErrDuplicateGroupName) {
+		return &Error{Status: http.StatusConflict, ScimType: uniqueness, Detail: "a group with the displayName already exists"}
+	}
+	return err
+}
+
+// readResource decodes the request body into resource, which must list
+// schema among its schemas.
+func readResource(w http.ResponseWriter, r *http.Request, resource any, schema string) error {
+	body, err := readBody(w, r)
+	if err != nil {
+		return err
+	}
+	var schemas struct {
+		Schemas []string `json:"schemas"`
+	}
+	if json.Unmarshal(body, &schemas) != nil || json.Unmarshal(body, resource) != nil {
+		return badRequest(invalidSyntax, "invalid request body")
+	}
+	if !slices.Contains(schemas.Schemas, schema) {
+		return badRequest(invalidSyntax, "schemas must include %s", schema)
+	}
+	return nil
+}
+
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	var body json.RawMessage
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&body); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, &Error{Status: http.StatusRequestEntityTooLarge, Detail: "request body too large"}
+		}
+		return nil, badRequest(invalidSyntax, "invalid request body")
+	}
+	return body, nil
+}
+
+type listParams struct {
+	filter filter
+	// startIndex is the 1-based index of the first resource of the page.
+	startIndex int
+	count      int
+	attributes []string
+	excluded   []string
+}
+
+func parseListParams(query url.Values) (listParams, error) {
+	params := listParams{
+		startIndex: 1,
+		count:      defaultCount,
+		attributes: splitList(query.Get("attributes")),
+		excluded:   splitList(query.Get("excludedAttributes")),
+	}
+
+	if s := query.Get("filter"); s != "" {
+		parsed, err := parseFilter(s)
+		if err != nil {
+			return listParams{}, badRequest(invalidFilter, "%s", err)
+		}
+		params.filter = parsed
+	}
+
+	// out of range values are clamped as RFC 7644 section 3.4.2.4 asks
+	if s := query.Get("startIndex"); s != "" {
+		startIndex, err := strconv.Atoi(s)
+		if err != nil {
+			return listParams{}, badRequest(invalidValue, "startIndex must be an integer")
+		}
+		params.startIndex = max(startIndex, 1)
+	}
+	if s := query.Get("count"); s != "" {
+		count, err := strconv.Atoi(s)
+		if err != nil {
+			return listParams{}, badRequest(invalidValue, "count must be an integer")
+		}
+		params.count = min(max(count, 0), maxResults)
+	}
+	return params, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	list := strings.Split(s, ",")
+	for i := range list {
+		list[i] = strings.TrimSpace(list[i])
+	}
+	return list
+}
+
+// writeList sends the page of resources params selects as a ListResponse.
+func writeList(w http.ResponseWriter, r *http.Request, resources []map[string]any, params listParams) {
+	start := min(params.startIndex-1, len(resources))
+	end := min(start+params.count, len(resources))
+
+	page := make([]map[string]any, 0, end-start)
+	for _, resource := range resources[start:end] {
+		page = append(page, project(resource, params.attributes, params.excluded))
+	}
+
+	writeJSON(w, r, http.StatusOK, map[string]any{
+		"schemas":      []string{listResponseSchema},
+		"totalResults": len(resources),
+		"startIndex":   params.startIndex,
+		"itemsPerPage": len(page),
+		"Resources":    page,
+	})
+}
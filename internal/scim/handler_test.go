@@ -0,0 +1,416 @@
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store/memory"
+	"github.com/google/uuid"
+)
+
+const testToken = "test-token"
+
+func newTestHandler(t *testing.T) (*Handler, *memory.UserStore) {
+	t.Helper()
+
+	userStore := memory.NewUserStore()
+	return NewHandler(userStore, userStore, Config{Token: testToken, BaseURL: "https://example.com/scim/v2/"}), userStore
+}
+
+func do(handler http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer "+testToken)
+	r.Header.Set("Content-Type", ContentType)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, status int) map[string]any {
+	t.Helper()
+
+	if w.Code != status {
+		t.Fatalf("Expected %d, got %d: %s", status, w.Code, w.Body)
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != ContentType {
+		t.Errorf("Expected %s, got %q", ContentType, contentType)
+	}
+	var body map[string]any
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return body
+}
+
+func createUser(t *testing.T, handler http.Handler, email string) map[string]any {
+	t.Helper()
+
+	return decode(t, do(handler, http.MethodPost, "/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"externalId": "`+email+`",
+		"userName": "`+email+`",
+		"active": true,
+		"name": {"givenName": "Ada", "familyName": "Lovelace"},
+		"emails": [{"value": "`+email+`", "type": "work", "primary": true}],
+		"phoneNumbers": [{"value": "+1 (202) 555-0123", "type": "mobile"}]
+	}`), http.StatusCreated)
+}
+
+func TestAuthentication(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	for _, authorization := range []string{"", "Bearer wrong", testToken} {
+		r := httptest.NewRequest(http.MethodGet, "/Users", nil)
+		r.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		body := decode(t, w, http.StatusUnauthorized)
+		if body["status"] != "401" || w.Header().Get("WWW-Authenticate") == "" {
+			t.Errorf("Expected a SCIM 401 error, got %v", body)
+		}
+	}
+}
+
+func TestUsers_Lifecycle(t *testing.T) {
+	handler, userStore := newTestHandler(t)
+
+	created := createUser(t, handler, "ada@example.com")
+	id := created["id"].(string)
+	if created["userName"] != "ada@example.com" || created["active"] != true {
+		t.Errorf("Expected the created user, got %v", created)
+	}
+	if location := created["meta"].(map[string]any)["location"]; location != "https://example.com/scim/v2/Users/"+id {
+		t.Errorf("Expected the user's location, got %v", location)
+	}
+
+	user, ok, _ := userStore.GetUserById(t.Context(), uuid.MustParse(id))
+	if !ok || user.FirstName != "Ada" || user.LastName != "Lovelace" || user.Email != "ada@example.com" ||
+		user.Phone != "+12025550123" || user.Status != model.StatusActive {
+		t.Fatalf("Expected the user to be stored, got %+v", user)
+	}
+
+	body := decode(t, do(handler, http.MethodPost, "/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "ada@example.com",
+		"name": {"givenName": "Ada", "familyName": "Byron"}
+	}`), http.StatusConflict)
+	if body["scimType"] != uniqueness {
+		t.Errorf("Expected a uniqueness error, got %v", body)
+	}
+
+	decode(t, do(handler, http.MethodPatch, "/Users/"+id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "path": "active", "value": "False"},
+			{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "ada@example.org"}
+		]
+	}`), http.StatusOK)
+	user, _, _ = userStore.GetUserById(t.Context(), uuid.MustParse(id))
+	if user.Status != model.StatusInactive || user.Email != "ada@example.org" {
+		t.Errorf("Expected the patch to deactivate the user and change their email, got %+v", user)
+	}
+
+	body = decode(t, do(handler, http.MethodPut, "/Users/"+id, `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "ada@example.org",
+		"emails": [{"value": "augusta@example.org", "primary": true}]
+	}`), http.StatusBadRequest)
+	if body["scimType"] != mutability {
+		t.Errorf("Expected a mutability error for a userName other than the primary email, got %v", body)
+	}
+
+	replaced := decode(t, do(handler, http.MethodPut, "/Users/"+id, `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "Augusta@example.org",
+		"name": {"givenName": "Augusta", "familyName": "King"},
+		"emails": [{"value": "home@example.org", "type": "home"}, {"value": "augusta@example.org", "primary": true}]
+	}`), http.StatusOK)
+	if replaced["userName"] != "augusta@example.org" || replaced["active"] != true || replaced["phoneNumbers"] != nil {
+		t.Errorf("Expected the primary email, active and no phone number, got %v", replaced)
+	}
+
+	got := decode(t, do(handler, http.MethodGet, "/Users/"+id+"?attributes=userName", ""), http.StatusOK)
+	if got["userName"] != "augusta@example.org" || got["id"] != id || got["name"] != nil {
+		t.Errorf("Expected only the id and userName, got %v", got)
+	}
+
+	if w := do(handler, http.MethodDelete, "/Users/"+id, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d: %s", w.Code, w.Body)
+	}
+	body = decode(t, do(handler, http.MethodGet, "/Users/"+id, ""), http.StatusNotFound)
+	if body["status"] != "404" {
+		t.Errorf("Expected a SCIM 404 error, got %v", body)
+	}
+}
+
+func TestUsers_PatchUserName(t *testing.T) {
+	handler, userStore := newTestHandler(t)
+	id := createUser(t, handler, "ada@example.com")["id"].(string)
+
+	// either half of the address may change alone
+	decode(t, do(handler, http.MethodPatch, "/Users/"+id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "replace", "path": "userName", "value": "ada@example.net"}]
+	}`), http.StatusOK)
+	user, _, _ := userStore.GetUserById(t.Context(), uuid.MustParse(id))
+	if user.Email != "ada@example.net" {
+		t.Errorf("Expected the new userName to be the email, got %s", user.Email)
+	}
+
+	body := decode(t, do(handler, http.MethodPatch, "/Users/"+id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "replace", "path": "userName", "value": "ada@example.org"},
+			{"op": "replace", "path": "emails[primary eq true].value", "value": "lovelace@example.org"}
+		]
+	}`), http.StatusBadRequest)
+	if body["scimType"] != mutability {
+		t.Errorf("Expected a mutability error, got %v", body)
+	}
+	user, _, _ = userStore.GetUserById(t.Context(), uuid.MustParse(id))
+	if user.Email != "ada@example.net" {
+		t.Errorf("Expected the refused patch to leave the email alone, got %s", user.Email)
+	}
+}
+
+func TestUsers_Invalid(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	id := createUser(t, handler, "ada@example.com")["id"].(string)
+
+	tests := []struct {
+		method, target, body, scimType string
+	}{
+		{http.MethodPost, "/Users", `{"userName": "a@example.com"}`, invalidSyntax},
+		{http.MethodPost, "/Users", `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"]}`, invalidValue},
+		{http.MethodPost, "/Users", `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "ada"}`, invalidValue},
+		{http.MethodPost, "/Users", `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "b@example.com"}`, invalidValue},
+		{http.MethodPost, "/Users", `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "b@example.com",
+			"name": {"givenName": "B", "familyName": "Lovelace"}}`, invalidValue},
+		{http.MethodPost, "/Users", `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "b@example.com",
+			"name": {"givenName": "Ada", "familyName": "Lovelace"}, "phoneNumbers": [{"value": "555"}]}`, invalidValue},
+		{http.MethodPatch, "/Users/" + id, `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+			"Operations": [{"op": "replace", "path": "groups", "value": []}]}`, mutability},
+		{http.MethodGet, "/Users?filter=" + "userName%20is%20x", "", invalidFilter},
+	}
+
+	for _, test := range tests {
+		body := decode(t, do(handler, test.method, test.target, test.body), http.StatusBadRequest)
+		if body["scimType"] != test.scimType {
+			t.Errorf("Expected %s for %s %s, got %v", test.scimType, test.method, test.target, body)
+		}
+	}
+}
+
+func TestUsers_Attributes(t *testing.T) {
+	handler, userStore := newTestHandler(t)
+	schema := `{"type": "object", "properties": {"department": {"type": "string"}, "floor": {"type": "integer"}}, "required": ["department"]}`
+	if _, err := userStore.SetAttributeSchema(t.Context(), json.RawMessage(schema)); err != nil {
+		t.Fatalf("SetAttributeSchema failed: %v", err)
+	}
+
+	body := decode(t, do(handler, http.MethodPost, "/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "ada@example.com",
+		"name": {"givenName": "Ada", "familyName": "Lovelace"}
+	}`), http.StatusBadRequest)
+	if body["scimType"] != invalidValue || !strings.Contains(body["detail"].(string), userExtensionSchema) {
+		t.Errorf("Expected an invalidValue error naming the extension, got %v", body)
+	}
+
+	created := decode(t, do(handler, http.MethodPost, "/Users", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User", "`+userExtensionSchema+`"],
+		"userName": "ada@example.com",
+		"name": {"givenName": "Ada", "familyName": "Lovelace"},
+		"`+userExtensionSchema+`": {"department": "eng"}
+	}`), http.StatusCreated)
+	id := created["id"].(string)
+	if extension, _ := created[userExtensionSchema].(map[string]any); extension["department"] != "eng" {
+		t.Errorf("Expected the attributes in the extension, got %v", created)
+	}
+
+	decode(t, do(handler, http.MethodPatch, "/Users/"+id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "replace", "path": "`+userExtensionSchema+`:department", "value": "sales"},
+			{"op": "add", "value": {"`+userExtensionSchema+`": {"floor": 3}}}
+		]
+	}`), http.StatusOK)
+	user, _, _ := userStore.GetUserById(t.Context(), uuid.MustParse(id))
+	if user.Attributes["department"] != "sales" || user.Attributes["floor"] != 3.0 {
+		t.Errorf("Expected the patched attributes, got %v", user.Attributes)
+	}
+
+	// identity providers that do not know the extension leave the attributes
+	decode(t, do(handler, http.MethodPut, "/Users/"+id, `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "ada@example.com",
+		"name": {"givenName": "Augusta", "familyName": "Lovelace"}
+	}`), http.StatusOK)
+	user, _, _ = userStore.GetUserById(t.Context(), uuid.MustParse(id))
+	if user.FirstName != "Augusta" || user.Attributes["department"] != "sales" {
+		t.Errorf("Expected the attributes to be kept, got %+v", user)
+	}
+}
+
+func TestUsers_List(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.org", "e@example.org"} {
+		createUser(t, handler, email)
+	}
+
+	body := decode(t, do(handler, http.MethodGet, "/Users?startIndex=2&count=2", ""), http.StatusOK)
+	if body["totalResults"] != 5.0 || body["startIndex"] != 2.0 || body["itemsPerPage"] != 2.0 || len(body["Resources"].([]any)) != 2 {
+		t.Errorf("Expected the second page of two of five users, got %v", body)
+	}
+
+	body = decode(t, do(handler, http.MethodGet, `/Users?filter=userName+eq+%22B%40EXAMPLE.COM%22`, ""), http.StatusOK)
+	resources := body["Resources"].([]any)
+	if body["totalResults"] != 1.0 || resources[0].(map[string]any)["userName"] != "b@example.com" {
+		t.Errorf("Expected b@example.com, got %v", body)
+	}
+
+	body = decode(t, do(handler, http.MethodGet, `/Users?filter=emails.value+ew+%22.org%22&startIndex=10`, ""), http.StatusOK)
+	if body["totalResults"] != 2.0 || len(body["Resources"].([]any)) != 0 {
+		t.Errorf("Expected an empty page of two users, got %v", body)
+	}
+}
+
+func TestGroups_Lifecycle(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	ada := createUser(t, handler, "ada@example.com")["id"].(string)
+	grace := createUser(t, handler, "grace@example.com")["id"].(string)
+
+	created := decode(t, do(handler, http.MethodPost, "/Groups", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+		"displayName": "Engineering",
+		"members": [{"value": "`+ada+`"}]
+	}`), http.StatusCreated)
+	id := created["id"].(string)
+	if members := created["members"].([]any); len(members) != 1 || members[0].(map[string]any)["value"] != ada {
+		t.Errorf("Expected ada as the only member, got %v", created)
+	}
+
+	decode(t, do(handler, http.MethodPost, "/Groups", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+		"displayName": "Engineering"
+	}`), http.StatusConflict)
+	decode(t, do(handler, http.MethodPost, "/Groups", `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+		"displayName": "Design",
+		"members": [{"value": "`+uuid.NewString()+`"}]
+	}`), http.StatusBadRequest)
+
+	patched := decode(t, do(handler, http.MethodPatch, "/Groups/"+id, `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Add", "path": "members", "value": [{"value": "`+grace+`"}]},
+			{"op": "Remove", "path": "members[value eq \"`+ada+`\"]"},
+			{"op": "Replace", "value": {"displayName": "Platform"}}
+		]
+	}`), http.StatusOK)
+	if members := patched["members"].([]any); patched["displayName"] != "Platform" || len(members) != 1 || members[0].(map[string]any)["value"] != grace {
+		t.Errorf("Expected Platform with grace as the only member, got %v", patched)
+	}
+
+	user := decode(t, do(handler, http.MethodGet, "/Users/"+grace, ""), http.StatusOK)
+	if groups, _ := user["groups"].([]any); len(groups) != 1 || groups[0].(map[string]any)["display"] != "Platform" {
+		t.Errorf("Expected grace to be in Platform, got %v", user)
+	}
+
+	list := decode(t, do(handler, http.MethodGet, `/Groups?filter=displayName+eq+%22platform%22&excludedAttributes=members`, ""), http.StatusOK)
+	resources := list["Resources"].([]any)
+	if list["totalResults"] != 1.0 || resources[0].(map[string]any)["members"] != nil {
+		t.Errorf("Expected Platform without its members, got %v", list)
+	}
+
+	replaced := decode(t, do(handler, http.MethodPut, "/Groups/"+id, `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+		"displayName": "Platform"
+	}`), http.StatusOK)
+	if replaced["members"] != nil {
+		t.Errorf("Expected no members, got %v", replaced)
+	}
+
+	if w := do(handler, http.MethodDelete, "/Groups/"+id, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d: %s", w.Code, w.Body)
+	}
+	decode(t, do(handler, http.MethodGet, "/Groups/"+id, ""), http.StatusNotFound)
+}
+
+// countingStore counts the reads of group members.
+type countingStore struct {
+	*memory.UserStore
+	memberReads int
+}
+
+func (store *countingStore) ListUsers(ctx context.Context, filter model.UserFilter, after uuid.UUID, limit int) ([]model.User, error) {
+	if filter.GroupId != uuid.Nil {
+		store.memberReads++
+	}
+	return store.UserStore.ListUsers(ctx, filter, after, limit)
+}
+
+func (store *countingStore) ListGroupMembers(ctx context.Context, groupIds []uuid.UUID) (map[uuid.UUID][]model.User, error) {
+	store.memberReads++
+	return store.UserStore.ListGroupMembers(ctx, groupIds)
+}
+
+func TestGroups_ListReadsMembersOnce(t *testing.T) {
+	userStore := &countingStore{UserStore: memory.NewUserStore()}
+	handler := NewHandler(userStore, userStore, Config{Token: testToken, BaseURL: "https://example.com/scim/v2/"})
+	ada := createUser(t, handler, "ada@example.com")["id"].(string)
+	for _, name := range []string{"Engineering", "Design", "Sales"} {
+		decode(t, do(handler, http.MethodPost, "/Groups", `{
+			"schemas": ["urn:ietf:params:scim:schemas:core:2.0:Group"],
+			"displayName": "`+name+`",
+			"members": [{"value": "`+ada+`"}]
+		}`), http.StatusCreated)
+	}
+
+	userStore.memberReads = 0
+	body := decode(t, do(handler, http.MethodGet, "/Groups", ""), http.StatusOK)
+	for _, resource := range body["Resources"].([]any) {
+		if members, _ := resource.(map[string]any)["members"].([]any); len(members) != 1 {
+			t.Errorf("Expected ada as the member, got %v", resource)
+		}
+	}
+	if userStore.memberReads != 1 {
+		t.Errorf("Expected the members of all groups in one read, got %d", userStore.memberReads)
+	}
+}
+
+func TestDiscovery(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	config := decode(t, do(handler, http.MethodGet, "/ServiceProviderConfig", ""), http.StatusOK)
+	if config["patch"].(map[string]any)["supported"] != true || config["bulk"].(map[string]any)["supported"] != false {
+		t.Errorf("Expected patch without bulk, got %v", config)
+	}
+
+	resourceTypes := decode(t, do(handler, http.MethodGet, "/ResourceTypes", ""), http.StatusOK)
+	if resourceTypes["totalResults"] != 2.0 {
+		t.Errorf("Expected two resource types, got %v", resourceTypes)
+	}
+	decode(t, do(handler, http.MethodGet, "/ResourceTypes/Group", ""), http.StatusOK)
+	decode(t, do(handler, http.MethodGet, "/ResourceTypes/Device", ""), http.StatusNotFound)
+
+	schema := decode(t, do(handler, http.MethodGet, "/Schemas/"+userSchema, ""), http.StatusOK)
+	if schema["name"] != "User" || schema["meta"].(map[string]any)["location"] != "https://example.com/scim/v2/Schemas/"+userSchema {
+		t.Errorf("Expected the user schema, got %v", schema)
+	}
+	schemas := decode(t, do(handler, http.MethodGet, "/Schemas", ""), http.StatusOK)
+	if schemas["totalResults"] != 3.0 {
+		t.Errorf("Expected the user, extension and group schemas, got %v", schemas)
+	}
+	userType := decode(t, do(handler, http.MethodGet, "/ResourceTypes/User", ""), http.StatusOK)
+	if extensions, _ := userType["schemaExtensions"].([]any); len(extensions) != 1 ||
+		extensions[0].(map[string]any)["schema"] != userExtensionSchema {
+		t.Errorf("Expected the user type to name the extension, got %v", userType)
+	}
+}
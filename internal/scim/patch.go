@@ -0,0 +1,349 @@
+package scim
+
+import (
+	"slices"
+	"strings"
+)
+
+type patchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []patchOperation `json:"Operations"`
+}
+
+type patchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+// applyPatch applies the operations of RFC 7644 section 3.5.2 to resource, the
+// JSON form of a resource. Operations naming one of the readOnly attributes
+// fail.
+//
+// Identity providers address a value by its type before it exists, as in
+// emails[type eq "work"].value, so adding or replacing through a filter that
+// matches nothing adds the value instead of failing. As the service keeps
+// only one email and phone number, the added value becomes the primary one.
+func applyPatch(resource map[string]any, operations []patchOperation, readOnly []string) error {
+	if len(operations) == 0 {
+		return badRequest(invalidValue, "no operations")
+	}
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		switch op {
+		case "add", "replace", "remove":
+		default:
+			return badRequest(invalidSyntax, "unsupported operation %q", operation.Op)
+		}
+
+		if operation.Path != "" {
+			if err := applyOperation(resource, op, operation.Path, operation.Value, readOnly); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if op == "remove" {
+			return badRequest(noTarget, "remove needs a path")
+		}
+		values, ok := operation.Value.(map[string]any)
+		if !ok {
+			return badRequest(invalidValue, "%s without a path needs an object value", op)
+		}
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			if err := applyOperation(resource, op, key, values[key], readOnly); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cutSchema returns the attribute path after schema, the URN pathString is
+// prefixed with, if it is.
+func cutSchema(pathString, schema string) (string, bool) {
+	if len(pathString) < len(schema) || !strings.EqualFold(pathString[:len(schema)], schema) {
+		return "", false
+	}
+	rest := pathString[len(schema):]
+	if rest == "" {
+		return "", true
+	}
+	if rest[0] != ':' {
+		return "", false
+	}
+	return rest[1:], true
+}
+
+// applyExtension applies an operation on the extension of custom attributes,
+// which the resource holds as an object of its own, or on its attribute path.
+func applyExtension(resource map[string]any, op, path string, value any) error {
+	extension, _ := resource[userExtensionSchema].(map[string]any)
+	if extension == nil {
+		extension = map[string]any{}
+	}
+
+	if path == "" {
+		values, ok := value.(map[string]any)
+		switch {
+		case op == "remove":
+			extension = map[string]any{}
+		case !ok:
+			return badRequest(invalidValue, "%s needs an object value", userExtensionSchema)
+		case op == "replace":
+			extension = values
+		default:
+			for key, value := range values {
+				extension[key] = value
+			}
+		}
+	} else if err := applyOperation(extension, op, path, value, nil); err != nil {
+		return err
+	}
+
+	resource[userExtensionSchema] = extension
+	return nil
+}
+
+func applyOperation(resource map[string]any, op, pathString string, value any, readOnly []string) error {
+	if rest, ok := cutSchema(pathString, userExtensionSchema); ok {
+		return applyExtension(resource, op, rest, value)
+	}
+
+	path, err := parsePatchPath(pathString)
+	if err != nil {
+		return badRequest(invalidPath, "%s", err)
+	}
+	for _, attr := range readOnly {
+		if strings.EqualFold(attr, path.attr) {
+			return badRequest(mutability, "%s is read-only", attr)
+		}
+	}
+	if op != "remove" && value == nil {
+		return badRequest(invalidValue, "%s of %s needs a value", op, pathString)
+	}
+
+	key := existingKey(resource, path.attr)
+	current := resource[key]
+
+	if path.filter != nil {
+		return applyFiltered(resource, key, op, path, value)
+	}
+
+	if path.sub != "" {
+		if _, multi := current.([]any); multi {
+			return badRequest(invalidPath, "%s needs a filter to select values", path.attr)
+		}
+		complexValue, _ := current.(map[string]any)
+		if complexValue == nil {
+			if op == "remove" {
+				return nil
+			}
+			complexValue = map[string]any{}
+		}
+		subKey := existingKey(complexValue, path.sub)
+		if op == "remove" {
+			delete(complexValue, subKey)
+		} else {
+			complexValue[subKey] = value
+		}
+		resource[key] = complexValue
+		return nil
+	}
+
+	switch op {
+	case "remove":
+		// remove with a value takes just those values out, as identity
+		// providers do to remove group members
+		if elements, multi := current.([]any); multi && value != nil {
+			removed := asSlice(value)
+			resource[key] = slices.DeleteFunc(elements, func(element any) bool {
+				return slices.ContainsFunc(removed, func(r any) bool { return sameValue(element, r) })
+			})
+			return nil
+		}
+		delete(resource, key)
+	case "add":
+		resource[key] = addValue(current, value)
+	case "replace":
+		currentComplex, okCurrent := current.(map[string]any)
+		valueComplex, okValue := value.(map[string]any)
+		if okCurrent && okValue {
+			resource[key] = merge(currentComplex, valueComplex)
+		} else {
+			resource[key] = value
+		}
+	}
+	return nil
+}
+
+// applyFiltered applies an operation to the values of a multi-valued
+// attribute selected by the filter of path.
+func applyFiltered(resource map[string]any, key, op string, path patchPath, value any) error {
+	current := resource[key]
+	elements, multi := current.([]any)
+	if current != nil && !multi {
+		return badRequest(invalidPath, "%s is not multi-valued", path.attr)
+	}
+
+	matched := false
+	kept := elements[:0:0]
+	for _, element := range elements {
+		complexValue, ok := element.(map[string]any)
+		if !ok || !path.filter.matches(complexValue) {
+			kept = append(kept, element)
+			continue
+		}
+		matched = true
+
+		switch {
+		case op == "remove" && path.sub == "":
+			continue
+		case op == "remove":
+			delete(complexValue, existingKey(complexValue, path.sub))
+		case path.sub != "":
+			complexValue[existingKey(complexValue, path.sub)] = value
+		case op == "replace":
+			if replacement, ok := value.(map[string]any); ok {
+				complexValue = replacement
+			}
+		default:
+			if addition, ok := value.(map[string]any); ok {
+				complexValue = merge(complexValue, addition)
+			}
+		}
+		kept = append(kept, complexValue)
+	}
+
+	if !matched {
+		if op == "remove" {
+			return badRequest(noTarget, "no values match %s", path.attr)
+		}
+		added := equalities(path.filter)
+		if path.sub != "" {
+			added[path.sub] = value
+		} else if addition, ok := value.(map[string]any); ok {
+			added = merge(added, addition)
+		}
+		if slices.ContainsFunc(elements, isPrimary) {
+			added["primary"] = true
+		}
+		kept = addValue(kept, added).([]any)
+	}
+	resource[key] = kept
+	return nil
+}
+
+// addValue adds value to current. Values are appended to multi-valued
+// attributes unless already present, sub-attributes are merged into complex
+// ones and others are replaced.
+func addValue(current, value any) any {
+	switch current := current.(type) {
+	case []any:
+		added := asSlice(value)
+		elements := slices.Clone(current)
+		for _, element := range added {
+			if isPrimary(element) {
+				for _, other := range elements {
+					if complexValue, ok := other.(map[string]any); ok {
+						delete(complexValue, existingKey(complexValue, "primary"))
+					}
+				}
+			}
+			if !slices.ContainsFunc(elements, func(e any) bool { return sameValue(e, element) }) {
+				elements = append(elements, element)
+			}
+		}
+		return elements
+	case map[string]any:
+		if valueComplex, ok := value.(map[string]any); ok {
+			return merge(current, valueComplex)
+		}
+	}
+	return value
+}
+
+func merge(current, value map[string]any) map[string]any {
+	merged := make(map[string]any, len(current)+len(value))
+	for key, v := range current {
+		merged[key] = v
+	}
+	for key, v := range value {
+		merged[existingKey(merged, key)] = v
+	}
+	return merged
+}
+
+// equalities returns the attributes an "eq" filter requires, such as
+// type: "work" for type eq "work", for a value added through the filter.
+func equalities(expr filter) map[string]any {
+	values := map[string]any{}
+	switch expr := expr.(type) {
+	case comparison:
+		if expr.op == "eq" && expr.path.sub == "" && expr.value != nil {
+			values[expr.path.attr] = expr.value
+		}
+	case logical:
+		if !expr.or {
+			values = merge(equalities(expr.left), equalities(expr.right))
+		}
+	}
+	return values
+}
+
+// existingKey returns the key of resource that matches name ignoring case,
+// or name if there is none.
+func existingKey(resource map[string]any, name string) string {
+	if _, ok := resource[name]; ok {
+		return name
+	}
+	for key := range resource {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
+
+func asSlice(value any) []any {
+	if values, ok := value.([]any); ok {
+		return values
+	}
+	return []any{value}
+}
+
+// sameValue reports whether two values of a multi-valued attribute have the
+// same "value" sub-attribute.
+func sameValue(a, b any) bool {
+	valueA, okA := valueOf(a)
+	valueB, okB := valueOf(b)
+	return okA && okB && strings.EqualFold(valueA, valueB)
+}
+
+func valueOf(element any) (string, bool) {
+	complexValue, ok := element.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	value, ok := lookup(complexValue, "value").(string)
+	return value, ok
+}
+
+func isPrimary(element any) bool {
+	complexValue, ok := element.(map[string]any)
+	if !ok {
+		return false
+	}
+	switch primary := lookup(complexValue, "primary").(type) {
+	case bool:
+		return primary
+	case string:
+		return strings.EqualFold(primary, "true")
+	}
+	return false
+}
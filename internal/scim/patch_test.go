@@ -0,0 +1,163 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func decodeMap(t *testing.T, s string) map[string]any {
+	t.Helper()
+
+	var m map[string]any
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		t.Fatalf("Failed to decode %s: %v", s, err)
+	}
+	return m
+}
+
+func decodeOperations(t *testing.T, s string) []patchOperation {
+	t.Helper()
+
+	var operations []patchOperation
+	if err := json.Unmarshal([]byte(s), &operations); err != nil {
+		t.Fatalf("Failed to decode %s: %v", s, err)
+	}
+	return operations
+}
+
+func TestApplyPatch(t *testing.T) {
+	const user = `{
+		"userName": "ada@example.com",
+		"name": {"givenName": "Ada", "familyName": "Lovelace"},
+		"emails": [{"value": "ada@example.com", "type": "work", "primary": true}],
+		"active": true
+	}`
+
+	tests := []struct {
+		name       string
+		operations string
+		want       string
+	}{
+		{
+			name:       "replace with a path",
+			operations: `[{"op": "replace", "path": "name.givenName", "value": "Augusta"}]`,
+			want: `{
+				"userName": "ada@example.com",
+				"name": {"givenName": "Augusta", "familyName": "Lovelace"},
+				"emails": [{"value": "ada@example.com", "type": "work", "primary": true}],
+				"active": true
+			}`,
+		},
+		{
+			name:       "replace without a path",
+			operations: `[{"op": "Replace", "value": {"active": "False", "name.familyName": "King"}}]`,
+			want: `{
+				"userName": "ada@example.com",
+				"name": {"givenName": "Ada", "familyName": "King"},
+				"emails": [{"value": "ada@example.com", "type": "work", "primary": true}],
+				"active": "False"
+			}`,
+		},
+		{
+			name:       "replace through a filter",
+			operations: `[{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "ada@example.org"}]`,
+			want: `{
+				"userName": "ada@example.com",
+				"name": {"givenName": "Ada", "familyName": "Lovelace"},
+				"emails": [{"value": "ada@example.org", "type": "work", "primary": true}],
+				"active": true
+			}`,
+		},
+		{
+			name:       "add through a filter that matches nothing",
+			operations: `[{"op": "add", "path": "emails[type eq \"home\"].value", "value": "ada@example.org"}]`,
+			want: `{
+				"userName": "ada@example.com",
+				"name": {"givenName": "Ada", "familyName": "Lovelace"},
+				"emails": [
+					{"value": "ada@example.com", "type": "work"},
+					{"value": "ada@example.org", "type": "home", "primary": true}
+				],
+				"active": true
+			}`,
+		},
+		{
+			name:       "add to a missing attribute",
+			operations: `[{"op": "add", "path": "phoneNumbers", "value": [{"value": "+12025550123", "type": "mobile"}]}]`,
+			want: `{
+				"userName": "ada@example.com",
+				"name": {"givenName": "Ada", "familyName": "Lovelace"},
+				"emails": [{"value": "ada@example.com", "type": "work", "primary": true}],
+				"phoneNumbers": [{"value": "+12025550123", "type": "mobile"}],
+				"active": true
+			}`,
+		},
+		{
+			name:       "remove",
+			operations: `[{"op": "remove", "path": "name.familyName"}, {"op": "remove", "path": "active"}]`,
+			want: `{
+				"userName": "ada@example.com",
+				"name": {"givenName": "Ada"},
+				"emails": [{"value": "ada@example.com", "type": "work", "primary": true}]
+			}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			resource := decodeMap(t, user)
+			if err := applyPatch(resource, decodeOperations(t, test.operations), []string{"id"}); err != nil {
+				t.Fatalf("Failed to apply: %v", err)
+			}
+			if want := decodeMap(t, test.want); !reflect.DeepEqual(resource, want) {
+				t.Errorf("Expected %v, got %v", want, resource)
+			}
+		})
+	}
+}
+
+func TestApplyPatch_Members(t *testing.T) {
+	resource := decodeMap(t, `{"displayName": "Ops", "members": [{"value": "a"}, {"value": "b"}]}`)
+
+	operations := decodeOperations(t, `[
+		{"op": "add", "path": "members", "value": [{"value": "b"}, {"value": "c"}]},
+		{"op": "remove", "path": "members", "value": [{"value": "a"}]},
+		{"op": "remove", "path": "members[value eq \"c\"]"}
+	]`)
+	if err := applyPatch(resource, operations, nil); err != nil {
+		t.Fatalf("Failed to apply: %v", err)
+	}
+
+	want := decodeMap(t, `{"displayName": "Ops", "members": [{"value": "b"}]}`)
+	if !reflect.DeepEqual(resource, want) {
+		t.Errorf("Expected %v, got %v", want, resource)
+	}
+}
+
+func TestApplyPatch_Errors(t *testing.T) {
+	tests := []struct {
+		operations string
+		scimType   string
+	}{
+		{`[]`, invalidValue},
+		{`[{"op": "move", "path": "active"}]`, invalidSyntax},
+		{`[{"op": "replace", "path": "id", "value": "x"}]`, mutability},
+		{`[{"op": "replace", "path": "emails[type eq", "value": "x"}]`, invalidPath},
+		{`[{"op": "remove"}]`, noTarget},
+		{`[{"op": "remove", "path": "members[value eq \"z\"]"}]`, noTarget},
+		{`[{"op": "replace", "path": "displayName"}]`, invalidValue},
+		{`[{"op": "add", "value": "x"}]`, invalidValue},
+	}
+
+	for _, test := range tests {
+		resource := decodeMap(t, `{"id": "1", "displayName": "Ops", "members": [{"value": "a"}]}`)
+		err := applyPatch(resource, decodeOperations(t, test.operations), []string{"id"})
+
+		var scimErr *Error
+		if !errors.As(err, &scimErr) || scimErr.ScimType != test.scimType {
+			t.Errorf("Expected %s for %s, got %v", test.scimType, test.operations, err)
+		}
+	}
+}
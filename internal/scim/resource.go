@@ -0,0 +1,309 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"example.com/user-management/internal/dto"
+	"example.com/user-management/internal/model"
+	"github.com/go-playground/validator/v10"
+)
+
+var validate = validator.New()
+
+type userResource struct {
+	Schemas      []string     `json:"schemas"`
+	ID           string       `json:"id,omitempty"`
+	UserName     string       `json:"userName"`
+	Name         *name        `json:"name,omitempty"`
+	Emails       []multiValue `json:"emails,omitempty"`
+	PhoneNumbers []multiValue `json:"phoneNumbers,omitempty"`
+	Active       *flexBool    `json:"active,omitempty"`
+	Groups       []reference  `json:"groups,omitempty"`
+	// Attributes are the user's custom attributes, those of the tenant's
+	// attribute schema.
+	Attributes map[string]any `json:"urn:user-management:params:scim:schemas:extension:attributes:2.0:User,omitempty"`
+	Meta       *meta          `json:"meta,omitempty"`
+}
+
+type name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+}
+
+type multiValue struct {
+	Value   string   `json:"value"`
+	Type    string   `json:"type,omitempty"`
+	Primary flexBool `json:"primary,omitempty"`
+}
+
+type reference struct {
+	Value   string `json:"value"`
+	Ref     string `json:"$ref,omitempty"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+}
+
+type groupResource struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	DisplayName string      `json:"displayName"`
+	Members     []reference `json:"members,omitempty"`
+	Meta        *meta       `json:"meta,omitempty"`
+}
+
+type meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+// flexBool also reads the strings "true" and "false", in any case, which
+// some identity providers send for booleans.
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var s string
+	if json.Unmarshal(data, &s) == nil {
+		parsed, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*b = flexBool(parsed)
+		return nil
+	}
+	var parsed bool
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return err
+	}
+	*b = flexBool(parsed)
+	return nil
+}
+
+func (handler *Handler) userResource(user model.User, groups []model.Group) userResource {
+	active := flexBool(user.Status == model.StatusActive)
+	resource := userResource{
+		Schemas:  []string{userSchema},
+		ID:       user.UserId.String(),
+		UserName: user.Email,
+		Name: &name{
+			Formatted:  strings.TrimSpace(user.FirstName + " " + user.LastName),
+			FamilyName: user.LastName,
+			GivenName:  user.FirstName,
+		},
+		Emails: []multiValue{{Value: user.Email, Type: "work", Primary: true}},
+		Active: &active,
+		Meta: &meta{
+			ResourceType: "User",
+			Location:     handler.location("Users", user.UserId.String()),
+		},
+	}
+	if user.Phone != "" {
+		resource.PhoneNumbers = []multiValue{{Value: user.Phone, Type: "work", Primary: true}}
+	}
+	if len(user.Attributes) > 0 {
+		resource.Schemas = append(resource.Schemas, userExtensionSchema)
+		resource.Attributes = user.Attributes
+	}
+	for _, group := range groups {
+		resource.Groups = append(resource.Groups, reference{
+			Value:   group.GroupId.String(),
+			Ref:     handler.location("Groups", group.GroupId.String()),
+			Display: group.Name,
+			Type:    "direct",
+		})
+	}
+	return resource
+}
+
+// apply sets the fields of user the resource holds, leaving the others, such
+// as Age, alone, and checks the user as the REST API does. The user has one
+// address for userName and the primary email, so a resource giving them
+// different values is refused rather than having one of them dropped.
+// Custom attributes are only replaced if the resource has the extension.
+func (resource userResource) apply(user *model.User) error {
+	userName := strings.TrimSpace(resource.UserName)
+	email := strings.TrimSpace(primaryValue(resource.Emails))
+	switch {
+	case email == "" && userName == "":
+		return badRequest(invalidValue, "userName or an email is required")
+	case email == "":
+		email = userName
+	case userName != "" && !strings.EqualFold(userName, email):
+		return badRequest(mutability, "userName %q must be the primary email %q", userName, email)
+	}
+	if validate.Var(email, "email") != nil {
+		return badRequest(invalidValue, "%q is not an email address", email)
+	}
+
+	// stores parse the number, as entered or in the national format of
+	// their default region
+	phone := strings.TrimSpace(primaryValue(resource.PhoneNumbers))
+
+	var firstName, lastName string
+	if resource.Name != nil {
+		firstName, lastName = resource.Name.GivenName, resource.Name.FamilyName
+	}
+
+	user.FirstName = firstName
+	user.LastName = lastName
+	user.Email = email
+	user.Phone = phone
+	user.Status = model.StatusActive
+	if resource.Active != nil && !*resource.Active {
+		user.Status = model.StatusInactive
+	}
+	if resource.Attributes != nil {
+		user.Attributes = resource.Attributes
+	}
+	return validateUser(*user)
+}
+
+// scimPaths name the fields of dto.CreateUserRequest by the attributes of a
+// SCIM user that hold them.
+var scimPaths = map[string]string{
+	"FirstName": "name.givenName",
+	"LastName":  "name.familyName",
+	"Email":     "userName",
+	"Phone":     "phoneNumbers",
+}
+
+// validateUser checks user by the rules users are created by over REST, but
+// for the phone number, which identity providers often do not have.
+func validateUser(user model.User) error {
+	request := dto.CreateUserRequest{
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+		Phone:     user.Phone,
+		Status:    user.Status,
+	}
+	var err error
+	if user.Phone == "" {
+		err = validate.StructExcept(request, "Phone")
+	} else {
+		err = validate.Struct(request)
+	}
+
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	field := invalid[0]
+	path := scimPaths[field.Field()]
+	switch field.Tag() {
+	case "required":
+		return badRequest(invalidValue, "%s is required", path)
+	case "min":
+		return badRequest(invalidValue, "%s must be at least %s characters", path, field.Param())
+	case "max":
+		return badRequest(invalidValue, "%s must be at most %s characters", path, field.Param())
+	}
+	return badRequest(invalidValue, "%s is invalid", path)
+}
+
+// primaryValue returns the primary value, or the first one if none is marked
+// primary, since the service keeps one email and phone number.
+func primaryValue(values []multiValue) string {
+	for _, value := range values {
+		if value.Primary {
+			return value.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+func (handler *Handler) groupResource(group model.Group, members []model.User) groupResource {
+	created := group.CreatedAt.UTC()
+	resource := groupResource{
+		Schemas:     []string{groupSchema},
+		ID:          group.GroupId.String(),
+		DisplayName: group.Name,
+		Meta: &meta{
+			ResourceType: "Group",
+			Created:      &created,
+			Location:     handler.location("Groups", group.GroupId.String()),
+		},
+	}
+	for _, member := range members {
+		resource.Members = append(resource.Members, reference{
+			Value:   member.UserId.String(),
+			Ref:     handler.location("Users", member.UserId.String()),
+			Display: member.Email,
+			Type:    "User",
+		})
+	}
+	return resource
+}
+
+func (resource groupResource) validate() error {
+	if length := len(resource.DisplayName); length < 2 || length > 100 {
+		return badRequest(invalidValue, "displayName must be 2 to 100 characters")
+	}
+	return nil
+}
+
+func (handler *Handler) location(endpoint, id string) string {
+	return handler.baseURL + "/" + endpoint + "/" + id
+}
+
+// toMap returns the JSON form of a resource, which filters, PATCH operations
+// and attribute selection work on.
+func toMap(resource any) map[string]any {
+	data, _ := json.Marshal(resource)
+	var m map[string]any
+	_ = json.Unmarshal(data, &m)
+	return m
+}
+
+// fromMap reads the JSON form of a resource back into resource.
+func fromMap(m map[string]any, resource any) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return badRequest(invalidValue, "%s", err)
+	}
+	if err := json.Unmarshal(data, resource); err != nil {
+		return badRequest(invalidValue, "%s", err)
+	}
+	return nil
+}
+
+// project keeps the attributes a request asked for with the attributes and
+// excludedAttributes parameters. The id and schemas are always kept.
+func project(resource map[string]any, attributes, excluded []string) map[string]any {
+	if len(attributes) == 0 && len(excluded) == 0 {
+		return resource
+	}
+	named := func(names []string, key string) bool {
+		for _, name := range names {
+			path, err := parseAttrPath(name)
+			if err == nil && strings.EqualFold(path.attr, key) {
+				return true
+			}
+		}
+		return false
+	}
+
+	projected := make(map[string]any, len(resource))
+	for key, value := range resource {
+		switch {
+		case key == "id" || key == "schemas":
+		case len(attributes) > 0 && !named(attributes, key):
+			continue
+		case named(excluded, key):
+			continue
+		}
+		projected[key] = value
+	}
+	return projected
+}
@@ -0,0 +1,110 @@
+// Package scim serves users and groups over SCIM 2.0 (RFC 7643 and RFC 7644)
+// so identity providers can provision them.
+//
+// A SCIM user is a model.User: userName and the primary email are both the
+// user's Email, so a request giving them different values is refused with
+// scimType mutability, name.givenName and name.familyName are FirstName and
+// LastName, the primary phone number is Phone and active is whether Status is
+// Active. The custom attributes of a user are the members of the extension
+// userExtensionSchema. A SCIM group is a model.Group whose displayName is its
+// Name and whose members are users. Attributes the service does not keep,
+// such as externalId, are accepted and dropped.
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"example.com/user-management/internal/logging"
+)
+
+// ContentType is the media type of SCIM requests and responses.
+const ContentType = "application/scim+json"
+
+const (
+	userSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	groupSchema        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	listResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	patchOpSchema      = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	errorSchema        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// userExtensionSchema holds the custom attributes of users, whose names and
+// types are those of the attribute schema of the user's tenant.
+const userExtensionSchema = "urn:user-management:params:scim:schemas:extension:attributes:2.0:User"
+
+// The scimType keywords of RFC 7644 section 3.12 that are returned.
+const (
+	invalidFilter = "invalidFilter"
+	invalidSyntax = "invalidSyntax"
+	invalidPath   = "invalidPath"
+	invalidValue  = "invalidValue"
+	noTarget      = "noTarget"
+	mutability    = "mutability"
+	uniqueness    = "uniqueness"
+)
+
+// Config enables the SCIM endpoints.
+type Config struct {
+	// Token is the bearer token identity providers authenticate with. The
+	// endpoints are off while it is empty.
+	Token string
+	// BaseURL is the absolute URL the endpoints are served under, which the
+	// locations of resources start with.
+	BaseURL string
+}
+
+// Error is a SCIM error response.
+type Error struct {
+	Status int
+	// ScimType is the keyword detailing a 400 or 409 response, such as
+	// "invalidFilter".
+	ScimType string
+	Detail   string
+}
+
+func (err *Error) Error() string {
+	return err.Detail
+}
+
+func (err *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Schemas  []string `json:"schemas"`
+		Status   string   `json:"status"`
+		ScimType string   `json:"scimType,omitempty"`
+		Detail   string   `json:"detail,omitempty"`
+	}{
+		Schemas:  []string{errorSchema},
+		Status:   fmt.Sprint(err.Status),
+		ScimType: err.ScimType,
+		Detail:   err.Detail,
+	})
+}
+
+func badRequest(scimType, format string, args ...any) *Error {
+	return &Error{Status: http.StatusBadRequest, ScimType: scimType, Detail: fmt.Sprintf(format, args...)}
+}
+
+func notFound(resourceType, id string) *Error {
+	return &Error{Status: http.StatusNotFound, Detail: fmt.Sprintf("%s %s not found", resourceType, id)}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, body any) {
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logging.FromContext(r.Context()).Error("failed to encode response", logging.Err(err))
+	}
+}
+
+// writeError sends err as a SCIM error, logging it as a server error unless
+// it is an *Error.
+func writeError(w http.ResponseWriter, r *http.Request, message string, err error) {
+	scimErr, ok := err.(*Error)
+	if !ok {
+		logging.FromContext(r.Context()).Error(message, logging.Err(err))
+		scimErr = &Error{Status: http.StatusInternalServerError, Detail: message}
+	}
+	writeJSON(w, r, scimErr.Status, scimErr)
+}
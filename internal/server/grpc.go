@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/rpc"
+	"example.com/user-management/internal/rpc/userpb"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tenant"
+	"example.com/user-management/internal/token"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// NewGRPC serves userStore over gRPC. Calls may carry an access token, as
+// "Bearer <token>" in authorization metadata, which calls only admins may
+// make need. Other calls name their tenant in x-tenant-id metadata;
+// multiTenant is false for stores that only have the default one.
+func NewGRPC(userStore store.UserStoreInterface, authenticator *auth.Authenticator, multiTenant bool) *grpc.Server {
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			ctx, err := withCaller(ctx, authenticator, multiTenant)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			ctx, err := withCaller(stream.Context(), authenticator, multiTenant)
+			if err != nil {
+				return err
+			}
+			return handler(srv, &tenantStream{ServerStream: stream, ctx: ctx})
+		}),
+	)
+
+	userpb.RegisterUserServiceServer(grpcServer, rpc.NewUserServer(userStore, authenticator))
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthServer.SetServingStatus(userpb.UserService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+
+	reflection.Register(grpcServer)
+
+	return grpcServer
+}
+
+// withCaller returns a copy of ctx carrying the claims of the call's access
+// token, if it has one, for auth.ClaimsFromContext. Like on the REST API, a
+// valid token names the tenant its bearer logged in to, whatever the call
+// claims, and an invalid one is turned away.
+func withCaller(ctx context.Context, authenticator *auth.Authenticator, multiTenant bool) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, "authorization")
+	if len(values) == 0 {
+		return withTenant(ctx, multiTenant)
+	}
+
+	accessToken, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "expected a bearer access token")
+	}
+	claims, err := authenticator.ParseAccessToken(ctx, accessToken)
+	if errors.Is(err, token.ErrInvalid) {
+		return nil, status.Error(codes.Unauthenticated, "invalid access token")
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to check access token", logging.Err(err))
+		return nil, status.Error(codes.Internal, "failed to check access token")
+	}
+	return tenant.WithID(auth.WithClaims(ctx, claims), claims.TenantId), nil
+}
+
+// withTenant returns a copy of ctx acting for the tenant in the call's
+// metadata, or for the default tenant if it names none.
+func withTenant(ctx context.Context, multiTenant bool) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, strings.ToLower(tenant.Header))
+	if len(values) == 0 {
+		return tenant.WithID(ctx, tenant.Default), nil
+	}
+
+	tenantId, err := uuid.Parse(values[0])
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid tenant id")
+	}
+	if tenantId != tenant.Default && !multiTenant {
+		return nil, status.Error(codes.InvalidArgument, "tenants not supported")
+	}
+	return tenant.WithID(ctx, tenantId), nil
+}
+
+type tenantStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (stream *tenantStream) Context() context.Context {
+	return stream.ctx
+}
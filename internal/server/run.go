@@ -0,0 +1,420 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/avatar"
+	"example.com/user-management/internal/blob"
+	"example.com/user-management/internal/config"
+	"example.com/user-management/internal/db"
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/emailaddr"
+	"example.com/user-management/internal/events"
+	"example.com/user-management/internal/health"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/mail"
+	"example.com/user-management/internal/metrics"
+	"example.com/user-management/internal/oidc"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/scim"
+	"example.com/user-management/internal/sms"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/store/cache"
+	"example.com/user-management/internal/store/memory"
+	"example.com/user-management/internal/store/sqlite"
+	"example.com/user-management/internal/token"
+	"example.com/user-management/internal/tracing"
+	"example.com/user-management/internal/verification"
+	"google.golang.org/grpc"
+)
+
+// readinessTimeout bounds each readiness check, so that a hung database
+// fails the probe rather than timing it out.
+const readinessTimeout = 2 * time.Second
+
+// Run sets up the configured store and serves the REST and gRPC APIs until
+// it receives SIGINT or SIGTERM, then drains and shuts down gracefully.
+func Run(cfg config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	slog.SetDefault(logging.New(os.Stdout, cfg.LogLevel))
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return fmt.Errorf("failed to set up tracing: %w", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("failed to flush traces", logging.Err(err))
+		}
+	}()
+
+	var userStore store.UserStoreInterface
+	var userEventStore store.UserEventStoreInterface
+	// the concrete store, for the features the decorators do not wrap
+	var concreteStore interface {
+		verification.EmailVerificationStore
+		verification.PhoneVerificationStore
+		auth.Store
+		store.GroupStoreInterface
+		store.AttributeSchemaStoreInterface
+		store.AvatarStoreInterface
+		store.BlobStoreInterface
+		store.OIDCStoreInterface
+	}
+	broker := events.NewBroker()
+	// the changes made by this instance; the local stores publish theirs to
+	// the broker, while Postgres has the broker carry those of every instance
+	localEvents := broker
+	serverMetrics := metrics.New()
+	checker := health.NewChecker(readinessTimeout)
+	emailRules := emailaddr.Rules{Providers: cfg.EmailProviderRules}
+	phoneParser := phonenumber.Parser{DefaultRegion: cfg.PhoneDefaultRegion}
+
+	switch cfg.Store {
+	case config.StoreMemory:
+		slog.Warn("using the in-memory user store, data will not be persisted")
+		memoryStore := memory.NewUserStore()
+		memoryStore.OnEvent(broker.Publish)
+		memoryStore.SetEmailRules(emailRules)
+		memoryStore.SetPhoneParser(phoneParser)
+		serverMetrics.RegisterUserCounts(memoryStore)
+		userStore, userEventStore, concreteStore = memoryStore, memoryStore, memoryStore
+
+	case config.StoreSQLite:
+		dbConn, err := sqlitedb.Open(cfg.SQLitePath)
+		if err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+		defer dbConn.Close()
+
+		// the database file belongs to this process, so keep it migrated
+		if _, err := sqlitedb.MigrateUp(dbConn, emailRules); err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+		if err := sqlitedb.CheckEmailRules(ctx, dbConn, emailRules); err != nil {
+			return err
+		}
+
+		sqliteStore := sqlite.NewUserStore(dbConn)
+		sqliteStore.OnEvent(broker.Publish)
+		sqliteStore.SetEmailRules(emailRules)
+		sqliteStore.SetPhoneParser(phoneParser)
+		serverMetrics.RegisterDB(dbConn, "sqlite")
+		serverMetrics.RegisterUserCounts(sqliteStore)
+		checker.Add("database", dbConn.PingContext)
+		checker.Add("migrations", func(ctx context.Context) error {
+			return checkMigrations(sqlitedb.HasPendingMigrations(ctx, dbConn))
+		})
+		userStore, concreteStore = sqliteStore, sqliteStore
+		userEventStore = sqlite.NewUserEventStore(dbConn)
+
+	default:
+		connectCtx, cancel := context.WithTimeout(ctx, cfg.DBConnectTimeout)
+		dbConn, err := db.OpenPostgresWithRetry(connectCtx, cfg.DSN)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer dbConn.Close()
+
+		postgresStore := store.NewUserStore(dbConn)
+		localEvents = events.NewBroker()
+		postgresStore.OnEvent(localEvents.Publish)
+		postgresStore.SetEmailRules(emailRules)
+		postgresStore.SetPhoneParser(phoneParser)
+		serverMetrics.RegisterDB(dbConn, "postgres")
+		serverMetrics.RegisterUserCounts(postgresStore)
+		checker.Add("database", dbConn.PingContext)
+		checkSchema := func(ctx context.Context) error {
+			if err := checkMigrations(db.HasPendingMigrations(ctx, dbConn)); err != nil {
+				return err
+			}
+			return db.CheckEmailRules(ctx, dbConn, emailRules)
+		}
+		// addresses keyed by other rules than the store's would let users
+		// share a mailbox; until the migrations run, readiness waits instead
+		if err := checkSchema(ctx); errors.Is(err, db.ErrEmailRulesMismatch) {
+			return err
+		}
+		checker.Add("migrations", checkSchema)
+		userStore, concreteStore = postgresStore, postgresStore
+		postgresEventStore := store.NewUserEventStore(dbConn)
+		userEventStore = postgresEventStore
+
+		listener, err := events.NewPostgresListener(cfg.DSN, broker, postgresEventStore)
+		if err != nil {
+			return fmt.Errorf("failed to listen for user events: %w", err)
+		}
+		listener.Start()
+		defer listener.Close()
+		checker.Add("eventListener", listener.Check)
+	}
+
+	if cfg.Cache.Size > 0 {
+		cachedStore := cache.NewUserStore(userStore, cache.Options{
+			Size:        cfg.Cache.Size,
+			TTL:         cfg.Cache.TTL,
+			NegativeTTL: cfg.Cache.NegativeTTL,
+		})
+		// writes that bypass the cache, such as email verifications, still
+		// record events, which invalidate as soon as they commit
+		go cachedStore.InvalidateOnEvents(ctx, localEvents)
+		if localEvents != broker && cfg.Cache.PeerInvalidation {
+			go cachedStore.InvalidateOnEvents(ctx, broker)
+		}
+		serverMetrics.RegisterCache(cachedStore)
+		userStore = cachedStore
+	}
+
+	// traced outside the cache, so that hits show up as spans without queries
+	userStore = tracing.InstrumentUserStore(userStore)
+	userStore = serverMetrics.InstrumentUserStore(userStore)
+
+	signer := newSigner(cfg)
+	mailSender := newMailSender(cfg)
+	emailVerifier, err := newEmailVerifier(cfg, concreteStore, mailSender, signer)
+	if err != nil {
+		return err
+	}
+	phoneVerifier := newPhoneVerifier(cfg, concreteStore, signer)
+	avatars, err := newAvatarService(cfg, concreteStore)
+	if err != nil {
+		return err
+	}
+	authenticator, err := newAuthenticator(cfg, concreteStore, mailSender, signer)
+	if err != nil {
+		return err
+	}
+	// let password reset emails requested before shutdown go out
+	defer authenticator.Wait()
+	scimURL, err := url.JoinPath(cfg.PublicURL, "scim/v2")
+	if err != nil {
+		return err
+	}
+	provider, err := newOIDCProvider(cfg, concreteStore, authenticator, signer)
+	if err != nil {
+		return err
+	}
+
+	grpcListener, err := net.Listen("tcp", cfg.GRPCAddr)
+	if err != nil {
+		return err
+	}
+	// only Postgres keeps tenants apart
+	multiTenant := cfg.Store == config.StorePostgres
+	grpcServer := NewGRPC(userStore, authenticator, multiTenant)
+	router := New(Dependencies{
+		UserStore:            userStore,
+		UserEventStore:       userEventStore,
+		Broker:               broker,
+		Metrics:              serverMetrics,
+		Health:               checker,
+		EmailVerifier:        emailVerifier,
+		PhoneVerifier:        phoneVerifier,
+		Authenticator:        authenticator,
+		AuditStore:           concreteStore,
+		GroupStore:           concreteStore,
+		AttributeSchemaStore: concreteStore,
+		Avatars:              avatars,
+		SCIM:                 scim.Config{Token: cfg.SCIMToken, BaseURL: scimURL},
+		OIDC:                 provider,
+		MultiTenant:          multiTenant,
+	})
+	httpServer := &http.Server{
+		Addr:    cfg.HTTPAddr,
+		Handler: router,
+	}
+
+	serveErrs := make(chan error, 2)
+	go func() {
+		slog.Info("starting gRPC server", slog.String("addr", cfg.GRPCAddr))
+		serveErrs <- grpcServer.Serve(grpcListener)
+	}()
+	go func() {
+		slog.Info("starting HTTP server", slog.String("addr", cfg.HTTPAddr))
+		serveErrs <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErrs:
+		grpcServer.Stop()
+		_ = httpServer.Close()
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+
+	// keep serving while the orchestrator notices the failing readiness probe
+	// and stops routing new requests here
+	slog.Info("draining", slog.String("delay", cfg.DrainDelay.String()))
+	checker.Drain()
+	time.Sleep(cfg.DrainDelay)
+
+	return shutdown(httpServer, grpcServer, cfg.ShutdownTimeout)
+}
+
+// newSigner signs the tokens and codes sent to users.
+func newSigner(cfg config.Config) *token.Signer {
+	key := []byte(cfg.TokenSecret)
+	if len(key) == 0 {
+		slog.Warn("no token secret configured, links and codes sent to users will stop working on restart")
+		key = token.RandomKey()
+	}
+	return token.NewSigner(key)
+}
+
+func newMailSender(cfg config.Config) mail.Sender {
+	switch cfg.Mail.Transport {
+	case config.MailSMTP:
+		return mail.NewSMTPSender(cfg.Mail.SMTPAddr, cfg.Mail.From, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword)
+	default:
+		slog.Info("writing emails to files", slog.String("dir", cfg.Mail.Dir))
+		return mail.NewFileSender(cfg.Mail.Dir, cfg.Mail.From)
+	}
+}
+
+func newEmailVerifier(cfg config.Config, verificationStore verification.EmailVerificationStore, sender mail.Sender, signer *token.Signer) (*verification.EmailVerifier, error) {
+	verifyURL, err := url.JoinPath(cfg.PublicURL, "verify-email")
+	if err != nil {
+		return nil, err
+	}
+
+	return verification.NewEmailVerifier(verificationStore, sender, signer, verification.EmailOptions{
+		TTL:       cfg.EmailVerificationTTL,
+		VerifyURL: verifyURL,
+	}), nil
+}
+
+func newPhoneVerifier(cfg config.Config, verificationStore verification.PhoneVerificationStore, signer *token.Signer) *verification.PhoneVerifier {
+	var sender sms.Sender
+	switch cfg.SMS.Transport {
+	case config.SMSFile:
+		slog.Info("writing text messages to a file", slog.String("path", cfg.SMS.File))
+		sender = sms.NewFileSender(cfg.SMS.File)
+	default:
+		sender = sms.NewLogSender(slog.Default())
+	}
+
+	return verification.NewPhoneVerifier(verificationStore, sender, signer, verification.PhoneOptions{
+		TTL:            cfg.PhoneVerificationTTL,
+		MaxAttempts:    cfg.PhoneVerificationMaxAttempts,
+		ResendInterval: cfg.PhoneVerificationResendInterval,
+	})
+}
+
+// newAvatarService keeps avatar images in the user store unless the config
+// asks for files.
+func newAvatarService(cfg config.Config, avatarStore interface {
+	avatar.Store
+	store.BlobStoreInterface
+}) (*avatar.Service, error) {
+	if cfg.Blob.Store != config.BlobFile {
+		return avatar.NewService(avatarStore, avatarStore), nil
+	}
+
+	slog.Info("keeping blobs in files", slog.String("dir", cfg.Blob.Dir))
+	fileStore, err := blob.NewFileStore(cfg.Blob.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up the blob directory: %w", err)
+	}
+	return avatar.NewService(avatarStore, fileStore), nil
+}
+
+// newAuthenticator points password reset links at the reset-password page,
+// which the web front end serves next to the API.
+func newAuthenticator(cfg config.Config, authStore auth.Store, sender mail.Sender, signer *token.Signer) (*auth.Authenticator, error) {
+	resetURL, err := url.JoinPath(cfg.PublicURL, "reset-password")
+	if err != nil {
+		return nil, err
+	}
+
+	mfaKey, err := cfg.MFAKey()
+	if err != nil {
+		return nil, err
+	}
+
+	return auth.NewAuthenticator(authStore, sender, signer, auth.Options{
+		AccessTokenTTL:  cfg.AccessTokenTTL,
+		SessionTTL:      cfg.SessionTTL,
+		ResetTTL:        cfg.PasswordResetTTL,
+		ResetURL:        resetURL,
+		MFAIssuer:       cfg.MFAIssuer,
+		MFAKey:          mfaKey,
+		MFAChallengeTTL: cfg.MFAChallengeTTL,
+		MFAMaxAttempts:  cfg.MFAMaxAttempts,
+		Admins:          cfg.AdminUserIds,
+	}), nil
+}
+
+// newOIDCProvider serves OpenID Connect under /oidc. Its refresh tokens last
+// as long as a login session.
+func newOIDCProvider(cfg config.Config, oidcStore oidc.Store, authenticator *auth.Authenticator, signer *token.Signer) (*oidc.Provider, error) {
+	issuer, err := url.JoinPath(cfg.PublicURL, "oidc")
+	if err != nil {
+		return nil, err
+	}
+
+	return oidc.NewProvider(oidcStore, authenticator, signer, oidc.Options{
+		Issuer:          issuer,
+		AccessTokenTTL:  cfg.AccessTokenTTL,
+		RefreshTokenTTL: cfg.SessionTTL,
+		KeyRotation:     cfg.OIDCKeyRotation,
+	}), nil
+}
+
+// checkMigrations turns the result of a pending migrations lookup into a
+// readiness check error.
+func checkMigrations(pending bool, err error) error {
+	if err != nil {
+		return err
+	}
+	if pending {
+		return errors.New("migrations pending")
+	}
+	return nil
+}
+
+// shutdown stops both servers, letting in-flight requests finish for up to
+// timeout before closing their connections.
+func shutdown(httpServer *http.Server, grpcServer *grpc.Server, timeout time.Duration) error {
+	slog.Info("shutting down", slog.String("timeout", timeout.String()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+
+	err := httpServer.Shutdown(ctx)
+	if err != nil {
+		// event streams never finish on their own
+		err = httpServer.Close()
+	}
+
+	select {
+	case <-grpcStopped:
+	case <-ctx.Done():
+		grpcServer.Stop()
+	}
+	return err
+}
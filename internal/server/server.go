@@ -1,45 +1,152 @@
 // @title User Management API
 // @version 1.0
 // @description REST API for User Management
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token from /auth/login, as "Bearer <token>"
 
 package server
 
 import (
-	"log"
 	"net/http"
 
 	_ "example.com/user-management/docs"
-	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/auth"
+	"example.com/user-management/internal/avatar"
+	"example.com/user-management/internal/events"
+	"example.com/user-management/internal/graph"
 	"example.com/user-management/internal/handler"
+	"example.com/user-management/internal/health"
+	"example.com/user-management/internal/logging"
+	"example.com/user-management/internal/metrics"
+	"example.com/user-management/internal/oidc"
+	"example.com/user-management/internal/scim"
 	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tracing"
+	"example.com/user-management/internal/verification"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-func New() http.Handler {
+// Dependencies are the stores and services the HTTP API is served from.
+type Dependencies struct {
+	UserStore      store.UserStoreInterface
+	UserEventStore store.UserEventStoreInterface
+	Broker         *events.Broker
+	Metrics        *metrics.Metrics
+	Health         *health.Checker
+	EmailVerifier  *verification.EmailVerifier
+	PhoneVerifier  *verification.PhoneVerifier
+	Authenticator  *auth.Authenticator
+	AuditStore     store.AuditStoreInterface
+	GroupStore     store.GroupStoreInterface
+	// AttributeSchemaStore keeps the schema of the custom attributes of users
+	AttributeSchemaStore store.AttributeSchemaStoreInterface
+	Avatars              *avatar.Service
+	// SCIM enables the SCIM provisioning endpoints when it has a token.
+	SCIM scim.Config
+	// OIDC signs users in to registered clients with OpenID Connect.
+	OIDC *oidc.Provider
+	// MultiTenant is set for stores that keep tenants apart. Others only
+	// serve the default tenant.
+	MultiTenant bool
+}
+
+func New(deps Dependencies) http.Handler {
 	router := chi.NewRouter()
 
-	router.Use(middleware.Logger)
+	router.Use(tracing.Middleware)
+	router.Use(middleware.RequestID)
+	router.Use(deps.Metrics.Middleware)
+	router.Use(logging.Middleware)
 	router.Use(middleware.Recoverer)
+	router.Use(handler.NewTenantResolver(deps.Authenticator, deps.MultiTenant).Middleware)
 
-	dbConn, err := db.NewPostgres()
-	if err != nil {
-		log.Fatalf("failed to connect to database: %v", err)
-	}
-
-	userStore := store.NewUserStore(dbConn)
-	userHandler := handler.NewUserHandler(userStore)
+	userHandler := handler.NewUserHandler(deps.UserStore)
+	userEventHandler := handler.NewUserEventHandler(deps.UserEventStore, deps.Broker)
+	emailVerificationHandler := handler.NewEmailVerificationHandler(deps.EmailVerifier)
+	phoneVerificationHandler := handler.NewPhoneVerificationHandler(deps.PhoneVerifier)
+	authHandler := handler.NewAuthHandler(deps.Authenticator)
+	auditHandler := handler.NewAuditHandler(deps.AuditStore)
+	groupHandler := handler.NewGroupHandler(deps.GroupStore, deps.UserStore)
+	attributeSchemaHandler := handler.NewAttributeSchemaHandler(deps.AttributeSchemaStore)
+	avatarHandler := handler.NewAvatarHandler(deps.Avatars)
+	oauthClientHandler := handler.NewOAuthClientHandler(deps.OIDC)
 
 	router.Route("/users", func(r chi.Router) {
 		r.Post("/", userHandler.CreateUser)
 		r.Get("/", userHandler.GetAllUsers)
+		r.Get("/events", userEventHandler.StreamUserEvents)
 		r.Get("/{id}", userHandler.GetUserById)
 		r.Patch("/{id}", userHandler.UpdateUser)
-		r.Delete("/{id}", userHandler.DeleteUser)
+		r.Put("/{id}", userHandler.PutUser)
+		r.With(authHandler.RequireAccessToken, authHandler.RequireAdmin).Delete("/{id}", userHandler.DeleteUser)
+		r.Post("/{id}/verify-email/send", emailVerificationHandler.SendVerificationEmail)
+		r.Post("/{id}/verify-phone/send", phoneVerificationHandler.SendVerificationCode)
+		r.Post("/{id}/verify-phone", phoneVerificationHandler.VerifyPhone)
+		r.Get("/{id}/audit-events", auditHandler.ListAuditEvents)
+		r.With(authHandler.RequireAccessToken, authHandler.RequireAdmin).Delete("/{id}/mfa", authHandler.ResetMFA)
+		r.Get("/{id}/groups", groupHandler.ListUserGroups)
+		r.Put("/{id}/avatar", avatarHandler.UploadAvatar)
+		r.Get("/{id}/avatar", avatarHandler.GetAvatar)
+		r.Delete("/{id}/avatar", avatarHandler.DeleteAvatar)
+	})
+	router.Route("/groups", func(r chi.Router) {
+		r.Post("/", groupHandler.CreateGroup)
+		r.Get("/", groupHandler.GetAllGroups)
+		r.Get("/{id}", groupHandler.GetGroupById)
+		r.Patch("/{id}", groupHandler.UpdateGroup)
+		r.Delete("/{id}", groupHandler.DeleteGroup)
+		r.Post("/{id}/members", groupHandler.AddGroupMember)
+		r.Get("/{id}/members", groupHandler.ListGroupMembers)
+		r.Delete("/{id}/members/{userId}", groupHandler.RemoveGroupMember)
+	})
+	router.Route("/attribute-schema", func(r chi.Router) {
+		r.Get("/", attributeSchemaHandler.GetAttributeSchema)
+		r.Group(func(r chi.Router) {
+			r.Use(authHandler.RequireAccessToken, authHandler.RequireAdmin)
+			r.Put("/", attributeSchemaHandler.SetAttributeSchema)
+			r.Delete("/", attributeSchemaHandler.DeleteAttributeSchema)
+		})
+	})
+	router.Route("/oauth-clients", func(r chi.Router) {
+		r.Use(authHandler.RequireAccessToken, authHandler.RequireAdmin)
+		r.Post("/", oauthClientHandler.RegisterOAuthClient)
+		r.Get("/", oauthClientHandler.GetAllOAuthClients)
+		r.Get("/{id}", oauthClientHandler.GetOAuthClient)
+		r.Delete("/{id}", oauthClientHandler.DeleteOAuthClient)
 	})
+	router.Get("/verify-email", emailVerificationHandler.VerifyEmail)
+
+	router.Route("/auth", func(r chi.Router) {
+		r.Post("/login", authHandler.Login)
+		r.Post("/login/mfa", authHandler.LoginMFA)
+		r.Post("/refresh", authHandler.Refresh)
+		r.Post("/logout", authHandler.Logout)
+		r.Post("/password/forgot", authHandler.ForgotPassword)
+		r.Post("/password/reset", authHandler.ResetPassword)
+
+		r.Group(func(r chi.Router) {
+			r.Use(authHandler.RequireAccessToken)
+			r.Post("/mfa/totp", authHandler.EnrolTOTP)
+			r.Post("/mfa/totp/confirm", authHandler.ConfirmTOTP)
+			r.Post("/mfa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+		})
+	})
+
+	router.With(authHandler.OptionalAccessToken).Post("/graphql", graph.NewHandler(deps.UserStore, deps.Authenticator).ServeHTTP)
+	if deps.SCIM.Token != "" {
+		router.Mount("/scim/v2", scim.NewHandler(deps.UserStore, deps.GroupStore, deps.SCIM))
+	}
+
+	router.Mount("/oidc", deps.OIDC)
 
 	router.Get("/doc/*", httpSwagger.WrapHandler)
+	router.Get("/metrics", deps.Metrics.Handler().ServeHTTP)
+	router.Get("/healthz", deps.Health.Live)
+	router.Get("/readyz", deps.Health.Ready)
 
 	return router
 }
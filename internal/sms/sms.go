@@ -0,0 +1,95 @@
+// Package sms sends the text messages the service writes to users.
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message is a text message to the E.164 number To.
+type Message struct {
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+// Sender delivers messages. Implementations must be safe for concurrent use.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender writes messages to the log instead of sending them, for local
+// development. The number is redacted like any other phone in the logs.
+type LogSender struct {
+	logger *slog.Logger
+}
+
+func NewLogSender(logger *slog.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+func (sender *LogSender) Send(ctx context.Context, msg Message) error {
+	sender.logger.InfoContext(ctx, "sms", slog.String("phone", msg.To), slog.String("body", msg.Body))
+	return nil
+}
+
+// FileSender appends every message to a file as a JSON line, for local
+// development.
+type FileSender struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+func (sender *FileSender) Send(_ context.Context, msg Message) error {
+	line, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sentAt"`
+	}{msg, time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+
+	file, err := os.OpenFile(sender.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// MemorySender keeps the messages it is given, for tests.
+type MemorySender struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemorySender() *MemorySender {
+	return &MemorySender{}
+}
+
+func (sender *MemorySender) Send(_ context.Context, msg Message) error {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	sender.messages = append(sender.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (sender *MemorySender) Messages() []Message {
+	sender.mu.Lock()
+	defer sender.mu.Unlock()
+	return append([]Message(nil), sender.messages...)
+}
@@ -0,0 +1,43 @@
+package sms
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSender_AppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sms.jsonl")
+	sender := NewFileSender(path)
+
+	for _, body := range []string{"first", "second"} {
+		if err := sender.Send(t.Context(), Message{To: "+12345678901", Body: body}); err != nil {
+			t.Fatalf("Send failed: %v", err)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer file.Close()
+
+	var bodies []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var msg Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("Invalid line %q: %v", scanner.Text(), err)
+		}
+		if msg.To != "+12345678901" {
+			t.Errorf("Expected message to +12345678901, got %q", msg.To)
+		}
+		bodies = append(bodies, msg.Body)
+	}
+
+	if len(bodies) != 2 || bodies[0] != "first" || bodies[1] != "second" {
+		t.Errorf("Expected [first second], got %q", bodies)
+	}
+}
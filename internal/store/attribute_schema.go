@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"example.com/user-management/internal/attributes"
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/model"
+)
+
+// AttributeSchemaStoreInterface keeps the schema the attributes of a tenant's
+// users must satisfy. The user stores check attributes against it on every
+// create and update, and return an error wrapping attributes.ErrInvalid for
+// those that do not.
+type AttributeSchemaStoreInterface interface {
+	GetAttributeSchema(ctx context.Context) (model.AttributeSchema, bool, error)
+	// SetAttributeSchema replaces the schema. It returns an error wrapping
+	// attributes.ErrInvalidSchema for schemas that do not compile.
+	SetAttributeSchema(ctx context.Context, schema json.RawMessage) (model.AttributeSchema, error)
+	// DeleteAttributeSchema removes the schema, after which any attributes
+	// are accepted.
+	DeleteAttributeSchema(ctx context.Context) (bool, error)
+}
+
+var _ AttributeSchemaStoreInterface = (*UserStore)(nil)
+
+func (store *UserStore) GetAttributeSchema(ctx context.Context) (model.AttributeSchema, bool, error) {
+	dbSchema, err := query(ctx, store, func(queries *db.Queries) (db.AttributeSchema, error) {
+		return queries.GetAttributeSchema(ctx)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.AttributeSchema{}, false, nil
+		}
+		return model.AttributeSchema{}, false, err
+	}
+
+	return mapDbAttributeSchemaToModel(&dbSchema), true, nil
+}
+
+func (store *UserStore) SetAttributeSchema(ctx context.Context, schema json.RawMessage) (model.AttributeSchema, error) {
+	if _, err := attributes.Parse(schema); err != nil {
+		return model.AttributeSchema{}, err
+	}
+
+	dbSchema, err := query(ctx, store, func(queries *db.Queries) (db.AttributeSchema, error) {
+		return queries.SetAttributeSchema(ctx, schema)
+	})
+	if err != nil {
+		return model.AttributeSchema{}, err
+	}
+
+	return mapDbAttributeSchemaToModel(&dbSchema), nil
+}
+
+func (store *UserStore) DeleteAttributeSchema(ctx context.Context) (bool, error) {
+	rows, err := query(ctx, store, func(queries *db.Queries) (int64, error) {
+		return queries.DeleteAttributeSchema(ctx)
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// attributeSchema returns the compiled attribute schema of the tenant queries
+// act for, or nil if it has none.
+func attributeSchema(ctx context.Context, queries *db.Queries) (*attributes.Schema, error) {
+	dbSchema, err := queries.GetAttributeSchema(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attributes.Parse(dbSchema.Schema)
+}
+
+// validateAttributes checks the attributes of user against the schema of the
+// tenant queries act for and returns them encoded for storage.
+func validateAttributes(ctx context.Context, queries *db.Queries, user model.User) (json.RawMessage, error) {
+	schema, err := attributeSchema(ctx, queries)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.Validate(user.Attributes); err != nil {
+		return nil, err
+	}
+	return attributes.Encode(user.Attributes)
+}
+
+// encodeAttributeFilter returns the attribute values of filter as a JSON
+// object, typed by the schema of the tenant queries act for, or NULL if it
+// names none.
+func encodeAttributeFilter(ctx context.Context, queries *db.Queries, filter model.UserFilter) (sql.NullString, error) {
+	if len(filter.Attributes) == 0 {
+		return sql.NullString{}, nil
+	}
+
+	schema, err := attributeSchema(ctx, queries)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	encoded, err := json.Marshal(schema.Filter(filter.Attributes))
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(encoded), Valid: true}, nil
+}
+
+func mapDbAttributeSchemaToModel(dbSchema *db.AttributeSchema) model.AttributeSchema {
+	return model.AttributeSchema{
+		Schema:    dbSchema.Schema,
+		UpdatedAt: dbSchema.UpdatedAt,
+	}
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/model"
+	"github.com/google/uuid"
+)
+
+type AuditStoreInterface interface {
+	RecordAuditEvent(ctx context.Context, event model.AuditEvent) error
+	// ListAuditEvents returns up to limit of the user's audit events, oldest
+	// first, starting after the event with the given id.
+	ListAuditEvents(ctx context.Context, userId uuid.UUID, after int64, limit int) ([]model.AuditEvent, error)
+}
+
+var _ AuditStoreInterface = (*UserStore)(nil)
+
+func (store *UserStore) RecordAuditEvent(ctx context.Context, event model.AuditEvent) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		return recordAuditEvent(ctx, queries, event)
+	})
+}
+
+func (store *UserStore) ListAuditEvents(ctx context.Context, userId uuid.UUID, after int64, limit int) ([]model.AuditEvent, error) {
+	dbEvents, err := query(ctx, store, func(queries *db.Queries) ([]db.AuditEvent, error) {
+		return queries.ListAuditEvents(ctx,
+			db.ListAuditEventsParams{
+				UserID:  uuid.NullUUID{UUID: userId, Valid: true},
+				AuditID: after,
+				Limit:   int32(limit),
+			},
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]model.AuditEvent, len(dbEvents))
+	for i, e := range dbEvents {
+		event, err := mapDbAuditEventToModel(&e)
+		if err != nil {
+			return nil, err
+		}
+		events[i] = event
+	}
+
+	return events, nil
+}
+
+func recordAuditEvent(ctx context.Context, queries *db.Queries, event model.AuditEvent) error {
+	if event.Detail == nil {
+		event.Detail = map[string]string{}
+	}
+	detail, err := json.Marshal(event.Detail)
+	if err != nil {
+		return err
+	}
+
+	var userId uuid.NullUUID
+	if event.UserId != nil {
+		userId = uuid.NullUUID{UUID: *event.UserId, Valid: true}
+	}
+
+	return queries.CreateAuditEvent(ctx,
+		db.CreateAuditEventParams{
+			UserID: userId,
+			Action: string(event.Action),
+			Detail: detail,
+		},
+	)
+}
+
+func mapDbAuditEventToModel(dbEvent *db.AuditEvent) (model.AuditEvent, error) {
+	event := model.AuditEvent{
+		AuditId:   dbEvent.AuditID,
+		Action:    model.AuditAction(dbEvent.Action),
+		CreatedAt: dbEvent.CreatedAt,
+	}
+	if dbEvent.UserID.Valid {
+		event.UserId = &dbEvent.UserID.UUID
+	}
+	if err := json.Unmarshal(dbEvent.Detail, &event.Detail); err != nil {
+		return model.AuditEvent{}, err
+	}
+	return event, nil
+}
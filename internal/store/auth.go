@@ -0,0 +1,285 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"time"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/model"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionExpired  = errors.New("session expired")
+	ErrResetNotFound   = errors.New("password reset not found")
+	ErrResetUsed       = errors.New("password reset already used")
+	ErrResetExpired    = errors.New("password reset expired")
+)
+
+type AuthStoreInterface interface {
+	// GetPasswordHash returns the hash of the user's password, or false if
+	// they have not set one.
+	GetPasswordHash(ctx context.Context, userId uuid.UUID) (string, bool, error)
+	CreateSession(ctx context.Context, session model.Session) error
+	// GetSession returns the session with sessionId, or false if there is
+	// none or it has been revoked.
+	GetSession(ctx context.Context, sessionId uuid.UUID) (model.Session, bool, error)
+	// RefreshSession replaces the refresh token of the session holding
+	// refreshTokenHash with newRefreshTokenHash, provided the session has
+	// not ended by now.
+	RefreshSession(ctx context.Context, refreshTokenHash, newRefreshTokenHash []byte, now time.Time) (model.Session, error)
+	// RevokeSession ends the session holding refreshTokenHash at now.
+	RevokeSession(ctx context.Context, refreshTokenHash []byte, now time.Time) (model.Session, error)
+	CreatePasswordReset(ctx context.Context, reset model.PasswordReset) error
+	GetPasswordReset(ctx context.Context, tokenId uuid.UUID) (model.PasswordReset, bool, error)
+	// ResetPassword uses up the reset with tokenId, along with any other the
+	// user was sent, then replaces their password hash and revokes all their
+	// sessions at now. Both changes are written to the audit trail.
+	ResetPassword(ctx context.Context, tokenId uuid.UUID, passwordHash string, now time.Time) error
+}
+
+var _ AuthStoreInterface = (*UserStore)(nil)
+
+// CheckSession returns ErrSessionExpired if session has ended by now.
+// Revoked sessions are never found, so they are not checked for.
+func CheckSession(session model.Session, now time.Time) error {
+	if !now.Before(session.ExpiresAt) {
+		return ErrSessionExpired
+	}
+	return nil
+}
+
+// CheckPasswordReset returns ErrResetUsed or ErrResetExpired if reset can no
+// longer be used at now.
+func CheckPasswordReset(reset model.PasswordReset, now time.Time) error {
+	if reset.UsedAt != nil {
+		return ErrResetUsed
+	}
+	if !now.Before(reset.ExpiresAt) {
+		return ErrResetExpired
+	}
+	return nil
+}
+
+// RevokedDetail describes how many sessions a password change ended, for the
+// audit trail.
+func RevokedDetail(revoked int64) map[string]string {
+	return map[string]string{"count": strconv.FormatInt(revoked, 10)}
+}
+
+func (store *UserStore) GetPasswordHash(ctx context.Context, userId uuid.UUID) (string, bool, error) {
+	credential, err := query(ctx, store, func(queries *db.Queries) (db.UserCredential, error) {
+		return queries.GetUserCredential(ctx, userId)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return credential.PasswordHash, true, nil
+}
+
+func (store *UserStore) CreateSession(ctx context.Context, session model.Session) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		return queries.CreateSession(ctx,
+			db.CreateSessionParams{
+				SessionID:        session.SessionId,
+				UserID:           session.UserId,
+				RefreshTokenHash: session.RefreshTokenHash,
+				CreatedAt:        session.CreatedAt,
+				ExpiresAt:        session.ExpiresAt,
+				Mfa:              session.MFA,
+			},
+		)
+	})
+}
+
+func (store *UserStore) RefreshSession(ctx context.Context, refreshTokenHash, newRefreshTokenHash []byte, now time.Time) (model.Session, error) {
+	var session model.Session
+	err := store.withTx(ctx, func(queries *db.Queries) error {
+		dbSession, err := queries.RotateSession(ctx,
+			db.RotateSessionParams{
+				NewRefreshTokenHash: newRefreshTokenHash,
+				RefreshTokenHash:    refreshTokenHash,
+			},
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrSessionNotFound
+			}
+			return err
+		}
+
+		session = mapDbSessionToModel(&dbSession)
+		return CheckSession(session, now)
+	})
+
+	if err != nil {
+		return model.Session{}, err
+	}
+	return session, nil
+}
+
+func (store *UserStore) GetSession(ctx context.Context, sessionId uuid.UUID) (model.Session, bool, error) {
+	dbSession, err := query(ctx, store, func(queries *db.Queries) (db.Session, error) {
+		return queries.GetSession(ctx, sessionId)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Session{}, false, nil
+		}
+		return model.Session{}, false, err
+	}
+
+	return mapDbSessionToModel(&dbSession), true, nil
+}
+
+func (store *UserStore) RevokeSession(ctx context.Context, refreshTokenHash []byte, now time.Time) (model.Session, error) {
+	dbSession, err := query(ctx, store, func(queries *db.Queries) (db.Session, error) {
+		return queries.RevokeSession(ctx,
+			db.RevokeSessionParams{
+				RevokedAt:        sql.NullTime{Time: now, Valid: true},
+				RefreshTokenHash: refreshTokenHash,
+			},
+		)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Session{}, ErrSessionNotFound
+		}
+		return model.Session{}, err
+	}
+
+	return mapDbSessionToModel(&dbSession), nil
+}
+
+func (store *UserStore) CreatePasswordReset(ctx context.Context, reset model.PasswordReset) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		return queries.CreatePasswordReset(ctx,
+			db.CreatePasswordResetParams{
+				TokenID:   reset.TokenId,
+				UserID:    reset.UserId,
+				ExpiresAt: reset.ExpiresAt,
+			},
+		)
+	})
+}
+
+func (store *UserStore) GetPasswordReset(ctx context.Context, tokenId uuid.UUID) (model.PasswordReset, bool, error) {
+	dbReset, err := query(ctx, store, func(queries *db.Queries) (db.PasswordReset, error) {
+		return queries.GetPasswordReset(ctx, tokenId)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.PasswordReset{}, false, nil
+		}
+		return model.PasswordReset{}, false, err
+	}
+
+	return mapDbPasswordResetToModel(&dbReset), true, nil
+}
+
+func (store *UserStore) ResetPassword(ctx context.Context, tokenId uuid.UUID, passwordHash string, now time.Time) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		dbReset, err := queries.GetPasswordReset(ctx, tokenId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrResetNotFound
+			}
+			return err
+		}
+
+		reset := mapDbPasswordResetToModel(&dbReset)
+		if err := CheckPasswordReset(reset, now); err != nil {
+			return err
+		}
+
+		usedAt := sql.NullTime{Time: now, Valid: true}
+		// a concurrent reset may have used it since it was read
+		used, err := queries.UsePasswordReset(ctx,
+			db.UsePasswordResetParams{
+				UsedAt:  usedAt,
+				TokenID: tokenId,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return ErrResetUsed
+		}
+
+		if _, err := queries.UseUserPasswordResets(ctx,
+			db.UseUserPasswordResetsParams{
+				UsedAt: usedAt,
+				UserID: reset.UserId,
+			},
+		); err != nil {
+			return err
+		}
+
+		if err := queries.UpsertUserCredential(ctx,
+			db.UpsertUserCredentialParams{
+				UserID:       reset.UserId,
+				PasswordHash: passwordHash,
+				UpdatedAt:    now,
+			},
+		); err != nil {
+			return err
+		}
+
+		revoked, err := queries.RevokeUserSessions(ctx,
+			db.RevokeUserSessionsParams{
+				RevokedAt: usedAt,
+				UserID:    reset.UserId,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &reset.UserId,
+			Action: model.AuditPasswordReset,
+		}); err != nil {
+			return err
+		}
+		return recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &reset.UserId,
+			Action: model.AuditSessionsRevoked,
+			Detail: RevokedDetail(revoked),
+		})
+	})
+}
+
+func mapDbSessionToModel(dbSession *db.Session) model.Session {
+	session := model.Session{
+		SessionId:        dbSession.SessionID,
+		UserId:           dbSession.UserID,
+		RefreshTokenHash: dbSession.RefreshTokenHash,
+		CreatedAt:        dbSession.CreatedAt,
+		ExpiresAt:        dbSession.ExpiresAt,
+		MFA:              dbSession.Mfa,
+	}
+	if dbSession.RevokedAt.Valid {
+		session.RevokedAt = &dbSession.RevokedAt.Time
+	}
+	return session
+}
+
+func mapDbPasswordResetToModel(dbReset *db.PasswordReset) model.PasswordReset {
+	reset := model.PasswordReset{
+		TokenId:   dbReset.TokenID,
+		UserId:    dbReset.UserID,
+		ExpiresAt: dbReset.ExpiresAt,
+	}
+	if dbReset.UsedAt.Valid {
+		reset.UsedAt = &dbReset.UsedAt.Time
+	}
+	return reset
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/model"
+	"github.com/google/uuid"
+)
+
+// AvatarStoreInterface records which avatar users have. The images
+// themselves are kept by a BlobStoreInterface.
+type AvatarStoreInterface interface {
+	// SetUserAvatar sets the AvatarUrl of the user with userId, empty for
+	// none, and returns the updated user.
+	SetUserAvatar(ctx context.Context, userId uuid.UUID, avatarUrl string) (model.User, bool, error)
+}
+
+// BlobStoreInterface keeps files, such as avatar images, apart for each
+// tenant.
+type BlobStoreInterface interface {
+	// PutBlob stores blob, replacing any blob with its key.
+	PutBlob(ctx context.Context, blob model.Blob) error
+	GetBlob(ctx context.Context, key string) (model.Blob, bool, error)
+	// DeleteBlobs removes every blob whose key starts with prefix and
+	// reports whether there were any.
+	DeleteBlobs(ctx context.Context, prefix string) (bool, error)
+}
+
+var (
+	_ AvatarStoreInterface = (*UserStore)(nil)
+	_ BlobStoreInterface   = (*UserStore)(nil)
+)
+
+func (store *UserStore) SetUserAvatar(ctx context.Context, userId uuid.UUID, avatarUrl string) (model.User, bool, error) {
+	var updatedUser model.User
+	err := store.withEventTx(ctx, func(queries *db.Queries) (model.UserEvent, error) {
+		dbUser, err := queries.SetUserAvatar(ctx,
+			db.SetUserAvatarParams{
+				AvatarUrl: avatarUrl,
+				UserID:    userId,
+			},
+		)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		updatedUser = mapDbUserToModel(&dbUser)
+		return recordUserEvent(ctx, queries, model.EventUserUpdated, updatedUser)
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, false, nil
+		}
+		return model.User{}, false, err
+	}
+	return updatedUser, true, nil
+}
+
+func (store *UserStore) PutBlob(ctx context.Context, blob model.Blob) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		return queries.PutBlob(ctx,
+			db.PutBlobParams{
+				Key:         blob.Key,
+				ContentType: blob.ContentType,
+				Data:        blob.Data,
+			},
+		)
+	})
+}
+
+func (store *UserStore) GetBlob(ctx context.Context, key string) (model.Blob, bool, error) {
+	dbBlob, err := query(ctx, store, func(queries *db.Queries) (db.Blob, error) {
+		return queries.GetBlob(ctx, key)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Blob{}, false, nil
+		}
+		return model.Blob{}, false, err
+	}
+
+	return model.Blob{
+		Key:         dbBlob.Key,
+		ContentType: dbBlob.ContentType,
+		Data:        dbBlob.Data,
+		UpdatedAt:   dbBlob.UpdatedAt,
+	}, true, nil
+}
+
+func (store *UserStore) DeleteBlobs(ctx context.Context, prefix string) (bool, error) {
+	rows, err := query(ctx, store, func(queries *db.Queries) (int64, error) {
+		return queries.DeleteBlobs(ctx, prefix)
+	})
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
@@ -0,0 +1,304 @@
+// Package cache provides a read-through caching decorator for the user store.
+package cache
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"example.com/user-management/internal/events"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tenant"
+	"github.com/google/uuid"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"golang.org/x/sync/singleflight"
+)
+
+const invalidationBuffer = 256
+
+type Options struct {
+	// Size is the maximum number of users kept. It must be positive.
+	Size int
+	// TTL bounds how long a cached user may be served.
+	TTL time.Duration
+	// NegativeTTL bounds how long an id is remembered as not found. Zero
+	// disables negative caching.
+	NegativeTTL time.Duration
+}
+
+// Stats counts lookups by id. A lookup answered from the negative cache is
+// a hit.
+type Stats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// UserStore caches users by id in front of another store. Writes made through
+// it invalidate the affected entries; writes made elsewhere are only seen once
+// the entry expires or Invalidate is called. Entries are only served to the
+// tenant they were loaded for.
+type UserStore struct {
+	next  store.UserStoreInterface
+	users *expirable.LRU[uuid.UUID, model.User]
+	// missing maps ids to the tenant they were not found in.
+	missing *expirable.LRU[uuid.UUID, uuid.UUID]
+	loads   singleflight.Group
+
+	// generation is bumped by every invalidation. A load only fills the cache
+	// if no invalidation happened while it was reading, so a slow read racing
+	// a write cannot bring back the old row.
+	mu         sync.Mutex
+	generation uint64
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+var _ store.UserStoreInterface = (*UserStore)(nil)
+
+func NewUserStore(next store.UserStoreInterface, opts Options) *UserStore {
+	userStore := &UserStore{
+		next:  next,
+		users: expirable.NewLRU[uuid.UUID, model.User](opts.Size, nil, opts.TTL),
+	}
+	if opts.NegativeTTL > 0 {
+		userStore.missing = expirable.NewLRU[uuid.UUID, uuid.UUID](opts.Size, nil, opts.NegativeTTL)
+	}
+	return userStore
+}
+
+func (userStore *UserStore) Stats() Stats {
+	return Stats{
+		Hits:   userStore.hits.Load(),
+		Misses: userStore.misses.Load(),
+	}
+}
+
+func (userStore *UserStore) CreateUser(ctx context.Context, user model.User) (model.User, error) {
+	createdUser, err := userStore.next.CreateUser(ctx, user)
+	if err == nil {
+		userStore.Invalidate(createdUser.UserId)
+	}
+	return createdUser, err
+}
+
+func (userStore *UserStore) GetAllUsers(ctx context.Context) ([]model.User, error) {
+	return userStore.next.GetAllUsers(ctx)
+}
+
+func (userStore *UserStore) GetUserById(ctx context.Context, userId uuid.UUID) (model.User, bool, error) {
+	tenantId := tenant.FromContext(ctx)
+	if user, ok, cached := userStore.lookup(tenantId, userId); cached {
+		userStore.hits.Add(1)
+		return user, ok, nil
+	}
+	userStore.misses.Add(1)
+
+	// concurrent misses for the same id in the same tenant share one query
+	result, err, _ := userStore.loads.Do(tenantId.String()+"/"+userId.String(), func() (any, error) {
+		generation := userStore.currentGeneration()
+
+		// the result is shared, so one caller giving up must not fail the
+		// others
+		user, ok, err := userStore.next.GetUserById(context.WithoutCancel(ctx), userId)
+		if err != nil {
+			return nil, err
+		}
+
+		var loaded []model.User
+		if ok {
+			loaded = append(loaded, user)
+		}
+		userStore.fill(generation, tenantId, []uuid.UUID{userId}, loaded)
+		return lookupResult{user: user, ok: ok}, nil
+	})
+	if err != nil {
+		return model.User{}, false, err
+	}
+
+	loaded := result.(lookupResult)
+	return loaded.user, loaded.ok, nil
+}
+
+func (userStore *UserStore) GetUsersByIds(ctx context.Context, userIds []uuid.UUID) ([]model.User, error) {
+	tenantId := tenant.FromContext(ctx)
+	users := make([]model.User, 0, len(userIds))
+	var uncached []uuid.UUID
+
+	seen := make(map[uuid.UUID]bool, len(userIds))
+	for _, userId := range userIds {
+		if seen[userId] {
+			continue
+		}
+		seen[userId] = true
+
+		user, ok, cached := userStore.lookup(tenantId, userId)
+		if !cached {
+			uncached = append(uncached, userId)
+			continue
+		}
+		userStore.hits.Add(1)
+		if ok {
+			users = append(users, user)
+		}
+	}
+
+	if len(uncached) == 0 {
+		return users, nil
+	}
+	userStore.misses.Add(uint64(len(uncached)))
+
+	generation := userStore.currentGeneration()
+	loaded, err := userStore.next.GetUsersByIds(ctx, uncached)
+	if err != nil {
+		return nil, err
+	}
+	userStore.fill(generation, tenantId, uncached, loaded)
+
+	return append(users, loaded...), nil
+}
+
+func (userStore *UserStore) ListUsers(ctx context.Context, filter model.UserFilter, after uuid.UUID, limit int) ([]model.User, error) {
+	return userStore.next.ListUsers(ctx, filter, after, limit)
+}
+
+func (userStore *UserStore) UpdateUser(ctx context.Context, user model.User, userId uuid.UUID) (model.User, bool, error) {
+	updatedUser, ok, err := userStore.next.UpdateUser(ctx, user, userId)
+	userStore.Invalidate(userId)
+	return updatedUser, ok, err
+}
+
+func (userStore *UserStore) PutUser(ctx context.Context, user model.User, precondition model.Precondition) (model.User, bool, error) {
+	putUser, created, err := userStore.next.PutUser(ctx, user, precondition)
+	userStore.Invalidate(user.UserId)
+	return putUser, created, err
+}
+
+func (userStore *UserStore) ModifyUser(ctx context.Context, userId uuid.UUID, precondition model.Precondition, modify func(user *model.User) error) (model.User, bool, error) {
+	modifiedUser, ok, err := userStore.next.ModifyUser(ctx, userId, precondition, modify)
+	userStore.Invalidate(userId)
+	return modifiedUser, ok, err
+}
+
+func (userStore *UserStore) DeleteUser(ctx context.Context, userId uuid.UUID) (bool, error) {
+	ok, err := userStore.next.DeleteUser(ctx, userId)
+	userStore.Invalidate(userId)
+	return ok, err
+}
+
+// Invalidate drops anything cached for userId.
+func (userStore *UserStore) Invalidate(userId uuid.UUID) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	userStore.generation++
+	userStore.users.Remove(userId)
+	if userStore.missing != nil {
+		userStore.missing.Remove(userId)
+	}
+}
+
+// Purge drops every cached entry.
+func (userStore *UserStore) Purge() {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	userStore.generation++
+	userStore.users.Purge()
+	if userStore.missing != nil {
+		userStore.missing.Purge()
+	}
+}
+
+// InvalidateOnEvents invalidates users as their change events arrive on the
+// broker until ctx is done. Fed by a PostgresListener this keeps the caches of
+// every instance in step with writes made by any of them.
+func (userStore *UserStore) InvalidateOnEvents(ctx context.Context, broker *events.Broker) {
+	for {
+		sub := broker.Subscribe(invalidationBuffer)
+
+		// events published while we were not subscribed are lost, so nothing
+		// cached before this point can be trusted
+		userStore.Purge()
+
+		if !userStore.invalidateFrom(ctx, sub) {
+			return
+		}
+	}
+}
+
+// invalidateFrom consumes sub until ctx is done, returning false, or until
+// the broker drops the subscription for falling behind, returning true.
+func (userStore *UserStore) invalidateFrom(ctx context.Context, sub *events.Subscription) bool {
+	defer sub.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case event, ok := <-sub.Events():
+			if !ok {
+				return true
+			}
+			userStore.Invalidate(event.UserId)
+		}
+	}
+}
+
+type lookupResult struct {
+	user model.User
+	ok   bool
+}
+
+// lookup reports whether userId is cached for tenantId and, if so, whether it
+// exists there.
+func (userStore *UserStore) lookup(tenantId, userId uuid.UUID) (user model.User, ok bool, cached bool) {
+	if user, ok := userStore.users.Get(userId); ok {
+		// a user belongs to one tenant and is hidden from the others
+		if user.TenantId != tenantId {
+			return model.User{}, false, true
+		}
+		return user, true, true
+	}
+	if userStore.missing != nil {
+		if missingIn, ok := userStore.missing.Get(userId); ok && missingIn == tenantId {
+			return model.User{}, false, true
+		}
+	}
+	return model.User{}, false, false
+}
+
+func (userStore *UserStore) currentGeneration() uint64 {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+	return userStore.generation
+}
+
+// fill caches the users loaded for userIds in tenantId and remembers the ids
+// that were not found, unless an invalidation happened since generation was
+// read.
+func (userStore *UserStore) fill(generation uint64, tenantId uuid.UUID, userIds []uuid.UUID, loaded []model.User) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	if generation != userStore.generation {
+		return
+	}
+
+	found := make(map[uuid.UUID]bool, len(loaded))
+	for _, user := range loaded {
+		found[user.UserId] = true
+		userStore.users.Add(user.UserId, user)
+	}
+
+	if userStore.missing == nil {
+		return
+	}
+	for _, userId := range userIds {
+		if !found[userId] {
+			userStore.missing.Add(userId, tenantId)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package cache
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/events"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/store/memory"
+	"example.com/user-management/internal/store/storetest"
+	"example.com/user-management/internal/tenant"
+	"github.com/google/uuid"
+)
+
+// countingStore counts reads that reach the wrapped store and can hold them
+// until release is closed.
+type countingStore struct {
+	store.UserStoreInterface
+	gets    atomic.Int32
+	release chan struct{}
+}
+
+func (s *countingStore) GetUserById(ctx context.Context, userId uuid.UUID) (model.User, bool, error) {
+	s.gets.Add(1)
+	if s.release != nil {
+		<-s.release
+	}
+	return s.UserStoreInterface.GetUserById(ctx, userId)
+}
+
+func newTestStore(t *testing.T, opts Options) (*UserStore, *countingStore) {
+	t.Helper()
+
+	next := &countingStore{UserStoreInterface: memory.NewUserStore()}
+	return NewUserStore(next, opts), next
+}
+
+func createUser(t *testing.T, userStore store.UserStoreInterface) model.User {
+	t.Helper()
+
+	user, err := userStore.CreateUser(t.Context(), model.User{
+		FirstName: "Alice",
+		LastName:  "Smith",
+		Email:     uuid.New().String() + "@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	return user
+}
+
+var testOptions = Options{Size: 100, TTL: time.Minute, NegativeTTL: time.Minute}
+
+func TestUserStoreConformance(t *testing.T) {
+	storetest.RunUserStoreTests(t, func(t *testing.T) store.UserStoreInterface {
+		userStore, _ := newTestStore(t, testOptions)
+		return userStore
+	})
+}
+
+func TestGetUserById_ServesRepeatsFromCache(t *testing.T) {
+	userStore, next := newTestStore(t, testOptions)
+	user := createUser(t, userStore)
+
+	for i := 0; i < 3; i++ {
+		got, ok, err := userStore.GetUserById(t.Context(), user.UserId)
+		if err != nil || !ok || !reflect.DeepEqual(got, user) {
+			t.Fatalf("GetUserById returned %+v, %v, %v", got, ok, err)
+		}
+	}
+
+	if n := next.gets.Load(); n != 1 {
+		t.Errorf("Expected 1 read from the store, got %d", n)
+	}
+	if stats := userStore.Stats(); stats != (Stats{Hits: 2, Misses: 1}) {
+		t.Errorf("Unexpected stats %+v", stats)
+	}
+}
+
+func TestGetUserById_CachesNotFound(t *testing.T) {
+	userStore, next := newTestStore(t, testOptions)
+	userId := uuid.New()
+
+	for i := 0; i < 2; i++ {
+		if _, ok, err := userStore.GetUserById(t.Context(), userId); err != nil || ok {
+			t.Fatalf("GetUserById returned %v, %v", ok, err)
+		}
+	}
+
+	if n := next.gets.Load(); n != 1 {
+		t.Errorf("Expected 1 read from the store, got %d", n)
+	}
+}
+
+func TestGetUserById_KeepsTenantsApart(t *testing.T) {
+	userStore, next := newTestStore(t, testOptions)
+	user := createUser(t, userStore)
+	otherTenant := tenant.WithID(t.Context(), uuid.New())
+
+	if _, ok, err := userStore.GetUserById(t.Context(), user.UserId); err != nil || !ok {
+		t.Fatalf("GetUserById returned %v, %v", ok, err)
+	}
+	if got, ok, err := userStore.GetUserById(otherTenant, user.UserId); err != nil || ok || got.UserId != uuid.Nil {
+		t.Errorf("Expected the user to be hidden from another tenant, got %+v, %v, %v", got, ok, err)
+	}
+
+	// not being found in one tenant says nothing about another
+	missingId := uuid.New()
+	for _, ctx := range []context.Context{otherTenant, t.Context()} {
+		if _, ok, err := userStore.GetUserById(ctx, missingId); err != nil || ok {
+			t.Fatalf("GetUserById returned %v, %v", ok, err)
+		}
+	}
+
+	if n := next.gets.Load(); n != 3 {
+		t.Errorf("Expected 3 reads from the store, got %d", n)
+	}
+}
+
+func TestGetUserById_NegativeCachingDisabled(t *testing.T) {
+	userStore, next := newTestStore(t, Options{Size: 100, TTL: time.Minute})
+	userId := uuid.New()
+
+	for i := 0; i < 2; i++ {
+		if _, _, err := userStore.GetUserById(t.Context(), userId); err != nil {
+			t.Fatalf("GetUserById failed: %v", err)
+		}
+	}
+
+	if n := next.gets.Load(); n != 2 {
+		t.Errorf("Expected 2 reads from the store, got %d", n)
+	}
+}
+
+func TestGetUserById_Expires(t *testing.T) {
+	userStore, next := newTestStore(t, Options{Size: 100, TTL: 10 * time.Millisecond})
+	user := createUser(t, userStore)
+
+	_, _, _ = userStore.GetUserById(t.Context(), user.UserId)
+	time.Sleep(20 * time.Millisecond)
+	_, _, _ = userStore.GetUserById(t.Context(), user.UserId)
+
+	if n := next.gets.Load(); n != 2 {
+		t.Errorf("Expected 2 reads from the store, got %d", n)
+	}
+}
+
+func TestGetUserById_ConcurrentMissesShareOneQuery(t *testing.T) {
+	userStore, next := newTestStore(t, testOptions)
+	user := createUser(t, userStore)
+	next.release = make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, ok, err := userStore.GetUserById(t.Context(), user.UserId); err != nil || !ok {
+				t.Errorf("GetUserById returned %v, %v", ok, err)
+			}
+		}()
+	}
+
+	// let every goroutine reach the store before releasing the first read
+	time.Sleep(50 * time.Millisecond)
+	close(next.release)
+	wg.Wait()
+
+	if n := next.gets.Load(); n != 1 {
+		t.Errorf("Expected 1 read from the store, got %d", n)
+	}
+}
+
+func TestUpdateAndDelete_Invalidate(t *testing.T) {
+	userStore, _ := newTestStore(t, testOptions)
+	user := createUser(t, userStore)
+	_, _, _ = userStore.GetUserById(t.Context(), user.UserId)
+
+	user.FirstName = "Updated"
+	if _, _, err := userStore.UpdateUser(t.Context(), user, user.UserId); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+
+	got, _, _ := userStore.GetUserById(t.Context(), user.UserId)
+	if got.FirstName != "Updated" {
+		t.Errorf("Expected the updated user, got %+v", got)
+	}
+
+	if _, err := userStore.DeleteUser(t.Context(), user.UserId); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+
+	if _, ok, _ := userStore.GetUserById(t.Context(), user.UserId); ok {
+		t.Errorf("Expected the deleted user not to be found")
+	}
+}
+
+func TestGetUsersByIds_UsesCache(t *testing.T) {
+	userStore, next := newTestStore(t, testOptions)
+	first := createUser(t, userStore)
+	second := createUser(t, userStore)
+	_, _, _ = userStore.GetUserById(t.Context(), first.UserId)
+
+	users, err := userStore.GetUsersByIds(t.Context(), []uuid.UUID{first.UserId, second.UserId, first.UserId})
+	if err != nil {
+		t.Fatalf("GetUsersByIds failed: %v", err)
+	}
+	if len(users) != 2 {
+		t.Fatalf("Expected 2 users, got %d", len(users))
+	}
+
+	// the second user was loaded by the batch and is now cached too
+	_, _, _ = userStore.GetUserById(t.Context(), second.UserId)
+	if n := next.gets.Load(); n != 1 {
+		t.Errorf("Expected 1 single read from the store, got %d", n)
+	}
+}
+
+func TestInvalidateOnEvents(t *testing.T) {
+	shared := memory.NewUserStore()
+	broker := events.NewBroker()
+	shared.OnEvent(broker.Publish)
+
+	// two instances caching the same data, one of them watching for changes
+	local := NewUserStore(shared, testOptions)
+	peer := NewUserStore(shared, testOptions)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go peer.InvalidateOnEvents(ctx, broker)
+
+	user := createUser(t, local)
+	_, _, _ = peer.GetUserById(t.Context(), user.UserId)
+
+	user.FirstName = "Updated"
+	if _, _, err := local.UpdateUser(t.Context(), user, user.UserId); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		got, _, _ := peer.GetUserById(t.Context(), user.UserId)
+		if got.FirstName == "Updated" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Peer cache was not invalidated")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
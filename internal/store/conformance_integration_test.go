@@ -0,0 +1,75 @@
+package store_test
+
+import (
+	"testing"
+
+	"example.com/user-management/internal/emailaddr"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/store/storetest"
+)
+
+func TestUserStoreConformance(t *testing.T) {
+	storetest.RunUserStoreTests(t, func(t *testing.T) store.UserStoreInterface {
+		return store.IntegrationUserStore()
+	})
+}
+
+func TestEmailProviderRules(t *testing.T) {
+	storetest.RunEmailProviderRulesTests(t, func(t *testing.T) store.UserStoreInterface {
+		return store.IntegrationUserStoreWithRules(emailaddr.Rules{Providers: true})
+	})
+}
+
+func TestEmailVerificationStoreConformance(t *testing.T) {
+	storetest.RunEmailVerificationStoreTests(t, func(t *testing.T) storetest.EmailVerificationStore {
+		return store.IntegrationUserStore()
+	})
+}
+
+func TestPhoneVerificationStoreConformance(t *testing.T) {
+	storetest.RunPhoneVerificationStoreTests(t, func(t *testing.T) storetest.PhoneVerificationStore {
+		return store.IntegrationUserStore()
+	})
+}
+
+func TestAuthStoreConformance(t *testing.T) {
+	storetest.RunAuthStoreTests(t, func(t *testing.T) storetest.AuthStore {
+		return store.IntegrationUserStore()
+	})
+}
+
+func TestMFAStoreConformance(t *testing.T) {
+	storetest.RunMFAStoreTests(t, func(t *testing.T) storetest.MFAStore {
+		return store.IntegrationUserStore()
+	})
+}
+
+func TestGroupStoreConformance(t *testing.T) {
+	storetest.RunGroupStoreTests(t, func(t *testing.T) storetest.GroupStore {
+		return store.IntegrationUserStore()
+	})
+}
+
+func TestAttributeSchemaStoreConformance(t *testing.T) {
+	storetest.RunAttributeSchemaStoreTests(t, func(t *testing.T) storetest.AttributeSchemaStore {
+		return store.IntegrationUserStore()
+	})
+}
+
+func TestAvatarStoreConformance(t *testing.T) {
+	storetest.RunAvatarStoreTests(t, func(t *testing.T) storetest.AvatarStore {
+		return store.IntegrationUserStore()
+	})
+}
+
+func TestBlobStoreConformance(t *testing.T) {
+	storetest.RunBlobStoreTests(t, func(t *testing.T) store.BlobStoreInterface {
+		return store.IntegrationUserStore()
+	})
+}
+
+func TestOIDCStoreConformance(t *testing.T) {
+	storetest.RunOIDCStoreTests(t, func(t *testing.T) storetest.OIDCStore {
+		return store.IntegrationUserStore()
+	})
+}
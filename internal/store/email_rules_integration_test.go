@@ -0,0 +1,19 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/emailaddr"
+)
+
+func TestCheckEmailRules(t *testing.T) {
+	// TestMain migrates without provider rules
+	if err := db.CheckEmailRules(t.Context(), dbConn, emailaddr.Rules{}); err != nil {
+		t.Errorf("Expected the rules migrated with to match, got %v", err)
+	}
+	if err := db.CheckEmailRules(t.Context(), dbConn, emailaddr.Rules{Providers: true}); !errors.Is(err, db.ErrEmailRulesMismatch) {
+		t.Errorf("Expected ErrEmailRulesMismatch, got %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/model"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrVerificationNotFound = errors.New("verification not found")
+	ErrVerificationUsed     = errors.New("verification already used")
+	ErrVerificationExpired  = errors.New("verification expired")
+	// ErrEmailChanged is returned when the user no longer has the address a
+	// verification was sent to.
+	ErrEmailChanged = errors.New("email changed since the verification was sent")
+)
+
+type EmailVerificationStoreInterface interface {
+	CreateEmailVerification(ctx context.Context, verification model.EmailVerification) error
+	// ConfirmEmailVerification uses up the verification with tokenId and marks
+	// the address it was sent to as verified at now, returning the updated
+	// user.
+	ConfirmEmailVerification(ctx context.Context, tokenId uuid.UUID, now time.Time) (model.User, error)
+}
+
+var _ EmailVerificationStoreInterface = (*UserStore)(nil)
+
+// CheckEmailVerification returns ErrVerificationUsed or ErrVerificationExpired
+// if verification can no longer be confirmed at now.
+func CheckEmailVerification(verification model.EmailVerification, now time.Time) error {
+	if verification.UsedAt != nil {
+		return ErrVerificationUsed
+	}
+	if !now.Before(verification.ExpiresAt) {
+		return ErrVerificationExpired
+	}
+	return nil
+}
+
+func (store *UserStore) CreateEmailVerification(ctx context.Context, verification model.EmailVerification) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		return queries.CreateEmailVerification(ctx,
+			db.CreateEmailVerificationParams{
+				TokenID:   verification.TokenId,
+				UserID:    verification.UserId,
+				Email:     verification.Email,
+				ExpiresAt: verification.ExpiresAt,
+			},
+		)
+	})
+}
+
+func (store *UserStore) ConfirmEmailVerification(ctx context.Context, tokenId uuid.UUID, now time.Time) (model.User, error) {
+	var verifiedUser model.User
+	err := store.withEventTx(ctx, func(queries *db.Queries) (model.UserEvent, error) {
+		dbVerification, err := queries.GetEmailVerification(ctx, tokenId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.UserEvent{}, ErrVerificationNotFound
+			}
+			return model.UserEvent{}, err
+		}
+
+		verification := mapDbEmailVerificationToModel(&dbVerification)
+		if err := CheckEmailVerification(verification, now); err != nil {
+			return model.UserEvent{}, err
+		}
+
+		// a concurrent confirmation may have used it since it was read
+		used, err := queries.UseEmailVerification(ctx,
+			db.UseEmailVerificationParams{
+				TokenID: tokenId,
+				UsedAt:  sql.NullTime{Time: now, Valid: true},
+			},
+		)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+		if used == 0 {
+			return model.UserEvent{}, ErrVerificationUsed
+		}
+
+		dbUser, err := queries.VerifyUserEmail(ctx,
+			db.VerifyUserEmailParams{
+				EmailVerifiedAt: sql.NullTime{Time: now, Valid: true},
+				UserID:          verification.UserId,
+				Email:           verification.Email,
+			},
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.UserEvent{}, ErrEmailChanged
+			}
+			return model.UserEvent{}, err
+		}
+
+		verifiedUser = mapDbUserToModel(&dbUser)
+		return recordUserEvent(ctx, queries, model.EventUserUpdated, verifiedUser)
+	})
+
+	if err != nil {
+		return model.User{}, err
+	}
+	return verifiedUser, nil
+}
+
+func mapDbEmailVerificationToModel(dbVerification *db.EmailVerification) model.EmailVerification {
+	verification := model.EmailVerification{
+		TokenId:   dbVerification.TokenID,
+		UserId:    dbVerification.UserID,
+		Email:     dbVerification.Email,
+		ExpiresAt: dbVerification.ExpiresAt,
+	}
+	if dbVerification.UsedAt.Valid {
+		verification.UsedAt = &dbVerification.UsedAt.Time
+	}
+	return verification
+}
@@ -0,0 +1,17 @@
+package store
+
+import "example.com/user-management/internal/emailaddr"
+
+// IntegrationUserStore exposes the Postgres-backed store set up in TestMain to
+// the external conformance test.
+func IntegrationUserStore() *UserStore {
+	return userStore
+}
+
+// IntegrationUserStoreWithRules returns a store on the same database that
+// keys addresses by rules.
+func IntegrationUserStoreWithRules(rules emailaddr.Rules) *UserStore {
+	rulesStore := NewUserStore(userStore.db)
+	rulesStore.SetEmailRules(rules)
+	return rulesStore
+}
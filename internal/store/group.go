@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/model"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrDuplicateGroupName is returned when a create or update would give
+	// two groups the same name.
+	ErrDuplicateGroupName = errors.New("group name already exists")
+	ErrGroupNotFound      = errors.New("group not found")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrAlreadyMember      = errors.New("user already a member of the group")
+)
+
+// GroupStoreInterface manages groups and who belongs to them. The members of
+// a group are listed through UserStoreInterface.ListUsers with a GroupId
+// filter.
+type GroupStoreInterface interface {
+	CreateGroup(ctx context.Context, group model.Group) (model.Group, error)
+	// GetAllGroups returns every group, ordered by name.
+	GetAllGroups(ctx context.Context) ([]model.Group, error)
+	GetGroupById(ctx context.Context, groupId uuid.UUID) (model.Group, bool, error)
+	UpdateGroup(ctx context.Context, group model.Group, groupId uuid.UUID) (model.Group, bool, error)
+	// DeleteGroup deletes the group along with its memberships. The members
+	// themselves are kept.
+	DeleteGroup(ctx context.Context, groupId uuid.UUID) (bool, error)
+	// AddGroupMember adds the user to the group. It returns ErrGroupNotFound
+	// or ErrUserNotFound if either does not exist, and ErrAlreadyMember if
+	// the user already belongs to the group.
+	AddGroupMember(ctx context.Context, groupId, userId uuid.UUID) error
+	// RemoveGroupMember takes the user out of the group, or returns false if
+	// they were not in it.
+	RemoveGroupMember(ctx context.Context, groupId, userId uuid.UUID) (bool, error)
+	// ListUserGroups returns the groups the user belongs to, ordered by name.
+	ListUserGroups(ctx context.Context, userId uuid.UUID) ([]model.Group, error)
+	// ListGroupMembers returns the members of each of the groups, ordered by
+	// id, in one read. Groups without members are left out.
+	ListGroupMembers(ctx context.Context, groupIds []uuid.UUID) (map[uuid.UUID][]model.User, error)
+}
+
+var _ GroupStoreInterface = (*UserStore)(nil)
+
+func (store *UserStore) CreateGroup(ctx context.Context, group model.Group) (model.Group, error) {
+	dbGroup, err := query(ctx, store, func(queries *db.Queries) (db.Group, error) {
+		return queries.CreateGroup(ctx,
+			db.CreateGroupParams{
+				Name:        group.Name,
+				Description: group.Description,
+			},
+		)
+	})
+	if err != nil {
+		return model.Group{}, mapGroupUniqueViolation(err)
+	}
+
+	return mapDbGroupToModel(&dbGroup), nil
+}
+
+func (store *UserStore) GetAllGroups(ctx context.Context) ([]model.Group, error) {
+	dbGroups, err := query(ctx, store, func(queries *db.Queries) ([]db.Group, error) {
+		return queries.GetAllGroups(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mapDbGroupsToModel(dbGroups), nil
+}
+
+func (store *UserStore) GetGroupById(ctx context.Context, groupId uuid.UUID) (model.Group, bool, error) {
+	dbGroup, err := query(ctx, store, func(queries *db.Queries) (db.Group, error) {
+		return queries.GetGroupByID(ctx, groupId)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Group{}, false, nil
+		}
+		return model.Group{}, false, err
+	}
+
+	return mapDbGroupToModel(&dbGroup), true, nil
+}
+
+func (store *UserStore) UpdateGroup(ctx context.Context, group model.Group, groupId uuid.UUID) (model.Group, bool, error) {
+	dbGroup, err := query(ctx, store, func(queries *db.Queries) (db.Group, error) {
+		return queries.UpdateGroup(ctx,
+			db.UpdateGroupParams{
+				Name:        group.Name,
+				Description: group.Description,
+				GroupID:     groupId,
+			},
+		)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Group{}, false, nil
+		}
+		return model.Group{}, false, mapGroupUniqueViolation(err)
+	}
+
+	return mapDbGroupToModel(&dbGroup), true, nil
+}
+
+func (store *UserStore) DeleteGroup(ctx context.Context, groupId uuid.UUID) (bool, error) {
+	deleted, err := query(ctx, store, func(queries *db.Queries) (int64, error) {
+		return queries.DeleteGroup(ctx, groupId)
+	})
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+func (store *UserStore) AddGroupMember(ctx context.Context, groupId, userId uuid.UUID) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		if _, err := queries.GetGroupByID(ctx, groupId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrGroupNotFound
+			}
+			return err
+		}
+		if _, err := queries.GetUserByID(ctx, userId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrUserNotFound
+			}
+			return err
+		}
+
+		added, err := queries.AddGroupMember(ctx,
+			db.AddGroupMemberParams{
+				GroupID: groupId,
+				UserID:  userId,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if added == 0 {
+			return ErrAlreadyMember
+		}
+		return nil
+	})
+}
+
+func (store *UserStore) RemoveGroupMember(ctx context.Context, groupId, userId uuid.UUID) (bool, error) {
+	removed, err := query(ctx, store, func(queries *db.Queries) (int64, error) {
+		return queries.RemoveGroupMember(ctx,
+			db.RemoveGroupMemberParams{
+				GroupID: groupId,
+				UserID:  userId,
+			},
+		)
+	})
+	if err != nil {
+		return false, err
+	}
+	return removed > 0, nil
+}
+
+func (store *UserStore) ListUserGroups(ctx context.Context, userId uuid.UUID) ([]model.Group, error) {
+	dbGroups, err := query(ctx, store, func(queries *db.Queries) ([]db.Group, error) {
+		return queries.ListUserGroups(ctx, userId)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mapDbGroupsToModel(dbGroups), nil
+}
+
+func (store *UserStore) ListGroupMembers(ctx context.Context, groupIds []uuid.UUID) (map[uuid.UUID][]model.User, error) {
+	rows, err := query(ctx, store, func(queries *db.Queries) ([]db.ListGroupMembersRow, error) {
+		return queries.ListGroupMembers(ctx, groupIds)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[uuid.UUID][]model.User)
+	for _, row := range rows {
+		members[row.GroupID] = append(members[row.GroupID], mapDbUserToModel(&row.User))
+	}
+	return members, nil
+}
+
+func mapGroupUniqueViolation(err error) error {
+	if isUniqueViolation(err) {
+		return ErrDuplicateGroupName
+	}
+	return err
+}
+
+func mapDbGroupsToModel(dbGroups []db.Group) []model.Group {
+	groups := make([]model.Group, len(dbGroups))
+	for i, g := range dbGroups {
+		groups[i] = mapDbGroupToModel(&g)
+	}
+	return groups
+}
+
+func mapDbGroupToModel(dbGroup *db.Group) model.Group {
+	return model.Group{
+		GroupId:     dbGroup.GroupID,
+		Name:        dbGroup.Name,
+		Description: dbGroup.Description,
+		CreatedAt:   dbGroup.CreatedAt,
+	}
+}
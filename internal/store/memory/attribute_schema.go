@@ -0,0 +1,73 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"example.com/user-management/internal/attributes"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+)
+
+var _ store.AttributeSchemaStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) GetAttributeSchema(ctx context.Context) (model.AttributeSchema, bool, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	if userStore.attributeSchema == nil {
+		return model.AttributeSchema{}, false, nil
+	}
+	return *userStore.attributeSchema, true, nil
+}
+
+func (userStore *UserStore) SetAttributeSchema(ctx context.Context, schema json.RawMessage) (model.AttributeSchema, error) {
+	if _, err := attributes.Parse(schema); err != nil {
+		return model.AttributeSchema{}, err
+	}
+
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	userStore.attributeSchema = &model.AttributeSchema{
+		Schema:    slices.Clone(schema),
+		UpdatedAt: time.Now(),
+	}
+	return *userStore.attributeSchema, nil
+}
+
+func (userStore *UserStore) DeleteAttributeSchema(ctx context.Context) (bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	existed := userStore.attributeSchema != nil
+	userStore.attributeSchema = nil
+	return existed, nil
+}
+
+// compiledAttributeSchema returns the attribute schema, or nil if there is
+// none. It must be called with the lock held.
+func (userStore *UserStore) compiledAttributeSchema() *attributes.Schema {
+	if userStore.attributeSchema == nil {
+		return nil
+	}
+	// only schemas that compiled are stored
+	schema, _ := attributes.Parse(userStore.attributeSchema.Schema)
+	return schema
+}
+
+// checkAttributes validates attrs against the attribute schema and returns
+// them as they would read back from a database column. It must be called with
+// the lock held.
+func (userStore *UserStore) checkAttributes(attrs map[string]any) (map[string]any, error) {
+	if err := userStore.compiledAttributeSchema().Validate(attrs); err != nil {
+		return nil, err
+	}
+	encoded, err := attributes.Encode(attrs)
+	if err != nil {
+		return nil, err
+	}
+	return attributes.Decode(encoded)
+}
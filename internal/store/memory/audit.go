@@ -0,0 +1,51 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.AuditStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) RecordAuditEvent(ctx context.Context, event model.AuditEvent) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	userStore.recordAuditEvent(event)
+	return nil
+}
+
+func (userStore *UserStore) ListAuditEvents(ctx context.Context, userId uuid.UUID, after int64, limit int) ([]model.AuditEvent, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	// audit ids start at 1 and have no gaps, so they double as indexes
+	start := min(max(after, 0), int64(len(userStore.auditEvents)))
+	events := []model.AuditEvent{}
+	for _, event := range userStore.auditEvents[start:] {
+		if len(events) == limit {
+			break
+		}
+		if event.UserId != nil && *event.UserId == userId {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// recordAuditEvent appends event to the audit trail. The caller must hold the
+// lock.
+func (userStore *UserStore) recordAuditEvent(event model.AuditEvent) {
+	event.AuditId = int64(len(userStore.auditEvents)) + 1
+	event.Detail = maps.Clone(event.Detail)
+	if event.Detail == nil {
+		event.Detail = map[string]string{}
+	}
+	event.CreatedAt = time.Now()
+	userStore.auditEvents = append(userStore.auditEvents, event)
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.AuthStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) GetPasswordHash(ctx context.Context, userId uuid.UUID) (string, bool, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	passwordHash, ok := userStore.passwordHashes[userId]
+	return passwordHash, ok, nil
+}
+
+func (userStore *UserStore) CreateSession(ctx context.Context, session model.Session) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	session.RevokedAt = nil
+	userStore.sessions[session.SessionId] = session
+	return nil
+}
+
+func (userStore *UserStore) GetSession(ctx context.Context, sessionId uuid.UUID) (model.Session, bool, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	session, ok := userStore.sessions[sessionId]
+	if !ok || session.RevokedAt != nil {
+		return model.Session{}, false, nil
+	}
+	return session, true, nil
+}
+
+func (userStore *UserStore) RefreshSession(ctx context.Context, refreshTokenHash, newRefreshTokenHash []byte, now time.Time) (model.Session, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	session, ok := userStore.liveSession(refreshTokenHash)
+	if !ok {
+		return model.Session{}, store.ErrSessionNotFound
+	}
+	if err := store.CheckSession(session, now); err != nil {
+		return model.Session{}, err
+	}
+
+	session.RefreshTokenHash = newRefreshTokenHash
+	userStore.sessions[session.SessionId] = session
+	return session, nil
+}
+
+func (userStore *UserStore) RevokeSession(ctx context.Context, refreshTokenHash []byte, now time.Time) (model.Session, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	session, ok := userStore.liveSession(refreshTokenHash)
+	if !ok {
+		return model.Session{}, store.ErrSessionNotFound
+	}
+
+	session.RevokedAt = &now
+	userStore.sessions[session.SessionId] = session
+	return session, nil
+}
+
+func (userStore *UserStore) CreatePasswordReset(ctx context.Context, reset model.PasswordReset) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	reset.UsedAt = nil
+	userStore.passwordResets[reset.TokenId] = reset
+	return nil
+}
+
+func (userStore *UserStore) GetPasswordReset(ctx context.Context, tokenId uuid.UUID) (model.PasswordReset, bool, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	reset, ok := userStore.passwordResets[tokenId]
+	return reset, ok, nil
+}
+
+func (userStore *UserStore) ResetPassword(ctx context.Context, tokenId uuid.UUID, passwordHash string, now time.Time) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	reset, ok := userStore.passwordResets[tokenId]
+	if !ok {
+		return store.ErrResetNotFound
+	}
+	if err := store.CheckPasswordReset(reset, now); err != nil {
+		return err
+	}
+
+	for id, other := range userStore.passwordResets {
+		if other.UserId == reset.UserId && other.UsedAt == nil {
+			other.UsedAt = &now
+			userStore.passwordResets[id] = other
+		}
+	}
+
+	userStore.passwordHashes[reset.UserId] = passwordHash
+
+	var revoked int64
+	for id, session := range userStore.sessions {
+		if session.UserId == reset.UserId && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			userStore.sessions[id] = session
+			revoked++
+		}
+	}
+
+	userStore.recordAuditEvent(model.AuditEvent{
+		UserId: &reset.UserId,
+		Action: model.AuditPasswordReset,
+	})
+	userStore.recordAuditEvent(model.AuditEvent{
+		UserId: &reset.UserId,
+		Action: model.AuditSessionsRevoked,
+		Detail: store.RevokedDetail(revoked),
+	})
+	return nil
+}
+
+// liveSession returns the session holding refreshTokenHash, unless it has
+// been revoked. The caller must hold the lock.
+func (userStore *UserStore) liveSession(refreshTokenHash []byte) (model.Session, bool) {
+	for _, session := range userStore.sessions {
+		if session.RevokedAt == nil && bytes.Equal(session.RefreshTokenHash, refreshTokenHash) {
+			return session, true
+		}
+	}
+	return model.Session{}, false
+}
@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var (
+	_ store.AvatarStoreInterface = (*UserStore)(nil)
+	_ store.BlobStoreInterface   = (*UserStore)(nil)
+)
+
+func (userStore *UserStore) SetUserAvatar(ctx context.Context, userId uuid.UUID, avatarUrl string) (model.User, bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	user, ok := userStore.users[userId]
+	if !ok {
+		return model.User{}, false, nil
+	}
+
+	user.AvatarUrl = avatarUrl
+	userStore.users[userId] = user
+	userStore.recordEvent(model.EventUserUpdated, user)
+
+	return user, true, nil
+}
+
+func (userStore *UserStore) PutBlob(ctx context.Context, blob model.Blob) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	blob.Data = slices.Clone(blob.Data)
+	blob.UpdatedAt = time.Now()
+	userStore.blobs[blob.Key] = blob
+	return nil
+}
+
+func (userStore *UserStore) GetBlob(ctx context.Context, key string) (model.Blob, bool, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	blob, ok := userStore.blobs[key]
+	return blob, ok, nil
+}
+
+func (userStore *UserStore) DeleteBlobs(ctx context.Context, prefix string) (bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	var deleted bool
+	for key := range userStore.blobs {
+		if strings.HasPrefix(key, prefix) {
+			delete(userStore.blobs, key)
+			deleted = true
+		}
+	}
+	return deleted, nil
+}
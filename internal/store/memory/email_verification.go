@@ -0,0 +1,48 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.EmailVerificationStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) CreateEmailVerification(ctx context.Context, verification model.EmailVerification) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	verification.UsedAt = nil
+	userStore.emailVerifications[verification.TokenId] = verification
+	return nil
+}
+
+func (userStore *UserStore) ConfirmEmailVerification(ctx context.Context, tokenId uuid.UUID, now time.Time) (model.User, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	verification, ok := userStore.emailVerifications[tokenId]
+	if !ok {
+		return model.User{}, store.ErrVerificationNotFound
+	}
+	if err := store.CheckEmailVerification(verification, now); err != nil {
+		return model.User{}, err
+	}
+
+	user, ok := userStore.users[verification.UserId]
+	if !ok || user.Email != verification.Email {
+		return model.User{}, store.ErrEmailChanged
+	}
+
+	verification.UsedAt = &now
+	userStore.emailVerifications[tokenId] = verification
+
+	user.EmailVerifiedAt = &now
+	userStore.users[user.UserId] = user
+	userStore.recordEvent(model.EventUserUpdated, user)
+
+	return user, nil
+}
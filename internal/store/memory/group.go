@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.GroupStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) CreateGroup(ctx context.Context, group model.Group) (model.Group, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	if userStore.groupNameTaken(group.Name, uuid.Nil) {
+		return model.Group{}, store.ErrDuplicateGroupName
+	}
+
+	group.GroupId = uuid.New()
+	group.CreatedAt = time.Now()
+	userStore.groups[group.GroupId] = group
+	userStore.groupMembers[group.GroupId] = make(map[uuid.UUID]bool)
+
+	return group, nil
+}
+
+func (userStore *UserStore) GetAllGroups(ctx context.Context) ([]model.Group, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	groups := make([]model.Group, 0, len(userStore.groups))
+	for _, g := range userStore.groups {
+		groups = append(groups, g)
+	}
+	sortGroupsByName(groups)
+	return groups, nil
+}
+
+func (userStore *UserStore) GetGroupById(ctx context.Context, groupId uuid.UUID) (model.Group, bool, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	group, ok := userStore.groups[groupId]
+	return group, ok, nil
+}
+
+func (userStore *UserStore) UpdateGroup(ctx context.Context, group model.Group, groupId uuid.UUID) (model.Group, bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	existing, ok := userStore.groups[groupId]
+	if !ok {
+		return model.Group{}, false, nil
+	}
+	if userStore.groupNameTaken(group.Name, groupId) {
+		return model.Group{}, false, store.ErrDuplicateGroupName
+	}
+
+	group.GroupId = groupId
+	group.CreatedAt = existing.CreatedAt
+	userStore.groups[groupId] = group
+
+	return group, true, nil
+}
+
+func (userStore *UserStore) DeleteGroup(ctx context.Context, groupId uuid.UUID) (bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	if _, ok := userStore.groups[groupId]; !ok {
+		return false, nil
+	}
+
+	delete(userStore.groups, groupId)
+	delete(userStore.groupMembers, groupId)
+	return true, nil
+}
+
+func (userStore *UserStore) AddGroupMember(ctx context.Context, groupId, userId uuid.UUID) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	members, ok := userStore.groupMembers[groupId]
+	if !ok {
+		return store.ErrGroupNotFound
+	}
+	if _, ok := userStore.users[userId]; !ok {
+		return store.ErrUserNotFound
+	}
+	if members[userId] {
+		return store.ErrAlreadyMember
+	}
+
+	members[userId] = true
+	return nil
+}
+
+func (userStore *UserStore) RemoveGroupMember(ctx context.Context, groupId, userId uuid.UUID) (bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	members := userStore.groupMembers[groupId]
+	if !members[userId] {
+		return false, nil
+	}
+
+	delete(members, userId)
+	return true, nil
+}
+
+func (userStore *UserStore) ListUserGroups(ctx context.Context, userId uuid.UUID) ([]model.Group, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	var groups []model.Group
+	for groupId, members := range userStore.groupMembers {
+		if members[userId] {
+			groups = append(groups, userStore.groups[groupId])
+		}
+	}
+	sortGroupsByName(groups)
+	return groups, nil
+}
+
+func (userStore *UserStore) ListGroupMembers(ctx context.Context, groupIds []uuid.UUID) (map[uuid.UUID][]model.User, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	members := make(map[uuid.UUID][]model.User)
+	for _, groupId := range groupIds {
+		if _, seen := members[groupId]; seen {
+			continue
+		}
+		for userId := range userStore.groupMembers[groupId] {
+			members[groupId] = append(members[groupId], userStore.users[userId])
+		}
+		// Postgres orders uuids by their bytes
+		slices.SortFunc(members[groupId], func(a, b model.User) int {
+			return bytes.Compare(a.UserId[:], b.UserId[:])
+		})
+	}
+	return members, nil
+}
+
+func (userStore *UserStore) groupNameTaken(name string, except uuid.UUID) bool {
+	for _, g := range userStore.groups {
+		if g.Name == name && g.GroupId != except {
+			return true
+		}
+	}
+	return false
+}
+
+func sortGroupsByName(groups []model.Group) {
+	slices.SortFunc(groups, func(a, b model.Group) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+}
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.MFAStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) CreateTOTPFactor(ctx context.Context, factor model.TOTPFactor) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	if existing, ok := userStore.totpFactors[factor.UserId]; ok && existing.ConfirmedAt != nil {
+		return store.ErrMFAEnabled
+	}
+
+	factor.LastUsedStep = 0
+	factor.ConfirmedAt = nil
+	userStore.totpFactors[factor.UserId] = factor
+	userStore.recordAuditEvent(model.AuditEvent{
+		UserId: &factor.UserId,
+		Action: model.AuditMFAEnrolmentStarted,
+	})
+	return nil
+}
+
+func (userStore *UserStore) GetTOTPFactor(ctx context.Context, userId uuid.UUID) (model.TOTPFactor, bool, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	factor, ok := userStore.totpFactors[userId]
+	return factor, ok, nil
+}
+
+func (userStore *UserStore) ConfirmTOTPFactor(ctx context.Context, userId uuid.UUID, step int64, recoveryCodeHashes [][]byte, now time.Time) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	factor, ok := userStore.totpFactors[userId]
+	if !ok {
+		return store.ErrFactorNotFound
+	}
+	if factor.ConfirmedAt != nil {
+		return store.ErrMFAEnabled
+	}
+
+	factor.ConfirmedAt = &now
+	factor.LastUsedStep = step
+	userStore.totpFactors[userId] = factor
+	userStore.recoveryCodes[userId] = slices.Clone(recoveryCodeHashes)
+	userStore.recordAuditEvent(model.AuditEvent{
+		UserId: &userId,
+		Action: model.AuditMFAEnabled,
+	})
+	return nil
+}
+
+func (userStore *UserStore) UseTOTPStep(ctx context.Context, userId uuid.UUID, step int64) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	factor, ok := userStore.totpFactors[userId]
+	if !ok || factor.ConfirmedAt == nil || factor.LastUsedStep >= step {
+		return store.ErrCodeReused
+	}
+
+	factor.LastUsedStep = step
+	userStore.totpFactors[userId] = factor
+	return nil
+}
+
+func (userStore *UserStore) ReplaceRecoveryCodes(ctx context.Context, userId uuid.UUID, codeHashes [][]byte) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	factor, ok := userStore.totpFactors[userId]
+	if !ok {
+		return store.ErrMFANotEnabled
+	}
+	if err := store.CheckTOTPFactorConfirmed(factor); err != nil {
+		return err
+	}
+
+	userStore.recoveryCodes[userId] = slices.Clone(codeHashes)
+	userStore.recordAuditEvent(model.AuditEvent{
+		UserId: &userId,
+		Action: model.AuditMFARecoveryCodesReplaced,
+	})
+	return nil
+}
+
+func (userStore *UserStore) UseRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash []byte, now time.Time) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	// only unused codes are kept
+	codeHashes := userStore.recoveryCodes[userId]
+	i := slices.IndexFunc(codeHashes, func(hash []byte) bool {
+		return bytes.Equal(hash, codeHash)
+	})
+	if i < 0 {
+		return store.ErrWrongCode
+	}
+
+	userStore.recoveryCodes[userId] = slices.Delete(codeHashes, i, i+1)
+	userStore.recordAuditEvent(model.AuditEvent{
+		UserId: &userId,
+		Action: model.AuditMFARecoveryCodeUsed,
+	})
+	return nil
+}
+
+func (userStore *UserStore) ResetMFA(ctx context.Context, userId uuid.UUID) (bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	if _, ok := userStore.totpFactors[userId]; !ok {
+		return false, nil
+	}
+
+	delete(userStore.totpFactors, userId)
+	delete(userStore.recoveryCodes, userId)
+	userStore.recordAuditEvent(model.AuditEvent{
+		UserId: &userId,
+		Action: model.AuditMFAReset,
+	})
+	return true, nil
+}
+
+func (userStore *UserStore) CreateMFAChallenge(ctx context.Context, challenge model.MFAChallenge) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	challenge.Attempts = 0
+	userStore.mfaChallenges[challenge.ChallengeId] = challenge
+	return nil
+}
+
+func (userStore *UserStore) CountMFAChallengeAttempt(ctx context.Context, challengeId uuid.UUID, maxAttempts int, now time.Time) (model.MFAChallenge, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	challenge, ok := userStore.mfaChallenges[challengeId]
+	if !ok {
+		return model.MFAChallenge{}, store.ErrChallengeNotFound
+	}
+	if challenge.Attempts >= maxAttempts {
+		return model.MFAChallenge{}, store.ErrTooManyAttempts
+	}
+
+	challenge.Attempts++
+	userStore.mfaChallenges[challengeId] = challenge
+	if err := store.CheckMFAChallenge(challenge, now); err != nil {
+		return model.MFAChallenge{}, err
+	}
+	return challenge, nil
+}
+
+func (userStore *UserStore) DeleteMFAChallenge(ctx context.Context, challengeId uuid.UUID) (bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	_, ok := userStore.mfaChallenges[challengeId]
+	delete(userStore.mfaChallenges, challengeId)
+	return ok, nil
+}
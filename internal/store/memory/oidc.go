@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.OIDCStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) CreateOAuthClient(ctx context.Context, client model.OAuthClient) (model.OAuthClient, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	client.ClientId = uuid.New()
+	client.RedirectURIs = slices.Clone(client.RedirectURIs)
+	client.CreatedAt = time.Now()
+	userStore.oauthClients[client.ClientId] = client
+	return client, nil
+}
+
+func (userStore *UserStore) GetOAuthClient(ctx context.Context, clientId uuid.UUID) (model.OAuthClient, bool, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	client, ok := userStore.oauthClients[clientId]
+	return client, ok, nil
+}
+
+func (userStore *UserStore) GetAllOAuthClients(ctx context.Context) ([]model.OAuthClient, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	clients := make([]model.OAuthClient, 0, len(userStore.oauthClients))
+	for _, client := range userStore.oauthClients {
+		clients = append(clients, client)
+	}
+	slices.SortFunc(clients, func(a, b model.OAuthClient) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return clients, nil
+}
+
+func (userStore *UserStore) DeleteOAuthClient(ctx context.Context, clientId uuid.UUID) (bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	if _, ok := userStore.oauthClients[clientId]; !ok {
+		return false, nil
+	}
+
+	delete(userStore.oauthClients, clientId)
+	for _, consents := range userStore.oauthConsents {
+		delete(consents, clientId)
+	}
+	for codeId, code := range userStore.authorizationCodes {
+		if code.ClientId == clientId {
+			delete(userStore.authorizationCodes, codeId)
+		}
+	}
+	for tokenId, token := range userStore.oauthRefreshTokens {
+		if token.ClientId == clientId {
+			delete(userStore.oauthRefreshTokens, tokenId)
+		}
+	}
+	return true, nil
+}
+
+func (userStore *UserStore) GetOAuthConsent(ctx context.Context, userId, clientId uuid.UUID) (model.OAuthConsent, bool, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	consent, ok := userStore.oauthConsents[userId][clientId]
+	return consent, ok, nil
+}
+
+func (userStore *UserStore) SaveOAuthConsent(ctx context.Context, consent model.OAuthConsent) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	consents, ok := userStore.oauthConsents[consent.UserId]
+	if !ok {
+		consents = make(map[uuid.UUID]model.OAuthConsent)
+		userStore.oauthConsents[consent.UserId] = consents
+	}
+	consents[consent.ClientId] = consent
+	return nil
+}
+
+func (userStore *UserStore) CreateAuthorizationCode(ctx context.Context, code model.AuthorizationCode) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	userStore.authorizationCodes[code.CodeId] = code
+	return nil
+}
+
+func (userStore *UserStore) ConsumeAuthorizationCode(ctx context.Context, codeId uuid.UUID) (model.AuthorizationCode, bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	code, ok := userStore.authorizationCodes[codeId]
+	delete(userStore.authorizationCodes, codeId)
+	return code, ok, nil
+}
+
+func (userStore *UserStore) CreateOAuthRefreshToken(ctx context.Context, token model.OAuthRefreshToken) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	userStore.oauthRefreshTokens[token.TokenId] = token
+	return nil
+}
+
+func (userStore *UserStore) RotateOAuthRefreshToken(ctx context.Context, tokenId, newTokenId, clientId uuid.UUID, now time.Time) (model.OAuthRefreshToken, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	token, ok := userStore.oauthRefreshTokens[tokenId]
+	if !ok || token.ClientId != clientId {
+		return model.OAuthRefreshToken{}, store.ErrRefreshTokenNotFound
+	}
+
+	if err := store.CheckOAuthRefreshToken(token, now); err != nil {
+		return model.OAuthRefreshToken{}, err
+	}
+
+	delete(userStore.oauthRefreshTokens, tokenId)
+	token.TokenId = newTokenId
+	userStore.oauthRefreshTokens[newTokenId] = token
+	return token, nil
+}
+
+func (userStore *UserStore) RevokeOAuthRefreshToken(ctx context.Context, tokenId, clientId uuid.UUID) (bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	token, ok := userStore.oauthRefreshTokens[tokenId]
+	if !ok || token.ClientId != clientId {
+		return false, nil
+	}
+	delete(userStore.oauthRefreshTokens, tokenId)
+	return true, nil
+}
+
+func (userStore *UserStore) CreateSigningKey(ctx context.Context, key model.SigningKey) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	userStore.signingKeys = append(userStore.signingKeys, key)
+	return nil
+}
+
+func (userStore *UserStore) GetSigningKeys(ctx context.Context) ([]model.SigningKey, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	keys := slices.Clone(userStore.signingKeys)
+	slices.SortFunc(keys, func(a, b model.SigningKey) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return keys, nil
+}
+
+func (userStore *UserStore) DeleteSigningKeys(ctx context.Context, before time.Time) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	userStore.signingKeys = slices.DeleteFunc(userStore.signingKeys, func(key model.SigningKey) bool {
+		return key.CreatedAt.Before(before)
+	})
+	return nil
+}
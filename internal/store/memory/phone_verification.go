@@ -0,0 +1,65 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.PhoneVerificationStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) CreatePhoneVerification(ctx context.Context, verification model.PhoneVerification, sentBefore time.Time) error {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	if existing, ok := userStore.phoneVerifications[verification.UserId]; ok && existing.SentAt.After(sentBefore) {
+		return store.ErrResendTooSoon
+	}
+
+	verification.Attempts = 0
+	userStore.phoneVerifications[verification.UserId] = verification
+	return nil
+}
+
+func (userStore *UserStore) GetPhoneVerification(ctx context.Context, userId uuid.UUID) (model.PhoneVerification, bool, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	verification, ok := userStore.phoneVerifications[userId]
+	return verification, ok, nil
+}
+
+func (userStore *UserStore) ConfirmPhoneVerification(ctx context.Context, userId uuid.UUID, codeHash []byte, maxAttempts int, now time.Time) (model.User, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	verification, ok := userStore.phoneVerifications[userId]
+	if !ok {
+		return model.User{}, store.ErrVerificationNotFound
+	}
+	if verification.Attempts >= maxAttempts {
+		return model.User{}, store.ErrTooManyAttempts
+	}
+
+	verification.Attempts++
+	userStore.phoneVerifications[userId] = verification
+	if err := store.CheckPhoneVerification(verification, codeHash, now); err != nil {
+		return model.User{}, err
+	}
+
+	delete(userStore.phoneVerifications, userId)
+
+	user, ok := userStore.users[userId]
+	if !ok || user.Phone != verification.Phone {
+		return model.User{}, store.ErrPhoneChanged
+	}
+
+	user.PhoneVerifiedAt = &now
+	userStore.users[userId] = user
+	userStore.recordEvent(model.EventUserUpdated, user)
+
+	return user, nil
+}
@@ -0,0 +1,470 @@
+// Package memory provides an in-memory implementation of the user store for
+// local development and tests. It follows the same rules as the Postgres
+// store: emails are unique, status defaults to Active and ages of zero or
+// less are stored as unset.
+package memory
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"example.com/user-management/internal/emailaddr"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+type UserStore struct {
+	mu     sync.RWMutex
+	users  map[uuid.UUID]model.User
+	events []model.UserEvent
+
+	emailVerifications map[uuid.UUID]model.EmailVerification
+	phoneVerifications map[uuid.UUID]model.PhoneVerification
+	passwordHashes     map[uuid.UUID]string
+	sessions           map[uuid.UUID]model.Session
+	passwordResets     map[uuid.UUID]model.PasswordReset
+	auditEvents        []model.AuditEvent
+	totpFactors        map[uuid.UUID]model.TOTPFactor
+	recoveryCodes      map[uuid.UUID][][]byte
+	mfaChallenges      map[uuid.UUID]model.MFAChallenge
+	groups             map[uuid.UUID]model.Group
+	// groupMembers holds the set of member ids of each group
+	groupMembers map[uuid.UUID]map[uuid.UUID]bool
+	// attributeSchema is nil until one is set
+	attributeSchema *model.AttributeSchema
+	blobs           map[string]model.Blob
+	oauthClients    map[uuid.UUID]model.OAuthClient
+	// oauthConsents is keyed by user id, then client id
+	oauthConsents      map[uuid.UUID]map[uuid.UUID]model.OAuthConsent
+	authorizationCodes map[uuid.UUID]model.AuthorizationCode
+	oauthRefreshTokens map[uuid.UUID]model.OAuthRefreshToken
+	signingKeys        []model.SigningKey
+
+	lastEventId int64
+	onEvent     func(model.UserEvent)
+	emailRules  emailaddr.Rules
+	phoneParser phonenumber.Parser
+}
+
+var (
+	_ store.UserStoreInterface      = (*UserStore)(nil)
+	_ store.UserEventStoreInterface = (*UserStore)(nil)
+)
+
+func NewUserStore() *UserStore {
+	return &UserStore{
+		users:              make(map[uuid.UUID]model.User),
+		emailVerifications: make(map[uuid.UUID]model.EmailVerification),
+		phoneVerifications: make(map[uuid.UUID]model.PhoneVerification),
+		passwordHashes:     make(map[uuid.UUID]string),
+		sessions:           make(map[uuid.UUID]model.Session),
+		passwordResets:     make(map[uuid.UUID]model.PasswordReset),
+		totpFactors:        make(map[uuid.UUID]model.TOTPFactor),
+		recoveryCodes:      make(map[uuid.UUID][][]byte),
+		mfaChallenges:      make(map[uuid.UUID]model.MFAChallenge),
+		groups:             make(map[uuid.UUID]model.Group),
+		groupMembers:       make(map[uuid.UUID]map[uuid.UUID]bool),
+		blobs:              make(map[string]model.Blob),
+		oauthClients:       make(map[uuid.UUID]model.OAuthClient),
+		oauthConsents:      make(map[uuid.UUID]map[uuid.UUID]model.OAuthConsent),
+		authorizationCodes: make(map[uuid.UUID]model.AuthorizationCode),
+		oauthRefreshTokens: make(map[uuid.UUID]model.OAuthRefreshToken),
+	}
+}
+
+// OnEvent registers fn to be called with every change event after it has been
+// recorded. It plays the part of LISTEN/NOTIFY for a single process.
+func (userStore *UserStore) OnEvent(fn func(model.UserEvent)) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+	userStore.onEvent = fn
+}
+
+// SetEmailRules sets which addresses are one mailbox, and so one user.
+func (userStore *UserStore) SetEmailRules(rules emailaddr.Rules) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+	userStore.emailRules = rules
+}
+
+// SetPhoneParser sets how phone numbers, such as those in national format,
+// are read.
+func (userStore *UserStore) SetPhoneParser(parser phonenumber.Parser) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+	userStore.phoneParser = parser
+}
+
+func (userStore *UserStore) CreateUser(ctx context.Context, user model.User) (model.User, error) {
+	user, err := store.NormalizeEmail(user)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	user, err = store.NormalizePhone(user, userStore.phoneParser)
+	if err != nil {
+		return model.User{}, err
+	}
+
+	if userStore.emailTaken(user.Email, uuid.Nil) {
+		return model.User{}, store.ErrDuplicateEmail
+	}
+	attrs, err := userStore.checkAttributes(user.Attributes)
+	if err != nil {
+		return model.User{}, err
+	}
+	user.Attributes = attrs
+
+	user.UserId = uuid.New()
+	user.EmailVerifiedAt = nil
+	user.PhoneVerifiedAt = nil
+	user.AvatarUrl = ""
+	if user.Status == "" {
+		user.Status = model.StatusActive
+	}
+	user = normalize(user)
+
+	userStore.users[user.UserId] = user
+	userStore.recordEvent(model.EventUserCreated, user)
+
+	return user, nil
+}
+
+func (userStore *UserStore) GetAllUsers(ctx context.Context) ([]model.User, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	users := make([]model.User, 0, len(userStore.users))
+	for _, u := range userStore.users {
+		users = append(users, u)
+	}
+	return users, nil
+}
+
+func (userStore *UserStore) GetUserById(ctx context.Context, userId uuid.UUID) (model.User, bool, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	user, ok := userStore.users[userId]
+	return user, ok, nil
+}
+
+func (userStore *UserStore) GetUsersByIds(ctx context.Context, userIds []uuid.UUID) ([]model.User, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	users := make([]model.User, 0, len(userIds))
+	seen := make(map[uuid.UUID]bool, len(userIds))
+	for _, userId := range userIds {
+		if user, ok := userStore.users[userId]; ok && !seen[userId] {
+			seen[userId] = true
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+func (userStore *UserStore) ListUsers(ctx context.Context, filter model.UserFilter, after uuid.UUID, limit int) ([]model.User, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	name := strings.ToLower(filter.Name)
+	wanted := userStore.compiledAttributeSchema().Filter(filter.Attributes)
+
+	var users []model.User
+	for _, u := range userStore.users {
+		if bytes.Compare(u.UserId[:], after[:]) <= 0 {
+			continue
+		}
+		if filter.Status != "" && u.Status != filter.Status {
+			continue
+		}
+		if filter.Email != "" && userStore.emailRules.Key(u.Email) != userStore.emailRules.Key(filter.Email) {
+			continue
+		}
+		if name != "" &&
+			!strings.Contains(strings.ToLower(u.FirstName), name) &&
+			!strings.Contains(strings.ToLower(u.LastName), name) {
+			continue
+		}
+		if filter.GroupId != uuid.Nil && !userStore.groupMembers[filter.GroupId][u.UserId] {
+			continue
+		}
+		if !hasAttributes(u, wanted) {
+			continue
+		}
+		users = append(users, u)
+	}
+
+	// Postgres orders uuids by their bytes
+	slices.SortFunc(users, func(a, b model.User) int {
+		return bytes.Compare(a.UserId[:], b.UserId[:])
+	})
+
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// CountUsersByStatus returns the number of users in each status. Statuses
+// without users are left out.
+func (userStore *UserStore) CountUsersByStatus(ctx context.Context) (map[model.Status]int, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	counts := make(map[model.Status]int)
+	for _, u := range userStore.users {
+		counts[u.Status]++
+	}
+	return counts, nil
+}
+
+func (userStore *UserStore) UpdateUser(ctx context.Context, user model.User, userId uuid.UUID) (model.User, bool, error) {
+	user, err := store.NormalizeEmail(user)
+	if err != nil {
+		return model.User{}, false, err
+	}
+
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	return userStore.update(user, userId)
+}
+
+func (userStore *UserStore) ModifyUser(ctx context.Context, userId uuid.UUID, precondition model.Precondition, modify func(user *model.User) error) (model.User, bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	existing, ok := userStore.users[userId]
+	if !ok {
+		return model.User{}, false, nil
+	}
+	if !precondition.Holds(existing, true) {
+		return model.User{}, false, store.ErrPreconditionFailed
+	}
+
+	// modify must not reach the stored user's attributes
+	user := existing
+	user.Attributes = maps.Clone(existing.Attributes)
+	if err := modify(&user); err != nil {
+		return model.User{}, false, err
+	}
+	user, err := store.NormalizeEmail(user)
+	if err != nil {
+		return model.User{}, false, err
+	}
+	return userStore.update(user, userId)
+}
+
+// update writes the fields of user over those of the user with userId, as
+// UpdateUser does. The caller holds mu.
+func (userStore *UserStore) update(user model.User, userId uuid.UUID) (model.User, bool, error) {
+	existing, ok := userStore.users[userId]
+	if !ok {
+		return model.User{}, false, nil
+	}
+	user, err := store.NormalizeChangedPhone(user, existing, userStore.phoneParser)
+	if err != nil {
+		return model.User{}, false, err
+	}
+	if userStore.emailTaken(user.Email, userId) {
+		return model.User{}, false, store.ErrDuplicateEmail
+	}
+	attrs, err := userStore.checkAttributes(user.Attributes)
+	if err != nil {
+		return model.User{}, false, err
+	}
+	user.Attributes = attrs
+
+	user.UserId = userId
+	user.AvatarUrl = existing.AvatarUrl
+	user.EmailVerifiedAt, user.PhoneVerifiedAt = nil, nil
+	if userStore.emailRules.Key(user.Email) == userStore.emailRules.Key(existing.Email) {
+		user.EmailVerifiedAt = existing.EmailVerifiedAt
+	}
+	if user.Phone == existing.Phone {
+		user.PhoneVerifiedAt = existing.PhoneVerifiedAt
+	}
+	user = normalize(user)
+
+	userStore.users[userId] = user
+	userStore.recordEvent(model.EventUserUpdated, user)
+
+	return user, true, nil
+}
+
+func (userStore *UserStore) PutUser(ctx context.Context, user model.User, precondition model.Precondition) (model.User, bool, error) {
+	user, err := store.NormalizeEmail(user)
+	if err != nil {
+		return model.User{}, false, err
+	}
+
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	existing, exists := userStore.users[user.UserId]
+	if !precondition.Holds(existing, exists) {
+		return model.User{}, false, store.ErrPreconditionFailed
+	}
+	if exists {
+		user, err = store.NormalizeChangedPhone(user, existing, userStore.phoneParser)
+	} else {
+		user, err = store.NormalizePhone(user, userStore.phoneParser)
+	}
+	if err != nil {
+		return model.User{}, false, err
+	}
+	if userStore.emailTaken(user.Email, user.UserId) {
+		return model.User{}, false, store.ErrDuplicateEmail
+	}
+	attrs, err := userStore.checkAttributes(user.Attributes)
+	if err != nil {
+		return model.User{}, false, err
+	}
+	user.Attributes = attrs
+
+	user.AvatarUrl = existing.AvatarUrl
+	user.EmailVerifiedAt, user.PhoneVerifiedAt = nil, nil
+	if exists && userStore.emailRules.Key(user.Email) == userStore.emailRules.Key(existing.Email) {
+		user.EmailVerifiedAt = existing.EmailVerifiedAt
+	}
+	if exists && user.Phone == existing.Phone {
+		user.PhoneVerifiedAt = existing.PhoneVerifiedAt
+	}
+	if user.Status == "" {
+		user.Status = model.StatusActive
+	}
+	user = normalize(user)
+
+	userStore.users[user.UserId] = user
+	if exists {
+		userStore.recordEvent(model.EventUserUpdated, user)
+	} else {
+		userStore.recordEvent(model.EventUserCreated, user)
+	}
+
+	return user, !exists, nil
+}
+
+func (userStore *UserStore) DeleteUser(ctx context.Context, userId uuid.UUID) (bool, error) {
+	userStore.mu.Lock()
+	defer userStore.mu.Unlock()
+
+	user, ok := userStore.users[userId]
+	if !ok {
+		return false, nil
+	}
+
+	delete(userStore.users, userId)
+	for tokenId, verification := range userStore.emailVerifications {
+		if verification.UserId == userId {
+			delete(userStore.emailVerifications, tokenId)
+		}
+	}
+	delete(userStore.phoneVerifications, userId)
+	delete(userStore.passwordHashes, userId)
+	for sessionId, session := range userStore.sessions {
+		if session.UserId == userId {
+			delete(userStore.sessions, sessionId)
+		}
+	}
+	for tokenId, reset := range userStore.passwordResets {
+		if reset.UserId == userId {
+			delete(userStore.passwordResets, tokenId)
+		}
+	}
+	delete(userStore.totpFactors, userId)
+	delete(userStore.recoveryCodes, userId)
+	for challengeId, challenge := range userStore.mfaChallenges {
+		if challenge.UserId == userId {
+			delete(userStore.mfaChallenges, challengeId)
+		}
+	}
+	for _, members := range userStore.groupMembers {
+		delete(members, userId)
+	}
+	delete(userStore.oauthConsents, userId)
+	for codeId, code := range userStore.authorizationCodes {
+		if code.UserId == userId {
+			delete(userStore.authorizationCodes, codeId)
+		}
+	}
+	for tokenId, token := range userStore.oauthRefreshTokens {
+		if token.UserId == userId {
+			delete(userStore.oauthRefreshTokens, tokenId)
+		}
+	}
+	userStore.recordEvent(model.EventUserDeleted, user)
+
+	return true, nil
+}
+
+func (userStore *UserStore) GetUserEventsAfter(ctx context.Context, eventId int64, limit int) ([]model.UserEvent, error) {
+	userStore.mu.RLock()
+	defer userStore.mu.RUnlock()
+
+	// event ids start at 1 and have no gaps, so they double as indexes
+	start := min(max(eventId, 0), int64(len(userStore.events)))
+	end := min(start+int64(limit), int64(len(userStore.events)))
+
+	return slices.Clone(userStore.events[start:end]), nil
+}
+
+func (userStore *UserStore) emailTaken(email string, except uuid.UUID) bool {
+	key := userStore.emailRules.Key(email)
+	for _, u := range userStore.users {
+		if userStore.emailRules.Key(u.Email) == key && u.UserId != except {
+			return true
+		}
+	}
+	return false
+}
+
+// recordEvent must be called with the write lock held.
+func (userStore *UserStore) recordEvent(eventType model.EventType, user model.User) {
+	userStore.lastEventId++
+	event := model.UserEvent{
+		EventId:   userStore.lastEventId,
+		Type:      eventType,
+		UserId:    user.UserId,
+		User:      user,
+		CreatedAt: time.Now(),
+	}
+	userStore.events = append(userStore.events, event)
+
+	if userStore.onEvent != nil {
+		userStore.onEvent(event)
+	}
+}
+
+// hasAttributes reports whether the user has every attribute in wanted with
+// the same value, as Postgres containment does.
+func hasAttributes(user model.User, wanted map[string]any) bool {
+	for name, value := range wanted {
+		held, ok := user.Attributes[name]
+		if !ok || !reflect.DeepEqual(held, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalize mirrors the NULL handling of the age column.
+func normalize(user model.User) model.User {
+	if user.Age < 0 {
+		user.Age = 0
+	}
+	return user
+}
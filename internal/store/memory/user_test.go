@@ -0,0 +1,135 @@
+package memory
+
+import (
+	"testing"
+
+	"example.com/user-management/internal/emailaddr"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/store/storetest"
+)
+
+func TestUserStoreConformance(t *testing.T) {
+	storetest.RunUserStoreTests(t, func(t *testing.T) store.UserStoreInterface {
+		return NewUserStore()
+	})
+}
+
+func TestEmailProviderRules(t *testing.T) {
+	storetest.RunEmailProviderRulesTests(t, func(t *testing.T) store.UserStoreInterface {
+		userStore := NewUserStore()
+		userStore.SetEmailRules(emailaddr.Rules{Providers: true})
+		return userStore
+	})
+}
+
+func TestEmailVerificationStoreConformance(t *testing.T) {
+	storetest.RunEmailVerificationStoreTests(t, func(t *testing.T) storetest.EmailVerificationStore {
+		return NewUserStore()
+	})
+}
+
+func TestPhoneVerificationStoreConformance(t *testing.T) {
+	storetest.RunPhoneVerificationStoreTests(t, func(t *testing.T) storetest.PhoneVerificationStore {
+		return NewUserStore()
+	})
+}
+
+func TestAuthStoreConformance(t *testing.T) {
+	storetest.RunAuthStoreTests(t, func(t *testing.T) storetest.AuthStore {
+		return NewUserStore()
+	})
+}
+
+func TestMFAStoreConformance(t *testing.T) {
+	storetest.RunMFAStoreTests(t, func(t *testing.T) storetest.MFAStore {
+		return NewUserStore()
+	})
+}
+
+func TestGroupStoreConformance(t *testing.T) {
+	storetest.RunGroupStoreTests(t, func(t *testing.T) storetest.GroupStore {
+		return NewUserStore()
+	})
+}
+
+func TestAttributeSchemaStoreConformance(t *testing.T) {
+	storetest.RunAttributeSchemaStoreTests(t, func(t *testing.T) storetest.AttributeSchemaStore {
+		return NewUserStore()
+	})
+}
+
+func TestAvatarStoreConformance(t *testing.T) {
+	storetest.RunAvatarStoreTests(t, func(t *testing.T) storetest.AvatarStore {
+		return NewUserStore()
+	})
+}
+
+func TestBlobStoreConformance(t *testing.T) {
+	storetest.RunBlobStoreTests(t, func(t *testing.T) store.BlobStoreInterface {
+		return NewUserStore()
+	})
+}
+
+func TestOIDCStoreConformance(t *testing.T) {
+	storetest.RunOIDCStoreTests(t, func(t *testing.T) storetest.OIDCStore {
+		return NewUserStore()
+	})
+}
+
+func TestUserEvents_RecordedForEachChange(t *testing.T) {
+	userStore := NewUserStore()
+
+	var published []model.UserEvent
+	userStore.OnEvent(func(event model.UserEvent) {
+		published = append(published, event)
+	})
+
+	user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if _, _, err := userStore.UpdateUser(t.Context(), user, user.UserId); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if _, err := userStore.DeleteUser(t.Context(), user.UserId); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+
+	got, err := userStore.GetUserEventsAfter(t.Context(), 1, 10)
+	if err != nil {
+		t.Fatalf("GetUserEventsAfter failed: %v", err)
+	}
+
+	want := []model.EventType{model.EventUserUpdated, model.EventUserDeleted}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d events, got %d", len(want), len(got))
+	}
+	for i, event := range got {
+		if event.EventId != int64(i+2) || event.Type != want[i] || event.UserId != user.UserId {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	}
+	if len(published) != 3 {
+		t.Errorf("Expected 3 published events, got %d", len(published))
+	}
+}
+
+func TestCreateUser_PhoneRegion(t *testing.T) {
+	userStore := NewUserStore()
+	userStore.SetPhoneParser(phonenumber.Parser{DefaultRegion: "LK"})
+
+	created, err := userStore.CreateUser(t.Context(), model.User{
+		FirstName: "Alice",
+		LastName:  "Smith",
+		Email:     "alice@example.com",
+		Phone:     "071 234 5678",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if created.Phone != "+94712345678" || created.PhoneCountry != "LK" || created.PhoneType != "mobile" {
+		t.Errorf("Expected the number of a mobile in LK, got %q, %q, %q", created.Phone, created.PhoneCountry, created.PhoneType)
+	}
+}
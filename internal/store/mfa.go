@@ -0,0 +1,326 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/model"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrMFAEnabled    = errors.New("multi-factor authentication already enabled")
+	ErrMFANotEnabled = errors.New("multi-factor authentication not enabled")
+	// ErrFactorNotFound is returned when the user has not started enrolling
+	// an authenticator app.
+	ErrFactorNotFound    = errors.New("factor not found")
+	ErrCodeReused        = errors.New("code already used")
+	ErrChallengeNotFound = errors.New("challenge not found")
+	ErrChallengeExpired  = errors.New("challenge expired")
+)
+
+type MFAStoreInterface interface {
+	// CreateTOTPFactor starts enrolling an authenticator app, replacing one
+	// the user has not confirmed. It returns ErrMFAEnabled if they already
+	// have a confirmed one.
+	CreateTOTPFactor(ctx context.Context, factor model.TOTPFactor) error
+	GetTOTPFactor(ctx context.Context, userId uuid.UUID) (model.TOTPFactor, bool, error)
+	// ConfirmTOTPFactor turns on the user's factor at now, counting the code
+	// of the given step as used, and replaces their recovery codes with
+	// recoveryCodeHashes. The change is written to the audit trail.
+	ConfirmTOTPFactor(ctx context.Context, userId uuid.UUID, step int64, recoveryCodeHashes [][]byte, now time.Time) error
+	// UseTOTPStep counts the code of the given step as used. It returns
+	// ErrCodeReused unless the step is later than the last one used.
+	UseTOTPStep(ctx context.Context, userId uuid.UUID, step int64) error
+	// ReplaceRecoveryCodes replaces the user's recovery codes with
+	// codeHashes. The change is written to the audit trail.
+	ReplaceRecoveryCodes(ctx context.Context, userId uuid.UUID, codeHashes [][]byte) error
+	// UseRecoveryCode uses up the user's recovery code with codeHash at now,
+	// or returns ErrWrongCode. The use is written to the audit trail.
+	UseRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash []byte, now time.Time) error
+	// ResetMFA removes the user's factor and recovery codes, so that they log
+	// in with their password alone, or returns false if they had no factor.
+	// The change is written to the audit trail.
+	ResetMFA(ctx context.Context, userId uuid.UUID) (bool, error)
+	CreateMFAChallenge(ctx context.Context, challenge model.MFAChallenge) error
+	// CountMFAChallengeAttempt counts an attempt at answering the challenge,
+	// returning ErrTooManyAttempts once maxAttempts have been made.
+	CountMFAChallengeAttempt(ctx context.Context, challengeId uuid.UUID, maxAttempts int, now time.Time) (model.MFAChallenge, error)
+	DeleteMFAChallenge(ctx context.Context, challengeId uuid.UUID) (bool, error)
+}
+
+var _ MFAStoreInterface = (*UserStore)(nil)
+
+// CheckTOTPFactorConfirmed returns ErrMFANotEnabled if factor does not guard
+// logins yet.
+func CheckTOTPFactorConfirmed(factor model.TOTPFactor) error {
+	if factor.ConfirmedAt == nil {
+		return ErrMFANotEnabled
+	}
+	return nil
+}
+
+// CheckMFAChallenge returns ErrChallengeExpired if challenge can no longer be
+// answered at now. The attempt must already have been counted.
+func CheckMFAChallenge(challenge model.MFAChallenge, now time.Time) error {
+	if !now.Before(challenge.ExpiresAt) {
+		return ErrChallengeExpired
+	}
+	return nil
+}
+
+func (store *UserStore) CreateTOTPFactor(ctx context.Context, factor model.TOTPFactor) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		created, err := queries.CreateTOTPFactor(ctx,
+			db.CreateTOTPFactorParams{
+				UserID:           factor.UserId,
+				SecretCiphertext: factor.SecretCiphertext,
+				CreatedAt:        factor.CreatedAt,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if created == 0 {
+			return ErrMFAEnabled
+		}
+
+		return recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &factor.UserId,
+			Action: model.AuditMFAEnrolmentStarted,
+		})
+	})
+}
+
+func (store *UserStore) GetTOTPFactor(ctx context.Context, userId uuid.UUID) (model.TOTPFactor, bool, error) {
+	dbFactor, err := query(ctx, store, func(queries *db.Queries) (db.TotpFactor, error) {
+		return queries.GetTOTPFactor(ctx, userId)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.TOTPFactor{}, false, nil
+		}
+		return model.TOTPFactor{}, false, err
+	}
+
+	return mapDbTOTPFactorToModel(&dbFactor), true, nil
+}
+
+func (store *UserStore) ConfirmTOTPFactor(ctx context.Context, userId uuid.UUID, step int64, recoveryCodeHashes [][]byte, now time.Time) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		confirmed, err := queries.ConfirmTOTPFactor(ctx,
+			db.ConfirmTOTPFactorParams{
+				ConfirmedAt: sql.NullTime{Time: now, Valid: true},
+				Step:        step,
+				UserID:      userId,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if confirmed == 0 {
+			if _, err := queries.GetTOTPFactor(ctx, userId); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return ErrFactorNotFound
+				}
+				return err
+			}
+			return ErrMFAEnabled
+		}
+
+		if err := replaceRecoveryCodes(ctx, queries, userId, recoveryCodeHashes); err != nil {
+			return err
+		}
+		return recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &userId,
+			Action: model.AuditMFAEnabled,
+		})
+	})
+}
+
+func (store *UserStore) UseTOTPStep(ctx context.Context, userId uuid.UUID, step int64) error {
+	used, err := query(ctx, store, func(queries *db.Queries) (int64, error) {
+		return queries.UseTOTPStep(ctx,
+			db.UseTOTPStepParams{
+				Step:   step,
+				UserID: userId,
+			},
+		)
+	})
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return ErrCodeReused
+	}
+	return nil
+}
+
+func (store *UserStore) ReplaceRecoveryCodes(ctx context.Context, userId uuid.UUID, codeHashes [][]byte) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		dbFactor, err := queries.GetTOTPFactor(ctx, userId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrMFANotEnabled
+			}
+			return err
+		}
+		if err := CheckTOTPFactorConfirmed(mapDbTOTPFactorToModel(&dbFactor)); err != nil {
+			return err
+		}
+
+		if err := replaceRecoveryCodes(ctx, queries, userId, codeHashes); err != nil {
+			return err
+		}
+		return recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &userId,
+			Action: model.AuditMFARecoveryCodesReplaced,
+		})
+	})
+}
+
+func (store *UserStore) UseRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash []byte, now time.Time) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		used, err := queries.UseRecoveryCode(ctx,
+			db.UseRecoveryCodeParams{
+				UsedAt:   sql.NullTime{Time: now, Valid: true},
+				UserID:   userId,
+				CodeHash: codeHash,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return ErrWrongCode
+		}
+
+		return recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &userId,
+			Action: model.AuditMFARecoveryCodeUsed,
+		})
+	})
+}
+
+func (store *UserStore) ResetMFA(ctx context.Context, userId uuid.UUID) (bool, error) {
+	var reset bool
+	err := store.withTx(ctx, func(queries *db.Queries) error {
+		deleted, err := queries.DeleteTOTPFactor(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return nil
+		}
+		reset = true
+
+		if err := queries.DeleteRecoveryCodes(ctx, userId); err != nil {
+			return err
+		}
+		return recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &userId,
+			Action: model.AuditMFAReset,
+		})
+	})
+
+	if err != nil {
+		return false, err
+	}
+	return reset, nil
+}
+
+func (store *UserStore) CreateMFAChallenge(ctx context.Context, challenge model.MFAChallenge) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		return queries.CreateMFAChallenge(ctx,
+			db.CreateMFAChallengeParams{
+				ChallengeID: challenge.ChallengeId,
+				UserID:      challenge.UserId,
+				ExpiresAt:   challenge.ExpiresAt,
+			},
+		)
+	})
+}
+
+func (store *UserStore) CountMFAChallengeAttempt(ctx context.Context, challengeId uuid.UUID, maxAttempts int, now time.Time) (model.MFAChallenge, error) {
+	var dbChallenge db.MfaChallenge
+	err := store.withTx(ctx, func(queries *db.Queries) error {
+		var err error
+		dbChallenge, err = queries.CountMFAChallengeAttempt(ctx,
+			db.CountMFAChallengeAttemptParams{
+				ChallengeID: challengeId,
+				MaxAttempts: int32(maxAttempts),
+			},
+		)
+		if errors.Is(err, sql.ErrNoRows) {
+			if _, err := queries.GetMFAChallenge(ctx, challengeId); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return ErrChallengeNotFound
+				}
+				return err
+			}
+			return ErrTooManyAttempts
+		}
+		return err
+	})
+	if err != nil {
+		return model.MFAChallenge{}, err
+	}
+
+	challenge := mapDbMFAChallengeToModel(&dbChallenge)
+	if err := CheckMFAChallenge(challenge, now); err != nil {
+		return model.MFAChallenge{}, err
+	}
+	return challenge, nil
+}
+
+func (store *UserStore) DeleteMFAChallenge(ctx context.Context, challengeId uuid.UUID) (bool, error) {
+	deleted, err := query(ctx, store, func(queries *db.Queries) (int64, error) {
+		return queries.DeleteMFAChallenge(ctx, challengeId)
+	})
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, queries *db.Queries, userId uuid.UUID, codeHashes [][]byte) error {
+	if err := queries.DeleteRecoveryCodes(ctx, userId); err != nil {
+		return err
+	}
+	for _, codeHash := range codeHashes {
+		if err := queries.CreateRecoveryCode(ctx,
+			db.CreateRecoveryCodeParams{
+				UserID:   userId,
+				CodeHash: codeHash,
+			},
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mapDbTOTPFactorToModel(dbFactor *db.TotpFactor) model.TOTPFactor {
+	factor := model.TOTPFactor{
+		UserId:           dbFactor.UserID,
+		SecretCiphertext: dbFactor.SecretCiphertext,
+		LastUsedStep:     dbFactor.LastUsedStep,
+		CreatedAt:        dbFactor.CreatedAt,
+	}
+	if dbFactor.ConfirmedAt.Valid {
+		factor.ConfirmedAt = &dbFactor.ConfirmedAt.Time
+	}
+	return factor
+}
+
+func mapDbMFAChallengeToModel(dbChallenge *db.MfaChallenge) model.MFAChallenge {
+	return model.MFAChallenge{
+		ChallengeId: dbChallenge.ChallengeID,
+		UserId:      dbChallenge.UserID,
+		Attempts:    int(dbChallenge.Attempts),
+		ExpiresAt:   dbChallenge.ExpiresAt,
+	}
+}
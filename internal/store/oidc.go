@@ -0,0 +1,323 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/model"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenExpired  = errors.New("refresh token expired")
+)
+
+// OIDCStoreInterface keeps the state of the OpenID Connect provider: the
+// registered clients, what users have consented to share with them, and the
+// codes and refresh tokens they hold.
+type OIDCStoreInterface interface {
+	// CreateOAuthClient registers client under a newly generated id.
+	CreateOAuthClient(ctx context.Context, client model.OAuthClient) (model.OAuthClient, error)
+	GetOAuthClient(ctx context.Context, clientId uuid.UUID) (model.OAuthClient, bool, error)
+	// GetAllOAuthClients returns every client, ordered by name.
+	GetAllOAuthClients(ctx context.Context) ([]model.OAuthClient, error)
+	// DeleteOAuthClient deletes the client along with the consents, codes
+	// and refresh tokens issued to it.
+	DeleteOAuthClient(ctx context.Context, clientId uuid.UUID) (bool, error)
+	GetOAuthConsent(ctx context.Context, userId, clientId uuid.UUID) (model.OAuthConsent, bool, error)
+	// SaveOAuthConsent creates or replaces the consent of the user for the
+	// client.
+	SaveOAuthConsent(ctx context.Context, consent model.OAuthConsent) error
+	CreateAuthorizationCode(ctx context.Context, code model.AuthorizationCode) error
+	// ConsumeAuthorizationCode deletes the code and returns it, so it can
+	// only be redeemed once. Expiry is left to the caller.
+	ConsumeAuthorizationCode(ctx context.Context, codeId uuid.UUID) (model.AuthorizationCode, bool, error)
+	CreateOAuthRefreshToken(ctx context.Context, token model.OAuthRefreshToken) error
+	// RotateOAuthRefreshToken replaces the id of the client's refresh token
+	// with newTokenId, provided it has not expired by now.
+	RotateOAuthRefreshToken(ctx context.Context, tokenId, newTokenId, clientId uuid.UUID, now time.Time) (model.OAuthRefreshToken, error)
+	// RevokeOAuthRefreshToken deletes the client's refresh token, or returns
+	// false if it has none with tokenId.
+	RevokeOAuthRefreshToken(ctx context.Context, tokenId, clientId uuid.UUID) (bool, error)
+	CreateSigningKey(ctx context.Context, key model.SigningKey) error
+	// GetSigningKeys returns every signing key, newest first.
+	GetSigningKeys(ctx context.Context) ([]model.SigningKey, error)
+	// DeleteSigningKeys deletes the keys created before the given time.
+	DeleteSigningKeys(ctx context.Context, before time.Time) error
+}
+
+var _ OIDCStoreInterface = (*UserStore)(nil)
+
+// CheckOAuthRefreshToken returns ErrRefreshTokenExpired if token has expired
+// by now.
+func CheckOAuthRefreshToken(token model.OAuthRefreshToken, now time.Time) error {
+	if !now.Before(token.ExpiresAt) {
+		return ErrRefreshTokenExpired
+	}
+	return nil
+}
+
+func (store *UserStore) CreateOAuthClient(ctx context.Context, client model.OAuthClient) (model.OAuthClient, error) {
+	redirectURIs, err := json.Marshal(client.RedirectURIs)
+	if err != nil {
+		return model.OAuthClient{}, err
+	}
+
+	dbClient, err := query(ctx, store, func(queries *db.Queries) (db.OauthClient, error) {
+		return queries.CreateOAuthClient(ctx,
+			db.CreateOAuthClientParams{
+				Name:         client.Name,
+				SecretHash:   client.SecretHash,
+				RedirectUris: redirectURIs,
+			},
+		)
+	})
+	if err != nil {
+		return model.OAuthClient{}, err
+	}
+
+	return mapDbOAuthClientToModel(&dbClient)
+}
+
+func (store *UserStore) GetOAuthClient(ctx context.Context, clientId uuid.UUID) (model.OAuthClient, bool, error) {
+	dbClient, err := query(ctx, store, func(queries *db.Queries) (db.OauthClient, error) {
+		return queries.GetOAuthClient(ctx, clientId)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.OAuthClient{}, false, nil
+		}
+		return model.OAuthClient{}, false, err
+	}
+
+	client, err := mapDbOAuthClientToModel(&dbClient)
+	if err != nil {
+		return model.OAuthClient{}, false, err
+	}
+	return client, true, nil
+}
+
+func (store *UserStore) GetAllOAuthClients(ctx context.Context) ([]model.OAuthClient, error) {
+	dbClients, err := query(ctx, store, func(queries *db.Queries) ([]db.OauthClient, error) {
+		return queries.GetAllOAuthClients(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	clients := make([]model.OAuthClient, 0, len(dbClients))
+	for i := range dbClients {
+		client, err := mapDbOAuthClientToModel(&dbClients[i])
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+func (store *UserStore) DeleteOAuthClient(ctx context.Context, clientId uuid.UUID) (bool, error) {
+	deleted, err := query(ctx, store, func(queries *db.Queries) (int64, error) {
+		return queries.DeleteOAuthClient(ctx, clientId)
+	})
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+func (store *UserStore) GetOAuthConsent(ctx context.Context, userId, clientId uuid.UUID) (model.OAuthConsent, bool, error) {
+	dbConsent, err := query(ctx, store, func(queries *db.Queries) (db.OauthConsent, error) {
+		return queries.GetOAuthConsent(ctx,
+			db.GetOAuthConsentParams{
+				UserID:   userId,
+				ClientID: clientId,
+			},
+		)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.OAuthConsent{}, false, nil
+		}
+		return model.OAuthConsent{}, false, err
+	}
+
+	return model.OAuthConsent{
+		UserId:    dbConsent.UserID,
+		ClientId:  dbConsent.ClientID,
+		Scope:     dbConsent.Scope,
+		GrantedAt: dbConsent.GrantedAt,
+	}, true, nil
+}
+
+func (store *UserStore) SaveOAuthConsent(ctx context.Context, consent model.OAuthConsent) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		return queries.SaveOAuthConsent(ctx,
+			db.SaveOAuthConsentParams{
+				UserID:    consent.UserId,
+				ClientID:  consent.ClientId,
+				Scope:     consent.Scope,
+				GrantedAt: consent.GrantedAt,
+			},
+		)
+	})
+}
+
+func (store *UserStore) CreateAuthorizationCode(ctx context.Context, code model.AuthorizationCode) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		return queries.CreateAuthorizationCode(ctx,
+			db.CreateAuthorizationCodeParams{
+				CodeID:        code.CodeId,
+				ClientID:      code.ClientId,
+				UserID:        code.UserId,
+				RedirectUri:   code.RedirectURI,
+				Scope:         code.Scope,
+				Nonce:         code.Nonce,
+				CodeChallenge: code.CodeChallenge,
+				ExpiresAt:     code.ExpiresAt,
+			},
+		)
+	})
+}
+
+func (store *UserStore) ConsumeAuthorizationCode(ctx context.Context, codeId uuid.UUID) (model.AuthorizationCode, bool, error) {
+	dbCode, err := query(ctx, store, func(queries *db.Queries) (db.OauthAuthorizationCode, error) {
+		return queries.ConsumeAuthorizationCode(ctx, codeId)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.AuthorizationCode{}, false, nil
+		}
+		return model.AuthorizationCode{}, false, err
+	}
+
+	return model.AuthorizationCode{
+		CodeId:        dbCode.CodeID,
+		ClientId:      dbCode.ClientID,
+		UserId:        dbCode.UserID,
+		RedirectURI:   dbCode.RedirectUri,
+		Scope:         dbCode.Scope,
+		Nonce:         dbCode.Nonce,
+		CodeChallenge: dbCode.CodeChallenge,
+		ExpiresAt:     dbCode.ExpiresAt,
+	}, true, nil
+}
+
+func (store *UserStore) CreateOAuthRefreshToken(ctx context.Context, token model.OAuthRefreshToken) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		return queries.CreateOAuthRefreshToken(ctx,
+			db.CreateOAuthRefreshTokenParams{
+				TokenID:   token.TokenId,
+				ClientID:  token.ClientId,
+				UserID:    token.UserId,
+				Scope:     token.Scope,
+				CreatedAt: token.CreatedAt,
+				ExpiresAt: token.ExpiresAt,
+			},
+		)
+	})
+}
+
+func (store *UserStore) RotateOAuthRefreshToken(ctx context.Context, tokenId, newTokenId, clientId uuid.UUID, now time.Time) (model.OAuthRefreshToken, error) {
+	var token model.OAuthRefreshToken
+	err := store.withTx(ctx, func(queries *db.Queries) error {
+		dbToken, err := queries.RotateOAuthRefreshToken(ctx,
+			db.RotateOAuthRefreshTokenParams{
+				NewTokenID: newTokenId,
+				TokenID:    tokenId,
+				ClientID:   clientId,
+			},
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrRefreshTokenNotFound
+			}
+			return err
+		}
+
+		token = model.OAuthRefreshToken{
+			TokenId:   dbToken.TokenID,
+			ClientId:  dbToken.ClientID,
+			UserId:    dbToken.UserID,
+			Scope:     dbToken.Scope,
+			CreatedAt: dbToken.CreatedAt,
+			ExpiresAt: dbToken.ExpiresAt,
+		}
+		return CheckOAuthRefreshToken(token, now)
+	})
+
+	if err != nil {
+		return model.OAuthRefreshToken{}, err
+	}
+	return token, nil
+}
+
+func (store *UserStore) RevokeOAuthRefreshToken(ctx context.Context, tokenId, clientId uuid.UUID) (bool, error) {
+	revoked, err := query(ctx, store, func(queries *db.Queries) (int64, error) {
+		return queries.RevokeOAuthRefreshToken(ctx,
+			db.RevokeOAuthRefreshTokenParams{
+				TokenID:  tokenId,
+				ClientID: clientId,
+			},
+		)
+	})
+	if err != nil {
+		return false, err
+	}
+	return revoked > 0, nil
+}
+
+func (store *UserStore) CreateSigningKey(ctx context.Context, key model.SigningKey) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		return queries.CreateSigningKey(ctx,
+			db.CreateSigningKeyParams{
+				KeyID:                key.KeyId,
+				PrivateKeyCiphertext: key.PrivateKeyCiphertext,
+				CreatedAt:            key.CreatedAt,
+			},
+		)
+	})
+}
+
+func (store *UserStore) GetSigningKeys(ctx context.Context) ([]model.SigningKey, error) {
+	dbKeys, err := query(ctx, store, func(queries *db.Queries) ([]db.OidcSigningKey, error) {
+		return queries.GetSigningKeys(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]model.SigningKey, len(dbKeys))
+	for i, dbKey := range dbKeys {
+		keys[i] = model.SigningKey{
+			KeyId:                dbKey.KeyID,
+			PrivateKeyCiphertext: dbKey.PrivateKeyCiphertext,
+			CreatedAt:            dbKey.CreatedAt,
+		}
+	}
+	return keys, nil
+}
+
+func (store *UserStore) DeleteSigningKeys(ctx context.Context, before time.Time) error {
+	return store.withTx(ctx, func(queries *db.Queries) error {
+		return queries.DeleteSigningKeys(ctx, before)
+	})
+}
+
+func mapDbOAuthClientToModel(dbClient *db.OauthClient) (model.OAuthClient, error) {
+	client := model.OAuthClient{
+		ClientId:   dbClient.ClientID,
+		Name:       dbClient.Name,
+		SecretHash: dbClient.SecretHash,
+		CreatedAt:  dbClient.CreatedAt,
+	}
+	if err := json.Unmarshal(dbClient.RedirectUris, &client.RedirectURIs); err != nil {
+		return model.OAuthClient{}, err
+	}
+	return client, nil
+}
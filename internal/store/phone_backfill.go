@@ -0,0 +1,76 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/tenant"
+)
+
+// PhoneBackfill is what NormalizePhones did.
+type PhoneBackfill struct {
+	// Normalized is how many numbers were rewritten.
+	Normalized int
+	// Unparseable are the users whose numbers could not be read, which are
+	// left as they were.
+	Unparseable []UnparseablePhone
+}
+
+// UnparseablePhone is a user whose number NormalizePhones could not read.
+type UnparseablePhone struct {
+	User model.User
+	// Err wraps phonenumber.ErrInvalid.
+	Err error
+}
+
+// NormalizePhones rewrites the phone numbers of the users in ctx's tenant,
+// or of every tenant with tenant.WithAllTenants, in E.164 format, for users
+// stored before numbers were normalized. Each user is updated in a
+// transaction of its own, acting for the user's tenant and recording an
+// update event, and numbers changed in the meantime are left to their writer.
+func (store *UserStore) NormalizePhones(ctx context.Context) (PhoneBackfill, error) {
+	var backfill PhoneBackfill
+
+	users, err := store.GetAllUsers(ctx)
+	if err != nil {
+		return backfill, err
+	}
+
+	for _, user := range users {
+		normalized, err := NormalizePhone(user, store.phoneParser)
+		if err != nil {
+			backfill.Unparseable = append(backfill.Unparseable, UnparseablePhone{User: user, Err: err})
+			continue
+		}
+		if normalized.Phone == user.Phone {
+			continue
+		}
+
+		// rows are only written for the tenant a transaction acts for
+		userCtx := tenant.WithID(ctx, user.TenantId)
+		err = store.withEventTx(userCtx, func(queries *db.Queries) (model.UserEvent, error) {
+			dbUser, err := queries.SetUserPhone(userCtx,
+				db.SetUserPhoneParams{
+					NewPhone: normalized.Phone,
+					UserID:   user.UserId,
+					Phone:    user.Phone,
+				},
+			)
+			if err != nil {
+				return model.UserEvent{}, err
+			}
+			return recordUserEvent(userCtx, queries, model.EventUserUpdated, mapDbUserToModel(&dbUser))
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return backfill, err
+		}
+		backfill.Normalized++
+	}
+	return backfill, nil
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/tenant"
+	"github.com/google/uuid"
+)
+
+// setStoredPhone writes phone as it is, the way numbers were stored before
+// they were normalized.
+func setStoredPhone(t *testing.T, ctx context.Context, userId uuid.UUID, oldPhone, phone string) {
+	t.Helper()
+	err := userStore.withTx(ctx, func(queries *db.Queries) error {
+		_, err := queries.SetUserPhone(ctx, db.SetUserPhoneParams{NewPhone: phone, UserID: userId, Phone: oldPhone})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("SetUserPhone failed: %v", err)
+	}
+}
+
+func TestNormalizePhones(t *testing.T) {
+	tenantA := tenant.WithID(t.Context(), uuid.New())
+	tenantB := tenant.WithID(t.Context(), uuid.New())
+	userA := createTenantUser(t, tenantA, "alice@example.com")
+	userB := createTenantUser(t, tenantB, "bob@example.com")
+	setStoredPhone(t, tenantA, userA.UserId, userA.Phone, "+1 (234) 567-8901")
+	setStoredPhone(t, tenantB, userB.UserId, userB.Phone, "555 0100")
+
+	backfill, err := userStore.NormalizePhones(tenant.WithAllTenants(t.Context()))
+	if err != nil {
+		t.Fatalf("NormalizePhones failed: %v", err)
+	}
+	if backfill.Normalized < 1 {
+		t.Errorf("Expected at least one number normalized, got %d", backfill.Normalized)
+	}
+
+	got, ok, err := userStore.GetUserById(tenantA, userA.UserId)
+	if err != nil || !ok {
+		t.Fatalf("GetUserById failed: %v, %v", ok, err)
+	}
+	if got.Phone != "+12345678901" || got.PhoneCountry != "US" {
+		t.Errorf("Expected the number in E.164 format, got %q in %q", got.Phone, got.PhoneCountry)
+	}
+
+	var unparseable *UnparseablePhone
+	for i := range backfill.Unparseable {
+		if backfill.Unparseable[i].User.UserId == userB.UserId {
+			unparseable = &backfill.Unparseable[i]
+		}
+	}
+	if unparseable == nil || !errors.Is(unparseable.Err, phonenumber.ErrInvalid) {
+		t.Fatalf("Expected the national number to be reported, got %+v", backfill.Unparseable)
+	}
+	got, _, err = userStore.GetUserById(tenantB, userB.UserId)
+	if err != nil || got.Phone != "555 0100" {
+		t.Errorf("Expected the unparseable number to be kept, got %q, %v", got.Phone, err)
+	}
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"crypto/hmac"
+	"database/sql"
+	"errors"
+	"time"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/model"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrResendTooSoon   = errors.New("a code was sent too recently")
+	ErrTooManyAttempts = errors.New("too many attempts")
+	ErrWrongCode       = errors.New("wrong code")
+	// ErrPhoneChanged is returned when the user no longer has the number a
+	// code was sent to.
+	ErrPhoneChanged = errors.New("phone changed since the code was sent")
+)
+
+type PhoneVerificationStoreInterface interface {
+	// CreatePhoneVerification replaces the user's outstanding code, unless
+	// that was sent after sentBefore, in which case it returns
+	// ErrResendTooSoon.
+	CreatePhoneVerification(ctx context.Context, verification model.PhoneVerification, sentBefore time.Time) error
+	GetPhoneVerification(ctx context.Context, userId uuid.UUID) (model.PhoneVerification, bool, error)
+	// ConfirmPhoneVerification counts an attempt at the user's outstanding
+	// code. If codeHash matches it, the code is used up and the number it
+	// was sent to marked as verified at now, and the updated user returned.
+	ConfirmPhoneVerification(ctx context.Context, userId uuid.UUID, codeHash []byte, maxAttempts int, now time.Time) (model.User, error)
+}
+
+var _ PhoneVerificationStoreInterface = (*UserStore)(nil)
+
+// CheckPhoneVerification returns ErrVerificationExpired or ErrWrongCode if
+// codeHash does not confirm verification at now. The attempt must already
+// have been counted.
+func CheckPhoneVerification(verification model.PhoneVerification, codeHash []byte, now time.Time) error {
+	if !now.Before(verification.ExpiresAt) {
+		return ErrVerificationExpired
+	}
+	if !hmac.Equal(verification.CodeHash, codeHash) {
+		return ErrWrongCode
+	}
+	return nil
+}
+
+func (store *UserStore) CreatePhoneVerification(ctx context.Context, verification model.PhoneVerification, sentBefore time.Time) error {
+	created, err := query(ctx, store, func(queries *db.Queries) (int64, error) {
+		return queries.CreatePhoneVerification(ctx,
+			db.CreatePhoneVerificationParams{
+				UserID:     verification.UserId,
+				Phone:      verification.Phone,
+				CodeHash:   verification.CodeHash,
+				ExpiresAt:  verification.ExpiresAt,
+				SentAt:     verification.SentAt,
+				SentBefore: sentBefore,
+			},
+		)
+	})
+	if err != nil {
+		return err
+	}
+	if created == 0 {
+		return ErrResendTooSoon
+	}
+	return nil
+}
+
+func (store *UserStore) GetPhoneVerification(ctx context.Context, userId uuid.UUID) (model.PhoneVerification, bool, error) {
+	dbVerification, err := query(ctx, store, func(queries *db.Queries) (db.PhoneVerification, error) {
+		return queries.GetPhoneVerification(ctx, userId)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.PhoneVerification{}, false, nil
+		}
+		return model.PhoneVerification{}, false, err
+	}
+
+	return mapDbPhoneVerificationToModel(&dbVerification), true, nil
+}
+
+func (store *UserStore) ConfirmPhoneVerification(ctx context.Context, userId uuid.UUID, codeHash []byte, maxAttempts int, now time.Time) (model.User, error) {
+	// the attempt is counted on its own, so that it sticks even when the
+	// code is wrong
+	dbVerification, err := query(ctx, store, func(queries *db.Queries) (db.PhoneVerification, error) {
+		return queries.CountPhoneVerificationAttempt(ctx,
+			db.CountPhoneVerificationAttemptParams{
+				UserID:      userId,
+				MaxAttempts: int32(maxAttempts),
+			},
+		)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, ok, err := store.GetPhoneVerification(ctx, userId); err != nil {
+			return model.User{}, err
+		} else if ok {
+			return model.User{}, ErrTooManyAttempts
+		}
+		return model.User{}, ErrVerificationNotFound
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+
+	verification := mapDbPhoneVerificationToModel(&dbVerification)
+	if err := CheckPhoneVerification(verification, codeHash, now); err != nil {
+		return model.User{}, err
+	}
+
+	var verifiedUser model.User
+	err = store.withEventTx(ctx, func(queries *db.Queries) (model.UserEvent, error) {
+		// a concurrent confirmation or a resend may have replaced it
+		deleted, err := queries.DeletePhoneVerification(ctx,
+			db.DeletePhoneVerificationParams{
+				UserID:   userId,
+				CodeHash: codeHash,
+			},
+		)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+		if deleted == 0 {
+			return model.UserEvent{}, ErrVerificationUsed
+		}
+
+		dbUser, err := queries.VerifyUserPhone(ctx,
+			db.VerifyUserPhoneParams{
+				PhoneVerifiedAt: sql.NullTime{Time: now, Valid: true},
+				UserID:          userId,
+				Phone:           verification.Phone,
+			},
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.UserEvent{}, ErrPhoneChanged
+			}
+			return model.UserEvent{}, err
+		}
+
+		verifiedUser = mapDbUserToModel(&dbUser)
+		return recordUserEvent(ctx, queries, model.EventUserUpdated, verifiedUser)
+	})
+
+	if err != nil {
+		return model.User{}, err
+	}
+	return verifiedUser, nil
+}
+
+func mapDbPhoneVerificationToModel(dbVerification *db.PhoneVerification) model.PhoneVerification {
+	return model.PhoneVerification{
+		UserId:    dbVerification.UserID,
+		Phone:     dbVerification.Phone,
+		CodeHash:  dbVerification.CodeHash,
+		Attempts:  int(dbVerification.Attempts),
+		ExpiresAt: dbVerification.ExpiresAt,
+		SentAt:    dbVerification.SentAt,
+	}
+}
@@ -0,0 +1,100 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"example.com/user-management/internal/attributes"
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+)
+
+var _ store.AttributeSchemaStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) GetAttributeSchema(ctx context.Context) (model.AttributeSchema, bool, error) {
+	dbSchema, err := userStore.queries.GetAttributeSchema(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.AttributeSchema{}, false, nil
+		}
+		return model.AttributeSchema{}, false, err
+	}
+
+	return mapDbAttributeSchemaToModel(&dbSchema), true, nil
+}
+
+func (userStore *UserStore) SetAttributeSchema(ctx context.Context, schema json.RawMessage) (model.AttributeSchema, error) {
+	if _, err := attributes.Parse(schema); err != nil {
+		return model.AttributeSchema{}, err
+	}
+
+	dbSchema, err := userStore.queries.SetAttributeSchema(ctx, string(schema))
+	if err != nil {
+		return model.AttributeSchema{}, err
+	}
+
+	return mapDbAttributeSchemaToModel(&dbSchema), nil
+}
+
+func (userStore *UserStore) DeleteAttributeSchema(ctx context.Context) (bool, error) {
+	rows, err := userStore.queries.DeleteAttributeSchema(ctx)
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
+
+// attributeSchema returns the compiled attribute schema, or nil if there is
+// none.
+func attributeSchema(ctx context.Context, queries *sqlitedb.Queries) (*attributes.Schema, error) {
+	dbSchema, err := queries.GetAttributeSchema(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attributes.Parse([]byte(dbSchema.Schema))
+}
+
+// validateAttributes checks the attributes of user against the schema and
+// returns them encoded for storage.
+func validateAttributes(ctx context.Context, queries *sqlitedb.Queries, user model.User) (string, error) {
+	schema, err := attributeSchema(ctx, queries)
+	if err != nil {
+		return "", err
+	}
+	if err := schema.Validate(user.Attributes); err != nil {
+		return "", err
+	}
+	encoded, err := attributes.Encode(user.Attributes)
+	return string(encoded), err
+}
+
+// encodeAttributeFilter returns the attribute values of filter as a JSON
+// object typed by the schema, or NULL if it names none.
+func encodeAttributeFilter(ctx context.Context, queries *sqlitedb.Queries, filter model.UserFilter) (sql.NullString, error) {
+	if len(filter.Attributes) == 0 {
+		return sql.NullString{}, nil
+	}
+
+	schema, err := attributeSchema(ctx, queries)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	encoded, err := json.Marshal(schema.Filter(filter.Attributes))
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(encoded), Valid: true}, nil
+}
+
+func mapDbAttributeSchemaToModel(dbSchema *sqlitedb.AttributeSchema) model.AttributeSchema {
+	return model.AttributeSchema{
+		Schema:    json.RawMessage(dbSchema.Schema),
+		UpdatedAt: dbSchema.UpdatedAt,
+	}
+}
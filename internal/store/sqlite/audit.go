@@ -0,0 +1,79 @@
+package sqlite
+
+import (
+	"context"
+	"encoding/json"
+
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.AuditStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) RecordAuditEvent(ctx context.Context, event model.AuditEvent) error {
+	return recordAuditEvent(ctx, userStore.queries, event)
+}
+
+func (userStore *UserStore) ListAuditEvents(ctx context.Context, userId uuid.UUID, after int64, limit int) ([]model.AuditEvent, error) {
+	dbEvents, err := userStore.queries.ListAuditEvents(ctx,
+		sqlitedb.ListAuditEventsParams{
+			UserID:  uuid.NullUUID{UUID: userId, Valid: true},
+			AuditID: after,
+			Limit:   int64(limit),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]model.AuditEvent, len(dbEvents))
+	for i, e := range dbEvents {
+		event, err := mapDbAuditEventToModel(&e)
+		if err != nil {
+			return nil, err
+		}
+		events[i] = event
+	}
+
+	return events, nil
+}
+
+func recordAuditEvent(ctx context.Context, queries *sqlitedb.Queries, event model.AuditEvent) error {
+	if event.Detail == nil {
+		event.Detail = map[string]string{}
+	}
+	detail, err := json.Marshal(event.Detail)
+	if err != nil {
+		return err
+	}
+
+	var userId uuid.NullUUID
+	if event.UserId != nil {
+		userId = uuid.NullUUID{UUID: *event.UserId, Valid: true}
+	}
+
+	return queries.CreateAuditEvent(ctx,
+		sqlitedb.CreateAuditEventParams{
+			UserID: userId,
+			Action: string(event.Action),
+			Detail: string(detail),
+		},
+	)
+}
+
+func mapDbAuditEventToModel(dbEvent *sqlitedb.AuditEvent) (model.AuditEvent, error) {
+	event := model.AuditEvent{
+		AuditId:   dbEvent.AuditID,
+		Action:    model.AuditAction(dbEvent.Action),
+		CreatedAt: dbEvent.CreatedAt,
+	}
+	if dbEvent.UserID.Valid {
+		event.UserId = &dbEvent.UserID.UUID
+	}
+	if err := json.Unmarshal([]byte(dbEvent.Detail), &event.Detail); err != nil {
+		return model.AuditEvent{}, err
+	}
+	return event, nil
+}
@@ -0,0 +1,216 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.AuthStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) GetPasswordHash(ctx context.Context, userId uuid.UUID) (string, bool, error) {
+	credential, err := userStore.queries.GetUserCredential(ctx, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return credential.PasswordHash, true, nil
+}
+
+func (userStore *UserStore) CreateSession(ctx context.Context, session model.Session) error {
+	return userStore.queries.CreateSession(ctx,
+		sqlitedb.CreateSessionParams{
+			SessionID:        session.SessionId,
+			UserID:           session.UserId,
+			RefreshTokenHash: session.RefreshTokenHash,
+			CreatedAt:        session.CreatedAt,
+			ExpiresAt:        session.ExpiresAt,
+			Mfa:              session.MFA,
+		},
+	)
+}
+
+func (userStore *UserStore) RefreshSession(ctx context.Context, refreshTokenHash, newRefreshTokenHash []byte, now time.Time) (model.Session, error) {
+	var session model.Session
+	err := userStore.inTx(ctx, func(queries *sqlitedb.Queries) error {
+		dbSession, err := queries.RotateSession(ctx,
+			sqlitedb.RotateSessionParams{
+				NewRefreshTokenHash: newRefreshTokenHash,
+				RefreshTokenHash:    refreshTokenHash,
+			},
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.ErrSessionNotFound
+			}
+			return err
+		}
+
+		session = mapDbSessionToModel(&dbSession)
+		return store.CheckSession(session, now)
+	})
+
+	if err != nil {
+		return model.Session{}, err
+	}
+	return session, nil
+}
+
+func (userStore *UserStore) GetSession(ctx context.Context, sessionId uuid.UUID) (model.Session, bool, error) {
+	dbSession, err := userStore.queries.GetSession(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Session{}, false, nil
+		}
+		return model.Session{}, false, err
+	}
+
+	return mapDbSessionToModel(&dbSession), true, nil
+}
+
+func (userStore *UserStore) RevokeSession(ctx context.Context, refreshTokenHash []byte, now time.Time) (model.Session, error) {
+	dbSession, err := userStore.queries.RevokeSession(ctx,
+		sqlitedb.RevokeSessionParams{
+			RevokedAt:        sql.NullTime{Time: now, Valid: true},
+			RefreshTokenHash: refreshTokenHash,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Session{}, store.ErrSessionNotFound
+		}
+		return model.Session{}, err
+	}
+
+	return mapDbSessionToModel(&dbSession), nil
+}
+
+func (userStore *UserStore) CreatePasswordReset(ctx context.Context, reset model.PasswordReset) error {
+	return userStore.queries.CreatePasswordReset(ctx,
+		sqlitedb.CreatePasswordResetParams{
+			TokenID:   reset.TokenId,
+			UserID:    reset.UserId,
+			ExpiresAt: reset.ExpiresAt,
+		},
+	)
+}
+
+func (userStore *UserStore) GetPasswordReset(ctx context.Context, tokenId uuid.UUID) (model.PasswordReset, bool, error) {
+	dbReset, err := userStore.queries.GetPasswordReset(ctx, tokenId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.PasswordReset{}, false, nil
+		}
+		return model.PasswordReset{}, false, err
+	}
+
+	return mapDbPasswordResetToModel(&dbReset), true, nil
+}
+
+func (userStore *UserStore) ResetPassword(ctx context.Context, tokenId uuid.UUID, passwordHash string, now time.Time) error {
+	return userStore.inTx(ctx, func(queries *sqlitedb.Queries) error {
+		dbReset, err := queries.GetPasswordReset(ctx, tokenId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.ErrResetNotFound
+			}
+			return err
+		}
+
+		reset := mapDbPasswordResetToModel(&dbReset)
+		if err := store.CheckPasswordReset(reset, now); err != nil {
+			return err
+		}
+
+		usedAt := sql.NullTime{Time: now, Valid: true}
+		// a concurrent reset may have used it since it was read
+		used, err := queries.UsePasswordReset(ctx,
+			sqlitedb.UsePasswordResetParams{
+				UsedAt:  usedAt,
+				TokenID: tokenId,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return store.ErrResetUsed
+		}
+
+		if _, err := queries.UseUserPasswordResets(ctx,
+			sqlitedb.UseUserPasswordResetsParams{
+				UsedAt: usedAt,
+				UserID: reset.UserId,
+			},
+		); err != nil {
+			return err
+		}
+
+		if err := queries.UpsertUserCredential(ctx,
+			sqlitedb.UpsertUserCredentialParams{
+				UserID:       reset.UserId,
+				PasswordHash: passwordHash,
+				UpdatedAt:    now,
+			},
+		); err != nil {
+			return err
+		}
+
+		revoked, err := queries.RevokeUserSessions(ctx,
+			sqlitedb.RevokeUserSessionsParams{
+				RevokedAt: usedAt,
+				UserID:    reset.UserId,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		if err := recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &reset.UserId,
+			Action: model.AuditPasswordReset,
+		}); err != nil {
+			return err
+		}
+		return recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &reset.UserId,
+			Action: model.AuditSessionsRevoked,
+			Detail: store.RevokedDetail(revoked),
+		})
+	})
+}
+
+func mapDbSessionToModel(dbSession *sqlitedb.Session) model.Session {
+	session := model.Session{
+		SessionId:        dbSession.SessionID,
+		UserId:           dbSession.UserID,
+		RefreshTokenHash: dbSession.RefreshTokenHash,
+		CreatedAt:        dbSession.CreatedAt,
+		ExpiresAt:        dbSession.ExpiresAt,
+		MFA:              dbSession.Mfa,
+	}
+	if dbSession.RevokedAt.Valid {
+		session.RevokedAt = &dbSession.RevokedAt.Time
+	}
+	return session
+}
+
+func mapDbPasswordResetToModel(dbReset *sqlitedb.PasswordReset) model.PasswordReset {
+	reset := model.PasswordReset{
+		TokenId:   dbReset.TokenID,
+		UserId:    dbReset.UserID,
+		ExpiresAt: dbReset.ExpiresAt,
+	}
+	if dbReset.UsedAt.Valid {
+		reset.UsedAt = &dbReset.UsedAt.Time
+	}
+	return reset
+}
@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var (
+	_ store.AvatarStoreInterface = (*UserStore)(nil)
+	_ store.BlobStoreInterface   = (*UserStore)(nil)
+)
+
+func (userStore *UserStore) SetUserAvatar(ctx context.Context, userId uuid.UUID, avatarUrl string) (model.User, bool, error) {
+	var updatedUser model.User
+	err := userStore.withTx(ctx, func(queries *sqlitedb.Queries) (model.UserEvent, error) {
+		dbUser, err := queries.SetUserAvatar(ctx,
+			sqlitedb.SetUserAvatarParams{
+				AvatarUrl: avatarUrl,
+				UserID:    userId,
+			},
+		)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		updatedUser = mapDbUserToModel(&dbUser)
+		return recordUserEvent(ctx, queries, model.EventUserUpdated, updatedUser)
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, false, nil
+		}
+		return model.User{}, false, err
+	}
+	return updatedUser, true, nil
+}
+
+func (userStore *UserStore) PutBlob(ctx context.Context, blob model.Blob) error {
+	return userStore.queries.PutBlob(ctx,
+		sqlitedb.PutBlobParams{
+			Key:         blob.Key,
+			ContentType: blob.ContentType,
+			Data:        blob.Data,
+		},
+	)
+}
+
+func (userStore *UserStore) GetBlob(ctx context.Context, key string) (model.Blob, bool, error) {
+	dbBlob, err := userStore.queries.GetBlob(ctx, key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Blob{}, false, nil
+		}
+		return model.Blob{}, false, err
+	}
+
+	return model.Blob{
+		Key:         dbBlob.Key,
+		ContentType: dbBlob.ContentType,
+		Data:        dbBlob.Data,
+		UpdatedAt:   dbBlob.UpdatedAt,
+	}, true, nil
+}
+
+func (userStore *UserStore) DeleteBlobs(ctx context.Context, prefix string) (bool, error) {
+	rows, err := userStore.queries.DeleteBlobs(ctx, prefix)
+	if err != nil {
+		return false, err
+	}
+	return rows > 0, nil
+}
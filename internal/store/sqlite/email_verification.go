@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.EmailVerificationStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) CreateEmailVerification(ctx context.Context, verification model.EmailVerification) error {
+	return userStore.queries.CreateEmailVerification(ctx,
+		sqlitedb.CreateEmailVerificationParams{
+			TokenID:   verification.TokenId,
+			UserID:    verification.UserId,
+			Email:     verification.Email,
+			ExpiresAt: verification.ExpiresAt,
+		},
+	)
+}
+
+func (userStore *UserStore) ConfirmEmailVerification(ctx context.Context, tokenId uuid.UUID, now time.Time) (model.User, error) {
+	var verifiedUser model.User
+	err := userStore.withTx(ctx, func(queries *sqlitedb.Queries) (model.UserEvent, error) {
+		dbVerification, err := queries.GetEmailVerification(ctx, tokenId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.UserEvent{}, store.ErrVerificationNotFound
+			}
+			return model.UserEvent{}, err
+		}
+
+		verification := mapDbEmailVerificationToModel(&dbVerification)
+		if err := store.CheckEmailVerification(verification, now); err != nil {
+			return model.UserEvent{}, err
+		}
+
+		used, err := queries.UseEmailVerification(ctx,
+			sqlitedb.UseEmailVerificationParams{
+				UsedAt:  sql.NullTime{Time: now, Valid: true},
+				TokenID: tokenId,
+			},
+		)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+		if used == 0 {
+			return model.UserEvent{}, store.ErrVerificationUsed
+		}
+
+		dbUser, err := queries.VerifyUserEmail(ctx,
+			sqlitedb.VerifyUserEmailParams{
+				EmailVerifiedAt: sql.NullTime{Time: now, Valid: true},
+				UserID:          verification.UserId,
+				Email:           verification.Email,
+			},
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.UserEvent{}, store.ErrEmailChanged
+			}
+			return model.UserEvent{}, err
+		}
+
+		verifiedUser = mapDbUserToModel(&dbUser)
+		return recordUserEvent(ctx, queries, model.EventUserUpdated, verifiedUser)
+	})
+
+	if err != nil {
+		return model.User{}, err
+	}
+	return verifiedUser, nil
+}
+
+func mapDbEmailVerificationToModel(dbVerification *sqlitedb.EmailVerification) model.EmailVerification {
+	verification := model.EmailVerification{
+		TokenId:   dbVerification.TokenID,
+		UserId:    dbVerification.UserID,
+		Email:     dbVerification.Email,
+		ExpiresAt: dbVerification.ExpiresAt,
+	}
+	if dbVerification.UsedAt.Valid {
+		verification.UsedAt = &dbVerification.UsedAt.Time
+	}
+	return verification
+}
@@ -0,0 +1,166 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.GroupStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) CreateGroup(ctx context.Context, group model.Group) (model.Group, error) {
+	dbGroup, err := userStore.queries.CreateGroup(ctx,
+		sqlitedb.CreateGroupParams{
+			GroupID:     uuid.New(),
+			Name:        group.Name,
+			Description: group.Description,
+		},
+	)
+	if err != nil {
+		return model.Group{}, mapGroupUniqueViolation(err)
+	}
+
+	return mapDbGroupToModel(&dbGroup), nil
+}
+
+func (userStore *UserStore) GetAllGroups(ctx context.Context) ([]model.Group, error) {
+	dbGroups, err := userStore.queries.GetAllGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapDbGroupsToModel(dbGroups), nil
+}
+
+func (userStore *UserStore) GetGroupById(ctx context.Context, groupId uuid.UUID) (model.Group, bool, error) {
+	dbGroup, err := userStore.queries.GetGroupByID(ctx, groupId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Group{}, false, nil
+		}
+		return model.Group{}, false, err
+	}
+
+	return mapDbGroupToModel(&dbGroup), true, nil
+}
+
+func (userStore *UserStore) UpdateGroup(ctx context.Context, group model.Group, groupId uuid.UUID) (model.Group, bool, error) {
+	dbGroup, err := userStore.queries.UpdateGroup(ctx,
+		sqlitedb.UpdateGroupParams{
+			Name:        group.Name,
+			Description: group.Description,
+			GroupID:     groupId,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.Group{}, false, nil
+		}
+		return model.Group{}, false, mapGroupUniqueViolation(err)
+	}
+
+	return mapDbGroupToModel(&dbGroup), true, nil
+}
+
+func (userStore *UserStore) DeleteGroup(ctx context.Context, groupId uuid.UUID) (bool, error) {
+	deleted, err := userStore.queries.DeleteGroup(ctx, groupId)
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+func (userStore *UserStore) AddGroupMember(ctx context.Context, groupId, userId uuid.UUID) error {
+	return userStore.inTx(ctx, func(queries *sqlitedb.Queries) error {
+		if _, err := queries.GetGroupByID(ctx, groupId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.ErrGroupNotFound
+			}
+			return err
+		}
+		if _, err := queries.GetUserByID(ctx, userId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.ErrUserNotFound
+			}
+			return err
+		}
+
+		added, err := queries.AddGroupMember(ctx,
+			sqlitedb.AddGroupMemberParams{
+				GroupID: groupId,
+				UserID:  userId,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if added == 0 {
+			return store.ErrAlreadyMember
+		}
+		return nil
+	})
+}
+
+func (userStore *UserStore) RemoveGroupMember(ctx context.Context, groupId, userId uuid.UUID) (bool, error) {
+	removed, err := userStore.queries.RemoveGroupMember(ctx,
+		sqlitedb.RemoveGroupMemberParams{
+			GroupID: groupId,
+			UserID:  userId,
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return removed > 0, nil
+}
+
+func (userStore *UserStore) ListUserGroups(ctx context.Context, userId uuid.UUID) ([]model.Group, error) {
+	dbGroups, err := userStore.queries.ListUserGroups(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapDbGroupsToModel(dbGroups), nil
+}
+
+func (userStore *UserStore) ListGroupMembers(ctx context.Context, groupIds []uuid.UUID) (map[uuid.UUID][]model.User, error) {
+	rows, err := userStore.queries.ListGroupMembers(ctx, groupIds)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make(map[uuid.UUID][]model.User)
+	for _, row := range rows {
+		members[row.GroupID] = append(members[row.GroupID], mapDbUserToModel(&row.User))
+	}
+	return members, nil
+}
+
+func mapGroupUniqueViolation(err error) error {
+	if isUniqueViolation(err) {
+		return store.ErrDuplicateGroupName
+	}
+	return err
+}
+
+func mapDbGroupsToModel(dbGroups []sqlitedb.Group) []model.Group {
+	groups := make([]model.Group, len(dbGroups))
+	for i, g := range dbGroups {
+		groups[i] = mapDbGroupToModel(&g)
+	}
+	return groups
+}
+
+func mapDbGroupToModel(dbGroup *sqlitedb.Group) model.Group {
+	return model.Group{
+		GroupId:     dbGroup.GroupID,
+		Name:        dbGroup.Name,
+		Description: dbGroup.Description,
+		CreatedAt:   dbGroup.CreatedAt,
+	}
+}
@@ -0,0 +1,255 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.MFAStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) CreateTOTPFactor(ctx context.Context, factor model.TOTPFactor) error {
+	return userStore.inTx(ctx, func(queries *sqlitedb.Queries) error {
+		created, err := queries.CreateTOTPFactor(ctx,
+			sqlitedb.CreateTOTPFactorParams{
+				UserID:           factor.UserId,
+				SecretCiphertext: factor.SecretCiphertext,
+				CreatedAt:        factor.CreatedAt,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if created == 0 {
+			return store.ErrMFAEnabled
+		}
+
+		return recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &factor.UserId,
+			Action: model.AuditMFAEnrolmentStarted,
+		})
+	})
+}
+
+func (userStore *UserStore) GetTOTPFactor(ctx context.Context, userId uuid.UUID) (model.TOTPFactor, bool, error) {
+	dbFactor, err := userStore.queries.GetTOTPFactor(ctx, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.TOTPFactor{}, false, nil
+		}
+		return model.TOTPFactor{}, false, err
+	}
+
+	return mapDbTOTPFactorToModel(&dbFactor), true, nil
+}
+
+func (userStore *UserStore) ConfirmTOTPFactor(ctx context.Context, userId uuid.UUID, step int64, recoveryCodeHashes [][]byte, now time.Time) error {
+	return userStore.inTx(ctx, func(queries *sqlitedb.Queries) error {
+		confirmed, err := queries.ConfirmTOTPFactor(ctx,
+			sqlitedb.ConfirmTOTPFactorParams{
+				ConfirmedAt: sql.NullTime{Time: now, Valid: true},
+				Step:        step,
+				UserID:      userId,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if confirmed == 0 {
+			if _, err := queries.GetTOTPFactor(ctx, userId); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return store.ErrFactorNotFound
+				}
+				return err
+			}
+			return store.ErrMFAEnabled
+		}
+
+		if err := replaceRecoveryCodes(ctx, queries, userId, recoveryCodeHashes); err != nil {
+			return err
+		}
+		return recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &userId,
+			Action: model.AuditMFAEnabled,
+		})
+	})
+}
+
+func (userStore *UserStore) UseTOTPStep(ctx context.Context, userId uuid.UUID, step int64) error {
+	used, err := userStore.queries.UseTOTPStep(ctx,
+		sqlitedb.UseTOTPStepParams{
+			Step:   step,
+			UserID: userId,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if used == 0 {
+		return store.ErrCodeReused
+	}
+	return nil
+}
+
+func (userStore *UserStore) ReplaceRecoveryCodes(ctx context.Context, userId uuid.UUID, codeHashes [][]byte) error {
+	return userStore.inTx(ctx, func(queries *sqlitedb.Queries) error {
+		dbFactor, err := queries.GetTOTPFactor(ctx, userId)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.ErrMFANotEnabled
+			}
+			return err
+		}
+		if err := store.CheckTOTPFactorConfirmed(mapDbTOTPFactorToModel(&dbFactor)); err != nil {
+			return err
+		}
+
+		if err := replaceRecoveryCodes(ctx, queries, userId, codeHashes); err != nil {
+			return err
+		}
+		return recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &userId,
+			Action: model.AuditMFARecoveryCodesReplaced,
+		})
+	})
+}
+
+func (userStore *UserStore) UseRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash []byte, now time.Time) error {
+	return userStore.inTx(ctx, func(queries *sqlitedb.Queries) error {
+		used, err := queries.UseRecoveryCode(ctx,
+			sqlitedb.UseRecoveryCodeParams{
+				UsedAt:   sql.NullTime{Time: now, Valid: true},
+				UserID:   userId,
+				CodeHash: codeHash,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			return store.ErrWrongCode
+		}
+
+		return recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &userId,
+			Action: model.AuditMFARecoveryCodeUsed,
+		})
+	})
+}
+
+func (userStore *UserStore) ResetMFA(ctx context.Context, userId uuid.UUID) (bool, error) {
+	var reset bool
+	err := userStore.inTx(ctx, func(queries *sqlitedb.Queries) error {
+		deleted, err := queries.DeleteTOTPFactor(ctx, userId)
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return nil
+		}
+		reset = true
+
+		if err := queries.DeleteRecoveryCodes(ctx, userId); err != nil {
+			return err
+		}
+		return recordAuditEvent(ctx, queries, model.AuditEvent{
+			UserId: &userId,
+			Action: model.AuditMFAReset,
+		})
+	})
+
+	if err != nil {
+		return false, err
+	}
+	return reset, nil
+}
+
+func (userStore *UserStore) CreateMFAChallenge(ctx context.Context, challenge model.MFAChallenge) error {
+	return userStore.queries.CreateMFAChallenge(ctx,
+		sqlitedb.CreateMFAChallengeParams{
+			ChallengeID: challenge.ChallengeId,
+			UserID:      challenge.UserId,
+			ExpiresAt:   challenge.ExpiresAt,
+		},
+	)
+}
+
+func (userStore *UserStore) CountMFAChallengeAttempt(ctx context.Context, challengeId uuid.UUID, maxAttempts int, now time.Time) (model.MFAChallenge, error) {
+	dbChallenge, err := userStore.queries.CountMFAChallengeAttempt(ctx,
+		sqlitedb.CountMFAChallengeAttemptParams{
+			ChallengeID: challengeId,
+			MaxAttempts: int64(maxAttempts),
+		},
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := userStore.queries.GetMFAChallenge(ctx, challengeId); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.MFAChallenge{}, store.ErrChallengeNotFound
+			}
+			return model.MFAChallenge{}, err
+		}
+		return model.MFAChallenge{}, store.ErrTooManyAttempts
+	}
+	if err != nil {
+		return model.MFAChallenge{}, err
+	}
+
+	challenge := mapDbMFAChallengeToModel(&dbChallenge)
+	if err := store.CheckMFAChallenge(challenge, now); err != nil {
+		return model.MFAChallenge{}, err
+	}
+	return challenge, nil
+}
+
+func (userStore *UserStore) DeleteMFAChallenge(ctx context.Context, challengeId uuid.UUID) (bool, error) {
+	deleted, err := userStore.queries.DeleteMFAChallenge(ctx, challengeId)
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, queries *sqlitedb.Queries, userId uuid.UUID, codeHashes [][]byte) error {
+	if err := queries.DeleteRecoveryCodes(ctx, userId); err != nil {
+		return err
+	}
+	for _, codeHash := range codeHashes {
+		if err := queries.CreateRecoveryCode(ctx,
+			sqlitedb.CreateRecoveryCodeParams{
+				UserID:   userId,
+				CodeHash: codeHash,
+			},
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func mapDbTOTPFactorToModel(dbFactor *sqlitedb.TotpFactor) model.TOTPFactor {
+	factor := model.TOTPFactor{
+		UserId:           dbFactor.UserID,
+		SecretCiphertext: dbFactor.SecretCiphertext,
+		LastUsedStep:     dbFactor.LastUsedStep,
+		CreatedAt:        dbFactor.CreatedAt,
+	}
+	if dbFactor.ConfirmedAt.Valid {
+		factor.ConfirmedAt = &dbFactor.ConfirmedAt.Time
+	}
+	return factor
+}
+
+func mapDbMFAChallengeToModel(dbChallenge *sqlitedb.MfaChallenge) model.MFAChallenge {
+	return model.MFAChallenge{
+		ChallengeId: dbChallenge.ChallengeID,
+		UserId:      dbChallenge.UserID,
+		Attempts:    int(dbChallenge.Attempts),
+		ExpiresAt:   dbChallenge.ExpiresAt,
+	}
+}
@@ -0,0 +1,251 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.OIDCStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) CreateOAuthClient(ctx context.Context, client model.OAuthClient) (model.OAuthClient, error) {
+	redirectURIs, err := json.Marshal(client.RedirectURIs)
+	if err != nil {
+		return model.OAuthClient{}, err
+	}
+
+	dbClient, err := userStore.queries.CreateOAuthClient(ctx,
+		sqlitedb.CreateOAuthClientParams{
+			ClientID:     uuid.New(),
+			Name:         client.Name,
+			SecretHash:   client.SecretHash,
+			RedirectUris: string(redirectURIs),
+		},
+	)
+	if err != nil {
+		return model.OAuthClient{}, err
+	}
+
+	return mapDbOAuthClientToModel(&dbClient)
+}
+
+func (userStore *UserStore) GetOAuthClient(ctx context.Context, clientId uuid.UUID) (model.OAuthClient, bool, error) {
+	dbClient, err := userStore.queries.GetOAuthClient(ctx, clientId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.OAuthClient{}, false, nil
+		}
+		return model.OAuthClient{}, false, err
+	}
+
+	client, err := mapDbOAuthClientToModel(&dbClient)
+	if err != nil {
+		return model.OAuthClient{}, false, err
+	}
+	return client, true, nil
+}
+
+func (userStore *UserStore) GetAllOAuthClients(ctx context.Context) ([]model.OAuthClient, error) {
+	dbClients, err := userStore.queries.GetAllOAuthClients(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clients := make([]model.OAuthClient, 0, len(dbClients))
+	for i := range dbClients {
+		client, err := mapDbOAuthClientToModel(&dbClients[i])
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	return clients, nil
+}
+
+func (userStore *UserStore) DeleteOAuthClient(ctx context.Context, clientId uuid.UUID) (bool, error) {
+	deleted, err := userStore.queries.DeleteOAuthClient(ctx, clientId)
+	if err != nil {
+		return false, err
+	}
+	return deleted > 0, nil
+}
+
+func (userStore *UserStore) GetOAuthConsent(ctx context.Context, userId, clientId uuid.UUID) (model.OAuthConsent, bool, error) {
+	dbConsent, err := userStore.queries.GetOAuthConsent(ctx,
+		sqlitedb.GetOAuthConsentParams{
+			UserID:   userId,
+			ClientID: clientId,
+		},
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.OAuthConsent{}, false, nil
+		}
+		return model.OAuthConsent{}, false, err
+	}
+
+	return model.OAuthConsent{
+		UserId:    dbConsent.UserID,
+		ClientId:  dbConsent.ClientID,
+		Scope:     dbConsent.Scope,
+		GrantedAt: dbConsent.GrantedAt,
+	}, true, nil
+}
+
+func (userStore *UserStore) SaveOAuthConsent(ctx context.Context, consent model.OAuthConsent) error {
+	return userStore.queries.SaveOAuthConsent(ctx,
+		sqlitedb.SaveOAuthConsentParams{
+			UserID:    consent.UserId,
+			ClientID:  consent.ClientId,
+			Scope:     consent.Scope,
+			GrantedAt: consent.GrantedAt,
+		},
+	)
+}
+
+func (userStore *UserStore) CreateAuthorizationCode(ctx context.Context, code model.AuthorizationCode) error {
+	return userStore.queries.CreateAuthorizationCode(ctx,
+		sqlitedb.CreateAuthorizationCodeParams{
+			CodeID:        code.CodeId,
+			ClientID:      code.ClientId,
+			UserID:        code.UserId,
+			RedirectUri:   code.RedirectURI,
+			Scope:         code.Scope,
+			Nonce:         code.Nonce,
+			CodeChallenge: code.CodeChallenge,
+			ExpiresAt:     code.ExpiresAt,
+		},
+	)
+}
+
+func (userStore *UserStore) ConsumeAuthorizationCode(ctx context.Context, codeId uuid.UUID) (model.AuthorizationCode, bool, error) {
+	dbCode, err := userStore.queries.ConsumeAuthorizationCode(ctx, codeId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.AuthorizationCode{}, false, nil
+		}
+		return model.AuthorizationCode{}, false, err
+	}
+
+	return model.AuthorizationCode{
+		CodeId:        dbCode.CodeID,
+		ClientId:      dbCode.ClientID,
+		UserId:        dbCode.UserID,
+		RedirectURI:   dbCode.RedirectUri,
+		Scope:         dbCode.Scope,
+		Nonce:         dbCode.Nonce,
+		CodeChallenge: dbCode.CodeChallenge,
+		ExpiresAt:     dbCode.ExpiresAt,
+	}, true, nil
+}
+
+func (userStore *UserStore) CreateOAuthRefreshToken(ctx context.Context, token model.OAuthRefreshToken) error {
+	return userStore.queries.CreateOAuthRefreshToken(ctx,
+		sqlitedb.CreateOAuthRefreshTokenParams{
+			TokenID:   token.TokenId,
+			ClientID:  token.ClientId,
+			UserID:    token.UserId,
+			Scope:     token.Scope,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+		},
+	)
+}
+
+func (userStore *UserStore) RotateOAuthRefreshToken(ctx context.Context, tokenId, newTokenId, clientId uuid.UUID, now time.Time) (model.OAuthRefreshToken, error) {
+	var token model.OAuthRefreshToken
+	err := userStore.inTx(ctx, func(queries *sqlitedb.Queries) error {
+		dbToken, err := queries.RotateOAuthRefreshToken(ctx,
+			sqlitedb.RotateOAuthRefreshTokenParams{
+				NewTokenID: newTokenId,
+				TokenID:    tokenId,
+				ClientID:   clientId,
+			},
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return store.ErrRefreshTokenNotFound
+			}
+			return err
+		}
+
+		token = model.OAuthRefreshToken{
+			TokenId:   dbToken.TokenID,
+			ClientId:  dbToken.ClientID,
+			UserId:    dbToken.UserID,
+			Scope:     dbToken.Scope,
+			CreatedAt: dbToken.CreatedAt,
+			ExpiresAt: dbToken.ExpiresAt,
+		}
+		return store.CheckOAuthRefreshToken(token, now)
+	})
+
+	if err != nil {
+		return model.OAuthRefreshToken{}, err
+	}
+	return token, nil
+}
+
+func (userStore *UserStore) RevokeOAuthRefreshToken(ctx context.Context, tokenId, clientId uuid.UUID) (bool, error) {
+	revoked, err := userStore.queries.RevokeOAuthRefreshToken(ctx,
+		sqlitedb.RevokeOAuthRefreshTokenParams{
+			TokenID:  tokenId,
+			ClientID: clientId,
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return revoked > 0, nil
+}
+
+func (userStore *UserStore) CreateSigningKey(ctx context.Context, key model.SigningKey) error {
+	return userStore.queries.CreateSigningKey(ctx,
+		sqlitedb.CreateSigningKeyParams{
+			KeyID:                key.KeyId,
+			PrivateKeyCiphertext: key.PrivateKeyCiphertext,
+			CreatedAt:            key.CreatedAt,
+		},
+	)
+}
+
+func (userStore *UserStore) GetSigningKeys(ctx context.Context) ([]model.SigningKey, error) {
+	dbKeys, err := userStore.queries.GetSigningKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]model.SigningKey, len(dbKeys))
+	for i, dbKey := range dbKeys {
+		keys[i] = model.SigningKey{
+			KeyId:                dbKey.KeyID,
+			PrivateKeyCiphertext: dbKey.PrivateKeyCiphertext,
+			CreatedAt:            dbKey.CreatedAt,
+		}
+	}
+	return keys, nil
+}
+
+func (userStore *UserStore) DeleteSigningKeys(ctx context.Context, before time.Time) error {
+	return userStore.queries.DeleteSigningKeys(ctx, before)
+}
+
+func mapDbOAuthClientToModel(dbClient *sqlitedb.OauthClient) (model.OAuthClient, error) {
+	client := model.OAuthClient{
+		ClientId:   dbClient.ClientID,
+		Name:       dbClient.Name,
+		SecretHash: dbClient.SecretHash,
+		CreatedAt:  dbClient.CreatedAt,
+	}
+	if err := json.Unmarshal([]byte(dbClient.RedirectUris), &client.RedirectURIs); err != nil {
+		return model.OAuthClient{}, err
+	}
+	return client, nil
+}
@@ -0,0 +1,58 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+)
+
+// NormalizePhones rewrites the phone numbers of all users in E.164 format,
+// for users stored before numbers were normalized, as the Postgres store's
+// NormalizePhones does. Each user is updated in a transaction of its own,
+// recording an update event, and numbers changed in the meantime are left to
+// their writer.
+func (userStore *UserStore) NormalizePhones(ctx context.Context) (store.PhoneBackfill, error) {
+	var backfill store.PhoneBackfill
+
+	users, err := userStore.GetAllUsers(ctx)
+	if err != nil {
+		return backfill, err
+	}
+
+	for _, user := range users {
+		normalized, err := store.NormalizePhone(user, userStore.phoneParser)
+		if err != nil {
+			backfill.Unparseable = append(backfill.Unparseable, store.UnparseablePhone{User: user, Err: err})
+			continue
+		}
+		if normalized.Phone == user.Phone {
+			continue
+		}
+
+		err = userStore.withTx(ctx, func(queries *sqlitedb.Queries) (model.UserEvent, error) {
+			dbUser, err := queries.SetUserPhone(ctx,
+				sqlitedb.SetUserPhoneParams{
+					NewPhone: normalized.Phone,
+					UserID:   user.UserId,
+					Phone:    user.Phone,
+				},
+			)
+			if err != nil {
+				return model.UserEvent{}, err
+			}
+			return recordUserEvent(ctx, queries, model.EventUserUpdated, mapDbUserToModel(&dbUser))
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return backfill, err
+		}
+		backfill.Normalized++
+	}
+	return backfill, nil
+}
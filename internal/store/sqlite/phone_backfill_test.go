@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"errors"
+	"testing"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+)
+
+func TestNormalizePhones(t *testing.T) {
+	dbConn := openTestDB(t)
+	userStore := NewUserStore(dbConn)
+
+	var users []model.User
+	for phone, email := range map[string]string{"+1 (234) 567-8901": "alice@example.com", "555 0100": "bob@example.com"} {
+		user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: email})
+		if err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+		// written as it is, the way numbers were stored before they were
+		// normalized
+		if _, err := dbConn.ExecContext(t.Context(), "UPDATE users SET phone = ? WHERE user_id = ?", phone, user.UserId); err != nil {
+			t.Fatalf("setting the phone failed: %v", err)
+		}
+		user.Phone = phone
+		users = append(users, user)
+	}
+
+	backfill, err := userStore.NormalizePhones(t.Context())
+	if err != nil {
+		t.Fatalf("NormalizePhones failed: %v", err)
+	}
+	if backfill.Normalized != 1 {
+		t.Errorf("Expected one number normalized, got %d", backfill.Normalized)
+	}
+	if len(backfill.Unparseable) != 1 || !errors.Is(backfill.Unparseable[0].Err, phonenumber.ErrInvalid) {
+		t.Fatalf("Expected the national number to be reported, got %+v", backfill.Unparseable)
+	}
+
+	for _, user := range users {
+		got, _, err := userStore.GetUserById(t.Context(), user.UserId)
+		if err != nil {
+			t.Fatalf("GetUserById failed: %v", err)
+		}
+		want := "+12345678901"
+		if user.Phone == "555 0100" {
+			want = user.Phone
+		}
+		if got.Phone != want {
+			t.Errorf("Expected %q, got %q", want, got.Phone)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+var _ store.PhoneVerificationStoreInterface = (*UserStore)(nil)
+
+func (userStore *UserStore) CreatePhoneVerification(ctx context.Context, verification model.PhoneVerification, sentBefore time.Time) error {
+	created, err := userStore.queries.CreatePhoneVerification(ctx,
+		sqlitedb.CreatePhoneVerificationParams{
+			UserID:     verification.UserId,
+			Phone:      verification.Phone,
+			CodeHash:   verification.CodeHash,
+			ExpiresAt:  verification.ExpiresAt,
+			SentAt:     verification.SentAt,
+			SentBefore: sentBefore,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if created == 0 {
+		return store.ErrResendTooSoon
+	}
+	return nil
+}
+
+func (userStore *UserStore) GetPhoneVerification(ctx context.Context, userId uuid.UUID) (model.PhoneVerification, bool, error) {
+	dbVerification, err := userStore.queries.GetPhoneVerification(ctx, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.PhoneVerification{}, false, nil
+		}
+		return model.PhoneVerification{}, false, err
+	}
+
+	return mapDbPhoneVerificationToModel(&dbVerification), true, nil
+}
+
+func (userStore *UserStore) ConfirmPhoneVerification(ctx context.Context, userId uuid.UUID, codeHash []byte, maxAttempts int, now time.Time) (model.User, error) {
+	// the attempt is counted on its own, so that it sticks even when the
+	// code is wrong
+	dbVerification, err := userStore.queries.CountPhoneVerificationAttempt(ctx,
+		sqlitedb.CountPhoneVerificationAttemptParams{
+			UserID:      userId,
+			MaxAttempts: int64(maxAttempts),
+		},
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, ok, err := userStore.GetPhoneVerification(ctx, userId); err != nil {
+			return model.User{}, err
+		} else if ok {
+			return model.User{}, store.ErrTooManyAttempts
+		}
+		return model.User{}, store.ErrVerificationNotFound
+	}
+	if err != nil {
+		return model.User{}, err
+	}
+
+	verification := mapDbPhoneVerificationToModel(&dbVerification)
+	if err := store.CheckPhoneVerification(verification, codeHash, now); err != nil {
+		return model.User{}, err
+	}
+
+	var verifiedUser model.User
+	err = userStore.withTx(ctx, func(queries *sqlitedb.Queries) (model.UserEvent, error) {
+		deleted, err := queries.DeletePhoneVerification(ctx,
+			sqlitedb.DeletePhoneVerificationParams{
+				UserID:   userId,
+				CodeHash: codeHash,
+			},
+		)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+		if deleted == 0 {
+			return model.UserEvent{}, store.ErrVerificationUsed
+		}
+
+		dbUser, err := queries.VerifyUserPhone(ctx,
+			sqlitedb.VerifyUserPhoneParams{
+				PhoneVerifiedAt: sql.NullTime{Time: now, Valid: true},
+				UserID:          userId,
+				Phone:           verification.Phone,
+			},
+		)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.UserEvent{}, store.ErrPhoneChanged
+			}
+			return model.UserEvent{}, err
+		}
+
+		verifiedUser = mapDbUserToModel(&dbUser)
+		return recordUserEvent(ctx, queries, model.EventUserUpdated, verifiedUser)
+	})
+
+	if err != nil {
+		return model.User{}, err
+	}
+	return verifiedUser, nil
+}
+
+func mapDbPhoneVerificationToModel(dbVerification *sqlitedb.PhoneVerification) model.PhoneVerification {
+	return model.PhoneVerification{
+		UserId:    dbVerification.UserID,
+		Phone:     dbVerification.Phone,
+		CodeHash:  dbVerification.CodeHash,
+		Attempts:  int(dbVerification.Attempts),
+		ExpiresAt: dbVerification.ExpiresAt,
+		SentAt:    dbVerification.SentAt,
+	}
+}
@@ -0,0 +1,472 @@
+// Package sqlite implements the user stores on SQLite for single-node
+// deployments. Behaviour matches the Postgres stores; the one difference is
+// that change events reach subscribers through OnEvent instead of
+// LISTEN/NOTIFY, so only the process that made a change sees it live.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"example.com/user-management/internal/attributes"
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/emailaddr"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+	sqlitedriver "modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type UserStore struct {
+	db          *sql.DB
+	queries     *sqlitedb.Queries
+	onEvent     func(model.UserEvent)
+	emailRules  emailaddr.Rules
+	phoneParser phonenumber.Parser
+}
+
+var _ store.UserStoreInterface = (*UserStore)(nil)
+
+func NewUserStore(dbConn *sql.DB) *UserStore {
+	return &UserStore{
+		db:      dbConn,
+		queries: sqlitedb.New(dbConn),
+	}
+}
+
+// OnEvent registers fn to be called with every change event once the
+// transaction recording it has committed. Call it before using the store.
+func (userStore *UserStore) OnEvent(fn func(model.UserEvent)) {
+	userStore.onEvent = fn
+}
+
+// SetEmailRules sets which addresses are one mailbox, and so one user. The
+// keys of addresses already stored are not changed. Call it before using the
+// store.
+func (userStore *UserStore) SetEmailRules(rules emailaddr.Rules) {
+	userStore.emailRules = rules
+}
+
+// SetPhoneParser sets how phone numbers, such as those in national format,
+// are read. Call it before using the store.
+func (userStore *UserStore) SetPhoneParser(parser phonenumber.Parser) {
+	userStore.phoneParser = parser
+}
+
+func (userStore *UserStore) CreateUser(ctx context.Context, user model.User) (model.User, error) {
+
+	user, err := store.NormalizeEmail(user)
+	if err != nil {
+		return model.User{}, err
+	}
+	user, err = store.NormalizePhone(user, userStore.phoneParser)
+	if err != nil {
+		return model.User{}, err
+	}
+	if user.Status == "" {
+		user.Status = model.StatusActive
+	}
+
+	var createdUser model.User
+	err = userStore.withTx(ctx, func(queries *sqlitedb.Queries) (model.UserEvent, error) {
+		encodedAttributes, err := validateAttributes(ctx, queries, user)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		dbUser, err := queries.CreateUser(ctx,
+			sqlitedb.CreateUserParams{
+				UserID:    uuid.New(),
+				FirstName: user.FirstName,
+				LastName:  user.LastName,
+				Email:     user.Email,
+				Phone:     user.Phone,
+				Age: sql.NullInt64{
+					Int64: int64(user.Age),
+					Valid: user.Age > 0,
+				},
+				Status:     string(user.Status),
+				Attributes: encodedAttributes,
+				EmailKey:   userStore.emailRules.Key(user.Email),
+			},
+		)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		createdUser = mapDbUserToModel(&dbUser)
+		return recordUserEvent(ctx, queries, model.EventUserCreated, createdUser)
+	})
+
+	if err != nil {
+		return model.User{}, mapUniqueViolation(err)
+	}
+
+	return createdUser, nil
+}
+
+func (userStore *UserStore) GetAllUsers(ctx context.Context) ([]model.User, error) {
+
+	dbUsers, err := userStore.queries.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapDbUsersToModel(dbUsers), nil
+}
+
+func (userStore *UserStore) GetUserById(ctx context.Context, userId uuid.UUID) (model.User, bool, error) {
+	dbUser, err := userStore.queries.GetUserByID(ctx, userId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, false, nil
+		}
+		return model.User{}, false, err
+	}
+
+	return mapDbUserToModel(&dbUser), true, nil
+}
+
+func (userStore *UserStore) GetUsersByIds(ctx context.Context, userIds []uuid.UUID) ([]model.User, error) {
+	dbUsers, err := userStore.queries.GetUsersByIDs(ctx, userIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapDbUsersToModel(dbUsers), nil
+}
+
+// ListUsers returns up to limit users matching filter, ordered by id and
+// starting after the given id. Pass uuid.Nil to start from the beginning.
+func (userStore *UserStore) ListUsers(ctx context.Context, filter model.UserFilter, after uuid.UUID, limit int) ([]model.User, error) {
+	attributeFilter, err := encodeAttributeFilter(ctx, userStore.queries, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	dbUsers, err := userStore.queries.ListUsers(ctx,
+		sqlitedb.ListUsersParams{
+			Status:     sql.NullString{String: string(filter.Status), Valid: filter.Status != ""},
+			EmailKey:   sql.NullString{String: userStore.emailRules.Key(filter.Email), Valid: filter.Email != ""},
+			Name:       sql.NullString{String: filter.Name, Valid: filter.Name != ""},
+			GroupID:    uuid.NullUUID{UUID: filter.GroupId, Valid: filter.GroupId != uuid.Nil},
+			Attributes: attributeFilter,
+			After:      after,
+			RowLimit:   int64(limit),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return mapDbUsersToModel(dbUsers), nil
+}
+
+// CountUsersByStatus returns the number of users in each status. Statuses
+// without users are left out.
+func (userStore *UserStore) CountUsersByStatus(ctx context.Context) (map[model.Status]int, error) {
+	rows, err := userStore.queries.CountUsersByStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[model.Status]int, len(rows))
+	for _, row := range rows {
+		counts[model.Status(row.Status)] = int(row.UserCount)
+	}
+
+	return counts, nil
+}
+
+func (userStore *UserStore) UpdateUser(ctx context.Context, user model.User, userId uuid.UUID) (model.User, bool, error) {
+
+	user, err := store.NormalizeEmail(user)
+	if err != nil {
+		return model.User{}, false, err
+	}
+
+	var updatedUser model.User
+	err = userStore.withTx(ctx, func(queries *sqlitedb.Queries) (model.UserEvent, error) {
+		current, err := queries.GetUserByID(ctx, userId)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+		user, err := store.NormalizeChangedPhone(user, mapDbUserToModel(&current), userStore.phoneParser)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		var event model.UserEvent
+		updatedUser, event, err = userStore.updateUser(ctx, queries, user, userId)
+		return event, err
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, false, nil
+		}
+		return model.User{}, false, mapUniqueViolation(err)
+	}
+
+	return updatedUser, true, nil
+}
+
+func (userStore *UserStore) PutUser(ctx context.Context, user model.User, precondition model.Precondition) (model.User, bool, error) {
+
+	user, err := store.NormalizeEmail(user)
+	if err != nil {
+		return model.User{}, false, err
+	}
+	if user.Status == "" {
+		user.Status = model.StatusActive
+	}
+
+	var putUser model.User
+	var created bool
+	err = userStore.withTx(ctx, func(queries *sqlitedb.Queries) (model.UserEvent, error) {
+		current, err := queries.GetUserByID(ctx, user.UserId)
+		exists := err == nil
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return model.UserEvent{}, err
+		}
+
+		if !exists {
+			if !precondition.Holds(model.User{}, false) {
+				return model.UserEvent{}, store.ErrPreconditionFailed
+			}
+			newUser, err := store.NormalizePhone(user, userStore.phoneParser)
+			if err != nil {
+				return model.UserEvent{}, err
+			}
+			encodedAttributes, err := validateAttributes(ctx, queries, newUser)
+			if err != nil {
+				return model.UserEvent{}, err
+			}
+
+			dbUser, err := queries.CreateUserWithID(
+				ctx,
+				sqlitedb.CreateUserWithIDParams{
+					UserID:    newUser.UserId,
+					FirstName: newUser.FirstName,
+					LastName:  newUser.LastName,
+					Email:     newUser.Email,
+					Phone:     newUser.Phone,
+					Age: sql.NullInt64{
+						Int64: int64(newUser.Age),
+						Valid: newUser.Age > 0,
+					},
+					Status:     string(newUser.Status),
+					Attributes: encodedAttributes,
+					EmailKey:   userStore.emailRules.Key(newUser.Email),
+				},
+			)
+			if err == nil {
+				putUser = mapDbUserToModel(&dbUser)
+				created = true
+				return recordUserEvent(ctx, queries, model.EventUserCreated, putUser)
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return model.UserEvent{}, err
+			}
+
+			// a concurrent put created the user since it was looked up
+			current, err = queries.GetUserByID(ctx, user.UserId)
+			if err != nil {
+				return model.UserEvent{}, err
+			}
+		}
+
+		currentUser := mapDbUserToModel(&current)
+		if !precondition.Holds(currentUser, true) {
+			return model.UserEvent{}, store.ErrPreconditionFailed
+		}
+		user, err := store.NormalizeChangedPhone(user, currentUser, userStore.phoneParser)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		var event model.UserEvent
+		putUser, event, err = userStore.updateUser(ctx, queries, user, user.UserId)
+		return event, err
+	})
+
+	if err != nil {
+		return model.User{}, false, mapUniqueViolation(err)
+	}
+
+	return putUser, created, nil
+}
+
+func (userStore *UserStore) ModifyUser(ctx context.Context, userId uuid.UUID, precondition model.Precondition, modify func(user *model.User) error) (model.User, bool, error) {
+	var modifiedUser model.User
+	// the store's one connection keeps other writes out of the transaction
+	err := userStore.withTx(ctx, func(queries *sqlitedb.Queries) (model.UserEvent, error) {
+		current, err := queries.GetUserByID(ctx, userId)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+		currentUser := mapDbUserToModel(&current)
+		if !precondition.Holds(currentUser, true) {
+			return model.UserEvent{}, store.ErrPreconditionFailed
+		}
+
+		user := currentUser
+		if err := modify(&user); err != nil {
+			return model.UserEvent{}, err
+		}
+		user, err = store.NormalizeEmail(user)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+		user, err = store.NormalizeChangedPhone(user, currentUser, userStore.phoneParser)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		var event model.UserEvent
+		modifiedUser, event, err = userStore.updateUser(ctx, queries, user, userId)
+		return event, err
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, false, nil
+		}
+		return model.User{}, false, mapUniqueViolation(err)
+	}
+
+	return modifiedUser, true, nil
+}
+
+func (userStore *UserStore) DeleteUser(ctx context.Context, userId uuid.UUID) (bool, error) {
+	err := userStore.withTx(ctx, func(queries *sqlitedb.Queries) (model.UserEvent, error) {
+		dbUser, err := queries.DeleteUser(ctx, userId)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		return recordUserEvent(ctx, queries, model.EventUserDeleted, mapDbUserToModel(&dbUser))
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// withTx runs fn inside a transaction so that a user change and the event
+// describing it are committed together, then hands the event to OnEvent.
+func (userStore *UserStore) withTx(ctx context.Context, fn func(queries *sqlitedb.Queries) (model.UserEvent, error)) error {
+	var event model.UserEvent
+	err := userStore.inTx(ctx, func(queries *sqlitedb.Queries) error {
+		var err error
+		event, err = fn(queries)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if userStore.onEvent != nil {
+		userStore.onEvent(event)
+	}
+	return nil
+}
+
+// inTx runs fn inside a transaction, for changes that do not produce a user
+// event.
+func (userStore *UserStore) inTx(ctx context.Context, fn func(queries *sqlitedb.Queries) error) error {
+	tx, err := userStore.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(userStore.queries.WithTx(tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// updateUser writes the fields of user over those of the user with userId
+// and records the update, in the caller's transaction.
+func (userStore *UserStore) updateUser(ctx context.Context, queries *sqlitedb.Queries, user model.User, userId uuid.UUID) (model.User, model.UserEvent, error) {
+	encodedAttributes, err := validateAttributes(ctx, queries, user)
+	if err != nil {
+		return model.User{}, model.UserEvent{}, err
+	}
+
+	dbUser, err := queries.UpdateUser(
+		ctx,
+		sqlitedb.UpdateUserParams{
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Email:     user.Email,
+			Phone:     user.Phone,
+			Age: sql.NullInt64{
+				Int64: int64(user.Age),
+				Valid: user.Age > 0,
+			},
+			Status:     string(user.Status),
+			Attributes: encodedAttributes,
+			EmailKey:   userStore.emailRules.Key(user.Email),
+			UserID:     userId,
+		},
+	)
+	if err != nil {
+		return model.User{}, model.UserEvent{}, err
+	}
+
+	updatedUser := mapDbUserToModel(&dbUser)
+	event, err := recordUserEvent(ctx, queries, model.EventUserUpdated, updatedUser)
+	return updatedUser, event, err
+}
+
+func mapUniqueViolation(err error) error {
+	if isUniqueViolation(err) {
+		return store.ErrDuplicateEmail
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlitedriver.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+func mapDbUsersToModel(dbUsers []sqlitedb.User) []model.User {
+	users := make([]model.User, len(dbUsers))
+	for i, u := range dbUsers {
+		users[i] = mapDbUserToModel(&u)
+	}
+	return users
+}
+
+func mapDbUserToModel(dbUser *sqlitedb.User) model.User {
+	user := model.User{
+		UserId:    dbUser.UserID,
+		FirstName: dbUser.FirstName,
+		LastName:  dbUser.LastName,
+		Email:     dbUser.Email,
+		Phone:     dbUser.Phone,
+		Age:       int(dbUser.Age.Int64),
+		Status:    model.Status(dbUser.Status),
+		AvatarUrl: dbUser.AvatarUrl,
+	}
+	if dbUser.EmailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &dbUser.EmailVerifiedAt.Time
+	}
+	if dbUser.PhoneVerifiedAt.Valid {
+		user.PhoneVerifiedAt = &dbUser.PhoneVerifiedAt.Time
+	}
+	// the column only ever holds objects written by attributes.Encode
+	user.Attributes, _ = attributes.Decode([]byte(dbUser.Attributes))
+	return store.DescribePhone(user)
+}
@@ -0,0 +1,83 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+)
+
+type UserEventStore struct {
+	queries *sqlitedb.Queries
+}
+
+var _ store.UserEventStoreInterface = (*UserEventStore)(nil)
+
+func NewUserEventStore(dbConn *sql.DB) *UserEventStore {
+	return &UserEventStore{
+		queries: sqlitedb.New(dbConn),
+	}
+}
+
+func (eventStore *UserEventStore) GetUserEventsAfter(ctx context.Context, eventId int64, limit int) ([]model.UserEvent, error) {
+	dbEvents, err := eventStore.queries.GetUserEventsAfter(ctx,
+		sqlitedb.GetUserEventsAfterParams{
+			EventID: eventId,
+			Limit:   int64(limit),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]model.UserEvent, len(dbEvents))
+	for i, e := range dbEvents {
+		event, err := mapDbUserEventToModel(&e)
+		if err != nil {
+			return nil, err
+		}
+		events[i] = event
+	}
+
+	return events, nil
+}
+
+// recordUserEvent appends an event to the durable log and returns it so that
+// it can be published after commit.
+func recordUserEvent(ctx context.Context, queries *sqlitedb.Queries, eventType model.EventType, user model.User) (model.UserEvent, error) {
+	payload, err := json.Marshal(user)
+	if err != nil {
+		return model.UserEvent{}, err
+	}
+
+	dbEvent, err := queries.CreateUserEvent(ctx,
+		sqlitedb.CreateUserEventParams{
+			EventType: string(eventType),
+			UserID:    user.UserId,
+			Payload:   string(payload),
+		},
+	)
+	if err != nil {
+		return model.UserEvent{}, err
+	}
+
+	return mapDbUserEventToModel(&dbEvent)
+}
+
+func mapDbUserEventToModel(dbEvent *sqlitedb.UserEvent) (model.UserEvent, error) {
+	var user model.User
+	if err := json.Unmarshal([]byte(dbEvent.Payload), &user); err != nil {
+		return model.UserEvent{}, err
+	}
+
+	return model.UserEvent{
+		EventId:   dbEvent.EventID,
+		Type:      model.EventType(dbEvent.EventType),
+		UserId:    dbEvent.UserID,
+		User:      user,
+		CreatedAt: dbEvent.CreatedAt,
+	}, nil
+}
@@ -0,0 +1,206 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	sqlitedb "example.com/user-management/internal/db/sqlite"
+	"example.com/user-management/internal/emailaddr"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/store/storetest"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dbConn, err := sqlitedb.Open(":memory:")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	t.Cleanup(func() { _ = dbConn.Close() })
+
+	if _, err := sqlitedb.MigrateUp(dbConn, emailaddr.Rules{}); err != nil {
+		t.Fatalf("MigrateUp failed: %v", err)
+	}
+	return dbConn
+}
+
+func TestUserStoreConformance(t *testing.T) {
+	storetest.RunUserStoreTests(t, func(t *testing.T) store.UserStoreInterface {
+		return NewUserStore(openTestDB(t))
+	})
+}
+
+func TestEmailProviderRules(t *testing.T) {
+	storetest.RunEmailProviderRulesTests(t, func(t *testing.T) store.UserStoreInterface {
+		userStore := NewUserStore(openTestDB(t))
+		userStore.SetEmailRules(emailaddr.Rules{Providers: true})
+		return userStore
+	})
+}
+
+func TestEmailVerificationStoreConformance(t *testing.T) {
+	storetest.RunEmailVerificationStoreTests(t, func(t *testing.T) storetest.EmailVerificationStore {
+		return NewUserStore(openTestDB(t))
+	})
+}
+
+func TestPhoneVerificationStoreConformance(t *testing.T) {
+	storetest.RunPhoneVerificationStoreTests(t, func(t *testing.T) storetest.PhoneVerificationStore {
+		return NewUserStore(openTestDB(t))
+	})
+}
+
+func TestAuthStoreConformance(t *testing.T) {
+	storetest.RunAuthStoreTests(t, func(t *testing.T) storetest.AuthStore {
+		return NewUserStore(openTestDB(t))
+	})
+}
+
+func TestMFAStoreConformance(t *testing.T) {
+	storetest.RunMFAStoreTests(t, func(t *testing.T) storetest.MFAStore {
+		return NewUserStore(openTestDB(t))
+	})
+}
+
+func TestGroupStoreConformance(t *testing.T) {
+	storetest.RunGroupStoreTests(t, func(t *testing.T) storetest.GroupStore {
+		return NewUserStore(openTestDB(t))
+	})
+}
+
+func TestAttributeSchemaStoreConformance(t *testing.T) {
+	storetest.RunAttributeSchemaStoreTests(t, func(t *testing.T) storetest.AttributeSchemaStore {
+		return NewUserStore(openTestDB(t))
+	})
+}
+
+func TestAvatarStoreConformance(t *testing.T) {
+	storetest.RunAvatarStoreTests(t, func(t *testing.T) storetest.AvatarStore {
+		return NewUserStore(openTestDB(t))
+	})
+}
+
+func TestBlobStoreConformance(t *testing.T) {
+	storetest.RunBlobStoreTests(t, func(t *testing.T) store.BlobStoreInterface {
+		return NewUserStore(openTestDB(t))
+	})
+}
+
+func TestOIDCStoreConformance(t *testing.T) {
+	storetest.RunOIDCStoreTests(t, func(t *testing.T) storetest.OIDCStore {
+		return NewUserStore(openTestDB(t))
+	})
+}
+
+func TestUserEvents_RecordedForEachChange(t *testing.T) {
+	dbConn := openTestDB(t)
+	userStore := NewUserStore(dbConn)
+	eventStore := NewUserEventStore(dbConn)
+
+	var published []model.UserEvent
+	userStore.OnEvent(func(event model.UserEvent) {
+		published = append(published, event)
+	})
+
+	user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if _, _, err := userStore.UpdateUser(t.Context(), user, user.UserId); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if _, err := userStore.DeleteUser(t.Context(), user.UserId); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+
+	got, err := eventStore.GetUserEventsAfter(t.Context(), 0, 10)
+	if err != nil {
+		t.Fatalf("GetUserEventsAfter failed: %v", err)
+	}
+
+	want := []model.EventType{model.EventUserCreated, model.EventUserUpdated, model.EventUserDeleted}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d events, got %d", len(want), len(got))
+	}
+	for i, event := range got {
+		if event.Type != want[i] || event.UserId != user.UserId || event.User.Email != user.Email {
+			t.Errorf("Unexpected event %+v", event)
+		}
+		if event.CreatedAt.IsZero() {
+			t.Errorf("Expected event %d to have a creation time", event.EventId)
+		}
+	}
+	if len(published) != len(want) {
+		t.Errorf("Expected %d published events, got %d", len(want), len(published))
+	}
+}
+
+func TestCountUsersByStatus(t *testing.T) {
+	userStore := NewUserStore(openTestDB(t))
+
+	for i, status := range []model.Status{model.StatusActive, model.StatusActive, model.StatusInactive} {
+		user := model.User{FirstName: "Alice", Email: fmt.Sprintf("alice.%d@example.com", i), Status: status}
+		if _, err := userStore.CreateUser(t.Context(), user); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+	}
+
+	counts, err := userStore.CountUsersByStatus(t.Context())
+	if err != nil {
+		t.Fatalf("CountUsersByStatus failed: %v", err)
+	}
+	if counts[model.StatusActive] != 2 || counts[model.StatusInactive] != 1 {
+		t.Errorf("Unexpected counts %v", counts)
+	}
+}
+
+func TestUnchangedLegacyPhone_IsKept(t *testing.T) {
+	dbConn := openTestDB(t)
+	userStore := NewUserStore(dbConn)
+
+	user, err := userStore.CreateUser(t.Context(), model.User{FirstName: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	// A number stored before numbers were normalized, which does not parse
+	// without a default region.
+	if _, err := dbConn.ExecContext(t.Context(), "UPDATE users SET phone = '555 0100' WHERE user_id = ?", user.UserId); err != nil {
+		t.Fatalf("setting the phone failed: %v", err)
+	}
+	user.Phone = "555 0100"
+
+	user.FirstName = "Alicia"
+	updated, _, err := userStore.UpdateUser(t.Context(), user, user.UserId)
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if updated.Phone != "555 0100" || updated.FirstName != "Alicia" {
+		t.Errorf("expected the new name and the stored phone, got %+v", updated)
+	}
+
+	user.LastName = "Smith"
+	if _, _, err := userStore.PutUser(t.Context(), user, model.Precondition{}); err != nil {
+		t.Fatalf("PutUser failed: %v", err)
+	}
+
+	modified, _, err := userStore.ModifyUser(t.Context(), user.UserId, model.Precondition{}, func(user *model.User) error {
+		user.Age = 30
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ModifyUser failed: %v", err)
+	}
+	if modified.Phone != "555 0100" || modified.Age != 30 {
+		t.Errorf("expected the age set and the stored phone, got %+v", modified)
+	}
+
+	user.Phone = "555 0101"
+	if _, _, err := userStore.UpdateUser(t.Context(), user, user.UserId); !errors.Is(err, phonenumber.ErrInvalid) {
+		t.Errorf("expected a changed phone to be checked, got %v", err)
+	}
+}
@@ -0,0 +1,198 @@
+package storetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"example.com/user-management/internal/attributes"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+// AttributeSchemaStore is a user store that also keeps an attribute schema.
+type AttributeSchemaStore interface {
+	store.UserStoreInterface
+	store.AttributeSchemaStoreInterface
+}
+
+const employeeSchema = `{
+	"type": "object",
+	"properties": {
+		"department": {"type": "string", "minLength": 2},
+		"employeeNumber": {"type": "integer"},
+		"contractor": {"type": "boolean"}
+	},
+	"required": ["department"]
+}`
+
+// RunAttributeSchemaStoreTests runs the attribute schema conformance suite
+// against the store returned by newStore. Every case removes the schema it
+// set, as the store may be shared with other tests.
+func RunAttributeSchemaStoreTests(t *testing.T, newStore func(t *testing.T) AttributeSchemaStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, schemaStore AttributeSchemaStore)
+	}{
+		{"SetAttributeSchema", testSetAttributeSchema},
+		{"SetAttributeSchema_Invalid", testSetAttributeSchemaInvalid},
+		{"DeleteAttributeSchema", testDeleteAttributeSchema},
+		{"CreateUser_ValidatesAttributes", testCreateUserValidatesAttributes},
+		{"UpdateUser_ValidatesAttributes", testUpdateUserValidatesAttributes},
+		{"ListUsers_TypedAttributes", testListUsersTypedAttributes},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+func setAttributeSchema(t *testing.T, schemaStore AttributeSchemaStore, schema string) model.AttributeSchema {
+	t.Helper()
+
+	set, err := schemaStore.SetAttributeSchema(t.Context(), json.RawMessage(schema))
+	if err != nil {
+		t.Fatalf("SetAttributeSchema failed: %v", err)
+	}
+	t.Cleanup(func() {
+		// the test's context is done by the time cleanups run
+		if _, err := schemaStore.DeleteAttributeSchema(context.Background()); err != nil {
+			t.Errorf("DeleteAttributeSchema failed: %v", err)
+		}
+	})
+	return set
+}
+
+func testSetAttributeSchema(t *testing.T, schemaStore AttributeSchemaStore) {
+	setAttributeSchema(t, schemaStore, employeeSchema)
+	replaced := setAttributeSchema(t, schemaStore, `{"type": "object"}`)
+	if replaced.UpdatedAt.IsZero() {
+		t.Errorf("Expected UpdatedAt to be set")
+	}
+
+	got, ok, err := schemaStore.GetAttributeSchema(t.Context())
+	if err != nil {
+		t.Fatalf("GetAttributeSchema failed: %v", err)
+	}
+	if !ok {
+		t.Fatalf("Expected a schema")
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(got.Schema, &schema); err != nil {
+		t.Fatalf("Failed to decode schema: %v", err)
+	}
+	if len(schema) != 1 || schema["type"] != "object" {
+		t.Errorf("Expected the replacing schema, got %s", got.Schema)
+	}
+}
+
+func testSetAttributeSchemaInvalid(t *testing.T, schemaStore AttributeSchemaStore) {
+	_, err := schemaStore.SetAttributeSchema(t.Context(), json.RawMessage(`{"type": "thing"}`))
+	if !errors.Is(err, attributes.ErrInvalidSchema) {
+		t.Errorf("Expected ErrInvalidSchema, got %v", err)
+	}
+
+	if _, ok, err := schemaStore.GetAttributeSchema(t.Context()); err != nil || ok {
+		t.Errorf("Expected no schema to be stored, got %v, %v", ok, err)
+	}
+}
+
+func testDeleteAttributeSchema(t *testing.T, schemaStore AttributeSchemaStore) {
+	setAttributeSchema(t, schemaStore, employeeSchema)
+
+	ok, err := schemaStore.DeleteAttributeSchema(t.Context())
+	if err != nil {
+		t.Fatalf("DeleteAttributeSchema failed: %v", err)
+	}
+	if !ok {
+		t.Errorf("Expected DeleteAttributeSchema to report a schema")
+	}
+
+	// any attributes go once the schema is gone
+	user := newUser()
+	user.Attributes = map[string]any{"anything": []any{"at", "all"}}
+	createUser(t, schemaStore, user)
+
+	if ok, err := schemaStore.DeleteAttributeSchema(t.Context()); err != nil || ok {
+		t.Errorf("Expected nothing left to delete, got %v, %v", ok, err)
+	}
+}
+
+func testCreateUserValidatesAttributes(t *testing.T, schemaStore AttributeSchemaStore) {
+	setAttributeSchema(t, schemaStore, employeeSchema)
+
+	user := newUser()
+	user.Attributes = map[string]any{"department": "eng", "employeeNumber": "forty-two"}
+	if _, err := schemaStore.CreateUser(t.Context(), user); !errors.Is(err, attributes.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+
+	user.Attributes = nil
+	if _, err := schemaStore.CreateUser(t.Context(), user); !errors.Is(err, attributes.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid for a missing required attribute, got %v", err)
+	}
+
+	// nothing was stored by the failed attempts
+	users, err := schemaStore.ListUsers(t.Context(), model.UserFilter{Email: user.Email}, uuid.Nil, 10)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(users) != 0 {
+		t.Errorf("Expected no users, got %d", len(users))
+	}
+
+	user.Attributes = map[string]any{"department": "eng", "employeeNumber": 42}
+	createUser(t, schemaStore, user)
+}
+
+func testUpdateUserValidatesAttributes(t *testing.T, schemaStore AttributeSchemaStore) {
+	setAttributeSchema(t, schemaStore, employeeSchema)
+	user := createUser(t, schemaStore, newUser())
+
+	user.Attributes = map[string]any{"department": "e"}
+	if _, _, err := schemaStore.UpdateUser(t.Context(), user, user.UserId); !errors.Is(err, attributes.ErrInvalid) {
+		t.Errorf("Expected ErrInvalid, got %v", err)
+	}
+
+	got, _, err := schemaStore.GetUserById(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("GetUserById failed: %v", err)
+	}
+	if got.Attributes["department"] != "eng" {
+		t.Errorf("Expected the attributes to be left alone, got %v", got.Attributes)
+	}
+}
+
+func testListUsersTypedAttributes(t *testing.T, schemaStore AttributeSchemaStore) {
+	setAttributeSchema(t, schemaStore, employeeSchema)
+	department := "dept-" + uuid.New().String()
+
+	user := newUser()
+	user.Attributes = map[string]any{"department": department, "employeeNumber": 7, "contractor": true}
+	user = createUser(t, schemaStore, user)
+
+	// query values are strings; the schema says how to read them
+	users, err := schemaStore.ListUsers(t.Context(), model.UserFilter{
+		Attributes: map[string]string{"department": department, "employeeNumber": "7", "contractor": "true"},
+	}, uuid.Nil, 10)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(users) != 1 || users[0].UserId != user.UserId {
+		t.Errorf("Expected the user, got %+v", users)
+	}
+
+	users, err = schemaStore.ListUsers(t.Context(), model.UserFilter{
+		Attributes: map[string]string{"department": department, "contractor": "false"},
+	}, uuid.Nil, 10)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(users) != 0 {
+		t.Errorf("Expected no users, got %d", len(users))
+	}
+}
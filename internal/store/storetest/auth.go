@@ -0,0 +1,325 @@
+package storetest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+// AuthStore is a user store that also keeps credentials, sessions and the
+// audit trail.
+type AuthStore interface {
+	store.UserStoreInterface
+	store.AuthStoreInterface
+	store.AuditStoreInterface
+}
+
+// RunAuthStoreTests runs the auth and audit conformance suite against the
+// store returned by newStore.
+func RunAuthStoreTests(t *testing.T, newStore func(t *testing.T) AuthStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, authStore AuthStore)
+	}{
+		{"RefreshSession", testRefreshSession},
+		{"RefreshSession_Expired", testRefreshSessionExpired},
+		{"RevokeSession", testRevokeSession},
+		{"GetSession", testGetSession},
+		{"ResetPassword", testResetPassword},
+		{"ResetPassword_Used", testResetPasswordUsed},
+		{"ResetPassword_Expired", testResetPasswordExpired},
+		{"ResetPassword_NotFound", testResetPasswordNotFound},
+		{"ListAuditEvents", testListAuditEvents},
+		{"DeleteUser_KeepsAuditEvents", testDeleteUserKeepsAuditEvents},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+// createSession creates a session for the user that expires in an hour.
+func createSession(t *testing.T, authStore AuthStore, userId uuid.UUID) model.Session {
+	t.Helper()
+
+	now := time.Now().UTC()
+	session := model.Session{
+		SessionId:        uuid.New(),
+		UserId:           userId,
+		RefreshTokenHash: []byte("hash of " + uuid.NewString()),
+		CreatedAt:        now,
+		ExpiresAt:        now.Add(time.Hour),
+	}
+	if err := authStore.CreateSession(t.Context(), session); err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	return session
+}
+
+// createPasswordReset creates a reset for the user that expires in an hour.
+func createPasswordReset(t *testing.T, authStore AuthStore, userId uuid.UUID) model.PasswordReset {
+	t.Helper()
+
+	reset := model.PasswordReset{
+		TokenId:   uuid.New(),
+		UserId:    userId,
+		ExpiresAt: time.Now().UTC().Add(time.Hour),
+	}
+	if err := authStore.CreatePasswordReset(t.Context(), reset); err != nil {
+		t.Fatalf("CreatePasswordReset failed: %v", err)
+	}
+	return reset
+}
+
+func listAuditActions(t *testing.T, auditStore store.AuditStoreInterface, userId uuid.UUID) []model.AuditAction {
+	t.Helper()
+
+	events, err := auditStore.ListAuditEvents(t.Context(), userId, 0, 100)
+	if err != nil {
+		t.Fatalf("ListAuditEvents failed: %v", err)
+	}
+	actions := make([]model.AuditAction, len(events))
+	for i, event := range events {
+		actions[i] = event.Action
+	}
+	return actions
+}
+
+func testRefreshSession(t *testing.T, authStore AuthStore) {
+	user := createUser(t, authStore, newUser())
+	session := createSession(t, authStore, user.UserId)
+
+	refreshed, err := authStore.RefreshSession(t.Context(), session.RefreshTokenHash, []byte("new hash"), time.Now().UTC())
+	if err != nil {
+		t.Fatalf("RefreshSession failed: %v", err)
+	}
+	if refreshed.SessionId != session.SessionId || refreshed.UserId != user.UserId {
+		t.Errorf("Expected session %v of user %v, got %+v", session.SessionId, user.UserId, refreshed)
+	}
+	if !bytes.Equal(refreshed.RefreshTokenHash, []byte("new hash")) {
+		t.Errorf("Expected the new refresh token hash, got %q", refreshed.RefreshTokenHash)
+	}
+
+	// the old refresh token is spent
+	_, err = authStore.RefreshSession(t.Context(), session.RefreshTokenHash, []byte("newer hash"), time.Now().UTC())
+	if !errors.Is(err, store.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for a spent refresh token, got %v", err)
+	}
+}
+
+func testRefreshSessionExpired(t *testing.T, authStore AuthStore) {
+	user := createUser(t, authStore, newUser())
+	session := createSession(t, authStore, user.UserId)
+
+	_, err := authStore.RefreshSession(t.Context(), session.RefreshTokenHash, []byte("new hash"), session.ExpiresAt.Add(time.Second))
+	if !errors.Is(err, store.ErrSessionExpired) {
+		t.Errorf("Expected ErrSessionExpired, got %v", err)
+	}
+}
+
+func testRevokeSession(t *testing.T, authStore AuthStore) {
+	user := createUser(t, authStore, newUser())
+	session := createSession(t, authStore, user.UserId)
+
+	revoked, err := authStore.RevokeSession(t.Context(), session.RefreshTokenHash, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("RevokeSession failed: %v", err)
+	}
+	if revoked.SessionId != session.SessionId || revoked.RevokedAt == nil {
+		t.Errorf("Expected session %v to be revoked, got %+v", session.SessionId, revoked)
+	}
+
+	if _, err := authStore.RefreshSession(t.Context(), session.RefreshTokenHash, []byte("new hash"), time.Now().UTC()); !errors.Is(err, store.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound for a revoked session, got %v", err)
+	}
+	if _, err := authStore.RevokeSession(t.Context(), session.RefreshTokenHash, time.Now().UTC()); !errors.Is(err, store.ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound when revoking twice, got %v", err)
+	}
+}
+
+func testGetSession(t *testing.T, authStore AuthStore) {
+	user := createUser(t, authStore, newUser())
+	session := createSession(t, authStore, user.UserId)
+
+	got, ok, err := authStore.GetSession(t.Context(), session.SessionId)
+	if err != nil {
+		t.Fatalf("GetSession failed: %v", err)
+	}
+	if !ok || got.SessionId != session.SessionId || got.UserId != user.UserId {
+		t.Errorf("Expected session %v of user %v, got %+v", session.SessionId, user.UserId, got)
+	}
+
+	if _, err := authStore.RevokeSession(t.Context(), session.RefreshTokenHash, time.Now().UTC()); err != nil {
+		t.Fatalf("RevokeSession failed: %v", err)
+	}
+	if _, ok, err := authStore.GetSession(t.Context(), session.SessionId); err != nil || ok {
+		t.Errorf("Expected a revoked session not to be found, got %v, %v", ok, err)
+	}
+	if _, ok, err := authStore.GetSession(t.Context(), uuid.New()); err != nil || ok {
+		t.Errorf("Expected an unknown session not to be found, got %v, %v", ok, err)
+	}
+}
+
+func testResetPassword(t *testing.T, authStore AuthStore) {
+	user := createUser(t, authStore, newUser())
+	other := createUser(t, authStore, newUser())
+	sessions := []model.Session{
+		createSession(t, authStore, user.UserId),
+		createSession(t, authStore, user.UserId),
+	}
+	otherSession := createSession(t, authStore, other.UserId)
+	reset := createPasswordReset(t, authStore, user.UserId)
+	olderReset := createPasswordReset(t, authStore, user.UserId)
+
+	if err := authStore.ResetPassword(t.Context(), reset.TokenId, "new password hash", time.Now().UTC()); err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+
+	passwordHash, ok, err := authStore.GetPasswordHash(t.Context(), user.UserId)
+	if err != nil || !ok {
+		t.Fatalf("GetPasswordHash failed: %v, %v", ok, err)
+	}
+	if passwordHash != "new password hash" {
+		t.Errorf("Expected the new password hash, got %q", passwordHash)
+	}
+
+	for _, session := range sessions {
+		if _, err := authStore.RefreshSession(t.Context(), session.RefreshTokenHash, []byte("new hash"), time.Now().UTC()); !errors.Is(err, store.ErrSessionNotFound) {
+			t.Errorf("Expected the user's sessions to be revoked, got %v", err)
+		}
+	}
+	if _, err := authStore.RefreshSession(t.Context(), otherSession.RefreshTokenHash, []byte("other new hash"), time.Now().UTC()); err != nil {
+		t.Errorf("Expected other users' sessions to be kept, got %v", err)
+	}
+
+	got, ok, err := authStore.GetPasswordReset(t.Context(), olderReset.TokenId)
+	if err != nil || !ok {
+		t.Fatalf("GetPasswordReset failed: %v, %v", ok, err)
+	}
+	if got.UsedAt == nil {
+		t.Errorf("Expected the user's other resets to be used up")
+	}
+
+	events, err := authStore.ListAuditEvents(t.Context(), user.UserId, 0, 100)
+	if err != nil {
+		t.Fatalf("ListAuditEvents failed: %v", err)
+	}
+	if len(events) != 2 || events[0].Action != model.AuditPasswordReset || events[1].Action != model.AuditSessionsRevoked {
+		t.Fatalf("Expected the reset and revocation to be audited, got %+v", events)
+	}
+	if events[1].Detail["count"] != "2" {
+		t.Errorf("Expected 2 revoked sessions, got %q", events[1].Detail["count"])
+	}
+}
+
+func testResetPasswordUsed(t *testing.T, authStore AuthStore) {
+	user := createUser(t, authStore, newUser())
+	reset := createPasswordReset(t, authStore, user.UserId)
+
+	if err := authStore.ResetPassword(t.Context(), reset.TokenId, "new password hash", time.Now().UTC()); err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+
+	err := authStore.ResetPassword(t.Context(), reset.TokenId, "newer password hash", time.Now().UTC())
+	if !errors.Is(err, store.ErrResetUsed) {
+		t.Errorf("Expected ErrResetUsed, got %v", err)
+	}
+
+	passwordHash, _, err := authStore.GetPasswordHash(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("GetPasswordHash failed: %v", err)
+	}
+	if passwordHash != "new password hash" {
+		t.Errorf("Expected the password to be kept, got %q", passwordHash)
+	}
+}
+
+func testResetPasswordExpired(t *testing.T, authStore AuthStore) {
+	user := createUser(t, authStore, newUser())
+	reset := createPasswordReset(t, authStore, user.UserId)
+
+	err := authStore.ResetPassword(t.Context(), reset.TokenId, "new password hash", reset.ExpiresAt.Add(time.Second))
+	if !errors.Is(err, store.ErrResetExpired) {
+		t.Errorf("Expected ErrResetExpired, got %v", err)
+	}
+
+	if _, ok, err := authStore.GetPasswordHash(t.Context(), user.UserId); err != nil || ok {
+		t.Errorf("Expected no password to be set, got %v, %v", ok, err)
+	}
+}
+
+func testResetPasswordNotFound(t *testing.T, authStore AuthStore) {
+	err := authStore.ResetPassword(t.Context(), uuid.New(), "new password hash", time.Now().UTC())
+	if !errors.Is(err, store.ErrResetNotFound) {
+		t.Errorf("Expected ErrResetNotFound, got %v", err)
+	}
+}
+
+func testListAuditEvents(t *testing.T, authStore AuthStore) {
+	user := createUser(t, authStore, newUser())
+	other := createUser(t, authStore, newUser())
+
+	record := func(event model.AuditEvent) {
+		t.Helper()
+		if err := authStore.RecordAuditEvent(t.Context(), event); err != nil {
+			t.Fatalf("RecordAuditEvent failed: %v", err)
+		}
+	}
+	record(model.AuditEvent{UserId: &user.UserId, Action: model.AuditLoginFailed, Detail: map[string]string{"reason": "wrong password"}})
+	record(model.AuditEvent{UserId: &other.UserId, Action: model.AuditLoginSucceeded})
+	record(model.AuditEvent{Action: model.AuditLoginFailed, Detail: map[string]string{"reason": "unknown email"}})
+	record(model.AuditEvent{UserId: &user.UserId, Action: model.AuditLoginSucceeded})
+	record(model.AuditEvent{UserId: &user.UserId, Action: model.AuditLogout})
+
+	events, err := authStore.ListAuditEvents(t.Context(), user.UserId, 0, 2)
+	if err != nil {
+		t.Fatalf("ListAuditEvents failed: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	first := events[0]
+	if first.Action != model.AuditLoginFailed || first.UserId == nil || *first.UserId != user.UserId {
+		t.Errorf("Unexpected first event %+v", first)
+	}
+	if first.Detail["reason"] != "wrong password" {
+		t.Errorf("Expected the detail to round-trip, got %v", first.Detail)
+	}
+	if first.CreatedAt.IsZero() {
+		t.Errorf("Expected CreatedAt to be set")
+	}
+	if events[1].Action != model.AuditLoginSucceeded || events[1].Detail == nil {
+		t.Errorf("Unexpected second event %+v", events[1])
+	}
+
+	rest, err := authStore.ListAuditEvents(t.Context(), user.UserId, events[1].AuditId, 10)
+	if err != nil {
+		t.Fatalf("ListAuditEvents failed: %v", err)
+	}
+	if len(rest) != 1 || rest[0].Action != model.AuditLogout {
+		t.Errorf("Expected only the logout after the second event, got %+v", rest)
+	}
+}
+
+func testDeleteUserKeepsAuditEvents(t *testing.T, authStore AuthStore) {
+	user := createUser(t, authStore, newUser())
+	createSession(t, authStore, user.UserId)
+	if err := authStore.RecordAuditEvent(t.Context(), model.AuditEvent{UserId: &user.UserId, Action: model.AuditLoginSucceeded}); err != nil {
+		t.Fatalf("RecordAuditEvent failed: %v", err)
+	}
+
+	if _, err := authStore.DeleteUser(t.Context(), user.UserId); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+
+	if actions := listAuditActions(t, authStore, user.UserId); len(actions) != 1 || actions[0] != model.AuditLoginSucceeded {
+		t.Errorf("Expected the audit trail to outlive the user, got %v", actions)
+	}
+}
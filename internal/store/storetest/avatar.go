@@ -0,0 +1,212 @@
+package storetest
+
+import (
+	"bytes"
+	"testing"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+// AvatarStore is a user store that also records avatars.
+type AvatarStore interface {
+	store.UserStoreInterface
+	store.AvatarStoreInterface
+}
+
+// RunAvatarStoreTests runs the avatar conformance suite against the store
+// returned by newStore.
+func RunAvatarStoreTests(t *testing.T, newStore func(t *testing.T) AvatarStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, avatarStore AvatarStore)
+	}{
+		{"SetUserAvatar", testSetUserAvatar},
+		{"SetUserAvatar_NotFound", testSetUserAvatarNotFound},
+		{"CreateAndUpdateUser_KeepAvatar", testCreateAndUpdateUserKeepAvatar},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+func setUserAvatar(t *testing.T, avatarStore AvatarStore, userId uuid.UUID, avatarUrl string) model.User {
+	t.Helper()
+
+	user, ok, err := avatarStore.SetUserAvatar(t.Context(), userId, avatarUrl)
+	if err != nil {
+		t.Fatalf("SetUserAvatar failed: %v", err)
+	}
+	if !ok {
+		t.Fatalf("Expected SetUserAvatar to find the user")
+	}
+	return user
+}
+
+func testSetUserAvatar(t *testing.T, avatarStore AvatarStore) {
+	user := createUser(t, avatarStore, newUser())
+	if user.AvatarUrl != "" {
+		t.Errorf("Expected no avatar, got %q", user.AvatarUrl)
+	}
+
+	avatarUrl := "/users/" + user.UserId.String() + "/avatar?v=1"
+	if updated := setUserAvatar(t, avatarStore, user.UserId, avatarUrl); updated.AvatarUrl != avatarUrl {
+		t.Errorf("Expected %q, got %q", avatarUrl, updated.AvatarUrl)
+	}
+	got, _, err := avatarStore.GetUserById(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("GetUserById failed: %v", err)
+	}
+	if got.AvatarUrl != avatarUrl {
+		t.Errorf("Expected %q to be stored, got %q", avatarUrl, got.AvatarUrl)
+	}
+
+	if cleared := setUserAvatar(t, avatarStore, user.UserId, ""); cleared.AvatarUrl != "" {
+		t.Errorf("Expected the avatar to be cleared, got %q", cleared.AvatarUrl)
+	}
+}
+
+func testSetUserAvatarNotFound(t *testing.T, avatarStore AvatarStore) {
+	_, ok, err := avatarStore.SetUserAvatar(t.Context(), uuid.New(), "/avatar")
+	if err != nil {
+		t.Fatalf("SetUserAvatar failed: %v", err)
+	}
+	if ok {
+		t.Errorf("Expected no user to be found")
+	}
+}
+
+func testCreateAndUpdateUserKeepAvatar(t *testing.T, avatarStore AvatarStore) {
+	// only SetUserAvatar sets the avatar
+	user := newUser()
+	user.AvatarUrl = "/made-up"
+	user = createUser(t, avatarStore, user)
+	if user.AvatarUrl != "" {
+		t.Errorf("Expected CreateUser to ignore the avatar, got %q", user.AvatarUrl)
+	}
+
+	user = setUserAvatar(t, avatarStore, user.UserId, "/avatar?v=1")
+	user.FirstName = "Alicia"
+	user.AvatarUrl = ""
+	updated, _, err := avatarStore.UpdateUser(t.Context(), user, user.UserId)
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if updated.AvatarUrl != "/avatar?v=1" {
+		t.Errorf("Expected UpdateUser to keep the avatar, got %q", updated.AvatarUrl)
+	}
+}
+
+// RunBlobStoreTests runs the blob store conformance suite against the store
+// returned by newStore. Keys are unique to each case, as the store may be
+// shared with other tests.
+func RunBlobStoreTests(t *testing.T, newStore func(t *testing.T) store.BlobStoreInterface) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, blobStore store.BlobStoreInterface)
+	}{
+		{"PutBlob", testPutBlob},
+		{"PutBlob_Replaces", testPutBlobReplaces},
+		{"GetBlob_NotFound", testGetBlobNotFound},
+		{"DeleteBlobs", testDeleteBlobs},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+// pngHeader starts the data of test blobs, so that stores that sniff the
+// content type find the one they were given.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func putBlob(t *testing.T, blobStore store.BlobStoreInterface, key string, data []byte) {
+	t.Helper()
+
+	err := blobStore.PutBlob(t.Context(), model.Blob{
+		Key:         key,
+		ContentType: "image/png",
+		Data:        append(bytes.Clone(pngHeader), data...),
+	})
+	if err != nil {
+		t.Fatalf("PutBlob failed: %v", err)
+	}
+}
+
+func getBlob(t *testing.T, blobStore store.BlobStoreInterface, key string) (model.Blob, bool) {
+	t.Helper()
+
+	blob, ok, err := blobStore.GetBlob(t.Context(), key)
+	if err != nil {
+		t.Fatalf("GetBlob failed: %v", err)
+	}
+	return blob, ok
+}
+
+func testPutBlob(t *testing.T, blobStore store.BlobStoreInterface) {
+	key := "tests/" + uuid.New().String() + "/blob"
+	putBlob(t, blobStore, key, []byte("data"))
+
+	blob, ok := getBlob(t, blobStore, key)
+	if !ok {
+		t.Fatalf("Expected the blob to be found")
+	}
+	if blob.Key != key || blob.ContentType != "image/png" || !bytes.HasSuffix(blob.Data, []byte("data")) {
+		t.Errorf("Expected the blob that was put, got %+v", blob)
+	}
+	if blob.UpdatedAt.IsZero() {
+		t.Errorf("Expected UpdatedAt to be set")
+	}
+}
+
+func testPutBlobReplaces(t *testing.T, blobStore store.BlobStoreInterface) {
+	key := "tests/" + uuid.New().String() + "/blob"
+	putBlob(t, blobStore, key, []byte("old"))
+	putBlob(t, blobStore, key, []byte("new"))
+
+	blob, _ := getBlob(t, blobStore, key)
+	if !bytes.HasSuffix(blob.Data, []byte("new")) {
+		t.Errorf("Expected the replacing data, got %q", blob.Data)
+	}
+}
+
+func testGetBlobNotFound(t *testing.T, blobStore store.BlobStoreInterface) {
+	if _, ok := getBlob(t, blobStore, "tests/"+uuid.New().String()); ok {
+		t.Errorf("Expected no blob to be found")
+	}
+}
+
+func testDeleteBlobs(t *testing.T, blobStore store.BlobStoreInterface) {
+	prefix := "tests/" + uuid.New().String() + "/"
+	putBlob(t, blobStore, prefix+"a", []byte("a"))
+	putBlob(t, blobStore, prefix+"b/c", []byte("c"))
+	// shares the prefix's characters but not its directory
+	kept := prefix[:len(prefix)-1] + "-kept"
+	putBlob(t, blobStore, kept, []byte("kept"))
+
+	deleted, err := blobStore.DeleteBlobs(t.Context(), prefix)
+	if err != nil {
+		t.Fatalf("DeleteBlobs failed: %v", err)
+	}
+	if !deleted {
+		t.Errorf("Expected DeleteBlobs to report blobs")
+	}
+	for _, key := range []string{prefix + "a", prefix + "b/c"} {
+		if _, ok := getBlob(t, blobStore, key); ok {
+			t.Errorf("Expected %s to be deleted", key)
+		}
+	}
+	if _, ok := getBlob(t, blobStore, kept); !ok {
+		t.Errorf("Expected %s to be kept", kept)
+	}
+
+	if deleted, err := blobStore.DeleteBlobs(t.Context(), prefix); err != nil || deleted {
+		t.Errorf("Expected nothing left to delete, got %v, %v", deleted, err)
+	}
+}
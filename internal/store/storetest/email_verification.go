@@ -0,0 +1,151 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+// EmailVerificationStore is a user store that also keeps email verifications.
+type EmailVerificationStore interface {
+	store.UserStoreInterface
+	store.EmailVerificationStoreInterface
+}
+
+// RunEmailVerificationStoreTests runs the email verification conformance
+// suite against the store returned by newStore.
+func RunEmailVerificationStoreTests(t *testing.T, newStore func(t *testing.T) EmailVerificationStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, verificationStore EmailVerificationStore)
+	}{
+		{"ConfirmEmailVerification", testConfirmEmailVerification},
+		{"ConfirmEmailVerification_SingleUse", testConfirmEmailVerificationSingleUse},
+		{"ConfirmEmailVerification_Expired", testConfirmEmailVerificationExpired},
+		{"ConfirmEmailVerification_NotFound", testConfirmEmailVerificationNotFound},
+		{"ConfirmEmailVerification_EmailChanged", testConfirmEmailVerificationEmailChanged},
+		{"UpdateUser_NewEmailIsUnverified", testUpdateUserNewEmailIsUnverified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+// createEmailVerification creates a user and a verification of their email
+// that expires in an hour.
+func createEmailVerification(t *testing.T, verificationStore EmailVerificationStore) (model.User, model.EmailVerification) {
+	t.Helper()
+
+	user := createUser(t, verificationStore, newUser())
+	verification := model.EmailVerification{
+		TokenId:   uuid.New(),
+		UserId:    user.UserId,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	if err := verificationStore.CreateEmailVerification(t.Context(), verification); err != nil {
+		t.Fatalf("CreateEmailVerification failed: %v", err)
+	}
+	return user, verification
+}
+
+func testConfirmEmailVerification(t *testing.T, verificationStore EmailVerificationStore) {
+	user, verification := createEmailVerification(t, verificationStore)
+	if user.EmailVerifiedAt != nil {
+		t.Fatalf("Expected a new user to be unverified, got %v", user.EmailVerifiedAt)
+	}
+
+	now := time.Now()
+	verified, err := verificationStore.ConfirmEmailVerification(t.Context(), verification.TokenId, now)
+	if err != nil {
+		t.Fatalf("ConfirmEmailVerification failed: %v", err)
+	}
+	if verified.EmailVerifiedAt == nil || !verified.EmailVerifiedAt.Round(time.Millisecond).Equal(now.Round(time.Millisecond)) {
+		t.Errorf("Expected email verified at %v, got %v", now, verified.EmailVerifiedAt)
+	}
+
+	got, _, err := verificationStore.GetUserById(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("GetUserById failed: %v", err)
+	}
+	if got.EmailVerifiedAt == nil {
+		t.Errorf("Expected the verification to be stored")
+	}
+}
+
+func testConfirmEmailVerificationSingleUse(t *testing.T, verificationStore EmailVerificationStore) {
+	_, verification := createEmailVerification(t, verificationStore)
+
+	if _, err := verificationStore.ConfirmEmailVerification(t.Context(), verification.TokenId, time.Now()); err != nil {
+		t.Fatalf("ConfirmEmailVerification failed: %v", err)
+	}
+
+	_, err := verificationStore.ConfirmEmailVerification(t.Context(), verification.TokenId, time.Now())
+	if !errors.Is(err, store.ErrVerificationUsed) {
+		t.Errorf("Expected ErrVerificationUsed, got %v", err)
+	}
+}
+
+func testConfirmEmailVerificationExpired(t *testing.T, verificationStore EmailVerificationStore) {
+	_, verification := createEmailVerification(t, verificationStore)
+
+	_, err := verificationStore.ConfirmEmailVerification(t.Context(), verification.TokenId, verification.ExpiresAt.Add(time.Second))
+	if !errors.Is(err, store.ErrVerificationExpired) {
+		t.Errorf("Expected ErrVerificationExpired, got %v", err)
+	}
+}
+
+func testConfirmEmailVerificationNotFound(t *testing.T, verificationStore EmailVerificationStore) {
+	_, err := verificationStore.ConfirmEmailVerification(t.Context(), uuid.New(), time.Now())
+	if !errors.Is(err, store.ErrVerificationNotFound) {
+		t.Errorf("Expected ErrVerificationNotFound, got %v", err)
+	}
+}
+
+func testConfirmEmailVerificationEmailChanged(t *testing.T, verificationStore EmailVerificationStore) {
+	user, verification := createEmailVerification(t, verificationStore)
+
+	user.Email = newUser().Email
+	if _, _, err := verificationStore.UpdateUser(t.Context(), user, user.UserId); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+
+	_, err := verificationStore.ConfirmEmailVerification(t.Context(), verification.TokenId, time.Now())
+	if !errors.Is(err, store.ErrEmailChanged) {
+		t.Errorf("Expected ErrEmailChanged, got %v", err)
+	}
+}
+
+func testUpdateUserNewEmailIsUnverified(t *testing.T, verificationStore EmailVerificationStore) {
+	_, verification := createEmailVerification(t, verificationStore)
+
+	user, err := verificationStore.ConfirmEmailVerification(t.Context(), verification.TokenId, time.Now())
+	if err != nil {
+		t.Fatalf("ConfirmEmailVerification failed: %v", err)
+	}
+
+	user.FirstName = "Alicia"
+	updated, _, err := verificationStore.UpdateUser(t.Context(), user, user.UserId)
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if updated.EmailVerifiedAt == nil {
+		t.Errorf("Expected other changes to keep the email verified")
+	}
+
+	updated.Email = newUser().Email
+	updated, _, err = verificationStore.UpdateUser(t.Context(), updated, user.UserId)
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if updated.EmailVerifiedAt != nil {
+		t.Errorf("Expected a new email to be unverified, got %v", updated.EmailVerifiedAt)
+	}
+}
@@ -0,0 +1,342 @@
+package storetest
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+// GroupStore is a user store that also keeps groups.
+type GroupStore interface {
+	store.UserStoreInterface
+	store.GroupStoreInterface
+}
+
+// RunGroupStoreTests runs the group conformance suite against the store
+// returned by newStore. Group names carry a random suffix, as the store may
+// be shared with other tests.
+func RunGroupStoreTests(t *testing.T, newStore func(t *testing.T) GroupStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, groupStore GroupStore)
+	}{
+		{"CreateGroup", testCreateGroup},
+		{"CreateGroup_DuplicateName", testCreateGroupDuplicateName},
+		{"GetAllGroups", testGetAllGroups},
+		{"UpdateGroup", testUpdateGroup},
+		{"UpdateGroup_NotFound", testUpdateGroupNotFound},
+		{"UpdateGroup_DuplicateName", testUpdateGroupDuplicateName},
+		{"AddGroupMember", testAddGroupMember},
+		{"AddGroupMember_NotFound", testAddGroupMemberNotFound},
+		{"RemoveGroupMember", testRemoveGroupMember},
+		{"ListUsers_Group", testListUsersGroup},
+		{"ListGroupMembers", testListGroupMembers},
+		{"DeleteGroup", testDeleteGroup},
+		{"DeleteUser_RemovesMemberships", testDeleteUserRemovesMemberships},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+func newGroup(name string) model.Group {
+	return model.Group{
+		Name:        name + "-" + uuid.New().String(),
+		Description: "The " + name + " team",
+	}
+}
+
+func createGroup(t *testing.T, groupStore GroupStore, group model.Group) model.Group {
+	t.Helper()
+
+	created, err := groupStore.CreateGroup(t.Context(), group)
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	return created
+}
+
+func addGroupMember(t *testing.T, groupStore GroupStore, groupId, userId uuid.UUID) {
+	t.Helper()
+
+	if err := groupStore.AddGroupMember(t.Context(), groupId, userId); err != nil {
+		t.Fatalf("AddGroupMember failed: %v", err)
+	}
+}
+
+// groupNames returns the names of the groups in groups, keeping only those
+// in want so that groups made by other tests are ignored.
+func groupNames(groups []model.Group, want ...model.Group) []string {
+	keep := make(map[uuid.UUID]bool, len(want))
+	for _, g := range want {
+		keep[g.GroupId] = true
+	}
+	var names []string
+	for _, g := range groups {
+		if keep[g.GroupId] {
+			names = append(names, g.Name)
+		}
+	}
+	return names
+}
+
+func testCreateGroup(t *testing.T, groupStore GroupStore) {
+	group := newGroup("platform")
+	created := createGroup(t, groupStore, group)
+
+	if created.GroupId == uuid.Nil {
+		t.Error("Expected a generated group id")
+	}
+	if created.Name != group.Name || created.Description != group.Description {
+		t.Errorf("Expected %+v, got %+v", group, created)
+	}
+	if created.CreatedAt.IsZero() {
+		t.Error("Expected a creation time")
+	}
+
+	got, ok, err := groupStore.GetGroupById(t.Context(), created.GroupId)
+	if err != nil || !ok {
+		t.Fatalf("GetGroupById failed: ok=%v err=%v", ok, err)
+	}
+	if got.Name != created.Name || got.Description != created.Description {
+		t.Errorf("Expected %+v, got %+v", created, got)
+	}
+
+	if _, ok, err := groupStore.GetGroupById(t.Context(), uuid.New()); err != nil || ok {
+		t.Errorf("Expected a missing group not to be found, got ok=%v err=%v", ok, err)
+	}
+}
+
+func testCreateGroupDuplicateName(t *testing.T, groupStore GroupStore) {
+	group := createGroup(t, groupStore, newGroup("platform"))
+
+	_, err := groupStore.CreateGroup(t.Context(), model.Group{Name: group.Name})
+	if !errors.Is(err, store.ErrDuplicateGroupName) {
+		t.Errorf("Expected ErrDuplicateGroupName, got %v", err)
+	}
+}
+
+func testGetAllGroups(t *testing.T, groupStore GroupStore) {
+	suffix := uuid.New().String()
+	b := createGroup(t, groupStore, model.Group{Name: "b-" + suffix})
+	a := createGroup(t, groupStore, model.Group{Name: "a-" + suffix})
+
+	groups, err := groupStore.GetAllGroups(t.Context())
+	if err != nil {
+		t.Fatalf("GetAllGroups failed: %v", err)
+	}
+
+	names := groupNames(groups, a, b)
+	if len(names) != 2 || names[0] != a.Name || names[1] != b.Name {
+		t.Errorf("Expected [%s %s] in name order, got %v", a.Name, b.Name, names)
+	}
+}
+
+func testUpdateGroup(t *testing.T, groupStore GroupStore) {
+	group := createGroup(t, groupStore, newGroup("platform"))
+
+	changes := newGroup("infrastructure")
+	updated, ok, err := groupStore.UpdateGroup(t.Context(), changes, group.GroupId)
+	if err != nil || !ok {
+		t.Fatalf("UpdateGroup failed: ok=%v err=%v", ok, err)
+	}
+	if updated.GroupId != group.GroupId || updated.Name != changes.Name || updated.Description != changes.Description {
+		t.Errorf("Unexpected updated group %+v", updated)
+	}
+}
+
+func testUpdateGroupNotFound(t *testing.T, groupStore GroupStore) {
+	_, ok, err := groupStore.UpdateGroup(t.Context(), newGroup("platform"), uuid.New())
+	if err != nil || ok {
+		t.Errorf("Expected a missing group not to be updated, got ok=%v err=%v", ok, err)
+	}
+}
+
+func testUpdateGroupDuplicateName(t *testing.T, groupStore GroupStore) {
+	taken := createGroup(t, groupStore, newGroup("platform"))
+	group := createGroup(t, groupStore, newGroup("infrastructure"))
+
+	group.Name = taken.Name
+	_, _, err := groupStore.UpdateGroup(t.Context(), group, group.GroupId)
+	if !errors.Is(err, store.ErrDuplicateGroupName) {
+		t.Errorf("Expected ErrDuplicateGroupName, got %v", err)
+	}
+}
+
+func testAddGroupMember(t *testing.T, groupStore GroupStore) {
+	user := createUser(t, groupStore, newUser())
+	b := createGroup(t, groupStore, newGroup("b"))
+	a := createGroup(t, groupStore, newGroup("a"))
+	addGroupMember(t, groupStore, b.GroupId, user.UserId)
+	addGroupMember(t, groupStore, a.GroupId, user.UserId)
+
+	if err := groupStore.AddGroupMember(t.Context(), a.GroupId, user.UserId); !errors.Is(err, store.ErrAlreadyMember) {
+		t.Errorf("Expected ErrAlreadyMember, got %v", err)
+	}
+
+	groups, err := groupStore.ListUserGroups(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("ListUserGroups failed: %v", err)
+	}
+	if len(groups) != 2 || groups[0].GroupId != a.GroupId || groups[1].GroupId != b.GroupId {
+		t.Errorf("Expected groups [%s %s], got %+v", a.Name, b.Name, groups)
+	}
+}
+
+func testAddGroupMemberNotFound(t *testing.T, groupStore GroupStore) {
+	user := createUser(t, groupStore, newUser())
+	group := createGroup(t, groupStore, newGroup("platform"))
+
+	if err := groupStore.AddGroupMember(t.Context(), uuid.New(), user.UserId); !errors.Is(err, store.ErrGroupNotFound) {
+		t.Errorf("Expected ErrGroupNotFound, got %v", err)
+	}
+	if err := groupStore.AddGroupMember(t.Context(), group.GroupId, uuid.New()); !errors.Is(err, store.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func testRemoveGroupMember(t *testing.T, groupStore GroupStore) {
+	user := createUser(t, groupStore, newUser())
+	group := createGroup(t, groupStore, newGroup("platform"))
+	addGroupMember(t, groupStore, group.GroupId, user.UserId)
+
+	removed, err := groupStore.RemoveGroupMember(t.Context(), group.GroupId, user.UserId)
+	if err != nil || !removed {
+		t.Fatalf("RemoveGroupMember failed: removed=%v err=%v", removed, err)
+	}
+
+	removed, err = groupStore.RemoveGroupMember(t.Context(), group.GroupId, user.UserId)
+	if err != nil || removed {
+		t.Errorf("Expected a second removal to find nothing, got removed=%v err=%v", removed, err)
+	}
+
+	groups, err := groupStore.ListUserGroups(t.Context(), user.UserId)
+	if err != nil || len(groups) != 0 {
+		t.Errorf("Expected no groups, got %+v, %v", groups, err)
+	}
+}
+
+func testListUsersGroup(t *testing.T, groupStore GroupStore) {
+	group := createGroup(t, groupStore, newGroup("platform"))
+	other := createGroup(t, groupStore, newGroup("infrastructure"))
+
+	var members []model.User
+	for range 3 {
+		user := createUser(t, groupStore, newUser())
+		addGroupMember(t, groupStore, group.GroupId, user.UserId)
+		members = append(members, user)
+	}
+	outsider := createUser(t, groupStore, newUser())
+	addGroupMember(t, groupStore, other.GroupId, outsider.UserId)
+
+	filter := model.UserFilter{GroupId: group.GroupId}
+	page, err := groupStore.ListUsers(t.Context(), filter, uuid.Nil, 2)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(page) != 2 {
+		t.Fatalf("Expected 2 users, got %d", len(page))
+	}
+	rest, err := groupStore.ListUsers(t.Context(), filter, page[1].UserId, 2)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(rest) != 1 {
+		t.Fatalf("Expected 1 user, got %d", len(rest))
+	}
+
+	got := make(map[uuid.UUID]bool)
+	for _, u := range append(page, rest...) {
+		got[u.UserId] = true
+	}
+	for _, member := range members {
+		if !got[member.UserId] {
+			t.Errorf("Expected member %s to be listed", member.UserId)
+		}
+	}
+}
+
+func testListGroupMembers(t *testing.T, groupStore GroupStore) {
+	group := createGroup(t, groupStore, newGroup("platform"))
+	other := createGroup(t, groupStore, newGroup("infrastructure"))
+	empty := createGroup(t, groupStore, newGroup("design"))
+
+	var want []uuid.UUID
+	for range 2 {
+		user := createUser(t, groupStore, newUser())
+		addGroupMember(t, groupStore, group.GroupId, user.UserId)
+		want = append(want, user.UserId)
+	}
+	outsider := createUser(t, groupStore, newUser())
+	addGroupMember(t, groupStore, other.GroupId, outsider.UserId)
+
+	members, err := groupStore.ListGroupMembers(t.Context(), []uuid.UUID{group.GroupId, other.GroupId, empty.GroupId})
+	if err != nil {
+		t.Fatalf("ListGroupMembers failed: %v", err)
+	}
+	if len(members) != 2 || len(members[empty.GroupId]) != 0 {
+		t.Fatalf("Expected the members of two groups, got %v", members)
+	}
+	slices.SortFunc(want, func(a, b uuid.UUID) int { return bytes.Compare(a[:], b[:]) })
+	var got []uuid.UUID
+	for _, member := range members[group.GroupId] {
+		got = append(got, member.UserId)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected members %v in id order, got %v", want, got)
+	}
+	if len(members[other.GroupId]) != 1 || members[other.GroupId][0].Email != outsider.Email {
+		t.Errorf("Expected the outsider in the other group, got %v", members[other.GroupId])
+	}
+}
+
+func testDeleteGroup(t *testing.T, groupStore GroupStore) {
+	user := createUser(t, groupStore, newUser())
+	group := createGroup(t, groupStore, newGroup("platform"))
+	addGroupMember(t, groupStore, group.GroupId, user.UserId)
+
+	deleted, err := groupStore.DeleteGroup(t.Context(), group.GroupId)
+	if err != nil || !deleted {
+		t.Fatalf("DeleteGroup failed: deleted=%v err=%v", deleted, err)
+	}
+	if _, ok, _ := groupStore.GetGroupById(t.Context(), group.GroupId); ok {
+		t.Error("Expected the group to be gone")
+	}
+	if _, ok, _ := groupStore.GetUserById(t.Context(), user.UserId); !ok {
+		t.Error("Expected the member to be kept")
+	}
+	if groups, err := groupStore.ListUserGroups(t.Context(), user.UserId); err != nil || len(groups) != 0 {
+		t.Errorf("Expected no groups, got %+v, %v", groups, err)
+	}
+
+	deleted, err = groupStore.DeleteGroup(t.Context(), group.GroupId)
+	if err != nil || deleted {
+		t.Errorf("Expected a second delete to find nothing, got deleted=%v err=%v", deleted, err)
+	}
+}
+
+func testDeleteUserRemovesMemberships(t *testing.T, groupStore GroupStore) {
+	user := createUser(t, groupStore, newUser())
+	group := createGroup(t, groupStore, newGroup("platform"))
+	addGroupMember(t, groupStore, group.GroupId, user.UserId)
+
+	if _, err := groupStore.DeleteUser(t.Context(), user.UserId); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+
+	members, err := groupStore.ListUsers(t.Context(), model.UserFilter{GroupId: group.GroupId}, uuid.Nil, 10)
+	if err != nil || len(members) != 0 {
+		t.Errorf("Expected no members, got %+v, %v", members, err)
+	}
+	if err := groupStore.AddGroupMember(t.Context(), group.GroupId, user.UserId); !errors.Is(err, store.ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound, got %v", err)
+	}
+}
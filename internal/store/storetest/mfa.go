@@ -0,0 +1,347 @@
+package storetest
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+// MFAStore is a user store that also keeps second factors and the audit
+// trail.
+type MFAStore interface {
+	store.UserStoreInterface
+	store.MFAStoreInterface
+	store.AuditStoreInterface
+}
+
+// RunMFAStoreTests runs the multi-factor authentication conformance suite
+// against the store returned by newStore.
+func RunMFAStoreTests(t *testing.T, newStore func(t *testing.T) MFAStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, mfaStore MFAStore)
+	}{
+		{"ConfirmTOTPFactor", testConfirmTOTPFactor},
+		{"CreateTOTPFactor_ReplacesUnconfirmed", testCreateTOTPFactorReplacesUnconfirmed},
+		{"CreateTOTPFactor_Enabled", testCreateTOTPFactorEnabled},
+		{"ConfirmTOTPFactor_NotFound", testConfirmTOTPFactorNotFound},
+		{"UseTOTPStep", testUseTOTPStep},
+		{"UseRecoveryCode", testUseRecoveryCode},
+		{"ReplaceRecoveryCodes", testReplaceRecoveryCodes},
+		{"ResetMFA", testResetMFA},
+		{"CountMFAChallengeAttempt", testCountMFAChallengeAttempt},
+		{"CountMFAChallengeAttempt_Expired", testCountMFAChallengeAttemptExpired},
+		{"DeleteUser_RemovesFactor", testDeleteUserRemovesFactor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+// createTOTPFactor starts enrolling a factor for the user.
+func createTOTPFactor(t *testing.T, mfaStore MFAStore, userId uuid.UUID, secret string) {
+	t.Helper()
+
+	factor := model.TOTPFactor{
+		UserId:           userId,
+		SecretCiphertext: []byte(secret),
+		CreatedAt:        time.Now().UTC(),
+	}
+	if err := mfaStore.CreateTOTPFactor(t.Context(), factor); err != nil {
+		t.Fatalf("CreateTOTPFactor failed: %v", err)
+	}
+}
+
+// enableTOTPFactor enrols and confirms a factor for the user at step 100,
+// with the given recovery codes.
+func enableTOTPFactor(t *testing.T, mfaStore MFAStore, userId uuid.UUID, recoveryCodeHashes ...[]byte) {
+	t.Helper()
+
+	createTOTPFactor(t, mfaStore, userId, "secret")
+	if err := mfaStore.ConfirmTOTPFactor(t.Context(), userId, 100, recoveryCodeHashes, time.Now().UTC()); err != nil {
+		t.Fatalf("ConfirmTOTPFactor failed: %v", err)
+	}
+}
+
+func mustGetTOTPFactor(t *testing.T, mfaStore MFAStore, userId uuid.UUID) model.TOTPFactor {
+	t.Helper()
+
+	factor, ok, err := mfaStore.GetTOTPFactor(t.Context(), userId)
+	if err != nil {
+		t.Fatalf("GetTOTPFactor failed: %v", err)
+	}
+	if !ok {
+		t.Fatalf("Expected a factor for user %v", userId)
+	}
+	return factor
+}
+
+func testConfirmTOTPFactor(t *testing.T, mfaStore MFAStore) {
+	user := createUser(t, mfaStore, newUser())
+	createTOTPFactor(t, mfaStore, user.UserId, "secret")
+
+	factor := mustGetTOTPFactor(t, mfaStore, user.UserId)
+	if factor.ConfirmedAt != nil {
+		t.Errorf("Expected an unconfirmed factor, got one confirmed at %v", factor.ConfirmedAt)
+	}
+	if string(factor.SecretCiphertext) != "secret" {
+		t.Errorf("Expected secret %q, got %q", "secret", factor.SecretCiphertext)
+	}
+
+	if err := mfaStore.ConfirmTOTPFactor(t.Context(), user.UserId, 100, [][]byte{[]byte("code")}, time.Now().UTC()); err != nil {
+		t.Fatalf("ConfirmTOTPFactor failed: %v", err)
+	}
+
+	factor = mustGetTOTPFactor(t, mfaStore, user.UserId)
+	if factor.ConfirmedAt == nil {
+		t.Error("Expected the factor to be confirmed")
+	}
+	if factor.LastUsedStep != 100 {
+		t.Errorf("Expected the confirming step to count as used, got %d", factor.LastUsedStep)
+	}
+
+	err := mfaStore.ConfirmTOTPFactor(t.Context(), user.UserId, 101, [][]byte{[]byte("code")}, time.Now().UTC())
+	if !errors.Is(err, store.ErrMFAEnabled) {
+		t.Errorf("Expected ErrMFAEnabled when confirming twice, got %v", err)
+	}
+
+	want := []model.AuditAction{model.AuditMFAEnrolmentStarted, model.AuditMFAEnabled}
+	if got := listAuditActions(t, mfaStore, user.UserId); !slices.Equal(got, want) {
+		t.Errorf("Expected audit actions %v, got %v", want, got)
+	}
+}
+
+func testCreateTOTPFactorReplacesUnconfirmed(t *testing.T, mfaStore MFAStore) {
+	user := createUser(t, mfaStore, newUser())
+	createTOTPFactor(t, mfaStore, user.UserId, "first")
+	createTOTPFactor(t, mfaStore, user.UserId, "second")
+
+	factor := mustGetTOTPFactor(t, mfaStore, user.UserId)
+	if string(factor.SecretCiphertext) != "second" {
+		t.Errorf("Expected the second secret to replace the first, got %q", factor.SecretCiphertext)
+	}
+}
+
+func testCreateTOTPFactorEnabled(t *testing.T, mfaStore MFAStore) {
+	user := createUser(t, mfaStore, newUser())
+	enableTOTPFactor(t, mfaStore, user.UserId, []byte("code"))
+
+	err := mfaStore.CreateTOTPFactor(t.Context(), model.TOTPFactor{
+		UserId:           user.UserId,
+		SecretCiphertext: []byte("other"),
+		CreatedAt:        time.Now().UTC(),
+	})
+	if !errors.Is(err, store.ErrMFAEnabled) {
+		t.Errorf("Expected ErrMFAEnabled, got %v", err)
+	}
+
+	factor := mustGetTOTPFactor(t, mfaStore, user.UserId)
+	if string(factor.SecretCiphertext) != "secret" || factor.ConfirmedAt == nil {
+		t.Errorf("Expected the confirmed factor to be kept, got %+v", factor)
+	}
+}
+
+func testConfirmTOTPFactorNotFound(t *testing.T, mfaStore MFAStore) {
+	user := createUser(t, mfaStore, newUser())
+
+	err := mfaStore.ConfirmTOTPFactor(t.Context(), user.UserId, 100, [][]byte{[]byte("code")}, time.Now().UTC())
+	if !errors.Is(err, store.ErrFactorNotFound) {
+		t.Errorf("Expected ErrFactorNotFound, got %v", err)
+	}
+}
+
+func testUseTOTPStep(t *testing.T, mfaStore MFAStore) {
+	user := createUser(t, mfaStore, newUser())
+
+	createTOTPFactor(t, mfaStore, user.UserId, "secret")
+	if err := mfaStore.UseTOTPStep(t.Context(), user.UserId, 101); !errors.Is(err, store.ErrCodeReused) {
+		t.Errorf("Expected ErrCodeReused for an unconfirmed factor, got %v", err)
+	}
+
+	if err := mfaStore.ConfirmTOTPFactor(t.Context(), user.UserId, 100, [][]byte{[]byte("code")}, time.Now().UTC()); err != nil {
+		t.Fatalf("ConfirmTOTPFactor failed: %v", err)
+	}
+	if err := mfaStore.UseTOTPStep(t.Context(), user.UserId, 100); !errors.Is(err, store.ErrCodeReused) {
+		t.Errorf("Expected ErrCodeReused for the confirming step, got %v", err)
+	}
+	if err := mfaStore.UseTOTPStep(t.Context(), user.UserId, 101); err != nil {
+		t.Fatalf("UseTOTPStep failed: %v", err)
+	}
+	if err := mfaStore.UseTOTPStep(t.Context(), user.UserId, 101); !errors.Is(err, store.ErrCodeReused) {
+		t.Errorf("Expected ErrCodeReused for a step used twice, got %v", err)
+	}
+	if err := mfaStore.UseTOTPStep(t.Context(), user.UserId, 99); !errors.Is(err, store.ErrCodeReused) {
+		t.Errorf("Expected ErrCodeReused for an earlier step, got %v", err)
+	}
+}
+
+func testUseRecoveryCode(t *testing.T, mfaStore MFAStore) {
+	user := createUser(t, mfaStore, newUser())
+	enableTOTPFactor(t, mfaStore, user.UserId, []byte("one"), []byte("two"))
+
+	if err := mfaStore.UseRecoveryCode(t.Context(), user.UserId, []byte("one"), time.Now().UTC()); err != nil {
+		t.Fatalf("UseRecoveryCode failed: %v", err)
+	}
+	if err := mfaStore.UseRecoveryCode(t.Context(), user.UserId, []byte("one"), time.Now().UTC()); !errors.Is(err, store.ErrWrongCode) {
+		t.Errorf("Expected ErrWrongCode for a used code, got %v", err)
+	}
+	if err := mfaStore.UseRecoveryCode(t.Context(), user.UserId, []byte("three"), time.Now().UTC()); !errors.Is(err, store.ErrWrongCode) {
+		t.Errorf("Expected ErrWrongCode for an unknown code, got %v", err)
+	}
+
+	// codes belong to one user
+	other := createUser(t, mfaStore, newUser())
+	enableTOTPFactor(t, mfaStore, other.UserId, []byte("other"))
+	if err := mfaStore.UseRecoveryCode(t.Context(), user.UserId, []byte("other"), time.Now().UTC()); !errors.Is(err, store.ErrWrongCode) {
+		t.Errorf("Expected ErrWrongCode for another user's code, got %v", err)
+	}
+
+	if err := mfaStore.UseRecoveryCode(t.Context(), user.UserId, []byte("two"), time.Now().UTC()); err != nil {
+		t.Fatalf("UseRecoveryCode failed: %v", err)
+	}
+	want := []model.AuditAction{
+		model.AuditMFAEnrolmentStarted,
+		model.AuditMFAEnabled,
+		model.AuditMFARecoveryCodeUsed,
+		model.AuditMFARecoveryCodeUsed,
+	}
+	if got := listAuditActions(t, mfaStore, user.UserId); !slices.Equal(got, want) {
+		t.Errorf("Expected audit actions %v, got %v", want, got)
+	}
+}
+
+func testReplaceRecoveryCodes(t *testing.T, mfaStore MFAStore) {
+	user := createUser(t, mfaStore, newUser())
+
+	err := mfaStore.ReplaceRecoveryCodes(t.Context(), user.UserId, [][]byte{[]byte("new")})
+	if !errors.Is(err, store.ErrMFANotEnabled) {
+		t.Errorf("Expected ErrMFANotEnabled without a factor, got %v", err)
+	}
+	createTOTPFactor(t, mfaStore, user.UserId, "secret")
+	err = mfaStore.ReplaceRecoveryCodes(t.Context(), user.UserId, [][]byte{[]byte("new")})
+	if !errors.Is(err, store.ErrMFANotEnabled) {
+		t.Errorf("Expected ErrMFANotEnabled with an unconfirmed factor, got %v", err)
+	}
+
+	if err := mfaStore.ConfirmTOTPFactor(t.Context(), user.UserId, 100, [][]byte{[]byte("old")}, time.Now().UTC()); err != nil {
+		t.Fatalf("ConfirmTOTPFactor failed: %v", err)
+	}
+	if err := mfaStore.ReplaceRecoveryCodes(t.Context(), user.UserId, [][]byte{[]byte("new")}); err != nil {
+		t.Fatalf("ReplaceRecoveryCodes failed: %v", err)
+	}
+
+	if err := mfaStore.UseRecoveryCode(t.Context(), user.UserId, []byte("old"), time.Now().UTC()); !errors.Is(err, store.ErrWrongCode) {
+		t.Errorf("Expected ErrWrongCode for a replaced code, got %v", err)
+	}
+	if err := mfaStore.UseRecoveryCode(t.Context(), user.UserId, []byte("new"), time.Now().UTC()); err != nil {
+		t.Errorf("Expected the new code to work, got %v", err)
+	}
+}
+
+func testResetMFA(t *testing.T, mfaStore MFAStore) {
+	user := createUser(t, mfaStore, newUser())
+
+	reset, err := mfaStore.ResetMFA(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("ResetMFA failed: %v", err)
+	}
+	if reset {
+		t.Error("Expected nothing to reset for a user without a factor")
+	}
+
+	enableTOTPFactor(t, mfaStore, user.UserId, []byte("code"))
+	reset, err = mfaStore.ResetMFA(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("ResetMFA failed: %v", err)
+	}
+	if !reset {
+		t.Error("Expected the factor to be reset")
+	}
+
+	if _, ok, err := mfaStore.GetTOTPFactor(t.Context(), user.UserId); err != nil || ok {
+		t.Errorf("Expected the factor to be gone, got ok=%v err=%v", ok, err)
+	}
+	if err := mfaStore.UseRecoveryCode(t.Context(), user.UserId, []byte("code"), time.Now().UTC()); !errors.Is(err, store.ErrWrongCode) {
+		t.Errorf("Expected the recovery codes to be gone, got %v", err)
+	}
+	if got := listAuditActions(t, mfaStore, user.UserId); got[len(got)-1] != model.AuditMFAReset {
+		t.Errorf("Expected the reset to be audited, got %v", got)
+	}
+
+	// the user can enrol again
+	enableTOTPFactor(t, mfaStore, user.UserId, []byte("code"))
+}
+
+func testCountMFAChallengeAttempt(t *testing.T, mfaStore MFAStore) {
+	user := createUser(t, mfaStore, newUser())
+	challenge := model.MFAChallenge{
+		ChallengeId: uuid.New(),
+		UserId:      user.UserId,
+		ExpiresAt:   time.Now().UTC().Add(time.Hour),
+	}
+	if err := mfaStore.CreateMFAChallenge(t.Context(), challenge); err != nil {
+		t.Fatalf("CreateMFAChallenge failed: %v", err)
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		counted, err := mfaStore.CountMFAChallengeAttempt(t.Context(), challenge.ChallengeId, 2, time.Now().UTC())
+		if err != nil {
+			t.Fatalf("CountMFAChallengeAttempt failed: %v", err)
+		}
+		if counted.UserId != user.UserId || counted.Attempts != attempt {
+			t.Errorf("Expected attempt %d by user %v, got %+v", attempt, user.UserId, counted)
+		}
+	}
+
+	_, err := mfaStore.CountMFAChallengeAttempt(t.Context(), challenge.ChallengeId, 2, time.Now().UTC())
+	if !errors.Is(err, store.ErrTooManyAttempts) {
+		t.Errorf("Expected ErrTooManyAttempts, got %v", err)
+	}
+
+	deleted, err := mfaStore.DeleteMFAChallenge(t.Context(), challenge.ChallengeId)
+	if err != nil || !deleted {
+		t.Fatalf("Expected the challenge to be deleted, got deleted=%v err=%v", deleted, err)
+	}
+	_, err = mfaStore.CountMFAChallengeAttempt(t.Context(), challenge.ChallengeId, 2, time.Now().UTC())
+	if !errors.Is(err, store.ErrChallengeNotFound) {
+		t.Errorf("Expected ErrChallengeNotFound after deleting, got %v", err)
+	}
+}
+
+func testCountMFAChallengeAttemptExpired(t *testing.T, mfaStore MFAStore) {
+	user := createUser(t, mfaStore, newUser())
+	challenge := model.MFAChallenge{
+		ChallengeId: uuid.New(),
+		UserId:      user.UserId,
+		ExpiresAt:   time.Now().UTC().Add(time.Hour),
+	}
+	if err := mfaStore.CreateMFAChallenge(t.Context(), challenge); err != nil {
+		t.Fatalf("CreateMFAChallenge failed: %v", err)
+	}
+
+	_, err := mfaStore.CountMFAChallengeAttempt(t.Context(), challenge.ChallengeId, 5, challenge.ExpiresAt.Add(time.Second))
+	if !errors.Is(err, store.ErrChallengeExpired) {
+		t.Errorf("Expected ErrChallengeExpired, got %v", err)
+	}
+}
+
+func testDeleteUserRemovesFactor(t *testing.T, mfaStore MFAStore) {
+	user := createUser(t, mfaStore, newUser())
+	enableTOTPFactor(t, mfaStore, user.UserId, []byte("code"))
+
+	if _, err := mfaStore.DeleteUser(t.Context(), user.UserId); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+
+	if _, ok, err := mfaStore.GetTOTPFactor(t.Context(), user.UserId); err != nil || ok {
+		t.Errorf("Expected the factor to be deleted with the user, got ok=%v err=%v", ok, err)
+	}
+}
@@ -0,0 +1,338 @@
+package storetest
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+// OIDCStore is a user store that also keeps the state of the OpenID Connect
+// provider.
+type OIDCStore interface {
+	store.UserStoreInterface
+	store.OIDCStoreInterface
+}
+
+// RunOIDCStoreTests runs the OpenID Connect conformance suite against the
+// store returned by newStore. Signing keys are shared by every tenant, so the
+// tests only look at the keys they create.
+func RunOIDCStoreTests(t *testing.T, newStore func(t *testing.T) OIDCStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, oidcStore OIDCStore)
+	}{
+		{"CreateOAuthClient", testCreateOAuthClient},
+		{"DeleteOAuthClient", testDeleteOAuthClient},
+		{"SaveOAuthConsent", testSaveOAuthConsent},
+		{"ConsumeAuthorizationCode", testConsumeAuthorizationCode},
+		{"RotateOAuthRefreshToken", testRotateOAuthRefreshToken},
+		{"RotateOAuthRefreshToken_Expired", testRotateOAuthRefreshTokenExpired},
+		{"RevokeOAuthRefreshToken", testRevokeOAuthRefreshToken},
+		{"SigningKeys", testSigningKeys},
+		{"DeleteUser_RemovesGrants", testDeleteUserRemovesGrants},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+func createOAuthClient(t *testing.T, oidcStore OIDCStore, secretHash []byte) model.OAuthClient {
+	t.Helper()
+
+	client, err := oidcStore.CreateOAuthClient(t.Context(), model.OAuthClient{
+		Name:         "App " + uuid.NewString(),
+		SecretHash:   secretHash,
+		RedirectURIs: []string{"https://app.example.com/callback", "http://localhost:8080/callback"},
+	})
+	if err != nil {
+		t.Fatalf("CreateOAuthClient failed: %v", err)
+	}
+	return client
+}
+
+// createOAuthRefreshToken creates a refresh token of the user for the client
+// that expires in an hour.
+func createOAuthRefreshToken(t *testing.T, oidcStore OIDCStore, clientId, userId uuid.UUID) model.OAuthRefreshToken {
+	t.Helper()
+
+	now := time.Now().UTC()
+	token := model.OAuthRefreshToken{
+		TokenId:   uuid.New(),
+		ClientId:  clientId,
+		UserId:    userId,
+		Scope:     "openid offline_access",
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	}
+	if err := oidcStore.CreateOAuthRefreshToken(t.Context(), token); err != nil {
+		t.Fatalf("CreateOAuthRefreshToken failed: %v", err)
+	}
+	return token
+}
+
+func createAuthorizationCode(t *testing.T, oidcStore OIDCStore, clientId, userId uuid.UUID) model.AuthorizationCode {
+	t.Helper()
+
+	code := model.AuthorizationCode{
+		CodeId:        uuid.New(),
+		ClientId:      clientId,
+		UserId:        userId,
+		RedirectURI:   "https://app.example.com/callback",
+		Scope:         "openid email",
+		Nonce:         "n-0S6_WzA2Mj",
+		CodeChallenge: "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM",
+		ExpiresAt:     time.Now().UTC().Add(time.Minute).Truncate(time.Microsecond),
+	}
+	if err := oidcStore.CreateAuthorizationCode(t.Context(), code); err != nil {
+		t.Fatalf("CreateAuthorizationCode failed: %v", err)
+	}
+	return code
+}
+
+func testCreateOAuthClient(t *testing.T, oidcStore OIDCStore) {
+	confidential := createOAuthClient(t, oidcStore, []byte("hash of the secret"))
+	public := createOAuthClient(t, oidcStore, nil)
+
+	if confidential.ClientId == uuid.Nil || confidential.CreatedAt.IsZero() {
+		t.Errorf("Expected an id and creation time, got %+v", confidential)
+	}
+	if confidential.Public() || !public.Public() {
+		t.Errorf("Expected only the client without a secret to be public")
+	}
+
+	got, ok, err := oidcStore.GetOAuthClient(t.Context(), confidential.ClientId)
+	if err != nil || !ok {
+		t.Fatalf("GetOAuthClient failed: %v, found %v", err, ok)
+	}
+	if got.Name != confidential.Name || string(got.SecretHash) != "hash of the secret" || !slices.Equal(got.RedirectURIs, confidential.RedirectURIs) {
+		t.Errorf("Expected %+v, got %+v", confidential, got)
+	}
+
+	clients, err := oidcStore.GetAllOAuthClients(t.Context())
+	if err != nil {
+		t.Fatalf("GetAllOAuthClients failed: %v", err)
+	}
+	found := 0
+	for _, client := range clients {
+		if client.ClientId == confidential.ClientId || client.ClientId == public.ClientId {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("Expected both clients to be listed, found %d", found)
+	}
+
+	if _, ok, err := oidcStore.GetOAuthClient(t.Context(), uuid.New()); err != nil || ok {
+		t.Errorf("Expected an unknown client not to be found, got %v, %v", ok, err)
+	}
+}
+
+func testDeleteOAuthClient(t *testing.T, oidcStore OIDCStore) {
+	user := createUser(t, oidcStore, newUser())
+	client := createOAuthClient(t, oidcStore, nil)
+	token := createOAuthRefreshToken(t, oidcStore, client.ClientId, user.UserId)
+
+	deleted, err := oidcStore.DeleteOAuthClient(t.Context(), client.ClientId)
+	if err != nil || !deleted {
+		t.Fatalf("DeleteOAuthClient failed: %v, deleted %v", err, deleted)
+	}
+	if _, ok, _ := oidcStore.GetOAuthClient(t.Context(), client.ClientId); ok {
+		t.Errorf("Expected the client to be gone")
+	}
+	if _, err := oidcStore.RotateOAuthRefreshToken(t.Context(), token.TokenId, uuid.New(), client.ClientId, time.Now().UTC()); !errors.Is(err, store.ErrRefreshTokenNotFound) {
+		t.Errorf("Expected the client's refresh tokens to be gone, got %v", err)
+	}
+
+	deleted, err = oidcStore.DeleteOAuthClient(t.Context(), client.ClientId)
+	if err != nil || deleted {
+		t.Errorf("Expected a second delete to find nothing, got %v, %v", deleted, err)
+	}
+}
+
+func testSaveOAuthConsent(t *testing.T, oidcStore OIDCStore) {
+	user := createUser(t, oidcStore, newUser())
+	client := createOAuthClient(t, oidcStore, nil)
+
+	if _, ok, err := oidcStore.GetOAuthConsent(t.Context(), user.UserId, client.ClientId); err != nil || ok {
+		t.Fatalf("Expected no consent yet, got %v, %v", ok, err)
+	}
+
+	for _, scope := range []string{"openid", "openid email profile"} {
+		consent := model.OAuthConsent{
+			UserId:    user.UserId,
+			ClientId:  client.ClientId,
+			Scope:     scope,
+			GrantedAt: time.Now().UTC(),
+		}
+		if err := oidcStore.SaveOAuthConsent(t.Context(), consent); err != nil {
+			t.Fatalf("SaveOAuthConsent failed: %v", err)
+		}
+	}
+
+	consent, ok, err := oidcStore.GetOAuthConsent(t.Context(), user.UserId, client.ClientId)
+	if err != nil || !ok {
+		t.Fatalf("GetOAuthConsent failed: %v, found %v", err, ok)
+	}
+	if consent.Scope != "openid email profile" {
+		t.Errorf("Expected the last consent to replace the first, got %q", consent.Scope)
+	}
+}
+
+func testConsumeAuthorizationCode(t *testing.T, oidcStore OIDCStore) {
+	user := createUser(t, oidcStore, newUser())
+	client := createOAuthClient(t, oidcStore, nil)
+	code := createAuthorizationCode(t, oidcStore, client.ClientId, user.UserId)
+
+	consumed, ok, err := oidcStore.ConsumeAuthorizationCode(t.Context(), code.CodeId)
+	if err != nil || !ok {
+		t.Fatalf("ConsumeAuthorizationCode failed: %v, found %v", err, ok)
+	}
+	if consumed.ClientId != code.ClientId || consumed.UserId != code.UserId || consumed.RedirectURI != code.RedirectURI ||
+		consumed.Scope != code.Scope || consumed.Nonce != code.Nonce || consumed.CodeChallenge != code.CodeChallenge ||
+		!consumed.ExpiresAt.Equal(code.ExpiresAt) {
+		t.Errorf("Expected %+v, got %+v", code, consumed)
+	}
+
+	// a code can only be redeemed once
+	if _, ok, err := oidcStore.ConsumeAuthorizationCode(t.Context(), code.CodeId); err != nil || ok {
+		t.Errorf("Expected a consumed code not to be found, got %v, %v", ok, err)
+	}
+}
+
+func testRotateOAuthRefreshToken(t *testing.T, oidcStore OIDCStore) {
+	user := createUser(t, oidcStore, newUser())
+	client := createOAuthClient(t, oidcStore, nil)
+	other := createOAuthClient(t, oidcStore, nil)
+	token := createOAuthRefreshToken(t, oidcStore, client.ClientId, user.UserId)
+
+	// a token only works for the client it was issued to
+	_, err := oidcStore.RotateOAuthRefreshToken(t.Context(), token.TokenId, uuid.New(), other.ClientId, time.Now().UTC())
+	if !errors.Is(err, store.ErrRefreshTokenNotFound) {
+		t.Errorf("Expected ErrRefreshTokenNotFound for another client, got %v", err)
+	}
+
+	newTokenId := uuid.New()
+	rotated, err := oidcStore.RotateOAuthRefreshToken(t.Context(), token.TokenId, newTokenId, client.ClientId, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("RotateOAuthRefreshToken failed: %v", err)
+	}
+	if rotated.TokenId != newTokenId || rotated.UserId != user.UserId || rotated.Scope != token.Scope {
+		t.Errorf("Expected token %v of user %v, got %+v", newTokenId, user.UserId, rotated)
+	}
+
+	// the old id is spent
+	_, err = oidcStore.RotateOAuthRefreshToken(t.Context(), token.TokenId, uuid.New(), client.ClientId, time.Now().UTC())
+	if !errors.Is(err, store.ErrRefreshTokenNotFound) {
+		t.Errorf("Expected ErrRefreshTokenNotFound for a spent refresh token, got %v", err)
+	}
+}
+
+func testRotateOAuthRefreshTokenExpired(t *testing.T, oidcStore OIDCStore) {
+	user := createUser(t, oidcStore, newUser())
+	client := createOAuthClient(t, oidcStore, nil)
+	token := createOAuthRefreshToken(t, oidcStore, client.ClientId, user.UserId)
+
+	_, err := oidcStore.RotateOAuthRefreshToken(t.Context(), token.TokenId, uuid.New(), client.ClientId, token.ExpiresAt.Add(time.Second))
+	if !errors.Is(err, store.ErrRefreshTokenExpired) {
+		t.Errorf("Expected ErrRefreshTokenExpired, got %v", err)
+	}
+}
+
+func testRevokeOAuthRefreshToken(t *testing.T, oidcStore OIDCStore) {
+	user := createUser(t, oidcStore, newUser())
+	client := createOAuthClient(t, oidcStore, nil)
+	other := createOAuthClient(t, oidcStore, nil)
+	token := createOAuthRefreshToken(t, oidcStore, client.ClientId, user.UserId)
+
+	if revoked, err := oidcStore.RevokeOAuthRefreshToken(t.Context(), token.TokenId, other.ClientId); err != nil || revoked {
+		t.Errorf("Expected another client not to revoke the token, got %v, %v", revoked, err)
+	}
+	if revoked, err := oidcStore.RevokeOAuthRefreshToken(t.Context(), token.TokenId, client.ClientId); err != nil || !revoked {
+		t.Fatalf("RevokeOAuthRefreshToken failed: %v, revoked %v", err, revoked)
+	}
+
+	_, err := oidcStore.RotateOAuthRefreshToken(t.Context(), token.TokenId, uuid.New(), client.ClientId, time.Now().UTC())
+	if !errors.Is(err, store.ErrRefreshTokenNotFound) {
+		t.Errorf("Expected ErrRefreshTokenNotFound for a revoked refresh token, got %v", err)
+	}
+}
+
+func testSigningKeys(t *testing.T, oidcStore OIDCStore) {
+	// far in the past, so no real key is deleted along with them
+	base := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	older := model.SigningKey{KeyId: uuid.NewString(), PrivateKeyCiphertext: []byte("older"), CreatedAt: base}
+	newer := model.SigningKey{KeyId: uuid.NewString(), PrivateKeyCiphertext: []byte("newer"), CreatedAt: base.Add(time.Hour)}
+	for _, key := range []model.SigningKey{older, newer} {
+		if err := oidcStore.CreateSigningKey(t.Context(), key); err != nil {
+			t.Fatalf("CreateSigningKey failed: %v", err)
+		}
+	}
+
+	keyIds := func() []string {
+		t.Helper()
+
+		keys, err := oidcStore.GetSigningKeys(t.Context())
+		if err != nil {
+			t.Fatalf("GetSigningKeys failed: %v", err)
+		}
+		var ids []string
+		for _, key := range keys {
+			if key.KeyId == older.KeyId || key.KeyId == newer.KeyId {
+				ids = append(ids, key.KeyId)
+			}
+		}
+		return ids
+	}
+
+	if got, want := keyIds(), []string{newer.KeyId, older.KeyId}; !slices.Equal(got, want) {
+		t.Errorf("Expected the keys newest first %v, got %v", want, got)
+	}
+
+	if err := oidcStore.DeleteSigningKeys(t.Context(), newer.CreatedAt); err != nil {
+		t.Fatalf("DeleteSigningKeys failed: %v", err)
+	}
+	if got, want := keyIds(), []string{newer.KeyId}; !slices.Equal(got, want) {
+		t.Errorf("Expected only %v to be kept, got %v", want, got)
+	}
+
+	if err := oidcStore.DeleteSigningKeys(t.Context(), newer.CreatedAt.Add(time.Second)); err != nil {
+		t.Fatalf("DeleteSigningKeys failed: %v", err)
+	}
+}
+
+func testDeleteUserRemovesGrants(t *testing.T, oidcStore OIDCStore) {
+	user := createUser(t, oidcStore, newUser())
+	client := createOAuthClient(t, oidcStore, nil)
+	code := createAuthorizationCode(t, oidcStore, client.ClientId, user.UserId)
+	token := createOAuthRefreshToken(t, oidcStore, client.ClientId, user.UserId)
+	if err := oidcStore.SaveOAuthConsent(t.Context(), model.OAuthConsent{
+		UserId:    user.UserId,
+		ClientId:  client.ClientId,
+		Scope:     "openid",
+		GrantedAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("SaveOAuthConsent failed: %v", err)
+	}
+
+	if _, err := oidcStore.DeleteUser(t.Context(), user.UserId); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+
+	if _, ok, _ := oidcStore.GetOAuthConsent(t.Context(), user.UserId, client.ClientId); ok {
+		t.Errorf("Expected the consent to be gone")
+	}
+	if _, ok, _ := oidcStore.ConsumeAuthorizationCode(t.Context(), code.CodeId); ok {
+		t.Errorf("Expected the authorization code to be gone")
+	}
+	if _, err := oidcStore.RotateOAuthRefreshToken(t.Context(), token.TokenId, uuid.New(), client.ClientId, time.Now().UTC()); !errors.Is(err, store.ErrRefreshTokenNotFound) {
+		t.Errorf("Expected the refresh token to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+)
+
+const maxPhoneAttempts = 3
+
+// PhoneVerificationStore is a user store that also keeps phone verifications.
+type PhoneVerificationStore interface {
+	store.UserStoreInterface
+	store.PhoneVerificationStoreInterface
+}
+
+// RunPhoneVerificationStoreTests runs the phone verification conformance
+// suite against the store returned by newStore.
+func RunPhoneVerificationStoreTests(t *testing.T, newStore func(t *testing.T) PhoneVerificationStore) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, verificationStore PhoneVerificationStore)
+	}{
+		{"ConfirmPhoneVerification", testConfirmPhoneVerification},
+		{"ConfirmPhoneVerification_WrongCode", testConfirmPhoneVerificationWrongCode},
+		{"ConfirmPhoneVerification_TooManyAttempts", testConfirmPhoneVerificationTooManyAttempts},
+		{"ConfirmPhoneVerification_Expired", testConfirmPhoneVerificationExpired},
+		{"ConfirmPhoneVerification_NotFound", testConfirmPhoneVerificationNotFound},
+		{"ConfirmPhoneVerification_PhoneChanged", testConfirmPhoneVerificationPhoneChanged},
+		{"CreatePhoneVerification_Throttled", testCreatePhoneVerificationThrottled},
+		{"UpdateUser_NewPhoneIsUnverified", testUpdateUserNewPhoneIsUnverified},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+// createPhoneVerification creates a user and a code for their phone that
+// expires in ten minutes.
+func createPhoneVerification(t *testing.T, verificationStore PhoneVerificationStore) (model.User, model.PhoneVerification) {
+	t.Helper()
+
+	user := createUser(t, verificationStore, newUser())
+	now := time.Now()
+	verification := model.PhoneVerification{
+		UserId:    user.UserId,
+		Phone:     user.Phone,
+		CodeHash:  []byte("hash of 123456"),
+		ExpiresAt: now.Add(10 * time.Minute),
+		SentAt:    now,
+	}
+	if err := verificationStore.CreatePhoneVerification(t.Context(), verification, now); err != nil {
+		t.Fatalf("CreatePhoneVerification failed: %v", err)
+	}
+	return user, verification
+}
+
+func confirmPhone(t *testing.T, verificationStore PhoneVerificationStore, verification model.PhoneVerification, codeHash string) (model.User, error) {
+	t.Helper()
+	return verificationStore.ConfirmPhoneVerification(t.Context(), verification.UserId, []byte(codeHash), maxPhoneAttempts, time.Now())
+}
+
+func testConfirmPhoneVerification(t *testing.T, verificationStore PhoneVerificationStore) {
+	user, verification := createPhoneVerification(t, verificationStore)
+	if user.PhoneVerifiedAt != nil {
+		t.Fatalf("Expected a new user to be unverified, got %v", user.PhoneVerifiedAt)
+	}
+
+	verified, err := confirmPhone(t, verificationStore, verification, "hash of 123456")
+	if err != nil {
+		t.Fatalf("ConfirmPhoneVerification failed: %v", err)
+	}
+	if verified.PhoneVerifiedAt == nil {
+		t.Errorf("Expected the phone to be verified")
+	}
+
+	got, _, err := verificationStore.GetUserById(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("GetUserById failed: %v", err)
+	}
+	if got.PhoneVerifiedAt == nil {
+		t.Errorf("Expected the verification to be stored")
+	}
+
+	// the code is used up
+	if _, err := confirmPhone(t, verificationStore, verification, "hash of 123456"); !errors.Is(err, store.ErrVerificationNotFound) {
+		t.Errorf("Expected ErrVerificationNotFound for a used code, got %v", err)
+	}
+}
+
+func testConfirmPhoneVerificationWrongCode(t *testing.T, verificationStore PhoneVerificationStore) {
+	_, verification := createPhoneVerification(t, verificationStore)
+
+	if _, err := confirmPhone(t, verificationStore, verification, "hash of 000000"); !errors.Is(err, store.ErrWrongCode) {
+		t.Fatalf("Expected ErrWrongCode, got %v", err)
+	}
+
+	got, ok, err := verificationStore.GetPhoneVerification(t.Context(), verification.UserId)
+	if err != nil || !ok {
+		t.Fatalf("GetPhoneVerification failed: %v, %v", ok, err)
+	}
+	if got.Attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got.Attempts)
+	}
+
+	if _, err := confirmPhone(t, verificationStore, verification, "hash of 123456"); err != nil {
+		t.Errorf("Expected the right code to work after a wrong one, got %v", err)
+	}
+}
+
+func testConfirmPhoneVerificationTooManyAttempts(t *testing.T, verificationStore PhoneVerificationStore) {
+	_, verification := createPhoneVerification(t, verificationStore)
+
+	for range maxPhoneAttempts {
+		if _, err := confirmPhone(t, verificationStore, verification, "hash of 000000"); !errors.Is(err, store.ErrWrongCode) {
+			t.Fatalf("Expected ErrWrongCode, got %v", err)
+		}
+	}
+
+	if _, err := confirmPhone(t, verificationStore, verification, "hash of 123456"); !errors.Is(err, store.ErrTooManyAttempts) {
+		t.Errorf("Expected ErrTooManyAttempts, got %v", err)
+	}
+}
+
+func testConfirmPhoneVerificationExpired(t *testing.T, verificationStore PhoneVerificationStore) {
+	_, verification := createPhoneVerification(t, verificationStore)
+
+	_, err := verificationStore.ConfirmPhoneVerification(t.Context(), verification.UserId, verification.CodeHash, maxPhoneAttempts, verification.ExpiresAt.Add(time.Second))
+	if !errors.Is(err, store.ErrVerificationExpired) {
+		t.Errorf("Expected ErrVerificationExpired, got %v", err)
+	}
+}
+
+func testConfirmPhoneVerificationNotFound(t *testing.T, verificationStore PhoneVerificationStore) {
+	user := createUser(t, verificationStore, newUser())
+
+	_, err := verificationStore.ConfirmPhoneVerification(t.Context(), user.UserId, []byte("hash of 123456"), maxPhoneAttempts, time.Now())
+	if !errors.Is(err, store.ErrVerificationNotFound) {
+		t.Errorf("Expected ErrVerificationNotFound, got %v", err)
+	}
+}
+
+func testConfirmPhoneVerificationPhoneChanged(t *testing.T, verificationStore PhoneVerificationStore) {
+	user, verification := createPhoneVerification(t, verificationStore)
+
+	user.Phone = "+12345678902"
+	if _, _, err := verificationStore.UpdateUser(t.Context(), user, user.UserId); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+
+	if _, err := confirmPhone(t, verificationStore, verification, "hash of 123456"); !errors.Is(err, store.ErrPhoneChanged) {
+		t.Errorf("Expected ErrPhoneChanged, got %v", err)
+	}
+}
+
+func testCreatePhoneVerificationThrottled(t *testing.T, verificationStore PhoneVerificationStore) {
+	_, verification := createPhoneVerification(t, verificationStore)
+	if _, err := confirmPhone(t, verificationStore, verification, "hash of 000000"); !errors.Is(err, store.ErrWrongCode) {
+		t.Fatalf("Expected ErrWrongCode, got %v", err)
+	}
+
+	resend := verification
+	resend.CodeHash = []byte("hash of 654321")
+	resend.SentAt = verification.SentAt.Add(time.Second)
+
+	// only codes sent before a minute ago may be replaced
+	err := verificationStore.CreatePhoneVerification(t.Context(), resend, resend.SentAt.Add(-time.Minute))
+	if !errors.Is(err, store.ErrResendTooSoon) {
+		t.Fatalf("Expected ErrResendTooSoon, got %v", err)
+	}
+
+	resend.SentAt = verification.SentAt.Add(time.Minute)
+	if err := verificationStore.CreatePhoneVerification(t.Context(), resend, verification.SentAt); err != nil {
+		t.Fatalf("CreatePhoneVerification failed: %v", err)
+	}
+
+	got, _, err := verificationStore.GetPhoneVerification(t.Context(), verification.UserId)
+	if err != nil {
+		t.Fatalf("GetPhoneVerification failed: %v", err)
+	}
+	if string(got.CodeHash) != "hash of 654321" || got.Attempts != 0 {
+		t.Errorf("Expected the new code with no attempts, got %q with %d", got.CodeHash, got.Attempts)
+	}
+}
+
+func testUpdateUserNewPhoneIsUnverified(t *testing.T, verificationStore PhoneVerificationStore) {
+	_, verification := createPhoneVerification(t, verificationStore)
+
+	user, err := confirmPhone(t, verificationStore, verification, "hash of 123456")
+	if err != nil {
+		t.Fatalf("ConfirmPhoneVerification failed: %v", err)
+	}
+
+	user.FirstName = "Alicia"
+	updated, _, err := verificationStore.UpdateUser(t.Context(), user, user.UserId)
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if updated.PhoneVerifiedAt == nil {
+		t.Errorf("Expected other changes to keep the phone verified")
+	}
+
+	updated.Phone = "+12345678902"
+	updated, _, err = verificationStore.UpdateUser(t.Context(), updated, user.UserId)
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if updated.PhoneVerifiedAt != nil {
+		t.Errorf("Expected a new phone to be unverified, got %v", updated.PhoneVerifiedAt)
+	}
+}
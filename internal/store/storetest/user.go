@@ -0,0 +1,713 @@
+// Package storetest holds the behaviour every UserStoreInterface
+// implementation must share. Each implementation runs it from its own tests.
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"example.com/user-management/internal/emailaddr"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+)
+
+// RunEmailProviderRulesTests checks the store returned by newStore, which is
+// given emailaddr.Rules with Providers set, treats the variants of an address
+// a provider delivers to one mailbox as one user.
+func RunEmailProviderRulesTests(t *testing.T, newStore func(t *testing.T) store.UserStoreInterface) {
+	userStore := newStore(t)
+	local := "alice" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	first := newUser()
+	first.Email = local + "@gmail.com"
+	first = createUser(t, userStore, first)
+
+	for _, email := range []string{
+		strings.ToUpper(local) + "@Gmail.com",
+		local[:3] + "." + local[3:] + "+news@googlemail.com",
+	} {
+		user := newUser()
+		user.Email = email
+		if _, err := userStore.CreateUser(t.Context(), user); !errors.Is(err, store.ErrDuplicateEmail) {
+			t.Errorf("Expected ErrDuplicateEmail for %s, got %v", email, err)
+		}
+	}
+
+	users, err := userStore.ListUsers(t.Context(), model.UserFilter{Email: local[:3] + "." + local[3:] + "@gmail.com"}, uuid.Nil, 10)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(users) != 1 || users[0].UserId != first.UserId {
+		t.Errorf("Expected the variant to find %s, got %v", first.UserId, users)
+	}
+}
+
+// RunUserStoreTests runs the conformance suite against the store returned by
+// newStore. The store may be shared with other tests, so every case works on
+// users it created itself.
+func RunUserStoreTests(t *testing.T, newStore func(t *testing.T) store.UserStoreInterface) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, userStore store.UserStoreInterface)
+	}{
+		{"CreateUser", testCreateUser},
+		{"CreateUser_DefaultStatus", testCreateUserDefaultStatus},
+		{"CreateUser_NoAttributes", testCreateUserNoAttributes},
+		{"CreateUser_DuplicateEmail", testCreateUserDuplicateEmail},
+		{"CreateUser_NormalizesEmail", testCreateUserNormalizesEmail},
+		{"CreateUser_SameMailbox", testCreateUserSameMailbox},
+		{"CreateUser_InvalidEmail", testCreateUserInvalidEmail},
+		{"CreateUser_NormalizesPhone", testCreateUserNormalizesPhone},
+		{"CreateUser_InvalidPhone", testCreateUserInvalidPhone},
+		{"GetAllUsers", testGetAllUsers},
+		{"GetUserById", testGetUserById},
+		{"GetUserById_NotFound", testGetUserByIdNotFound},
+		{"GetUsersByIds", testGetUsersByIds},
+		{"ListUsers_FilterAndPaginate", testListUsersFilterAndPaginate},
+		{"ListUsers_StatusAndEmail", testListUsersStatusAndEmail},
+		{"ListUsers_EmailIgnoresCase", testListUsersEmailIgnoresCase},
+		{"ListUsers_Attributes", testListUsersAttributes},
+		{"UpdateUser", testUpdateUser},
+		{"UpdateUser_ClearsAge", testUpdateUserClearsAge},
+		{"UpdateUser_ReplacesAttributes", testUpdateUserReplacesAttributes},
+		{"UpdateUser_NotFound", testUpdateUserNotFound},
+		{"UpdateUser_DuplicateEmail", testUpdateUserDuplicateEmail},
+		{"PutUser", testPutUser},
+		{"PutUser_IfMatch", testPutUserIfMatch},
+		{"PutUser_IfNoneMatch", testPutUserIfNoneMatch},
+		{"PutUser_IfNoneMatchConcurrent", testPutUserIfNoneMatchConcurrent},
+		{"PutUser_DuplicateEmail", testPutUserDuplicateEmail},
+		{"ModifyUser", testModifyUser},
+		{"ModifyUser_Concurrent", testModifyUserConcurrent},
+		{"DeleteUser", testDeleteUser},
+		{"DeleteUser_NotFound", testDeleteUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.run(t, newStore(t))
+		})
+	}
+}
+
+func newUser() model.User {
+	return model.User{
+		FirstName: "Alice",
+		LastName:  "Smith",
+		Email:     fmt.Sprintf("alice.%s@example.com", uuid.New().String()),
+		Phone:     "+12345678901",
+		Age:       30,
+		Status:    model.StatusActive,
+		Attributes: map[string]any{
+			"department":     "eng",
+			"employeeNumber": 42.0,
+		},
+	}
+}
+
+func createUser(t *testing.T, userStore store.UserStoreInterface, user model.User) model.User {
+	t.Helper()
+
+	created, err := userStore.CreateUser(t.Context(), user)
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	return created
+}
+
+func testCreateUser(t *testing.T, userStore store.UserStoreInterface) {
+	user := newUser()
+	user.Status = model.StatusInactive
+
+	created := createUser(t, userStore, user)
+
+	if created.UserId == uuid.Nil {
+		t.Errorf("Expected non-nil UserId")
+	}
+	user.UserId = created.UserId
+	user.PhoneCountry, user.PhoneType = "US", "fixedOrMobile"
+	if !reflect.DeepEqual(created, user) {
+		t.Errorf("Expected %+v, got %+v", user, created)
+	}
+}
+
+func testCreateUserDefaultStatus(t *testing.T, userStore store.UserStoreInterface) {
+	user := newUser()
+	user.Status = ""
+
+	created := createUser(t, userStore, user)
+
+	if created.Status != model.StatusActive {
+		t.Errorf("Expected status Active, got %s", created.Status)
+	}
+}
+
+func testCreateUserNoAttributes(t *testing.T, userStore store.UserStoreInterface) {
+	user := newUser()
+	user.Attributes = nil
+
+	created := createUser(t, userStore, user)
+
+	if created.Attributes == nil || len(created.Attributes) != 0 {
+		t.Errorf("Expected empty attributes, got %#v", created.Attributes)
+	}
+}
+
+func testCreateUserDuplicateEmail(t *testing.T, userStore store.UserStoreInterface) {
+	user := createUser(t, userStore, newUser())
+
+	duplicate := newUser()
+	duplicate.Email = user.Email
+
+	_, err := userStore.CreateUser(t.Context(), duplicate)
+	if !errors.Is(err, store.ErrDuplicateEmail) {
+		t.Errorf("Expected ErrDuplicateEmail, got %v", err)
+	}
+}
+
+func testCreateUserNormalizesEmail(t *testing.T, userStore store.UserStoreInterface) {
+	user := newUser()
+	local := "Alice." + uuid.New().String()
+	user.Email = "  " + local + "@Bücher.EXAMPLE "
+
+	created := createUser(t, userStore, user)
+	if want := local + "@xn--bcher-kva.example"; created.Email != want {
+		t.Errorf("Expected email %q, got %q", want, created.Email)
+	}
+}
+
+func testCreateUserSameMailbox(t *testing.T, userStore store.UserStoreInterface) {
+	local := "alice" + strings.ReplaceAll(uuid.New().String(), "-", "")
+	first := newUser()
+	first.Email = local + "@gmail.com"
+	createUser(t, userStore, first)
+
+	user := newUser()
+	user.Email = strings.ToUpper(local) + "@Gmail.com"
+	if _, err := userStore.CreateUser(t.Context(), user); !errors.Is(err, store.ErrDuplicateEmail) {
+		t.Errorf("Expected ErrDuplicateEmail for %s, got %v", user.Email, err)
+	}
+
+	// without provider rules the variants Gmail delivers to the same
+	// mailbox are users of their own
+	variant := newUser()
+	variant.Email = local[:3] + "." + local[3:] + "+news@gmail.com"
+	createUser(t, userStore, variant)
+}
+
+func testCreateUserNormalizesPhone(t *testing.T, userStore store.UserStoreInterface) {
+	user := newUser()
+	user.Phone = "+94 (71) 234-5678"
+
+	created := createUser(t, userStore, user)
+	if created.Phone != "+94712345678" || created.PhoneCountry != "LK" || created.PhoneType != "mobile" {
+		t.Errorf("Expected the E.164 number of a mobile in LK, got %q, %q, %q", created.Phone, created.PhoneCountry, created.PhoneType)
+	}
+
+	got, _, err := userStore.GetUserById(t.Context(), created.UserId)
+	if err != nil {
+		t.Fatalf("GetUserById failed: %v", err)
+	}
+	if got.Phone != created.Phone || got.PhoneCountry != "LK" || got.PhoneType != "mobile" {
+		t.Errorf("Expected the number as created, got %q, %q, %q", got.Phone, got.PhoneCountry, got.PhoneType)
+	}
+}
+
+func testCreateUserInvalidPhone(t *testing.T, userStore store.UserStoreInterface) {
+	// without a default region a national number cannot be read
+	for _, phone := range []string{"+1 555 555 0100", "0712345678"} {
+		user := newUser()
+		user.Phone = phone
+		if _, err := userStore.CreateUser(t.Context(), user); !errors.Is(err, phonenumber.ErrInvalid) {
+			t.Errorf("Expected phonenumber.ErrInvalid for %s, got %v", phone, err)
+		}
+	}
+}
+
+func testCreateUserInvalidEmail(t *testing.T, userStore store.UserStoreInterface) {
+	user := newUser()
+	user.Email = "alice@"
+
+	if _, err := userStore.CreateUser(t.Context(), user); !errors.Is(err, emailaddr.ErrInvalid) {
+		t.Errorf("Expected emailaddr.ErrInvalid, got %v", err)
+	}
+}
+
+func testGetAllUsers(t *testing.T, userStore store.UserStoreInterface) {
+	first := createUser(t, userStore, newUser())
+	second := createUser(t, userStore, newUser())
+
+	users, err := userStore.GetAllUsers(t.Context())
+	if err != nil {
+		t.Fatalf("GetAllUsers failed: %v", err)
+	}
+
+	found := 0
+	for _, u := range users {
+		if u.UserId == first.UserId || u.UserId == second.UserId {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("Expected both created users, found %d", found)
+	}
+}
+
+func testGetUserById(t *testing.T, userStore store.UserStoreInterface) {
+	user := createUser(t, userStore, newUser())
+
+	got, ok, err := userStore.GetUserById(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("GetUserById failed: %v", err)
+	}
+	if !ok {
+		t.Fatalf("User not found")
+	}
+	if !reflect.DeepEqual(got, user) {
+		t.Errorf("Expected %+v, got %+v", user, got)
+	}
+}
+
+func testGetUserByIdNotFound(t *testing.T, userStore store.UserStoreInterface) {
+	_, ok, err := userStore.GetUserById(t.Context(), uuid.New())
+	if err != nil {
+		t.Fatalf("GetUserById failed: %v", err)
+	}
+	if ok {
+		t.Errorf("Expected user not to be found")
+	}
+}
+
+func testGetUsersByIds(t *testing.T, userStore store.UserStoreInterface) {
+	first := createUser(t, userStore, newUser())
+	second := createUser(t, userStore, newUser())
+
+	users, err := userStore.GetUsersByIds(t.Context(), []uuid.UUID{first.UserId, second.UserId, uuid.New()})
+	if err != nil {
+		t.Fatalf("GetUsersByIds failed: %v", err)
+	}
+
+	if len(users) != 2 {
+		t.Errorf("Expected 2 users, got %d", len(users))
+	}
+}
+
+func testListUsersFilterAndPaginate(t *testing.T, userStore store.UserStoreInterface) {
+	lastName := "Paged" + uuid.New().String()[:8]
+	for i := 0; i < 3; i++ {
+		user := newUser()
+		user.LastName = lastName
+		createUser(t, userStore, user)
+	}
+
+	// the name filter is case-insensitive and matches part of either name
+	filter := model.UserFilter{Name: "pAGED" + lastName[5:]}
+
+	page, err := userStore.ListUsers(t.Context(), filter, uuid.Nil, 2)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(page) != 2 {
+		t.Fatalf("Expected 2 users on first page, got %d", len(page))
+	}
+	if page[0].UserId.String() >= page[1].UserId.String() {
+		t.Errorf("Expected users ordered by id")
+	}
+
+	rest, err := userStore.ListUsers(t.Context(), filter, page[1].UserId, 2)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(rest) != 1 {
+		t.Fatalf("Expected 1 user on second page, got %d", len(rest))
+	}
+	if rest[0].UserId.String() <= page[1].UserId.String() {
+		t.Errorf("Expected second page to start after the cursor")
+	}
+}
+
+func testListUsersStatusAndEmail(t *testing.T, userStore store.UserStoreInterface) {
+	user := newUser()
+	user.Status = model.StatusInactive
+	user = createUser(t, userStore, user)
+
+	users, err := userStore.ListUsers(t.Context(), model.UserFilter{Email: user.Email, Status: model.StatusInactive}, uuid.Nil, 10)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(users) != 1 || users[0].UserId != user.UserId {
+		t.Errorf("Expected only the created user, got %+v", users)
+	}
+
+	users, err = userStore.ListUsers(t.Context(), model.UserFilter{Email: user.Email, Status: model.StatusActive}, uuid.Nil, 10)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(users) != 0 {
+		t.Errorf("Expected no users, got %d", len(users))
+	}
+}
+
+func testListUsersEmailIgnoresCase(t *testing.T, userStore store.UserStoreInterface) {
+	user := createUser(t, userStore, newUser())
+
+	users, err := userStore.ListUsers(t.Context(), model.UserFilter{Email: strings.ToUpper(user.Email)}, uuid.Nil, 10)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(users) != 1 || users[0].UserId != user.UserId {
+		t.Errorf("Expected only %s, got %+v", user.UserId, users)
+	}
+}
+
+func testListUsersAttributes(t *testing.T, userStore store.UserStoreInterface) {
+	department := "dept-" + uuid.New().String()
+	user := newUser()
+	user.Attributes = map[string]any{"department": department, "costCentre": "cc-1", "employeeNumber": 42.0}
+	user = createUser(t, userStore, user)
+
+	other := newUser()
+	other.Attributes = map[string]any{"department": department, "costCentre": "cc-2"}
+	createUser(t, userStore, other)
+
+	users, err := userStore.ListUsers(t.Context(), model.UserFilter{
+		Attributes: map[string]string{"department": department, "costCentre": "cc-1"},
+	}, uuid.Nil, 10)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(users) != 1 || users[0].UserId != user.UserId {
+		t.Errorf("Expected only the user with both attributes, got %+v", users)
+	}
+
+	users, err = userStore.ListUsers(t.Context(), model.UserFilter{
+		Attributes: map[string]string{"department": department},
+	}, uuid.Nil, 10)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("Expected both users of the department, got %d", len(users))
+	}
+
+	// without a schema, values are strings and do not match numbers
+	users, err = userStore.ListUsers(t.Context(), model.UserFilter{
+		Attributes: map[string]string{"department": department, "employeeNumber": "42"},
+	}, uuid.Nil, 10)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(users) != 0 {
+		t.Errorf("Expected no users, got %d", len(users))
+	}
+}
+
+func testUpdateUser(t *testing.T, userStore store.UserStoreInterface) {
+	user := createUser(t, userStore, newUser())
+
+	user.FirstName = "UpdatedName"
+	user.Status = model.StatusInactive
+	updated, ok, err := userStore.UpdateUser(t.Context(), user, user.UserId)
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if !ok {
+		t.Fatalf("UpdateUser returned not ok")
+	}
+	if !reflect.DeepEqual(updated, user) {
+		t.Errorf("Expected %+v, got %+v", user, updated)
+	}
+
+	got, _, err := userStore.GetUserById(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("GetUserById failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, user) {
+		t.Errorf("Expected update to be stored, got %+v", got)
+	}
+}
+
+func testUpdateUserClearsAge(t *testing.T, userStore store.UserStoreInterface) {
+	user := createUser(t, userStore, newUser())
+
+	user.Age = 0
+	updated, _, err := userStore.UpdateUser(t.Context(), user, user.UserId)
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if updated.Age != 0 {
+		t.Errorf("Expected age to be cleared, got %d", updated.Age)
+	}
+}
+
+func testUpdateUserReplacesAttributes(t *testing.T, userStore store.UserStoreInterface) {
+	user := createUser(t, userStore, newUser())
+
+	user.Attributes = map[string]any{"costCentre": "cc-9"}
+	updated, _, err := userStore.UpdateUser(t.Context(), user, user.UserId)
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if !reflect.DeepEqual(updated.Attributes, user.Attributes) {
+		t.Errorf("Expected attributes %v, got %v", user.Attributes, updated.Attributes)
+	}
+}
+
+func testUpdateUserNotFound(t *testing.T, userStore store.UserStoreInterface) {
+	_, ok, err := userStore.UpdateUser(t.Context(), newUser(), uuid.New())
+	if err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if ok {
+		t.Errorf("Expected UpdateUser to report not found")
+	}
+}
+
+func testUpdateUserDuplicateEmail(t *testing.T, userStore store.UserStoreInterface) {
+	first := createUser(t, userStore, newUser())
+	second := createUser(t, userStore, newUser())
+
+	second.Email = first.Email
+	_, _, err := userStore.UpdateUser(t.Context(), second, second.UserId)
+	if !errors.Is(err, store.ErrDuplicateEmail) {
+		t.Errorf("Expected ErrDuplicateEmail, got %v", err)
+	}
+}
+
+func testPutUser(t *testing.T, userStore store.UserStoreInterface) {
+	user := newUser()
+	user.UserId = uuid.New()
+
+	created, ok, err := userStore.PutUser(t.Context(), user, model.Precondition{})
+	if err != nil {
+		t.Fatalf("PutUser failed: %v", err)
+	}
+	if !ok {
+		t.Errorf("Expected PutUser to create the user")
+	}
+	if created.UserId != user.UserId {
+		t.Errorf("Expected user id %s, got %s", user.UserId, created.UserId)
+	}
+
+	replacement := newUser()
+	replacement.UserId = user.UserId
+	replacement.Age = 0
+	replacement.Attributes = nil
+	replaced, ok, err := userStore.PutUser(t.Context(), replacement, model.Precondition{})
+	if err != nil {
+		t.Fatalf("PutUser failed: %v", err)
+	}
+	if ok {
+		t.Errorf("Expected PutUser to replace the user")
+	}
+	if replaced.Email != replacement.Email || replaced.Age != 0 || len(replaced.Attributes) != 0 {
+		t.Errorf("Expected the user to be replaced, got %+v", replaced)
+	}
+
+	got, _, err := userStore.GetUserById(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("GetUserById failed: %v", err)
+	}
+	if got.Version() != replaced.Version() {
+		t.Errorf("Expected replacement to be stored, got %+v", got)
+	}
+}
+
+func testPutUserIfMatch(t *testing.T, userStore store.UserStoreInterface) {
+	user := createUser(t, userStore, newUser())
+
+	user.FirstName = "Replaced"
+	_, _, err := userStore.PutUser(t.Context(), user, model.Precondition{IfMatch: []string{"stale"}})
+	if !errors.Is(err, store.ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+	}
+
+	current, _, err := userStore.GetUserById(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("GetUserById failed: %v", err)
+	}
+	replaced, _, err := userStore.PutUser(t.Context(), user, model.Precondition{IfMatch: []string{current.Version()}})
+	if err != nil {
+		t.Fatalf("PutUser failed: %v", err)
+	}
+	if replaced.FirstName != "Replaced" {
+		t.Errorf("Expected the user to be replaced, got %+v", replaced)
+	}
+
+	missing := newUser()
+	missing.UserId = uuid.New()
+	_, _, err = userStore.PutUser(t.Context(), missing, model.Precondition{IfMatch: []string{"*"}})
+	if !errors.Is(err, store.ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed for a missing user, got %v", err)
+	}
+}
+
+func testPutUserIfNoneMatch(t *testing.T, userStore store.UserStoreInterface) {
+	user := newUser()
+	user.UserId = uuid.New()
+
+	_, ok, err := userStore.PutUser(t.Context(), user, model.Precondition{IfNoneMatch: true})
+	if err != nil {
+		t.Fatalf("PutUser failed: %v", err)
+	}
+	if !ok {
+		t.Errorf("Expected PutUser to create the user")
+	}
+
+	_, _, err = userStore.PutUser(t.Context(), user, model.Precondition{IfNoneMatch: true})
+	if !errors.Is(err, store.ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+	}
+}
+
+// testPutUserIfNoneMatchConcurrent checks that of puts racing to create the
+// same user, exactly one does.
+func testPutUserIfNoneMatchConcurrent(t *testing.T, userStore store.UserStoreInterface) {
+	const puts = 8
+	userId := uuid.New()
+
+	var wg sync.WaitGroup
+	results := make(chan error, puts)
+	for i := range puts {
+		wg.Go(func() {
+			user := newUser()
+			user.UserId = userId
+			user.Email = fmt.Sprintf("racer%d@example.com", i)
+			_, created, err := userStore.PutUser(t.Context(), user, model.Precondition{IfNoneMatch: true})
+			if err == nil && !created {
+				err = errors.New("replaced the user")
+			}
+			results <- err
+		})
+	}
+	wg.Wait()
+	close(results)
+
+	var creates int
+	for err := range results {
+		switch {
+		case err == nil:
+			creates++
+		case !errors.Is(err, store.ErrPreconditionFailed):
+			t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+		}
+	}
+	if creates != 1 {
+		t.Errorf("Expected exactly one put to create the user, got %d", creates)
+	}
+}
+
+func testPutUserDuplicateEmail(t *testing.T, userStore store.UserStoreInterface) {
+	first := createUser(t, userStore, newUser())
+
+	user := newUser()
+	user.UserId = uuid.New()
+	user.Email = first.Email
+	_, _, err := userStore.PutUser(t.Context(), user, model.Precondition{})
+	if !errors.Is(err, store.ErrDuplicateEmail) {
+		t.Errorf("Expected ErrDuplicateEmail, got %v", err)
+	}
+}
+
+func testModifyUser(t *testing.T, userStore store.UserStoreInterface) {
+	user := createUser(t, userStore, newUser())
+
+	_, _, err := userStore.ModifyUser(t.Context(), user.UserId, model.Precondition{IfMatch: []string{"stale"}}, func(*model.User) error {
+		t.Errorf("Expected modify not to run when the precondition fails")
+		return nil
+	})
+	if !errors.Is(err, store.ErrPreconditionFailed) {
+		t.Errorf("Expected ErrPreconditionFailed, got %v", err)
+	}
+
+	errModify := errors.New("modify failed")
+	_, _, err = userStore.ModifyUser(t.Context(), user.UserId, model.Precondition{}, func(user *model.User) error {
+		user.FirstName = "Discarded"
+		return errModify
+	})
+	if !errors.Is(err, errModify) {
+		t.Errorf("Expected the error of modify, got %v", err)
+	}
+
+	modified, ok, err := userStore.ModifyUser(t.Context(), user.UserId, model.Precondition{IfMatch: []string{user.Version()}}, func(user *model.User) error {
+		user.LastName = "Modified"
+		return nil
+	})
+	if err != nil || !ok {
+		t.Fatalf("ModifyUser failed: %v, %v", ok, err)
+	}
+	if modified.FirstName != user.FirstName || modified.LastName != "Modified" {
+		t.Errorf("Expected only the last name to change, got %+v", modified)
+	}
+
+	_, ok, err = userStore.ModifyUser(t.Context(), uuid.New(), model.Precondition{}, func(*model.User) error { return nil })
+	if err != nil || ok {
+		t.Errorf("Expected no user to modify, got %v, %v", ok, err)
+	}
+}
+
+// testModifyUserConcurrent checks that modifications racing on one user each
+// see the others' writes.
+func testModifyUserConcurrent(t *testing.T, userStore store.UserStoreInterface) {
+	const modifications = 8
+	user := newUser()
+	user.Age = 20
+	user = createUser(t, userStore, user)
+
+	var wg sync.WaitGroup
+	for range modifications {
+		wg.Go(func() {
+			_, _, err := userStore.ModifyUser(t.Context(), user.UserId, model.Precondition{}, func(user *model.User) error {
+				user.Age++
+				return nil
+			})
+			if err != nil {
+				t.Errorf("ModifyUser failed: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	got, _, err := userStore.GetUserById(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("GetUserById failed: %v", err)
+	}
+	if got.Age != user.Age+modifications {
+		t.Errorf("Expected age %d, got %d", user.Age+modifications, got.Age)
+	}
+}
+
+func testDeleteUser(t *testing.T, userStore store.UserStoreInterface) {
+	user := createUser(t, userStore, newUser())
+
+	ok, err := userStore.DeleteUser(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	if !ok {
+		t.Fatalf("DeleteUser returned not ok")
+	}
+
+	_, ok, err = userStore.GetUserById(t.Context(), user.UserId)
+	if err != nil {
+		t.Fatalf("GetUserById failed: %v", err)
+	}
+	if ok {
+		t.Errorf("Expected user to be deleted")
+	}
+}
+
+func testDeleteUserNotFound(t *testing.T, userStore store.UserStoreInterface) {
+	ok, err := userStore.DeleteUser(t.Context(), uuid.New())
+	if err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+	if ok {
+		t.Errorf("Expected DeleteUser to report not found")
+	}
+}
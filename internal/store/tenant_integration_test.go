@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/tenant"
+	"github.com/google/uuid"
+)
+
+func createTenantUser(t *testing.T, ctx context.Context, email string) model.User {
+	t.Helper()
+	created, err := userStore.CreateUser(ctx, model.User{
+		FirstName: "Alice",
+		LastName:  "Smith",
+		Email:     email,
+		Phone:     "+12345678901",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	return created
+}
+
+func TestTenants_CannotReadEachOthersUsers(t *testing.T) {
+	tenantA := tenant.WithID(t.Context(), uuid.New())
+	tenantB := tenant.WithID(t.Context(), uuid.New())
+	userA := createTenantUser(t, tenantA, "alice@example.com")
+	userB := createTenantUser(t, tenantB, "bob@example.com")
+
+	if userA.TenantId != tenant.FromContext(tenantA) {
+		t.Errorf("Expected tenant %s, got %s", tenant.FromContext(tenantA), userA.TenantId)
+	}
+
+	if _, ok, err := userStore.GetUserById(tenantA, userB.UserId); err != nil || ok {
+		t.Errorf("Expected another tenant's user to be hidden, got %v, %v", ok, err)
+	}
+	if _, ok, err := userStore.GetUserById(t.Context(), userB.UserId); err != nil || ok {
+		t.Errorf("Expected the default tenant not to see the user, got %v, %v", ok, err)
+	}
+
+	users, err := userStore.GetUsersByIds(tenantA, []uuid.UUID{userA.UserId, userB.UserId})
+	if err != nil {
+		t.Fatalf("GetUsersByIds failed: %v", err)
+	}
+	if len(users) != 1 || users[0].UserId != userA.UserId {
+		t.Errorf("Expected only the tenant's own user, got %+v", users)
+	}
+
+	all, err := userStore.GetAllUsers(tenantA)
+	if err != nil {
+		t.Fatalf("GetAllUsers failed: %v", err)
+	}
+	if len(all) != 1 || all[0].UserId != userA.UserId {
+		t.Errorf("Expected only the tenant's own user, got %+v", all)
+	}
+
+	listed, err := userStore.ListUsers(tenantA, model.UserFilter{Email: userB.Email}, uuid.Nil, 100)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(listed) != 0 {
+		t.Errorf("Expected no users, got %+v", listed)
+	}
+
+	if _, ok, err := userStore.UpdateUser(tenantA, userB, userB.UserId); err != nil || ok {
+		t.Errorf("Expected updating another tenant's user to find nothing, got %v, %v", ok, err)
+	}
+	if ok, err := userStore.DeleteUser(tenantA, userB.UserId); err != nil || ok {
+		t.Errorf("Expected deleting another tenant's user to find nothing, got %v, %v", ok, err)
+	}
+	if _, ok, err := userStore.GetUserById(tenantB, userB.UserId); err != nil || !ok {
+		t.Errorf("Expected the user to be left alone, got %v, %v", ok, err)
+	}
+}
+
+func TestTenants_EmailUniquePerTenant(t *testing.T) {
+	tenantA := tenant.WithID(t.Context(), uuid.New())
+	tenantB := tenant.WithID(t.Context(), uuid.New())
+
+	createTenantUser(t, tenantA, "shared@example.com")
+	createTenantUser(t, tenantB, "shared@example.com")
+
+	_, err := userStore.CreateUser(tenantA, model.User{
+		FirstName: "Alice",
+		LastName:  "Smith",
+		Email:     "shared@example.com",
+		Phone:     "+12345678901",
+	})
+	if !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("Expected ErrDuplicateEmail, got %v", err)
+	}
+}
+
+func TestTenants_GroupsAndEventsKeptApart(t *testing.T) {
+	tenantA := tenant.WithID(t.Context(), uuid.New())
+	tenantB := tenant.WithID(t.Context(), uuid.New())
+	userA := createTenantUser(t, tenantA, "alice@example.com")
+	userB := createTenantUser(t, tenantB, "bob@example.com")
+
+	// group names are unique per tenant too
+	groupA, err := userStore.CreateGroup(tenantA, model.Group{Name: "Engineering"})
+	if err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+	if _, err := userStore.CreateGroup(tenantB, model.Group{Name: "Engineering"}); err != nil {
+		t.Fatalf("CreateGroup failed: %v", err)
+	}
+
+	if _, ok, err := userStore.GetGroupById(tenantB, groupA.GroupId); err != nil || ok {
+		t.Errorf("Expected another tenant's group to be hidden, got %v, %v", ok, err)
+	}
+	if err := userStore.AddGroupMember(tenantA, groupA.GroupId, userB.UserId); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("Expected ErrUserNotFound adding another tenant's user, got %v", err)
+	}
+
+	eventStore := NewUserEventStore(dbConn)
+	events, err := eventStore.GetUserEventsAfter(tenantA, 0, 1000000)
+	if err != nil {
+		t.Fatalf("GetUserEventsAfter failed: %v", err)
+	}
+	for _, event := range events {
+		if event.UserId != userA.UserId {
+			t.Errorf("Expected only the tenant's own events, got one for %s", event.UserId)
+		}
+	}
+
+	// system work sees every tenant
+	all, err := eventStore.GetUserEventsAfter(tenant.WithAllTenants(t.Context()), 0, 1000000)
+	if err != nil {
+		t.Fatalf("GetUserEventsAfter failed: %v", err)
+	}
+	seen := map[uuid.UUID]bool{}
+	for _, event := range all {
+		seen[event.UserId] = true
+	}
+	if !seen[userA.UserId] || !seen[userB.UserId] {
+		t.Errorf("Expected events of both tenants")
+	}
+}
+
+// TestTenants_EnforcedByDatabase reads the table directly, without the store's
+// queries, to show that row level security rather than the application keeps
+// tenants apart.
+func TestTenants_EnforcedByDatabase(t *testing.T) {
+	tenantA := tenant.WithID(t.Context(), uuid.New())
+	userB := createTenantUser(t, tenant.WithID(t.Context(), uuid.New()), "bob@example.com")
+
+	count := func(t *testing.T, settings ...string) int {
+		t.Helper()
+		tx, err := dbConn.BeginTx(t.Context(), nil)
+		if err != nil {
+			t.Fatalf("BeginTx failed: %v", err)
+		}
+		defer tx.Rollback()
+
+		if _, err := tx.ExecContext(t.Context(), "SET LOCAL ROLE app_tenant"); err != nil {
+			t.Fatalf("SET ROLE failed: %v", err)
+		}
+		for i := 0; i < len(settings); i += 2 {
+			if _, err := tx.ExecContext(t.Context(), "SELECT set_config($1, $2, true)", settings[i], settings[i+1]); err != nil {
+				t.Fatalf("set_config failed: %v", err)
+			}
+		}
+
+		var n int
+		if err := tx.QueryRowContext(t.Context(), "SELECT count(*) FROM users WHERE user_id = $1", userB.UserId).Scan(&n); err != nil {
+			t.Fatalf("count failed: %v", err)
+		}
+		return n
+	}
+
+	if n := count(t); n != 0 {
+		t.Errorf("Expected no rows without a tenant, got %d", n)
+	}
+	if n := count(t, "app.tenant_id", tenant.FromContext(tenantA).String()); n != 0 {
+		t.Errorf("Expected no rows for another tenant, got %d", n)
+	}
+	if n := count(t, "app.tenant_id", userB.TenantId.String()); n != 1 {
+		t.Errorf("Expected the user's own tenant to see it, got %d", n)
+	}
+
+	// rows cannot be written into another tenant either
+	tx, err := dbConn.BeginTx(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(t.Context(), "SELECT set_config('role', 'app_tenant', true), set_config('app.tenant_id', $1, true)", tenant.FromContext(tenantA).String()); err != nil {
+		t.Fatalf("set_config failed: %v", err)
+	}
+	_, err = tx.ExecContext(t.Context(),
+		"INSERT INTO users (first_name, last_name, email, email_key, phone, tenant_id) VALUES ('Eve', 'Smith', 'eve@example.com', 'eve@example.com', '+12345678901', $1)",
+		userB.TenantId,
+	)
+	if err == nil {
+		t.Errorf("Expected inserting into another tenant to be refused")
+	}
+}
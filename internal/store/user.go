@@ -5,59 +5,140 @@ import (
 	"database/sql"
 	"errors"
 
+	"example.com/user-management/internal/attributes"
 	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/emailaddr"
 	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/phonenumber"
+	"example.com/user-management/internal/tenant"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+// ErrDuplicateEmail is returned when a create or update would give two users
+// the same email address.
+var ErrDuplicateEmail = errors.New("email already exists")
+
+// ErrPreconditionFailed is returned when a conditional write finds the user in
+// a state its precondition does not allow.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ErrUserIdTaken is returned when a user cannot be created with the id it was
+// given because another tenant's user has it.
+var ErrUserIdTaken = errors.New("user id taken")
+
 type UserStore struct {
-	queries *db.Queries
+	db          *sql.DB
+	queries     *db.Queries
+	emailRules  emailaddr.Rules
+	phoneParser phonenumber.Parser
+	onEvent     func(model.UserEvent)
 }
 
 type UserStoreInterface interface {
-	CreateUser(user model.User) (model.User, error)
-	GetAllUsers() ([]model.User, error)
-	GetUserById(userId uuid.UUID) (model.User, bool, error)
-	UpdateUser(user model.User, userId uuid.UUID) (model.User, bool, error)
-	DeleteUser(userId uuid.UUID) (bool, error)
+	CreateUser(ctx context.Context, user model.User) (model.User, error)
+	GetAllUsers(ctx context.Context) ([]model.User, error)
+	GetUserById(ctx context.Context, userId uuid.UUID) (model.User, bool, error)
+	GetUsersByIds(ctx context.Context, userIds []uuid.UUID) ([]model.User, error)
+	ListUsers(ctx context.Context, filter model.UserFilter, after uuid.UUID, limit int) ([]model.User, error)
+	UpdateUser(ctx context.Context, user model.User, userId uuid.UUID) (model.User, bool, error)
+	// PutUser creates the user with user.UserId, or replaces the fields of
+	// the existing one as UpdateUser does, and reports whether it was
+	// created. If precondition does not hold it returns
+	// ErrPreconditionFailed.
+	PutUser(ctx context.Context, user model.User, precondition model.Precondition) (model.User, bool, error)
+	// ModifyUser locks the user with userId, checks precondition against it
+	// and stores what modify makes of it as UpdateUser does, so that nothing
+	// is written in between. It reports false if there is no such user, and
+	// returns the error of modify as it is.
+	ModifyUser(ctx context.Context, userId uuid.UUID, precondition model.Precondition, modify func(user *model.User) error) (model.User, bool, error)
+	DeleteUser(ctx context.Context, userId uuid.UUID) (bool, error)
 }
 
 func NewUserStore(dbConn *sql.DB) *UserStore {
 	return &UserStore{
+		db:      dbConn,
 		queries: db.New(dbConn),
 	}
 }
 
-func (store *UserStore) CreateUser(user model.User) (model.User, error) {
+// OnEvent registers fn to be called with every change event this store
+// records, once the transaction recording it has committed. Listeners of the
+// user_events channel see the changes of every instance; fn sees this one's
+// at once. Call it before using the store.
+func (store *UserStore) OnEvent(fn func(model.UserEvent)) {
+	store.onEvent = fn
+}
+
+// SetEmailRules sets which addresses are one mailbox, and so one user. The
+// keys of addresses already stored are not changed. Call it before using the
+// store.
+func (store *UserStore) SetEmailRules(rules emailaddr.Rules) {
+	store.emailRules = rules
+}
+
+// SetPhoneParser sets how phone numbers, such as those in national format,
+// are read. Call it before using the store.
+func (store *UserStore) SetPhoneParser(parser phonenumber.Parser) {
+	store.phoneParser = parser
+}
+
+func (store *UserStore) CreateUser(ctx context.Context, user model.User) (model.User, error) {
 
+	user, err := NormalizeEmail(user)
+	if err != nil {
+		return model.User{}, err
+	}
+	user, err = NormalizePhone(user, store.phoneParser)
+	if err != nil {
+		return model.User{}, err
+	}
 	if user.Status == "" {
 		user.Status = model.StatusActive
 	}
 
-	createdUser, err := store.queries.CreateUser(context.Background(),
-		db.CreateUserParams{
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Email:     user.Email,
-			Phone:     user.Phone,
-			Age: sql.NullInt32{
-				Int32: int32(user.Age),
-				Valid: user.Age > 0,
+	var createdUser model.User
+	err = store.withEventTx(ctx, func(queries *db.Queries) (model.UserEvent, error) {
+		encodedAttributes, err := validateAttributes(ctx, queries, user)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		dbUser, err := queries.CreateUser(ctx,
+			db.CreateUserParams{
+				FirstName: user.FirstName,
+				LastName:  user.LastName,
+				Email:     user.Email,
+				Phone:     user.Phone,
+				Age: sql.NullInt32{
+					Int32: int32(user.Age),
+					Valid: user.Age > 0,
+				},
+				Status:     string(user.Status),
+				Attributes: encodedAttributes,
+				EmailKey:   store.emailRules.Key(user.Email),
 			},
-			Status: string(user.Status),
-		},
-	)
+		)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		createdUser = mapDbUserToModel(&dbUser)
+		return recordUserEvent(ctx, queries, model.EventUserCreated, createdUser)
+	})
 
 	if err != nil {
-		return model.User{}, err
+		return model.User{}, mapUniqueViolation(err)
 	}
 
-	return mapDbUserToModel(&createdUser), nil
+	return createdUser, nil
 }
 
-func (store *UserStore) GetAllUsers() ([]model.User, error) {
+func (store *UserStore) GetAllUsers(ctx context.Context) ([]model.User, error) {
 
-	dbUsers, err := store.queries.GetAllUsers(context.Background())
+	dbUsers, err := query(ctx, store, func(queries *db.Queries) ([]db.User, error) {
+		return queries.GetAllUsers(ctx)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -70,8 +151,10 @@ func (store *UserStore) GetAllUsers() ([]model.User, error) {
 	return users, nil
 }
 
-func (store *UserStore) GetUserById(userId uuid.UUID) (model.User, bool, error) {
-	dbUser, err := store.queries.GetUserByID(context.Background(), userId)
+func (store *UserStore) GetUserById(ctx context.Context, userId uuid.UUID) (model.User, bool, error) {
+	dbUser, err := query(ctx, store, func(queries *db.Queries) (db.User, error) {
+		return queries.GetUserByID(ctx, userId)
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return model.User{}, false, nil
@@ -82,37 +165,248 @@ func (store *UserStore) GetUserById(userId uuid.UUID) (model.User, bool, error)
 	return mapDbUserToModel(&dbUser), true, nil
 }
 
-func (store *UserStore) UpdateUser(user model.User, userId uuid.UUID) (model.User, bool, error) {
+func (store *UserStore) GetUsersByIds(ctx context.Context, userIds []uuid.UUID) ([]model.User, error) {
+	dbUsers, err := query(ctx, store, func(queries *db.Queries) ([]db.User, error) {
+		return queries.GetUsersByIDs(ctx, userIds)
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	dbUser, err := store.queries.UpdateUser(
-		context.Background(),
-		db.UpdateUserParams{
-			UserID:    userId,
-			FirstName: user.FirstName,
-			LastName:  user.LastName,
-			Email:     user.Email,
-			Phone:     user.Phone,
-			Age: sql.NullInt32{
-				Int32: int32(user.Age),
-				Valid: user.Age > 0,
+	users := make([]model.User, len(dbUsers))
+	for i, u := range dbUsers {
+		users[i] = mapDbUserToModel(&u)
+	}
+
+	return users, nil
+}
+
+// ListUsers returns up to limit users matching filter, ordered by id and
+// starting after the given id. Pass uuid.Nil to start from the beginning.
+func (store *UserStore) ListUsers(ctx context.Context, filter model.UserFilter, after uuid.UUID, limit int) ([]model.User, error) {
+	dbUsers, err := query(ctx, store, func(queries *db.Queries) ([]db.User, error) {
+		attributeFilter, err := encodeAttributeFilter(ctx, queries, filter)
+		if err != nil {
+			return nil, err
+		}
+
+		return queries.ListUsers(ctx,
+			db.ListUsersParams{
+				Status:     sql.NullString{String: string(filter.Status), Valid: filter.Status != ""},
+				EmailKey:   sql.NullString{String: store.emailRules.Key(filter.Email), Valid: filter.Email != ""},
+				Name:       sql.NullString{String: filter.Name, Valid: filter.Name != ""},
+				GroupID:    uuid.NullUUID{UUID: filter.GroupId, Valid: filter.GroupId != uuid.Nil},
+				Attributes: attributeFilter,
+				After:      after,
+				RowLimit:   int32(limit),
 			},
-			Status: string(user.Status),
-		},
-	)
+		)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]model.User, len(dbUsers))
+	for i, u := range dbUsers {
+		users[i] = mapDbUserToModel(&u)
+	}
+
+	return users, nil
+}
+
+// CountUsersByStatus returns the number of users in each status. Statuses
+// without users are left out.
+func (store *UserStore) CountUsersByStatus(ctx context.Context) (map[model.Status]int, error) {
+	rows, err := query(ctx, store, func(queries *db.Queries) ([]db.CountUsersByStatusRow, error) {
+		return queries.CountUsersByStatus(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[model.Status]int, len(rows))
+	for _, row := range rows {
+		counts[model.Status(row.Status)] = int(row.UserCount)
+	}
+
+	return counts, nil
+}
+
+func (store *UserStore) UpdateUser(ctx context.Context, user model.User, userId uuid.UUID) (model.User, bool, error) {
+
+	user, err := NormalizeEmail(user)
+	if err != nil {
+		return model.User{}, false, err
+	}
+
+	var updatedUser model.User
+	err = store.withEventTx(ctx, func(queries *db.Queries) (model.UserEvent, error) {
+		current, err := queries.GetUserByIDForUpdate(ctx, userId)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+		user, err := NormalizeChangedPhone(user, mapDbUserToModel(&current), store.phoneParser)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		var event model.UserEvent
+		updatedUser, event, err = store.updateUser(ctx, queries, user, userId)
+		return event, err
+	})
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return model.User{}, false, nil
 		}
+		return model.User{}, false, mapUniqueViolation(err)
+	}
+
+	return updatedUser, true, nil
+
+}
+
+func (store *UserStore) PutUser(ctx context.Context, user model.User, precondition model.Precondition) (model.User, bool, error) {
+
+	user, err := NormalizeEmail(user)
+	if err != nil {
 		return model.User{}, false, err
 	}
+	if user.Status == "" {
+		user.Status = model.StatusActive
+	}
 
-	return mapDbUserToModel(&dbUser), true, nil
+	var putUser model.User
+	var created bool
+	err = store.withEventTx(ctx, func(queries *db.Queries) (model.UserEvent, error) {
+		// locking the row keeps it as the precondition saw it
+		current, err := queries.GetUserByIDForUpdate(ctx, user.UserId)
+		exists := err == nil
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return model.UserEvent{}, err
+		}
 
+		if !exists {
+			if !precondition.Holds(model.User{}, false) {
+				return model.UserEvent{}, ErrPreconditionFailed
+			}
+			newUser, err := NormalizePhone(user, store.phoneParser)
+			if err != nil {
+				return model.UserEvent{}, err
+			}
+			encodedAttributes, err := validateAttributes(ctx, queries, newUser)
+			if err != nil {
+				return model.UserEvent{}, err
+			}
+
+			dbUser, err := queries.CreateUserWithID(
+				ctx,
+				db.CreateUserWithIDParams{
+					UserID:    newUser.UserId,
+					FirstName: newUser.FirstName,
+					LastName:  newUser.LastName,
+					Email:     newUser.Email,
+					Phone:     newUser.Phone,
+					Age: sql.NullInt32{
+						Int32: int32(newUser.Age),
+						Valid: newUser.Age > 0,
+					},
+					Status:     string(newUser.Status),
+					Attributes: encodedAttributes,
+					EmailKey:   store.emailRules.Key(newUser.Email),
+				},
+			)
+			if err == nil {
+				putUser = mapDbUserToModel(&dbUser)
+				created = true
+				return recordUserEvent(ctx, queries, model.EventUserCreated, putUser)
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				return model.UserEvent{}, err
+			}
+
+			// the insert waited for a concurrent put that created the user,
+			// which this one now sees, or the id is another tenant's
+			current, err = queries.GetUserByIDForUpdate(ctx, user.UserId)
+			if errors.Is(err, sql.ErrNoRows) {
+				return model.UserEvent{}, ErrUserIdTaken
+			}
+			if err != nil {
+				return model.UserEvent{}, err
+			}
+		}
+
+		currentUser := mapDbUserToModel(&current)
+		if !precondition.Holds(currentUser, true) {
+			return model.UserEvent{}, ErrPreconditionFailed
+		}
+		user, err := NormalizeChangedPhone(user, currentUser, store.phoneParser)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		var event model.UserEvent
+		putUser, event, err = store.updateUser(ctx, queries, user, user.UserId)
+		return event, err
+	})
+
+	if err != nil {
+		return model.User{}, false, mapUniqueViolation(err)
+	}
+
+	return putUser, created, nil
+}
+
+func (store *UserStore) ModifyUser(ctx context.Context, userId uuid.UUID, precondition model.Precondition, modify func(user *model.User) error) (model.User, bool, error) {
+	var modifiedUser model.User
+	err := store.withEventTx(ctx, func(queries *db.Queries) (model.UserEvent, error) {
+		current, err := queries.GetUserByIDForUpdate(ctx, userId)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+		currentUser := mapDbUserToModel(&current)
+		if !precondition.Holds(currentUser, true) {
+			return model.UserEvent{}, ErrPreconditionFailed
+		}
+
+		user := currentUser
+		if err := modify(&user); err != nil {
+			return model.UserEvent{}, err
+		}
+		user, err = NormalizeEmail(user)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+		user, err = NormalizeChangedPhone(user, currentUser, store.phoneParser)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		var event model.UserEvent
+		modifiedUser, event, err = store.updateUser(ctx, queries, user, userId)
+		return event, err
+	})
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.User{}, false, nil
+		}
+		return model.User{}, false, mapUniqueViolation(err)
+	}
+
+	return modifiedUser, true, nil
 }
 
-func (store *UserStore) DeleteUser(userId uuid.UUID) (bool, error) {
-	_, err := store.queries.DeleteUser(context.Background(), userId)
+func (store *UserStore) DeleteUser(ctx context.Context, userId uuid.UUID) (bool, error) {
+	err := store.withEventTx(ctx, func(queries *db.Queries) (model.UserEvent, error) {
+		dbUser, err := queries.DeleteUser(ctx, userId)
+		if err != nil {
+			return model.UserEvent{}, err
+		}
+
+		return recordUserEvent(ctx, queries, model.EventUserDeleted, mapDbUserToModel(&dbUser))
+	})
+
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return false, nil
@@ -122,14 +416,183 @@ func (store *UserStore) DeleteUser(userId uuid.UUID) (bool, error) {
 	return true, nil
 }
 
+// withTx runs fn inside a transaction so that a user change and the event
+// describing it are committed together. The transaction acts for the tenant in
+// ctx, so row level security hides every other tenant's rows; reads go through
+// it as well.
+func (store *UserStore) withTx(ctx context.Context, fn func(queries *db.Queries) error) error {
+	return withTenantTx(ctx, store.db, store.queries, fn)
+}
+
+// withEventTx runs fn, a user change, through withTx, then hands the event
+// it recorded to OnEvent.
+func (store *UserStore) withEventTx(ctx context.Context, fn func(queries *db.Queries) (model.UserEvent, error)) error {
+	var event model.UserEvent
+	err := store.withTx(ctx, func(queries *db.Queries) error {
+		var err error
+		event, err = fn(queries)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if store.onEvent != nil {
+		store.onEvent(event)
+	}
+	return nil
+}
+
+// query runs a single query through withTx and returns its result.
+func query[T any](ctx context.Context, store *UserStore, fn func(queries *db.Queries) (T, error)) (T, error) {
+	var result T
+	err := store.withTx(ctx, func(queries *db.Queries) error {
+		var err error
+		result, err = fn(queries)
+		return err
+	})
+	return result, err
+}
+
+func withTenantTx(ctx context.Context, dbConn *sql.DB, queries *db.Queries, fn func(queries *db.Queries) error) error {
+	tx, err := dbConn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	txQueries := queries.WithTx(tx)
+	allTenants := "off"
+	if tenant.AllTenants(ctx) {
+		allTenants = "on"
+	}
+	if err := txQueries.ActAsTenant(ctx,
+		db.ActAsTenantParams{
+			TenantID:   tenant.FromContext(ctx).String(),
+			AllTenants: allTenants,
+		},
+	); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	if err := fn(txQueries); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// NormalizeEmail returns user with its address as stores keep it, or an
+// error wrapping emailaddr.ErrInvalid. Uniqueness is checked on the key of
+// the address, so the form entered is kept for display.
+func NormalizeEmail(user model.User) (model.User, error) {
+	email, err := emailaddr.Normalize(user.Email)
+	if err != nil {
+		return model.User{}, err
+	}
+	user.Email = email
+	return user, nil
+}
+
+// NormalizePhone returns user with its phone number in E.164 format, as
+// parser reads it, and its country and type, or an error wrapping
+// phonenumber.ErrInvalid. Users provisioned without a number keep none.
+func NormalizePhone(user model.User, parser phonenumber.Parser) (model.User, error) {
+	if user.Phone != "" {
+		phone, err := parser.Normalize(user.Phone)
+		if err != nil {
+			return model.User{}, err
+		}
+		user.Phone = phone
+	}
+	return DescribePhone(user), nil
+}
+
+// NormalizeChangedPhone is NormalizePhone for a write over stored, the user
+// as it is. A number left as it is stored is kept as it is, so that users
+// stored before numbers were normalized, whose numbers may not be readable,
+// can still be changed otherwise.
+func NormalizeChangedPhone(user, stored model.User, parser phonenumber.Parser) (model.User, error) {
+	if user.Phone == stored.Phone {
+		return DescribePhone(user), nil
+	}
+	return NormalizePhone(user, parser)
+}
+
+// DescribePhone returns user with the country and type of its phone number,
+// which stores derive rather than keep.
+func DescribePhone(user model.User) model.User {
+	country, numberType := phonenumber.Describe(user.Phone)
+	user.PhoneCountry, user.PhoneType = country, string(numberType)
+	return user
+}
+
+// updateUser writes the fields of user over those of the user with userId
+// and records the update, in the caller's transaction.
+func (store *UserStore) updateUser(ctx context.Context, queries *db.Queries, user model.User, userId uuid.UUID) (model.User, model.UserEvent, error) {
+	encodedAttributes, err := validateAttributes(ctx, queries, user)
+	if err != nil {
+		return model.User{}, model.UserEvent{}, err
+	}
+
+	dbUser, err := queries.UpdateUser(
+		ctx,
+		db.UpdateUserParams{
+			UserID:    userId,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Email:     user.Email,
+			Phone:     user.Phone,
+			Age: sql.NullInt32{
+				Int32: int32(user.Age),
+				Valid: user.Age > 0,
+			},
+			Status:     string(user.Status),
+			Attributes: encodedAttributes,
+			EmailKey:   store.emailRules.Key(user.Email),
+		},
+	)
+	if err != nil {
+		return model.User{}, model.UserEvent{}, err
+	}
+
+	updatedUser := mapDbUserToModel(&dbUser)
+	event, err := recordUserEvent(ctx, queries, model.EventUserUpdated, updatedUser)
+	return updatedUser, event, err
+}
+
+func mapUniqueViolation(err error) error {
+	if isUniqueViolation(err) {
+		return ErrDuplicateEmail
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
 func mapDbUserToModel(dbUser *db.User) model.User {
-	return model.User{
+	user := model.User{
 		UserId:    dbUser.UserID,
+		TenantId:  dbUser.TenantID,
 		FirstName: dbUser.FirstName,
 		LastName:  dbUser.LastName,
 		Email:     dbUser.Email,
 		Phone:     dbUser.Phone,
 		Age:       int(dbUser.Age.Int32),
 		Status:    model.Status(dbUser.Status),
+		AvatarUrl: dbUser.AvatarUrl,
+	}
+	if dbUser.EmailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &dbUser.EmailVerifiedAt.Time
+	}
+	if dbUser.PhoneVerifiedAt.Valid {
+		user.PhoneVerifiedAt = &dbUser.PhoneVerifiedAt.Time
 	}
+	// the column only ever holds objects written by attributes.Encode
+	user.Attributes, _ = attributes.Decode(dbUser.Attributes)
+	return DescribePhone(user)
 }
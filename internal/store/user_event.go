@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/model"
+)
+
+type UserEventStore struct {
+	db      *sql.DB
+	queries *db.Queries
+}
+
+type UserEventStoreInterface interface {
+	GetUserEventsAfter(ctx context.Context, eventId int64, limit int) ([]model.UserEvent, error)
+}
+
+func NewUserEventStore(dbConn *sql.DB) *UserEventStore {
+	return &UserEventStore{
+		db:      dbConn,
+		queries: db.New(dbConn),
+	}
+}
+
+func (store *UserEventStore) GetUserEventsAfter(ctx context.Context, eventId int64, limit int) ([]model.UserEvent, error) {
+	var dbEvents []db.UserEvent
+	err := withTenantTx(ctx, store.db, store.queries, func(queries *db.Queries) error {
+		var err error
+		dbEvents, err = queries.GetUserEventsAfter(ctx,
+			db.GetUserEventsAfterParams{
+				EventID: eventId,
+				Limit:   int32(limit),
+			},
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]model.UserEvent, len(dbEvents))
+	for i, e := range dbEvents {
+		event, err := mapDbUserEventToModel(&e)
+		if err != nil {
+			return nil, err
+		}
+		events[i] = event
+	}
+
+	return events, nil
+}
+
+// GetLastUserEventId returns the id of the latest event, or 0 if there is
+// none yet.
+func (store *UserEventStore) GetLastUserEventId(ctx context.Context) (int64, error) {
+	var eventId int64
+	err := withTenantTx(ctx, store.db, store.queries, func(queries *db.Queries) error {
+		var err error
+		eventId, err = queries.GetLastUserEventId(ctx)
+		return err
+	})
+	return eventId, err
+}
+
+// recordUserEvent appends an event to the durable log and notifies listeners
+// on the user_events channel, and returns it. Postgres only delivers the
+// notification once the surrounding transaction commits.
+func recordUserEvent(ctx context.Context, queries *db.Queries, eventType model.EventType, user model.User) (model.UserEvent, error) {
+	payload, err := json.Marshal(user)
+	if err != nil {
+		return model.UserEvent{}, err
+	}
+
+	// Sequence values are handed out at insert but become visible at commit,
+	// so a reader could see event 6 before a slower transaction commits event
+	// 5 and never read 5 after resuming past 6. Holding this lock until commit
+	// makes ids visible in order. Readers resuming by id only read one
+	// tenant's events, so the lock is per tenant: writes of one tenant wait
+	// for each other's commits, those of different tenants do not.
+	if err := queries.LockUserEvents(ctx); err != nil {
+		return model.UserEvent{}, err
+	}
+
+	dbEvent, err := queries.CreateUserEvent(ctx,
+		db.CreateUserEventParams{
+			EventType: string(eventType),
+			UserID:    user.UserId,
+			Payload:   payload,
+		},
+	)
+	if err != nil {
+		return model.UserEvent{}, err
+	}
+
+	event, err := mapDbUserEventToModel(&dbEvent)
+	if err != nil {
+		return model.UserEvent{}, err
+	}
+
+	notification, err := json.Marshal(event)
+	if err != nil {
+		return model.UserEvent{}, err
+	}
+
+	return event, queries.NotifyUserEvent(ctx, string(notification))
+}
+
+func mapDbUserEventToModel(dbEvent *db.UserEvent) (model.UserEvent, error) {
+	var user model.User
+	if err := json.Unmarshal(dbEvent.Payload, &user); err != nil {
+		return model.UserEvent{}, err
+	}
+
+	return model.UserEvent{
+		EventId:   dbEvent.EventID,
+		Type:      model.EventType(dbEvent.EventType),
+		UserId:    dbEvent.UserID,
+		User:      user,
+		CreatedAt: dbEvent.CreatedAt,
+	}, nil
+}
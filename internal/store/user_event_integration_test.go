@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/tenant"
+	"github.com/google/uuid"
+)
+
+func TestUserEvents_RecordedForEachChange(t *testing.T) {
+	eventStore := NewUserEventStore(dbConn)
+
+	before, err := eventStore.GetUserEventsAfter(t.Context(), 0, 1000000)
+	if err != nil {
+		t.Fatalf("GetUserEventsAfter failed: %v", err)
+	}
+	var lastEventId int64
+	if len(before) > 0 {
+		lastEventId = before[len(before)-1].EventId
+	}
+
+	user := createTestUser(t)
+	user.FirstName = "UpdatedName"
+	if _, _, err := userStore.UpdateUser(t.Context(), user, user.UserId); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+	if _, err := userStore.DeleteUser(t.Context(), user.UserId); err != nil {
+		t.Fatalf("DeleteUser failed: %v", err)
+	}
+
+	got, err := eventStore.GetUserEventsAfter(t.Context(), lastEventId, 10)
+	if err != nil {
+		t.Fatalf("GetUserEventsAfter failed: %v", err)
+	}
+
+	var userEvents []model.UserEvent
+	for _, e := range got {
+		if e.UserId == user.UserId {
+			userEvents = append(userEvents, e)
+		}
+	}
+
+	expected := []model.EventType{model.EventUserCreated, model.EventUserUpdated, model.EventUserDeleted}
+	if len(userEvents) != len(expected) {
+		t.Fatalf("Expected %d events, got %d", len(expected), len(userEvents))
+	}
+	for i, e := range userEvents {
+		if e.Type != expected[i] {
+			t.Errorf("Expected event %d to be %s, got %s", i, expected[i], e.Type)
+		}
+	}
+	if userEvents[2].User.FirstName != "UpdatedName" {
+		t.Errorf("Expected deleted event to carry the last user state, got %s", userEvents[2].User.FirstName)
+	}
+}
+
+func TestUserEvents_AssignedInCommitOrder(t *testing.T) {
+	eventStore := NewUserEventStore(dbConn)
+
+	// a transaction that has recorded an event but not committed yet
+	tx, err := dbConn.BeginTx(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	defer tx.Rollback()
+	lockUserEvents(t, tx, tenant.Default)
+
+	user := model.User{
+		FirstName: "Alice",
+		LastName:  "Smith",
+		Email:     fmt.Sprintf("alice.%s@example.com", uuid.New().String()),
+		Phone:     "+12345678901",
+		Age:       30,
+		Status:    model.StatusActive,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		var err error
+		user, err = userStore.CreateUser(t.Context(), user)
+		errs <- err
+	}()
+
+	select {
+	case <-errs:
+		t.Fatal("Expected the event to wait for the pending transaction")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if err := <-errs; err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	lastEventId, err := eventStore.GetLastUserEventId(t.Context())
+	if err != nil {
+		t.Fatalf("GetLastUserEventId failed: %v", err)
+	}
+	got, err := eventStore.GetUserEventsAfter(t.Context(), lastEventId-1, 10)
+	if err != nil {
+		t.Fatalf("GetUserEventsAfter failed: %v", err)
+	}
+	if len(got) != 1 || got[0].UserId != user.UserId {
+		t.Fatalf("Expected the last event to be the creation of %s, got %+v", user.UserId, got)
+	}
+}
+
+func TestUserEvents_OtherTenantsDoNotWait(t *testing.T) {
+	tx, err := dbConn.BeginTx(t.Context(), nil)
+	if err != nil {
+		t.Fatalf("BeginTx failed: %v", err)
+	}
+	defer tx.Rollback()
+	lockUserEvents(t, tx, tenant.Default)
+
+	ctx, cancel := context.WithTimeout(tenant.WithID(t.Context(), uuid.New()), 5*time.Second)
+	defer cancel()
+	if _, err := userStore.CreateUser(ctx, model.User{
+		FirstName: "Alice",
+		LastName:  "Smith",
+		Email:     fmt.Sprintf("alice.%s@example.com", uuid.NewString()),
+	}); err != nil {
+		t.Fatalf("Expected another tenant's event not to wait for the pending transaction: %v", err)
+	}
+}
+
+// lockUserEvents takes the event lock of tenantId in tx, as a transaction
+// recording an event of that tenant does.
+func lockUserEvents(t *testing.T, tx *sql.Tx, tenantId uuid.UUID) {
+	t.Helper()
+	queries := db.New(tx)
+	if err := queries.ActAsTenant(t.Context(),
+		db.ActAsTenantParams{TenantID: tenantId.String(), AllTenants: "off"},
+	); err != nil {
+		t.Fatalf("ActAsTenant failed: %v", err)
+	}
+	if err := queries.LockUserEvents(t.Context()); err != nil {
+		t.Fatalf("LockUserEvents failed: %v", err)
+	}
+}
+
+func TestOnEvent_CalledForCommittedChanges(t *testing.T) {
+	publishingStore := NewUserStore(dbConn)
+	var published []model.UserEvent
+	publishingStore.OnEvent(func(event model.UserEvent) {
+		published = append(published, event)
+	})
+
+	user, err := publishingStore.CreateUser(t.Context(), model.User{
+		FirstName: "Alice",
+		LastName:  "Smith",
+		Email:     fmt.Sprintf("alice.%s@example.com", uuid.NewString()),
+	})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	// avatars are set beside the decorated store, as verifications are
+	if _, _, err := publishingStore.SetUserAvatar(t.Context(), user.UserId, "/avatars/alice.png"); err != nil {
+		t.Fatalf("SetUserAvatar failed: %v", err)
+	}
+	if _, _, err := publishingStore.UpdateUser(t.Context(), user, uuid.New()); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+
+	expected := []model.EventType{model.EventUserCreated, model.EventUserUpdated}
+	if len(published) != len(expected) {
+		t.Fatalf("Expected %d events, got %+v", len(expected), published)
+	}
+	for i, event := range published {
+		if event.Type != expected[i] || event.UserId != user.UserId || event.EventId == 0 {
+			t.Errorf("Unexpected event %+v", event)
+		}
+	}
+}
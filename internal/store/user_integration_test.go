@@ -3,11 +3,14 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"testing"
 
+	"example.com/user-management/internal/db"
+	"example.com/user-management/internal/emailaddr"
 	"example.com/user-management/internal/model"
 	"example.com/user-management/internal/testutils"
 	"github.com/google/uuid"
@@ -24,22 +27,7 @@ func TestMain(m *testing.M) {
 
 	dbConn = env.DB
 
-	schema := `
-	CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
-
-	CREATE TABLE IF NOT EXISTS users (
-		user_id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-		first_name TEXT NOT NULL,
-		last_name TEXT NOT NULL,
-		email TEXT NOT NULL UNIQUE,
-		phone TEXT NOT NULL,
-		age INT,
-		status TEXT NOT NULL DEFAULT 'Active' CHECK (status IN ('Active','Inactive')),
-		created_at TIMESTAMP NOT NULL DEFAULT now()
-	);
-	`
-
-	if _, err := dbConn.Exec(schema); err != nil {
+	if _, err := db.MigrateUp(dbConn, emailaddr.Rules{}); err != nil {
 		log.Fatal(err)
 	}
 
@@ -59,7 +47,7 @@ func createTestUser(t *testing.T) model.User {
 		Status:    model.StatusActive,
 	}
 
-	created, err := userStore.CreateUser(user)
+	created, err := userStore.CreateUser(t.Context(), user)
 	if err != nil {
 		t.Fatalf("CreateUser failed: %v", err)
 	}
@@ -76,7 +64,7 @@ func TestCreateUser(t *testing.T) {
 		Status:    model.StatusActive,
 	}
 
-	created, err := userStore.CreateUser(user)
+	created, err := userStore.CreateUser(t.Context(), user)
 	if err != nil {
 		t.Fatalf("CreateUser failed: %v", err)
 	}
@@ -98,7 +86,7 @@ func TestCreateUser_DefaultStatus(t *testing.T) {
 		Age:       25,
 	}
 
-	created, err := userStore.CreateUser(user)
+	created, err := userStore.CreateUser(t.Context(), user)
 	if err != nil {
 		t.Fatalf("CreateUser failed: %v", err)
 	}
@@ -111,11 +99,21 @@ func TestCreateUser_DefaultStatus(t *testing.T) {
 	}
 }
 
+func TestCreateUser_DuplicateEmail(t *testing.T) {
+	user := createTestUser(t)
+	user.UserId = uuid.Nil
+
+	_, err := userStore.CreateUser(t.Context(), user)
+	if !errors.Is(err, ErrDuplicateEmail) {
+		t.Errorf("Expected ErrDuplicateEmail, got %v", err)
+	}
+}
+
 func TestGetAllUsers(t *testing.T) {
 	createTestUser(t)
 	createTestUser(t)
 
-	users, err := userStore.GetAllUsers()
+	users, err := userStore.GetAllUsers(t.Context())
 	if err != nil {
 		t.Fatalf("GetAllUsers failed: %v", err)
 	}
@@ -128,7 +126,7 @@ func TestGetAllUsers(t *testing.T) {
 func TestGetUserById(t *testing.T) {
 	user := createTestUser(t)
 
-	got, ok, err := userStore.GetUserById(user.UserId)
+	got, ok, err := userStore.GetUserById(t.Context(), user.UserId)
 	if err != nil {
 		t.Fatalf("GetUserById failed: %v", err)
 	}
@@ -140,11 +138,57 @@ func TestGetUserById(t *testing.T) {
 	}
 }
 
+func TestGetUsersByIds(t *testing.T) {
+	first := createTestUser(t)
+	second := createTestUser(t)
+
+	users, err := userStore.GetUsersByIds(t.Context(), []uuid.UUID{first.UserId, second.UserId, uuid.New()})
+	if err != nil {
+		t.Fatalf("GetUsersByIds failed: %v", err)
+	}
+
+	if len(users) != 2 {
+		t.Errorf("Expected 2 users, got %d", len(users))
+	}
+}
+
+func TestListUsers_FilterAndPaginate(t *testing.T) {
+	lastName := "Paged" + uuid.New().String()[:8]
+	for i := 0; i < 3; i++ {
+		user := createTestUser(t)
+		user.LastName = lastName
+		if _, _, err := userStore.UpdateUser(t.Context(), user, user.UserId); err != nil {
+			t.Fatalf("UpdateUser failed: %v", err)
+		}
+	}
+
+	filter := model.UserFilter{Name: lastName}
+
+	page, err := userStore.ListUsers(t.Context(), filter, uuid.Nil, 2)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(page) != 2 {
+		t.Fatalf("Expected 2 users on first page, got %d", len(page))
+	}
+
+	rest, err := userStore.ListUsers(t.Context(), filter, page[1].UserId, 2)
+	if err != nil {
+		t.Fatalf("ListUsers failed: %v", err)
+	}
+	if len(rest) != 1 {
+		t.Fatalf("Expected 1 user on second page, got %d", len(rest))
+	}
+	if rest[0].UserId == page[0].UserId || rest[0].UserId == page[1].UserId {
+		t.Errorf("Expected pages not to overlap")
+	}
+}
+
 func TestUpdateUser(t *testing.T) {
 	user := createTestUser(t)
 
 	user.FirstName = "UpdatedName"
-	updated, ok, err := userStore.UpdateUser(user, user.UserId)
+	updated, ok, err := userStore.UpdateUser(t.Context(), user, user.UserId)
 	if err != nil {
 		t.Fatalf("UpdateUser failed: %v", err)
 	}
@@ -159,7 +203,7 @@ func TestUpdateUser(t *testing.T) {
 func TestDeleteUser(t *testing.T) {
 	user := createTestUser(t)
 
-	ok, err := userStore.DeleteUser(user.UserId)
+	ok, err := userStore.DeleteUser(t.Context(), user.UserId)
 	if err != nil {
 		t.Fatalf("DeleteUser failed: %v", err)
 	}
@@ -167,14 +211,14 @@ func TestDeleteUser(t *testing.T) {
 		t.Fatalf("DeleteUser returned not ok")
 	}
 
-	_, exists, _ := userStore.GetUserById(user.UserId)
+	_, exists, _ := userStore.GetUserById(t.Context(), user.UserId)
 	if exists {
 		t.Errorf("Expected user to be deleted")
 	}
 }
 
 func TestGetUserById_NotFound(t *testing.T) {
-	_, ok, err := userStore.GetUserById(uuid.New())
+	_, ok, err := userStore.GetUserById(t.Context(), uuid.New())
 	if err != nil {
 		t.Fatalf("GetUserById failed: %v", err)
 	}
@@ -193,7 +237,7 @@ func TestUpdateUser_NotFound(t *testing.T) {
 		Age:       50,
 		Status:    model.StatusInactive,
 	}
-	_, ok, err := userStore.UpdateUser(user, user.UserId)
+	_, ok, err := userStore.UpdateUser(t.Context(), user, user.UserId)
 	if err != nil {
 		t.Fatalf("UpdateUser failed: %v", err)
 	}
@@ -203,7 +247,7 @@ func TestUpdateUser_NotFound(t *testing.T) {
 }
 
 func TestDeleteUser_NotFound(t *testing.T) {
-	ok, err := userStore.DeleteUser(uuid.New())
+	ok, err := userStore.DeleteUser(t.Context(), uuid.New())
 	if err != nil {
 		t.Fatalf("DeleteUser failed: %v", err)
 	}
@@ -211,3 +255,24 @@ func TestDeleteUser_NotFound(t *testing.T) {
 		t.Errorf("Expected delete to return ok=false")
 	}
 }
+
+func TestCountUsersByStatus(t *testing.T) {
+	before, err := userStore.CountUsersByStatus(t.Context())
+	if err != nil {
+		t.Fatalf("CountUsersByStatus failed: %v", err)
+	}
+
+	user := createTestUser(t)
+	user.Status = model.StatusInactive
+	if _, _, err := userStore.UpdateUser(t.Context(), user, user.UserId); err != nil {
+		t.Fatalf("UpdateUser failed: %v", err)
+	}
+
+	after, err := userStore.CountUsersByStatus(t.Context())
+	if err != nil {
+		t.Fatalf("CountUsersByStatus failed: %v", err)
+	}
+	if after[model.StatusInactive] != before[model.StatusInactive]+1 {
+		t.Errorf("Expected one more inactive user, got %d then %d", before[model.StatusInactive], after[model.StatusInactive])
+	}
+}
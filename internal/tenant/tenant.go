@@ -0,0 +1,48 @@
+// Package tenant carries the tenant a request acts for. Business units sharing
+// a deployment are tenants: every user belongs to exactly one, and the
+// Postgres store only sees the rows of the tenant in its context.
+package tenant
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Header names the tenant of a request made without an access token.
+const Header = "X-Tenant-ID"
+
+// Default is the tenant of requests that name none. Stores without tenants
+// keep every user in it.
+var Default = uuid.Nil
+
+type idKey struct{}
+
+type allTenantsKey struct{}
+
+// WithID returns a copy of ctx acting for tenantId.
+func WithID(ctx context.Context, tenantId uuid.UUID) context.Context {
+	return context.WithValue(ctx, idKey{}, tenantId)
+}
+
+// FromContext returns the tenant stored by WithID, or Default.
+func FromContext(ctx context.Context) uuid.UUID {
+	tenantId, ok := ctx.Value(idKey{}).(uuid.UUID)
+	if !ok {
+		return Default
+	}
+	return tenantId
+}
+
+// WithAllTenants returns a copy of ctx that sees the rows of every tenant. It
+// is for system work such as metrics and relaying change events, never for
+// work done on behalf of a request.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allTenantsKey{}, true)
+}
+
+// AllTenants reports whether ctx was returned by WithAllTenants.
+func AllTenants(ctx context.Context) bool {
+	all, _ := ctx.Value(allTenantsKey{}).(bool)
+	return all
+}
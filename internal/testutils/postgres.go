@@ -11,7 +11,10 @@ import (
 )
 
 type PostgresEnv struct {
-	DB        *sql.DB
+	DB *sql.DB
+	// DSN connects to the same database, for clients opening their own
+	// connections.
+	DSN       string
 	Terminate func()
 }
 
@@ -59,7 +62,8 @@ func SetUpPostgresEnv(ctx context.Context) *PostgresEnv {
 	}
 
 	return &PostgresEnv{
-		DB: dbConn,
+		DB:  dbConn,
+		DSN: dsn,
 		Terminate: func() {
 			if err := postgres.Terminate(ctx); err != nil {
 				log.Printf("failed to terminate postgres container: %v", err)
@@ -0,0 +1,58 @@
+package token
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestSigner_RoundTrip(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+
+	for _, tenantId := range []uuid.UUID{uuid.Nil, uuid.New()} {
+		id := uuid.New()
+
+		gotTenant, got, err := signer.Verify("email-verification", signer.Sign("email-verification", tenantId, id))
+		if err != nil {
+			t.Fatalf("Verify failed: %v", err)
+		}
+		if gotTenant != tenantId || got != id {
+			t.Errorf("Expected tenant %s and id %s, got %s and %s", tenantId, id, gotTenant, got)
+		}
+	}
+}
+
+func TestSigner_RejectsForeignTokens(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	valid := signer.Sign("email-verification", uuid.Nil, uuid.New())
+	tampered := []byte(valid)
+	tampered[0] ^= 'A' ^ 'B'
+
+	// moving a token to another tenant breaks its signature
+	tenantToken, _ := base64.RawURLEncoding.DecodeString(signer.Sign("email-verification", uuid.New(), uuid.New()))
+	otherTenant := uuid.New()
+	copy(tenantToken, otherTenant[:])
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"other purpose", signer.Sign("password-reset", uuid.Nil, uuid.New())},
+		{"other key", NewSigner([]byte("other")).Sign("email-verification", uuid.Nil, uuid.New())},
+		{"tampered", string(tampered)},
+		{"other tenant", base64.RawURLEncoding.EncodeToString(tenantToken)},
+		{"truncated", valid[:len(valid)-1]},
+		{"not base64", "not a token!"},
+		{"empty", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := signer.Verify("email-verification", test.token); !errors.Is(err, ErrInvalid) {
+				t.Errorf("Expected ErrInvalid, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package tracing
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TraceIdHeader carries the trace id of every traced response.
+const TraceIdHeader = "X-Trace-Id"
+
+// Middleware starts a server span for every request, continuing the trace of
+// an incoming traceparent header. Spans are named after the chi route pattern
+// that served the request, such as "GET /users/{id}", rather than its raw
+// path. It must be installed on the top-level router.
+func Middleware(next http.Handler) http.Handler {
+	named := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if traceId := TraceId(r.Context()); traceId != "" {
+			w.Header().Set(TraceIdHeader, traceId)
+		}
+
+		next.ServeHTTP(w, r)
+
+		// the pattern is only known once chi has routed the request
+		if route := routePattern(r); route != "" {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(spanName(r))
+			span.SetAttributes(semconv.HTTPRoute(route))
+		}
+	})
+
+	// otelhttp renames the span itself once a route has matched, so it has to
+	// agree on the name
+	return otelhttp.NewHandler(named, "http.server",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return spanName(r)
+		}),
+	)
+}
+
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+// spanName is the method followed by the route pattern, or just the method
+// before routing and for requests no route matched.
+func spanName(r *http.Request) string {
+	if route := routePattern(r); route != "" {
+		return r.Method + " " + route
+	}
+	return r.Method
+}
+
+// Error replies like http.Error, adding the request's trace id to the message
+// so that a failure reported by a client can be found in the traces.
+func Error(w http.ResponseWriter, r *http.Request, message string, code int) {
+	if traceId := TraceId(r.Context()); traceId != "" {
+		message = fmt.Sprintf("%s (trace id %s)", message, traceId)
+	}
+	http.Error(w, message, code)
+}
@@ -0,0 +1,108 @@
+package tracing
+
+import (
+	"context"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const userIdKey = attribute.Key("user.id")
+
+// InstrumentUserStore wraps next so that every call runs in its own span,
+// named after the method, such as "UserStore.GetUserById". The spans of the
+// SQL queries a call makes are its children.
+func InstrumentUserStore(next store.UserStoreInterface) store.UserStoreInterface {
+	return &tracedUserStore{next: next}
+}
+
+type tracedUserStore struct {
+	next store.UserStoreInterface
+}
+
+func (userStore *tracedUserStore) start(ctx context.Context, method string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer().Start(ctx, "UserStore."+method,
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// end finishes span, marking it failed if err is set. Not-found results are
+// not errors.
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (userStore *tracedUserStore) CreateUser(ctx context.Context, user model.User) (model.User, error) {
+	ctx, span := userStore.start(ctx, "CreateUser")
+	createdUser, err := userStore.next.CreateUser(ctx, user)
+	if err == nil {
+		span.SetAttributes(userIdKey.String(createdUser.UserId.String()))
+	}
+	end(span, err)
+	return createdUser, err
+}
+
+func (userStore *tracedUserStore) GetAllUsers(ctx context.Context) ([]model.User, error) {
+	ctx, span := userStore.start(ctx, "GetAllUsers")
+	users, err := userStore.next.GetAllUsers(ctx)
+	end(span, err)
+	return users, err
+}
+
+func (userStore *tracedUserStore) GetUserById(ctx context.Context, userId uuid.UUID) (model.User, bool, error) {
+	ctx, span := userStore.start(ctx, "GetUserById", userIdKey.String(userId.String()))
+	user, ok, err := userStore.next.GetUserById(ctx, userId)
+	end(span, err)
+	return user, ok, err
+}
+
+func (userStore *tracedUserStore) GetUsersByIds(ctx context.Context, userIds []uuid.UUID) ([]model.User, error) {
+	ctx, span := userStore.start(ctx, "GetUsersByIds", attribute.Int("user.ids.count", len(userIds)))
+	users, err := userStore.next.GetUsersByIds(ctx, userIds)
+	end(span, err)
+	return users, err
+}
+
+func (userStore *tracedUserStore) ListUsers(ctx context.Context, filter model.UserFilter, after uuid.UUID, limit int) ([]model.User, error) {
+	ctx, span := userStore.start(ctx, "ListUsers", attribute.Int("page.limit", limit))
+	users, err := userStore.next.ListUsers(ctx, filter, after, limit)
+	end(span, err)
+	return users, err
+}
+
+func (userStore *tracedUserStore) UpdateUser(ctx context.Context, user model.User, userId uuid.UUID) (model.User, bool, error) {
+	ctx, span := userStore.start(ctx, "UpdateUser", userIdKey.String(userId.String()))
+	updatedUser, ok, err := userStore.next.UpdateUser(ctx, user, userId)
+	end(span, err)
+	return updatedUser, ok, err
+}
+
+func (userStore *tracedUserStore) PutUser(ctx context.Context, user model.User, precondition model.Precondition) (model.User, bool, error) {
+	ctx, span := userStore.start(ctx, "PutUser", userIdKey.String(user.UserId.String()))
+	putUser, created, err := userStore.next.PutUser(ctx, user, precondition)
+	end(span, err)
+	return putUser, created, err
+}
+
+func (userStore *tracedUserStore) ModifyUser(ctx context.Context, userId uuid.UUID, precondition model.Precondition, modify func(user *model.User) error) (model.User, bool, error) {
+	ctx, span := userStore.start(ctx, "ModifyUser", userIdKey.String(userId.String()))
+	modifiedUser, ok, err := userStore.next.ModifyUser(ctx, userId, precondition, modify)
+	end(span, err)
+	return modifiedUser, ok, err
+}
+
+func (userStore *tracedUserStore) DeleteUser(ctx context.Context, userId uuid.UUID) (bool, error) {
+	ctx, span := userStore.start(ctx, "DeleteUser", userIdKey.String(userId.String()))
+	ok, err := userStore.next.DeleteUser(ctx, userId)
+	end(span, err)
+	return ok, err
+}
@@ -0,0 +1,124 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store/memory"
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.41.0"
+)
+
+// recordSpans installs a tracer provider that keeps every finished span for
+// the rest of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return recorder
+}
+
+func TestMiddleware_NamesSpansByRoutePattern(t *testing.T) {
+	recorder := recordSpans(t)
+
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		Error(w, r, "User Not Found!", http.StatusNotFound)
+	})
+
+	const traceId = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-"+traceId+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	if span.Name() != "GET /users/{id}" {
+		t.Errorf("Expected span name GET /users/{id}, got %s", span.Name())
+	}
+	if span.SpanContext().TraceID().String() != traceId {
+		t.Errorf("Expected the incoming trace %s to be continued, got %s", traceId, span.SpanContext().TraceID())
+	}
+
+	var route string
+	for _, attr := range span.Attributes() {
+		if attr.Key == semconv.HTTPRouteKey {
+			route = attr.Value.AsString()
+		}
+	}
+	if route != "/users/{id}" {
+		t.Errorf("Expected http.route /users/{id}, got %q", route)
+	}
+
+	if got := w.Header().Get(TraceIdHeader); got != traceId {
+		t.Errorf("Expected %s header %s, got %q", TraceIdHeader, traceId, got)
+	}
+	if !strings.Contains(w.Body.String(), traceId) {
+		t.Errorf("Expected the error body to contain the trace id, got %q", w.Body.String())
+	}
+}
+
+func TestError_WithoutTrace(t *testing.T) {
+	w := httptest.NewRecorder()
+	Error(w, httptest.NewRequest(http.MethodGet, "/", nil), "Invalid User Id!", http.StatusBadRequest)
+
+	if w.Body.String() != "Invalid User Id!\n" {
+		t.Errorf("Expected the plain message, got %q", w.Body.String())
+	}
+}
+
+func TestInstrumentUserStore_CreatesChildSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	userStore := InstrumentUserStore(memory.NewUserStore())
+
+	ctx, parent := otel.Tracer("test").Start(t.Context(), "request")
+	if _, err := userStore.CreateUser(ctx, model.User{Email: "alice@example.com"}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	_, _ = userStore.CreateUser(ctx, model.User{Email: "alice@example.com"})
+	parent.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(spans))
+	}
+
+	for _, span := range spans[:2] {
+		if span.Name() != "UserStore.CreateUser" {
+			t.Errorf("Expected span name UserStore.CreateUser, got %s", span.Name())
+		}
+		if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("Expected %s to be a child of the request span", span.Name())
+		}
+	}
+	if spans[0].Status().Code != codes.Unset {
+		t.Errorf("Expected the first create to succeed, got status %v", spans[0].Status())
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("Expected the duplicate create to be marked as failed, got status %v", spans[1].Status())
+	}
+}
@@ -0,0 +1,120 @@
+// Package verification confirms that users control the contact details they
+// gave us.
+package verification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"example.com/user-management/internal/mail"
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/tenant"
+	"example.com/user-management/internal/token"
+	"github.com/google/uuid"
+)
+
+const emailPurpose = "email-verification"
+
+var (
+	ErrUserNotFound    = errors.New("user not found")
+	ErrAlreadyVerified = errors.New("already verified")
+)
+
+// EmailVerificationStore is what EmailVerifier needs from the store: the
+// stores that implement verifications are also user stores.
+type EmailVerificationStore interface {
+	store.UserStoreInterface
+	store.EmailVerificationStoreInterface
+}
+
+type EmailOptions struct {
+	// TTL is how long a verification link stays valid.
+	TTL time.Duration
+	// VerifyURL is the public address of GET /verify-email. The token is
+	// added as the token query parameter.
+	VerifyURL string
+}
+
+// EmailVerifier mails users a link to confirm their address and confirms it
+// when they follow the link.
+type EmailVerifier struct {
+	store  EmailVerificationStore
+	sender mail.Sender
+	signer *token.Signer
+	opts   EmailOptions
+	now    func() time.Time
+}
+
+func NewEmailVerifier(verificationStore EmailVerificationStore, sender mail.Sender, signer *token.Signer, opts EmailOptions) *EmailVerifier {
+	return &EmailVerifier{
+		store:  verificationStore,
+		sender: sender,
+		signer: signer,
+		opts:   opts,
+		now:    time.Now,
+	}
+}
+
+// Send mails the user a new verification link for their current address.
+// Links sent earlier stay valid until they expire.
+func (verifier *EmailVerifier) Send(ctx context.Context, userId uuid.UUID) error {
+	user, ok, err := verifier.store.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrAlreadyVerified
+	}
+
+	verification := model.EmailVerification{
+		TokenId:   uuid.New(),
+		UserId:    user.UserId,
+		Email:     user.Email,
+		ExpiresAt: verifier.now().Add(verifier.opts.TTL),
+	}
+	if err := verifier.store.CreateEmailVerification(ctx, verification); err != nil {
+		return fmt.Errorf("failed to store verification: %w", err)
+	}
+
+	link, err := url.Parse(verifier.opts.VerifyURL)
+	if err != nil {
+		return err
+	}
+	query := link.Query()
+	query.Set("token", verifier.signer.Sign(emailPurpose, tenant.FromContext(ctx), verification.TokenId))
+	link.RawQuery = query.Encode()
+
+	msg, err := mail.NewMessage(user.Email, "verify_email", map[string]any{
+		"FirstName": user.FirstName,
+		"Email":     user.Email,
+		"Link":      link.String(),
+		"ExpiresIn": verifier.opts.TTL.String(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := verifier.sender.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	return nil
+}
+
+// Verify confirms the address the token was mailed to and returns the
+// updated user. Besides token.ErrInvalid it returns the store's verification
+// errors.
+func (verifier *EmailVerifier) Verify(ctx context.Context, tokenString string) (model.User, error) {
+	tenantId, tokenId, err := verifier.signer.Verify(emailPurpose, tokenString)
+	if err != nil {
+		return model.User{}, err
+	}
+	// links are followed without naming a tenant, so the token does
+	ctx = tenant.WithID(ctx, tenantId)
+	return verifier.store.ConfirmEmailVerification(ctx, tokenId, verifier.now())
+}
@@ -0,0 +1,140 @@
+package verification
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"example.com/user-management/internal/model"
+	"example.com/user-management/internal/sms"
+	"example.com/user-management/internal/store"
+	"example.com/user-management/internal/token"
+	"github.com/google/uuid"
+)
+
+const phonePurpose = "phone-verification"
+
+// ThrottledError is returned when a code was sent too recently for another
+// to be sent.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (err *ThrottledError) Error() string {
+	return fmt.Sprintf("a code was sent too recently, retry in %s", err.RetryAfter.Round(time.Second))
+}
+
+// PhoneVerificationStore is what PhoneVerifier needs from the store.
+type PhoneVerificationStore interface {
+	store.UserStoreInterface
+	store.PhoneVerificationStoreInterface
+}
+
+type PhoneOptions struct {
+	// TTL is how long a code stays valid.
+	TTL time.Duration
+	// MaxAttempts is how many guesses a code allows, right or wrong.
+	MaxAttempts int
+	// ResendInterval is how long a user must wait between codes.
+	ResendInterval time.Duration
+}
+
+// PhoneVerifier texts users a one-time code to confirm their number and
+// confirms it when they enter the code.
+type PhoneVerifier struct {
+	store  PhoneVerificationStore
+	sender sms.Sender
+	signer *token.Signer
+	opts   PhoneOptions
+	now    func() time.Time
+}
+
+func NewPhoneVerifier(verificationStore PhoneVerificationStore, sender sms.Sender, signer *token.Signer, opts PhoneOptions) *PhoneVerifier {
+	return &PhoneVerifier{
+		store:  verificationStore,
+		sender: sender,
+		signer: signer,
+		opts:   opts,
+		now:    time.Now,
+	}
+}
+
+// Send texts the user a new code for their current number, replacing any
+// code sent before. It returns a *ThrottledError if the last code was sent
+// less than ResendInterval ago.
+func (verifier *PhoneVerifier) Send(ctx context.Context, userId uuid.UUID) error {
+	user, ok, err := verifier.store.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUserNotFound
+	}
+	if user.PhoneVerifiedAt != nil {
+		return ErrAlreadyVerified
+	}
+
+	// UTC, so that SQLite compares the stored times correctly as text
+	now := verifier.now().UTC()
+	if existing, ok, err := verifier.store.GetPhoneVerification(ctx, userId); err != nil {
+		return err
+	} else if ok {
+		if wait := existing.SentAt.Add(verifier.opts.ResendInterval).Sub(now); wait > 0 {
+			return &ThrottledError{RetryAfter: wait}
+		}
+	}
+
+	code, err := newCode()
+	if err != nil {
+		return err
+	}
+
+	verification := model.PhoneVerification{
+		UserId:    user.UserId,
+		Phone:     user.Phone,
+		CodeHash:  verifier.hash(userId, code),
+		ExpiresAt: now.Add(verifier.opts.TTL),
+		SentAt:    now,
+	}
+	err = verifier.store.CreatePhoneVerification(ctx, verification, now.Add(-verifier.opts.ResendInterval))
+	if errors.Is(err, store.ErrResendTooSoon) {
+		// another request sent one since we looked
+		return &ThrottledError{RetryAfter: verifier.opts.ResendInterval}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store verification: %w", err)
+	}
+
+	msg := sms.Message{
+		To:   user.Phone,
+		Body: fmt.Sprintf("Your verification code is %s. It expires in %s.", code, verifier.opts.TTL),
+	}
+	if err := verifier.sender.Send(ctx, msg); err != nil {
+		return fmt.Errorf("failed to send verification code: %w", err)
+	}
+	return nil
+}
+
+// Verify checks code against the user's outstanding code and, if it
+// matches, confirms the number it was sent to and returns the updated user.
+// It returns the store's verification errors otherwise.
+func (verifier *PhoneVerifier) Verify(ctx context.Context, userId uuid.UUID, code string) (model.User, error) {
+	return verifier.store.ConfirmPhoneVerification(ctx, userId, verifier.hash(userId, code), verifier.opts.MaxAttempts, verifier.now().UTC())
+}
+
+// hash binds the code to the user, so that equal codes hash differently.
+func (verifier *PhoneVerifier) hash(userId uuid.UUID, code string) []byte {
+	return verifier.signer.Digest(phonePurpose, userId[:], []byte(code))
+}
+
+// newCode returns a random six digit code.
+func newCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n), nil
+}
@@ -2,15 +2,18 @@ package main
 
 import (
 	"log"
-	"net/http"
 
+	"example.com/user-management/internal/config"
 	"example.com/user-management/internal/server"
 )
 
 func main() {
-	log.Println("Starting server on port: 8080")
-	err := http.ListenAndServe(":8080", server.New())
+	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	if err := server.Run(cfg); err != nil {
+		log.Fatal(err)
+	}
 }